@@ -82,6 +82,8 @@ func Bootstrap(templates embed.FS, diggerController controllers.DiggerController
 
 	r.POST("/github-app-webhook", diggerController.GithubAppWebHook)
 	r.POST("/github-app-webhook/aam", diggerController.GithubAppWebHookAfterMerge)
+	r.POST("/gitlab-webhook", diggerController.GitlabWebHook)
+	r.POST("/bitbucket-webhook", diggerController.BitbucketWebHook)
 
 	tenantActionsGroup := r.Group("/api/tenants")
 	tenantActionsGroup.Use(middleware.CORSMiddleware())
@@ -112,6 +114,11 @@ func Bootstrap(templates embed.FS, diggerController controllers.DiggerController
 	authorized.GET("/repos/:repo/projects/:projectName/drift-policy", controllers.FindDriftPolicy)
 	authorized.GET("/orgs/:organisation/drift-policy", controllers.FindDriftPolicyForOrg)
 
+	authorized.GET("/repos/:repo/projects/:projectName/policies/:type/revisions", controllers.ListPolicyRevisionsForRepoAndProject)
+	authorized.GET("/orgs/:organisation/policies/:type/revisions", controllers.ListPolicyRevisionsForOrg)
+
+	authorized.POST("/repos/:repo/projects/:projectName/policy-decisions", controllers.ReportPolicyDecision)
+
 	authorized.GET("/repos/:repo/projects/:projectName/runs", controllers.RunHistoryForProject)
 	authorized.POST("/repos/:repo/projects/:projectName/runs", controllers.CreateRunForProject)
 
@@ -120,6 +127,12 @@ func Bootstrap(templates embed.FS, diggerController controllers.DiggerController
 	authorized.GET("/repos/:repo/projects", controllers.FindProjectsForRepo)
 	authorized.POST("/repos/:repo/report-projects", controllers.ReportProjectsForRepo)
 
+	authorized.GET("/repos/:repo/plans", controllers.ListPlans)
+	authorized.PUT("/repos/:repo/plans/*planName", controllers.UploadPlan)
+	authorized.GET("/repos/:repo/plans/*planName", controllers.DownloadPlan)
+	authorized.HEAD("/repos/:repo/plans/*planName", controllers.PlanExists)
+	authorized.DELETE("/repos/:repo/plans/*planName", controllers.DeletePlan)
+
 	authorized.GET("/orgs/:organisation/projects", controllers.FindProjectsForOrg)
 
 	admin.PUT("/repos/:repo/projects/:projectName/access-policy", controllers.UpsertAccessPolicyForRepoAndProject)
@@ -131,6 +144,12 @@ func Bootstrap(templates embed.FS, diggerController controllers.DiggerController
 	admin.PUT("/repos/:repo/projects/:projectName/drift-policy", controllers.UpsertDriftPolicyForRepoAndProject)
 	admin.PUT("/orgs/:organisation/drift-policy", controllers.UpsertDriftPolicyForOrg)
 
+	admin.POST("/repos/:repo/projects/:projectName/policies/:type/revisions/:revision/rollback", controllers.RollbackPolicyForRepoAndProject)
+	admin.POST("/orgs/:organisation/policies/:type/revisions/:revision/rollback", controllers.RollbackPolicyForOrg)
+
+	admin.GET("/repos/:repo/projects/:projectName/policy-decisions", controllers.ListPolicyDecisionsForRepoAndProject)
+	admin.GET("/orgs/:organisation/policy-decisions", controllers.ListPolicyDecisionsForOrg)
+
 	admin.POST("/tokens/issue-access-token", controllers.IssueAccessTokenForOrg)
 
 	r.Use(middleware.CORSMiddleware())
@@ -0,0 +1,91 @@
+package ci_backends
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/diggerhq/digger/backend/models"
+	"io"
+	"log"
+	"net/http"
+)
+
+const bitbucketBaseURL = "https://api.bitbucket.org/2.0"
+
+// BitbucketPipelineCi runs jobs with a custom pipeline of bitbucket-pipelines.yml, the pipeline runs `digger run_spec` with the DIGGER_SPEC variable
+type BitbucketPipelineCi struct {
+	HttpClient *http.Client
+	// defaults to the Bitbucket Cloud api
+	BaseUrl string
+	Token   string
+	// name of the custom pipeline, e.g. digger for `custom: digger:`
+	Pipeline string
+}
+
+type bitbucketPipelineVariable struct {
+	Key     string `json:"key"`
+	Value   string `json:"value"`
+	Secured bool   `json:"secured"`
+}
+
+type bitbucketPipelineRequest struct {
+	Target struct {
+		Type     string `json:"type"`
+		RefType  string `json:"ref_type"`
+		RefName  string `json:"ref_name"`
+		Selector struct {
+			Type    string `json:"type"`
+			Pattern string `json:"pattern"`
+		} `json:"selector"`
+	} `json:"target"`
+	Variables []bitbucketPipelineVariable `json:"variables"`
+}
+
+func (b BitbucketPipelineCi) TriggerWorkflow(repoOwner string, repoName string, job models.DiggerJob, jobString string, commentId int64) error {
+	log.Printf("TriggerBitbucketPipeline: repoOwner: %v, repoName: %v, commentId: %v", repoOwner, repoName, commentId)
+	runSpec, err := getRunSpec(models.DiggerVCSBitbucket, "", repoOwner, repoName, job, jobString, commentId)
+	if err != nil {
+		log.Printf("could not create run spec: %v", err)
+		return err
+	}
+
+	var pipelineRequest bitbucketPipelineRequest
+	pipelineRequest.Target.Type = "pipeline_ref_target"
+	pipelineRequest.Target.RefType = "branch"
+	pipelineRequest.Target.RefName = job.Batch.BranchName
+	pipelineRequest.Target.Selector.Type = "custom"
+	pipelineRequest.Target.Selector.Pattern = b.Pipeline
+	pipelineRequest.Variables = []bitbucketPipelineVariable{{Key: "DIGGER_SPEC", Value: runSpec, Secured: true}}
+	body, err := json.Marshal(pipelineRequest)
+	if err != nil {
+		return fmt.Errorf("could not marshal pipeline request: %v", err)
+	}
+
+	baseUrl := b.BaseUrl
+	if baseUrl == "" {
+		baseUrl = bitbucketBaseURL
+	}
+	url := fmt.Sprintf("%s/repositories/%s/%s/pipelines/", baseUrl, repoOwner, repoName)
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", b.Token))
+	req.Header.Set("Content-Type", "application/json")
+
+	client := b.HttpClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not trigger pipeline for %v/%v: %v", repoOwner, repoName, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		responseBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to trigger pipeline. Status code: %d, response: %s", resp.StatusCode, responseBody)
+	}
+	return nil
+}
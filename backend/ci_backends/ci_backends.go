@@ -1,8 +1,13 @@
 package ci_backends
 
 import (
+	"encoding/json"
+	"fmt"
 	"github.com/diggerhq/digger/backend/models"
 	"github.com/diggerhq/digger/backend/utils"
+	"github.com/diggerhq/digger/libs/orchestrator"
+	"github.com/diggerhq/digger/libs/spec"
+	"strconv"
 )
 
 type CiBackend interface {
@@ -17,4 +22,59 @@ type CiBackendOptions struct {
 	RepoFullName         string
 	RepoOwner            string
 	RepoName             string
+	// empty for GitHub
+	VCS models.DiggerVCSType
+}
+
+func jobRunName(job models.DiggerJob, jobSpec orchestrator.JobJson) string {
+	batchIdShort := job.Batch.ID.String()[:8]
+	diggerCommand := fmt.Sprintf("digger %v", job.Batch.BatchType)
+	return fmt.Sprintf("[%v] %v %v By: %v PR: %v", batchIdShort, diggerCommand, jobSpec.ProjectName, jobSpec.RequestedBy, *jobSpec.PullRequestNumber)
+}
+
+// getRunSpec builds the spec `digger run_spec` runs in pipelines that are not GitHub Actions workflows
+func getRunSpec(vcsType models.DiggerVCSType, baseUrl string, repoOwner string, repoName string, job models.DiggerJob, jobString string, commentId int64) (string, error) {
+	var jobSpec orchestrator.JobJson
+	err := json.Unmarshal([]byte(jobString), &jobSpec)
+	if err != nil {
+		return "", fmt.Errorf("could not unmarshal job string: %v", err)
+	}
+
+	runSpec := spec.Spec{
+		JobId:     job.DiggerJobID,
+		CommentId: strconv.FormatInt(commentId, 10),
+		RunName:   jobRunName(job, jobSpec),
+		Job:       jobSpec,
+		Reporter: spec.ReporterSpec{
+			ReporterType:      "lazy",
+			ReportingStrategy: "comments_per_run",
+		},
+		Lock: spec.LockSpec{
+			LockType: "noop",
+		},
+		Backend: spec.BackendSpec{
+			BackendType:             "backend",
+			BackendHostname:         jobSpec.BackendHostname,
+			BackendOrganisationName: jobSpec.BackendOrganisationName,
+			BackendJobToken:         jobSpec.BackendJobToken,
+		},
+		VCS: spec.VcsSpec{
+			Actor:     jobSpec.RequestedBy,
+			RepoOwner: repoOwner,
+			RepoName:  repoName,
+			VcsType:   string(vcsType),
+			BaseUrl:   baseUrl,
+		},
+		Policy: spec.PolicySpec{
+			PolicyType: "http",
+		},
+		PlanStorage: spec.PlanStorageSpec{
+			StorageType: "backend",
+		},
+	}
+	marshalled, err := json.Marshal(runSpec)
+	if err != nil {
+		return "", fmt.Errorf("could not marshal run spec: %v", err)
+	}
+	return string(marshalled), nil
 }
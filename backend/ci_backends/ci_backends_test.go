@@ -0,0 +1,111 @@
+package ci_backends
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/diggerhq/digger/backend/models"
+	"github.com/diggerhq/digger/libs/orchestrator"
+	"github.com/diggerhq/digger/libs/spec"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	go_gitlab "github.com/xanzy/go-gitlab"
+)
+
+func testJob(t *testing.T) (models.DiggerJob, string) {
+	prNumber := 7
+	jobString, err := json.Marshal(orchestrator.JobJson{
+		ProjectName:             "prod-vpc",
+		Commands:                []string{"mantis plan"},
+		PullRequestNumber:       &prNumber,
+		RequestedBy:             "alice",
+		BackendHostname:         "https://digger.example.com",
+		BackendOrganisationName: "acme",
+		BackendJobToken:         "cli:token",
+	})
+	require.NoError(t, err)
+	batch := &models.DiggerBatch{
+		ID:         uuid.MustParse("5f0c6a2e-8d1b-4c3a-9e7f-123456789abc"),
+		BranchName: "vpc-eu-west-2",
+		BatchType:  orchestrator.DiggerCommandPlan,
+	}
+	return models.DiggerJob{DiggerJobID: "job-1", Batch: batch}, string(jobString)
+}
+
+func assertRunSpec(t *testing.T, runSpecString string, vcsType string, repoOwner string) {
+	var runSpec spec.Spec
+	require.NoError(t, json.Unmarshal([]byte(runSpecString), &runSpec))
+	assert.Equal(t, "job-1", runSpec.JobId)
+	assert.Equal(t, "42", runSpec.CommentId)
+	assert.Equal(t, "[5f0c6a2e] digger plan prod-vpc By: alice PR: 7", runSpec.RunName)
+	assert.Equal(t, "prod-vpc", runSpec.Job.ProjectName)
+	assert.Equal(t, vcsType, runSpec.VCS.VcsType)
+	assert.Equal(t, repoOwner, runSpec.VCS.RepoOwner)
+	assert.Equal(t, "infra-live", runSpec.VCS.RepoName)
+	assert.Equal(t, "https://digger.example.com", runSpec.Backend.BackendHostname)
+	assert.Equal(t, "cli:token", runSpec.Backend.BackendJobToken)
+	assert.Equal(t, "backend", runSpec.PlanStorage.StorageType)
+}
+
+func TestGitlabPipelineCiTriggersPipelineOnBranch(t *testing.T) {
+	job, jobString := testJob(t)
+	var request struct {
+		Ref       string `json:"ref"`
+		Variables []struct {
+			Key          string `json:"key"`
+			Value        string `json:"value"`
+			VariableType string `json:"variable_type"`
+		} `json:"variables"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/api/v4/projects/platform%2Faws%2Finfra-live/pipeline", r.URL.EscapedPath())
+		assert.Equal(t, "token", r.Header.Get("Private-Token"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": 1}`))
+	}))
+	defer server.Close()
+	client, err := go_gitlab.NewClient("token", go_gitlab.WithBaseURL(server.URL))
+	require.NoError(t, err)
+
+	ci := GitlabPipelineCi{Client: client}
+	require.NoError(t, ci.TriggerWorkflow("platform/aws", "infra-live", job, jobString, 42))
+	assert.Equal(t, "vpc-eu-west-2", request.Ref)
+	require.Len(t, request.Variables, 1)
+	assert.Equal(t, "DIGGER_SPEC", request.Variables[0].Key)
+	assert.Equal(t, "env_var", request.Variables[0].VariableType)
+	assertRunSpec(t, request.Variables[0].Value, "gitlab", "platform/aws")
+}
+
+func TestBitbucketPipelineCiTriggersCustomPipeline(t *testing.T) {
+	job, jobString := testJob(t)
+	var request bitbucketPipelineRequest
+	status := http.StatusCreated
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/repositories/acme/infra-live/pipelines/", r.URL.Path)
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	ci := BitbucketPipelineCi{HttpClient: server.Client(), BaseUrl: server.URL, Token: "token", Pipeline: "digger"}
+	require.NoError(t, ci.TriggerWorkflow("acme", "infra-live", job, jobString, 42))
+	assert.Equal(t, "pipeline_ref_target", request.Target.Type)
+	assert.Equal(t, "branch", request.Target.RefType)
+	assert.Equal(t, "vpc-eu-west-2", request.Target.RefName)
+	assert.Equal(t, "custom", request.Target.Selector.Type)
+	assert.Equal(t, "digger", request.Target.Selector.Pattern)
+	require.Len(t, request.Variables, 1)
+	assert.Equal(t, "DIGGER_SPEC", request.Variables[0].Key)
+	assert.True(t, request.Variables[0].Secured)
+	assertRunSpec(t, request.Variables[0].Value, "bitbucket", "acme")
+
+	status = http.StatusNotFound
+	assert.ErrorContains(t, ci.TriggerWorkflow("acme", "infra-live", job, jobString, 42), "Status code: 404")
+}
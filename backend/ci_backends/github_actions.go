@@ -26,16 +26,11 @@ func (g GithubActionCi) TriggerWorkflow(repoOwner string, repoName string, job m
 		return fmt.Errorf("could not marshal json string: %v", err)
 	}
 
-	batchIdShort := job.Batch.ID.String()[:8]
-	diggerCommand := fmt.Sprintf("digger %v", job.Batch.BatchType)
-	projectName := jobSpec.ProjectName
-	requestedBy := jobSpec.RequestedBy
-	prNumber := *jobSpec.PullRequestNumber
 	inputs := orchestrator_scheduler.WorkflowInput{
 		Id:        job.DiggerJobID,
 		JobString: jobString,
 		CommentId: strconv.FormatInt(commentId, 10),
-		RunName:   fmt.Sprintf("[%v] %v %v By: %v PR: %v", batchIdShort, diggerCommand, projectName, requestedBy, prNumber),
+		RunName:   jobRunName(job, jobSpec),
 	}
 
 	_, err = client.Actions.CreateWorkflowDispatchEventByFileName(context.Background(), repoOwner, repoName, job.WorkflowFile, github.CreateWorkflowDispatchEventRequest{
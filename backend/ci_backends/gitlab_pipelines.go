@@ -0,0 +1,41 @@
+package ci_backends
+
+import (
+	"fmt"
+	"github.com/diggerhq/digger/backend/models"
+	go_gitlab "github.com/xanzy/go-gitlab"
+	"log"
+)
+
+// GitlabPipelineCi runs jobs in pipelines of the merge request branch, the pipeline runs `digger run_spec` with the DIGGER_SPEC variable
+type GitlabPipelineCi struct {
+	Client *go_gitlab.Client
+	// api url of self-managed instances, passed on to the spec
+	BaseUrl string
+}
+
+func (g GitlabPipelineCi) TriggerWorkflow(repoOwner string, repoName string, job models.DiggerJob, jobString string, commentId int64) error {
+	log.Printf("TriggerGitlabPipeline: repoOwner: %v, repoName: %v, commentId: %v", repoOwner, repoName, commentId)
+	runSpec, err := getRunSpec(models.DiggerVCSGitlab, g.BaseUrl, repoOwner, repoName, job, jobString, commentId)
+	if err != nil {
+		log.Printf("could not create run spec: %v", err)
+		return err
+	}
+
+	// the owner of GitLab projects can be a group with subgroups
+	projectPath := repoOwner + "/" + repoName
+	_, _, err = g.Client.Pipelines.CreatePipeline(projectPath, &go_gitlab.CreatePipelineOptions{
+		Ref: go_gitlab.Ptr(job.Batch.BranchName),
+		Variables: &[]*go_gitlab.PipelineVariableOptions{
+			{
+				Key:          go_gitlab.Ptr("DIGGER_SPEC"),
+				Value:        go_gitlab.Ptr(runSpec),
+				VariableType: go_gitlab.Ptr("env_var"),
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("could not create pipeline for %v: %v", projectPath, err)
+	}
+	return nil
+}
@@ -2,8 +2,11 @@ package ci_backends
 
 import (
 	"fmt"
+	"github.com/diggerhq/digger/backend/models"
 	"github.com/diggerhq/digger/backend/utils"
 	"log"
+	"net/http"
+	"os"
 )
 
 type CiBackendProvider interface {
@@ -13,6 +16,26 @@ type CiBackendProvider interface {
 type DefaultBackendProvider struct{}
 
 func (d DefaultBackendProvider) GetCiBackend(options CiBackendOptions) (CiBackend, error) {
+	switch options.VCS {
+	case models.DiggerVCSGitlab:
+		client, _, err := utils.GetGitlabClient()
+		if err != nil {
+			log.Printf("GetCiBackend: could not get gitlab client: %v", err)
+			return nil, fmt.Errorf("could not get gitlab client: %v", err)
+		}
+		return &GitlabPipelineCi{Client: client, BaseUrl: os.Getenv("GITLAB_BASE_URL")}, nil
+	case models.DiggerVCSBitbucket:
+		token := os.Getenv("BITBUCKET_TOKEN")
+		if token == "" {
+			return nil, fmt.Errorf("could not get bitbucket pipelines: BITBUCKET_TOKEN is not set")
+		}
+		pipeline := os.Getenv("BITBUCKET_PIPELINE_NAME")
+		if pipeline == "" {
+			pipeline = "digger"
+		}
+		return &BitbucketPipelineCi{HttpClient: http.DefaultClient, Token: token, Pipeline: pipeline}, nil
+	}
+
 	client, _, err := utils.GetGithubClient(options.GithubClientProvider, options.GithubInstallationId, options.RepoFullName)
 	if err != nil {
 		log.Printf("GetCiBackend: could not get github client: %v", err)
@@ -1 +1,230 @@
 package controllers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/diggerhq/digger/backend/models"
+	"github.com/diggerhq/digger/backend/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/go-github/v61/github"
+)
+
+type bitbucketBranch struct {
+	Branch struct {
+		Name string `json:"name"`
+	} `json:"branch"`
+	Commit struct {
+		Hash string `json:"hash"`
+	} `json:"commit"`
+}
+
+// bitbucketWebhookEvent is the payload of pull request and pull request comment events
+type bitbucketWebhookEvent struct {
+	Actor struct {
+		Nickname string `json:"nickname"`
+	} `json:"actor"`
+	Repository struct {
+		FullName  string `json:"full_name"`
+		Workspace struct {
+			Slug string `json:"slug"`
+		} `json:"workspace"`
+		Links struct {
+			Html struct {
+				Href string `json:"href"`
+			} `json:"html"`
+		} `json:"links"`
+	} `json:"repository"`
+	PullRequest struct {
+		Id          int             `json:"id"`
+		Draft       bool            `json:"draft"`
+		Source      bitbucketBranch `json:"source"`
+		Destination bitbucketBranch `json:"destination"`
+	} `json:"pullrequest"`
+	Comment *struct {
+		Id      int64 `json:"id"`
+		Content struct {
+			Raw string `json:"raw"`
+		} `json:"content"`
+	} `json:"comment"`
+}
+
+// verifyBitbucketSignature checks the X-Hub-Signature header of webhooks with a secret
+func verifyBitbucketSignature(payload []byte, signature string, secret string) bool {
+	if secret == "" {
+		return false
+	}
+	expected, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil || !strings.HasPrefix(signature, "sha256=") {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// repoSlug is the name of the repository in urls, the name of the repository may differ from it
+func (event bitbucketWebhookEvent) repoSlug() string {
+	return strings.TrimPrefix(event.Repository.FullName, event.Repository.Workspace.Slug+"/")
+}
+
+func (event bitbucketWebhookEvent) cloneUrl() string {
+	return event.Repository.Links.Html.Href + ".git"
+}
+
+// bitbucketEventToGithubEvent converts pull request events to the GitHub events the orchestrator works with,
+// it returns nil for events that don't run any jobs
+func bitbucketEventToGithubEvent(eventKey string, event bitbucketWebhookEvent, defaultBranch string) (interface{}, error) {
+	repo := &github.Repository{
+		Name:          github.String(event.repoSlug()),
+		FullName:      github.String(event.Repository.FullName),
+		DefaultBranch: github.String(defaultBranch),
+		CloneURL:      github.String(event.cloneUrl()),
+		Owner:         &github.User{Login: github.String(event.Repository.Workspace.Slug)},
+	}
+	sender := &github.User{Login: github.String(event.Actor.Nickname)}
+	pullRequest := event.PullRequest
+
+	merged := false
+	var action string
+	switch eventKey {
+	case "pullrequest:created":
+		action = "opened"
+	case "pullrequest:updated":
+		// Bitbucket doesn't tell pushes apart from other updates
+		action = "synchronize"
+	case "pullrequest:fulfilled":
+		action = "closed"
+		merged = true
+	case "pullrequest:rejected":
+		action = "closed"
+	case "pullrequest:comment_created":
+		if event.Comment == nil {
+			return nil, fmt.Errorf("comment event without comment")
+		}
+		return &github.IssueCommentEvent{
+			Action:  github.String("created"),
+			Issue:   &github.Issue{Number: github.Int(pullRequest.Id), Draft: github.Bool(pullRequest.Draft)},
+			Comment: &github.IssueComment{ID: github.Int64(event.Comment.Id), Body: github.String(event.Comment.Content.Raw)},
+			Repo:    repo,
+			Sender:  sender,
+		}, nil
+	default:
+		return nil, nil
+	}
+	return &github.PullRequestEvent{
+		Action: github.String(action),
+		Number: github.Int(pullRequest.Id),
+		PullRequest: &github.PullRequest{
+			Number: github.Int(pullRequest.Id),
+			Merged: github.Bool(merged),
+			Draft:  github.Bool(pullRequest.Draft),
+			Head:   &github.PullRequestBranch{Ref: github.String(pullRequest.Source.Branch.Name), SHA: github.String(pullRequest.Source.Commit.Hash)},
+			Base:   &github.PullRequestBranch{Ref: github.String(pullRequest.Destination.Branch.Name)},
+		},
+		Repo:   repo,
+		Sender: sender,
+	}, nil
+}
+
+// BitbucketWebHook handles pull request events signed with the BITBUCKET_WEBHOOK_SECRET
+// for repositories of the default organisation, see getVCSOrganisationId
+func (d DiggerController) BitbucketWebHook(c *gin.Context) {
+	c.Header("Content-Type", "application/json")
+	log.Printf("BitbucketWebHook")
+
+	payload, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		log.Printf("Error reading bitbucket webhook's payload: %v", err)
+		c.String(http.StatusBadRequest, "Error reading bitbucket webhook's payload")
+		return
+	}
+
+	if !verifyBitbucketSignature(payload, c.GetHeader("X-Hub-Signature"), os.Getenv("BITBUCKET_WEBHOOK_SECRET")) {
+		log.Printf("Error validating bitbucket webhook's signature")
+		c.String(http.StatusUnauthorized, "Error validating bitbucket webhook's signature")
+		return
+	}
+
+	eventKey := c.GetHeader("X-Event-Key")
+	if !strings.HasPrefix(eventKey, "pullrequest:") {
+		log.Printf("Unhandled event, event key %v", eventKey)
+		c.JSON(http.StatusOK, "ok")
+		return
+	}
+
+	var event bitbucketWebhookEvent
+	err = json.Unmarshal(payload, &event)
+	if err != nil {
+		log.Printf("Failed to parse Bitbucket Event. :%v\n", err)
+		c.String(http.StatusBadRequest, "Failed to parse Bitbucket Event")
+		return
+	}
+
+	service, token, err := utils.GetBitbucketService(event.Repository.Workspace.Slug, event.repoSlug())
+	if err != nil {
+		log.Printf("GetBitbucketService error: %v", err)
+		c.String(http.StatusInternalServerError, "Error getting bitbucket service")
+		return
+	}
+
+	// pull request events don't include the main branch of the repository
+	defaultBranch, err := service.GetDefaultBranch()
+	if err != nil {
+		log.Printf("GetDefaultBranch error: %v", err)
+		c.String(http.StatusInternalServerError, "Error getting default branch")
+		return
+	}
+
+	ghEvent, err := bitbucketEventToGithubEvent(eventKey, event, defaultBranch)
+	if err != nil {
+		log.Printf("Failed to convert Bitbucket Event. :%v\n", err)
+		c.String(http.StatusBadRequest, "Failed to convert Bitbucket Event")
+		return
+	}
+	if ghEvent == nil {
+		log.Printf("bitbucket %v event doesn't run any jobs, ignoring", eventKey)
+		c.JSON(http.StatusOK, "ok")
+		return
+	}
+
+	orgId, err := getVCSOrganisationId()
+	if err != nil {
+		log.Printf("getVCSOrganisationId error: %v", err)
+		c.String(http.StatusInternalServerError, "Error getting organisation")
+		return
+	}
+	bitbucketRepo := vcsRepo{
+		VCS:            models.DiggerVCSBitbucket,
+		OrganisationId: orgId,
+		Owner:          event.Repository.Workspace.Slug,
+		Name:           event.repoSlug(),
+		FullName:       event.Repository.FullName,
+		CloneUrl:       event.cloneUrl(),
+		CloneUser:      "x-token-auth",
+		Token:          *token,
+		PrService:      service,
+	}
+
+	switch ghEvent := ghEvent.(type) {
+	case *github.PullRequestEvent:
+		err = handleVCSPullRequestEvent(bitbucketRepo, ghEvent, d.CiBackendProvider)
+	case *github.IssueCommentEvent:
+		err = handleVCSIssueCommentEvent(bitbucketRepo, ghEvent, d.CiBackendProvider)
+	}
+	if err != nil {
+		log.Printf("bitbucket %v event error: %v", eventKey, err)
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, "ok")
+}
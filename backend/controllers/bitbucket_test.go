@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/go-github/v61/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var bitbucketPullRequestPayload = `{
+  "actor": {"nickname": "alice"},
+  "repository": {
+    "name": "Infra Live",
+    "full_name": "acme/infra-live",
+    "workspace": {"slug": "acme"},
+    "links": {"html": {"href": "https://bitbucket.org/acme/infra-live"}}
+  },
+  "pullrequest": {
+    "id": 12,
+    "draft": false,
+    "source": {"branch": {"name": "vpc-eu-west-2"}, "commit": {"hash": "9c8d7e6"}},
+    "destination": {"branch": {"name": "main"}, "commit": {"hash": "1a2b3c4"}}
+  },
+  "comment": {"id": 501, "content": {"raw": "mantis apply"}}
+}`
+
+func signBitbucketPayload(payload []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyBitbucketSignature(t *testing.T) {
+	payload := []byte(bitbucketPullRequestPayload)
+	assert.True(t, verifyBitbucketSignature(payload, signBitbucketPayload(payload, "s3cret"), "s3cret"))
+	assert.False(t, verifyBitbucketSignature(payload, signBitbucketPayload(payload, "other"), "s3cret"))
+	assert.False(t, verifyBitbucketSignature(payload, "sha256=zz", "s3cret"))
+	assert.False(t, verifyBitbucketSignature(payload, signBitbucketPayload(payload, ""), ""))
+}
+
+func TestBitbucketEventToGithubEvent(t *testing.T) {
+	var event bitbucketWebhookEvent
+	require.NoError(t, json.Unmarshal([]byte(bitbucketPullRequestPayload), &event))
+
+	ghEvent, err := bitbucketEventToGithubEvent("pullrequest:fulfilled", event, "main")
+	require.NoError(t, err)
+	prEvent := ghEvent.(*github.PullRequestEvent)
+	assert.Equal(t, "closed", prEvent.GetAction())
+	assert.True(t, prEvent.GetPullRequest().GetMerged())
+	assert.Equal(t, 12, prEvent.GetPullRequest().GetNumber())
+	assert.Equal(t, "vpc-eu-west-2", prEvent.GetPullRequest().GetHead().GetRef())
+	assert.Equal(t, "9c8d7e6", prEvent.GetPullRequest().GetHead().GetSHA())
+	assert.Equal(t, "main", prEvent.GetPullRequest().GetBase().GetRef())
+	assert.Equal(t, "infra-live", prEvent.GetRepo().GetName())
+	assert.Equal(t, "acme", prEvent.GetRepo().GetOwner().GetLogin())
+	assert.Equal(t, "https://bitbucket.org/acme/infra-live.git", prEvent.GetRepo().GetCloneURL())
+	assert.Equal(t, "main", prEvent.GetRepo().GetDefaultBranch())
+
+	ghEvent, err = bitbucketEventToGithubEvent("pullrequest:rejected", event, "main")
+	require.NoError(t, err)
+	assert.False(t, ghEvent.(*github.PullRequestEvent).GetPullRequest().GetMerged())
+
+	ghEvent, err = bitbucketEventToGithubEvent("pullrequest:comment_created", event, "main")
+	require.NoError(t, err)
+	commentEvent := ghEvent.(*github.IssueCommentEvent)
+	assert.Equal(t, 12, commentEvent.GetIssue().GetNumber())
+	assert.Equal(t, int64(501), commentEvent.GetComment().GetID())
+	assert.Equal(t, "mantis apply", commentEvent.GetComment().GetBody())
+	assert.Equal(t, "alice", commentEvent.GetSender().GetLogin())
+
+	ghEvent, err = bitbucketEventToGithubEvent("pullrequest:approved", event, "main")
+	require.NoError(t, err)
+	assert.Nil(t, ghEvent)
+}
+
+func TestBitbucketWebHookRejectsInvalidSignature(t *testing.T) {
+	t.Setenv("BITBUCKET_WEBHOOK_SECRET", "s3cret")
+	r := gin.New()
+	r.POST("/bitbucket-webhook", DiggerController{}.BitbucketWebHook)
+	payload := []byte(bitbucketPullRequestPayload)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/bitbucket-webhook", bytes.NewReader(payload))
+	req.Header.Set("X-Event-Key", "pullrequest:created")
+	req.Header.Set("X-Hub-Signature", signBitbucketPayload(payload, "other"))
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// repository events are acknowledged without calling Bitbucket
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/bitbucket-webhook", bytes.NewReader(payload))
+	req.Header.Set("X-Event-Key", "repo:push")
+	req.Header.Set("X-Hub-Signature", signBitbucketPayload(payload, "s3cret"))
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
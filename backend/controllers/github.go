@@ -16,10 +16,7 @@ import (
 	"strings"
 
 	"github.com/diggerhq/digger/backend/ci_backends"
-	"github.com/diggerhq/digger/backend/locking"
-	"github.com/diggerhq/digger/backend/segment"
 	"github.com/diggerhq/digger/backend/services"
-	comment_updater "github.com/diggerhq/digger/libs/comment_utils/reporting"
 	dg_locking "github.com/diggerhq/digger/libs/locking"
 	orchestrator_scheduler "github.com/diggerhq/digger/libs/orchestrator/scheduler"
 	"github.com/google/uuid"
@@ -108,6 +105,18 @@ func (d DiggerController) GithubAppWebHook(c *gin.Context) {
 			c.String(http.StatusInternalServerError, err.Error())
 			return
 		}
+	case *github.CheckRunEvent:
+		log.Printf("CheckRunEvent, action: %v\n", event.GetAction())
+		if event.GetAction() != "requested_action" {
+			c.String(http.StatusOK, "OK")
+			return
+		}
+		err := handleCheckRunEvent(gh, event, d.CiBackendProvider)
+		if err != nil {
+			log.Printf("handleCheckRunEvent error: %v", err)
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
 	case *github.PullRequestEvent:
 		log.Printf("Got pull request event for %d", *event.PullRequest.ID)
 		err := handlePullRequestEvent(gh, event, d.CiBackendProvider)
@@ -449,193 +458,41 @@ func handlePushEvent(gh utils.GithubClientProvider, payload *github.PushEvent) e
 	return nil
 }
 
-func handlePullRequestEvent(gh utils.GithubClientProvider, payload *github.PullRequestEvent, ciBackendProvider ci_backends.CiBackendProvider) error {
-	installationId := *payload.Installation.ID
-	repoName := *payload.Repo.Name
-	repoOwner := *payload.Repo.Owner.Login
-	repoFullName := *payload.Repo.FullName
-	cloneURL := *payload.Repo.CloneURL
-	prNumber := *payload.PullRequest.Number
-	isDraft := payload.PullRequest.GetDraft()
-	commitSha := payload.PullRequest.Head.GetSHA()
-	branch := payload.PullRequest.Head.GetRef()
-
+// getGithubVCSRepo returns the repository of GitHub app webhooks
+func getGithubVCSRepo(gh utils.GithubClientProvider, installationId int64, repository *github.Repository) (*vcsRepo, error) {
 	link, err := models.DB.GetGithubAppInstallationLink(installationId)
 	if err != nil {
 		log.Printf("Error getting GetGithubAppInstallationLink: %v", err)
-		return fmt.Errorf("error getting github app link")
-	}
-	organisationId := link.OrganisationId
-
-	diggerYmlStr, ghService, config, projectsGraph, _, _, err := getDiggerConfigForPR(gh, installationId, repoFullName, repoOwner, repoName, cloneURL, prNumber)
-	if err != nil {
-		ghService, _, err := utils.GetGithubService(gh, installationId, repoFullName, repoOwner, repoName)
-		if err != nil {
-			log.Printf("GetGithubService error: %v", err)
-			return fmt.Errorf("error getting ghService to post error comment")
-		}
-		utils.InitCommentReporter(ghService, prNumber, fmt.Sprintf(":x: Could not load digger config, error: %v", err))
-		log.Printf("getDiggerConfigForPR error: %v", err)
-		return fmt.Errorf("error getting digger config")
-	}
-
-	impactedProjects, impactedProjectsSourceMapping, _, err := dg_github.ProcessGitHubPullRequestEvent(payload, config, projectsGraph, ghService)
-	if err != nil {
-		log.Printf("Error processing event: %v", err)
-		utils.InitCommentReporter(ghService, prNumber, fmt.Sprintf(":x: Error processing event: %v", err))
-		return fmt.Errorf("error processing event")
-	}
-
-	jobsForImpactedProjects, _, err := dg_github.ConvertGithubPullRequestEventToJobs(payload, impactedProjects, nil, *config)
-	if err != nil {
-		log.Printf("Error converting event to jobsForImpactedProjects: %v", err)
-		utils.InitCommentReporter(ghService, prNumber, fmt.Sprintf(":x: Error converting event to jobsForImpactedProjects: %v", err))
-		return fmt.Errorf("error converting event to jobsForImpactedProjects")
-	}
-
-	if len(jobsForImpactedProjects) == 0 {
-		// do not report if no projects are impacted to minimise noise in the PR thread
-		// TODO use status checks instead: https://github.com/diggerhq/digger/issues/1135
-		log.Printf("No projects impacted; not starting any jobs")
-		// This one is for aggregate reporting
-		err = utils.SetPRStatusForJobs(ghService, prNumber, jobsForImpactedProjects)
-		return nil
-	}
-
-	diggerCommand, err := orchestrator.GetCommandFromJob(jobsForImpactedProjects[0])
-	if err != nil {
-		log.Printf("could not determine digger command from job: %v", jobsForImpactedProjects[0].Commands)
-		utils.InitCommentReporter(ghService, prNumber, fmt.Sprintf(":x: could not determine digger command from job: %v", err))
-		return fmt.Errorf("unkown digger command in comment %v", err)
-	}
-
-	if *diggerCommand == orchestrator.DiggerCommandNoop {
-		log.Printf("job is of type noop, no actions top perform")
-		return nil
-	}
-
-	// perform locking/unlocking in backend
-	if config.PrLocks {
-		for _, project := range impactedProjects {
-			prLock := dg_locking.PullRequestLock{
-				InternalLock: locking.BackendDBLock{
-					OrgId: organisationId,
-				},
-				CIService:        ghService,
-				Reporter:         comment_updater.NoopReporter{},
-				ProjectName:      project.Name,
-				ProjectNamespace: repoFullName,
-				PrNumber:         prNumber,
-			}
-			err = PerformLockingActionFromCommand(prLock, *diggerCommand)
-			if err != nil {
-				utils.InitCommentReporter(ghService, prNumber, fmt.Sprintf(":x: Failed perform lock action on project: %v %v", project.Name, err))
-				return fmt.Errorf("failed to perform lock action on project: %v, %v", project.Name, err)
-			}
-		}
-	}
-
-	// if commands are locking or unlocking we don't need to trigger any jobs
-	if *diggerCommand == orchestrator.DiggerCommandUnlock ||
-		*diggerCommand == orchestrator.DiggerCommandLock {
-		utils.InitCommentReporter(ghService, prNumber, fmt.Sprintf(":white_check_mark: Command %v completed successfully", *diggerCommand))
-		return nil
-	}
-
-	if !config.AllowDraftPRs && isDraft {
-		log.Printf("Draft PRs are disabled, skipping PR: %v", prNumber)
-		return nil
-	}
-
-	commentReporter, err := utils.InitCommentReporter(ghService, prNumber, ":construction_worker: Digger starting...")
-	if err != nil {
-		log.Printf("Error initializing comment reporter: %v", err)
-		return fmt.Errorf("error initializing comment reporter")
-	}
-
-	err = utils.ReportInitialJobsStatus(commentReporter, jobsForImpactedProjects)
-	if err != nil {
-		log.Printf("Failed to comment initial status for jobs: %v", err)
-		utils.InitCommentReporter(ghService, prNumber, fmt.Sprintf(":x: Failed to comment initial status for jobs: %v", err))
-		return fmt.Errorf("failed to comment initial status for jobs")
-	}
-
-	err = utils.SetPRStatusForJobs(ghService, prNumber, jobsForImpactedProjects)
-	if err != nil {
-		log.Printf("error setting status for PR: %v", err)
-		utils.InitCommentReporter(ghService, prNumber, fmt.Sprintf(":x: error setting status for PR: %v", err))
-		fmt.Errorf("error setting status for PR: %v", err)
-	}
-
-	impactedProjectsMap := make(map[string]dg_configuration.Project)
-	for _, p := range impactedProjects {
-		impactedProjectsMap[p.Name] = p
-	}
-
-	impactedJobsMap := make(map[string]orchestrator.Job)
-	for _, j := range jobsForImpactedProjects {
-		impactedJobsMap[j.ProjectName] = j
+		return nil, fmt.Errorf("error getting github app link")
 	}
 
-	batchId, _, err := utils.ConvertJobsToDiggerJobs(*diggerCommand, organisationId, impactedJobsMap, impactedProjectsMap, projectsGraph, installationId, branch, prNumber, repoOwner, repoName, repoFullName, commitSha, commentReporter.CommentId, diggerYmlStr)
+	ghService, token, err := utils.GetGithubService(gh, installationId, repository.GetFullName(), repository.GetOwner().GetLogin(), repository.GetName())
 	if err != nil {
-		log.Printf("ConvertJobsToDiggerJobs error: %v", err)
-		utils.InitCommentReporter(ghService, prNumber, fmt.Sprintf(":x: ConvertJobsToDiggerJobs error: %v", err))
-		return fmt.Errorf("error converting jobs")
-	}
-
-	if config.CommentRenderMode == dg_configuration.CommentRenderModeGroupByModule {
-		sourceDetails, err := comment_updater.PostInitialSourceComments(ghService, prNumber, impactedProjectsSourceMapping)
-		if err != nil {
-			log.Printf("PostInitialSourceComments error: %v", err)
-			utils.InitCommentReporter(ghService, prNumber, fmt.Sprintf(":x: PostInitialSourceComments error: %v", err))
-			return fmt.Errorf("error posting initial comments")
-		}
-		batch, err := models.DB.GetDiggerBatch(batchId)
-		if err != nil {
-			log.Printf("GetDiggerBatch error: %v", err)
-			utils.InitCommentReporter(ghService, prNumber, fmt.Sprintf(":x: PostInitialSourceComments error: %v", err))
-			return fmt.Errorf("error getting digger batch")
-		}
-		batch.SourceDetails, err = json.Marshal(sourceDetails)
-		if err != nil {
-			log.Printf("sourceDetails, json Marshal error: %v", err)
-			utils.InitCommentReporter(ghService, prNumber, fmt.Sprintf(":x: json Marshal error: %v", err))
-			return fmt.Errorf("error marshalling sourceDetails")
-		}
-		err = models.DB.UpdateDiggerBatch(batch)
-		if err != nil {
-			log.Printf("UpdateDiggerBatch error: %v", err)
-			utils.InitCommentReporter(ghService, prNumber, fmt.Sprintf(":x: UpdateDiggerBatch error: %v", err))
-			return fmt.Errorf("error updating digger batch")
-		}
+		log.Printf("GetGithubService error: %v", err)
+		return nil, fmt.Errorf("error getting github service")
 	}
 
-	segment.Track(strconv.Itoa(int(organisationId)), "backend_trigger_job")
-
-	ciBackend, err := ciBackendProvider.GetCiBackend(
-		ci_backends.CiBackendOptions{
-			GithubClientProvider: gh,
-			GithubInstallationId: installationId,
-			RepoName:             repoName,
-			RepoOwner:            repoOwner,
-			RepoFullName:         repoFullName,
-		},
-	)
-	if err != nil {
-		log.Printf("GetCiBackend error: %v", err)
-		utils.InitCommentReporter(ghService, prNumber, fmt.Sprintf(":x: GetCiBackend error: %v", err))
-		return fmt.Errorf("error fetching ci backed %v", err)
-	}
+	return &vcsRepo{
+		VCS:                  models.DiggerVCSGithub,
+		OrganisationId:       link.OrganisationId,
+		Owner:                repository.GetOwner().GetLogin(),
+		Name:                 repository.GetName(),
+		FullName:             repository.GetFullName(),
+		CloneUrl:             repository.GetCloneURL(),
+		CloneUser:            "x-access-token",
+		Token:                *token,
+		PrService:            ghService,
+		GithubClientProvider: gh,
+		GithubInstallationId: installationId,
+	}, nil
+}
 
-	err = TriggerDiggerJobs(ciBackend, repoOwner, repoName, batchId, prNumber, ghService)
+func handlePullRequestEvent(gh utils.GithubClientProvider, payload *github.PullRequestEvent, ciBackendProvider ci_backends.CiBackendProvider) error {
+	repo, err := getGithubVCSRepo(gh, *payload.Installation.ID, payload.Repo)
 	if err != nil {
-		log.Printf("TriggerDiggerJobs error: %v", err)
-		utils.InitCommentReporter(ghService, prNumber, fmt.Sprintf(":x: TriggerDiggerJobs error: %v", err))
-		return fmt.Errorf("error triggerring Digger Jobs")
+		return err
 	}
-
-	return nil
+	return handleVCSPullRequestEvent(*repo, payload, ciBackendProvider)
 }
 
 func getDiggerConfigForBranch(gh utils.GithubClientProvider, installationId int64, repoFullName string, repoOwner string, repoName string, cloneUrl string, branch string, prNumber int) (string, *dg_github.GithubService, *dg_configuration.DiggerConfig, graph.Graph[string, dg_configuration.Project], error) {
@@ -673,31 +530,6 @@ func getDiggerConfigForBranch(gh utils.GithubClientProvider, installationId int6
 	return diggerYmlStr, ghService, config, dependencyGraph, nil
 }
 
-// TODO: Refactor this func to receive ghService as input
-func getDiggerConfigForPR(gh utils.GithubClientProvider, installationId int64, repoFullName string, repoOwner string, repoName string, cloneUrl string, prNumber int) (string, *dg_github.GithubService, *dg_configuration.DiggerConfig, graph.Graph[string, dg_configuration.Project], *string, *string, error) {
-	ghService, _, err := utils.GetGithubService(gh, installationId, repoFullName, repoOwner, repoName)
-	if err != nil {
-		log.Printf("Error getting github service: %v", err)
-		return "", nil, nil, nil, nil, nil, fmt.Errorf("error getting github service")
-	}
-
-	var prBranch string
-	prBranch, prCommitSha, err := ghService.GetBranchName(prNumber)
-	if err != nil {
-		log.Printf("Error getting branch name: %v", err)
-		return "", nil, nil, nil, nil, nil, fmt.Errorf("error getting branch name")
-	}
-
-	diggerYmlStr, ghService, config, dependencyGraph, err := getDiggerConfigForBranch(gh, installationId, repoFullName, repoOwner, repoName, cloneUrl, prBranch, prNumber)
-	if err != nil {
-		log.Printf("Error loading mantis.yml: %v", err)
-		return "", nil, nil, nil, nil, nil, fmt.Errorf("error loading mantis.yml")
-	}
-
-	log.Printf("Digger config loadded successfully\n")
-	return diggerYmlStr, ghService, config, dependencyGraph, &prBranch, &prCommitSha, nil
-}
-
 func GetRepoByInstllationId(installationId int64, repoOwner string, repoName string) (*models.Repo, error) {
 	link, err := models.DB.GetGithubAppInstallationLink(installationId)
 	if err != nil {
@@ -726,210 +558,63 @@ func getBatchType(jobs []orchestrator.Job) orchestrator_scheduler.DiggerBatchTyp
 	}
 }
 
-func handleIssueCommentEvent(gh utils.GithubClientProvider, payload *github.IssueCommentEvent, ciBackendProvider ci_backends.CiBackendProvider) error {
-	installationId := *payload.Installation.ID
-	repoName := *payload.Repo.Name
-	repoOwner := *payload.Repo.Owner.Login
-	repoFullName := *payload.Repo.FullName
-	cloneURL := *payload.Repo.CloneURL
-	issueNumber := *payload.Issue.Number
-	isDraft := payload.Issue.GetDraft()
-	commentId := *payload.GetComment().ID
-
-	link, err := models.DB.GetGithubAppInstallationLink(installationId)
-	if err != nil {
-		log.Printf("Error getting GetGithubAppInstallationLink: %v", err)
-		return fmt.Errorf("error getting github app link")
-	}
-	orgId := link.OrganisationId
-
-	if *payload.Action != "created" {
-		log.Printf("comment is not of type 'created', ignoring")
-		return nil
-	}
-
-	if !strings.HasPrefix(*payload.Comment.Body, "mantis") {
-		log.Printf("comment is not a mantis command, ignoring")
-		return nil
-	}
-
-	diggerYmlStr, ghService, config, projectsGraph, branch, commitSha, err := getDiggerConfigForPR(gh, installationId, repoFullName, repoOwner, repoName, cloneURL, issueNumber)
-	if err != nil {
-		ghService, _, err := utils.GetGithubService(gh, installationId, repoFullName, repoOwner, repoName)
-		if err != nil {
-			log.Printf("GetGithubService error: %v", err)
-			return fmt.Errorf("error getting ghService to post error comment")
-		}
-		utils.InitCommentReporter(ghService, issueNumber, fmt.Sprintf(":x: Could not load digger config, error: %v", err))
-		log.Printf("getDiggerConfigForPR error: %v", err)
-		return fmt.Errorf("error getting mantis config")
-	}
-
-	err = ghService.CreateCommentReaction(commentId, string(dg_github.GithubCommentEyesReaction))
-	if err != nil {
-		log.Printf("CreateCommentReaction error: %v", err)
-	}
-
-	if !config.AllowDraftPRs && isDraft {
-		log.Printf("AllowDraftPRs is disabled, skipping PR: %v", issueNumber)
-		return nil
-	}
-
-	commentReporter, err := utils.InitCommentReporter(ghService, issueNumber, ":construction_worker: Digger starting....")
+func handleCheckRunEvent(gh utils.GithubClientProvider, payload *github.CheckRunEvent, ciBackendProvider ci_backends.CiBackendProvider) error {
+	repo, err := getGithubVCSRepo(gh, *payload.Installation.ID, payload.Repo)
 	if err != nil {
-		log.Printf("Error initializing comment reporter: %v", err)
-		return fmt.Errorf("error initializing comment reporter")
-	}
-
-	diggerCommand, err := orchestrator.GetCommandFromComment(*payload.Comment.Body)
-	if err != nil {
-		log.Printf("unkown mantis command in comment: %v", *payload.Comment.Body)
-		utils.InitCommentReporter(ghService, issueNumber, fmt.Sprintf(":x: Could not recognise comment, error: %v", err))
-		return fmt.Errorf("unkown mantis command in comment %v", err)
-	}
-
-	prBranchName, _, err := ghService.GetBranchName(issueNumber)
-	if err != nil {
-		log.Printf("GetBranchName error: %v", err)
-		utils.InitCommentReporter(ghService, issueNumber, fmt.Sprintf(":x: GetBranchName error: %v", err))
-		return fmt.Errorf("error while fetching branch name")
-	}
-
-	impactedProjects, impactedProjectsSourceMapping, requestedProject, _, err := dg_github.ProcessGitHubIssueCommentEvent(payload, config, projectsGraph, ghService)
-	if err != nil {
-		log.Printf("Error processing event: %v", err)
-		utils.InitCommentReporter(ghService, issueNumber, fmt.Sprintf(":x: Error processing event: %v", err))
-		return fmt.Errorf("error processing event")
-	}
-	log.Printf("GitHub IssueComment event processed successfully\n")
-
-	// perform unlocking in backend
-	if config.PrLocks {
-		for _, project := range impactedProjects {
-			prLock := dg_locking.PullRequestLock{
-				InternalLock: locking.BackendDBLock{
-					OrgId: orgId,
-				},
-				CIService:        ghService,
-				Reporter:         comment_updater.NoopReporter{},
-				ProjectName:      project.Name,
-				ProjectNamespace: repoFullName,
-				PrNumber:         issueNumber,
-			}
-			err = PerformLockingActionFromCommand(prLock, *diggerCommand)
-			if err != nil {
-				utils.InitCommentReporter(ghService, issueNumber, fmt.Sprintf(":x: Failed perform lock action on project: %v %v", project.Name, err))
-				return fmt.Errorf("failed perform lock action on project: %v %v", project.Name, err)
-			}
-		}
-	}
-
-	// if commands are locking or unlocking we don't need to trigger any jobs
-	if *diggerCommand == orchestrator.DiggerCommandUnlock ||
-		*diggerCommand == orchestrator.DiggerCommandLock {
-		utils.InitCommentReporter(ghService, issueNumber, fmt.Sprintf(":white_check_mark: Command %v completed successfully", *diggerCommand))
-		return nil
+		return err
 	}
-
-	jobs, _, err := dg_github.ConvertGithubIssueCommentEventToJobs(payload, impactedProjects, requestedProject, config.Workflows, prBranchName)
+	commentEvent, err := checkRunActionToIssueComment(payload, repo.PrService)
 	if err != nil {
-		log.Printf("Error converting event to jobs: %v", err)
-		utils.InitCommentReporter(ghService, issueNumber, fmt.Sprintf(":x: Error converting event to jobs: %v", err))
-		return fmt.Errorf("error converting event to jobs")
+		return err
 	}
-	log.Printf("GitHub IssueComment event converted to Jobs successfully\n")
+	return handleVCSIssueCommentEvent(*repo, commentEvent, ciBackendProvider)
+}
 
-	err = utils.ReportInitialJobsStatus(commentReporter, jobs)
+// checkRunActionToIssueComment turns a click on a check run button into the equivalent mantis comment for its project
+func checkRunActionToIssueComment(event *github.CheckRunEvent, prService orchestrator.PullRequestService) (*github.IssueCommentEvent, error) {
+	prNumber, projectName, err := dg_github.ParseCheckRunExternalId(event.GetCheckRun().GetExternalID())
 	if err != nil {
-		log.Printf("Failed to comment initial status for jobs: %v", err)
-		utils.InitCommentReporter(ghService, issueNumber, fmt.Sprintf(":x: Failed to comment initial status for jobs: %v", err))
-		return fmt.Errorf("failed to comment initial status for jobs")
+		return nil, err
 	}
+	var command string
+	switch event.GetRequestedAction().Identifier {
+	case dg_github.CheckRunActionPlan:
+		command = "mantis plan"
+	case dg_github.CheckRunActionApply:
+		command = "mantis apply"
+	default:
+		return nil, fmt.Errorf("unknown check run action: %v", event.GetRequestedAction().Identifier)
+	}
+	// check run events don't tell whether the pull request is a draft, the draft check needs it
+	prDetails, err := prService.GetPullRequestDetails(prNumber)
+	if err != nil {
+		return nil, fmt.Errorf("could not get pull request %v: %v", prNumber, err)
+	}
+	return &github.IssueCommentEvent{
+		Action:       github.String("created"),
+		Installation: event.Installation,
+		Repo:         event.Repo,
+		Sender:       event.Sender,
+		Issue:        &github.Issue{Number: github.Int(prNumber), Draft: github.Bool(prDetails.Draft)},
+		Comment: &github.IssueComment{
+			ID:   github.Int64(0),
+			Body: github.String(fmt.Sprintf("%v -p %v", command, projectName)),
+			User: event.Sender,
+		},
+	}, nil
+}
 
-	if len(jobs) == 0 {
-		log.Printf("no projects impacated, succeeding")
-		// This one is for aggregate reporting
-		err = utils.SetPRStatusForJobs(ghService, issueNumber, jobs)
+func handleIssueCommentEvent(gh utils.GithubClientProvider, payload *github.IssueCommentEvent, ciBackendProvider ci_backends.CiBackendProvider) error {
+	if *payload.Action != "created" {
+		log.Printf("comment is not of type 'created', ignoring")
 		return nil
 	}
 
-	err = utils.SetPRStatusForJobs(ghService, issueNumber, jobs)
-	if err != nil {
-		log.Printf("error setting status for PR: %v", err)
-		utils.InitCommentReporter(ghService, issueNumber, fmt.Sprintf(":x: error setting status for PR: %v", err))
-		fmt.Errorf("error setting status for PR: %v", err)
-	}
-
-	impactedProjectsMap := make(map[string]dg_configuration.Project)
-	for _, p := range impactedProjects {
-		impactedProjectsMap[p.Name] = p
-	}
-
-	impactedProjectsJobMap := make(map[string]orchestrator.Job)
-	for _, j := range jobs {
-		impactedProjectsJobMap[j.ProjectName] = j
-	}
-
-	batchId, _, err := utils.ConvertJobsToDiggerJobs(*diggerCommand, orgId, impactedProjectsJobMap, impactedProjectsMap, projectsGraph, installationId, *branch, issueNumber, repoOwner, repoName, repoFullName, *commitSha, commentReporter.CommentId, diggerYmlStr)
-	if err != nil {
-		log.Printf("ConvertJobsToDiggerJobs error: %v", err)
-		utils.InitCommentReporter(ghService, issueNumber, fmt.Sprintf(":x: ConvertJobsToDiggerJobs error: %v", err))
-		return fmt.Errorf("error convertingjobs")
-	}
-
-	if config.CommentRenderMode == dg_configuration.CommentRenderModeGroupByModule &&
-		(*diggerCommand == orchestrator.DiggerCommandPlan || *diggerCommand == orchestrator.DiggerCommandApply) {
-
-		sourceDetails, err := comment_updater.PostInitialSourceComments(ghService, issueNumber, impactedProjectsSourceMapping)
-		if err != nil {
-			log.Printf("PostInitialSourceComments error: %v", err)
-			utils.InitCommentReporter(ghService, issueNumber, fmt.Sprintf(":x: PostInitialSourceComments error: %v", err))
-			return fmt.Errorf("error posting initial comments")
-		}
-		batch, err := models.DB.GetDiggerBatch(batchId)
-		if err != nil {
-			log.Printf("GetDiggerBatch error: %v", err)
-			utils.InitCommentReporter(ghService, issueNumber, fmt.Sprintf(":x: PostInitialSourceComments error: %v", err))
-			return fmt.Errorf("error getting digger batch")
-		}
-
-		batch.SourceDetails, err = json.Marshal(sourceDetails)
-		if err != nil {
-			log.Printf("sourceDetails, json Marshal error: %v", err)
-			utils.InitCommentReporter(ghService, issueNumber, fmt.Sprintf(":x: json Marshal error: %v", err))
-			return fmt.Errorf("error marshalling sourceDetails")
-		}
-		err = models.DB.UpdateDiggerBatch(batch)
-		if err != nil {
-			log.Printf("UpdateDiggerBatch error: %v", err)
-			utils.InitCommentReporter(ghService, issueNumber, fmt.Sprintf(":x: UpdateDiggerBatch error: %v", err))
-			return fmt.Errorf("error updating digger batch")
-		}
-	}
-
-	segment.Track(strconv.Itoa(int(orgId)), "backend_trigger_job")
-
-	ciBackend, err := ciBackendProvider.GetCiBackend(
-		ci_backends.CiBackendOptions{
-			GithubClientProvider: gh,
-			GithubInstallationId: installationId,
-			RepoName:             repoName,
-			RepoOwner:            repoOwner,
-			RepoFullName:         repoFullName,
-		},
-	)
+	repo, err := getGithubVCSRepo(gh, *payload.Installation.ID, payload.Repo)
 	if err != nil {
-		log.Printf("GetCiBackend error: %v", err)
-		utils.InitCommentReporter(ghService, issueNumber, fmt.Sprintf(":x: GetCiBackend error: %v", err))
-		return fmt.Errorf("error fetching ci backed %v", err)
-	}
-	err = TriggerDiggerJobs(ciBackend, repoOwner, repoName, batchId, issueNumber, ghService)
-	if err != nil {
-		log.Printf("TriggerDiggerJobs error: %v", err)
-		utils.InitCommentReporter(ghService, issueNumber, fmt.Sprintf(":x: TriggerDiggerJobs error: %v", err))
-		return fmt.Errorf("error triggerring Digger Jobs")
+		return err
 	}
-	return nil
+	return handleVCSIssueCommentEvent(*repo, payload, ciBackendProvider)
 }
 
 func PerformLockingActionFromCommand(prLock dg_locking.PullRequestLock, command orchestrator.DiggerCommand) error {
@@ -950,6 +635,11 @@ func PerformLockingActionFromCommand(prLock dg_locking.PullRequestLock, command
 		if err != nil {
 			err = fmt.Errorf("failed to lock project: %v", err)
 		}
+	case orchestrator.DiggerCommandDestroy:
+		_, err = prLock.Lock()
+		if err != nil {
+			err = fmt.Errorf("failed to lock project: %v", err)
+		}
 	case orchestrator.DiggerCommandLock:
 		_, err = prLock.Lock()
 		if err != nil {
@@ -959,7 +649,7 @@ func PerformLockingActionFromCommand(prLock dg_locking.PullRequestLock, command
 	return err
 }
 
-func TriggerDiggerJobs(ciBackend ci_backends.CiBackend, repoOwner string, repoName string, batchId *uuid.UUID, prNumber int, prService *dg_github.GithubService) error {
+func TriggerDiggerJobs(ciBackend ci_backends.CiBackend, repoOwner string, repoName string, batchId *uuid.UUID, prNumber int, prService orchestrator.PullRequestService) error {
 	_, err := models.DB.GetDiggerBatch(batchId)
 	if err != nil {
 		log.Printf("failed to get digger batch, %v\n", err)
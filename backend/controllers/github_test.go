@@ -11,6 +11,7 @@ import (
 	"github.com/diggerhq/digger/backend/utils"
 	configuration "github.com/diggerhq/digger/libs/digger_config"
 	orchestrator "github.com/diggerhq/digger/libs/orchestrator"
+	dg_github "github.com/diggerhq/digger/libs/orchestrator/github"
 	"github.com/google/go-github/v61/github"
 	"github.com/migueleliasweb/go-github-mock/src/mock"
 	"github.com/stretchr/testify/assert"
@@ -590,7 +591,8 @@ func setupSuite(tb testing.TB) (func(tb testing.TB), *models.Database) {
 	// migrate tables
 	err = gdb.AutoMigrate(&models.Policy{}, &models.Organisation{}, &models.Repo{}, &models.Project{}, &models.Token{},
 		&models.User{}, &models.ProjectRun{}, &models.GithubAppInstallation{}, &models.GithubApp{}, &models.GithubAppInstallationLink{},
-		&models.GithubDiggerJobLink{}, &models.DiggerJob{}, &models.DiggerJobParentLink{}, &models.JobToken{})
+		&models.GithubDiggerJobLink{}, &models.DiggerJob{}, &models.DiggerJobParentLink{}, &models.JobToken{}, &models.StoredPlan{},
+		&models.PolicyRevision{}, &models.PolicyDecision{})
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -713,6 +715,35 @@ func TestGithubHandleIssueCommentEvent(t *testing.T) {
 	assert.Equal(t, 0, len(jobs))
 }
 
+type draftPullRequestService struct {
+	dg_github.MockCiService
+}
+
+func (s draftPullRequestService) GetPullRequestDetails(prNumber int) (*orchestrator.PullRequestDetails, error) {
+	return &orchestrator.PullRequestDetails{Draft: true}, nil
+}
+
+func TestCheckRunActionToIssueComment(t *testing.T) {
+	sender := &github.User{Login: github.String("alice")}
+	event := &github.CheckRunEvent{
+		Action:          github.String("requested_action"),
+		CheckRun:        &github.CheckRun{ExternalID: github.String("mantis:7:dev")},
+		RequestedAction: &github.RequestedAction{Identifier: "apply"},
+		Sender:          sender,
+	}
+	comment, err := checkRunActionToIssueComment(event, draftPullRequestService{})
+	assert.NoError(t, err)
+	assert.Equal(t, "mantis apply -p dev", comment.GetComment().GetBody())
+	assert.Equal(t, 7, comment.GetIssue().GetNumber())
+	assert.True(t, comment.GetIssue().GetDraft())
+	assert.Equal(t, "created", comment.GetAction())
+	assert.Equal(t, sender, comment.GetComment().GetUser())
+
+	event.CheckRun.ExternalID = github.String("other-app")
+	_, err = checkRunActionToIssueComment(event, draftPullRequestService{})
+	assert.Error(t, err)
+}
+
 func TestJobsTreeWithOneJobsAndTwoProjects(t *testing.T) {
 	teardownSuite, _ := setupSuite(t)
 	defer teardownSuite(t)
@@ -731,7 +762,7 @@ func TestJobsTreeWithOneJobsAndTwoProjects(t *testing.T) {
 	graph, err := configuration.CreateProjectDependencyGraph(projects)
 	assert.NoError(t, err)
 
-	_, result, err := utils.ConvertJobsToDiggerJobs("", 1, jobs, projectMap, graph, 41584295, "", 2, "diggerhq", "parallel_jobs_demo", "diggerhq/parallel_jobs_demo", "", 123, "test")
+	_, result, err := utils.ConvertJobsToDiggerJobs(models.DiggerVCSGithub, "", 1, jobs, projectMap, graph, 41584295, "", 2, "diggerhq", "parallel_jobs_demo", "diggerhq/parallel_jobs_demo", "", 123, "test")
 	assert.NoError(t, err)
 	assert.Equal(t, 1, len(result))
 	parentLinks, err := models.DB.GetDiggerJobParentLinksChildId(&result["dev"].DiggerJobID)
@@ -760,7 +791,7 @@ func TestJobsTreeWithTwoDependantJobs(t *testing.T) {
 	projectMap["dev"] = project1
 	projectMap["prod"] = project2
 
-	_, result, err := utils.ConvertJobsToDiggerJobs("", 1, jobs, projectMap, graph, 123, "", 2, "", "", "test", "", 123, "test")
+	_, result, err := utils.ConvertJobsToDiggerJobs(models.DiggerVCSGithub, "", 1, jobs, projectMap, graph, 123, "", 2, "", "", "test", "", 123, "test")
 	assert.NoError(t, err)
 	assert.Equal(t, 2, len(result))
 
@@ -793,7 +824,7 @@ func TestJobsTreeWithTwoIndependentJobs(t *testing.T) {
 	projectMap["dev"] = project1
 	projectMap["prod"] = project2
 
-	_, result, err := utils.ConvertJobsToDiggerJobs("", 1, jobs, projectMap, graph, 123, "", 2, "", "", "test", "", 123, "test")
+	_, result, err := utils.ConvertJobsToDiggerJobs(models.DiggerVCSGithub, "", 1, jobs, projectMap, graph, 123, "", 2, "", "", "test", "", 123, "test")
 	assert.NoError(t, err)
 	assert.Equal(t, 2, len(result))
 	parentLinks, err := models.DB.GetDiggerJobParentLinksChildId(&result["dev"].DiggerJobID)
@@ -838,7 +869,7 @@ func TestJobsTreeWithThreeLevels(t *testing.T) {
 	projectMap["555"] = project5
 	projectMap["666"] = project6
 
-	_, result, err := utils.ConvertJobsToDiggerJobs("", 1, jobs, projectMap, graph, 123, "", 2, "", "", "test", "", 123, "test")
+	_, result, err := utils.ConvertJobsToDiggerJobs(models.DiggerVCSGithub, "", 1, jobs, projectMap, graph, 123, "", 2, "", "", "test", "", 123, "test")
 	assert.NoError(t, err)
 	assert.Equal(t, 6, len(result))
 	parentLinks, err := models.DB.GetDiggerJobParentLinksChildId(&result["111"].DiggerJobID)
@@ -0,0 +1,220 @@
+package controllers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/diggerhq/digger/backend/models"
+	"github.com/diggerhq/digger/backend/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/go-github/v61/github"
+)
+
+type gitlabProject struct {
+	PathWithNamespace string `json:"path_with_namespace"`
+	DefaultBranch     string `json:"default_branch"`
+	GitHttpUrl        string `json:"git_http_url"`
+}
+
+type gitlabMergeRequest struct {
+	IId            int    `json:"iid"`
+	SourceBranch   string `json:"source_branch"`
+	TargetBranch   string `json:"target_branch"`
+	Draft          bool   `json:"draft"`
+	WorkInProgress bool   `json:"work_in_progress"`
+	LastCommit     struct {
+		Id string `json:"id"`
+	} `json:"last_commit"`
+}
+
+// gitlabWebhookEvent is the payload of merge request and note hooks
+type gitlabWebhookEvent struct {
+	ObjectKind string `json:"object_kind"`
+	User       struct {
+		Username string `json:"username"`
+	} `json:"user"`
+	Project          gitlabProject `json:"project"`
+	ObjectAttributes struct {
+		gitlabMergeRequest
+		Action string `json:"action"`
+		// set on updates that pushed commits
+		OldRev string `json:"oldrev"`
+		// attributes of notes
+		Id           int64  `json:"id"`
+		Note         string `json:"note"`
+		NoteableType string `json:"noteable_type"`
+	} `json:"object_attributes"`
+	MergeRequest *gitlabMergeRequest `json:"merge_request"`
+}
+
+func verifyGitlabToken(token string, secret string) bool {
+	if secret == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(secret)) == 1
+}
+
+func gitlabRepository(project gitlabProject) *github.Repository {
+	// the namespace of GitLab projects can be a group with subgroups, the name may differ from the path
+	owner, name := "", project.PathWithNamespace
+	if i := strings.LastIndex(project.PathWithNamespace, "/"); i >= 0 {
+		owner, name = project.PathWithNamespace[:i], project.PathWithNamespace[i+1:]
+	}
+	return &github.Repository{
+		Name:          github.String(name),
+		FullName:      github.String(project.PathWithNamespace),
+		DefaultBranch: github.String(project.DefaultBranch),
+		CloneURL:      github.String(project.GitHttpUrl),
+		Owner:         &github.User{Login: github.String(owner)},
+	}
+}
+
+// gitlabEventToGithubEvent converts merge request and note hooks to the GitHub events the orchestrator works with,
+// it returns nil for events that don't run any jobs
+func gitlabEventToGithubEvent(event gitlabWebhookEvent) (interface{}, error) {
+	repo := gitlabRepository(event.Project)
+	sender := &github.User{Login: github.String(event.User.Username)}
+
+	switch event.ObjectKind {
+	case "merge_request":
+		attributes := event.ObjectAttributes
+		merged := false
+		var action string
+		switch attributes.Action {
+		case "open":
+			action = "opened"
+		case "reopen":
+			action = "reopened"
+		case "update":
+			// updates of the title or description don't need a new plan
+			if attributes.OldRev == "" {
+				return nil, nil
+			}
+			action = "synchronize"
+		case "close":
+			action = "closed"
+		case "merge":
+			action = "closed"
+			merged = true
+		default:
+			return nil, nil
+		}
+		return &github.PullRequestEvent{
+			Action: github.String(action),
+			Number: github.Int(attributes.IId),
+			PullRequest: &github.PullRequest{
+				Number: github.Int(attributes.IId),
+				Merged: github.Bool(merged),
+				Draft:  github.Bool(attributes.Draft || attributes.WorkInProgress),
+				Head:   &github.PullRequestBranch{Ref: github.String(attributes.SourceBranch), SHA: github.String(attributes.LastCommit.Id)},
+				Base:   &github.PullRequestBranch{Ref: github.String(attributes.TargetBranch)},
+			},
+			Repo:   repo,
+			Sender: sender,
+		}, nil
+	case "note":
+		attributes := event.ObjectAttributes
+		if attributes.NoteableType != "MergeRequest" || event.MergeRequest == nil {
+			return nil, nil
+		}
+		if attributes.Action != "" && attributes.Action != "create" {
+			return nil, nil
+		}
+		return &github.IssueCommentEvent{
+			Action: github.String("created"),
+			Issue: &github.Issue{
+				Number: github.Int(event.MergeRequest.IId),
+				Draft:  github.Bool(event.MergeRequest.Draft || event.MergeRequest.WorkInProgress),
+			},
+			Comment: &github.IssueComment{ID: github.Int64(attributes.Id), Body: github.String(attributes.Note)},
+			Repo:    repo,
+			Sender:  sender,
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported gitlab event %v", event.ObjectKind)
+}
+
+// GitlabWebHook handles merge request and note hooks authenticated with the GITLAB_WEBHOOK_SECRET token
+// for repositories of the default organisation, see getVCSOrganisationId
+func (d DiggerController) GitlabWebHook(c *gin.Context) {
+	c.Header("Content-Type", "application/json")
+	log.Printf("GitlabWebHook")
+
+	if !verifyGitlabToken(c.GetHeader("X-Gitlab-Token"), os.Getenv("GITLAB_WEBHOOK_SECRET")) {
+		log.Printf("Error validating gitlab webhook's token")
+		c.String(http.StatusUnauthorized, "Error validating gitlab webhook's token")
+		return
+	}
+
+	payload, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		log.Printf("Error reading gitlab webhook's payload: %v", err)
+		c.String(http.StatusBadRequest, "Error reading gitlab webhook's payload")
+		return
+	}
+
+	var event gitlabWebhookEvent
+	err = json.Unmarshal(payload, &event)
+	if err != nil {
+		log.Printf("Failed to parse Gitlab Event. :%v\n", err)
+		c.String(http.StatusBadRequest, "Failed to parse Gitlab Event")
+		return
+	}
+
+	ghEvent, err := gitlabEventToGithubEvent(event)
+	if err != nil {
+		log.Printf("Unhandled event: %v", err)
+		c.JSON(http.StatusOK, "ok")
+		return
+	}
+	if ghEvent == nil {
+		log.Printf("gitlab %v event doesn't run any jobs, ignoring", event.ObjectKind)
+		c.JSON(http.StatusOK, "ok")
+		return
+	}
+
+	service, token, err := utils.GetGitlabService(event.Project.PathWithNamespace)
+	if err != nil {
+		log.Printf("GetGitlabService error: %v", err)
+		c.String(http.StatusInternalServerError, "Error getting gitlab service")
+		return
+	}
+	repo := gitlabRepository(event.Project)
+	orgId, err := getVCSOrganisationId()
+	if err != nil {
+		log.Printf("getVCSOrganisationId error: %v", err)
+		c.String(http.StatusInternalServerError, "Error getting organisation")
+		return
+	}
+	gitlabRepo := vcsRepo{
+		VCS:            models.DiggerVCSGitlab,
+		OrganisationId: orgId,
+		Owner:          repo.GetOwner().GetLogin(),
+		Name:           repo.GetName(),
+		FullName:       repo.GetFullName(),
+		CloneUrl:       repo.GetCloneURL(),
+		CloneUser:      "oauth2",
+		Token:          *token,
+		PrService:      service,
+	}
+
+	switch ghEvent := ghEvent.(type) {
+	case *github.PullRequestEvent:
+		err = handleVCSPullRequestEvent(gitlabRepo, ghEvent, d.CiBackendProvider)
+	case *github.IssueCommentEvent:
+		err = handleVCSIssueCommentEvent(gitlabRepo, ghEvent, d.CiBackendProvider)
+	}
+	if err != nil {
+		log.Printf("gitlab %v event error: %v", event.ObjectKind, err)
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, "ok")
+}
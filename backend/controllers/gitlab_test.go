@@ -0,0 +1,134 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/go-github/v61/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var gitlabMergeRequestPayload = `{
+  "object_kind": "merge_request",
+  "user": {"username": "alice"},
+  "project": {
+    "name": "Infra Live",
+    "path_with_namespace": "platform/aws/infra-live",
+    "default_branch": "main",
+    "git_http_url": "https://gitlab.example.com/platform/aws/infra-live.git"
+  },
+  "object_attributes": {
+    "iid": 7,
+    "action": "update",
+    "oldrev": "1a2b3c",
+    "source_branch": "vpc-eu-west-2",
+    "target_branch": "main",
+    "draft": false,
+    "last_commit": {"id": "4d5e6f"}
+  }
+}`
+
+var gitlabNotePayload = `{
+  "object_kind": "note",
+  "user": {"username": "bob"},
+  "project": {
+    "path_with_namespace": "platform/aws/infra-live",
+    "default_branch": "main",
+    "git_http_url": "https://gitlab.example.com/platform/aws/infra-live.git"
+  },
+  "object_attributes": {
+    "id": 1244,
+    "note": "mantis plan -p prod-vpc",
+    "noteable_type": "MergeRequest",
+    "action": "create"
+  },
+  "merge_request": {"iid": 7, "source_branch": "vpc-eu-west-2", "work_in_progress": true}
+}`
+
+func parseGitlabEvent(t *testing.T, payload string, modify func(event *gitlabWebhookEvent)) interface{} {
+	var event gitlabWebhookEvent
+	require.NoError(t, json.Unmarshal([]byte(payload), &event))
+	if modify != nil {
+		modify(&event)
+	}
+	ghEvent, err := gitlabEventToGithubEvent(event)
+	require.NoError(t, err)
+	return ghEvent
+}
+
+func TestVerifyGitlabToken(t *testing.T) {
+	assert.True(t, verifyGitlabToken("s3cret", "s3cret"))
+	assert.False(t, verifyGitlabToken("wrong", "s3cret"))
+	// webhooks are rejected until a secret is configured
+	assert.False(t, verifyGitlabToken("", ""))
+}
+
+func TestGitlabMergeRequestToPullRequestEvent(t *testing.T) {
+	event := parseGitlabEvent(t, gitlabMergeRequestPayload, nil).(*github.PullRequestEvent)
+	assert.Equal(t, "synchronize", event.GetAction())
+	assert.Equal(t, 7, event.GetPullRequest().GetNumber())
+	assert.Equal(t, "vpc-eu-west-2", event.GetPullRequest().GetHead().GetRef())
+	assert.Equal(t, "4d5e6f", event.GetPullRequest().GetHead().GetSHA())
+	assert.Equal(t, "main", event.GetPullRequest().GetBase().GetRef())
+	assert.Equal(t, "platform/aws", event.GetRepo().GetOwner().GetLogin())
+	assert.Equal(t, "infra-live", event.GetRepo().GetName())
+	assert.Equal(t, "platform/aws/infra-live", event.GetRepo().GetFullName())
+	assert.Equal(t, "main", event.GetRepo().GetDefaultBranch())
+	assert.Equal(t, "alice", event.GetSender().GetLogin())
+
+	event = parseGitlabEvent(t, gitlabMergeRequestPayload, func(event *gitlabWebhookEvent) {
+		event.ObjectAttributes.Action = "merge"
+	}).(*github.PullRequestEvent)
+	assert.Equal(t, "closed", event.GetAction())
+	assert.True(t, event.GetPullRequest().GetMerged())
+
+	// title and description updates don't push commits
+	assert.Nil(t, parseGitlabEvent(t, gitlabMergeRequestPayload, func(event *gitlabWebhookEvent) {
+		event.ObjectAttributes.OldRev = ""
+	}))
+	assert.Nil(t, parseGitlabEvent(t, gitlabMergeRequestPayload, func(event *gitlabWebhookEvent) {
+		event.ObjectAttributes.Action = "approved"
+	}))
+}
+
+func TestGitlabNoteToIssueCommentEvent(t *testing.T) {
+	event := parseGitlabEvent(t, gitlabNotePayload, nil).(*github.IssueCommentEvent)
+	assert.Equal(t, "created", event.GetAction())
+	assert.Equal(t, 7, event.GetIssue().GetNumber())
+	assert.True(t, event.GetIssue().GetDraft())
+	assert.Equal(t, int64(1244), event.GetComment().GetID())
+	assert.Equal(t, "mantis plan -p prod-vpc", event.GetComment().GetBody())
+	assert.Equal(t, "bob", event.GetSender().GetLogin())
+
+	assert.Nil(t, parseGitlabEvent(t, gitlabNotePayload, func(event *gitlabWebhookEvent) {
+		event.ObjectAttributes.NoteableType = "Issue"
+		event.MergeRequest = nil
+	}))
+	assert.Nil(t, parseGitlabEvent(t, gitlabNotePayload, func(event *gitlabWebhookEvent) {
+		event.ObjectAttributes.Action = "update"
+	}))
+}
+
+func TestGitlabWebHookRejectsInvalidToken(t *testing.T) {
+	t.Setenv("GITLAB_WEBHOOK_SECRET", "s3cret")
+	r := gin.New()
+	r.POST("/gitlab-webhook", DiggerController{}.GitlabWebHook)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/gitlab-webhook", bytes.NewReader([]byte(gitlabMergeRequestPayload)))
+	req.Header.Set("X-Gitlab-Token", "wrong")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// events that don't run jobs are acknowledged without calling GitLab
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/gitlab-webhook", bytes.NewReader([]byte(`{"object_kind": "merge_request", "object_attributes": {"action": "approved"}}`)))
+	req.Header.Set("X-Gitlab-Token", "s3cret")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
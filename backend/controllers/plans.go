@@ -0,0 +1,197 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/diggerhq/digger/backend/middleware"
+	"github.com/diggerhq/digger/backend/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// plans larger than this are rejected on upload
+const maxStoredPlanSize = 256 << 20
+
+type StoredPlanInfo struct {
+	Name         string    `json:"name"`
+	Size         int       `json:"size"`
+	LastModified time.Time `json:"last_modified"`
+}
+
+// planStorageDir is where plan contents are kept when PLAN_STORAGE_DIR is set, otherwise they are stored in the database
+func planStorageDir() string {
+	return os.Getenv("PLAN_STORAGE_DIR")
+}
+
+func storedPlanDiskPath(orgId uint, repo string, name string) string {
+	// escaping keeps every repo and plan name a single path element
+	return filepath.Join(planStorageDir(), strconv.FormatUint(uint64(orgId), 10), url.PathEscape(repo), url.PathEscape(name))
+}
+
+func storedPlanParams(c *gin.Context) (uint, string, string, bool) {
+	orgId, exists := c.Get(middleware.ORGANISATION_ID_KEY)
+	if !exists {
+		log.Printf("Organisation ID not found in context")
+		c.String(http.StatusForbidden, "Not allowed to access this resource")
+		return 0, "", "", false
+	}
+	repo := c.Param("repo")
+	name := strings.TrimPrefix(c.Param("planName"), "/")
+	if name == "" || name == "." || name == ".." {
+		c.String(http.StatusBadRequest, "Invalid plan name")
+		return 0, "", "", false
+	}
+	return orgId.(uint), repo, name, true
+}
+
+func findStoredPlan(c *gin.Context, orgId uint, repo string, name string) (*models.StoredPlan, bool) {
+	plan, err := models.DB.GetStoredPlan(orgId, repo, name)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.String(http.StatusNotFound, fmt.Sprintf("Could not find plan %v for repo %v", name, repo))
+		} else {
+			log.Printf("Error fetching plan %v for repo %v: %v", name, repo, err)
+			c.String(http.StatusInternalServerError, "Unknown error occurred while fetching database")
+		}
+		return nil, false
+	}
+	return plan, true
+}
+
+func UploadPlan(c *gin.Context) {
+	orgId, repo, name, ok := storedPlanParams(c)
+	if !ok {
+		return
+	}
+
+	content, err := io.ReadAll(io.LimitReader(c.Request.Body, maxStoredPlanSize+1))
+	if err != nil {
+		log.Printf("Error reading plan %v for repo %v: %v", name, repo, err)
+		c.String(http.StatusBadRequest, "Could not read plan")
+		return
+	}
+	if len(content) > maxStoredPlanSize {
+		c.String(http.StatusRequestEntityTooLarge, "Plan is too large")
+		return
+	}
+	size := len(content)
+
+	if planStorageDir() != "" {
+		path := storedPlanDiskPath(orgId, repo, name)
+		err = os.MkdirAll(filepath.Dir(path), 0700)
+		if err == nil {
+			err = os.WriteFile(path, content, 0600)
+		}
+		if err != nil {
+			log.Printf("Error writing plan %v for repo %v to disk: %v", name, repo, err)
+			c.String(http.StatusInternalServerError, "Could not store plan")
+			return
+		}
+		content = nil
+	}
+
+	_, err = models.DB.UpsertStoredPlan(orgId, repo, name, content, size)
+	if err != nil {
+		log.Printf("Error saving plan %v for repo %v: %v", name, repo, err)
+		c.String(http.StatusInternalServerError, "Could not store plan")
+		return
+	}
+	c.String(http.StatusOK, "")
+}
+
+func DownloadPlan(c *gin.Context) {
+	orgId, repo, name, ok := storedPlanParams(c)
+	if !ok {
+		return
+	}
+	plan, ok := findStoredPlan(c, orgId, repo, name)
+	if !ok {
+		return
+	}
+
+	content := plan.Content
+	if planStorageDir() != "" {
+		var err error
+		content, err = os.ReadFile(storedPlanDiskPath(orgId, repo, name))
+		if err != nil {
+			log.Printf("Error reading plan %v for repo %v from disk: %v", name, repo, err)
+			c.String(http.StatusInternalServerError, "Could not read plan")
+			return
+		}
+	}
+	c.Data(http.StatusOK, "application/octet-stream", content)
+}
+
+func PlanExists(c *gin.Context) {
+	orgId, repo, name, ok := storedPlanParams(c)
+	if !ok {
+		return
+	}
+	plan, ok := findStoredPlan(c, orgId, repo, name)
+	if !ok {
+		return
+	}
+	c.Header("Content-Length", strconv.Itoa(plan.Size))
+	c.Header("Last-Modified", plan.UpdatedAt.UTC().Format(http.TimeFormat))
+	c.Status(http.StatusOK)
+}
+
+func DeletePlan(c *gin.Context) {
+	orgId, repo, name, ok := storedPlanParams(c)
+	if !ok {
+		return
+	}
+	plan, ok := findStoredPlan(c, orgId, repo, name)
+	if !ok {
+		return
+	}
+
+	if planStorageDir() != "" {
+		err := os.Remove(storedPlanDiskPath(orgId, repo, name))
+		if err != nil && !os.IsNotExist(err) {
+			log.Printf("Error deleting plan %v for repo %v from disk: %v", name, repo, err)
+			c.String(http.StatusInternalServerError, "Could not delete plan")
+			return
+		}
+	}
+	err := models.DB.DeleteStoredPlan(plan)
+	if err != nil {
+		log.Printf("Error deleting plan %v for repo %v: %v", name, repo, err)
+		c.String(http.StatusInternalServerError, "Could not delete plan")
+		return
+	}
+	c.String(http.StatusOK, "")
+}
+
+func ListPlans(c *gin.Context) {
+	orgId, exists := c.Get(middleware.ORGANISATION_ID_KEY)
+	if !exists {
+		log.Printf("Organisation ID not found in context")
+		c.String(http.StatusForbidden, "Not allowed to access this resource")
+		return
+	}
+	repo := c.Param("repo")
+
+	plans, err := models.DB.ListStoredPlans(orgId.(uint), repo, c.Query("prefix"))
+	if err != nil {
+		log.Printf("Error listing plans for repo %v: %v", repo, err)
+		c.String(http.StatusInternalServerError, "Unknown error occurred while fetching database")
+		return
+	}
+
+	response := make([]StoredPlanInfo, 0, len(plans))
+	for _, plan := range plans {
+		response = append(response, StoredPlanInfo{Name: plan.Name, Size: plan.Size, LastModified: plan.UpdatedAt})
+	}
+	c.JSON(http.StatusOK, response)
+}
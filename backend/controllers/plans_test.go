@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/diggerhq/digger/backend/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func plansRouter(orgId uint) *gin.Engine {
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set(middleware.ORGANISATION_ID_KEY, orgId)
+	})
+	r.GET("/repos/:repo/plans", ListPlans)
+	r.PUT("/repos/:repo/plans/*planName", UploadPlan)
+	r.GET("/repos/:repo/plans/*planName", DownloadPlan)
+	r.HEAD("/repos/:repo/plans/*planName", PlanExists)
+	r.DELETE("/repos/:repo/plans/*planName", DeletePlan)
+	return r
+}
+
+func doPlanRequest(r *gin.Engine, method string, path string, body []byte) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, bytes.NewReader(body))
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func testStoredPlanRoundTrip(t *testing.T) {
+	r := plansRouter(1)
+	path := "/repos/diggerhq-demo/plans/diggerhq-demo-12-dev.tfplan"
+
+	w := doPlanRequest(r, http.MethodHead, path, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = doPlanRequest(r, http.MethodPut, path, []byte("first plan"))
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doPlanRequest(r, http.MethodPut, path, []byte("second plan"))
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doPlanRequest(r, http.MethodPut, "/repos/diggerhq-demo/plans/diggerhq-demo-13-dev.tfplan", []byte("other plan"))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = doPlanRequest(r, http.MethodHead, path, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doPlanRequest(r, http.MethodGet, path, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "second plan", w.Body.String())
+
+	// plans are scoped to the organisation of the token
+	w = doPlanRequest(plansRouter(2), http.MethodGet, path, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = doPlanRequest(r, http.MethodGet, "/repos/diggerhq-demo/plans?prefix=diggerhq-demo-12-", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var plans []StoredPlanInfo
+	err := json.Unmarshal(w.Body.Bytes(), &plans)
+	assert.NoError(t, err)
+	assert.Len(t, plans, 1)
+	assert.Equal(t, "diggerhq-demo-12-dev.tfplan", plans[0].Name)
+	assert.Equal(t, len("second plan"), plans[0].Size)
+
+	w = doPlanRequest(r, http.MethodDelete, path, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doPlanRequest(r, http.MethodGet, path, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doPlanRequest(r, http.MethodPut, path, []byte("third plan"))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestStoredPlansInDatabase(t *testing.T) {
+	teardownSuite, _ := setupSuite(t)
+	defer teardownSuite(t)
+	os.Unsetenv("PLAN_STORAGE_DIR")
+
+	testStoredPlanRoundTrip(t)
+}
+
+func TestStoredPlansOnDisk(t *testing.T) {
+	teardownSuite, database := setupSuite(t)
+	defer teardownSuite(t)
+	dir := t.TempDir()
+	t.Setenv("PLAN_STORAGE_DIR", dir)
+
+	testStoredPlanRoundTrip(t)
+
+	plan, err := database.GetStoredPlan(1, "diggerhq-demo", "diggerhq-demo-12-dev.tfplan")
+	assert.NoError(t, err)
+	assert.Empty(t, plan.Content)
+	content, err := os.ReadFile(storedPlanDiskPath(1, "diggerhq-demo", "diggerhq-demo-12-dev.tfplan"))
+	assert.NoError(t, err)
+	assert.Equal(t, "third plan", string(content))
+}
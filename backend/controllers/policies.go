@@ -13,6 +13,8 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 type CreatePolicyInput struct {
@@ -156,27 +158,25 @@ func upsertPolicyForOrg(c *gin.Context, policyType string) {
 	policyResult := models.DB.GormDB.Where("organisation_id = ? AND (repo_id IS NULL AND project_id IS NULL) AND type = ?", org.ID, policyType).Take(&policy)
 
 	if policyResult.RowsAffected == 0 {
-		err := models.DB.GormDB.Create(&models.Policy{
+		policy = models.Policy{
 			OrganisationID: org.ID,
 			Type:           policyType,
-			Policy:         string(policyData),
-		}).Error
-
-		if err != nil {
-			log.Printf("Error creating policy: %v", err)
-			c.String(http.StatusInternalServerError, "Error creating policy")
-			return
-		}
-	} else {
-		err := policyResult.Update("policy", string(policyData)).Error
-		if err != nil {
-			log.Printf("Error updating policy: %v", err)
-			c.String(http.StatusInternalServerError, "Error updating policy")
-			return
 		}
 	}
 
-	c.JSON(http.StatusOK, gin.H{"success": true})
+	author, ok := policyAuthor(c)
+	if !ok {
+		return
+	}
+
+	revision, err := models.DB.SavePolicy(&policy, string(policyData), author, nil)
+	if err != nil {
+		log.Printf("Error saving policy: %v", err)
+		c.String(http.StatusInternalServerError, "Error saving policy")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "revision": revision.Revision})
 }
 
 func UpsertAccessPolicyForRepoAndProject(c *gin.Context) {
@@ -246,28 +246,204 @@ func upsertPolicyForRepoAndProject(c *gin.Context, policyType string) {
 	policyResult := models.DB.GormDB.Where("organisation_id = ? AND repo_id = ? AND project_id = ? AND type = ?", orgID, repoModel.ID, projectModel.ID, policyType).Take(&policy)
 
 	if policyResult.RowsAffected == 0 {
-		err := models.DB.GormDB.Create(&models.Policy{
+		policy = models.Policy{
 			OrganisationID: orgID.(uint),
 			RepoID:         &repoModel.ID,
 			ProjectID:      &projectModel.ID,
 			Type:           policyType,
-			Policy:         string(policyData),
-		}).Error
-		if err != nil {
-			log.Printf("Error creating policy: %v", err)
-			c.String(http.StatusInternalServerError, "Error creating policy")
-			return
 		}
-	} else {
-		err := policyResult.Update("policy", string(policyData)).Error
-		if err != nil {
-			log.Printf("Error updating policy: %v", err)
-			c.String(http.StatusInternalServerError, "Error updating policy")
-			return
+	}
+
+	author, ok := policyAuthor(c)
+	if !ok {
+		return
+	}
+
+	revision, err := models.DB.SavePolicy(&policy, string(policyData), author, nil)
+	if err != nil {
+		log.Printf("Error saving policy: %v", err)
+		c.String(http.StatusInternalServerError, "Error saving policy")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "revision": revision.Revision})
+}
+
+type PolicyRevisionInfo struct {
+	Revision     int       `json:"revision"`
+	Policy       string    `json:"policy"`
+	Hash         string    `json:"hash"`
+	Diff         string    `json:"diff"`
+	Author       string    `json:"author"`
+	RestoredFrom *int      `json:"restored_from,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// policyAuthor is who the revision is recorded for, saves of requests without one are refused
+func policyAuthor(c *gin.Context) (string, bool) {
+	author := c.GetString(middleware.USER_KEY)
+	if author == "" {
+		c.String(http.StatusForbidden, "Could not identify who is saving the policy")
+		return "", false
+	}
+	return author, true
+}
+
+func validPolicyType(c *gin.Context) (string, bool) {
+	policyType := c.Param("type")
+	switch policyType {
+	case models.POLICY_TYPE_ACCESS, models.POLICY_TYPE_PLAN, models.POLICY_TYPE_DRIFT:
+		return policyType, true
+	}
+	c.String(http.StatusBadRequest, "Unknown policy type: "+policyType)
+	return "", false
+}
+
+// policyForOrgFromContext finds the organisation wide policy of the type in the path
+func policyForOrgFromContext(c *gin.Context) (*models.Policy, bool) {
+	policyType, ok := validPolicyType(c)
+	if !ok {
+		return nil, false
+	}
+	organisation := c.Param("organisation")
+	var policy models.Policy
+	err := JoinedOrganisationRepoProjectQuery().
+		Where("organisations.name = ? AND (repos.id IS NULL AND projects.id IS NULL) AND policies.type = ? ", organisation, policyType).
+		First(&policy).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.String(http.StatusNotFound, "Could not find policy for organisation: "+organisation)
+		} else {
+			c.String(http.StatusInternalServerError, "Unknown error occurred while fetching database")
 		}
+		return nil, false
+	}
+
+	loggedInOrganisation := c.GetUint(middleware.ORGANISATION_ID_KEY)
+	if policy.OrganisationID != loggedInOrganisation {
+		log.Printf("Organisation ID %v does not match logged in organisation ID %v", policy.OrganisationID, loggedInOrganisation)
+		c.String(http.StatusForbidden, "Not allowed to access this resource")
+		return nil, false
 	}
+	return &policy, true
+}
 
-	c.JSON(http.StatusOK, gin.H{"success": true})
+// policyForRepoAndProjectFromContext finds the project policy of the type in the path
+func policyForRepoAndProjectFromContext(c *gin.Context) (*models.Policy, bool) {
+	policyType, ok := validPolicyType(c)
+	if !ok {
+		return nil, false
+	}
+	orgId, exists := c.Get(middleware.ORGANISATION_ID_KEY)
+	if !exists {
+		log.Printf("Organisation ID not found in context")
+		c.String(http.StatusForbidden, "Not allowed to access this resource")
+		return nil, false
+	}
+	repo := c.Param("repo")
+	projectName := c.Param("projectName")
+	var policy models.Policy
+	err := JoinedOrganisationRepoProjectQuery().
+		Where("repos.name = ? AND projects.name = ? AND policies.organisation_id = ? AND policies.type = ?", repo, projectName, orgId, policyType).
+		First(&policy).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.String(http.StatusNotFound, fmt.Sprintf("Could not find policy for repo %v and project name %v", repo, projectName))
+		} else {
+			c.String(http.StatusInternalServerError, "Unknown error occurred while fetching database")
+		}
+		return nil, false
+	}
+	return &policy, true
+}
+
+func ListPolicyRevisionsForOrg(c *gin.Context) {
+	policy, ok := policyForOrgFromContext(c)
+	if !ok {
+		return
+	}
+	listPolicyRevisions(c, policy)
+}
+
+func ListPolicyRevisionsForRepoAndProject(c *gin.Context) {
+	policy, ok := policyForRepoAndProjectFromContext(c)
+	if !ok {
+		return
+	}
+	listPolicyRevisions(c, policy)
+}
+
+func listPolicyRevisions(c *gin.Context, policy *models.Policy) {
+	revisions, err := models.DB.GetPolicyRevisions(policy.ID)
+	if err != nil {
+		log.Printf("Error fetching revisions of policy %v: %v", policy.ID, err)
+		c.String(http.StatusInternalServerError, "Error fetching policy revisions")
+		return
+	}
+	response := make([]PolicyRevisionInfo, 0, len(revisions))
+	for _, revision := range revisions {
+		response = append(response, policyRevisionInfo(revision))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func policyRevisionInfo(revision models.PolicyRevision) PolicyRevisionInfo {
+	return PolicyRevisionInfo{
+		Revision:     revision.Revision,
+		Policy:       revision.Content,
+		Hash:         revision.ContentHash,
+		Diff:         revision.Diff,
+		Author:       revision.Author,
+		RestoredFrom: revision.RestoredFrom,
+		CreatedAt:    revision.CreatedAt,
+	}
+}
+
+func RollbackPolicyForOrg(c *gin.Context) {
+	policy, ok := policyForOrgFromContext(c)
+	if !ok {
+		return
+	}
+	rollbackPolicy(c, policy)
+}
+
+func RollbackPolicyForRepoAndProject(c *gin.Context) {
+	policy, ok := policyForRepoAndProjectFromContext(c)
+	if !ok {
+		return
+	}
+	rollbackPolicy(c, policy)
+}
+
+// rollbackPolicy restores the content of an earlier revision as a new revision, history is never rewritten
+func rollbackPolicy(c *gin.Context, policy *models.Policy) {
+	revisionNumber, err := strconv.Atoi(c.Param("revision"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid revision: "+c.Param("revision"))
+		return
+	}
+	revision, err := models.DB.GetPolicyRevision(policy.ID, revisionNumber)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.String(http.StatusNotFound, fmt.Sprintf("Could not find revision %v of policy", revisionNumber))
+		} else {
+			c.String(http.StatusInternalServerError, "Unknown error occurred while fetching database")
+		}
+		return
+	}
+
+	author, ok := policyAuthor(c)
+	if !ok {
+		return
+	}
+
+	restored, err := models.DB.SavePolicy(policy, revision.Content, author, &revisionNumber)
+	if err != nil {
+		log.Printf("Error rolling back policy %v to revision %v: %v", policy.ID, revisionNumber, err)
+		c.String(http.StatusInternalServerError, "Error rolling back policy")
+		return
+	}
+	c.JSON(http.StatusOK, policyRevisionInfo(*restored))
 }
 
 func IssueAccessTokenForOrg(c *gin.Context) {
@@ -0,0 +1,167 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/diggerhq/digger/backend/middleware"
+	"github.com/diggerhq/digger/backend/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func policiesRouter(orgId uint) *gin.Engine {
+	return policiesRouterForUser(orgId, "alice")
+}
+
+func policiesRouterForUser(orgId uint, user string) *gin.Engine {
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set(middleware.ORGANISATION_ID_KEY, orgId)
+		if user != "" {
+			c.Set(middleware.USER_KEY, user)
+		}
+	})
+	r.GET("/orgs/:organisation/access-policy", FindAccessPolicyForOrg)
+	r.PUT("/orgs/:organisation/access-policy", UpsertAccessPolicyForOrg)
+	r.PUT("/repos/:repo/projects/:projectName/plan-policy", UpsertPlanPolicyForRepoAndProject)
+	r.GET("/orgs/:organisation/policies/:type/revisions", ListPolicyRevisionsForOrg)
+	r.GET("/repos/:repo/projects/:projectName/policies/:type/revisions", ListPolicyRevisionsForRepoAndProject)
+	r.POST("/orgs/:organisation/policies/:type/revisions/:revision/rollback", RollbackPolicyForOrg)
+	r.POST("/repos/:repo/projects/:projectName/policy-decisions", ReportPolicyDecision)
+	r.GET("/repos/:repo/projects/:projectName/policy-decisions", ListPolicyDecisionsForRepoAndProject)
+	r.GET("/orgs/:organisation/policy-decisions", ListPolicyDecisionsForOrg)
+	return r
+}
+
+func doPolicyRequest(r *gin.Engine, method string, path string, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, bytes.NewReader([]byte(body)))
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestPolicyRevisionsAndRollback(t *testing.T) {
+	teardownSuite, _ := setupSuite(t)
+	defer teardownSuite(t)
+	r := policiesRouter(1)
+
+	w := doPolicyRequest(r, http.MethodPut, "/orgs/testOrg/access-policy", "package digger\nallow = true\n")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doPolicyRequest(r, http.MethodPut, "/orgs/testOrg/access-policy", "package digger\nallow = false\n")
+	assert.Equal(t, http.StatusOK, w.Code)
+	// saving the same content again does not create a revision
+	w = doPolicyRequest(r, http.MethodPut, "/orgs/testOrg/access-policy", "package digger\nallow = false\n")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = doPolicyRequest(r, http.MethodGet, "/orgs/testOrg/policies/access/revisions", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var revisions []PolicyRevisionInfo
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &revisions))
+	assert.Len(t, revisions, 2)
+	assert.Equal(t, 2, revisions[0].Revision)
+	assert.Equal(t, "alice", revisions[0].Author)
+	assert.Contains(t, revisions[0].Diff, "-allow = true")
+	assert.Contains(t, revisions[0].Diff, "+allow = false")
+	assert.Equal(t, models.PolicyContentHash("package digger\nallow = false\n"), revisions[0].Hash)
+
+	w = doPolicyRequest(r, http.MethodPost, "/orgs/testOrg/policies/access/revisions/1/rollback", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var restored PolicyRevisionInfo
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &restored))
+	assert.Equal(t, 3, restored.Revision)
+	assert.Equal(t, 1, *restored.RestoredFrom)
+
+	w = doPolicyRequest(r, http.MethodGet, "/orgs/testOrg/access-policy", "")
+	assert.Equal(t, "package digger\nallow = true\n", w.Body.String())
+
+	w = doPolicyRequest(r, http.MethodPost, "/orgs/testOrg/policies/access/revisions/7/rollback", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doPolicyRequest(r, http.MethodGet, "/orgs/testOrg/policies/unknown/revisions", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	// revisions are scoped to the organisation of the token
+	w = doPolicyRequest(policiesRouter(2), http.MethodGet, "/orgs/testOrg/policies/access/revisions", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = doPolicyRequest(r, http.MethodPut, "/repos/test%20repo/projects/test%20project/plan-policy", "package digger\n")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doPolicyRequest(r, http.MethodGet, "/repos/test%20repo/projects/test%20project/policies/plan/revisions", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &revisions))
+	assert.Len(t, revisions, 1)
+}
+
+func TestPolicySaveWithoutAuthorIsRefused(t *testing.T) {
+	teardownSuite, _ := setupSuite(t)
+	defer teardownSuite(t)
+
+	w := doPolicyRequest(policiesRouterForUser(1, ""), http.MethodPut, "/orgs/testOrg/access-policy", "package digger\nallow = true\n")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = doPolicyRequest(policiesRouterForUser(1, "job-token:3"), http.MethodPut, "/orgs/testOrg/access-policy", "package digger\nallow = true\n")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doPolicyRequest(policiesRouter(1), http.MethodGet, "/orgs/testOrg/policies/access/revisions", "")
+	var revisions []PolicyRevisionInfo
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &revisions))
+	assert.Len(t, revisions, 1)
+	assert.Equal(t, "job-token:3", revisions[0].Author)
+}
+
+func TestPolicyDecisions(t *testing.T) {
+	teardownSuite, _ := setupSuite(t)
+	defer teardownSuite(t)
+	r := policiesRouter(1)
+
+	policy := "package digger\nallow = false\n"
+	w := doPolicyRequest(r, http.MethodPut, "/orgs/testOrg/access-policy", policy)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	decision := PolicyDecisionInput{
+		Type:        "access",
+		Action:      "digger apply",
+		RequestedBy: "bob",
+		InputHash:   "abc",
+		PolicyHash:  models.PolicyContentHash(policy),
+		Allowed:     false,
+	}
+	body, _ := json.Marshal(decision)
+	w = doPolicyRequest(r, http.MethodPost, "/repos/repo/projects/prod/policy-decisions", string(body))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	decision = PolicyDecisionInput{Type: "plan", InputHash: "def", PolicyHash: "other", Allowed: true, Violations: []string{"warned"}}
+	body, _ = json.Marshal(decision)
+	w = doPolicyRequest(r, http.MethodPost, "/repos/repo/projects/dev/policy-decisions", string(body))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = doPolicyRequest(r, http.MethodPost, "/repos/repo/projects/dev/policy-decisions", `{"type": "unknown"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var decisions []PolicyDecisionInfo
+	w = doPolicyRequest(r, http.MethodGet, "/orgs/testOrg/policy-decisions", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &decisions))
+	assert.Len(t, decisions, 2)
+
+	w = doPolicyRequest(r, http.MethodGet, "/orgs/testOrg/policy-decisions?allowed=false", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &decisions))
+	assert.Len(t, decisions, 1)
+	assert.Equal(t, "prod", decisions[0].Project)
+	assert.Equal(t, "bob", decisions[0].RequestedBy)
+	assert.Equal(t, 1, *decisions[0].PolicyRevision)
+	assert.Equal(t, []string{}, decisions[0].Violations)
+
+	w = doPolicyRequest(r, http.MethodGet, "/repos/repo/projects/dev/policy-decisions", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	decisions = nil
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &decisions))
+	assert.Len(t, decisions, 1)
+	assert.Nil(t, decisions[0].PolicyRevision)
+	assert.Equal(t, []string{"warned"}, decisions[0].Violations)
+
+	w = doPolicyRequest(r, http.MethodGet, "/orgs/testOrg/policy-decisions?since=yesterday", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,209 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/diggerhq/digger/backend/middleware"
+	"github.com/diggerhq/digger/backend/models"
+	"github.com/gin-gonic/gin"
+)
+
+// decisions returned when no limit is requested
+const defaultPolicyDecisionsLimit = 100
+
+// PolicyDecisionInput is what jobs report after evaluating a policy
+type PolicyDecisionInput struct {
+	Type        string   `json:"type"`
+	Action      string   `json:"action"`
+	RequestedBy string   `json:"requested_by"`
+	InputHash   string   `json:"input_hash"`
+	PolicyHash  string   `json:"policy_hash"`
+	Allowed     bool     `json:"allowed"`
+	Violations  []string `json:"violations"`
+}
+
+type PolicyDecisionInfo struct {
+	Id          uint     `json:"id"`
+	Repo        string   `json:"repo"`
+	Project     string   `json:"project"`
+	Type        string   `json:"type"`
+	Action      string   `json:"action"`
+	RequestedBy string   `json:"requested_by"`
+	InputHash   string   `json:"input_hash"`
+	PolicyHash  string   `json:"policy_hash"`
+	Allowed     bool     `json:"allowed"`
+	Violations  []string `json:"violations"`
+	// PolicyRevision is the revision of the backend policy that was evaluated, policies from other sources have none
+	PolicyRevision *int      `json:"policy_revision,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+func ReportPolicyDecision(c *gin.Context) {
+	orgId, exists := c.Get(middleware.ORGANISATION_ID_KEY)
+	if !exists {
+		log.Printf("Organisation ID not found in context")
+		c.String(http.StatusForbidden, "Not allowed to access this resource")
+		return
+	}
+
+	var input PolicyDecisionInput
+	err := c.BindJSON(&input)
+	if err != nil {
+		log.Printf("Error binding policy decision: %v", err)
+		c.String(http.StatusBadRequest, "Invalid policy decision")
+		return
+	}
+	switch input.Type {
+	case models.POLICY_TYPE_ACCESS, models.POLICY_TYPE_PLAN, models.POLICY_TYPE_DRIFT:
+	default:
+		c.String(http.StatusBadRequest, "Unknown policy type: "+input.Type)
+		return
+	}
+
+	violations, err := json.Marshal(nonNilStrings(input.Violations))
+	if err != nil {
+		log.Printf("Error encoding policy violations: %v", err)
+		c.String(http.StatusInternalServerError, "Error saving policy decision")
+		return
+	}
+	decision := models.PolicyDecision{
+		OrganisationID: orgId.(uint),
+		Repo:           c.Param("repo"),
+		Project:        c.Param("projectName"),
+		Type:           input.Type,
+		Action:         input.Action,
+		RequestedBy:    input.RequestedBy,
+		InputHash:      input.InputHash,
+		PolicyHash:     input.PolicyHash,
+		Allowed:        input.Allowed,
+		Violations:     string(violations),
+	}
+
+	var revision *models.PolicyRevision
+	if input.PolicyHash != "" {
+		revision, err = models.DB.FindPolicyRevisionByHash(decision.OrganisationID, input.Type, input.PolicyHash)
+		if err != nil {
+			log.Printf("Error finding policy revision with hash %v: %v", input.PolicyHash, err)
+		} else if revision != nil {
+			decision.PolicyRevisionID = &revision.ID
+		}
+	}
+
+	err = models.DB.CreatePolicyDecision(&decision)
+	if err != nil {
+		log.Printf("Error saving policy decision: %v", err)
+		c.String(http.StatusInternalServerError, "Error saving policy decision")
+		return
+	}
+	decision.PolicyRevision = revision
+	c.JSON(http.StatusOK, policyDecisionInfo(decision))
+}
+
+// ListPolicyDecisionsForOrg returns the decisions of an organisation, filtered by the repo, project, type, allowed
+// and since query parameters
+func ListPolicyDecisionsForOrg(c *gin.Context) {
+	organisation := c.Param("organisation")
+	org := models.Organisation{}
+	orgResult := models.DB.GormDB.Where("name = ?", organisation).Take(&org)
+	if orgResult.RowsAffected == 0 {
+		c.String(http.StatusNotFound, "Could not find organisation: "+organisation)
+		return
+	}
+	loggedInOrganisation := c.GetUint(middleware.ORGANISATION_ID_KEY)
+	if org.ID != loggedInOrganisation {
+		log.Printf("Organisation ID %v does not match logged in organisation ID %v", org.ID, loggedInOrganisation)
+		c.String(http.StatusForbidden, "Not allowed to access this resource")
+		return
+	}
+	listPolicyDecisions(c, org.ID, c.Query("repo"), c.Query("project"))
+}
+
+func ListPolicyDecisionsForRepoAndProject(c *gin.Context) {
+	orgId, exists := c.Get(middleware.ORGANISATION_ID_KEY)
+	if !exists {
+		log.Printf("Organisation ID not found in context")
+		c.String(http.StatusForbidden, "Not allowed to access this resource")
+		return
+	}
+	listPolicyDecisions(c, orgId.(uint), c.Param("repo"), c.Param("projectName"))
+}
+
+func listPolicyDecisions(c *gin.Context, orgId uint, repo string, project string) {
+	filter := models.PolicyDecisionFilter{
+		Repo:    repo,
+		Project: project,
+		Type:    c.Query("type"),
+		Limit:   defaultPolicyDecisionsLimit,
+	}
+	if allowed := c.Query("allowed"); allowed != "" {
+		value, err := strconv.ParseBool(allowed)
+		if err != nil {
+			c.String(http.StatusBadRequest, "Invalid allowed parameter: "+allowed)
+			return
+		}
+		filter.Allowed = &value
+	}
+	if since := c.Query("since"); since != "" {
+		value, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			c.String(http.StatusBadRequest, "Invalid since parameter, expected an RFC 3339 time: "+since)
+			return
+		}
+		filter.Since = &value
+	}
+	if limit := c.Query("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value <= 0 {
+			c.String(http.StatusBadRequest, "Invalid limit parameter: "+limit)
+			return
+		}
+		filter.Limit = value
+	}
+
+	decisions, err := models.DB.GetPolicyDecisions(orgId, filter)
+	if err != nil {
+		log.Printf("Error fetching policy decisions: %v", err)
+		c.String(http.StatusInternalServerError, "Error fetching policy decisions")
+		return
+	}
+	response := make([]PolicyDecisionInfo, 0, len(decisions))
+	for _, decision := range decisions {
+		response = append(response, policyDecisionInfo(decision))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func policyDecisionInfo(decision models.PolicyDecision) PolicyDecisionInfo {
+	violations := []string{}
+	if err := json.Unmarshal([]byte(decision.Violations), &violations); err != nil {
+		log.Printf("Could not decode violations of policy decision %v: %v", decision.ID, err)
+	}
+	info := PolicyDecisionInfo{
+		Id:          decision.ID,
+		Repo:        decision.Repo,
+		Project:     decision.Project,
+		Type:        decision.Type,
+		Action:      decision.Action,
+		RequestedBy: decision.RequestedBy,
+		InputHash:   decision.InputHash,
+		PolicyHash:  decision.PolicyHash,
+		Allowed:     decision.Allowed,
+		Violations:  violations,
+		CreatedAt:   decision.CreatedAt,
+	}
+	if decision.PolicyRevision != nil {
+		info.PolicyRevision = &decision.PolicyRevision.Revision
+	}
+	return info
+}
+
+func nonNilStrings(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
@@ -4,12 +4,15 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/diggerhq/digger/backend/ci_backends"
 	"github.com/diggerhq/digger/backend/middleware"
 	"github.com/diggerhq/digger/backend/models"
 	"github.com/diggerhq/digger/backend/services"
 	"github.com/diggerhq/digger/backend/utils"
 	"github.com/diggerhq/digger/libs/comment_utils/reporting"
+	"github.com/diggerhq/digger/libs/comment_utils/templates"
 	"github.com/diggerhq/digger/libs/digger_config"
+	"github.com/diggerhq/digger/libs/notifications"
 	"github.com/diggerhq/digger/libs/orchestrator"
 	orchestrator_scheduler "github.com/diggerhq/digger/libs/orchestrator/scheduler"
 	"github.com/diggerhq/digger/libs/terraform_utils"
@@ -349,6 +352,8 @@ func (d DiggerController) SetJobStatusForProject(c *gin.Context) {
 		return
 	}
 
+	// only the request moving the job to succeeded or failed sends its notifications
+	jobCompleted := false
 	switch request.Status {
 	case "started":
 		job.Status = orchestrator_scheduler.DiggerJobStarted
@@ -359,23 +364,31 @@ func (d DiggerController) SetJobStatusForProject(c *gin.Context) {
 			return
 		}
 
-		client, _, err := utils.GetGithubClient(d.GithubClientProvider, job.Batch.GithubInstallationId, job.Batch.RepoFullName)
-		if err != nil {
-			log.Printf("Error Creating github client: %v", err)
-		} else {
-			_, workflowRunUrl, err := utils.GetWorkflowIdAndUrlFromDiggerJobId(client, job.Batch.RepoOwner, job.Batch.RepoName, job.DiggerJobID)
+		// the runs of other VCSs' pipelines are not looked up by job id
+		if job.Batch.GetVCS() == models.DiggerVCSGithub {
+			client, _, err := utils.GetGithubClient(d.GithubClientProvider, job.Batch.GithubInstallationId, job.Batch.RepoFullName)
 			if err != nil {
-				log.Printf("Error getting workflow ID from job: %v", err)
+				log.Printf("Error Creating github client: %v", err)
 			} else {
-				job.WorkflowRunUrl = &workflowRunUrl
-				err = models.DB.UpdateDiggerJob(job)
+				_, workflowRunUrl, err := utils.GetWorkflowIdAndUrlFromDiggerJobId(client, job.Batch.RepoOwner, job.Batch.RepoName, job.DiggerJobID)
 				if err != nil {
-					log.Printf("Error updating digger job: %v", err)
+					log.Printf("Error getting workflow ID from job: %v", err)
+				} else {
+					job.WorkflowRunUrl = &workflowRunUrl
+					err = models.DB.UpdateDiggerJob(job)
+					if err != nil {
+						log.Printf("Error updating digger job: %v", err)
+					}
 				}
 			}
 		}
 	case "succeeded":
-		job.Status = orchestrator_scheduler.DiggerJobSucceeded
+		jobCompleted, err = models.DB.UpdateDiggerJobStatus(job, orchestrator_scheduler.DiggerJobSucceeded)
+		if err != nil {
+			log.Printf("Error updating job status: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating job status"})
+			return
+		}
 		job.TerraformOutput = request.TerraformOutput
 		if request.Footprint != nil {
 			job.PlanFootprint, err = json.Marshal(request.Footprint)
@@ -397,6 +410,26 @@ func (d DiggerController) SetJobStatusForProject(c *gin.Context) {
 					log.Printf("Recovered from panic while executing goroutine dispatching digger jobs: %v ", r)
 				}
 			}()
+			workflowFileName := "mantis_workflow.yml"
+
+			if job.Batch.GetVCS() != models.DiggerVCSGithub {
+				ciBackend, err := d.CiBackendProvider.GetCiBackend(ci_backends.CiBackendOptions{
+					RepoFullName: job.Batch.RepoFullName,
+					RepoOwner:    job.Batch.RepoOwner,
+					RepoName:     job.Batch.RepoName,
+					VCS:          job.Batch.GetVCS(),
+				})
+				if err != nil {
+					log.Printf("Error getting ci backend: %v", err)
+					return
+				}
+				err = services.DiggerJobCompleted(ciBackend, &job.Batch.ID, job, job.Batch.RepoOwner, job.Batch.RepoName, workflowFileName)
+				if err != nil {
+					log.Printf("Error triggering job: %v", err)
+				}
+				return
+			}
+
 			ghClientProvider := d.GithubClientProvider
 			installationLink, err := models.DB.GetGithubInstallationLinkForOrg(orgId)
 			if err != nil {
@@ -422,8 +455,6 @@ func (d DiggerController) SetJobStatusForProject(c *gin.Context) {
 				return
 			}
 
-			workflowFileName := "mantis_workflow.yml"
-
 			if !strings.Contains(jobLink.RepoFullName, "/") {
 				log.Printf("Repo full name %v does not contain a slash", jobLink.RepoFullName)
 				return
@@ -431,7 +462,8 @@ func (d DiggerController) SetJobStatusForProject(c *gin.Context) {
 
 			repoFullNameSplit := strings.Split(jobLink.RepoFullName, "/")
 			client, _, err := ghClientProvider.Get(installations[0].GithubAppId, installationLink.GithubInstallationId)
-			err = services.DiggerJobCompleted(client, &job.Batch.ID, job, repoFullNameSplit[0], repoFullNameSplit[1], workflowFileName)
+			ciBackend := ci_backends.GithubActionCi{Client: client}
+			err = services.DiggerJobCompleted(ciBackend, &job.Batch.ID, job, repoFullNameSplit[0], repoFullNameSplit[1], workflowFileName)
 			if err != nil {
 				log.Printf("Error triggering job: %v", err)
 				return
@@ -444,7 +476,12 @@ func (d DiggerController) SetJobStatusForProject(c *gin.Context) {
 		}
 
 	case "failed":
-		job.Status = orchestrator_scheduler.DiggerJobFailed
+		jobCompleted, err = models.DB.UpdateDiggerJobStatus(job, orchestrator_scheduler.DiggerJobFailed)
+		if err != nil {
+			log.Printf("Error updating job status: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating job status"})
+			return
+		}
 		job.TerraformOutput = request.TerraformOutput
 		err := models.DB.UpdateDiggerJob(job)
 		if err != nil {
@@ -466,6 +503,13 @@ func (d DiggerController) SetJobStatusForProject(c *gin.Context) {
 		return
 	}
 
+	if request.Status == "succeeded" || request.Status == "failed" {
+		err = CompleteCheckRunForJob(d.GithubClientProvider, job, request.JobSummary)
+		if err != nil {
+			log.Printf("Error completing check run: %v", err)
+		}
+	}
+
 	// get batch ID
 	// check if all jobs have succeeded at this point
 	// if so, perform merge of PR (if configured to do so)
@@ -476,6 +520,16 @@ func (d DiggerController) SetJobStatusForProject(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating batch status"})
 		return
 	}
+	if jobCompleted {
+		go func(batch models.DiggerBatch, job models.DiggerJob) {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Printf("Recovered from panic while sending notifications of job %v: %v", job.DiggerJobID, r)
+				}
+			}()
+			NotifyJobCompletedIfEnabled(&batch, &job)
+		}(*batch, *job)
+	}
 
 	err = AutomergePRforBatchIfEnabled(d.GithubClientProvider, batch)
 	if err != nil {
@@ -591,6 +645,11 @@ func UpdateCommentsForBatchGroup(gh utils.GithubClientProvider, batch *models.Di
 		return nil
 	}
 
+	if batch.GetVCS() != models.DiggerVCSGithub {
+		log.Printf("module source comments are only posted on GitHub, skipping")
+		return nil
+	}
+
 	ghService, _, err := utils.GetGithubService(
 		gh,
 		batch.GithubInstallationId,
@@ -617,13 +676,82 @@ func UpdateCommentsForBatchGroup(gh utils.GithubClientProvider, batch *models.Di
 		projectToTerraformOutput[serialJob.ProjectName] = job.TerraformOutput
 	}
 
+	// the backend serves many repositories, so templates come from the digger config of the batch
+	commentTemplates, err := templates.Parse(diggerConfigYml.CommentTemplates)
+	if err != nil {
+		log.Printf("Invalid comment templates in digger config of batch %v, using the defaults: %v", batch.ID, err)
+		commentTemplates = templates.Defaults()
+	}
+
 	for _, detail := range sourceDetails {
-		reporter := reporting.SourceGroupingReporter{serializedJobs, batch.PrNumber, ghService}
+		reporter := reporting.SourceGroupingReporter{Jobs: serializedJobs, PrNumber: batch.PrNumber, PrService: ghService, Templates: commentTemplates}
 		reporter.UpdateComment(sourceDetails, detail.SourceLocation, projectToTerraformOutput)
 	}
 	return nil
 }
 
+// CompleteCheckRunForJob completes the check run the backend started for the job when the batch reports check runs
+func CompleteCheckRunForJob(gh utils.GithubClientProvider, job *models.DiggerJob, summary *terraform_utils.PlanSummary) error {
+	batch := job.Batch
+	if batch.GetVCS() != models.DiggerVCSGithub {
+		return nil
+	}
+	diggerConfigYml, err := digger_config.LoadDiggerConfigYamlFromString(batch.DiggerConfig)
+	if err != nil {
+		return fmt.Errorf("error loading digger config from batch: %v", err)
+	}
+	if diggerConfigYml.ReportingCheckRuns == nil || !*diggerConfigYml.ReportingCheckRuns {
+		return nil
+	}
+
+	var command, commandTitle string
+	switch batch.BatchType {
+	case orchestrator.DiggerCommandPlan:
+		command, commandTitle = "plan", "Plan"
+	case orchestrator.DiggerCommandApply:
+		command, commandTitle = "apply", "Apply"
+	default:
+		return nil
+	}
+	var jobSpec orchestrator.JobJson
+	err = json.Unmarshal(job.SerializedJobSpec, &jobSpec)
+	if err != nil {
+		return fmt.Errorf("could not unmarshal job spec: %v", err)
+	}
+
+	status := "success"
+	title := fmt.Sprintf("%v succeeded", commandTitle)
+	if job.Status == orchestrator_scheduler.DiggerJobFailed {
+		status = "failure"
+		title = fmt.Sprintf("%v failed", commandTitle)
+	}
+	if summary != nil && command == "plan" {
+		title = fmt.Sprintf("%v: %v to add, %v to change, %v to destroy", title, summary.ResourcesCreated, summary.ResourcesUpdated, summary.ResourcesDeleted)
+	}
+	details := fmt.Sprintf("### %v for `%v`\n", commandTitle, jobSpec.ProjectName)
+	if job.PRCommentUrl != "" {
+		details += fmt.Sprintf("\n[Output](%v)\n", job.PRCommentUrl)
+	}
+	if job.WorkflowRunUrl != nil {
+		details += fmt.Sprintf("\n[Workflow run](%v)\n", *job.WorkflowRunUrl)
+	}
+	output := orchestrator.CheckRunOutput{Title: title, Summary: details}
+	if job.TerraformOutput != "" {
+		output.Text = "```terraform\n" + job.TerraformOutput + "\n```"
+	}
+
+	checksService, err := utils.GetGithubCheckRunsService(gh, batch.GithubInstallationId, batch.RepoFullName, batch.RepoOwner, batch.RepoName)
+	if err != nil {
+		return err
+	}
+	statusContext := jobSpec.ProjectName + "/" + command
+	err = checksService.SetCheckRunOutput(batch.PrNumber, statusContext, output)
+	if err != nil {
+		return err
+	}
+	return checksService.SetStatus(batch.PrNumber, status, statusContext)
+}
+
 func AutomergePRforBatchIfEnabled(gh utils.GithubClientProvider, batch *models.DiggerBatch) error {
 	diggerYmlString := batch.DiggerConfig
 	diggerConfigYml, err := digger_config.LoadDiggerConfigYamlFromString(diggerYmlString)
@@ -639,18 +767,12 @@ func AutomergePRforBatchIfEnabled(gh utils.GithubClientProvider, batch *models.D
 		automerge = false
 	}
 	if batch.Status == orchestrator_scheduler.BatchJobSucceeded && batch.BatchType == orchestrator.DiggerCommandApply && automerge == true {
-		ghService, _, err := utils.GetGithubService(
-			gh,
-			batch.GithubInstallationId,
-			batch.RepoFullName,
-			batch.RepoOwner,
-			batch.RepoName,
-		)
+		prService, err := utils.GetPrServiceForBatch(gh, batch)
 		if err != nil {
-			log.Printf("Error getting github service: %v", err)
-			return fmt.Errorf("error getting github service: %v", err)
+			log.Printf("Error getting pr service: %v", err)
+			return fmt.Errorf("error getting pr service: %v", err)
 		}
-		err = ghService.MergePullRequest(batch.PrNumber)
+		err = prService.MergePullRequest(batch.PrNumber)
 		if err != nil {
 			log.Printf("Error merging pull request: %v", err)
 			return fmt.Errorf("error merging pull request: %v", err)
@@ -658,3 +780,54 @@ func AutomergePRforBatchIfEnabled(gh utils.GithubClientProvider, batch *models.D
 	}
 	return nil
 }
+
+// NotifyJobCompletedIfEnabled sends the plan or apply result of a job to the sinks of the mantis.yml of its batch
+func NotifyJobCompletedIfEnabled(batch *models.DiggerBatch, job *models.DiggerJob) {
+	diggerConfigYml, err := digger_config.LoadDiggerConfigYamlFromString(batch.DiggerConfig)
+	if err != nil {
+		log.Printf("Error loading digger config from batch: %v", err)
+		return
+	}
+	if diggerConfigYml.Notifications == nil || len(diggerConfigYml.Notifications.Routes) == 0 {
+		return
+	}
+	event, ok := jobNotificationEvent(batch, job)
+	if !ok {
+		return
+	}
+	notifications.LoadBackend(diggerConfigYml.Notifications.ToNotifications()).Notify(event)
+}
+
+// jobNotificationEvent returns the event of a failed plan, or of a failed or succeeded apply
+func jobNotificationEvent(batch *models.DiggerBatch, job *models.DiggerJob) (notifications.Event, bool) {
+	var eventType notifications.EventType
+	switch {
+	case batch.BatchType == orchestrator.DiggerCommandPlan && job.Status == orchestrator_scheduler.DiggerJobFailed:
+		eventType = notifications.PlanFailed
+	case batch.BatchType == orchestrator.DiggerCommandApply && job.Status == orchestrator_scheduler.DiggerJobFailed:
+		eventType = notifications.ApplyFailed
+	case batch.BatchType == orchestrator.DiggerCommandApply && job.Status == orchestrator_scheduler.DiggerJobSucceeded:
+		eventType = notifications.ApplySucceeded
+	default:
+		return notifications.Event{}, false
+	}
+	var jobSpec orchestrator.JobJson
+	err := json.Unmarshal(job.SerializedJobSpec, &jobSpec)
+	if err != nil {
+		log.Printf("Failed to unmarshal spec of job %v: %v", job.DiggerJobID, err)
+		return notifications.Event{}, false
+	}
+	event := notifications.Event{
+		Type:        eventType,
+		Repository:  batch.RepoFullName,
+		Project:     jobSpec.ProjectName,
+		PrNumber:    batch.PrNumber,
+		RequestedBy: jobSpec.RequestedBy,
+		Message:     fmt.Sprintf("%v of %v %v", batch.BatchType, jobSpec.ProjectName, job.Status.ToString()),
+		Details:     job.TerraformOutput,
+	}
+	if job.WorkflowRunUrl != nil && *job.WorkflowRunUrl != "#" {
+		event.Url = *job.WorkflowRunUrl
+	}
+	return event, true
+}
@@ -1,14 +1,18 @@
 package controllers
 
 import (
+	"encoding/json"
 	"github.com/diggerhq/digger/backend/models"
 	"github.com/diggerhq/digger/backend/utils"
+	"github.com/diggerhq/digger/libs/notifications"
 	"github.com/diggerhq/digger/libs/orchestrator"
 	orchestrator_scheduler "github.com/diggerhq/digger/libs/orchestrator/scheduler"
+	"github.com/diggerhq/digger/libs/terraform_utils"
 	"github.com/google/go-github/v61/github"
 	"github.com/google/uuid"
 	"github.com/migueleliasweb/go-github-mock/src/mock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"net/http"
 	"testing"
 )
@@ -93,3 +97,89 @@ func TestAutomergeWhenBatchIsSuccessfulStatus(t *testing.T) {
 	assert.True(t, isMergeCalled)
 
 }
+
+func TestJobNotificationEvent(t *testing.T) {
+	runUrl := "https://github.com/org/repo/actions/runs/1"
+	pending := "#"
+	batch := models.DiggerBatch{PrNumber: 2, RepoFullName: "org/repo", BatchType: orchestrator.DiggerCommandApply}
+	succeeded := models.DiggerJob{Status: orchestrator_scheduler.DiggerJobSucceeded, SerializedJobSpec: []byte(`{"projectName":"dev","requestedBy":"alice"}`), TerraformOutput: "Apply complete!", WorkflowRunUrl: &runUrl}
+	failed := models.DiggerJob{Status: orchestrator_scheduler.DiggerJobFailed, SerializedJobSpec: []byte(`{"projectName":"prod"}`), TerraformOutput: "Error: boom", WorkflowRunUrl: &pending}
+
+	event, ok := jobNotificationEvent(&batch, &succeeded)
+	assert.True(t, ok)
+	assert.Equal(t, notifications.ApplySucceeded, event.Type)
+	assert.Equal(t, "dev", event.Project)
+	assert.Equal(t, "alice", event.RequestedBy)
+	assert.Equal(t, "org/repo", event.Repository)
+	assert.Equal(t, 2, event.PrNumber)
+	assert.Equal(t, runUrl, event.Url)
+	event, ok = jobNotificationEvent(&batch, &failed)
+	assert.True(t, ok)
+	assert.Equal(t, notifications.ApplyFailed, event.Type)
+	assert.Equal(t, "Error: boom", event.Details)
+	assert.Equal(t, "", event.Url)
+
+	batch.BatchType = orchestrator.DiggerCommandPlan
+	_, ok = jobNotificationEvent(&batch, &succeeded)
+	assert.False(t, ok)
+	event, ok = jobNotificationEvent(&batch, &failed)
+	assert.True(t, ok)
+	assert.Equal(t, notifications.PlanFailed, event.Type)
+	assert.Equal(t, "prod", event.Project)
+}
+
+func TestCompleteCheckRunForJob(t *testing.T) {
+	teardownSuite, _ := setupSuite(t)
+	defer teardownSuite(t)
+	var updates []map[string]interface{}
+	mockedHTTPClient := mock.NewMockedHTTPClient(
+		mock.WithRequestMatch(
+			mock.GetReposPullsByOwnerByRepoByPullNumber,
+			github.PullRequest{Number: github.Int(2), Head: &github.PullRequestBranch{SHA: github.String("abc123")}},
+		),
+		mock.WithRequestMatch(
+			mock.GetReposCommitsCheckRunsByOwnerByRepoByRef,
+			github.ListCheckRunsResults{CheckRuns: []*github.CheckRun{{ID: github.Int64(42), ExternalID: github.String("mantis:2:dev")}}},
+		),
+		mock.WithRequestMatchHandler(
+			mock.PatchReposCheckRunsByOwnerByRepoByCheckRunId,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var update map[string]interface{}
+				json.NewDecoder(r.Body).Decode(&update)
+				updates = append(updates, update)
+				w.Write([]byte(`{"id": 42}`))
+			}),
+		),
+	)
+	gh := &utils.DiggerGithubClientMockProvider{}
+	gh.MockedHTTPClient = mockedHTTPClient
+
+	batch := models.DiggerBatch{
+		PrNumber:             2,
+		DiggerConfig:         "projects:\n  - name: dev\n    dir: dev\n",
+		GithubInstallationId: int64(41584295),
+		RepoFullName:         "diggerhq/github-job-scheduler",
+		RepoOwner:            "diggerhq",
+		RepoName:             "github-job-scheduler",
+		BatchType:            orchestrator.DiggerCommandPlan,
+	}
+	job := models.DiggerJob{
+		Batch:             &batch,
+		Status:            orchestrator_scheduler.DiggerJobSucceeded,
+		SerializedJobSpec: []byte(`{"projectName":"dev"}`),
+		TerraformOutput:   "No changes.",
+	}
+	err := CompleteCheckRunForJob(gh, &job, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, updates)
+
+	batch.DiggerConfig += "reporting_check_runs: true\n"
+	err = CompleteCheckRunForJob(gh, &job, &terraform_utils.PlanSummary{ResourcesCreated: 1})
+	assert.NoError(t, err)
+	require.Len(t, updates, 2)
+	output := updates[0]["output"].(map[string]interface{})
+	assert.Equal(t, "Plan succeeded: 1 to add, 0 to change, 0 to destroy", output["title"])
+	assert.Equal(t, "```terraform\nNo changes.\n```", output["text"])
+	assert.Equal(t, "success", updates[1]["conclusion"])
+	assert.Len(t, updates[1]["actions"], 2)
+}
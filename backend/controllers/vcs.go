@@ -0,0 +1,497 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/diggerhq/digger/backend/ci_backends"
+	"github.com/diggerhq/digger/backend/locking"
+	"github.com/diggerhq/digger/backend/models"
+	"github.com/diggerhq/digger/backend/segment"
+	"github.com/diggerhq/digger/backend/utils"
+	comment_updater "github.com/diggerhq/digger/libs/comment_utils/reporting"
+	dg_configuration "github.com/diggerhq/digger/libs/digger_config"
+	dg_locking "github.com/diggerhq/digger/libs/locking"
+	"github.com/diggerhq/digger/libs/notifications"
+	"github.com/diggerhq/digger/libs/orchestrator"
+	dg_github "github.com/diggerhq/digger/libs/orchestrator/github"
+	"github.com/dominikbraun/graph"
+	"github.com/google/go-github/v61/github"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+)
+
+// vcsRepo is the repository an event was sent for, events of other VCSs are converted to GitHub events to share
+// the orchestrator
+type vcsRepo struct {
+	VCS            models.DiggerVCSType
+	OrganisationId uint
+	Owner          string
+	Name           string
+	FullName       string
+	CloneUrl       string
+	// username the VCS expects with access tokens when cloning
+	CloneUser string
+	Token     string
+	PrService orchestrator.PullRequestService
+	// only set for GitHub
+	GithubClientProvider utils.GithubClientProvider
+	GithubInstallationId int64
+}
+
+// getVCSOrganisationId returns the organisation of GitLab and Bitbucket webhooks. They are single-tenant, all of
+// their repositories belong to the default organisation and are accessed with the GITLAB_TOKEN and BITBUCKET_TOKEN
+// of the backend
+func getVCSOrganisationId() (uint, error) {
+	org, err := models.DB.GetOrganisation(models.DEFAULT_ORG_NAME)
+	if err != nil {
+		return 0, fmt.Errorf("could not get organisation: %v", err)
+	}
+	if org == nil {
+		return 0, fmt.Errorf("organisation %v not found", models.DEFAULT_ORG_NAME)
+	}
+	return org.ID, nil
+}
+
+func getDiggerConfigForVCSBranch(repo vcsRepo, branch string, prNumber int) (string, *dg_configuration.DiggerConfig, graph.Graph[string, dg_configuration.Project], error) {
+	var config *dg_configuration.DiggerConfig
+	var diggerYmlStr string
+	var dependencyGraph graph.Graph[string, dg_configuration.Project]
+
+	changedFiles, err := repo.PrService.GetChangedFiles(prNumber)
+	if err != nil {
+		log.Printf("Error getting changed files: %v", err)
+		return "", nil, nil, fmt.Errorf("error getting changed files")
+	}
+	err = utils.CloneGitRepoAsUserAndDoAction(repo.CloneUrl, branch, repo.CloneUser, repo.Token, func(dir string) error {
+		diggerYmlBytes, err := os.ReadFile(path.Join(dir, "mantis.yml"))
+		diggerYmlStr = string(diggerYmlBytes)
+		config, _, dependencyGraph, err = dg_configuration.LoadDiggerConfig(dir, true, changedFiles)
+		if err != nil {
+			log.Printf("Error loading digger config: %v", err)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Error cloning and loading config: %v", err)
+		return "", nil, nil, fmt.Errorf("error cloning and loading config")
+	}
+
+	log.Printf("Digger config loadded successfully\n")
+	return diggerYmlStr, config, dependencyGraph, nil
+}
+
+// lockVCSProjects performs locking and unlocking of the impacted projects in the backend
+func lockVCSProjects(repo vcsRepo, prNumber int, impactedProjects []dg_configuration.Project, diggerCommand orchestrator.DiggerCommand, notifier notifications.Notifier) error {
+	for _, project := range impactedProjects {
+		prLock := dg_locking.PullRequestLock{
+			InternalLock: locking.BackendDBLock{
+				OrgId: repo.OrganisationId,
+			},
+			CIService:        repo.PrService,
+			Reporter:         comment_updater.NoopReporter{},
+			Notifier:         notifier,
+			ProjectName:      project.Name,
+			ProjectNamespace: repo.FullName,
+			PrNumber:         prNumber,
+		}
+		if project.EphemeralWorkspace {
+			prLock.EphemeralWorkspace = dg_configuration.EphemeralWorkspaceName(prNumber)
+		}
+		err := PerformLockingActionFromCommand(prLock, diggerCommand)
+		if err != nil {
+			return fmt.Errorf("failed to perform lock action on project: %v, %v", project.Name, err)
+		}
+	}
+	return nil
+}
+
+// startVCSCheckRuns creates the check runs of the jobs with the GitHub App when the repository reports check runs.
+// Buttons of check runs created with the GITHUB_TOKEN of the workflows would not reach the App
+func startVCSCheckRuns(repo vcsRepo, config *dg_configuration.DiggerConfig, prNumber int, jobs []orchestrator.Job) {
+	if repo.VCS != models.DiggerVCSGithub || !config.ReportingCheckRuns {
+		return
+	}
+	checksService, err := utils.GetGithubCheckRunsService(repo.GithubClientProvider, repo.GithubInstallationId, repo.FullName, repo.Owner, repo.Name)
+	if err != nil {
+		log.Printf("Could not get check runs service: %v", err)
+		return
+	}
+	err = utils.StartCheckRunsForJobs(checksService, prNumber, jobs)
+	if err != nil {
+		log.Printf("Could not start check runs: %v", err)
+	}
+}
+
+// groupJobsByCommand returns the commands of the jobs in the order of their first job with the jobs running them
+func groupJobsByCommand(jobs []orchestrator.Job) ([]orchestrator.DiggerCommand, map[orchestrator.DiggerCommand][]orchestrator.Job, error) {
+	commands := make([]orchestrator.DiggerCommand, 0)
+	jobsByCommand := make(map[orchestrator.DiggerCommand][]orchestrator.Job)
+	for _, job := range jobs {
+		command, err := orchestrator.GetCommandFromJob(job)
+		if err != nil {
+			return nil, nil, fmt.Errorf("job of project %v: %v", job.ProjectName, err)
+		}
+		if _, ok := jobsByCommand[*command]; !ok {
+			commands = append(commands, *command)
+		}
+		jobsByCommand[*command] = append(jobsByCommand[*command], job)
+	}
+	return commands, jobsByCommand, nil
+}
+
+// pullRequestLockForJob is the lock of the project of a job, jobs in ephemeral workspaces have locks of their own
+func pullRequestLockForJob(repo vcsRepo, prNumber int, job orchestrator.Job, notifier notifications.Notifier) dg_locking.PullRequestLock {
+	prLock := dg_locking.PullRequestLock{
+		InternalLock: locking.BackendDBLock{
+			OrgId: repo.OrganisationId,
+		},
+		CIService:        repo.PrService,
+		Reporter:         comment_updater.NoopReporter{},
+		Notifier:         notifier,
+		ProjectName:      job.ProjectName,
+		ProjectNamespace: repo.FullName,
+		PrNumber:         prNumber,
+	}
+	if job.EphemeralWorkspace {
+		prLock.EphemeralWorkspace = job.ProjectWorkspace
+	}
+	return prLock
+}
+
+// postInitialSourceComments posts the comments of the group by module render mode and stores them with the batch
+func postInitialSourceComments(repo vcsRepo, batchId *uuid.UUID, prNumber int, impactedProjectsSourceMapping map[string]dg_configuration.ProjectToSourceMapping) error {
+	ghService, ok := repo.PrService.(*dg_github.GithubService)
+	if !ok {
+		log.Printf("comment render mode %v is only supported on GitHub, using the default comments", dg_configuration.CommentRenderModeGroupByModule)
+		return nil
+	}
+	sourceDetails, err := comment_updater.PostInitialSourceComments(ghService, prNumber, impactedProjectsSourceMapping)
+	if err != nil {
+		log.Printf("PostInitialSourceComments error: %v", err)
+		return fmt.Errorf("PostInitialSourceComments error: %v", err)
+	}
+	batch, err := models.DB.GetDiggerBatch(batchId)
+	if err != nil {
+		log.Printf("GetDiggerBatch error: %v", err)
+		return fmt.Errorf("GetDiggerBatch error: %v", err)
+	}
+	batch.SourceDetails, err = json.Marshal(sourceDetails)
+	if err != nil {
+		log.Printf("sourceDetails, json Marshal error: %v", err)
+		return fmt.Errorf("json Marshal error: %v", err)
+	}
+	err = models.DB.UpdateDiggerBatch(batch)
+	if err != nil {
+		log.Printf("UpdateDiggerBatch error: %v", err)
+		return fmt.Errorf("UpdateDiggerBatch error: %v", err)
+	}
+	return nil
+}
+
+// triggerVCSJobs stores the jobs as a batch and triggers the jobs without parents in the CI of the VCS,
+// impactedProjectsSourceMapping is only set when the batch gets group by module comments
+func triggerVCSJobs(repo vcsRepo, ciBackendProvider ci_backends.CiBackendProvider, diggerCommand orchestrator.DiggerCommand, config *dg_configuration.DiggerConfig, projectsGraph graph.Graph[string, dg_configuration.Project], impactedProjects []dg_configuration.Project, impactedProjectsSourceMapping map[string]dg_configuration.ProjectToSourceMapping, jobs []orchestrator.Job, branch string, commitSha string, prNumber int, commentId int64, diggerYmlStr string) error {
+	impactedProjectsMap := make(map[string]dg_configuration.Project)
+	for _, p := range impactedProjects {
+		impactedProjectsMap[p.Name] = p
+	}
+
+	impactedJobsMap := make(map[string]orchestrator.Job)
+	for _, j := range jobs {
+		impactedJobsMap[j.ProjectName] = j
+	}
+
+	batchId, _, err := utils.ConvertJobsToDiggerJobs(repo.VCS, diggerCommand, repo.OrganisationId, impactedJobsMap, impactedProjectsMap, projectsGraph, repo.GithubInstallationId, branch, prNumber, repo.Owner, repo.Name, repo.FullName, commitSha, commentId, diggerYmlStr)
+	if err != nil {
+		log.Printf("ConvertJobsToDiggerJobs error: %v", err)
+		return fmt.Errorf("ConvertJobsToDiggerJobs error: %v", err)
+	}
+
+	if config.CommentRenderMode == dg_configuration.CommentRenderModeGroupByModule && impactedProjectsSourceMapping != nil {
+		err = postInitialSourceComments(repo, batchId, prNumber, impactedProjectsSourceMapping)
+		if err != nil {
+			return err
+		}
+	}
+
+	segment.Track(strconv.Itoa(int(repo.OrganisationId)), "backend_trigger_job")
+
+	ciBackend, err := ciBackendProvider.GetCiBackend(
+		ci_backends.CiBackendOptions{
+			GithubClientProvider: repo.GithubClientProvider,
+			GithubInstallationId: repo.GithubInstallationId,
+			RepoName:             repo.Name,
+			RepoOwner:            repo.Owner,
+			RepoFullName:         repo.FullName,
+			VCS:                  repo.VCS,
+		},
+	)
+	if err != nil {
+		log.Printf("GetCiBackend error: %v", err)
+		return fmt.Errorf("GetCiBackend error: %v", err)
+	}
+
+	err = TriggerDiggerJobs(ciBackend, repo.Owner, repo.Name, batchId, prNumber, repo.PrService)
+	if err != nil {
+		log.Printf("TriggerDiggerJobs error: %v", err)
+		return fmt.Errorf("TriggerDiggerJobs error: %v", err)
+	}
+	return nil
+}
+
+// handleVCSPullRequestEvent runs the jobs of pull and merge request events of all VCSs
+func handleVCSPullRequestEvent(repo vcsRepo, payload *github.PullRequestEvent, ciBackendProvider ci_backends.CiBackendProvider) error {
+	prService := repo.PrService
+	prNumber := *payload.PullRequest.Number
+	isDraft := payload.PullRequest.GetDraft()
+	commitSha := payload.PullRequest.Head.GetSHA()
+	branch := payload.PullRequest.Head.GetRef()
+
+	diggerYmlStr, config, projectsGraph, err := getDiggerConfigForVCSBranch(repo, branch, prNumber)
+	if err != nil {
+		utils.InitCommentReporter(prService, prNumber, fmt.Sprintf(":x: Could not load digger config, error: %v", err))
+		log.Printf("getDiggerConfigForVCSBranch error: %v", err)
+		return fmt.Errorf("error getting digger config")
+	}
+
+	impactedProjects, impactedProjectsSourceMapping, _, err := dg_github.ProcessGitHubPullRequestEvent(payload, config, projectsGraph, prService)
+	if err != nil {
+		log.Printf("Error processing event: %v", err)
+		utils.InitCommentReporter(prService, prNumber, fmt.Sprintf(":x: Error processing event: %v", err))
+		return fmt.Errorf("error processing event")
+	}
+
+	jobsForImpactedProjects, _, err := dg_github.ConvertGithubPullRequestEventToJobs(payload, impactedProjects, nil, *config)
+	if err != nil {
+		log.Printf("Error converting event to jobsForImpactedProjects: %v", err)
+		utils.InitCommentReporter(prService, prNumber, fmt.Sprintf(":x: Error converting event to jobsForImpactedProjects: %v", err))
+		return fmt.Errorf("error converting event to jobsForImpactedProjects")
+	}
+
+	if len(jobsForImpactedProjects) == 0 {
+		// do not report if no projects are impacted to minimise noise in the PR thread
+		// TODO use status checks instead: https://github.com/diggerhq/digger/issues/1135
+		log.Printf("No projects impacted; not starting any jobs")
+		// This one is for aggregate reporting
+		err = utils.SetPRStatusForJobs(prService, prNumber, jobsForImpactedProjects)
+		return nil
+	}
+
+	commands, jobsByCommand, err := groupJobsByCommand(jobsForImpactedProjects)
+	if err != nil {
+		log.Printf("could not determine digger command from jobs: %v", err)
+		utils.InitCommentReporter(prService, prNumber, fmt.Sprintf(":x: could not determine digger command from job: %v", err))
+		return fmt.Errorf("unkown digger command in comment %v", err)
+	}
+
+	// perform locking/unlocking in backend, closed pull requests release their locks whatever their jobs run
+	if config.PrLocks {
+		notifier := notifications.LoadBackend(config.Notifications)
+		for _, command := range commands {
+			if command == orchestrator.DiggerCommandNoop {
+				continue
+			}
+			for _, job := range jobsByCommand[command] {
+				prLock := pullRequestLockForJob(repo, prNumber, job, notifier)
+				if *payload.Action == "closed" {
+					_, err = prLock.Unlock()
+				} else {
+					err = PerformLockingActionFromCommand(prLock, command)
+				}
+				if err != nil {
+					utils.InitCommentReporter(prService, prNumber, fmt.Sprintf(":x: Failed perform lock action on project: %v %v", job.ProjectName, err))
+					return fmt.Errorf("failed to perform lock action on project: %v, %v", job.ProjectName, err)
+				}
+			}
+		}
+	}
+
+	// locking, unlocking and noop commands don't need to trigger any jobs
+	var lockingCommand *orchestrator.DiggerCommand
+	runCommands := make([]orchestrator.DiggerCommand, 0)
+	for _, command := range commands {
+		switch command {
+		case orchestrator.DiggerCommandNoop:
+		case orchestrator.DiggerCommandUnlock, orchestrator.DiggerCommandLock:
+			lockingCommand = lo.ToPtr(command)
+		default:
+			runCommands = append(runCommands, command)
+		}
+	}
+	if len(runCommands) == 0 {
+		if lockingCommand == nil {
+			log.Printf("jobs are of type noop, no actions top perform")
+		} else {
+			utils.InitCommentReporter(prService, prNumber, fmt.Sprintf(":white_check_mark: Command %v completed successfully", *lockingCommand))
+		}
+		return nil
+	}
+
+	if !config.AllowDraftPRs && isDraft {
+		log.Printf("Draft PRs are disabled, skipping PR: %v", prNumber)
+		return nil
+	}
+
+	runJobs := make([]orchestrator.Job, 0)
+	for _, command := range runCommands {
+		runJobs = append(runJobs, jobsByCommand[command]...)
+	}
+	err = utils.SetPRStatusForJobs(prService, prNumber, runJobs)
+	if err != nil {
+		log.Printf("error setting status for PR: %v", err)
+		utils.InitCommentReporter(prService, prNumber, fmt.Sprintf(":x: error setting status for PR: %v", err))
+	}
+	startVCSCheckRuns(repo, config, prNumber, runJobs)
+
+	// each command runs in a batch of its own, e.g. the teardown of ephemeral workspaces and the apply after a merge
+	for _, command := range runCommands {
+		jobs := jobsByCommand[command]
+		commentReporter, err := utils.InitCommentReporter(prService, prNumber, ":construction_worker: Digger starting...")
+		if err != nil {
+			log.Printf("Error initializing comment reporter: %v", err)
+			return fmt.Errorf("error initializing comment reporter")
+		}
+
+		err = utils.ReportInitialJobsStatus(commentReporter, jobs)
+		if err != nil {
+			log.Printf("Failed to comment initial status for jobs: %v", err)
+			utils.InitCommentReporter(prService, prNumber, fmt.Sprintf(":x: Failed to comment initial status for jobs: %v", err))
+			return fmt.Errorf("failed to comment initial status for jobs")
+		}
+
+		err = triggerVCSJobs(repo, ciBackendProvider, command, config, projectsGraph, impactedProjects, impactedProjectsSourceMapping, jobs, branch, commitSha, prNumber, commentReporter.CommentId, diggerYmlStr)
+		if err != nil {
+			utils.InitCommentReporter(prService, prNumber, fmt.Sprintf(":x: %v", err))
+			return fmt.Errorf("error triggerring Digger Jobs")
+		}
+	}
+	return nil
+}
+
+// handleVCSIssueCommentEvent runs the jobs of mantis comments on pull and merge requests of all VCSs
+func handleVCSIssueCommentEvent(repo vcsRepo, payload *github.IssueCommentEvent, ciBackendProvider ci_backends.CiBackendProvider) error {
+	prService := repo.PrService
+	issueNumber := *payload.Issue.Number
+	isDraft := payload.Issue.GetDraft()
+	commentId := payload.GetComment().GetID()
+
+	if *payload.Action != "created" {
+		log.Printf("comment is not of type 'created', ignoring")
+		return nil
+	}
+
+	if !strings.HasPrefix(*payload.Comment.Body, "mantis") {
+		log.Printf("comment is not a mantis command, ignoring")
+		return nil
+	}
+
+	branch, commitSha, err := prService.GetBranchName(issueNumber)
+	if err != nil {
+		log.Printf("GetBranchName error: %v", err)
+		utils.InitCommentReporter(prService, issueNumber, fmt.Sprintf(":x: GetBranchName error: %v", err))
+		return fmt.Errorf("error while fetching branch name")
+	}
+
+	diggerYmlStr, config, projectsGraph, err := getDiggerConfigForVCSBranch(repo, branch, issueNumber)
+	if err != nil {
+		utils.InitCommentReporter(prService, issueNumber, fmt.Sprintf(":x: Could not load digger config, error: %v", err))
+		log.Printf("getDiggerConfigForVCSBranch error: %v", err)
+		return fmt.Errorf("error getting mantis config")
+	}
+
+	// commands requested from check run buttons have no comment to react to
+	if commentId != 0 {
+		err = prService.CreateCommentReaction(commentId, string(dg_github.GithubCommentEyesReaction))
+		if err != nil {
+			log.Printf("CreateCommentReaction error: %v", err)
+		}
+	}
+
+	if !config.AllowDraftPRs && isDraft {
+		log.Printf("AllowDraftPRs is disabled, skipping PR: %v", issueNumber)
+		return nil
+	}
+
+	commentReporter, err := utils.InitCommentReporter(prService, issueNumber, ":construction_worker: Digger starting....")
+	if err != nil {
+		log.Printf("Error initializing comment reporter: %v", err)
+		return fmt.Errorf("error initializing comment reporter")
+	}
+
+	diggerCommand, err := orchestrator.GetCommandFromComment(*payload.Comment.Body)
+	if err != nil {
+		log.Printf("unkown mantis command in comment: %v", *payload.Comment.Body)
+		utils.InitCommentReporter(prService, issueNumber, fmt.Sprintf(":x: Could not recognise comment, error: %v", err))
+		return fmt.Errorf("unkown mantis command in comment %v", err)
+	}
+
+	impactedProjects, impactedProjectsSourceMapping, requestedProject, _, err := dg_github.ProcessGitHubIssueCommentEvent(payload, config, projectsGraph, prService)
+	if err != nil {
+		log.Printf("Error processing event: %v", err)
+		utils.InitCommentReporter(prService, issueNumber, fmt.Sprintf(":x: Error processing event: %v", err))
+		return fmt.Errorf("error processing event")
+	}
+	log.Printf("IssueComment event processed successfully\n")
+
+	// perform locking/unlocking in backend
+	if config.PrLocks {
+		err = lockVCSProjects(repo, issueNumber, impactedProjects, *diggerCommand, notifications.LoadBackend(config.Notifications))
+		if err != nil {
+			utils.InitCommentReporter(prService, issueNumber, fmt.Sprintf(":x: %v", err))
+			return err
+		}
+	}
+
+	// if commands are locking or unlocking we don't need to trigger any jobs
+	if *diggerCommand == orchestrator.DiggerCommandUnlock ||
+		*diggerCommand == orchestrator.DiggerCommandLock {
+		utils.InitCommentReporter(prService, issueNumber, fmt.Sprintf(":white_check_mark: Command %v completed successfully", *diggerCommand))
+		return nil
+	}
+
+	jobs, _, err := dg_github.ConvertGithubIssueCommentEventToJobs(payload, impactedProjects, requestedProject, config.Workflows, branch)
+	if err != nil {
+		log.Printf("Error converting event to jobs: %v", err)
+		utils.InitCommentReporter(prService, issueNumber, fmt.Sprintf(":x: Error converting event to jobs: %v", err))
+		return fmt.Errorf("error converting event to jobs")
+	}
+	log.Printf("IssueComment event converted to Jobs successfully\n")
+
+	err = utils.ReportInitialJobsStatus(commentReporter, jobs)
+	if err != nil {
+		log.Printf("Failed to comment initial status for jobs: %v", err)
+		utils.InitCommentReporter(prService, issueNumber, fmt.Sprintf(":x: Failed to comment initial status for jobs: %v", err))
+		return fmt.Errorf("failed to comment initial status for jobs")
+	}
+
+	err = utils.SetPRStatusForJobs(prService, issueNumber, jobs)
+	if err != nil {
+		log.Printf("error setting status for PR: %v", err)
+		utils.InitCommentReporter(prService, issueNumber, fmt.Sprintf(":x: error setting status for PR: %v", err))
+	}
+	startVCSCheckRuns(repo, config, issueNumber, jobs)
+
+	if len(jobs) == 0 {
+		log.Printf("no projects impacated, succeeding")
+		return nil
+	}
+
+	// only plans and applies get group by module comments
+	if *diggerCommand != orchestrator.DiggerCommandPlan && *diggerCommand != orchestrator.DiggerCommandApply {
+		impactedProjectsSourceMapping = nil
+	}
+
+	err = triggerVCSJobs(repo, ciBackendProvider, *diggerCommand, config, projectsGraph, impactedProjects, impactedProjectsSourceMapping, jobs, branch, commitSha, issueNumber, commentReporter.CommentId, diggerYmlStr)
+	if err != nil {
+		utils.InitCommentReporter(prService, issueNumber, fmt.Sprintf(":x: %v", err))
+		return fmt.Errorf("error triggerring Digger Jobs")
+	}
+	return nil
+}
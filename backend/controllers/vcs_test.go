@@ -0,0 +1,35 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/diggerhq/digger/libs/notifications"
+	"github.com/diggerhq/digger/libs/orchestrator"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupJobsByCommand(t *testing.T) {
+	jobs := []orchestrator.Job{
+		{ProjectName: "dev", ProjectWorkspace: "pr-7", EphemeralWorkspace: true, Commands: []string{"mantis destroy", "mantis delete-workspace"}},
+		{ProjectName: "dev", ProjectWorkspace: "default", Commands: []string{"mantis apply"}},
+		{ProjectName: "prod", ProjectWorkspace: "default", Commands: []string{"mantis apply"}},
+	}
+	commands, jobsByCommand, err := groupJobsByCommand(jobs)
+	assert.NoError(t, err)
+	assert.Equal(t, []orchestrator.DiggerCommand{orchestrator.DiggerCommandDestroy, orchestrator.DiggerCommandApply}, commands)
+	assert.Equal(t, 1, len(jobsByCommand[orchestrator.DiggerCommandDestroy]))
+	assert.Equal(t, 2, len(jobsByCommand[orchestrator.DiggerCommandApply]))
+
+	_, _, err = groupJobsByCommand([]orchestrator.Job{{ProjectName: "dev", Commands: []string{"echo"}}})
+	assert.Error(t, err)
+}
+
+func TestPullRequestLockForJob(t *testing.T) {
+	repo := vcsRepo{FullName: "org/repo", OrganisationId: 1}
+	prLock := pullRequestLockForJob(repo, 7, orchestrator.Job{ProjectName: "dev", ProjectWorkspace: "pr-7", EphemeralWorkspace: true}, notifications.NoopNotifier{})
+	assert.Equal(t, "org/repo#dev#pr-7", prLock.LockId())
+
+	prLock = pullRequestLockForJob(repo, 7, orchestrator.Job{ProjectName: "dev", ProjectWorkspace: "default"}, notifications.NoopNotifier{})
+	assert.Equal(t, "org/repo#dev", prLock.LockId())
+	assert.Equal(t, notifications.NoopNotifier{}, prLock.Notifier)
+}
@@ -24,6 +24,7 @@ func HttpBasicWebAuth() gin.HandlerFunc {
 			username: password,
 		})(c)
 		c.Set(ACCESS_LEVEL_KEY, models.AdminPolicyType)
+		c.Set(USER_KEY, username)
 		setDefaultOrganisationId(c)
 		c.Next()
 	}
@@ -54,6 +55,7 @@ func HttpBasicApiAuth() gin.HandlerFunc {
 				username: password,
 			})(c)
 			c.Set(ACCESS_LEVEL_KEY, models.AdminPolicyType)
+			c.Set(USER_KEY, username)
 			setDefaultOrganisationId(c)
 			c.Next()
 			return
@@ -80,10 +82,12 @@ func HttpBasicApiAuth() gin.HandlerFunc {
 			} else {
 				setDefaultOrganisationId(c)
 				c.Set(ACCESS_LEVEL_KEY, jobToken.Type)
+				c.Set(USER_KEY, tokenUser("job-token", jobToken.ID))
 			}
 		} else if token == os.Getenv("BEARER_AUTH_TOKEN") {
 			setDefaultOrganisationId(c)
 			c.Set(ACCESS_LEVEL_KEY, models.AdminPolicyType)
+			c.Set(USER_KEY, "bearer-token")
 			c.Next()
 		} else {
 			c.String(http.StatusForbidden, "Invalid Bearer token")
@@ -39,6 +39,9 @@ func SetContextParameters(c *gin.Context, auth services.Auth, token *jwt.Token)
 		}
 
 		c.Set(ORGANISATION_ID_KEY, org.ID)
+		if sub, ok := claims["sub"].(string); ok {
+			c.Set(USER_KEY, sub)
+		}
 
 		segment.GetClient()
 		segment.IdentifyClient(strconv.Itoa(int(org.ID)), org.Name, org.Name, org.Name, org.Name, strconv.Itoa(int(org.ID)), "")
@@ -206,6 +209,7 @@ func JWTBearerTokenAuth(auth services.Auth) gin.HandlerFunc {
 			} else {
 				c.Set(ORGANISATION_ID_KEY, jobToken.OrganisationID)
 				c.Set(ACCESS_LEVEL_KEY, jobToken.Type)
+				c.Set(USER_KEY, tokenUser("job-token", jobToken.ID))
 			}
 		} else if strings.HasPrefix(token, "t:") {
 			var dbToken models.Token
@@ -225,6 +229,7 @@ func JWTBearerTokenAuth(auth services.Auth) gin.HandlerFunc {
 			}
 			c.Set(ORGANISATION_ID_KEY, dbToken.OrganisationID)
 			c.Set(ACCESS_LEVEL_KEY, dbToken.Type)
+			c.Set(USER_KEY, tokenUser("api-token", token.ID))
 		} else {
 			jwtPublicKey := os.Getenv("JWT_PUBLIC_KEY")
 			if jwtPublicKey == "" {
@@ -309,3 +314,6 @@ func CORSMiddleware() gin.HandlerFunc {
 
 const ORGANISATION_ID_KEY = "organisation_ID"
 const ACCESS_LEVEL_KEY = "access_level"
+
+// USER_KEY identifies who made the request, requests authenticated with a token of no user are identified by the token
+const USER_KEY = "user"
@@ -79,3 +79,8 @@ func CheckJobToken(c *gin.Context, token string) (*models.JobToken, error) {
 	log.Printf("Token: %v access level: %v", jobToken.Value, jobToken.Type)
 	return jobToken, nil
 }
+
+// tokenUser identifies requests authenticated with a token that belongs to no user, e.g. job-token:12
+func tokenUser(kind string, id uint) string {
+	return fmt.Sprintf("%v:%v", kind, id)
+}
@@ -9,6 +9,7 @@ func NoopWebAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		setDefaultOrganisationId(c)
 		c.Set(ACCESS_LEVEL_KEY, models.AdminPolicyType)
+		c.Set(USER_KEY, "anonymous")
 		c.Next()
 	}
 }
@@ -17,6 +18,7 @@ func NoopApiAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		setDefaultOrganisationId(c)
 		c.Set(ACCESS_LEVEL_KEY, models.AdminPolicyType)
+		c.Set(USER_KEY, "anonymous")
 		c.Next()
 	}
 }
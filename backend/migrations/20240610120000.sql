@@ -0,0 +1,18 @@
+-- Create "stored_plans" table
+CREATE TABLE "public"."stored_plans" (
+  "id" bigserial NOT NULL,
+  "created_at" timestamptz NULL,
+  "updated_at" timestamptz NULL,
+  "deleted_at" timestamptz NULL,
+  "organisation_id" bigint NULL,
+  "repo" text NULL,
+  "name" text NULL,
+  "size" bigint NULL,
+  "content" bytea NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "fk_stored_plans_organisation" FOREIGN KEY ("organisation_id") REFERENCES "public"."organisations" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION
+);
+-- Create index "idx_stored_plan_name" to table: "stored_plans"
+CREATE UNIQUE INDEX "idx_stored_plan_name" ON "public"."stored_plans" ("organisation_id", "repo", "name");
+-- Create index "idx_stored_plans_deleted_at" to table: "stored_plans"
+CREATE INDEX "idx_stored_plans_deleted_at" ON "public"."stored_plans" ("deleted_at");
@@ -0,0 +1,54 @@
+-- Modify "policies" table
+ALTER TABLE "public"."policies" ADD COLUMN "revision" bigint NULL;
+-- Create "policy_revisions" table
+CREATE TABLE "public"."policy_revisions" (
+  "id" bigserial NOT NULL,
+  "created_at" timestamptz NULL,
+  "updated_at" timestamptz NULL,
+  "deleted_at" timestamptz NULL,
+  "policy_id" bigint NULL,
+  "revision" bigint NULL,
+  "content" text NULL,
+  "content_hash" text NULL,
+  "diff" text NULL,
+  "author" text NULL,
+  "restored_from" bigint NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "fk_policy_revisions_policy" FOREIGN KEY ("policy_id") REFERENCES "public"."policies" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION
+);
+-- Create index "idx_policy_revision" to table: "policy_revisions"
+CREATE UNIQUE INDEX "idx_policy_revision" ON "public"."policy_revisions" ("policy_id", "revision");
+-- Create index "idx_policy_revisions_content_hash" to table: "policy_revisions"
+CREATE INDEX "idx_policy_revisions_content_hash" ON "public"."policy_revisions" ("content_hash");
+-- Create index "idx_policy_revisions_deleted_at" to table: "policy_revisions"
+CREATE INDEX "idx_policy_revisions_deleted_at" ON "public"."policy_revisions" ("deleted_at");
+-- Record the current content of existing policies as their first revision
+INSERT INTO "public"."policy_revisions" ("created_at", "updated_at", "policy_id", "revision", "content", "content_hash", "diff", "author")
+SELECT now(), now(), "id", 1, "policy", encode(sha256(convert_to(coalesce("policy", ''), 'UTF8')), 'hex'), '', ''
+FROM "public"."policies" WHERE "deleted_at" IS NULL;
+UPDATE "public"."policies" SET "revision" = 1 WHERE "deleted_at" IS NULL;
+-- Create "policy_decisions" table
+CREATE TABLE "public"."policy_decisions" (
+  "id" bigserial NOT NULL,
+  "created_at" timestamptz NULL,
+  "updated_at" timestamptz NULL,
+  "deleted_at" timestamptz NULL,
+  "organisation_id" bigint NULL,
+  "repo" text NULL,
+  "project" text NULL,
+  "type" text NULL,
+  "action" text NULL,
+  "requested_by" text NULL,
+  "input_hash" text NULL,
+  "policy_hash" text NULL,
+  "policy_revision_id" bigint NULL,
+  "allowed" boolean NULL,
+  "violations" text NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "fk_policy_decisions_organisation" FOREIGN KEY ("organisation_id") REFERENCES "public"."organisations" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION,
+  CONSTRAINT "fk_policy_decisions_policy_revision" FOREIGN KEY ("policy_revision_id") REFERENCES "public"."policy_revisions" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION
+);
+-- Create index "idx_policy_decision" to table: "policy_decisions"
+CREATE INDEX "idx_policy_decision" ON "public"."policy_decisions" ("organisation_id", "repo", "project");
+-- Create index "idx_policy_decisions_deleted_at" to table: "policy_decisions"
+CREATE INDEX "idx_policy_decisions_deleted_at" ON "public"."policy_decisions" ("deleted_at");
@@ -0,0 +1,3 @@
+-- Modify "digger_batches" table
+ALTER TABLE "public"."digger_batches" ADD COLUMN "vcs" text NULL;
+UPDATE "public"."digger_batches" SET "vcs" = 'github';
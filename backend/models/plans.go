@@ -0,0 +1,14 @@
+package models
+
+import "gorm.io/gorm"
+
+// StoredPlan is a plan file uploaded by a job, Content is empty when plans are kept on disk
+type StoredPlan struct {
+	gorm.Model
+	Organisation   *Organisation
+	OrganisationID uint   `gorm:"uniqueIndex:idx_stored_plan_name"`
+	Repo           string `gorm:"uniqueIndex:idx_stored_plan_name"`
+	Name           string `gorm:"uniqueIndex:idx_stored_plan_name"`
+	Size           int
+	Content        []byte
+}
@@ -1,6 +1,11 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"gorm.io/gorm"
+)
 
 const (
 	POLICY_TYPE_ACCESS = "access"
@@ -20,4 +25,46 @@ type Policy struct {
 	OrganisationID uint
 	Repo           *Repo
 	RepoID         *uint
+	// Revision is the number of the latest PolicyRevision
+	Revision int
+}
+
+// PolicyRevision is an immutable copy of a policy, one is recorded every time the policy changes
+type PolicyRevision struct {
+	gorm.Model
+	Policy      *Policy
+	PolicyID    uint `gorm:"uniqueIndex:idx_policy_revision"`
+	Revision    int  `gorm:"uniqueIndex:idx_policy_revision"`
+	Content     string
+	ContentHash string `gorm:"index"`
+	// Diff is a unified diff against the previous revision
+	Diff   string
+	Author string
+	// RestoredFrom is the revision a rollback restored
+	RestoredFrom *int
+}
+
+// PolicyDecision is the outcome of a policy evaluated by a job
+type PolicyDecision struct {
+	gorm.Model
+	Organisation     *Organisation
+	OrganisationID   uint   `gorm:"index:idx_policy_decision"`
+	Repo             string `gorm:"index:idx_policy_decision"`
+	Project          string `gorm:"index:idx_policy_decision"`
+	Type             string
+	Action           string
+	RequestedBy      string
+	InputHash        string
+	PolicyHash       string
+	PolicyRevision   *PolicyRevision
+	PolicyRevisionID *uint
+	Allowed          bool
+	// Violations is a JSON encoded list of messages
+	Violations string
+}
+
+// PolicyContentHash identifies the content of a policy, jobs report it with their decisions
+func PolicyContentHash(content string) string {
+	hash := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(hash[:])
 }
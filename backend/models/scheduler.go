@@ -17,6 +17,14 @@ type DiggerJobParentLink struct {
 	ParentDiggerJobId string `gorm:"size:50,index:idx_parent_digger_job_id"`
 }
 
+type DiggerVCSType string
+
+const (
+	DiggerVCSGithub    DiggerVCSType = "github"
+	DiggerVCSGitlab    DiggerVCSType = "gitlab"
+	DiggerVCSBitbucket DiggerVCSType = "bitbucket"
+)
+
 type DiggerBatch struct {
 	ID                   uuid.UUID `gorm:"primary_key"`
 	PrNumber             int
@@ -29,6 +37,8 @@ type DiggerBatch struct {
 	RepoOwner            string
 	RepoName             string
 	BatchType            orchestrator.DiggerCommand
+	// the VCS the pull request lives in, empty for batches created before other VCSs were supported
+	VCS DiggerVCSType
 	// used for module source grouping comments
 	SourceDetails []byte
 }
@@ -104,6 +114,15 @@ func (j *DiggerJob) MapToJsonStruct() (orchestrator_scheduler.SerializedJob, err
 		ResourcesDeleted: j.DiggerJobSummary.ResourcesDeleted,
 	}, nil
 }
+
+// GetVCS returns github for batches created before the VCS was recorded
+func (b *DiggerBatch) GetVCS() DiggerVCSType {
+	if b.VCS == "" {
+		return DiggerVCSGithub
+	}
+	return b.VCS
+}
+
 func (b *DiggerBatch) MapToJsonStruct() (orchestrator_scheduler.SerializedBatch, error) {
 	res := orchestrator_scheduler.SerializedBatch{
 		ID:           b.ID.String(),
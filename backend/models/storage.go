@@ -9,10 +9,13 @@ import (
 	scheduler "github.com/diggerhq/digger/libs/orchestrator/scheduler"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/pmezard/go-difflib/difflib"
 	"github.com/samber/lo"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -411,6 +414,17 @@ func (db *Database) GetGithubAppInstallations(installationId int64) ([]GithubApp
 	return installations, nil
 }
 
+func (db *Database) GetActiveGithubAppInstallations() ([]GithubAppInstallation, error) {
+	var installations []GithubAppInstallation
+	result := db.GormDB.Where("status=?", GithubAppInstallActive).Find(&installations)
+	if result.Error != nil {
+		if !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, result.Error
+		}
+	}
+	return installations, nil
+}
+
 // GetGithubAppInstallationLink repoFullName should be in the following format: org/repo_name, for example "diggerhq/github-job-scheduler"
 func (db *Database) GetGithubAppInstallationLink(installationId int64) (*GithubAppInstallationLink, error) {
 	var link GithubAppInstallationLink
@@ -587,10 +601,11 @@ func (db *Database) GetDiggerBatch(batchId *uuid.UUID) (*DiggerBatch, error) {
 	return batch, nil
 }
 
-func (db *Database) CreateDiggerBatch(githubInstallationId int64, repoOwner string, repoName string, repoFullname string, PRNumber int, diggerConfig string, branchName string, batchType orchestrator.DiggerCommand, commentId *int64) (*DiggerBatch, error) {
+func (db *Database) CreateDiggerBatch(vcs DiggerVCSType, githubInstallationId int64, repoOwner string, repoName string, repoFullname string, PRNumber int, diggerConfig string, branchName string, batchType orchestrator.DiggerCommand, commentId *int64) (*DiggerBatch, error) {
 	uid := uuid.New()
 	batch := &DiggerBatch{
 		ID:                   uid,
+		VCS:                  vcs,
 		GithubInstallationId: githubInstallationId,
 		RepoOwner:            repoOwner,
 		RepoName:             repoName,
@@ -879,6 +894,20 @@ func (db *Database) UpdateDiggerJob(job *DiggerJob) error {
 	return nil
 }
 
+// UpdateDiggerJobStatus moves the job to status unless another request changed its status first, it returns whether
+// this call made the transition
+func (db *Database) UpdateDiggerJobStatus(job *DiggerJob, status scheduler.DiggerJobStatus) (bool, error) {
+	if job.Status == status {
+		return false, nil
+	}
+	result := db.GormDB.Model(&DiggerJob{}).Where("id = ? AND status = ?", job.ID, job.Status).Update("status", status)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	job.Status = status
+	return result.RowsAffected == 1, nil
+}
+
 func (db *Database) GetDiggerJobsForBatch(batchId uuid.UUID) ([]DiggerJob, error) {
 	jobs := make([]DiggerJob, 0)
 
@@ -1224,3 +1253,189 @@ func (db *Database) DeleteDiggerLock(lock *DiggerLock) error {
 	log.Printf("DeleteDiggerLock %v %v has been deleted successfully\n", lock.LockId, lock.Resource)
 	return nil
 }
+
+func (db *Database) UpsertStoredPlan(orgId uint, repo string, name string, content []byte, size int) (*StoredPlan, error) {
+	plan := &StoredPlan{OrganisationID: orgId, Repo: repo, Name: name, Content: content, Size: size}
+	// concurrent uploads of the same plan update one row, idx_stored_plan_name is unique
+	result := db.GormDB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "organisation_id"}, {Name: "repo"}, {Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"updated_at", "content", "size"}),
+	}).Create(plan)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	log.Printf("UpsertStoredPlan (id: %v, repo: %v, name: %v) has been saved successfully\n", plan.ID, repo, name)
+	return plan, nil
+}
+
+func (db *Database) GetStoredPlan(orgId uint, repo string, name string) (*StoredPlan, error) {
+	plan := &StoredPlan{}
+	result := db.GormDB.Where("organisation_id = ? AND repo = ? AND name = ?", orgId, repo, name).First(plan)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return plan, nil
+}
+
+// ListStoredPlans returns the plans of a repo without their contents
+func (db *Database) ListStoredPlans(orgId uint, repo string, prefix string) ([]StoredPlan, error) {
+	var plans []StoredPlan
+	query := db.GormDB.Omit("content").Where("organisation_id = ? AND repo = ?", orgId, repo)
+	if prefix != "" {
+		query = query.Where("name LIKE ? ESCAPE '\\'", strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(prefix)+"%")
+	}
+	result := query.Order("name").Find(&plans)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return plans, nil
+}
+
+func (db *Database) DeleteStoredPlan(plan *StoredPlan) error {
+	// hard delete so that a plan with the same name can be uploaded again
+	result := db.GormDB.Unscoped().Delete(plan)
+	if result.Error != nil {
+		return result.Error
+	}
+	log.Printf("DeleteStoredPlan (id: %v, repo: %v, name: %v) has been deleted successfully\n", plan.ID, plan.Repo, plan.Name)
+	return nil
+}
+
+// SavePolicy stores new content for a policy and records it as a new revision, unchanged content is not recorded
+func (db *Database) SavePolicy(policy *Policy, content string, author string, restoredFrom *int) (*PolicyRevision, error) {
+	var revision *PolicyRevision
+	err := db.GormDB.Transaction(func(tx *gorm.DB) error {
+		previous := ""
+		if policy.ID != 0 {
+			// the row stays locked until the revision is recorded, so concurrent saves get consecutive revisions
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(policy, policy.ID).Error
+			if err != nil {
+				return err
+			}
+			if policy.Revision > 0 && policy.Policy == content {
+				revision = &PolicyRevision{}
+				return tx.Where("policy_id = ? AND revision = ?", policy.ID, policy.Revision).First(revision).Error
+			}
+			previous = policy.Policy
+		}
+
+		policy.Policy = content
+		policy.Revision++
+		if err := tx.Save(policy).Error; err != nil {
+			return err
+		}
+		revision = &PolicyRevision{
+			PolicyID:     policy.ID,
+			Revision:     policy.Revision,
+			Content:      content,
+			ContentHash:  PolicyContentHash(content),
+			Diff:         policyDiff(previous, content, policy.Revision),
+			Author:       author,
+			RestoredFrom: restoredFrom,
+		}
+		return tx.Create(revision).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("SavePolicy (id: %v, type: %v) saved revision %v\n", policy.ID, policy.Type, revision.Revision)
+	return revision, nil
+}
+
+func policyDiff(previous string, content string, revision int) string {
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(previous),
+		B:        difflib.SplitLines(content),
+		FromFile: fmt.Sprintf("revision %v", revision-1),
+		ToFile:   fmt.Sprintf("revision %v", revision),
+		Context:  3,
+	})
+	if err != nil {
+		log.Printf("could not diff policy revision %v: %v", revision, err)
+		return ""
+	}
+	return diff
+}
+
+// GetPolicyRevisions returns the revisions of a policy, newest first
+func (db *Database) GetPolicyRevisions(policyId uint) ([]PolicyRevision, error) {
+	var revisions []PolicyRevision
+	result := db.GormDB.Where("policy_id = ?", policyId).Order("revision desc").Find(&revisions)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return revisions, nil
+}
+
+func (db *Database) GetPolicyRevision(policyId uint, revision int) (*PolicyRevision, error) {
+	policyRevision := &PolicyRevision{}
+	result := db.GormDB.Where("policy_id = ? AND revision = ?", policyId, revision).First(policyRevision)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return policyRevision, nil
+}
+
+// FindPolicyRevisionByHash returns the latest revision of the organisation's policies with the given content, or nil
+func (db *Database) FindPolicyRevisionByHash(orgId uint, policyType string, contentHash string) (*PolicyRevision, error) {
+	var revisions []PolicyRevision
+	result := db.GormDB.Joins("JOIN policies ON policies.id = policy_revisions.policy_id").
+		Where("policies.organisation_id = ? AND policies.type = ? AND policy_revisions.content_hash = ?", orgId, policyType, contentHash).
+		Order("policy_revisions.created_at desc").Limit(1).Find(&revisions)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if len(revisions) == 0 {
+		return nil, nil
+	}
+	return &revisions[0], nil
+}
+
+func (db *Database) CreatePolicyDecision(decision *PolicyDecision) error {
+	result := db.GormDB.Create(decision)
+	if result.Error != nil {
+		return result.Error
+	}
+	log.Printf("CreatePolicyDecision (id: %v, repo: %v, project: %v, type: %v, allowed: %v) has been created successfully\n",
+		decision.ID, decision.Repo, decision.Project, decision.Type, decision.Allowed)
+	return nil
+}
+
+// PolicyDecisionFilter narrows down policy decisions, empty fields match everything
+type PolicyDecisionFilter struct {
+	Repo    string
+	Project string
+	Type    string
+	Allowed *bool
+	Since   *time.Time
+	Limit   int
+}
+
+// GetPolicyDecisions returns the decisions of an organisation, newest first
+func (db *Database) GetPolicyDecisions(orgId uint, filter PolicyDecisionFilter) ([]PolicyDecision, error) {
+	var decisions []PolicyDecision
+	query := db.GormDB.Preload("PolicyRevision").Where("organisation_id = ?", orgId)
+	if filter.Repo != "" {
+		query = query.Where("repo = ?", filter.Repo)
+	}
+	if filter.Project != "" {
+		query = query.Where("project = ?", filter.Project)
+	}
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if filter.Allowed != nil {
+		query = query.Where("allowed = ?", *filter.Allowed)
+	}
+	if filter.Since != nil {
+		query = query.Where("created_at >= ?", *filter.Since)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	result := query.Order("created_at desc").Order("id desc").Find(&decisions)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return decisions, nil
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"github.com/diggerhq/digger/libs/orchestrator"
+	"github.com/diggerhq/digger/libs/orchestrator/scheduler"
 	"github.com/stretchr/testify/assert"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -151,7 +152,7 @@ func TestGetDiggerJobsForBatchPreloadsSummary(t *testing.T) {
 	resourcesUpdated := uint(2)
 	resourcesDeleted := uint(3)
 
-	batch, err := DB.CreateDiggerBatch(123, repoOwner, repoName, repoFullName, prNumber, diggerconfig, branchName, batchType, &commentId)
+	batch, err := DB.CreateDiggerBatch(DiggerVCSGithub, 123, repoOwner, repoName, repoFullName, prNumber, diggerconfig, branchName, batchType, &commentId)
 	assert.NoError(t, err)
 
 	job, err := DB.CreateDiggerJob(batch.ID, []byte(jobSpec), "workflow_file.yml")
@@ -165,3 +166,30 @@ func TestGetDiggerJobsForBatchPreloadsSummary(t *testing.T) {
 	assert.Equal(t, jobssss[0].DiggerJobSummary.ResourcesUpdated, resourcesUpdated)
 	assert.Equal(t, jobssss[0].DiggerJobSummary.ResourcesDeleted, resourcesDeleted)
 }
+
+func TestUpdateDiggerJobStatusTransitionsOnce(t *testing.T) {
+	teardownSuite, _, _ := setupSuite(t)
+	defer teardownSuite(t)
+
+	commentId := int64(123)
+	batch, err := DB.CreateDiggerBatch(DiggerVCSGithub, 123, "test", "test", "test/test", 123, "", "main", orchestrator.DiggerCommandApply, &commentId)
+	assert.NoError(t, err)
+	job, err := DB.CreateDiggerJob(batch.ID, []byte("abc"), "workflow_file.yml")
+	assert.NoError(t, err)
+	// a second report of the job read the job before the first one updated it
+	staleJob := *job
+
+	transitioned, err := DB.UpdateDiggerJobStatus(job, scheduler.DiggerJobFailed)
+	assert.NoError(t, err)
+	assert.True(t, transitioned)
+	transitioned, err = DB.UpdateDiggerJobStatus(&staleJob, scheduler.DiggerJobFailed)
+	assert.NoError(t, err)
+	assert.False(t, transitioned)
+	transitioned, err = DB.UpdateDiggerJobStatus(job, scheduler.DiggerJobFailed)
+	assert.NoError(t, err)
+	assert.False(t, transitioned)
+
+	stored, err := DB.GetDiggerJob(job.DiggerJobID)
+	assert.NoError(t, err)
+	assert.Equal(t, scheduler.DiggerJobFailed, stored.Status)
+}
@@ -6,12 +6,11 @@ import (
 	"github.com/diggerhq/digger/backend/config"
 	"github.com/diggerhq/digger/backend/models"
 	orchestrator_scheduler "github.com/diggerhq/digger/libs/orchestrator/scheduler"
-	"github.com/google/go-github/v61/github"
 	"github.com/google/uuid"
 	"log"
 )
 
-func DiggerJobCompleted(client *github.Client, batchId *uuid.UUID, parentJob *models.DiggerJob, repoOwner string, repoName string, workflowFileName string) error {
+func DiggerJobCompleted(ciBackend ci_backends.CiBackend, batchId *uuid.UUID, parentJob *models.DiggerJob, repoOwner string, repoName string, workflowFileName string) error {
 	log.Printf("DiggerJobCompleted parentJobId: %v", parentJob.DiggerJobID)
 
 	jobLinksForParent, err := models.DB.GetDiggerJobParentLinksByParentId(&parentJob.DiggerJobID)
@@ -44,7 +43,6 @@ func DiggerJobCompleted(client *github.Client, batchId *uuid.UUID, parentJob *mo
 			if err != nil {
 				return err
 			}
-			ciBackend := ci_backends.GithubActionCi{Client: client}
 			ScheduleJob(ciBackend, repoOwner, repoName, batchId, job)
 		}
 
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/diggerhq/digger/backend/models"
+	"github.com/diggerhq/digger/backend/utils"
+	"github.com/diggerhq/digger/libs/storage"
+)
+
+// PruneStoredPlans removes the stored plans of closed pull requests and plans older than PLAN_RETENTION_TTL
+// for every repository the github app is installed in. The plan storage is configured with the same
+// environment variables the digger cli uses, so the task is a no-op when PLAN_UPLOAD_DESTINATION is not set.
+func PruneStoredPlans() {
+	if os.Getenv("PLAN_UPLOAD_DESTINATION") == "" {
+		return
+	}
+	var ttl time.Duration
+	if ttlValue := os.Getenv("PLAN_RETENTION_TTL"); ttlValue != "" {
+		var err error
+		ttl, err = time.ParseDuration(ttlValue)
+		if err != nil {
+			log.Printf("Invalid PLAN_RETENTION_TTL %v: %v", ttlValue, err)
+			return
+		}
+	}
+
+	// the storage is checked once up front, plan storages of each repository only differ in their credentials
+	prNumber := 0
+	planStorage, err := storage.NewPlanStorageFromEnv("", "", "", &prNumber)
+	if err != nil {
+		log.Printf("Failed to create plan storage: %v", err)
+		return
+	}
+	if planStorage == nil {
+		log.Printf("Plan storage %v does not support pruning", os.Getenv("PLAN_UPLOAD_DESTINATION"))
+		return
+	}
+
+	installations, err := models.DB.GetActiveGithubAppInstallations()
+	if err != nil {
+		log.Printf("Failed to get github app installations: %v", err)
+		return
+	}
+	for _, installation := range installations {
+		repoOwner, repoName, found := strings.Cut(installation.Repo, "/")
+		if !found {
+			continue
+		}
+		service, token, err := utils.GetGithubService(&utils.DiggerGithubRealClientProvider{}, installation.GithubInstallationId, installation.Repo, repoOwner, repoName)
+		if err != nil {
+			log.Printf("Failed to get github service for %v: %v", installation.Repo, err)
+			continue
+		}
+		planStorage, err := storage.NewPlanStorageFromEnv(*token, repoOwner, repoName, &prNumber)
+		if err != nil {
+			log.Printf("Failed to create plan storage for %v: %v", installation.Repo, err)
+			continue
+		}
+		pruned, err := storage.PrunePlans(planStorage, storage.PruneOptions{
+			ProjectNamespace:  installation.Repo,
+			TTL:               ttl,
+			PullRequestClosed: service.IsMergedOrClosed,
+			Now:               time.Now(),
+		})
+		if err != nil {
+			log.Printf("Failed to prune plans of %v: %v", installation.Repo, err)
+		}
+		log.Printf("Pruned %v stored plans of %v", len(pruned), installation.Repo)
+	}
+}
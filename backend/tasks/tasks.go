@@ -57,17 +57,27 @@ func main() {
 			repoFullName := batch.RepoFullName
 			repoName := batch.RepoName
 			repoOwner := batch.RepoOwner
-			githubInstallationid := batch.GithubInstallationId
-			service, _, err := utils.GetGithubService(&utils.DiggerGithubRealClientProvider{}, githubInstallationid, repoFullName, repoOwner, repoName)
+			ciBackend, err := ci_backends.DefaultBackendProvider{}.GetCiBackend(ci_backends.CiBackendOptions{
+				GithubClientProvider: &utils.DiggerGithubRealClientProvider{},
+				GithubInstallationId: batch.GithubInstallationId,
+				RepoFullName:         repoFullName,
+				RepoOwner:            repoOwner,
+				RepoName:             repoName,
+				VCS:                  batch.GetVCS(),
+			})
 			if err != nil {
-				log.Printf("Failed to get github service: %v", err)
+				log.Printf("Failed to get ci backend: %v", err)
+				continue
 			}
-
-			ciBackend := ci_backends.GithubActionCi{Client: service.Client}
 			services.ScheduleJob(ciBackend, repoOwner, repoName, &batch.ID, &job)
 		}
 	})
 
+	// Garbage collect stored plans
+	c.AddFunc("0 0 3 * * *", func() {
+		PruneStoredPlans()
+	})
+
 	// Start the Cron job scheduler
 	c.Start()
 
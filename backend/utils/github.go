@@ -31,6 +31,11 @@ func createTempDir() string {
 type action func(string) error
 
 func CloneGitRepoAndDoAction(repoUrl string, branch string, token string, action action) error {
+	return CloneGitRepoAsUserAndDoAction(repoUrl, branch, "x-access-token", token, action)
+}
+
+// CloneGitRepoAsUserAndDoAction clones with the username the VCS expects for token authentication
+func CloneGitRepoAsUserAndDoAction(repoUrl string, branch string, username string, token string, action action) error {
 	dir := createTempDir()
 	cloneOptions := git.CloneOptions{
 		URL:           repoUrl,
@@ -41,7 +46,7 @@ func CloneGitRepoAndDoAction(repoUrl string, branch string, token string, action
 
 	if token != "" {
 		cloneOptions.Auth = &http.BasicAuth{
-			Username: "x-access-token", // anything except an empty string
+			Username: username,
 			Password: token,
 		}
 	}
@@ -160,7 +165,24 @@ func GetGithubService(gh GithubClientProvider, installationId int64, repoFullNam
 	return &ghService, token, nil
 }
 
-func SetPRStatusForJobs(prService *github2.GithubService, prNumber int, jobs []orchestrator.Job) error {
+// GetGithubCheckRunsService reports statuses as check runs of the GitHub App of the installation, they offer plan and
+// apply buttons whose events the App receives
+func GetGithubCheckRunsService(gh GithubClientProvider, installationId int64, repoFullName string, repoOwner string, repoName string) (*github2.CheckRunsService, error) {
+	installation, err := models.DB.GetGithubAppInstallationByIdAndRepo(installationId, repoFullName)
+	if err != nil {
+		log.Printf("Error getting installation: %v", err)
+		return nil, fmt.Errorf("Error getting installation: %v", err)
+	}
+	ghService, _, err := GetGithubService(gh, installationId, repoFullName, repoOwner, repoName)
+	if err != nil {
+		return nil, err
+	}
+	checksService := github2.NewCheckRunsService(*ghService)
+	checksService.AppId = installation.GithubAppId
+	return checksService, nil
+}
+
+func SetPRStatusForJobs(prService orchestrator.PullRequestService, prNumber int, jobs []orchestrator.Job) error {
 	for _, job := range jobs {
 		for _, command := range job.Commands {
 			var err error
@@ -207,6 +229,26 @@ func SetPRStatusForJobs(prService *github2.GithubService, prNumber int, jobs []o
 	return nil
 }
 
+// StartCheckRunsForJobs creates the pending check runs of the plans and applies of the jobs
+func StartCheckRunsForJobs(checksService *github2.CheckRunsService, prNumber int, jobs []orchestrator.Job) error {
+	for _, job := range jobs {
+		for _, command := range job.Commands {
+			var err error
+			switch command {
+			case "mantis plan":
+				err = checksService.SetStatus(prNumber, "pending", job.ProjectName+"/plan")
+			case "mantis apply":
+				err = checksService.SetStatus(prNumber, "pending", job.ProjectName+"/apply")
+			}
+			if err != nil {
+				log.Printf("Error starting check run: %v", err)
+				return fmt.Errorf("error starting check run: %v", err)
+			}
+		}
+	}
+	return nil
+}
+
 func GetWorkflowIdAndUrlFromDiggerJobId(client *github.Client, repoOwner string, repoName string, diggerJobID string) (int64, string, error) {
 	timeFilter := time.Now().Add(-5 * time.Minute)
 	runs, _, err := client.Actions.ListRepositoryWorkflowRuns(context.Background(), repoOwner, repoName, &github.ListWorkflowRunsOptions{
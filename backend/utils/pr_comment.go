@@ -3,18 +3,17 @@ package utils
 import (
 	"fmt"
 	"github.com/diggerhq/digger/libs/orchestrator"
-	github2 "github.com/diggerhq/digger/libs/orchestrator/github"
 	"log"
 	"strconv"
 )
 
 type CommentReporter struct {
 	PrNumber  int
-	PrService *github2.GithubService
+	PrService orchestrator.PullRequestService
 	CommentId int64
 }
 
-func InitCommentReporter(prService *github2.GithubService, prNumber int, commentMessage string) (*CommentReporter, error) {
+func InitCommentReporter(prService orchestrator.PullRequestService, prNumber int, commentMessage string) (*CommentReporter, error) {
 	comment, err := prService.PublishComment(prNumber, commentMessage)
 	if err != nil {
 		return nil, fmt.Errorf("count not initialize comment reporter: %v", err)
@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"fmt"
+	net "net/http"
+	"os"
+
+	"github.com/diggerhq/digger/backend/models"
+	"github.com/diggerhq/digger/libs/orchestrator"
+	"github.com/diggerhq/digger/libs/orchestrator/bitbucket"
+	"github.com/diggerhq/digger/libs/orchestrator/gitlab"
+	go_gitlab "github.com/xanzy/go-gitlab"
+)
+
+// GetGitlabClient returns a client authenticated with GITLAB_TOKEN, GITLAB_BASE_URL is the api url of self-managed instances
+func GetGitlabClient() (*go_gitlab.Client, string, error) {
+	token := os.Getenv("GITLAB_TOKEN")
+	if token == "" {
+		return nil, "", fmt.Errorf("GITLAB_TOKEN is not set")
+	}
+	var options []go_gitlab.ClientOptionFunc
+	if baseUrl := os.Getenv("GITLAB_BASE_URL"); baseUrl != "" {
+		options = append(options, go_gitlab.WithBaseURL(baseUrl))
+	}
+	client, err := go_gitlab.NewClient(token, options...)
+	if err != nil {
+		return nil, "", fmt.Errorf("could not create gitlab client: %v", err)
+	}
+	return client, token, nil
+}
+
+func GetGitlabService(projectPath string) (*gitlab.GitLabService, *string, error) {
+	token := os.Getenv("GITLAB_TOKEN")
+	if token == "" {
+		return nil, nil, fmt.Errorf("GITLAB_TOKEN is not set")
+	}
+	service, err := gitlab.NewGitLabServiceForProject(token, os.Getenv("GITLAB_BASE_URL"), projectPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return service, &token, nil
+}
+
+// GetBitbucketService returns a service authenticated with the BITBUCKET_TOKEN access token
+func GetBitbucketService(workspace string, repoName string) (*bitbucket.BitbucketAPI, *string, error) {
+	token := os.Getenv("BITBUCKET_TOKEN")
+	if token == "" {
+		return nil, nil, fmt.Errorf("BITBUCKET_TOKEN is not set")
+	}
+	return &bitbucket.BitbucketAPI{
+		AuthToken:     token,
+		HttpClient:    net.Client{},
+		RepoWorkspace: workspace,
+		RepoName:      repoName,
+	}, &token, nil
+}
+
+// GetPrServiceForBatch returns the service of the VCS the pull request of the batch lives in
+func GetPrServiceForBatch(gh GithubClientProvider, batch *models.DiggerBatch) (orchestrator.PullRequestService, error) {
+	switch batch.GetVCS() {
+	case models.DiggerVCSGitlab:
+		service, _, err := GetGitlabService(batch.RepoFullName)
+		return service, err
+	case models.DiggerVCSBitbucket:
+		service, _, err := GetBitbucketService(batch.RepoOwner, batch.RepoName)
+		return service, err
+	default:
+		service, _, err := GetGithubService(gh, batch.GithubInstallationId, batch.RepoFullName, batch.RepoOwner, batch.RepoName)
+		return service, err
+	}
+}
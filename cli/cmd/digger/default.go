@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/diggerhq/digger/cli/pkg/digger"
 	"github.com/diggerhq/digger/cli/pkg/drift"
+	"github.com/diggerhq/digger/cli/pkg/gitea"
 	"github.com/diggerhq/digger/cli/pkg/github"
 	"github.com/diggerhq/digger/cli/pkg/policy"
 	"github.com/diggerhq/digger/cli/pkg/usage"
@@ -25,6 +26,9 @@ var defaultCmd = &cobra.Command{
 		case digger.GitHub:
 			logLeader = os.Getenv("GITHUB_ACTOR")
 			github.GitHubCI(lock, policy.PolicyCheckerProviderBasic{}, BackendApi, ReportStrategy, dg_github.GithubServiceProviderBasic{}, comment_updater.CommentUpdaterProviderBasic{}, drift.DriftNotificationProviderBasic{})
+		case digger.Gitea:
+			logLeader = os.Getenv("GITHUB_ACTOR")
+			gitea.GiteaCI(lock, policy.PolicyCheckerProviderBasic{}, BackendApi, ReportStrategy)
 		case digger.None:
 			print("No CI detected.")
 			os.Exit(10)
@@ -5,12 +5,12 @@ import (
 	core_backend "github.com/diggerhq/digger/cli/pkg/core/backend"
 	core_policy "github.com/diggerhq/digger/cli/pkg/core/policy"
 	"github.com/diggerhq/digger/cli/pkg/digger"
-	"github.com/diggerhq/digger/cli/pkg/storage"
 	"github.com/diggerhq/digger/cli/pkg/usage"
 	"github.com/diggerhq/digger/libs/comment_utils/reporting"
 	"github.com/diggerhq/digger/libs/comment_utils/summary"
 	"github.com/diggerhq/digger/libs/digger_config"
 	core_locking "github.com/diggerhq/digger/libs/locking"
+	"github.com/diggerhq/digger/libs/notifications"
 	orchestrator "github.com/diggerhq/digger/libs/orchestrator"
 	"log"
 	"os"
@@ -26,7 +26,7 @@ func exec(actor string, projectName string, repoNamespace string, command string
 
 	}
 
-	planStorage := storage.NewPlanStorage("", "", "", actor, nil)
+	planStorage := digger.NewPlanStorage("", "", "", actor, nil)
 
 	changedFiles, err := prService.GetChangedFiles(prNumber)
 	if err != nil {
@@ -44,7 +44,7 @@ func exec(actor string, projectName string, repoNamespace string, command string
 	}
 
 	jobs = digger.SortedCommandsByDependency(jobs, &dependencyGraph)
-	_, _, err = digger.RunJobs(jobs, prService, orgService, lock, reporter, planStorage, policyChecker, comment_updater.NoopCommentUpdater{}, backendApi, "", false, false, 123, currentDir)
+	_, _, err = digger.RunJobs(jobs, prService, orgService, lock, reporter, planStorage, policyChecker, comment_updater.NoopCommentUpdater{}, backendApi, "", false, false, 123, currentDir, diggerConfig.Hooks, diggerConfig.ProviderCache, digger.LoadCommentTemplates(currentDir), notifications.Load(diggerConfig.Notifications, os.LookupEnv))
 }
 
 /*
@@ -6,6 +6,7 @@ import (
 	"github.com/diggerhq/digger/libs/comment_utils/reporting"
 	comment_updater "github.com/diggerhq/digger/libs/comment_utils/summary"
 	"github.com/diggerhq/digger/libs/locking"
+	"github.com/diggerhq/digger/libs/notifications"
 
 	"github.com/diggerhq/digger/cli/pkg/digger"
 	"github.com/diggerhq/digger/cli/pkg/github/models"
@@ -898,7 +899,7 @@ func TestGitHubNewPullRequestContext(t *testing.T) {
 
 	event := context.Event.(github.PullRequestEvent)
 	jobs, _, err := dggithub.ConvertGithubPullRequestEventToJobs(&event, impactedProjects, requestedProject, diggerConfig)
-	_, _, err = digger.RunJobs(jobs, prManager, prManager, lock, reporter, planStorage, policyChecker, comment_updater.NoopCommentUpdater{}, backendApi, "123", false, false, 1, "dir")
+	_, _, err = digger.RunJobs(jobs, prManager, prManager, lock, reporter, planStorage, policyChecker, comment_updater.NoopCommentUpdater{}, backendApi, "123", false, false, 1, "dir", configuration.Hooks{}, configuration.ProviderCache{Upgrade: true}, nil, notifications.NoopNotifier{})
 
 	assert.NoError(t, err)
 	if err != nil {
@@ -929,7 +930,7 @@ func TestGitHubNewCommentContext(t *testing.T) {
 
 	event := context.Event.(github.IssueCommentEvent)
 	jobs, _, err := dggithub.ConvertGithubIssueCommentEventToJobs(&event, impactedProjects, requestedProject, map[string]configuration.Workflow{}, "prbranch")
-	_, _, err = digger.RunJobs(jobs, prManager, prManager, lock, reporter, planStorage, policyChecker, comment_updater.NoopCommentUpdater{}, backendApi, "123", false, false, 1, "")
+	_, _, err = digger.RunJobs(jobs, prManager, prManager, lock, reporter, planStorage, policyChecker, comment_updater.NoopCommentUpdater{}, backendApi, "123", false, false, 1, "", configuration.Hooks{}, configuration.ProviderCache{Upgrade: true}, nil, notifications.NoopNotifier{})
 	assert.NoError(t, err)
 	if err != nil {
 		log.Println(err)
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	core_backend "github.com/diggerhq/digger/cli/pkg/core/backend"
 	core_policy "github.com/diggerhq/digger/cli/pkg/core/policy"
 	"github.com/diggerhq/digger/cli/pkg/usage"
@@ -29,7 +30,7 @@ var planCmd = &cobra.Command{
 
 		prService, orgService, reporter, err := runConfig.GetServices()
 		if err != nil {
-			usage.ReportErrorAndExit(runConfig.Actor, "Unrecognised reporter: "+runConfig.Reporter, 1)
+			usage.ReportErrorAndExit(runConfig.Actor, fmt.Sprintf("Failed to set up reporter %v: %v", runConfig.Reporter, err), 1)
 		}
 
 		plan(runConfig.Actor, args[0], runConfig.RepoNamespace, runConfig.PRNumber, lock, PolicyChecker, *reporter, *prService, *orgService, BackendApi)
@@ -40,6 +41,10 @@ func init() {
 	flags := []pflag.Flag{
 		{Name: "github-token", Usage: "Github token (for github reporter)"},
 		{Name: "bitbucket-token", Usage: "Bitbucket token (for bitbucket reporter)"},
+		{Name: "gitlab-token", Usage: "GitLab token (for gitlab reporter), defaults to GITLAB_TOKEN"},
+		{Name: "gitlab-base-url", Usage: "GitLab API url of self-managed instances (for gitlab reporter)"},
+		{Name: "azure-token", Usage: "Azure DevOps personal access token (for azure reporter), defaults to AZURE_TOKEN"},
+		{Name: "azure-base-url", Usage: "Azure DevOps organisation url (for azure reporter), e.g. https://dev.azure.com/org"},
 		{Name: "repo-namespace", Usage: "The namespace of this repo"},
 		{Name: "actor", Usage: "The actor of this command"},
 		{Name: "reporter", Usage: "The reporter to use (defaults to stdout)"},
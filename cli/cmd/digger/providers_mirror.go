@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/diggerhq/digger/cli/pkg/core/terraform"
+	"github.com/diggerhq/digger/cli/pkg/usage"
+	"github.com/diggerhq/digger/libs/digger_config"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var vipProvidersMirror *viper.Viper
+
+type ProvidersMirrorConfig struct {
+	Dir       string   `mapstructure:"dir"`
+	Platforms []string `mapstructure:"platform"`
+}
+
+var providersMirrorCmd = &cobra.Command{
+	Use:   "providers-mirror [flags]",
+	Short: "Prepare a filesystem provider mirror",
+	Long:  `Download the providers locked in each project's .terraform.lock.hcl into a filesystem mirror that offline runners can install from`,
+	Run: func(cmd *cobra.Command, args []string) {
+		var mirrorConfig ProvidersMirrorConfig
+		vipProvidersMirror.Unmarshal(&mirrorConfig)
+
+		diggerConfig, _, _, err := digger_config.LoadDiggerConfig("./", false, nil)
+		if err != nil {
+			usage.ReportErrorAndExit("", fmt.Sprintf("Failed to load digger config. %s", err), 1)
+		}
+
+		mirrorDir := mirrorConfig.Dir
+		if mirrorDir == "" {
+			mirrorDir = diggerConfig.ProviderCache.MirrorDir
+		}
+		if mirrorDir == "" {
+			usage.ReportErrorAndExit("", "No mirror directory, set provider_cache.mirror_dir or pass --dir", 1)
+		}
+		platforms := mirrorConfig.Platforms
+		if len(platforms) == 0 {
+			platforms = diggerConfig.ProviderCache.MirrorPlatforms
+		}
+
+		currentDir, err := os.Getwd()
+		if err != nil {
+			usage.ReportErrorAndExit("", fmt.Sprintf("Failed to get current dir. %s", err), 4)
+		}
+		err = terraform.PrepareProviderMirror(diggerConfig.Projects, currentDir, mirrorDir, platforms)
+		if err != nil {
+			usage.ReportErrorAndExit("", fmt.Sprintf("Failed to prepare provider mirror. %s", err), 8)
+		}
+	},
+}
+
+func init() {
+	vipProvidersMirror = viper.New()
+	vipProvidersMirror.SetEnvPrefix("DIGGER")
+	vipProvidersMirror.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
+	vipProvidersMirror.AutomaticEnv()
+
+	providersMirrorCmd.Flags().String("dir", "", "The directory to write the provider mirror to (defaults to provider_cache.mirror_dir)")
+	providersMirrorCmd.Flags().StringSlice("platform", []string{}, "Target platforms to mirror providers for, e.g. linux_amd64")
+	vipProvidersMirror.BindPFlag("dir", providersMirrorCmd.Flags().Lookup("dir"))
+	vipProvidersMirror.BindPFlag("platform", providersMirrorCmd.Flags().Lookup("platform"))
+
+	rootCmd.AddCommand(providersMirrorCmd)
+}
@@ -2,15 +2,19 @@ package main
 
 import (
 	"fmt"
+	"github.com/diggerhq/digger/cli/pkg/azure"
 	"github.com/diggerhq/digger/cli/pkg/backend"
-	"github.com/diggerhq/digger/cli/pkg/bitbucket"
 	core_backend "github.com/diggerhq/digger/cli/pkg/core/backend"
 	core_policy "github.com/diggerhq/digger/cli/pkg/core/policy"
+	"github.com/diggerhq/digger/cli/pkg/reports"
 	"github.com/diggerhq/digger/cli/pkg/utils"
 	"github.com/diggerhq/digger/libs/comment_utils/reporting"
 	locking2 "github.com/diggerhq/digger/libs/locking"
 	"github.com/diggerhq/digger/libs/orchestrator"
+	"github.com/diggerhq/digger/libs/orchestrator/bitbucket"
 	orchestrator_github "github.com/diggerhq/digger/libs/orchestrator/github"
+	"github.com/diggerhq/digger/libs/orchestrator/gitlab"
+	"github.com/diggerhq/digger/libs/storage"
 	"github.com/spf13/cobra"
 	"log"
 	"net/http"
@@ -26,6 +30,10 @@ type RunConfig struct {
 	Actor          string `mapstructure:"actor"`
 	GithubToken    string `mapstructure:"github-token"`
 	BitbucketToken string `mapstructure:"bitbucket-token"`
+	GitlabToken    string `mapstructure:"gitlab-token"`
+	GitlabBaseUrl  string `mapstructure:"gitlab-base-url"`
+	AzureToken     string `mapstructure:"azure-token"`
+	AzureBaseUrl   string `mapstructure:"azure-base-url"`
 }
 
 func (r *RunConfig) GetServices() (*orchestrator.PullRequestService, *orchestrator.OrgService, *reporting.Reporter, error) {
@@ -63,6 +71,43 @@ func (r *RunConfig) GetServices() (*orchestrator.PullRequestService, *orchestrat
 			PrNumber:          r.PRNumber,
 			IsSupportMarkdown: false,
 		}
+	case "gitlab":
+		token := valueOrEnv(r.GitlabToken, "GITLAB_TOKEN")
+		if token == "" {
+			return nil, nil, nil, fmt.Errorf("gitlab reporter requires --gitlab-token or GITLAB_TOKEN")
+		}
+		service, err := gitlab.NewGitLabServiceForProject(token, valueOrEnv(r.GitlabBaseUrl, "GITLAB_BASE_URL"), r.RepoNamespace)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		prService = service
+		orgService = service
+		reporter = &reporting.CiReporter{
+			CiService:         prService,
+			ReportStrategy:    ReportStrategy,
+			PrNumber:          r.PRNumber,
+			IsSupportMarkdown: true,
+		}
+	case "azure":
+		token := valueOrEnv(r.AzureToken, "AZURE_TOKEN")
+		baseUrl := valueOrEnv(r.AzureBaseUrl, "AZURE_BASE_URL")
+		if token == "" || baseUrl == "" {
+			return nil, nil, nil, fmt.Errorf("azure reporter requires --azure-token and --azure-base-url, or AZURE_TOKEN and AZURE_BASE_URL")
+		}
+		// the namespace of Azure Repos is project/repository
+		projectName, repositoryName := utils.ParseRepoNamespace(r.RepoNamespace)
+		service, err := azure.NewAzureReposService(token, baseUrl, projectName, repositoryName)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to create Azure Repos service: %v", err)
+		}
+		prService = service
+		orgService = service
+		reporter = &reporting.CiReporter{
+			CiService:         prService,
+			ReportStrategy:    ReportStrategy,
+			PrNumber:          r.PRNumber,
+			IsSupportMarkdown: true,
+		}
 
 	case "stdout":
 		print("Using Stdout.")
@@ -77,12 +122,22 @@ func (r *RunConfig) GetServices() (*orchestrator.PullRequestService, *orchestrat
 	return &prService, &orgService, &reporter, nil
 }
 
+func valueOrEnv(value string, envName string) string {
+	if value != "" {
+		return value
+	}
+	return os.Getenv(envName)
+}
+
 var PolicyChecker core_policy.Checker
 var BackendApi core_backend.Api
 var ReportStrategy reporting.ReportStrategy
 var lock locking2.Lock
 
 func PreRun(cmd *cobra.Command, args []string) {
+	if reportPath, _ := cmd.Flags().GetString("report-path"); reportPath != "" {
+		reports.SetPath(reportPath)
+	}
 	if cmd.Name() == "run_spec" {
 		return
 	}
@@ -93,16 +148,23 @@ func PreRun(cmd *cobra.Command, args []string) {
 	BackendApi = backend.NewBackendApi(hostName, token)
 	//PolicyChecker = policy.NewPolicyChecker(hostName, orgName, token)
 
+	// the full output of reports that do not fit in a comment is uploaded as a workflow artifact
+	var overflow reporting.OverflowStorage
+	if artifacts := storage.NewArtifactOverflowStorageFromEnv(); artifacts != nil {
+		overflow = artifacts
+	}
 	if os.Getenv("REPORTING_STRATEGY") == "comments_per_run" || os.Getenv("ACCUMULATE_PLANS") == "true" {
 		ReportStrategy = &reporting.CommentPerRunStrategy{
 			TimeOfRun: time.Now(),
+			Overflow:  overflow,
 		}
 	} else if os.Getenv("REPORTING_STRATEGY") == "latest_run_comment" {
 		ReportStrategy = &reporting.LatestRunCommentStrategy{
 			TimeOfRun: time.Now(),
+			Overflow:  overflow,
 		}
 	} else {
-		ReportStrategy = &reporting.MultipleCommentsStrategy{}
+		ReportStrategy = &reporting.MultipleCommentsStrategy{Overflow: overflow}
 	}
 
 	var err error
@@ -125,3 +187,7 @@ var rootCmd = &cobra.Command{
 	Short:            "An open source IaC orchestration tool",
 	PersistentPreRun: PreRun,
 }
+
+func init() {
+	rootCmd.PersistentFlags().String("report-path", "", "Directory to write JSON, JUnit and SARIF job reports to, defaults to DIGGER_REPORT_PATH")
+}
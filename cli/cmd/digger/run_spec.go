@@ -33,12 +33,13 @@ var runSpecCmd = &cobra.Command{
 		}
 		err = spec2.RunSpec(
 			spec,
-			lib_spec.VCSProvider{},
-			lib_spec.JobSpecProvider{},
-			lib_spec.LockProvider{},
-			lib_spec.ReporterProvider{},
-			lib_spec.BackendApiProvider{},
-			lib_spec.PolicyProvider{},
+			spec2.VCSProvider{},
+			spec2.JobSpecProvider{},
+			spec2.LockProvider{},
+			spec2.ReporterProvider{},
+			spec2.BackendApiProvider{},
+			spec2.PolicyProvider{},
+			spec2.PlanStorageProvider{},
 			comment_summary.CommentUpdaterProviderBasic{},
 		)
 		if err != nil {
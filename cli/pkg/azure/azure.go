@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/diggerhq/digger/cli/pkg/utils"
 	digger_config2 "github.com/diggerhq/digger/libs/digger_config"
 	orchestrator "github.com/diggerhq/digger/libs/orchestrator"
 	"github.com/microsoft/azure-devops-go-api/azuredevops"
@@ -174,7 +173,7 @@ func (a *AzureReposService) GetChangedFiles(prNumber int) ([]string, error) {
 }
 
 func (a *AzureReposService) PublishComment(prNumber int, comment string) (*orchestrator.Comment, error) {
-	_, err := a.Client.CreateThread(context.Background(), git.CreateThreadArgs{
+	thread, err := a.Client.CreateThread(context.Background(), git.CreateThreadArgs{
 		Project:       &a.ProjectName,
 		PullRequestId: &prNumber,
 		RepositoryId:  &a.RepositoryId,
@@ -184,7 +183,11 @@ func (a *AzureReposService) PublishComment(prNumber int, comment string) (*orche
 			}},
 		},
 	})
-	return nil, err
+	if err != nil {
+		return nil, err
+	}
+	// EditComment updates whole threads, so threads are what reporters get to edit
+	return &orchestrator.Comment{Id: *thread.Id, Body: &comment}, nil
 }
 
 func (svc *AzureReposService) ListIssues() ([]*orchestrator.Issue, error) {
@@ -339,11 +342,58 @@ func (a *AzureReposService) EditComment(prNumber int, id interface{}, comment st
 	return err
 }
 
+// MaxCommentLength is the Azure DevOps limit for pull request thread comments
+func (a *AzureReposService) MaxCommentLength() int {
+	return 150000
+}
+
 func (a *AzureReposService) CreateCommentReaction(id interface{}, reaction string) error {
 	// TODO implement me
 	return nil
 }
 
+func (a *AzureReposService) GetLabels(prNumber int) ([]string, error) {
+	labels, err := a.Client.GetPullRequestLabels(context.Background(), git.GetPullRequestLabelsArgs{
+		Project:       &a.ProjectName,
+		RepositoryId:  &a.RepositoryId,
+		PullRequestId: &prNumber,
+	})
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0)
+	for _, label := range *labels {
+		if label.Name != nil {
+			names = append(names, *label.Name)
+		}
+	}
+	return names, nil
+}
+
+func (a *AzureReposService) GetPullRequestDetails(prNumber int) (*orchestrator.PullRequestDetails, error) {
+	pullRequest, err := a.Client.GetPullRequestById(context.Background(), git.GetPullRequestByIdArgs{
+		Project:       &a.ProjectName,
+		PullRequestId: &prNumber,
+	})
+	if err != nil {
+		return nil, err
+	}
+	details := &orchestrator.PullRequestDetails{}
+	if pullRequest.CreatedBy != nil && pullRequest.CreatedBy.UniqueName != nil {
+		details.Author = *pullRequest.CreatedBy.UniqueName
+	}
+	if pullRequest.TargetRefName != nil {
+		details.BaseBranch = strings.TrimPrefix(*pullRequest.TargetRefName, "refs/heads/")
+	}
+	if pullRequest.SourceRefName != nil {
+		details.HeadBranch = strings.TrimPrefix(*pullRequest.SourceRefName, "refs/heads/")
+	}
+	if pullRequest.IsDraft != nil {
+		details.Draft = *pullRequest.IsDraft
+	}
+	return details, nil
+}
+
 func (a *AzureReposService) GetBranchName(prNumber int) (string, string, error) {
 	//TODO implement me
 	return "", "", nil
@@ -403,7 +453,7 @@ func ProcessAzureReposEvent(azureEvent interface{}, diggerConfig *digger_config2
 		}
 
 		impactedProjects, _ = diggerConfig.GetModifiedProjects(changedFiles)
-		requestedProject := utils.ParseProjectName(azureEvent.(AzureCommentEvent).Resource.Comment.Content)
+		requestedProject := orchestrator.ParseProjectName(azureEvent.(AzureCommentEvent).Resource.Comment.Content)
 
 		if requestedProject == "" {
 			return impactedProjects, nil, prNumber, nil
@@ -441,6 +491,7 @@ func ConvertAzureEventToCommands(parseAzureContext Azure, impactedProjects []dig
 				ProjectDir:         project.Dir,
 				ProjectWorkspace:   project.Workspace,
 				Terragrunt:         project.Terragrunt,
+				TerragruntRunAll:   project.TerragruntRunAll,
 				OpenTofu:           project.OpenTofu,
 				Commands:           workflow.Configuration.OnPullRequestPushed,
 				ApplyStage:         orchestrator.ToConfigStage(workflow.Apply),
@@ -451,6 +502,7 @@ func ConvertAzureEventToCommands(parseAzureContext Azure, impactedProjects []dig
 				Namespace:          parseAzureContext.BaseUrl + "/" + parseAzureContext.ProjectName,
 				StateEnvVars:       stateEnvVars,
 				CommandEnvVars:     commandEnvVars,
+				EnvAllowlist:       digger_config2.CollectEnvAllowlist(workflow.EnvVars),
 				StateEnvProvider:   StateEnvProvider,
 				CommandEnvProvider: CommandEnvProvider,
 			})
@@ -471,6 +523,7 @@ func ConvertAzureEventToCommands(parseAzureContext Azure, impactedProjects []dig
 				ProjectDir:         project.Dir,
 				ProjectWorkspace:   project.Workspace,
 				Terragrunt:         project.Terragrunt,
+				TerragruntRunAll:   project.TerragruntRunAll,
 				OpenTofu:           project.OpenTofu,
 				Commands:           workflow.Configuration.OnPullRequestClosed,
 				ApplyStage:         orchestrator.ToConfigStage(workflow.Apply),
@@ -481,6 +534,7 @@ func ConvertAzureEventToCommands(parseAzureContext Azure, impactedProjects []dig
 				Namespace:          parseAzureContext.BaseUrl + "/" + parseAzureContext.ProjectName,
 				StateEnvVars:       stateEnvVars,
 				CommandEnvVars:     commandEnvVars,
+				EnvAllowlist:       digger_config2.CollectEnvAllowlist(workflow.EnvVars),
 				StateEnvProvider:   StateEnvProvider,
 				CommandEnvProvider: CommandEnvProvider,
 			})
@@ -501,6 +555,7 @@ func ConvertAzureEventToCommands(parseAzureContext Azure, impactedProjects []dig
 					ProjectDir:         project.Dir,
 					ProjectWorkspace:   project.Workspace,
 					Terragrunt:         project.Terragrunt,
+					TerragruntRunAll:   project.TerragruntRunAll,
 					OpenTofu:           project.OpenTofu,
 					Commands:           workflow.Configuration.OnCommitToDefault,
 					ApplyStage:         orchestrator.ToConfigStage(workflow.Apply),
@@ -511,6 +566,7 @@ func ConvertAzureEventToCommands(parseAzureContext Azure, impactedProjects []dig
 					Namespace:          parseAzureContext.BaseUrl + "/" + parseAzureContext.ProjectName,
 					StateEnvVars:       stateEnvVars,
 					CommandEnvVars:     commandEnvVars,
+					EnvAllowlist:       digger_config2.CollectEnvAllowlist(workflow.EnvVars),
 					StateEnvProvider:   StateEnvProvider,
 					CommandEnvProvider: CommandEnvProvider,
 				})
@@ -538,7 +594,7 @@ func ConvertAzureEventToCommands(parseAzureContext Azure, impactedProjects []dig
 			if strings.Contains(diggerCommand, command) {
 				for _, project := range runForProjects {
 					workspace := project.Workspace
-					workspaceOverride, err := utils.ParseWorkspace(diggerCommand)
+					workspaceOverride, err := orchestrator.ParseWorkspace(diggerCommand)
 					if err != nil {
 						return []orchestrator.Job{}, coversAllImpactedProjects, err
 					}
@@ -556,6 +612,7 @@ func ConvertAzureEventToCommands(parseAzureContext Azure, impactedProjects []dig
 						ProjectDir:         project.Dir,
 						ProjectWorkspace:   workspace,
 						Terragrunt:         project.Terragrunt,
+						TerragruntRunAll:   project.TerragruntRunAll,
 						OpenTofu:           project.OpenTofu,
 						Commands:           []string{command},
 						ApplyStage:         orchestrator.ToConfigStage(workflow.Apply),
@@ -566,6 +623,7 @@ func ConvertAzureEventToCommands(parseAzureContext Azure, impactedProjects []dig
 						Namespace:          parseAzureContext.BaseUrl + "/" + parseAzureContext.ProjectName,
 						StateEnvVars:       stateEnvVars,
 						CommandEnvVars:     commandEnvVars,
+						EnvAllowlist:       digger_config2.CollectEnvAllowlist(workflow.EnvVars),
 						StateEnvProvider:   StateEnvProvider,
 						CommandEnvProvider: CommandEnvProvider,
 					})
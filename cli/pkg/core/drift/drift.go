@@ -1,5 +1,15 @@
 package drift
 
+// Drift describes drift detected in a project and what the drift policy decided about it
+type Drift struct {
+	Severity string
+	// Notify lists who should be told about the drift
+	Notify []string
+	// Resources are the addresses of the drifted resources, IgnoredResources drifted too but the policy ignores them
+	Resources        []string
+	IgnoredResources []string
+}
+
 type Notification interface {
-	Send(projectName string, plan string) error
+	Send(projectName string, plan string, drift Drift) error
 }
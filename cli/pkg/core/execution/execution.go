@@ -6,26 +6,33 @@ import (
 	"os"
 	"path"
 	"regexp"
-	"strconv"
 	"strings"
 
+	"github.com/diggerhq/digger/libs/comment_utils/templates"
 	"github.com/diggerhq/digger/libs/comment_utils/utils"
 	"github.com/diggerhq/digger/libs/locking"
 	"github.com/diggerhq/digger/libs/terraform_utils"
 	"github.com/samber/lo"
 
 	"github.com/diggerhq/digger/cli/pkg/core/runners"
-	"github.com/diggerhq/digger/cli/pkg/core/storage"
 	"github.com/diggerhq/digger/cli/pkg/core/terraform"
 	"github.com/diggerhq/digger/libs/comment_utils/reporting"
 	configuration "github.com/diggerhq/digger/libs/digger_config"
 	"github.com/diggerhq/digger/libs/orchestrator"
+	"github.com/diggerhq/digger/libs/storage"
 )
 
 type Executor interface {
 	Plan() (*terraform_utils.PlanSummary, bool, bool, string, string, error)
 	Apply() (bool, string, error)
 	Destroy() (bool, error)
+	DeleteWorkspace() (bool, error)
+}
+
+// PlanJsonRetriever fetches the stored plan of a project as json for policy checks ahead of an apply
+type PlanJsonRetriever interface {
+	Executor
+	RetrievePlanJson() (string, error)
 }
 
 type LockingExecutorWrapper struct {
@@ -75,6 +82,19 @@ func (l LockingExecutorWrapper) Destroy() (bool, error) {
 	}
 }
 
+func (l LockingExecutorWrapper) DeleteWorkspace() (bool, error) {
+	locked, err := l.ProjectLock.Lock()
+	if err != nil {
+		return false, fmt.Errorf("mantis delete-workspace, error locking project: %v", err)
+	}
+	log.Printf("Lock result: %t\n", locked)
+	if locked {
+		return l.Executor.DeleteWorkspace()
+	} else {
+		return false, nil
+	}
+}
+
 func (l LockingExecutorWrapper) Unlock() error {
 	err := l.ProjectLock.ForceUnlock()
 	if err != nil {
@@ -109,6 +129,8 @@ type DiggerExecutor struct {
 	Reporter          reporting.Reporter
 	PlanStorage       storage.PlanStorage
 	PlanPathProvider  PlanPathProvider
+	// Templates render the comments of the executor, the defaults are used when nil
+	Templates *templates.Templates
 }
 
 type DiggerExecutorResult struct {
@@ -129,6 +151,8 @@ type DiggerExecutorPlanResult struct {
 type PlanPathProvider interface {
 	LocalPlanFilePath() string
 	StoredPlanFilePath() string
+	// LegacyStoredPlanFilePath is looked up when no plan is stored under StoredPlanFilePath, empty when there is none
+	LegacyStoredPlanFilePath() string
 	ArtifactName() string
 }
 
@@ -137,6 +161,8 @@ type ProjectPathProvider struct {
 	ProjectPath      string
 	ProjectNamespace string
 	ProjectName      string
+	// set for projects running in an ephemeral per pull request workspace
+	EphemeralWorkspace string
 }
 
 func (d ProjectPathProvider) ArtifactName() string {
@@ -144,30 +170,53 @@ func (d ProjectPathProvider) ArtifactName() string {
 }
 
 func (d ProjectPathProvider) StoredPlanFilePath() string {
-	if d.PRNumber != nil {
-		prNumber := strconv.Itoa(*d.PRNumber)
-		return strings.ReplaceAll(d.ProjectNamespace, "/", "-") + "-" + prNumber + "-" + d.ProjectName + ".tfplan"
-	} else {
-		return strings.ReplaceAll(d.ProjectNamespace, "/", "-") + "-" + d.ProjectName + ".tfplan"
-	}
+	return storage.StoredPlanFilePath(d.ProjectNamespace, d.PRNumber, d.EphemeralWorkspace, d.ProjectName)
+}
 
+// LegacyStoredPlanFilePath is the path plans of the project were stored under by older versions, it is empty for
+// projects in ephemeral workspaces which those versions didn't support
+func (d ProjectPathProvider) LegacyStoredPlanFilePath() string {
+	if d.EphemeralWorkspace != "" {
+		return ""
+	}
+	return storage.LegacyStoredPlanFilePath(d.ProjectNamespace, d.PRNumber, d.ProjectName)
 }
 
 func (d ProjectPathProvider) LocalPlanFilePath() string {
 	return path.Join(d.ProjectPath, d.StoredPlanFilePath())
 }
 
+// findStoredPlan returns the path the plan of planPathProvider is stored under, falling back to its legacy path when
+// only a plan stored by an older version exists. The path is StoredPlanFilePath when no plan is stored
+func findStoredPlan(planStorage storage.PlanStorage, planPathProvider PlanPathProvider) (string, bool, error) {
+	storedPlanFilePath := planPathProvider.StoredPlanFilePath()
+	exists, err := planStorage.PlanExists(planPathProvider.ArtifactName(), storedPlanFilePath)
+	if err != nil || exists {
+		return storedPlanFilePath, exists, err
+	}
+	legacyStoredPlanFilePath := planPathProvider.LegacyStoredPlanFilePath()
+	if legacyStoredPlanFilePath == "" {
+		return storedPlanFilePath, false, nil
+	}
+	exists, err = planStorage.PlanExists(planPathProvider.ArtifactName(), legacyStoredPlanFilePath)
+	if err != nil || !exists {
+		return storedPlanFilePath, false, err
+	}
+	log.Printf("Using the plan stored under its legacy path %v", legacyStoredPlanFilePath)
+	return legacyStoredPlanFilePath, true, nil
+}
+
 func (d DiggerExecutor) RetrievePlanJson() (string, error) {
 	executor := d
 	planStorage := executor.PlanStorage
 	planPathProvider := executor.PlanPathProvider
-	storedPlanExists, err := planStorage.PlanExists(planPathProvider.ArtifactName(), planPathProvider.StoredPlanFilePath())
+	storedPlanFilePath, storedPlanExists, err := findStoredPlan(planStorage, planPathProvider)
 	if err != nil {
 		return "", fmt.Errorf("failed to check if stored plan exists. %v", err)
 	}
 	if storedPlanExists {
 		log.Printf("Pre-apply plan retrieval: stored plan exists in artefact, retrieving")
-		storedPlanPath, err := planStorage.RetrievePlan(planPathProvider.LocalPlanFilePath(), planPathProvider.ArtifactName(), planPathProvider.StoredPlanFilePath())
+		storedPlanPath, err := planStorage.RetrievePlan(planPathProvider.LocalPlanFilePath(), planPathProvider.ArtifactName(), storedPlanFilePath)
 		if err != nil {
 			return "", fmt.Errorf("failed to retrieve stored plan path. %v", err)
 		}
@@ -212,7 +261,7 @@ func (d DiggerExecutor) Plan() (*terraform_utils.PlanSummary, bool, bool, string
 		if step.Action == "init" {
 			_, stderr, err := d.TerraformExecutor.Init(step.ExtraArgs, d.StateEnvVars)
 			if err != nil {
-				reportError(d.Reporter, stderr)
+				reportError(d.Reporter, d.Templates, stderr)
 				return nil, false, false, "", "", fmt.Errorf("error running init: %v", err)
 			}
 		}
@@ -263,34 +312,32 @@ func (d DiggerExecutor) Plan() (*terraform_utils.PlanSummary, bool, bool, string
 			}
 		}
 		if step.Action == "run" {
-			var commands []string
-			if os.Getenv("ACTIVATE_VENV") == "true" {
-				commands = append(commands, fmt.Sprintf("source %v/.venv/bin/activate", os.Getenv("GITHUB_WORKSPACE")))
-			}
-			commands = append(commands, step.Value)
-			log.Printf("Running %v for **%v**\n", step.Value, d.ProjectNamespace+"#"+d.ProjectName)
-			_, _, err := d.CommandRunner.Run(d.ProjectPath, step.Shell, commands, d.RunEnvVars)
+			_, _, err := d.runCommandStep(step)
 			if err != nil {
 				return nil, false, false, "", "", fmt.Errorf("error running command: %v", err)
 			}
 		}
 	}
 	reportAdditionalOutput(d.Reporter, d.projectId())
-	
+
 	return planSummary, true, !isEmptyPlan, plan, terraformPlanOutput, nil
 }
 
-func reportError(r reporting.Reporter, stderr string) {
-	if r.SupportsMarkdown() {
-		_, _, commentErr := r.Report(stderr, utils.AsCollapsibleComment("Error during init.", false))
-		if commentErr != nil {
-			log.Printf("error publishing comment: %v", commentErr)
-		}
-	} else {
-		_, _, commentErr := r.Report(stderr, utils.AsComment("Error during init."))
-		if commentErr != nil {
-			log.Printf("error publishing comment: %v", commentErr)
-		}
+func (d DiggerExecutor) runCommandStep(step orchestrator.Step) (string, string, error) {
+	var commands []string
+	if os.Getenv("ACTIVATE_VENV") == "true" {
+		commands = append(commands, fmt.Sprintf("source %v/.venv/bin/activate", os.Getenv("GITHUB_WORKSPACE")))
+	}
+	commands = append(commands, step.Value)
+	log.Printf("Running %v for **%v**\n", step.Value, d.ProjectNamespace+"#"+d.ProjectName)
+	return d.CommandRunner.Run(d.ProjectPath, step.Shell, commands, d.RunEnvVars)
+}
+
+func reportError(r reporting.Reporter, t *templates.Templates, stderr string) {
+	formatter := templates.OrDefaults(t).Formatter(templates.Error, templates.CommentData{Title: "Error during init.", Markdown: r.SupportsMarkdown()})
+	_, _, commentErr := r.Report(stderr, formatter)
+	if commentErr != nil {
+		log.Printf("error publishing comment: %v", commentErr)
 	}
 }
 
@@ -299,7 +346,11 @@ func (d DiggerExecutor) Apply() (bool, string, error) {
 	var plansFilename *string
 	if d.PlanStorage != nil {
 		var err error
-		plansFilename, err = d.PlanStorage.RetrievePlan(d.PlanPathProvider.LocalPlanFilePath(), d.PlanPathProvider.ArtifactName(), d.PlanPathProvider.StoredPlanFilePath())
+		storedPlanFilePath, _, err := findStoredPlan(d.PlanStorage, d.PlanPathProvider)
+		if err != nil {
+			return false, "", fmt.Errorf("error retrieving plan: %v", err)
+		}
+		plansFilename, err = d.PlanStorage.RetrievePlan(d.PlanPathProvider.LocalPlanFilePath(), d.PlanPathProvider.ArtifactName(), storedPlanFilePath)
 		if err != nil {
 			return false, "", fmt.Errorf("error retrieving plan: %v", err)
 		}
@@ -324,7 +375,7 @@ func (d DiggerExecutor) Apply() (bool, string, error) {
 		if step.Action == "init" {
 			stdout, stderr, err := d.TerraformExecutor.Init(step.ExtraArgs, d.StateEnvVars)
 			if err != nil {
-				reportTerraformError(d.Reporter, stderr)
+				reportTerraformError(d.Reporter, d.Templates, stderr)
 				return false, stdout, fmt.Errorf("error running init: %v", err)
 			}
 		}
@@ -333,20 +384,14 @@ func (d DiggerExecutor) Apply() (bool, string, error) {
 			applyArgs = append(applyArgs, step.ExtraArgs...)
 			stdout, stderr, err := d.TerraformExecutor.Apply(applyArgs, plansFilename, d.CommandEnvVars)
 			applyOutput = cleanupTerraformApply(true, err, stdout, stderr)
-			reportTerraformApplyOutput(d.Reporter, d.projectId(), applyOutput)
+			reportTerraformApplyOutput(d.Reporter, d.Templates, d.projectId(), applyOutput)
 			if err != nil {
-				reportApplyError(d.Reporter, err)
+				reportApplyError(d.Reporter, d.Templates, err)
 				return false, stdout, fmt.Errorf("error executing apply: %v", err)
 			}
 		}
 		if step.Action == "run" {
-			var commands []string
-			if os.Getenv("ACTIVATE_VENV") == "true" {
-				commands = append(commands, fmt.Sprintf("source %v/.venv/bin/activate", os.Getenv("GITHUB_WORKSPACE")))
-			}
-			commands = append(commands, step.Value)
-			log.Printf("Running %v for **%v**\n", step.Value, d.ProjectNamespace+"#"+d.ProjectName)
-			_, stderr, err := d.CommandRunner.Run(d.ProjectPath, step.Shell, commands, d.RunEnvVars)
+			_, stderr, err := d.runCommandStep(step)
 			if err != nil {
 				return false, stderr, fmt.Errorf("error running command: %v", err)
 			}
@@ -356,27 +401,21 @@ func (d DiggerExecutor) Apply() (bool, string, error) {
 	return true, applyOutput, nil
 }
 
-func reportApplyError(r reporting.Reporter, err error) {
-	if r.SupportsMarkdown() {
-		_, _, commentErr := r.Report(err.Error(), utils.AsCollapsibleComment("Error during applying.", false))
-		if commentErr != nil {
-			log.Printf("error publishing comment: %v", err)
-		}
-	} else {
-		_, _, commentErr := r.Report(err.Error(), utils.AsComment("Error during applying."))
-		if commentErr != nil {
-			log.Printf("error publishing comment: %v", err)
-		}
+func reportApplyError(r reporting.Reporter, t *templates.Templates, err error) {
+	formatter := templates.OrDefaults(t).Formatter(templates.Error, templates.CommentData{Title: "Error during applying.", Markdown: r.SupportsMarkdown()})
+	_, _, commentErr := r.Report(err.Error(), formatter)
+	if commentErr != nil {
+		log.Printf("error publishing comment: %v", err)
 	}
 }
 
-func reportTerraformApplyOutput(r reporting.Reporter, projectId string, applyOutput string) {
-	var formatter func(string) string
-	if r.SupportsMarkdown() {
-		formatter = utils.GetTerraformOutputAsCollapsibleComment("Apply output", false)
-	} else {
-		formatter = utils.GetTerraformOutputAsComment("Apply output")
-	}
+func reportTerraformApplyOutput(r reporting.Reporter, t *templates.Templates, projectId string, applyOutput string) {
+	formatter := templates.OrDefaults(t).Formatter(templates.Apply, templates.CommentData{
+		Project:  projectId,
+		Title:    "Apply output",
+		Code:     true,
+		Markdown: r.SupportsMarkdown(),
+	})
 
 	_, _, commentErr := r.Report(applyOutput, formatter)
 	if commentErr != nil {
@@ -384,17 +423,11 @@ func reportTerraformApplyOutput(r reporting.Reporter, projectId string, applyOut
 	}
 }
 
-func reportTerraformError(r reporting.Reporter, stderr string) {
-	if r.SupportsMarkdown() {
-		_, _, commentErr := r.Report(stderr, utils.GetTerraformOutputAsCollapsibleComment("Error during init.", false))
-		if commentErr != nil {
-			log.Printf("error publishing comment: %v", commentErr)
-		}
-	} else {
-		_, _, commentErr := r.Report(stderr, utils.GetTerraformOutputAsComment("Error during init."))
-		if commentErr != nil {
-			log.Printf("error publishing comment: %v", commentErr)
-		}
+func reportTerraformError(r reporting.Reporter, t *templates.Templates, stderr string) {
+	formatter := templates.OrDefaults(t).Formatter(templates.Error, templates.CommentData{Title: "Error during init.", Code: true, Markdown: r.SupportsMarkdown()})
+	_, _, commentErr := r.Report(stderr, formatter)
+	if commentErr != nil {
+		log.Printf("error publishing comment: %v", commentErr)
 	}
 }
 
@@ -441,7 +474,7 @@ func (d DiggerExecutor) Destroy() (bool, error) {
 		if step.Action == "init" {
 			_, stderr, err := d.TerraformExecutor.Init(step.ExtraArgs, d.StateEnvVars)
 			if err != nil {
-				reportError(d.Reporter, stderr)
+				reportError(d.Reporter, d.Templates, stderr)
 				return false, fmt.Errorf("error running init: %v", err)
 			}
 		}
@@ -454,6 +487,20 @@ func (d DiggerExecutor) Destroy() (bool, error) {
 	return true, nil
 }
 
+func (d DiggerExecutor) DeleteWorkspace() (bool, error) {
+	_, stderr, err := d.TerraformExecutor.Init([]string{}, d.StateEnvVars)
+	if err != nil {
+		reportError(d.Reporter, d.Templates, stderr)
+		return false, fmt.Errorf("error running init: %v", err)
+	}
+	_, stderr, err = d.TerraformExecutor.DeleteWorkspace([]string{}, d.StateEnvVars)
+	if err != nil {
+		reportError(d.Reporter, d.Templates, stderr)
+		return false, fmt.Errorf("error deleting workspace: %v", err)
+	}
+	return true, nil
+}
+
 func cleanupTerraformOutput(nonEmptyOutput bool, planError error, stdout string, stderr string, regexStr *string) string {
 	var errorStr string
 
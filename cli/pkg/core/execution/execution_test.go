@@ -1,6 +1,7 @@
 package execution
 
 import (
+	"github.com/diggerhq/digger/libs/storage"
 	"github.com/stretchr/testify/assert"
 	"strings"
 	"testing"
@@ -260,3 +261,38 @@ Changes to Outputs:
 	index := strings.Index(stdout, "OpenTofu will perform the following actions:")
 	assert.Equal(t, stdout[index:], res)
 }
+
+func TestFindStoredPlanFallsBackToLegacyPath(t *testing.T) {
+	planStorage := &storage.PlanStorageLocal{Dir: t.TempDir()}
+	prNumber := 12
+	provider := ProjectPathProvider{PRNumber: &prNumber, ProjectNamespace: "org/repo", ProjectName: "dev"}
+
+	storedPlanFilePath, exists, err := findStoredPlan(planStorage, provider)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+	assert.Equal(t, "org-repo~12~dev.tfplan", storedPlanFilePath)
+
+	assert.NoError(t, planStorage.StorePlanFile([]byte("legacy"), provider.ArtifactName(), "org-repo-12-dev.tfplan"))
+	storedPlanFilePath, exists, err = findStoredPlan(planStorage, provider)
+	assert.NoError(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, "org-repo-12-dev.tfplan", storedPlanFilePath)
+
+	assert.NoError(t, planStorage.StorePlanFile([]byte("current"), provider.ArtifactName(), provider.StoredPlanFilePath()))
+	storedPlanFilePath, exists, err = findStoredPlan(planStorage, provider)
+	assert.NoError(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, "org-repo~12~dev.tfplan", storedPlanFilePath)
+
+	ephemeral := ProjectPathProvider{PRNumber: &prNumber, ProjectNamespace: "org/repo", ProjectName: "dev", EphemeralWorkspace: "pr-12"}
+	_, exists, err = findStoredPlan(planStorage, ephemeral)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestMergedEphemeralProjectDoesNotApplyPlanOfPreview(t *testing.T) {
+	prNumber := 7
+	preview := ProjectPathProvider{PRNumber: &prNumber, ProjectNamespace: "org/repo", ProjectName: "dev", EphemeralWorkspace: "pr-7"}
+	merged := ProjectPathProvider{PRNumber: &prNumber, ProjectNamespace: "org/repo", ProjectName: "dev"}
+	assert.NotEqual(t, preview.StoredPlanFilePath(), merged.StoredPlanFilePath())
+}
@@ -0,0 +1,373 @@
+package execution
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/diggerhq/digger/libs/comment_utils/utils"
+	"github.com/diggerhq/digger/libs/orchestrator"
+	"github.com/diggerhq/digger/libs/terraform_utils"
+)
+
+// file names terragrunt uses below --terragrunt-out-dir and --terragrunt-json-out-dir for every module
+const runAllPlanFileName = "tfplan.tfplan"
+const runAllPlanJsonFileName = "tfplan.json"
+
+// TerragruntRunAllExecutor runs a terragrunt stack with run-all, plans are stored and summarised per module
+type TerragruntRunAllExecutor struct {
+	DiggerExecutor
+}
+
+type RunAllModulePlan struct {
+	Dir         string
+	PlanJson    string
+	PlanSummary terraform_utils.PlanSummary
+	IsEmpty     bool
+}
+
+type runAllPlanManifest struct {
+	Modules []string `json:"modules"`
+}
+
+func (t TerragruntRunAllExecutor) planOutDir() string {
+	return strings.TrimSuffix(t.PlanPathProvider.LocalPlanFilePath(), ".tfplan") + "-run-all"
+}
+
+func (t TerragruntRunAllExecutor) moduleArtifactName(moduleDir string) string {
+	return t.PlanPathProvider.ArtifactName() + "-" + strings.ReplaceAll(moduleDir, "/", "-")
+}
+
+func (t TerragruntRunAllExecutor) moduleStoredPlanFilePath(moduleDir string) string {
+	return strings.TrimSuffix(t.PlanPathProvider.StoredPlanFilePath(), ".tfplan") + "-" + strings.ReplaceAll(moduleDir, "/", "-") + ".tfplan"
+}
+
+func (t TerragruntRunAllExecutor) Plan() (*terraform_utils.PlanSummary, bool, bool, string, string, error) {
+	plan := ""
+	terraformPlanOutput := ""
+	planSummary := &terraform_utils.PlanSummary{}
+	isEmptyPlan := true
+	var planSteps []orchestrator.Step
+
+	if t.PlanStage != nil {
+		planSteps = t.PlanStage.Steps
+	} else {
+		planSteps = []orchestrator.Step{
+			{
+				Action: "init",
+			},
+			{
+				Action: "plan",
+			},
+		}
+	}
+
+	outDir := t.planOutDir()
+	for _, step := range planSteps {
+		if step.Action == "init" {
+			_, stderr, err := t.TerraformExecutor.Init(step.ExtraArgs, t.StateEnvVars)
+			if err != nil {
+				reportError(t.Reporter, t.Templates, stderr)
+				return nil, false, false, "", "", fmt.Errorf("error running init: %v", err)
+			}
+		}
+		if step.Action == "plan" {
+			err := os.RemoveAll(outDir)
+			if err != nil {
+				return nil, false, false, "", "", fmt.Errorf("error cleaning up previous plans: %v", err)
+			}
+			planArgs := []string{"--terragrunt-out-dir", outDir, "--terragrunt-json-out-dir", outDir, "-lock-timeout=3m"}
+			planArgs = append(planArgs, step.ExtraArgs...)
+			_, stdout, stderr, err := t.TerraformExecutor.Plan(planArgs, t.CommandEnvVars)
+			if err != nil {
+				return nil, false, false, "", "", fmt.Errorf("error executing plan: %v", err)
+			}
+
+			modules, err := ReadRunAllPlans(outDir)
+			if err != nil {
+				return nil, false, false, "", "", err
+			}
+			planSummary, isEmptyPlan = aggregateRunAllPlans(modules)
+			terraformPlanOutput, err = mergeRunAllPlanJsons(modules)
+			if err != nil {
+				return nil, false, false, "", "", err
+			}
+
+			if t.PlanStorage != nil {
+				err = t.storeModulePlans(outDir, modules)
+				if err != nil {
+					return nil, false, false, "", "", err
+				}
+			}
+			reportRunAllPlanSummary(t, modules)
+			plan = cleanupTerraformPlan(!isEmptyPlan, nil, stdout, stderr)
+		}
+		if step.Action == "run" {
+			_, _, err := t.runCommandStep(step)
+			if err != nil {
+				return nil, false, false, "", "", fmt.Errorf("error running command: %v", err)
+			}
+		}
+	}
+	reportAdditionalOutput(t.Reporter, t.projectId())
+
+	return planSummary, true, !isEmptyPlan, plan, terraformPlanOutput, nil
+}
+
+func (t TerragruntRunAllExecutor) Apply() (bool, string, error) {
+	var applyOutput string
+	outDir := t.planOutDir()
+	usePlans := false
+	if t.PlanStorage != nil {
+		err := t.retrieveModulePlans(outDir)
+		if err != nil {
+			return false, "", fmt.Errorf("error retrieving plan: %v", err)
+		}
+		usePlans = true
+	}
+
+	var applySteps []orchestrator.Step
+	if t.ApplyStage != nil {
+		applySteps = t.ApplyStage.Steps
+	} else {
+		applySteps = []orchestrator.Step{
+			{
+				Action: "init",
+			},
+			{
+				Action: "apply",
+			},
+		}
+	}
+
+	for _, step := range applySteps {
+		if step.Action == "init" {
+			stdout, stderr, err := t.TerraformExecutor.Init(step.ExtraArgs, t.StateEnvVars)
+			if err != nil {
+				reportTerraformError(t.Reporter, t.Templates, stderr)
+				return false, stdout, fmt.Errorf("error running init: %v", err)
+			}
+		}
+		if step.Action == "apply" {
+			var applyArgs []string
+			if usePlans {
+				applyArgs = append(applyArgs, "--terragrunt-out-dir", outDir)
+			}
+			applyArgs = append(applyArgs, "-lock-timeout=3m")
+			applyArgs = append(applyArgs, step.ExtraArgs...)
+			stdout, stderr, err := t.TerraformExecutor.Apply(applyArgs, nil, t.CommandEnvVars)
+			applyOutput = cleanupTerraformApply(true, err, stdout, stderr)
+			reportTerraformApplyOutput(t.Reporter, t.Templates, t.projectId(), applyOutput)
+			if err != nil {
+				reportApplyError(t.Reporter, t.Templates, err)
+				return false, stdout, fmt.Errorf("error executing apply: %v", err)
+			}
+		}
+		if step.Action == "run" {
+			_, stderr, err := t.runCommandStep(step)
+			if err != nil {
+				return false, stderr, fmt.Errorf("error running command: %v", err)
+			}
+		}
+	}
+	reportAdditionalOutput(t.Reporter, t.projectId())
+	return true, applyOutput, nil
+}
+
+// RetrievePlanJson returns the stored plans of every module merged into a single plan json
+func (t TerragruntRunAllExecutor) RetrievePlanJson() (string, error) {
+	outDir := t.planOutDir()
+	err := t.retrieveModulePlans(outDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to retrieve stored plans: %v", err)
+	}
+
+	// Running terraform init to load providers
+	for _, step := range t.PlanStage.Steps {
+		if step.Action == "init" {
+			t.TerraformExecutor.Init(step.ExtraArgs, t.StateEnvVars)
+			break
+		}
+	}
+
+	manifest, err := readRunAllManifest(outDir)
+	if err != nil {
+		return "", err
+	}
+	var modules []RunAllModulePlan
+	for _, dir := range manifest.Modules {
+		// show has to run in the module so that terragrunt resolves its working dir
+		showArgs := []string{"-no-color", "-json", path.Join(outDir, dir, runAllPlanFileName)}
+		planJson, _, err := t.TerraformExecutor.Show(append(showArgs, "--terragrunt-working-dir", path.Join(t.ProjectPath, dir)), t.CommandEnvVars)
+		if err != nil {
+			return "", fmt.Errorf("failed to show plan of module %v: %v", dir, err)
+		}
+		modules = append(modules, RunAllModulePlan{Dir: dir, PlanJson: planJson})
+	}
+	return mergeRunAllPlanJsons(modules)
+}
+
+func (t TerragruntRunAllExecutor) storeModulePlans(outDir string, modules []RunAllModulePlan) error {
+	manifest := runAllPlanManifest{Modules: []string{}}
+	for _, module := range modules {
+		fileBytes, err := os.ReadFile(path.Join(outDir, module.Dir, runAllPlanFileName))
+		if err != nil {
+			return fmt.Errorf("error reading plan of module %v: %v", module.Dir, err)
+		}
+		err = t.PlanStorage.StorePlanFile(fileBytes, t.moduleArtifactName(module.Dir), t.moduleStoredPlanFilePath(module.Dir))
+		if err != nil {
+			return fmt.Errorf("error storing plan of module %v: %v", module.Dir, err)
+		}
+		manifest.Modules = append(manifest.Modules, module.Dir)
+	}
+
+	manifestBytes, err := json.Marshal(manifest)
+	if err != nil {
+		return fmt.Errorf("error serialising run-all plan manifest: %v", err)
+	}
+	err = os.WriteFile(path.Join(outDir, "manifest.json"), manifestBytes, 0644)
+	if err != nil {
+		return fmt.Errorf("error writing run-all plan manifest: %v", err)
+	}
+	// the manifest is stored under the project's plan path so the usual plan existence checks keep working
+	err = t.PlanStorage.StorePlanFile(manifestBytes, t.PlanPathProvider.ArtifactName(), t.PlanPathProvider.StoredPlanFilePath())
+	if err != nil {
+		return fmt.Errorf("error storing run-all plan manifest: %v", err)
+	}
+	return nil
+}
+
+func (t TerragruntRunAllExecutor) retrieveModulePlans(outDir string) error {
+	err := os.MkdirAll(outDir, 0755)
+	if err != nil {
+		return err
+	}
+	_, err = t.PlanStorage.RetrievePlan(path.Join(outDir, "manifest.json"), t.PlanPathProvider.ArtifactName(), t.PlanPathProvider.StoredPlanFilePath())
+	if err != nil {
+		return fmt.Errorf("error retrieving run-all plan manifest: %v", err)
+	}
+	manifest, err := readRunAllManifest(outDir)
+	if err != nil {
+		return err
+	}
+	for _, dir := range manifest.Modules {
+		err := os.MkdirAll(path.Join(outDir, dir), 0755)
+		if err != nil {
+			return err
+		}
+		_, err = t.PlanStorage.RetrievePlan(path.Join(outDir, dir, runAllPlanFileName), t.moduleArtifactName(dir), t.moduleStoredPlanFilePath(dir))
+		if err != nil {
+			return fmt.Errorf("error retrieving plan of module %v: %v", dir, err)
+		}
+	}
+	return nil
+}
+
+func readRunAllManifest(outDir string) (*runAllPlanManifest, error) {
+	manifestBytes, err := os.ReadFile(path.Join(outDir, "manifest.json"))
+	if err != nil {
+		return nil, fmt.Errorf("error reading run-all plan manifest: %v", err)
+	}
+	var manifest runAllPlanManifest
+	err = json.Unmarshal(manifestBytes, &manifest)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing run-all plan manifest: %v", err)
+	}
+	return &manifest, nil
+}
+
+// ReadRunAllPlans collects the json plans terragrunt wrote below outDir, one per module, sorted by module dir
+func ReadRunAllPlans(outDir string) ([]RunAllModulePlan, error) {
+	var modules []RunAllModulePlan
+	err := filepath.WalkDir(outDir, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || entry.Name() != runAllPlanJsonFileName {
+			return nil
+		}
+		dir, err := filepath.Rel(outDir, filepath.Dir(filePath))
+		if err != nil {
+			return err
+		}
+		planJson, err := os.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		isEmpty, summary, err := terraform_utils.GetPlanSummary(string(planJson))
+		if err != nil {
+			return fmt.Errorf("error summarising plan of module %v: %v", dir, err)
+		}
+		modules = append(modules, RunAllModulePlan{
+			Dir:         filepath.ToSlash(dir),
+			PlanJson:    string(planJson),
+			PlanSummary: *summary,
+			IsEmpty:     isEmpty,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading run-all plans: %v", err)
+	}
+	sort.Slice(modules, func(i, j int) bool { return modules[i].Dir < modules[j].Dir })
+	return modules, nil
+}
+
+func aggregateRunAllPlans(modules []RunAllModulePlan) (*terraform_utils.PlanSummary, bool) {
+	summary := &terraform_utils.PlanSummary{}
+	isEmpty := true
+	for _, module := range modules {
+		summary.ResourcesCreated += module.PlanSummary.ResourcesCreated
+		summary.ResourcesUpdated += module.PlanSummary.ResourcesUpdated
+		summary.ResourcesDeleted += module.PlanSummary.ResourcesDeleted
+		isEmpty = isEmpty && module.IsEmpty
+	}
+	return summary, isEmpty
+}
+
+// mergeRunAllPlanJsons concatenates the resource changes of all modules so that plan policies see the whole stack
+func mergeRunAllPlanJsons(modules []RunAllModulePlan) (string, error) {
+	resourceChanges := make([]json.RawMessage, 0)
+	for _, module := range modules {
+		var plan struct {
+			ResourceChanges []json.RawMessage `json:"resource_changes"`
+		}
+		err := json.Unmarshal([]byte(module.PlanJson), &plan)
+		if err != nil {
+			return "", fmt.Errorf("error parsing plan of module %v: %v", module.Dir, err)
+		}
+		resourceChanges = append(resourceChanges, plan.ResourceChanges...)
+	}
+	merged, err := json.Marshal(map[string]interface{}{
+		"format_version":   "1.2",
+		"resource_changes": resourceChanges,
+	})
+	if err != nil {
+		return "", fmt.Errorf("error merging run-all plans: %v", err)
+	}
+	return string(merged), nil
+}
+
+func reportRunAllPlanSummary(t TerragruntRunAllExecutor, modules []RunAllModulePlan) {
+	var report strings.Builder
+	report.WriteString("| Module | Add | Change | Destroy |\n|---|---|---|---|\n")
+	for _, module := range modules {
+		report.WriteString(fmt.Sprintf("| %v | %v | %v | %v |\n", module.Dir, module.PlanSummary.ResourcesCreated, module.PlanSummary.ResourcesUpdated, module.PlanSummary.ResourcesDeleted))
+	}
+	title := fmt.Sprintf("Terragrunt run-all plan summary for %v", t.projectId())
+	var err error
+	if t.Reporter.SupportsMarkdown() {
+		_, _, err = t.Reporter.Report(report.String(), utils.AsCollapsibleComment(title, false))
+	} else {
+		_, _, err = t.Reporter.Report(report.String(), utils.AsComment(title))
+	}
+	if err != nil {
+		log.Printf("error publishing comment: %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+package execution
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeRunAllPlan(t *testing.T, outDir string, moduleDir string, actions string) {
+	err := os.MkdirAll(path.Join(outDir, moduleDir), 0755)
+	assert.NoError(t, err)
+	planJson := `{"format_version":"1.2","resource_changes":[{"address":"null_resource.test","change":{"actions":` + actions + `}}]}`
+	err = os.WriteFile(path.Join(outDir, moduleDir, runAllPlanJsonFileName), []byte(planJson), 0644)
+	assert.NoError(t, err)
+}
+
+func TestReadRunAllPlansSummarisesEveryModule(t *testing.T) {
+	outDir := t.TempDir()
+	writeRunAllPlan(t, outDir, "vpc", `["create"]`)
+	writeRunAllPlan(t, outDir, "apps/api", `["no-op"]`)
+
+	modules, err := ReadRunAllPlans(outDir)
+	assert.NoError(t, err)
+	assert.Len(t, modules, 2)
+	assert.Equal(t, "apps/api", modules[0].Dir)
+	assert.True(t, modules[0].IsEmpty)
+	assert.Equal(t, "vpc", modules[1].Dir)
+	assert.False(t, modules[1].IsEmpty)
+	assert.Equal(t, uint(1), modules[1].PlanSummary.ResourcesCreated)
+
+	summary, isEmpty := aggregateRunAllPlans(modules)
+	assert.False(t, isEmpty)
+	assert.Equal(t, uint(1), summary.ResourcesCreated)
+
+	merged, err := mergeRunAllPlanJsons(modules)
+	assert.NoError(t, err)
+	assert.Contains(t, merged, `"create"`)
+	assert.Contains(t, merged, `"no-op"`)
+}
+
+func TestReadRunAllPlansWithoutModules(t *testing.T) {
+	modules, err := ReadRunAllPlans(t.TempDir())
+	assert.NoError(t, err)
+	summary, isEmpty := aggregateRunAllPlans(modules)
+	assert.True(t, isEmpty)
+	assert.Equal(t, uint(0), summary.ResourcesCreated)
+}
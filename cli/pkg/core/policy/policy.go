@@ -1,7 +1,10 @@
 package policy
 
 import (
+	"path"
+
 	"github.com/diggerhq/digger/libs/orchestrator"
+	"github.com/diggerhq/digger/libs/terraform_utils"
 )
 
 type Provider interface {
@@ -13,9 +16,72 @@ type Provider interface {
 
 type Checker interface {
 	// TODO refactor arguments - use AccessPolicyContext
-	CheckAccessPolicy(ciService orchestrator.OrgService, prService *orchestrator.PullRequestService, SCMOrganisation string, SCMrepository string, projectName string, projectDir string, command string, prNumber *int, requestedBy string, planPolicyViolations []string) (bool, error)
-	CheckPlanPolicy(SCMrepository string, SCMOrganisation string, projectname string, projectDir string, planOutput string) (bool, []string, error)
-	CheckDriftPolicy(SCMOrganisation string, SCMrepository string, projectname string) (bool, error)
+	CheckAccessPolicy(ciService orchestrator.OrgService, prService *orchestrator.PullRequestService, SCMOrganisation string, SCMrepository string, projectName string, projectDir string, projectWorkspace string, command string, prNumber *int, requestedBy string, planPolicyViolations []string, planSummary *terraform_utils.PlanSummary) (bool, error)
+	CheckPlanPolicy(SCMrepository string, SCMOrganisation string, projectname string, projectDir string, planOutput string) (bool, PlanPolicyResult, error)
+	// CheckDriftPolicy decides what to do about drift, planOutput is the output of terraform show -json of the drift plan
+	CheckDriftPolicy(SCMOrganisation string, SCMrepository string, projectname string, planOutput string) (DriftPolicyResult, error)
+}
+
+// Violation is a result of a deny, warn or info rule of a plan policy. Rules either return a message
+// or an object with the msg, rule_id, resource and remediation keys
+type Violation struct {
+	Message         string `json:"msg"`
+	RuleId          string `json:"rule_id,omitempty"`
+	ResourceAddress string `json:"resource,omitempty"`
+	Remediation     string `json:"remediation,omitempty"`
+}
+
+func (v Violation) String() string {
+	message := v.Message
+	if v.ResourceAddress != "" {
+		message = v.ResourceAddress + ": " + message
+	}
+	if v.RuleId != "" {
+		message = "[" + v.RuleId + "] " + message
+	}
+	if v.Remediation != "" {
+		message = message + " (" + v.Remediation + ")"
+	}
+	return message
+}
+
+// PlanPolicyResult holds the results of the rules of a plan policy, only deny results block a plan
+type PlanPolicyResult struct {
+	Deny []Violation `json:"deny"`
+	Warn []Violation `json:"warn"`
+	Info []Violation `json:"info"`
+}
+
+// DenyMessages returns the messages of deny results, they are passed to access policies as planPolicyViolations
+func (r PlanPolicyResult) DenyMessages() []string {
+	messages := make([]string, 0, len(r.Deny))
+	for _, violation := range r.Deny {
+		messages = append(messages, violation.Message)
+	}
+	return messages
+}
+
+// DriftPolicyResult holds the enable, notify, severity and ignore rules of a drift policy
+type DriftPolicyResult struct {
+	Enable bool `json:"enable"`
+	// Notify lists who is told about the drift, e.g. slack handles or team names
+	Notify   []string `json:"notify"`
+	Severity string   `json:"severity"`
+	// Ignore lists the addresses of resources whose drift is not reported, they can be patterns like aws_instance.*
+	Ignore []string `json:"ignore"`
+}
+
+// IsIgnored reports whether drift in the resource with the given address is ignored
+func (r DriftPolicyResult) IsIgnored(address string) bool {
+	for _, pattern := range r.Ignore {
+		if pattern == address {
+			return true
+		}
+		if matched, err := path.Match(pattern, address); err == nil && matched {
+			return true
+		}
+	}
+	return false
 }
 
 type PolicyCheckerProvider interface {
@@ -7,6 +7,7 @@ import (
 	"io/ioutil"
 	"os"
 	"os/exec"
+	"strings"
 )
 
 type CommandRun interface {
@@ -14,6 +15,41 @@ type CommandRun interface {
 }
 
 type CommandRunner struct {
+	// when set, commands start from a clean environment with only these runner env vars
+	EnvAllowlist []string
+}
+
+// Environ builds the environment of a command, only variables matching allowlist are inherited from the runner
+// unless allowlist is nil. Entries ending in * match by prefix.
+func Environ(allowlist []string, envs map[string]string) []string {
+	var env []string
+	if allowlist == nil {
+		env = os.Environ()
+	} else {
+		for _, kv := range os.Environ() {
+			name, _, _ := strings.Cut(kv, "=")
+			if isAllowed(allowlist, name) {
+				env = append(env, kv)
+			}
+		}
+	}
+	for k, v := range envs {
+		env = append(env, fmt.Sprintf("%s=%s", k, v))
+	}
+	return env
+}
+
+func isAllowed(allowlist []string, name string) bool {
+	for _, allowed := range allowlist {
+		if prefix, ok := strings.CutSuffix(allowed, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if allowed == name {
+			return true
+		}
+	}
+	return false
 }
 
 func (c CommandRunner) Run(workingDir string, shell string, commands []string, envs map[string]string) (string, string, error) {
@@ -40,11 +76,7 @@ func (c CommandRunner) Run(workingDir string, shell string, commands []string, e
 	cmd := exec.Command(shell, args...)
 	cmd.Dir = workingDir
 
-	env := os.Environ()
-	for k, v := range envs {
-		env = append(env, fmt.Sprintf("%s=%s", k, v))
-	}
-	cmd.Env = env
+	cmd.Env = Environ(c.EnvAllowlist, envs)
 
 	var stdout, stderr bytes.Buffer
 	mwout := io.MultiWriter(os.Stdout, &stdout)
@@ -0,0 +1,33 @@
+package runners
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvironWithoutAllowlistInheritsEverything(t *testing.T) {
+	t.Setenv("RUNNER_SECRET", "s3cr3t")
+	env := Environ(nil, map[string]string{"TF_VAR_x": "1"})
+	assert.Contains(t, env, "RUNNER_SECRET=s3cr3t")
+	assert.Contains(t, env, "TF_VAR_x=1")
+}
+
+func TestEnvironWithAllowlist(t *testing.T) {
+	t.Setenv("RUNNER_SECRET", "s3cr3t")
+	t.Setenv("TF_LOG", "debug")
+	t.Setenv("AWS_REGION", "us-east-1")
+	env := Environ([]string{"TF_*", "AWS_REGION"}, map[string]string{"TF_VAR_x": "1"})
+	assert.NotContains(t, env, "RUNNER_SECRET=s3cr3t")
+	assert.Contains(t, env, "TF_LOG=debug")
+	assert.Contains(t, env, "AWS_REGION=us-east-1")
+	assert.Contains(t, env, "TF_VAR_x=1")
+}
+
+func TestCommandRunnerUsesAllowlist(t *testing.T) {
+	t.Setenv("RUNNER_SECRET", "s3cr3t")
+	runner := CommandRunner{EnvAllowlist: []string{"PATH"}}
+	stdout, _, err := runner.Run(t.TempDir(), "", []string{"echo \"secret=${RUNNER_SECRET:-unset}\""}, map[string]string{})
+	assert.NoError(t, err)
+	assert.Contains(t, stdout, "secret=unset")
+}
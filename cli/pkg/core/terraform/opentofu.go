@@ -8,15 +8,19 @@ import (
 	"os"
 	"os/exec"
 	"strings"
+
+	"github.com/diggerhq/digger/cli/pkg/core/runners"
 )
 
 type OpenTofu struct {
-	WorkingDir string
-	Workspace  string
+	WorkingDir   string
+	Workspace    string
+	EnvAllowlist []string
+	Providers    ProviderInstallation
 }
 
 func (tf OpenTofu) Init(params []string, envs map[string]string) (string, string, error) {
-	params = append(params, "-upgrade=true")
+	params = append(params, tf.Providers.initArgs()...)
 	params = append(params, "-input=false")
 	params = append(params, "-no-color")
 	stdout, stderr, _, err := tf.runOpentofuCommand("init", true, envs, params...)
@@ -76,13 +80,32 @@ func (tf OpenTofu) Destroy(params []string, envs map[string]string) (string, str
 	return stdout, stderr, err
 }
 
+func (tf OpenTofu) DeleteWorkspace(params []string, envs map[string]string) (string, string, error) {
+	if tf.Workspace == "default" {
+		return "", "", fmt.Errorf("the default workspace cannot be deleted")
+	}
+	_, _, _, err := tf.runOpentofuCommand("workspace", true, envs, "select", "default")
+	if err != nil {
+		return "", "", err
+	}
+	params = append(params, tf.Workspace)
+	stdout, stderr, _, err := tf.runOpentofuCommand("workspace", true, envs, append([]string{"delete"}, params...)...)
+	return stdout, stderr, err
+}
+
+// MirrorProviders is the tofu counterpart of Terraform.MirrorProviders
+func (tf OpenTofu) MirrorProviders(mirrorDir string, platforms []string, envs map[string]string) (string, string, error) {
+	stdout, stderr, _, err := tf.runOpentofuCommand("providers", true, envs, mirrorArgs(mirrorDir, platforms)...)
+	return stdout, stderr, err
+}
+
 func (tf OpenTofu) switchToWorkspace(envs map[string]string) error {
 	workspaces, _, _, err := tf.runOpentofuCommand("workspace", false, envs, "list")
 	if err != nil {
 		return err
 	}
 	workspaces = tf.formatOpentofuWorkspaces(workspaces)
-	if strings.Contains(workspaces, tf.Workspace) {
+	if workspaceExists(workspaces, tf.Workspace) {
 		_, _, _, err := tf.runOpentofuCommand("workspace", true, envs, "select", tf.Workspace)
 		if err != nil {
 			return err
@@ -123,11 +146,7 @@ func (tf OpenTofu) runOpentofuCommand(command string, printOutputToStdout bool,
 	log.Printf("Running command: opentofu %v", expandedArgs)
 	cmd.Dir = tf.WorkingDir
 
-	env := os.Environ()
-	for k, v := range envs {
-		env = append(env, fmt.Sprintf("%s=%s", k, v))
-	}
-	cmd.Env = env
+	cmd.Env = runners.Environ(tf.EnvAllowlist, tf.Providers.withEnvs(envs))
 	cmd.Stdout = mwout
 	cmd.Stderr = mwerr
 
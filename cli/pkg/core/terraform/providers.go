@@ -0,0 +1,103 @@
+package terraform
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path"
+
+	configuration "github.com/diggerhq/digger/libs/digger_config"
+)
+
+// ProviderInstallation controls how init installs providers, the zero value keeps the default behaviour
+// of downloading and upgrading providers on every init
+type ProviderInstallation struct {
+	// shared plugin cache, exposed to terraform as TF_PLUGIN_CACHE_DIR
+	PluginCacheDir string
+	// skip -upgrade so that the provider versions pinned in .terraform.lock.hcl are used
+	RespectLockfile bool
+	// install providers only from this filesystem mirror, for offline runners
+	MirrorDir string
+}
+
+func NewProviderInstallation(providerCache configuration.ProviderCache) (ProviderInstallation, error) {
+	installation := ProviderInstallation{
+		RespectLockfile: !providerCache.Upgrade,
+		MirrorDir:       providerCache.MirrorDir,
+	}
+	if providerCache.Enabled {
+		installation.PluginCacheDir = providerCache.Dir
+		if installation.PluginCacheDir == "" {
+			installation.PluginCacheDir = configuration.DefaultProviderCacheDir()
+		}
+		// terraform ignores the cache if the directory does not exist
+		err := os.MkdirAll(installation.PluginCacheDir, 0755)
+		if err != nil {
+			return ProviderInstallation{}, fmt.Errorf("could not create plugin cache dir %v: %v", installation.PluginCacheDir, err)
+		}
+	}
+	return installation, nil
+}
+
+func (p ProviderInstallation) initArgs() []string {
+	var args []string
+	if !p.RespectLockfile {
+		args = append(args, "-upgrade=true")
+	}
+	if p.MirrorDir != "" {
+		args = append(args, "-plugin-dir="+p.MirrorDir)
+	}
+	return args
+}
+
+// withEnvs adds the plugin cache to envs without modifying the caller's map, explicitly configured env vars win
+func (p ProviderInstallation) withEnvs(envs map[string]string) map[string]string {
+	if p.PluginCacheDir == "" {
+		return envs
+	}
+	result := map[string]string{"TF_PLUGIN_CACHE_DIR": p.PluginCacheDir}
+	for k, v := range envs {
+		result[k] = v
+	}
+	return result
+}
+
+func mirrorArgs(mirrorDir string, platforms []string) []string {
+	args := []string{"mirror"}
+	for _, platform := range platforms {
+		args = append(args, "-platform="+platform)
+	}
+	return append(args, mirrorDir)
+}
+
+type providerMirrorer interface {
+	MirrorProviders(mirrorDir string, platforms []string, envs map[string]string) (string, string, error)
+}
+
+// PrepareProviderMirror fills mirrorDir with the providers locked by each project's .terraform.lock.hcl,
+// projects without a lock file and terragrunt projects are skipped
+func PrepareProviderMirror(projects []configuration.Project, workingDir string, mirrorDir string, platforms []string) error {
+	for _, project := range projects {
+		projectPath := path.Join(workingDir, project.Dir)
+		if project.Terragrunt {
+			log.Printf("skipping terragrunt project %v, provider mirrors are not supported for terragrunt", project.Name)
+			continue
+		}
+		if _, err := os.Stat(path.Join(projectPath, ".terraform.lock.hcl")); err != nil {
+			log.Printf("skipping project %v, no .terraform.lock.hcl found in %v", project.Name, projectPath)
+			continue
+		}
+
+		var mirrorer providerMirrorer
+		if project.OpenTofu {
+			mirrorer = OpenTofu{WorkingDir: projectPath, Workspace: project.Workspace}
+		} else {
+			mirrorer = Terraform{WorkingDir: projectPath, Workspace: project.Workspace}
+		}
+		_, stderr, err := mirrorer.MirrorProviders(mirrorDir, platforms, map[string]string{})
+		if err != nil {
+			return fmt.Errorf("failed to mirror providers of project %v: %v, %v", project.Name, err, stderr)
+		}
+	}
+	return nil
+}
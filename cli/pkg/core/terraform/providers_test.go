@@ -0,0 +1,40 @@
+package terraform
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	configuration "github.com/diggerhq/digger/libs/digger_config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProviderInstallationDefaultsToUpgrade(t *testing.T) {
+	installation, err := NewProviderInstallation(configuration.ProviderCache{Enabled: false, Upgrade: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"-upgrade=true"}, installation.initArgs())
+	envs := map[string]string{"A": "b"}
+	assert.Equal(t, envs, installation.withEnvs(envs))
+}
+
+func TestProviderInstallationWithCacheAndMirror(t *testing.T) {
+	cacheDir := path.Join(t.TempDir(), "cache")
+	installation, err := NewProviderInstallation(configuration.ProviderCache{Enabled: true, Dir: cacheDir, Upgrade: false, MirrorDir: "/mirror"})
+	assert.NoError(t, err)
+
+	_, err = os.Stat(cacheDir)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"-plugin-dir=/mirror"}, installation.initArgs())
+
+	envs := map[string]string{"A": "b"}
+	assert.Equal(t, map[string]string{"A": "b", "TF_PLUGIN_CACHE_DIR": cacheDir}, installation.withEnvs(envs))
+	assert.Equal(t, map[string]string{"A": "b"}, envs)
+	assert.Equal(t, map[string]string{"TF_PLUGIN_CACHE_DIR": "/custom"}, installation.withEnvs(map[string]string{"TF_PLUGIN_CACHE_DIR": "/custom"}))
+}
+
+func TestPrepareProviderMirrorSkipsProjectsWithoutLockfile(t *testing.T) {
+	dir := t.TempDir()
+	projects := []configuration.Project{{Name: "no-lockfile", Dir: "."}, {Name: "tg", Dir: ".", Terragrunt: true}}
+	err := PrepareProviderMirror(projects, dir, path.Join(dir, "mirror"), nil)
+	assert.NoError(t, err)
+}
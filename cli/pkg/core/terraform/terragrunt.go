@@ -4,17 +4,44 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"log"
 	"os"
 	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/diggerhq/digger/cli/pkg/core/runners"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
 )
 
 type Terragrunt struct {
-	WorkingDir string
+	WorkingDir   string
+	Workspace    string
+	EnvAllowlist []string
+	Providers    ProviderInstallation
+	// run init, plan, apply and destroy with run-all across the modules below WorkingDir
+	RunAll bool
+	// scopes run-all to these module dirs relative to WorkingDir, every module is included when empty
+	IncludeDirs []string
 }
 
 func (terragrunt Terragrunt) Init(params []string, envs map[string]string) (string, string, error) {
-	return terragrunt.runTerragruntCommand("init", true, envs, params...)
+	stdout, stderr, err := terragrunt.runTerragruntCommand("init", true, envs, params...)
+	if err != nil {
+		return stdout, stderr, err
+	}
 
+	if terragrunt.Workspace != "" && terragrunt.Workspace != "default" {
+		_, wstderr, werr := terragrunt.runTerragruntCommand("workspace", true, envs, "select", "-or-create=true", terragrunt.Workspace)
+		if werr != nil {
+			log.Printf("Fatal: Error terragrunt switch to workspace %v", werr)
+			return stdout, wstderr, werr
+		}
+	}
+	return stdout, stderr, err
 }
 
 func (terragrunt Terragrunt) Apply(params []string, plan *string, envs map[string]string) (string, string, error) {
@@ -44,17 +71,43 @@ func (terragrunt Terragrunt) Show(params []string, envs map[string]string) (stri
 	return stdout, stderr, err
 }
 
+func (terragrunt Terragrunt) DeleteWorkspace(params []string, envs map[string]string) (string, string, error) {
+	if terragrunt.Workspace == "" || terragrunt.Workspace == "default" {
+		return "", "", fmt.Errorf("the default workspace cannot be deleted")
+	}
+	_, _, err := terragrunt.runTerragruntCommand("workspace", true, envs, "select", "default")
+	if err != nil {
+		return "", "", err
+	}
+	params = append(params, terragrunt.Workspace)
+	return terragrunt.runTerragruntCommand("workspace", true, envs, append([]string{"delete"}, params...)...)
+}
+
+func (terragrunt Terragrunt) commandArgs(command string) []string {
+	if !terragrunt.RunAll || command == "show" {
+		return []string{command}
+	}
+	args := []string{"run-all", command, "--terragrunt-non-interactive"}
+	for _, dir := range terragrunt.IncludeDirs {
+		args = append(args, "--terragrunt-include-dir", dir)
+	}
+	if len(terragrunt.IncludeDirs) > 0 {
+		args = append(args, "--terragrunt-strict-include")
+	}
+	return args
+}
+
 func (terragrunt Terragrunt) runTerragruntCommand(command string, printOutputToStdout bool, envs map[string]string, arg ...string) (string, string, error) {
-	args := []string{command}
+	args := terragrunt.commandArgs(command)
 	args = append(args, arg...)
 	cmd := exec.Command("terragrunt", args...)
 	cmd.Dir = terragrunt.WorkingDir
 
-	env := os.Environ()
+	env := runners.Environ(terragrunt.EnvAllowlist, nil)
 	env = append(env, "TF_CLI_ARGS=-no-color")
 	env = append(env, "TF_IN_AUTOMATION=true")
 
-	for k, v := range envs {
+	for k, v := range terragrunt.Providers.withEnvs(envs) {
 		env = append(env, fmt.Sprintf("%s=%s", k, v))
 	}
 
@@ -80,3 +133,134 @@ func (terragrunt Terragrunt) runTerragruntCommand(command string, printOutputToS
 
 	return stdout.String(), stderr.String(), err
 }
+
+// TerragruntIncludeDirs maps changed files to the dirs of the run-all modules containing them, relative to projectDir,
+// and adds the modules depending on them, so dependents reading their outputs run too. ok is false when a changed file
+// belongs to no module, e.g. a shared root config, or the dependencies can't be read, so every module has to run
+func TerragruntIncludeDirs(repoRoot string, projectDir string, changedFiles []string) (includeDirs []string, ok bool) {
+	projectDir = path.Clean(projectDir)
+	seen := make(map[string]bool)
+	for _, file := range changedFiles {
+		rel, err := filepath.Rel(projectDir, path.Clean(file))
+		if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+			continue
+		}
+		moduleDir := ""
+		for dir := path.Dir(rel); dir != "." && dir != "/"; dir = path.Dir(dir) {
+			if _, err := os.Stat(path.Join(repoRoot, projectDir, dir, "terragrunt.hcl")); err == nil {
+				moduleDir = dir
+				break
+			}
+		}
+		if moduleDir == "" {
+			return nil, false
+		}
+		if !seen[moduleDir] {
+			seen[moduleDir] = true
+			includeDirs = append(includeDirs, moduleDir)
+		}
+	}
+	if len(includeDirs) == 0 {
+		return includeDirs, true
+	}
+
+	dependents, err := terragruntDependents(path.Join(repoRoot, projectDir))
+	if err != nil {
+		log.Printf("could not read the dependencies of the terragrunt modules, running all of them: %v", err)
+		return nil, false
+	}
+	for i := 0; i < len(includeDirs); i++ {
+		for _, dependent := range dependents[includeDirs[i]] {
+			if !seen[dependent] {
+				seen[dependent] = true
+				includeDirs = append(includeDirs, dependent)
+			}
+		}
+	}
+	return includeDirs, true
+}
+
+// terragruntDependents maps the dirs of the modules below root to the dirs of the modules depending on them through
+// dependency and dependencies blocks, relative to root
+func terragruntDependents(root string) (map[string][]string, error) {
+	dependents := make(map[string][]string)
+	err := filepath.WalkDir(root, func(file string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() && file != root && strings.HasPrefix(entry.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if entry.IsDir() || entry.Name() != "terragrunt.hcl" {
+			return nil
+		}
+		moduleDir, err := filepath.Rel(root, filepath.Dir(file))
+		if err != nil {
+			return err
+		}
+		dependencies, err := terragruntDependencies(file)
+		if err != nil {
+			return err
+		}
+		for _, dependency := range dependencies {
+			dependencyDir := path.Join(filepath.ToSlash(moduleDir), dependency)
+			if filepath.IsAbs(dependency) {
+				dependencyDir, err = filepath.Rel(root, dependency)
+				if err != nil {
+					return err
+				}
+				dependencyDir = filepath.ToSlash(dependencyDir)
+			}
+			dependents[dependencyDir] = append(dependents[dependencyDir], filepath.ToSlash(moduleDir))
+		}
+		return nil
+	})
+	return dependents, err
+}
+
+// terragruntDependencies returns the config paths of the dependency blocks and the paths of the dependencies block of a
+// terragrunt.hcl file. Paths have to be string literals, functions like find_in_parent_folders can't be evaluated here
+func terragruntDependencies(file string) ([]string, error) {
+	hclFile, diags := hclparse.NewParser().ParseHCLFile(file)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("could not parse %v: %v", file, diags.Error())
+	}
+	body, ok := hclFile.Body.(*hclsyntax.Body)
+	if !ok {
+		return nil, fmt.Errorf("could not parse %v", file)
+	}
+	var dependencies []string
+	for _, block := range body.Blocks {
+		var attribute *hclsyntax.Attribute
+		switch block.Type {
+		case "dependency":
+			attribute = block.Body.Attributes["config_path"]
+		case "dependencies":
+			attribute = block.Body.Attributes["paths"]
+		}
+		if attribute == nil {
+			continue
+		}
+		value, diags := attribute.Expr.Value(nil)
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("could not evaluate %v of %v: %v", attribute.Name, file, diags.Error())
+		}
+		if value.IsNull() || !value.IsWhollyKnown() {
+			return nil, fmt.Errorf("%v of %v has no value", attribute.Name, file)
+		}
+		if value.Type() == cty.String {
+			dependencies = append(dependencies, value.AsString())
+			continue
+		}
+		if !value.CanIterateElements() {
+			return nil, fmt.Errorf("%v of %v is not a string or a list of strings", attribute.Name, file)
+		}
+		for _, element := range value.AsValueSlice() {
+			if element.Type() != cty.String || element.IsNull() {
+				return nil, fmt.Errorf("%v of %v is not a list of strings", attribute.Name, file)
+			}
+			dependencies = append(dependencies, element.AsString())
+		}
+	}
+	return dependencies, nil
+}
@@ -0,0 +1,58 @@
+package terraform
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTerragruntRunAllCommandArgs(t *testing.T) {
+	tg := Terragrunt{RunAll: true, IncludeDirs: []string{"vpc"}}
+	assert.Equal(t, []string{"run-all", "plan", "--terragrunt-non-interactive", "--terragrunt-include-dir", "vpc", "--terragrunt-strict-include"}, tg.commandArgs("plan"))
+	assert.Equal(t, []string{"show"}, tg.commandArgs("show"))
+	assert.Equal(t, []string{"plan"}, Terragrunt{}.commandArgs("plan"))
+}
+
+func TestTerragruntIncludeDirs(t *testing.T) {
+	repoRoot := t.TempDir()
+	for _, dir := range []string{"live/vpc", "live/apps/api"} {
+		assert.NoError(t, os.MkdirAll(path.Join(repoRoot, dir), 0755))
+		assert.NoError(t, os.WriteFile(path.Join(repoRoot, dir, "terragrunt.hcl"), []byte(""), 0644))
+	}
+	assert.NoError(t, os.MkdirAll(path.Join(repoRoot, "live/apps/api/files"), 0755))
+
+	includeDirs, ok := TerragruntIncludeDirs(repoRoot, "live", []string{"live/vpc/terragrunt.hcl", "live/apps/api/files/policy.json", "live/vpc/main.tf", "other/README.md"})
+	assert.True(t, ok)
+	assert.Equal(t, []string{"vpc", "apps/api"}, includeDirs)
+
+	_, ok = TerragruntIncludeDirs(repoRoot, "live", []string{"live/terragrunt.hcl"})
+	assert.False(t, ok)
+}
+
+func TestTerragruntIncludeDirsAddsDependents(t *testing.T) {
+	repoRoot := t.TempDir()
+	modules := map[string]string{
+		"live/vpc":      ``,
+		"live/db":       "dependency \"vpc\" {\n  config_path = \"../vpc\"\n}\n",
+		"live/apps/api": "dependencies {\n  paths = [\"../../db\"]\n}\n",
+		"live/dns":      ``,
+	}
+	for dir, config := range modules {
+		assert.NoError(t, os.MkdirAll(path.Join(repoRoot, dir), 0755))
+		assert.NoError(t, os.WriteFile(path.Join(repoRoot, dir, "terragrunt.hcl"), []byte(config), 0644))
+	}
+
+	includeDirs, ok := TerragruntIncludeDirs(repoRoot, "live", []string{"live/vpc/main.tf"})
+	assert.True(t, ok)
+	assert.Equal(t, []string{"vpc", "db", "apps/api"}, includeDirs)
+
+	includeDirs, ok = TerragruntIncludeDirs(repoRoot, "live", []string{"live/dns/main.tf"})
+	assert.True(t, ok)
+	assert.Equal(t, []string{"dns"}, includeDirs)
+
+	assert.NoError(t, os.WriteFile(path.Join(repoRoot, "live/dns/terragrunt.hcl"), []byte("dependency \"vpc\" {\n  config_path = find_in_parent_folders(\"vpc\")\n}\n"), 0644))
+	_, ok = TerragruntIncludeDirs(repoRoot, "live", []string{"live/vpc/main.tf"})
+	assert.False(t, ok)
+}
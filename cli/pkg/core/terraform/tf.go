@@ -9,6 +9,8 @@ import (
 	"os/exec"
 	"regexp"
 	"strings"
+
+	"github.com/diggerhq/digger/cli/pkg/core/runners"
 )
 
 type TerraformExecutor interface {
@@ -17,15 +19,18 @@ type TerraformExecutor interface {
 	Destroy([]string, map[string]string) (string, string, error)
 	Plan([]string, map[string]string) (bool, string, string, error)
 	Show([]string, map[string]string) (string, string, error)
+	DeleteWorkspace([]string, map[string]string) (string, string, error)
 }
 
 type Terraform struct {
-	WorkingDir string
-	Workspace  string
+	WorkingDir   string
+	Workspace    string
+	EnvAllowlist []string
+	Providers    ProviderInstallation
 }
 
 func (tf Terraform) Init(params []string, envs map[string]string) (string, string, error) {
-	params = append(params, "-upgrade=true")
+	params = append(params, tf.Providers.initArgs()...)
 	params = append(params, "-input=false")
 	params = append(params, "-no-color")
 	stdout, stderr, _, err := tf.runTerraformCommand("init", true, envs, params...)
@@ -58,13 +63,32 @@ func (tf Terraform) Destroy(params []string, envs map[string]string) (string, st
 	return stdout, stderr, err
 }
 
+func (tf Terraform) DeleteWorkspace(params []string, envs map[string]string) (string, string, error) {
+	if tf.Workspace == "default" {
+		return "", "", fmt.Errorf("the default workspace cannot be deleted")
+	}
+	_, _, _, err := tf.runTerraformCommand("workspace", true, envs, "select", "default")
+	if err != nil {
+		return "", "", err
+	}
+	params = append(params, tf.Workspace)
+	stdout, stderr, _, err := tf.runTerraformCommand("workspace", true, envs, append([]string{"delete"}, params...)...)
+	return stdout, stderr, err
+}
+
+// MirrorProviders downloads the providers of the configuration, as pinned in .terraform.lock.hcl, into a filesystem mirror
+func (tf Terraform) MirrorProviders(mirrorDir string, platforms []string, envs map[string]string) (string, string, error) {
+	stdout, stderr, _, err := tf.runTerraformCommand("providers", true, envs, mirrorArgs(mirrorDir, platforms)...)
+	return stdout, stderr, err
+}
+
 func (tf Terraform) switchToWorkspace(envs map[string]string) error {
 	workspaces, _, _, err := tf.runTerraformCommand("workspace", false, envs, "list")
 	if err != nil {
 		return err
 	}
 	workspaces = tf.formatTerraformWorkspaces(workspaces)
-	if strings.Contains(workspaces, tf.Workspace) {
+	if workspaceExists(workspaces, tf.Workspace) {
 		_, _, _, err := tf.runTerraformCommand("workspace", true, envs, "select", tf.Workspace)
 		if err != nil {
 			return err
@@ -105,11 +129,7 @@ func (tf Terraform) runTerraformCommand(command string, printOutputToStdout bool
 	log.Printf("Running command: terraform %v", RedactSecrets(expandedArgs))
 	cmd.Dir = tf.WorkingDir
 
-	env := os.Environ()
-	for k, v := range envs {
-		env = append(env, fmt.Sprintf("%s=%s", k, v))
-	}
-	cmd.Env = env
+	cmd.Env = runners.Environ(tf.EnvAllowlist, tf.Providers.withEnvs(envs))
 	cmd.Stdout = mwout
 	cmd.Stderr = mwerr
 
@@ -136,6 +156,16 @@ func (tf Terraform) formatTerraformWorkspaces(list string) string {
 	return list
 }
 
+// workspaceExists checks for an exact match in a formatted workspace list so that e.g. "pr-1" does not match "pr-12"
+func workspaceExists(workspaces string, workspace string) bool {
+	for _, w := range strings.Split(workspaces, ",") {
+		if w == workspace {
+			return true
+		}
+	}
+	return false
+}
+
 func (tf Terraform) Plan(params []string, envs map[string]string) (bool, string, string, error) {
 	params = append(append(append(params, "-input=false"), "-no-color"), "-detailed-exitcode")
 	stdout, stderr, statusCode, err := tf.runTerraformCommand("plan", true, envs, params...)
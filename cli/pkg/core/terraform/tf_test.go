@@ -73,3 +73,9 @@ func TestRedactSecrets(t *testing.T) {
 	assert.Equal(t, redactedSecrets[1], "-backend-config=secret_key=<REDACTED>")
 	assert.Equal(t, redactedSecrets[2], "-backend-config=token=<REDACTED>")
 }
+
+func TestWorkspaceExistsMatchesExactName(t *testing.T) {
+	assert.True(t, workspaceExists("default,pr-1,pr-12", "pr-1"))
+	assert.True(t, workspaceExists("default,pr-12", "pr-12"))
+	assert.False(t, workspaceExists("default,pr-12", "pr-1"))
+}
@@ -0,0 +1,161 @@
+package digger
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/diggerhq/digger/cli/pkg/core/policy"
+	"github.com/diggerhq/digger/libs/comment_utils/reporting"
+	"github.com/diggerhq/digger/libs/orchestrator"
+	"github.com/diggerhq/digger/libs/terraform_utils"
+)
+
+// space left in check run texts for the code fence around the terraform output
+const checkRunTextLimit = 65000
+
+var resourceIndex = regexp.MustCompile(`\[[^\]]*\]$`)
+
+// reportPlanCheckRun attaches the plan of a project and its policy results to the project's check run
+func reportPlanCheckRun(checks orchestrator.CheckRunReporter, job orchestrator.Job, projectPath string, plan string, summary *terraform_utils.PlanSummary, result policy.PlanPolicyResult) {
+	title := "Plan succeeded"
+	if len(result.Deny) > 0 {
+		title = "Plan failed policy checks"
+	}
+	if summary != nil {
+		title = fmt.Sprintf("%v: %v to add, %v to change, %v to destroy", title, summary.ResourcesCreated, summary.ResourcesUpdated, summary.ResourcesDeleted)
+	}
+
+	var details strings.Builder
+	fmt.Fprintf(&details, "### Plan for `%v`\n\n", job.ProjectName)
+	if summary != nil {
+		fmt.Fprintf(&details, "| + | ~ | - |\n|---|---|---|\n| %v | %v | %v |\n\n", summary.ResourcesCreated, summary.ResourcesUpdated, summary.ResourcesDeleted)
+	}
+	writeViolations(&details, "Policy violations :x:", result.Deny)
+	writeViolations(&details, "Warnings :warning:", result.Warn)
+	writeViolations(&details, "Info :information_source:", result.Info)
+
+	output := orchestrator.CheckRunOutput{
+		Title:       title,
+		Summary:     details.String(),
+		Text:        "```terraform\n" + reporting.TruncatePlanOutput(plan, checkRunTextLimit, "The full output is available in the job logs.") + "\n```",
+		Annotations: policyAnnotations(projectPath, job.ProjectDir, result),
+	}
+	err := checks.SetCheckRunOutput(*job.PullRequestNumber, job.ProjectName+"/plan", output)
+	if err != nil {
+		log.Printf("Failed to report plan to check run: %v", err)
+	}
+}
+
+// reportApplyCheckRun attaches the apply output of a project to the project's check run
+func reportApplyCheckRun(checks orchestrator.CheckRunReporter, job orchestrator.Job, applyOutput string, applyErr error) {
+	title := "Apply succeeded"
+	summary := fmt.Sprintf("### Apply for `%v`\n", job.ProjectName)
+	if applyErr != nil {
+		title = "Apply failed"
+		summary = summary + "\n" + applyErr.Error() + "\n"
+	}
+	output := orchestrator.CheckRunOutput{
+		Title:   title,
+		Summary: summary,
+		Text:    "```terraform\n" + reporting.TruncatePlanOutput(applyOutput, checkRunTextLimit, "The full output is available in the job logs.") + "\n```",
+	}
+	err := checks.SetCheckRunOutput(*job.PullRequestNumber, job.ProjectName+"/apply", output)
+	if err != nil {
+		log.Printf("Failed to report apply to check run: %v", err)
+	}
+}
+
+func writeViolations(details *strings.Builder, title string, violations []policy.Violation) {
+	if len(violations) == 0 {
+		return
+	}
+	fmt.Fprintf(details, "#### %v\n", title)
+	for _, violation := range violations {
+		fmt.Fprintf(details, "- %v\n", violation.String())
+	}
+	details.WriteString("\n")
+}
+
+// policyAnnotations points violations about resources of the root module at the blocks declaring them
+func policyAnnotations(projectPath string, projectDir string, result policy.PlanPolicyResult) []orchestrator.CheckRunAnnotation {
+	levels := []struct {
+		level      string
+		violations []policy.Violation
+	}{
+		{"failure", result.Deny},
+		{"warning", result.Warn},
+		{"notice", result.Info},
+	}
+	var annotations []orchestrator.CheckRunAnnotation
+	for _, l := range levels {
+		for _, violation := range l.violations {
+			if violation.ResourceAddress == "" {
+				continue
+			}
+			file, line, found := findResourceDeclaration(projectPath, violation.ResourceAddress)
+			if !found {
+				continue
+			}
+			title := "Policy violation"
+			if violation.RuleId != "" {
+				title = violation.RuleId
+			}
+			message := violation.Message
+			if violation.Remediation != "" {
+				message = message + "\n" + violation.Remediation
+			}
+			annotations = append(annotations, orchestrator.CheckRunAnnotation{
+				Path:      filepath.ToSlash(filepath.Join(projectDir, file)),
+				StartLine: line,
+				EndLine:   line,
+				Level:     l.level,
+				Title:     title,
+				Message:   message,
+			})
+		}
+	}
+	return annotations
+}
+
+// findResourceDeclaration finds the file and line declaring a resource address like aws_s3_bucket.logs[0]
+func findResourceDeclaration(projectPath string, address string) (string, int, bool) {
+	address = resourceIndex.ReplaceAllString(address, "")
+	parts := strings.Split(address, ".")
+	kind := "resource"
+	if len(parts) == 3 && parts[0] == "data" {
+		kind = "data"
+		parts = parts[1:]
+	}
+	if len(parts) != 2 {
+		// resources of child modules are declared outside of the project's files
+		return "", 0, false
+	}
+	declaration := regexp.MustCompile(fmt.Sprintf(`^\s*%v\s+"%v"\s+"%v"`, kind, regexp.QuoteMeta(parts[0]), regexp.QuoteMeta(parts[1])))
+
+	files, err := filepath.Glob(filepath.Join(projectPath, "*.tf"))
+	if err != nil {
+		return "", 0, false
+	}
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(f)
+		line := 0
+		for scanner.Scan() {
+			line++
+			if declaration.MatchString(scanner.Text()) {
+				f.Close()
+				return filepath.Base(file), line, true
+			}
+		}
+		f.Close()
+	}
+	return "", 0, false
+}
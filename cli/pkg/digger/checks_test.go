@@ -0,0 +1,42 @@
+package digger
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/diggerhq/digger/cli/pkg/core/policy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyAnnotationsPointAtResourceDeclarations(t *testing.T) {
+	dir := t.TempDir()
+	main := "provider \"aws\" {}\n\nresource \"aws_s3_bucket\" \"logs\" {\n  bucket = \"logs\"\n}\n"
+	data := "data \"aws_iam_policy_document\" \"logs\" {\n}\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte(main), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "data.tf"), []byte(data), 0644))
+
+	result := policy.PlanPolicyResult{
+		Deny: []policy.Violation{
+			{Message: "bucket is public", RuleId: "S3_001", ResourceAddress: "aws_s3_bucket.logs[0]", Remediation: "set acl to private"},
+			{Message: "no owner tag"},
+			{Message: "module resource", ResourceAddress: "module.vpc.aws_vpc.main"},
+		},
+		Warn: []policy.Violation{
+			{Message: "wide policy", ResourceAddress: "data.aws_iam_policy_document.logs"},
+		},
+	}
+	annotations := policyAnnotations(dir, "prod", result)
+	require.Len(t, annotations, 2)
+
+	assert.Equal(t, "prod/main.tf", annotations[0].Path)
+	assert.Equal(t, 3, annotations[0].StartLine)
+	assert.Equal(t, "failure", annotations[0].Level)
+	assert.Equal(t, "S3_001", annotations[0].Title)
+	assert.Equal(t, "bucket is public\nset acl to private", annotations[0].Message)
+
+	assert.Equal(t, "prod/data.tf", annotations[1].Path)
+	assert.Equal(t, 1, annotations[1].StartLine)
+	assert.Equal(t, "warning", annotations[1].Level)
+}
@@ -0,0 +1,158 @@
+package digger
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/diggerhq/digger/cli/pkg/core/backend"
+	"github.com/diggerhq/digger/cli/pkg/core/policy"
+	"github.com/diggerhq/digger/cli/pkg/usage"
+	"github.com/diggerhq/digger/cli/pkg/utils"
+	"github.com/diggerhq/digger/libs/comment_utils/reporting"
+	comment_updater "github.com/diggerhq/digger/libs/comment_utils/summary"
+	"github.com/diggerhq/digger/libs/comment_utils/templates"
+	config "github.com/diggerhq/digger/libs/digger_config"
+	locking2 "github.com/diggerhq/digger/libs/locking"
+	"github.com/diggerhq/digger/libs/notifications"
+	orchestrator "github.com/diggerhq/digger/libs/orchestrator"
+	dg_github "github.com/diggerhq/digger/libs/orchestrator/github"
+	"github.com/diggerhq/digger/libs/storage"
+	"github.com/dominikbraun/graph"
+	"github.com/google/go-github/v61/github"
+	"gopkg.in/yaml.v3"
+)
+
+// LoadCommentTemplates loads the comment templates of the repository checked out in workingDir, from its templates
+// directory and the comment_templates of its config file
+func LoadCommentTemplates(workingDir string) *templates.Templates {
+	return templates.LoadOrDefaults(workingDir, config.ReadCommentTemplates(workingDir))
+}
+
+// NewPlanStorage creates the plan storage configured with PLAN_UPLOAD_DESTINATION, exiting when its configuration is invalid
+func NewPlanStorage(ghToken string, ghRepoOwner string, ghRepositoryName string, requestedBy string, prNumber *int) storage.PlanStorage {
+	planStorage, err := storage.NewPlanStorageFromEnv(ghToken, ghRepoOwner, ghRepositoryName, prNumber)
+	if err != nil {
+		usage.ReportErrorAndExit(requestedBy, err.Error(), 9)
+	}
+	return planStorage
+}
+
+// ReportProjects sends the projects of the digger config of repository to the backend, failures are logged
+func ReportProjects(backendApi backend.Api, repository string, diggerConfig *config.DiggerConfig, diggerConfigYaml *config.DiggerConfigYaml) {
+	yamlData, err := yaml.Marshal(diggerConfigYaml)
+	if err != nil {
+		log.Fatalf("error: %v", err)
+	}
+
+	repo := strings.ReplaceAll(repository, "/", "-")
+	for _, p := range diggerConfig.Projects {
+		err = backendApi.ReportProject(repo, p.Name, string(yamlData))
+		if err != nil {
+			log.Printf("Failed to report project %s. %s\n", p.Name, err)
+		}
+	}
+}
+
+// RunPullRequestEvent runs the jobs of a pull request or comment event in CIs without a backend, events of other VCSs
+// are converted to GitHub events first. prService reports the statuses of projects, ciService comments on the pull
+// request, answers help comments, merges it when automerge is on and sets the aggregate status
+func RunPullRequestEvent(ghEvent interface{}, prService orchestrator.PullRequestService, ciService orchestrator.PullRequestService, orgService orchestrator.OrgService, lock locking2.Lock, policyChecker policy.Checker, backendApi backend.Api, reportingStrategy reporting.ReportStrategy, diggerConfig *config.DiggerConfig, dependencyGraph graph.Graph[string, config.Project], actor string, token string, repoOwner string, repositoryName string, currentDir string) {
+	impactedProjects, requestedProject, prNumber, err := dg_github.ProcessGitHubEvent(ghEvent, diggerConfig, ciService)
+	if err != nil {
+		if errors.Is(err, dg_github.UnhandledMergeGroupEventError) {
+			usage.ReportErrorAndExit(actor, fmt.Sprintf("Graceful handling of GitHub event. %s", err), 0)
+		} else {
+			usage.ReportErrorAndExit(actor, fmt.Sprintf("Failed to process event. %s", err), 6)
+		}
+	}
+	log.Printf("Following projects are impacted by pull request #%d\n", prNumber)
+	for _, p := range impactedProjects {
+		log.Printf("- %s\n", p.Name)
+	}
+	log.Println("Event processed successfully")
+
+	if dg_github.CheckIfHelpComment(ghEvent) {
+		reply := utils.GetCommands()
+		_, err := ciService.PublishComment(prNumber, reply)
+		if err != nil {
+			usage.ReportErrorAndExit(actor, "Failed to publish help command output", 1)
+		}
+	}
+
+	if len(impactedProjects) == 0 {
+		usage.ReportErrorAndExit(actor, "No projects impacted", 0)
+	}
+
+	var jobs []orchestrator.Job
+	coversAllImpactedProjects := false
+	err = nil
+	if prEvent, ok := ghEvent.(github.PullRequestEvent); ok {
+		jobs, coversAllImpactedProjects, err = dg_github.ConvertGithubPullRequestEventToJobs(&prEvent, impactedProjects, requestedProject, *diggerConfig)
+	} else if commentEvent, ok := ghEvent.(github.IssueCommentEvent); ok {
+		prBranchName, _, branchErr := ciService.GetBranchName(prNumber)
+		if branchErr != nil {
+			usage.ReportErrorAndExit(actor, fmt.Sprintf("Error while retriving default branch from Issue: %v", branchErr), 6)
+		}
+		jobs, coversAllImpactedProjects, err = dg_github.ConvertGithubIssueCommentEventToJobs(&commentEvent, impactedProjects, requestedProject, diggerConfig.Workflows, prBranchName)
+	} else {
+		usage.ReportErrorAndExit(actor, fmt.Sprintf("Unsupported event type. %T", ghEvent), 6)
+	}
+
+	if err != nil {
+		usage.ReportErrorAndExit(actor, fmt.Sprintf("Failed to convert event to commands. %s", err), 7)
+	}
+	log.Println("Event converted to commands successfully")
+	logCommands(jobs)
+
+	err = ciService.SetOutput(prNumber, "DIGGER_PR_NUMBER", fmt.Sprintf("%v", prNumber))
+	if err != nil {
+		usage.ReportErrorAndExit(actor, fmt.Sprintf("Failed to set job output. Exiting. %s", err), 4)
+	}
+
+	planStorage := NewPlanStorage(token, repoOwner, repositoryName, actor, &prNumber)
+
+	reporter := &reporting.CiReporter{
+		CiService:         ciService,
+		PrNumber:          prNumber,
+		ReportStrategy:    reportingStrategy,
+		IsSupportMarkdown: true,
+	}
+
+	jobs = SortedCommandsByDependency(jobs, &dependencyGraph)
+
+	allAppliesSuccessful, atLeastOneApply, err := RunJobs(jobs, prService, orgService, lock, reporter, planStorage, policyChecker, comment_updater.NoopCommentUpdater{}, backendApi, "", false, false, 0, currentDir, diggerConfig.Hooks, diggerConfig.ProviderCache, LoadCommentTemplates(currentDir), notifications.Load(diggerConfig.Notifications, os.LookupEnv))
+	if err != nil {
+		usage.ReportErrorAndExit(actor, fmt.Sprintf("Failed to run commands. %s", err), 8)
+	}
+
+	if diggerConfig.AutoMerge && allAppliesSuccessful && atLeastOneApply && coversAllImpactedProjects {
+		MergePullRequest(ciService, prNumber)
+		log.Println("PR merged successfully")
+	}
+
+	if allAppliesSuccessful {
+		// aggreate status checks: success
+		if atLeastOneApply {
+			ciService.SetStatus(prNumber, "success", "digger/apply")
+		} else {
+			ciService.SetStatus(prNumber, "success", "digger/plan")
+		}
+	}
+
+	log.Println("Commands executed successfully")
+}
+
+func logCommands(projectCommands []orchestrator.Job) {
+	logMessage := fmt.Sprintf("Following commands are going to be executed:\n")
+	for _, pc := range projectCommands {
+		logMessage += fmt.Sprintf("project: %s: commands: ", pc.ProjectName)
+		for _, c := range pc.Commands {
+			logMessage += fmt.Sprintf("\"%s\", ", c)
+		}
+		logMessage += "\n"
+	}
+	log.Print(logMessage)
+}
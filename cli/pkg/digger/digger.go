@@ -13,6 +13,7 @@ import (
 	"time"
 
 	comment_updater "github.com/diggerhq/digger/libs/comment_utils/summary"
+	"github.com/diggerhq/digger/libs/comment_utils/templates"
 	coreutils "github.com/diggerhq/digger/libs/comment_utils/utils"
 	locking2 "github.com/diggerhq/digger/libs/locking"
 
@@ -21,13 +22,16 @@ import (
 	"github.com/diggerhq/digger/cli/pkg/core/execution"
 	"github.com/diggerhq/digger/cli/pkg/core/policy"
 	"github.com/diggerhq/digger/cli/pkg/core/runners"
-	"github.com/diggerhq/digger/cli/pkg/core/storage"
 	"github.com/diggerhq/digger/cli/pkg/core/terraform"
+	"github.com/diggerhq/digger/cli/pkg/hooks"
+	"github.com/diggerhq/digger/cli/pkg/reports"
 	"github.com/diggerhq/digger/cli/pkg/usage"
 	utils "github.com/diggerhq/digger/cli/pkg/utils"
 	"github.com/diggerhq/digger/libs/comment_utils/reporting"
 	config "github.com/diggerhq/digger/libs/digger_config"
+	"github.com/diggerhq/digger/libs/notifications"
 	orchestrator "github.com/diggerhq/digger/libs/orchestrator"
+	"github.com/diggerhq/digger/libs/storage"
 	"github.com/diggerhq/digger/libs/terraform_utils"
 
 	"github.com/dominikbraun/graph"
@@ -41,6 +45,7 @@ const (
 	GitLab    = CIName("gitlab")
 	BitBucket = CIName("bitbucket")
 	Azure     = CIName("azure")
+	Gitea     = CIName("gitea")
 )
 
 func (ci CIName) String() string {
@@ -53,6 +58,10 @@ func DetectCI() CIName {
 		return os.Getenv(key) != ""
 	}
 
+	// Gitea and Forgejo runners set GITHUB_ACTIONS too
+	if notEmpty("GITEA_ACTIONS") || notEmpty("FORGEJO_ACTIONS") {
+		return Gitea
+	}
 	if notEmpty("GITHUB_ACTIONS") {
 		return GitHub
 	}
@@ -69,14 +78,25 @@ func DetectCI() CIName {
 
 }
 
-func RunJobs(jobs []orchestrator.Job, prService orchestrator.PullRequestService, orgService orchestrator.OrgService, lock locking2.Lock, reporter reporting.Reporter, planStorage storage.PlanStorage, policyChecker policy.Checker, commentUpdater comment_updater.CommentUpdater, backendApi backend.Api, jobId string, reportFinalStatusToBackend bool, reportTerraformOutput bool, prCommentId int64, workingDir string) (bool, bool, error) {
+func RunJobs(jobs []orchestrator.Job, prService orchestrator.PullRequestService, orgService orchestrator.OrgService, lock locking2.Lock, reporter reporting.Reporter, planStorage storage.PlanStorage, policyChecker policy.Checker, commentUpdater comment_updater.CommentUpdater, backendApi backend.Api, jobId string, reportFinalStatusToBackend bool, reportTerraformOutput bool, prCommentId int64, workingDir string, hooksConfig config.Hooks, providerCache config.ProviderCache, commentTemplates *templates.Templates, notifier notifications.Notifier) (bool, bool, error) {
 
 	defer reporter.Flush()
 
+	providers, err := terraform.NewProviderInstallation(providerCache)
+	if err != nil {
+		return false, false, err
+	}
+
 	runStartedAt := time.Now()
+	jobsReport := reports.Report{StartedAt: runStartedAt}
+	defer func() {
+		jobsReport.FinishedAt = time.Now()
+		reports.WriteConfigured(jobsReport)
+	}()
 
 	exectorResults := make([]execution.DiggerExecutorResult, len(jobs))
 	appliesPerProject := make(map[string]bool)
+	hookRunner := hooks.HookRunner{Hooks: hooksConfig}
 
 	for i, job := range jobs {
 		splits := strings.Split(job.Namespace, "/")
@@ -84,22 +104,34 @@ func RunJobs(jobs []orchestrator.Job, prService orchestrator.PullRequestService,
 		SCMrepository := splits[1]
 
 		for _, command := range job.Commands {
-			allowedToPerformCommand, err := policyChecker.CheckAccessPolicy(orgService, &prService, SCMOrganisation, SCMrepository, job.ProjectName, job.ProjectDir, command, job.PullRequestNumber, job.RequestedBy, []string{})
+			jobReport := newJobReport(job, command)
+			allowedToPerformCommand, err := policyChecker.CheckAccessPolicy(orgService, &prService, SCMOrganisation, SCMrepository, job.ProjectName, job.ProjectDir, job.ProjectWorkspace, command, job.PullRequestNumber, job.RequestedBy, []string{}, nil)
 
 			if err != nil {
 				return false, false, fmt.Errorf("error checking policy: %v", err)
 			}
 
 			if !allowedToPerformCommand {
-				msg := reportPolicyError(job.ProjectName, command, job.RequestedBy, reporter)
+				msg := reportPolicyError(job.ProjectName, command, job.RequestedBy, reporter, commentTemplates)
 				log.Printf("Skipping command ... %v for project %v", command, job.ProjectName)
 				log.Println(msg)
 				appliesPerProject[job.ProjectName] = false
+				jobReport.Status = reports.StatusSkipped
+				jobReport.Error = msg
+				jobsReport.Jobs = append(jobsReport.Jobs, jobReport)
+				notifyJobResult(notifier, job, jobReport, msg, policyDeniedError{msg}, reportFinalStatusToBackend)
 				continue
 			}
 
-			executorResult, output, planJson, err := run(command, job, policyChecker, orgService, SCMOrganisation, SCMrepository, job.PullRequestNumber, job.RequestedBy, reporter, lock, prService, job.Namespace, workingDir, planStorage, appliesPerProject)
+			executorResult, output, planJson, err := runWithHooks(hookRunner, command, job, workingDir, reporter, commentTemplates, func() (*execution.DiggerExecutorResult, string, string, error) {
+				return run(command, job, policyChecker, orgService, SCMOrganisation, SCMrepository, job.PullRequestNumber, job.RequestedBy, reporter, lock, prService, job.Namespace, workingDir, planStorage, appliesPerProject, providers, &jobReport, commentTemplates, notifier)
+			})
+			jobReport.DurationSeconds = time.Since(jobReport.StartedAt).Seconds()
 			if err != nil {
+				jobReport.Status = reports.StatusFailed
+				jobReport.Error = err.Error()
+				jobsReport.Jobs = append(jobsReport.Jobs, jobReport)
+				notifyJobResult(notifier, job, jobReport, output, err, reportFinalStatusToBackend)
 				_, reportErr := backendApi.ReportProjectRun(SCMOrganisation+"-"+SCMrepository, job.ProjectName, runStartedAt, time.Now(), "FAILED", command, output)
 				if reportErr != nil {
 					log.Printf("error reporting project Run err: %v.\n", reportErr)
@@ -112,6 +144,9 @@ func RunJobs(jobs []orchestrator.Job, prService orchestrator.PullRequestService,
 				break
 			}
 			exectorResults[i] = *executorResult
+			jobReport.Status = reports.StatusSucceeded
+			jobsReport.Jobs = append(jobsReport.Jobs, jobReport)
+			notifyJobResult(notifier, job, jobReport, output, nil, reportFinalStatusToBackend)
 			var runDetails backend.RunDetails
 			runDetails, err = backendApi.ReportProjectRun(SCMOrganisation+"-"+SCMrepository, job.ProjectName, runStartedAt, time.Now(), "SUCCESS", command, output)
 			if err != nil {
@@ -173,41 +208,134 @@ func RunJobs(jobs []orchestrator.Job, prService orchestrator.PullRequestService,
 	return allAppliesSuccess, atLeastOneApply, nil
 }
 
-func reportPolicyError(projectName string, command string, requestedBy string, reporter reporting.Reporter) string {
-	msg := fmt.Sprintf("User %s is not allowed to perform action: %s. Check your policies :x:", requestedBy, command)
-	if reporter.SupportsMarkdown() {
-		_, _, err := reporter.Report(msg, coreutils.AsCollapsibleComment(fmt.Sprintf("Policy violation for <b>%v - %v</b>", projectName, command), false))
+// runWithHooks wraps a single command run with the repo level lifecycle hooks
+func runWithHooks(hookRunner hooks.HookRunner, command string, job orchestrator.Job, workingDir string, reporter reporting.Reporter, commentTemplates *templates.Templates, runCommand func() (*execution.DiggerExecutorResult, string, string, error)) (*execution.DiggerExecutorResult, string, string, error) {
+	projectPath := path.Join(workingDir, job.ProjectDir)
+	hookRunner.CommandRunner = runners.CommandRunner{EnvAllowlist: job.EnvAllowlist}
+	hookContext := hooks.Context{
+		Namespace:   job.Namespace,
+		ProjectName: job.ProjectName,
+		ProjectDir:  job.ProjectDir,
+		Workspace:   job.ProjectWorkspace,
+		Command:     command,
+		PrNumber:    job.PullRequestNumber,
+		RequestedBy: job.RequestedBy,
+	}
+
+	var preEvent, postEvent hooks.Event
+	switch command {
+	case "mantis plan":
+		preEvent, postEvent = hooks.EventPrePlan, hooks.EventPostPlan
+	case "mantis apply":
+		preEvent, postEvent = hooks.EventPreApply, hooks.EventPostApply
+	}
+
+	var executorResult *execution.DiggerExecutorResult
+	var output, planJson string
+	var err error
+	if preEvent != "" {
+		hookContext.Event = preEvent
+		err = hookRunner.Run(projectPath, job.RunEnvVars, hookContext)
 		if err != nil {
-			log.Printf("Error publishing comment: %v", err)
+			output = reportHookError(reporter, commentTemplates, job.ProjectName, err)
 		}
-	} else {
-		_, _, err := reporter.Report(msg, coreutils.AsComment(fmt.Sprintf("Policy violation for %v - %v", projectName, command)))
+	}
+	if err == nil {
+		executorResult, output, planJson, err = runCommand()
+	}
+	if err == nil && postEvent != "" {
+		hookContext.Event = postEvent
+		hookContext.Outcome = hooks.OutcomeSuccess
+		if executorResult != nil && executorResult.PlanResult != nil {
+			hookContext.PlanSummary = &executorResult.PlanResult.PlanSummary
+		}
+		err = hookRunner.Run(projectPath, job.RunEnvVars, hookContext)
 		if err != nil {
-			log.Printf("Error publishing comment: %v", err)
+			output = reportHookError(reporter, commentTemplates, job.ProjectName, err)
+		}
+	}
+	if err != nil {
+		hookContext.Event = hooks.EventOnFailure
+		hookContext.Outcome = hooks.OutcomeFailure
+		hookContext.Error = err.Error()
+		hookErr := hookRunner.Run(projectPath, job.RunEnvVars, hookContext)
+		if hookErr != nil {
+			log.Printf("failed to run on_failure hooks for project %v: %v", job.ProjectName, hookErr)
 		}
 	}
+	return executorResult, output, planJson, err
+}
+
+func reportHookError(reporter reporting.Reporter, commentTemplates *templates.Templates, projectName string, err error) string {
+	msg := fmt.Sprintf("Hook failed for project %v: %v", projectName, err)
+	log.Println(msg)
+	formatter := templates.OrDefaults(commentTemplates).Formatter(templates.Error, templates.CommentData{Project: projectName, Title: "Hook failure"})
+	_, _, reportErr := reporter.Report(msg, formatter)
+	if reportErr != nil {
+		log.Printf("Error publishing comment: %v", reportErr)
+	}
+	return msg
+}
+
+func reportPolicyError(projectName string, command string, requestedBy string, reporter reporting.Reporter, commentTemplates *templates.Templates) string {
+	msg := fmt.Sprintf("User %s is not allowed to perform action: %s. Check your policies :x:", requestedBy, command)
+	data := templates.CommentData{
+		Project:          projectName,
+		Title:            fmt.Sprintf("Policy violation for %v - %v", projectName, command),
+		Markdown:         reporter.SupportsMarkdown(),
+		PolicyViolations: []string{msg},
+	}
+	if reporter.SupportsMarkdown() {
+		data.Title = fmt.Sprintf("Policy violation for <b>%v - %v</b>", projectName, command)
+	}
+	_, _, err := reporter.Report(msg, templates.OrDefaults(commentTemplates).Formatter(templates.Error, data))
+	if err != nil {
+		log.Printf("Error publishing comment: %v", err)
+	}
 	return msg
 }
 
-func run(command string, job orchestrator.Job, policyChecker policy.Checker, orgService orchestrator.OrgService, SCMOrganisation string, SCMrepository string, PRNumber *int, requestedBy string, reporter reporting.Reporter, lock locking2.Lock, prService orchestrator.PullRequestService, projectNamespace string, workingDir string, planStorage storage.PlanStorage, appliesPerProject map[string]bool) (*execution.DiggerExecutorResult, string, string, error) {
+// planPolicyReport lists the deny, warn and info results of plan policies below title, one per line
+func planPolicyReport(title string, result policy.PlanPolicyResult, lineSeparator string) string {
+	lines := []string{title}
+	for _, violation := range result.Deny {
+		lines = append(lines, fmt.Sprintf("    %v", violation))
+	}
+	if len(result.Warn) > 0 {
+		lines = append(lines, "Warnings :warning:")
+		for _, violation := range result.Warn {
+			lines = append(lines, fmt.Sprintf("    %v", violation))
+		}
+	}
+	if len(result.Info) > 0 {
+		lines = append(lines, "Info :information_source:")
+		for _, violation := range result.Info {
+			lines = append(lines, fmt.Sprintf("    %v", violation))
+		}
+	}
+	return strings.Join(lines, lineSeparator)
+}
+
+func run(command string, job orchestrator.Job, policyChecker policy.Checker, orgService orchestrator.OrgService, SCMOrganisation string, SCMrepository string, PRNumber *int, requestedBy string, reporter reporting.Reporter, lock locking2.Lock, prService orchestrator.PullRequestService, projectNamespace string, workingDir string, planStorage storage.PlanStorage, appliesPerProject map[string]bool, providers terraform.ProviderInstallation, jobReport *reports.JobReport, commentTemplates *templates.Templates, notifier notifications.Notifier) (*execution.DiggerExecutorResult, string, string, error) {
 	log.Printf("Running '%s' for project '%s' (workflow: %s)\n", command, job.ProjectName, job.ProjectWorkflow)
 	var planJson string
-	allowedToPerformCommand, err := policyChecker.CheckAccessPolicy(orgService, &prService, SCMOrganisation, SCMrepository, job.ProjectName, job.ProjectDir, command, job.PullRequestNumber, requestedBy, []string{})
+	allowedToPerformCommand, err := policyChecker.CheckAccessPolicy(orgService, &prService, SCMOrganisation, SCMrepository, job.ProjectName, job.ProjectDir, job.ProjectWorkspace, command, job.PullRequestNumber, requestedBy, []string{}, nil)
 
 	if err != nil {
 		return nil, "error checking policy", planJson, fmt.Errorf("error checking policy: %v", err)
 	}
 
 	if !allowedToPerformCommand {
-		msg := reportPolicyError(job.ProjectName, command, requestedBy, reporter)
+		msg := reportPolicyError(job.ProjectName, command, requestedBy, reporter, commentTemplates)
 		log.Println(msg)
-		return nil, msg, planJson, errors.New(msg)
+		return nil, msg, planJson, policyDeniedError{msg}
 	}
 
 	err = job.PopulateAwsCredentialsEnvVarsForJob()
 	if err != nil {
 		log.Fatalf("failed to fetch AWS keys, %v", err)
 	}
+	reporter = reporting.NewMaskingReporter(reporter, job.SecretValues())
 
 	projectLock := &locking2.PullRequestLock{
 		InternalLock:     lock,
@@ -216,45 +344,66 @@ func run(command string, job orchestrator.Job, policyChecker policy.Checker, org
 		ProjectName:      job.ProjectName,
 		ProjectNamespace: projectNamespace,
 		PrNumber:         *job.PullRequestNumber,
+		Templates:        commentTemplates,
+		Notifier:         notifier,
+	}
+	if job.EphemeralWorkspace {
+		projectLock.EphemeralWorkspace = job.ProjectWorkspace
 	}
 
 	var terraformExecutor terraform.TerraformExecutor
 	projectPath := path.Join(workingDir, job.ProjectDir)
 	if job.Terragrunt {
-		terraformExecutor = terraform.Terragrunt{WorkingDir: projectPath}
+		terragrunt := terraform.Terragrunt{WorkingDir: projectPath, Workspace: job.ProjectWorkspace, EnvAllowlist: job.EnvAllowlist, Providers: providers, RunAll: job.TerragruntRunAll}
+		if job.TerragruntRunAll && job.PullRequestNumber != nil {
+			changedFiles, err := prService.GetChangedFiles(*job.PullRequestNumber)
+			if err != nil {
+				log.Printf("could not list changed files, running all terragrunt modules: %v", err)
+			} else if includeDirs, ok := terraform.TerragruntIncludeDirs(workingDir, job.ProjectDir, changedFiles); ok {
+				terragrunt.IncludeDirs = includeDirs
+			}
+		}
+		terraformExecutor = terragrunt
 	} else if job.OpenTofu {
-		terraformExecutor = terraform.OpenTofu{WorkingDir: projectPath, Workspace: job.ProjectWorkspace}
+		terraformExecutor = terraform.OpenTofu{WorkingDir: projectPath, Workspace: job.ProjectWorkspace, EnvAllowlist: job.EnvAllowlist, Providers: providers}
 	} else {
-		terraformExecutor = terraform.Terraform{WorkingDir: projectPath, Workspace: job.ProjectWorkspace}
+		terraformExecutor = terraform.Terraform{WorkingDir: projectPath, Workspace: job.ProjectWorkspace, EnvAllowlist: job.EnvAllowlist, Providers: providers}
 	}
 
-	commandRunner := runners.CommandRunner{}
+	commandRunner := runners.CommandRunner{EnvAllowlist: job.EnvAllowlist}
 	planPathProvider := execution.ProjectPathProvider{
 		ProjectPath:      projectPath,
 		ProjectNamespace: projectNamespace,
 		ProjectName:      job.ProjectName,
 		PRNumber:         PRNumber,
 	}
+	if job.EphemeralWorkspace {
+		planPathProvider.EphemeralWorkspace = job.ProjectWorkspace
+	}
 
+	var executor execution.PlanJsonRetriever = execution.DiggerExecutor{
+		ProjectNamespace:  projectNamespace,
+		ProjectName:       job.ProjectName,
+		ProjectPath:       projectPath,
+		StateEnvVars:      job.StateEnvVars,
+		RunEnvVars:        job.RunEnvVars,
+		CommandEnvVars:    job.CommandEnvVars,
+		ApplyStage:        job.ApplyStage,
+		PlanStage:         job.PlanStage,
+		CommandRunner:     commandRunner,
+		TerraformExecutor: terraformExecutor,
+		Reporter:          reporter,
+		PlanStorage:       planStorage,
+		PlanPathProvider:  planPathProvider,
+		Templates:         commentTemplates,
+	}
+	if job.TerragruntRunAll {
+		executor = execution.TerragruntRunAllExecutor{DiggerExecutor: executor.(execution.DiggerExecutor)}
+	}
 	diggerExecutor := execution.LockingExecutorWrapper{
-		ProjectLock: projectLock,
-		Executor: execution.DiggerExecutor{
-			ProjectNamespace:  projectNamespace,
-			ProjectName:       job.ProjectName,
-			ProjectPath:       projectPath,
-			StateEnvVars:      job.StateEnvVars,
-			RunEnvVars:        job.RunEnvVars,
-			CommandEnvVars:    job.CommandEnvVars,
-			ApplyStage:        job.ApplyStage,
-			PlanStage:         job.PlanStage,
-			CommandRunner:     commandRunner,
-			TerraformExecutor: terraformExecutor,
-			Reporter:          reporter,
-			PlanStorage:       planStorage,
-			PlanPathProvider:  planPathProvider,
-		},
+		ProjectLock: recordingLock{ProjectLock: projectLock, outcome: &jobReport.Lock},
+		Executor:    executor,
 	}
-	executor := diggerExecutor.Executor.(execution.DiggerExecutor)
 
 	switch command {
 
@@ -281,14 +430,22 @@ func run(command string, job orchestrator.Job, policyChecker policy.Checker, org
 
 			return nil, msg, planJson, fmt.Errorf(msg)
 		} else if planPerformed {
+			if planSummary != nil {
+				jobReport.PlanSummary = &reports.PlanSummary{Created: planSummary.ResourcesCreated, Updated: planSummary.ResourcesUpdated, Deleted: planSummary.ResourcesDeleted}
+			}
 			if isNonEmptyPlan {
-				reportTerraformPlanOutput(reporter, projectLock.LockId(), plan)
-				planIsAllowed, messages, err := policyChecker.CheckPlanPolicy(SCMrepository, SCMOrganisation, job.ProjectName, job.ProjectDir, planJsonOutput)
+				planIsAllowed, planPolicyResult, err := policyChecker.CheckPlanPolicy(SCMrepository, SCMOrganisation, job.ProjectName, job.ProjectDir, planJsonOutput)
+				reportTerraformPlanOutput(reporter, commentTemplates, job.ProjectName, plan, planSummary, planPolicyResult.DenyMessages())
 				if err != nil {
 					msg := fmt.Sprintf("Failed to validate plan. %v", err)
 					log.Printf(msg)
 					return nil, msg, planJson, fmt.Errorf(msg)
 				}
+				jobReport.PolicyViolations = jobPolicyViolations(projectPath, job.ProjectDir, planPolicyResult)
+				checks, useCheckRuns := prService.(orchestrator.CheckRunReporter)
+				if useCheckRuns {
+					reportPlanCheckRun(checks, job, projectPath, plan, planSummary, planPolicyResult)
+				}
 				var planPolicyFormatter func(report string) string
 				summary := fmt.Sprintf("Terraform plan validation check (%v)", job.ProjectName)
 				if reporter.SupportsMarkdown() {
@@ -303,22 +460,23 @@ func run(command string, job orchestrator.Job, policyChecker policy.Checker, org
 				}
 
 				if !planIsAllowed {
-					planReportMessage := "Terraform plan failed validation checks :x:<br>"
-					preformattedMessaged := make([]string, 0)
-					for _, message := range messages {
-						preformattedMessaged = append(preformattedMessaged, fmt.Sprintf("    %v", message))
-					}
-					planReportMessage = planReportMessage + strings.Join(preformattedMessaged, "<br>")
+					planReportMessage := planPolicyReport("Terraform plan failed validation checks :x:", planPolicyResult, "<br>")
 					_, _, err = reporter.Report(planReportMessage, planPolicyFormatter)
 
 					if err != nil {
 						log.Printf("Failed to report plan. %v", err)
 					}
+					if useCheckRuns {
+						err = prService.SetStatus(*job.PullRequestNumber, "failure", job.ProjectName+"/plan")
+						if err != nil {
+							log.Printf("Failed to complete plan check run. %v", err)
+						}
+					}
 					msg := fmt.Sprintf("Plan is not allowed")
 					log.Printf(msg)
-					return nil, msg, planJson, fmt.Errorf(msg)
+					return nil, msg, planJson, policyDeniedError{msg}
 				} else {
-					_, _, err := reporter.Report("Terraform plan validation checks succeeded :white_check_mark:", planPolicyFormatter)
+					_, _, err := reporter.Report(planPolicyReport("Terraform plan validation checks succeeded :white_check_mark:", planPolicyResult, "<br>"), planPolicyFormatter)
 					if err != nil {
 						log.Printf("Failed to report plan. %v", err)
 					}
@@ -335,6 +493,9 @@ func run(command string, job orchestrator.Job, policyChecker policy.Checker, org
 				msg := fmt.Sprintf("Failed to set PR status. %v", err)
 				return nil, msg, planJson, fmt.Errorf(msg)
 			}
+			if planStorage != nil {
+				jobReport.PlanArtifact = &reports.PlanArtifact{Name: planPathProvider.ArtifactName(), Path: planPathProvider.StoredPlanFilePath()}
+			}
 			result := execution.DiggerExecutorResult{
 				TerraformOutput: plan,
 				PlanResult: &execution.DiggerExecutorPlanResult{
@@ -370,15 +531,16 @@ func run(command string, job orchestrator.Job, policyChecker policy.Checker, org
 		}
 		log.Printf("PR status, mergeable: %v, merged: %v\n", isMergeable, isMerged)
 		if !isMergeable && !isMerged {
-			comment := reportApplyMergeabilityError(reporter)
+			comment := reportApplyMergeabilityError(reporter, commentTemplates)
 
 			return nil, comment, planJson, fmt.Errorf(comment)
 		} else {
 
 			// checking policies (plan, access)
 			var planPolicyViolations []string
+			var storedPlanSummary *terraform_utils.PlanSummary
 
-			if os.Getenv("PLAN_UPLOAD_DESTINATION") != "" {
+			if planStorage != nil {
 				terraformPlanJsonStr, err := executor.RetrievePlanJson()
 				if err != nil {
 					msg := fmt.Sprintf("Failed to retrieve stored plan. %v", err)
@@ -386,33 +548,40 @@ func run(command string, job orchestrator.Job, policyChecker policy.Checker, org
 					return nil, msg, planJson, fmt.Errorf(msg)
 				}
 
-				_, violations, err := policyChecker.CheckPlanPolicy(SCMrepository, SCMOrganisation, job.ProjectName, job.ProjectDir, terraformPlanJsonStr)
+				_, planPolicyResult, err := policyChecker.CheckPlanPolicy(SCMrepository, SCMOrganisation, job.ProjectName, job.ProjectDir, terraformPlanJsonStr)
 				if err != nil {
 					msg := fmt.Sprintf("Failed to check plan policy. %v", err)
 					log.Printf(msg)
 					return nil, msg, planJson, fmt.Errorf(msg)
 				}
-				planPolicyViolations = violations
+				planPolicyViolations = planPolicyResult.DenyMessages()
+				_, storedPlanSummary, err = terraform_utils.GetPlanSummary(terraformPlanJsonStr)
+				if err != nil {
+					log.Printf("Failed to summarize stored plan, access policies will not get its summary. %v", err)
+				}
 			} else {
 				log.Printf("Skipping plan policy checks because plan storage is not configured.")
 				planPolicyViolations = []string{}
 			}
 
-			allowedToApply, err := policyChecker.CheckAccessPolicy(orgService, &prService, SCMOrganisation, SCMrepository, job.ProjectName, job.ProjectDir, command, job.PullRequestNumber, requestedBy, planPolicyViolations)
+			allowedToApply, err := policyChecker.CheckAccessPolicy(orgService, &prService, SCMOrganisation, SCMrepository, job.ProjectName, job.ProjectDir, job.ProjectWorkspace, command, job.PullRequestNumber, requestedBy, planPolicyViolations, storedPlanSummary)
 			if err != nil {
 				msg := fmt.Sprintf("Failed to run plan policy check before apply. %v", err)
 				log.Printf(msg)
 				return nil, msg, planJson, fmt.Errorf(msg)
 			}
 			if !allowedToApply {
-				msg := reportPolicyError(job.ProjectName, command, requestedBy, reporter)
+				msg := reportPolicyError(job.ProjectName, command, requestedBy, reporter, commentTemplates)
 				log.Println(msg)
-				return nil, msg, planJson, errors.New(msg)
+				return nil, msg, planJson, policyDeniedError{msg}
 			}
 
 			// Running apply
 
 			applyPerformed, output, err := diggerExecutor.Apply()
+			if checks, ok := prService.(orchestrator.CheckRunReporter); ok && (err != nil || applyPerformed) {
+				reportApplyCheckRun(checks, job, output, err)
+			}
 			if err != nil {
 				//TODO reuse executor error handling
 				log.Printf("Failed to Run mantis apply command. %v", err)
@@ -452,6 +621,30 @@ func run(command string, job orchestrator.Job, policyChecker policy.Checker, org
 		result := execution.DiggerExecutorResult{}
 		return &result, "", planJson, nil
 
+	case "mantis delete-workspace":
+		if !job.EphemeralWorkspace {
+			msg := fmt.Sprintf("Project %v does not use an ephemeral workspace, refusing to delete workspace %v", job.ProjectName, job.ProjectWorkspace)
+			return nil, msg, planJson, errors.New(msg)
+		}
+		err := usage.SendUsageRecord(requestedBy, job.EventName, "delete-workspace")
+		if err != nil {
+			log.Printf("failed to send usage report. %v", err)
+		}
+		_, err = diggerExecutor.DeleteWorkspace()
+		if err != nil {
+			msg := fmt.Sprintf("Failed to delete workspace %v. %v", job.ProjectWorkspace, err)
+			return nil, msg, planJson, errors.New(msg)
+		}
+
+		if planStorage != nil {
+			err = planStorage.DeleteStoredPlan(planPathProvider.ArtifactName(), planPathProvider.StoredPlanFilePath())
+			if err != nil {
+				log.Printf("failed to delete stored plan file '%v':  %v", planPathProvider.StoredPlanFilePath(), err)
+			}
+		}
+		result := execution.DiggerExecutorResult{}
+		return &result, "", planJson, nil
+
 	case "mantis unlock":
 		err := usage.SendUsageRecord(requestedBy, job.EventName, "unlock")
 		if err != nil {
@@ -487,32 +680,31 @@ func run(command string, job orchestrator.Job, policyChecker policy.Checker, org
 	return &execution.DiggerExecutorResult{}, "", planJson, nil
 }
 
-func reportApplyMergeabilityError(reporter reporting.Reporter) string {
+func reportApplyMergeabilityError(reporter reporting.Reporter, commentTemplates *templates.Templates) string {
 	comment := "cannot perform Apply since the PR is not currently mergeable"
 	log.Println(comment)
 
-	if reporter.SupportsMarkdown() {
-		_, _, err := reporter.Report(comment, coreutils.AsCollapsibleComment("Apply error", false))
-		if err != nil {
-			log.Printf("error publishing comment: %v\n", err)
-		}
-	} else {
-		_, _, err := reporter.Report(comment, coreutils.AsComment("Apply error"))
-		if err != nil {
-			log.Printf("error publishing comment: %v\n", err)
-		}
+	formatter := templates.OrDefaults(commentTemplates).Formatter(templates.Error, templates.CommentData{Title: "Apply error", Markdown: reporter.SupportsMarkdown()})
+	_, _, err := reporter.Report(comment, formatter)
+	if err != nil {
+		log.Printf("error publishing comment: %v\n", err)
 	}
 	return comment
 }
 
-func reportTerraformPlanOutput(reporter reporting.Reporter, projectId string, plan string) {
-	var formatter func(string) string
-
-	if reporter.SupportsMarkdown() {
-		formatter = coreutils.GetTerraformOutputAsCollapsibleComment("Plan output", true)
-	} else {
-		formatter = coreutils.GetTerraformOutputAsComment("Plan output")
+func reportTerraformPlanOutput(reporter reporting.Reporter, commentTemplates *templates.Templates, projectName string, plan string, summary *terraform_utils.PlanSummary, policyViolations []string) {
+	data := templates.CommentData{
+		Project:          projectName,
+		Title:            "Plan output",
+		Code:             true,
+		Markdown:         reporter.SupportsMarkdown(),
+		Open:             true,
+		PolicyViolations: policyViolations,
+	}
+	if summary != nil {
+		data.Summary = &templates.ChangeSummary{Created: summary.ResourcesCreated, Updated: summary.ResourcesUpdated, Deleted: summary.ResourcesDeleted}
 	}
+	formatter := templates.OrDefaults(commentTemplates).Formatter(templates.Plan, data)
 
 	_, _, err := reporter.Report(plan, formatter)
 	if err != nil {
@@ -542,14 +734,19 @@ func RunJob(
 	backendApi backend.Api,
 	driftNotification *core_drift.Notification,
 	workingDir string,
+	providerCache config.ProviderCache,
 ) error {
 	runStartedAt := time.Now()
+	providers, err := terraform.NewProviderInstallation(providerCache)
+	if err != nil {
+		return err
+	}
 	SCMOrganisation, SCMrepository := utils.ParseRepoNamespace(repo)
 	log.Printf("Running '%s' for project '%s'\n", job.Commands, job.ProjectName)
 	var runDetails backend.RunDetails
 	for _, command := range job.Commands {
 
-		allowedToPerformCommand, err := policyChecker.CheckAccessPolicy(orgService, nil, SCMOrganisation, SCMrepository, job.ProjectName, job.ProjectDir, command, nil, requestedBy, []string{})
+		allowedToPerformCommand, err := policyChecker.CheckAccessPolicy(orgService, nil, SCMOrganisation, SCMrepository, job.ProjectName, job.ProjectDir, job.ProjectWorkspace, command, nil, requestedBy, []string{}, nil)
 
 		if err != nil {
 			return fmt.Errorf("error checking policy: %v", err)
@@ -576,14 +773,14 @@ func RunJob(
 		var terraformExecutor terraform.TerraformExecutor
 		projectPath := path.Join(workingDir, job.ProjectDir)
 		if job.Terragrunt {
-			terraformExecutor = terraform.Terragrunt{WorkingDir: projectPath}
+			terraformExecutor = terraform.Terragrunt{WorkingDir: projectPath, Workspace: job.ProjectWorkspace, EnvAllowlist: job.EnvAllowlist, Providers: providers, RunAll: job.TerragruntRunAll}
 		} else if job.OpenTofu {
-			terraformExecutor = terraform.OpenTofu{WorkingDir: projectPath, Workspace: job.ProjectWorkspace}
+			terraformExecutor = terraform.OpenTofu{WorkingDir: projectPath, Workspace: job.ProjectWorkspace, EnvAllowlist: job.EnvAllowlist, Providers: providers}
 		} else {
-			terraformExecutor = terraform.Terraform{WorkingDir: projectPath, Workspace: job.ProjectWorkspace}
+			terraformExecutor = terraform.Terraform{WorkingDir: projectPath, Workspace: job.ProjectWorkspace, EnvAllowlist: job.EnvAllowlist, Providers: providers}
 		}
 
-		commandRunner := runners.CommandRunner{}
+		commandRunner := runners.CommandRunner{EnvAllowlist: job.EnvAllowlist}
 
 		planPathProvider := execution.ProjectPathProvider{
 			ProjectPath:      projectPath,
@@ -592,7 +789,7 @@ func RunJob(
 			PRNumber:         job.PullRequestNumber,
 		}
 
-		diggerExecutor := execution.DiggerExecutor{
+		var diggerExecutor execution.Executor = execution.DiggerExecutor{
 			ProjectNamespace:  repo,
 			ProjectName:       job.ProjectName,
 			ProjectPath:       projectPath,
@@ -602,11 +799,14 @@ func RunJob(
 			ApplyStage:        job.ApplyStage,
 			PlanStage:         job.PlanStage,
 			CommandRunner:     commandRunner,
-			Reporter:          &reporting.StdOutReporter{},
+			Reporter:          reporting.NewMaskingReporter(&reporting.StdOutReporter{}, job.SecretValues()),
 			TerraformExecutor: terraformExecutor,
 			PlanStorage:       planStorage,
 			PlanPathProvider:  planPathProvider,
 		}
+		if job.TerragruntRunAll {
+			diggerExecutor = execution.TerragruntRunAllExecutor{DiggerExecutor: diggerExecutor.(execution.DiggerExecutor)}
+		}
 
 		switch command {
 		case "mantis plan":
@@ -626,8 +826,8 @@ func RunJob(
 				}
 				return fmt.Errorf(msg)
 			}
-			planIsAllowed, messages, err := policyChecker.CheckPlanPolicy(SCMrepository, SCMOrganisation, job.ProjectName, job.ProjectDir, planJsonOutput)
-			log.Printf(strings.Join(messages, "\n"))
+			planIsAllowed, planPolicyResult, err := policyChecker.CheckPlanPolicy(SCMrepository, SCMOrganisation, job.ProjectName, job.ProjectDir, planJsonOutput)
+			log.Print(planPolicyReport("Plan policy results:", planPolicyResult, "\n"))
 			if err != nil {
 				msg := fmt.Sprintf("Failed to validate plan %v", err)
 				log.Printf(msg)
@@ -711,42 +911,77 @@ func runDriftDetection(policyChecker policy.Checker, SCMOrganisation string, SCM
 	if err != nil {
 		log.Printf("Failed to send usage report. %v", err)
 	}
-	policyEnabled, err := policyChecker.CheckDriftPolicy(SCMOrganisation, SCMrepository, projectName)
+	_, planPerformed, nonEmptyPlan, plan, planJson, err := diggerExecutor.Plan()
+	if err != nil {
+		msg := fmt.Sprintf("failed to Run mantis plan command. %v", err)
+		log.Print(msg)
+		return msg, errors.New(msg)
+	}
+	if !planPerformed {
+		log.Printf("No plan performed")
+		return plan, nil
+	}
+
+	driftResult, err := policyChecker.CheckDriftPolicy(SCMOrganisation, SCMrepository, projectName, planJson)
 	if err != nil {
 		msg := fmt.Sprintf("failed to check drift policy. %v", err)
 		log.Printf(msg)
 		return msg, fmt.Errorf(msg)
 	}
 
-	if !policyEnabled {
+	if !driftResult.Enable {
 		msg := "skipping this drift application since it is not enabled for this project"
 		log.Printf(msg)
 		return msg, nil
 	}
-	_, planPerformed, nonEmptyPlan, plan, _, err := diggerExecutor.Plan()
+
+	if !nonEmptyPlan {
+		log.Printf("No drift detected")
+		return plan, nil
+	}
+
+	drift, err := driftFromPlan(planJson, driftResult)
 	if err != nil {
-		msg := fmt.Sprintf("failed to Run mantis plan command. %v", err)
+		msg := fmt.Sprintf("failed to read drifted resources. %v", err)
 		log.Printf(msg)
 		return msg, fmt.Errorf(msg)
 	}
+	if len(drift.Resources) == 0 {
+		log.Printf("Drift detected only in ignored resources: %v", drift.IgnoredResources)
+		return plan, nil
+	}
 
-	if planPerformed && nonEmptyPlan {
-		if notification == nil {
-			log.Print("Warning: no notification configured, not sending any notifications")
-			return plan, nil
-		}
-		err := (*notification).Send(projectName, plan)
-		if err != nil {
-			log.Printf("Error sending drift drift: %v", err)
-		}
-	} else if planPerformed && !nonEmptyPlan {
-		log.Printf("No drift detected")
-	} else {
-		log.Printf("No plan performed")
+	if notification == nil {
+		log.Print("Warning: no notification configured, not sending any notifications")
+		return plan, nil
+	}
+	err = (*notification).Send(projectName, plan, drift)
+	if err != nil {
+		log.Printf("Error sending drift drift: %v", err)
 	}
 	return plan, nil
 }
 
+// driftFromPlan splits the resources a drift plan changes into reported and ignored ones
+func driftFromPlan(planJson string, driftResult policy.DriftPolicyResult) (core_drift.Drift, error) {
+	changes, err := terraform_utils.GetChangedResources(planJson)
+	if err != nil {
+		return core_drift.Drift{}, err
+	}
+	drift := core_drift.Drift{
+		Severity: driftResult.Severity,
+		Notify:   driftResult.Notify,
+	}
+	for _, change := range changes {
+		if driftResult.IsIgnored(change.Address) {
+			drift.IgnoredResources = append(drift.IgnoredResources, change.Address)
+		} else {
+			drift.Resources = append(drift.Resources, change.Address)
+		}
+	}
+	return drift, nil
+}
+
 func SortedCommandsByDependency(project []orchestrator.Job, dependencyGraph *graph.Graph[string, config.Project]) []orchestrator.Job {
 	var sortedCommands []orchestrator.Job
 	sortedGraph, err := graph.StableTopologicalSort(*dependencyGraph, func(s string, s2 string) bool {
@@ -9,10 +9,11 @@ import (
 	"time"
 
 	"github.com/diggerhq/digger/cli/pkg/core/execution"
-	"github.com/diggerhq/digger/cli/pkg/utils"
+	"github.com/diggerhq/digger/cli/pkg/core/policy"
 	"github.com/diggerhq/digger/libs/comment_utils/reporting"
 	configuration "github.com/diggerhq/digger/libs/digger_config"
 	orchestrator "github.com/diggerhq/digger/libs/orchestrator"
+	"github.com/diggerhq/digger/libs/storage"
 
 	"github.com/dominikbraun/graph"
 	"github.com/stretchr/testify/assert"
@@ -55,6 +56,11 @@ func (m *MockTerraformExecutor) Destroy(params []string, envs map[string]string)
 	return "", "", nil
 }
 
+func (m *MockTerraformExecutor) DeleteWorkspace(params []string, envs map[string]string) (string, string, error) {
+	m.Commands = append(m.Commands, RunInfo{"DeleteWorkspace", strings.Join(params, " "), time.Now()})
+	return "", "", nil
+}
+
 func (m *MockTerraformExecutor) Show(params []string, envs map[string]string) (string, string, error) {
 	nonEmptyTerraformPlanJson := "{\"format_version\":\"1.1\",\"terraform_version\":\"1.4.6\",\"planned_values\":{\"root_module\":{\"resources\":[{\"address\":\"null_resource.test\",\"mode\":\"managed\",\"type\":\"null_resource\",\"name\":\"test\",\"provider_name\":\"registry.terraform.io/hashicorp/null\",\"schema_version\":0,\"values\":{\"id\":\"7587790946951100994\",\"triggers\":null},\"sensitive_values\":{}},{\"address\":\"null_resource.testx\",\"mode\":\"managed\",\"type\":\"null_resource\",\"name\":\"testx\",\"provider_name\":\"registry.terraform.io/hashicorp/null\",\"schema_version\":0,\"values\":{\"triggers\":null},\"sensitive_values\":{}}]}},\"resource_changes\":[{\"address\":\"null_resource.test\",\"mode\":\"managed\",\"type\":\"null_resource\",\"name\":\"test\",\"provider_name\":\"registry.terraform.io/hashicorp/null\",\"change\":{\"actions\":[\"no-op\"],\"before\":{\"id\":\"7587790946951100994\",\"triggers\":null},\"after\":{\"id\":\"7587790946951100994\",\"triggers\":null},\"after_unknown\":{},\"before_sensitive\":{},\"after_sensitive\":{}}},{\"address\":\"null_resource.testx\",\"mode\":\"managed\",\"type\":\"null_resource\",\"name\":\"testx\",\"provider_name\":\"registry.terraform.io/hashicorp/null\",\"change\":{\"actions\":[\"create\"],\"before\":null,\"after\":{\"triggers\":null},\"after_unknown\":{\"id\":true},\"before_sensitive\":false,\"after_sensitive\":{}}}],\"prior_state\":{\"format_version\":\"1.0\",\"terraform_version\":\"1.4.6\",\"values\":{\"root_module\":{\"resources\":[{\"address\":\"null_resource.test\",\"mode\":\"managed\",\"type\":\"null_resource\",\"name\":\"test\",\"provider_name\":\"registry.terraform.io/hashicorp/null\",\"schema_version\":0,\"values\":{\"id\":\"7587790946951100994\",\"triggers\":null},\"sensitive_values\":{}}]}}},\"configuration\":{\"provider_config\":{\"null\":{\"name\":\"null\",\"full_name\":\"registry.terraform.io/hashicorp/null\"}},\"root_module\":{\"resources\":[{\"address\":\"null_resource.test\",\"mode\":\"managed\",\"type\":\"null_resource\",\"name\":\"test\",\"provider_config_key\":\"null\",\"schema_version\":0},{\"address\":\"null_resource.testx\",\"mode\":\"managed\",\"type\":\"null_resource\",\"name\":\"testx\",\"provider_config_key\":\"null\",\"schema_version\":0}]}}}\n"
 	m.Commands = append(m.Commands, RunInfo{"Show", strings.Join(params, " "), time.Now()})
@@ -83,6 +89,14 @@ func (m *MockPRManager) GetApprovals(prNumber int) ([]string, error) {
 	return []string{}, nil
 }
 
+func (m *MockPRManager) GetLabels(prNumber int) ([]string, error) {
+	return []string{}, nil
+}
+
+func (m *MockPRManager) GetPullRequestDetails(prNumber int) (*orchestrator.PullRequestDetails, error) {
+	return &orchestrator.PullRequestDetails{}, nil
+}
+
 func (m *MockPRManager) PublishComment(prNumber int, comment string) (*orchestrator.Comment, error) {
 	m.Commands = append(m.Commands, RunInfo{"PublishComment", strconv.Itoa(prNumber) + " " + comment, time.Now()})
 	return nil, nil
@@ -143,6 +157,10 @@ func (m *MockPRManager) EditComment(prNumber int, id interface{}, comment string
 	return nil
 }
 
+func (m *MockPRManager) MaxCommentLength() int {
+	return 65536
+}
+
 func (m *MockPRManager) CreateCommentReaction(id interface{}, reaction string) error {
 	m.Commands = append(m.Commands, RunInfo{"EditComment", strconv.Itoa(id.(int)) + " " + reaction, time.Now()})
 	return nil
@@ -216,6 +234,16 @@ func (m *MockPlanStorage) PlanExists(artifactName string, storedPlanFilePath str
 	return false, nil
 }
 
+func (m *MockPlanStorage) ListPlans(prefix string) ([]storage.PlanInfo, error) {
+	m.Commands = append(m.Commands, RunInfo{"ListPlans", prefix, time.Now()})
+	return nil, nil
+}
+
+func (m *MockPlanStorage) DeletePlan(plan storage.PlanInfo) error {
+	m.Commands = append(m.Commands, RunInfo{"DeletePlan", plan.StoredPlanFilePath, time.Now()})
+	return nil
+}
+
 type MockPlanPathProvider struct {
 	Commands []RunInfo
 }
@@ -230,6 +258,11 @@ func (m MockPlanPathProvider) StoredPlanFilePath() string {
 	return "plan"
 }
 
+func (m MockPlanPathProvider) LegacyStoredPlanFilePath() string {
+	m.Commands = append(m.Commands, RunInfo{"LegacyStoredPlanFilePath", "", time.Now()})
+	return ""
+}
+
 func (m MockPlanPathProvider) LocalPlanFilePath() string {
 	m.Commands = append(m.Commands, RunInfo{"LocalPlanFilePath", "", time.Now()})
 	return "plan"
@@ -281,7 +314,7 @@ func TestCorrectCommandExecutionWhenApplying(t *testing.T) {
 
 	commandStrings := allCommandsInOrderWithParams(terraformExecutor, commandRunner, prManager, lock, planStorage, planPathProvider)
 
-	assert.Equal(t, []string{"RetrievePlan plan", "Init ", "Apply -lock-timeout=3m", "PublishComment 1 <details ><summary>Apply output</summary>\n\n```terraform\n\n```\n</details>", "Run   echo"}, commandStrings)
+	assert.Equal(t, []string{"PlanExists plan", "RetrievePlan plan", "Init ", "Apply -lock-timeout=3m", "PublishComment 1 <details ><summary>Apply output</summary>\n\n```terraform\n\n```\n</details>", "Run   echo"}, commandStrings)
 }
 
 func TestCorrectCommandExecutionWhenDestroying(t *testing.T) {
@@ -463,32 +496,15 @@ func TestSortedCommandByDependency(t *testing.T) {
 
 }
 
-func TestParseWorkspace(t *testing.T) {
-	var commentTests = []struct {
-		in  string
-		out string
-		err bool
-	}{
-		{"test", "", false},
-		{"test -w workspace", "workspace", false},
-		{"test -w workspace -w workspace2", "", true},
-		{"test -w", "", true},
-	}
-
-	for _, tt := range commentTests {
-		out, err := utils.ParseWorkspace(tt.in)
-		if tt.err {
-			if err == nil {
-				t.Errorf("ParseWorkspace(%q) = %q, want error", tt.in, out)
-			}
-		} else {
-			if err != nil {
-				t.Errorf("ParseWorkspace(%q) = %q, want %q", tt.in, err, tt.out)
-			}
-			if out != tt.out {
-				t.Errorf("ParseWorkspace(%q) = %q, want %q", tt.in, out, tt.out)
-			}
-		}
-	}
-
+func TestDriftFromPlanSkipsIgnoredResources(t *testing.T) {
+	planJson := `{"resource_changes": [
+		{"address": "aws_iam_role.admin", "change": {"actions": ["update"]}},
+		{"address": "aws_autoscaling_group.web", "change": {"actions": ["update"]}},
+		{"address": "null_resource.a", "change": {"actions": ["no-op"]}}
+	]}`
+	drift, err := driftFromPlan(planJson, policy.DriftPolicyResult{Enable: true, Severity: "high", Ignore: []string{"aws_autoscaling_group.*"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "high", drift.Severity)
+	assert.Equal(t, []string{"aws_iam_role.admin"}, drift.Resources)
+	assert.Equal(t, []string{"aws_autoscaling_group.web"}, drift.IgnoredResources)
 }
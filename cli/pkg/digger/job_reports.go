@@ -0,0 +1,71 @@
+package digger
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/diggerhq/digger/cli/pkg/core/policy"
+	"github.com/diggerhq/digger/cli/pkg/reports"
+	locking2 "github.com/diggerhq/digger/libs/locking"
+	"github.com/diggerhq/digger/libs/orchestrator"
+)
+
+// recordingLock records the outcome of the last lock attempt of a project for the job report
+type recordingLock struct {
+	locking2.ProjectLock
+	outcome *reports.LockOutcome
+}
+
+func (l recordingLock) Lock() (bool, error) {
+	locked, err := l.ProjectLock.Lock()
+	if err != nil {
+		*l.outcome = reports.LockError
+	} else if locked {
+		*l.outcome = reports.LockAcquired
+	} else {
+		*l.outcome = reports.LockHeld
+	}
+	return locked, err
+}
+
+func newJobReport(job orchestrator.Job, command string) reports.JobReport {
+	return reports.JobReport{
+		Project:   job.ProjectName,
+		Directory: job.ProjectDir,
+		Workspace: job.ProjectWorkspace,
+		Command:   command,
+		StartedAt: time.Now(),
+	}
+}
+
+// jobPolicyViolations converts plan policy results for the job report, locating resources like the check run annotations
+func jobPolicyViolations(projectPath string, projectDir string, result policy.PlanPolicyResult) []reports.PolicyViolation {
+	severities := []struct {
+		severity   string
+		violations []policy.Violation
+	}{
+		{"deny", result.Deny},
+		{"warn", result.Warn},
+		{"info", result.Info},
+	}
+	var violations []reports.PolicyViolation
+	for _, s := range severities {
+		for _, violation := range s.violations {
+			reported := reports.PolicyViolation{
+				Severity:    s.severity,
+				RuleId:      violation.RuleId,
+				Resource:    violation.ResourceAddress,
+				Message:     violation.Message,
+				Remediation: violation.Remediation,
+			}
+			if violation.ResourceAddress != "" {
+				if file, line, found := findResourceDeclaration(projectPath, violation.ResourceAddress); found {
+					reported.File = filepath.ToSlash(filepath.Join(projectDir, file))
+					reported.Line = line
+				}
+			}
+			violations = append(violations, reported)
+		}
+	}
+	return violations
+}
@@ -0,0 +1,56 @@
+package digger
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/diggerhq/digger/cli/pkg/core/policy"
+	"github.com/diggerhq/digger/cli/pkg/reports"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeProjectLock struct {
+	locked bool
+	err    error
+}
+
+func (l fakeProjectLock) Lock() (bool, error)   { return l.locked, l.err }
+func (l fakeProjectLock) Unlock() (bool, error) { return true, nil }
+func (l fakeProjectLock) ForceUnlock() error    { return nil }
+func (l fakeProjectLock) LockId() string        { return "org/repo#dev" }
+
+func TestRecordingLockRecordsOutcome(t *testing.T) {
+	cases := []struct {
+		lock     fakeProjectLock
+		expected reports.LockOutcome
+	}{
+		{fakeProjectLock{locked: true}, reports.LockAcquired},
+		{fakeProjectLock{locked: false}, reports.LockHeld},
+		{fakeProjectLock{err: errors.New("dynamodb unavailable")}, reports.LockError},
+	}
+	for _, c := range cases {
+		var outcome reports.LockOutcome
+		lock := recordingLock{ProjectLock: c.lock, outcome: &outcome}
+		locked, err := lock.Lock()
+		assert.Equal(t, c.lock.locked, locked)
+		assert.Equal(t, c.lock.err, err)
+		assert.Equal(t, c.expected, outcome)
+	}
+}
+
+func TestJobPolicyViolations(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte("resource \"aws_s3_bucket\" \"logs\" {\n}\n"), 0644))
+
+	violations := jobPolicyViolations(dir, "dev", policy.PlanPolicyResult{
+		Deny: []policy.Violation{{Message: "bucket is public", RuleId: "S3_001", ResourceAddress: "aws_s3_bucket.logs"}},
+		Info: []policy.Violation{{Message: "plan reviewed"}},
+	})
+	assert.Equal(t, []reports.PolicyViolation{
+		{Severity: "deny", RuleId: "S3_001", Resource: "aws_s3_bucket.logs", Message: "bucket is public", File: "dev/main.tf", Line: 1},
+		{Severity: "info", Message: "plan reviewed"},
+	}, violations)
+}
@@ -0,0 +1,87 @@
+package digger
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/diggerhq/digger/cli/pkg/reports"
+	"github.com/diggerhq/digger/libs/notifications"
+	"github.com/diggerhq/digger/libs/orchestrator"
+)
+
+// policyDeniedError stops a command denied by the access or plan policies
+type policyDeniedError struct {
+	msg string
+}
+
+func (e policyDeniedError) Error() string {
+	return e.msg
+}
+
+// notifyJobResult sends the events of a finished command. Plan and apply results of jobs orchestrated by the backend
+// are sent by the backend once their batch completes, policy denials are only known to the cli
+func notifyJobResult(notifier notifications.Notifier, job orchestrator.Job, jobReport reports.JobReport, output string, err error, reportedToBackend bool) {
+	event := notifications.Event{
+		Repository:  job.Namespace,
+		Project:     job.ProjectName,
+		RequestedBy: job.RequestedBy,
+		Url:         ciRunUrl(),
+	}
+	if job.PullRequestNumber != nil {
+		event.PrNumber = *job.PullRequestNumber
+	}
+
+	var denied policyDeniedError
+	switch {
+	case errors.As(err, &denied):
+		event.Type = notifications.PolicyDenied
+		event.Message = fmt.Sprintf("%v of %v was denied by policy: %v", jobReport.Command, job.ProjectName, denied.Error())
+		event.Details = denyViolations(jobReport)
+	case reportedToBackend:
+		return
+	case jobReport.Command == "mantis plan" && err != nil:
+		event.Type = notifications.PlanFailed
+		event.Message = err.Error()
+	case jobReport.Command == "mantis apply" && err != nil:
+		event.Type = notifications.ApplyFailed
+		event.Message = err.Error()
+	case jobReport.Command == "mantis apply" && jobReport.Lock == reports.LockHeld:
+		// the project is locked by another pull request, nothing was applied
+		return
+	case jobReport.Command == "mantis apply":
+		event.Type = notifications.ApplySucceeded
+		event.Message = fmt.Sprintf("Apply of %v succeeded", job.ProjectName)
+		event.Details = output
+	default:
+		return
+	}
+	if err != nil && output != err.Error() {
+		event.Details = output
+	}
+	notifier.Notify(event)
+}
+
+func denyViolations(jobReport reports.JobReport) string {
+	var lines []string
+	for _, violation := range jobReport.PolicyViolations {
+		if violation.Severity != "deny" {
+			continue
+		}
+		line := violation.Message
+		if violation.Resource != "" {
+			line = violation.Resource + ": " + line
+		}
+		lines = append(lines, "- "+line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ciRunUrl links notifications to the CI run that sent them
+func ciRunUrl() string {
+	if runId := os.Getenv("GITHUB_RUN_ID"); runId != "" {
+		return fmt.Sprintf("%v/%v/actions/runs/%v", os.Getenv("GITHUB_SERVER_URL"), os.Getenv("GITHUB_REPOSITORY"), runId)
+	}
+	return os.Getenv("CI_JOB_URL")
+}
@@ -0,0 +1,66 @@
+package digger
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/diggerhq/digger/cli/pkg/reports"
+	"github.com/diggerhq/digger/libs/notifications"
+	"github.com/diggerhq/digger/libs/orchestrator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingNotifier struct {
+	events []notifications.Event
+}
+
+func (n *recordingNotifier) Notify(event notifications.Event) {
+	n.events = append(n.events, event)
+}
+
+func TestNotifyJobResult(t *testing.T) {
+	prNumber := 7
+	job := orchestrator.Job{ProjectName: "dev", Namespace: "org/repo", RequestedBy: "alice", PullRequestNumber: &prNumber}
+	plan := reports.JobReport{Command: "mantis plan"}
+	apply := reports.JobReport{Command: "mantis apply", Lock: reports.LockAcquired}
+	deniedPlan := reports.JobReport{Command: "mantis plan", PolicyViolations: []reports.PolicyViolation{
+		{Severity: "deny", Resource: "aws_s3_bucket.logs", Message: "bucket is public"},
+		{Severity: "warn", Message: "missing owner tag"},
+	}}
+
+	cases := []struct {
+		name              string
+		jobReport         reports.JobReport
+		output            string
+		err               error
+		reportedToBackend bool
+		expected          notifications.EventType
+		details           string
+	}{
+		{"plan failed", plan, "Error: invalid reference", errors.New("Failed to run plan"), false, notifications.PlanFailed, "Error: invalid reference"},
+		{"plan succeeded", plan, "No changes", nil, false, "", ""},
+		{"apply failed", apply, "Failed to run apply", errors.New("Failed to run apply"), false, notifications.ApplyFailed, ""},
+		{"apply succeeded", apply, "Apply complete!", nil, false, notifications.ApplySucceeded, "Apply complete!"},
+		{"apply blocked by lock", reports.JobReport{Command: "mantis apply", Lock: reports.LockHeld}, "", nil, false, "", ""},
+		{"apply sent by backend", apply, "Apply complete!", nil, true, "", ""},
+		{"plan denied", deniedPlan, "Plan is not allowed", policyDeniedError{"Plan is not allowed"}, true, notifications.PolicyDenied, "- aws_s3_bucket.logs: bucket is public"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			notifier := &recordingNotifier{}
+			notifyJobResult(notifier, job, c.jobReport, c.output, c.err, c.reportedToBackend)
+			if c.expected == "" {
+				assert.Empty(t, notifier.events)
+				return
+			}
+			require.Len(t, notifier.events, 1)
+			event := notifier.events[0]
+			assert.Equal(t, c.expected, event.Type)
+			assert.Equal(t, "org/repo", event.Repository)
+			assert.Equal(t, "dev", event.Project)
+			assert.Equal(t, 7, event.PrNumber)
+			assert.Equal(t, c.details, event.Details)
+		})
+	}
+}
@@ -9,6 +9,8 @@ import (
 	"net/http"
 	"regexp"
 	"strings"
+
+	core_drift "github.com/diggerhq/digger/cli/pkg/core/drift"
 )
 
 type SlackNotification struct {
@@ -40,8 +42,32 @@ func SplitCodeBlocks(message string) []string {
 	return res
 }
 
-func (slack SlackNotification) Send(projectName string, plan string) error {
-	message := fmt.Sprintf(":bangbang: Drift detected in digger project %v details below: \n\n```\n%v\n```", projectName, plan)
+// SlackDriftMessage formats a drift notification, drifted resources and notify targets are listed above the plan
+func SlackDriftMessage(projectName string, plan string, drift core_drift.Drift) string {
+	message := ":bangbang: Drift detected in digger project " + projectName
+	if drift.Severity != "" {
+		message = fmt.Sprintf(":bangbang: [%v] Drift detected in digger project %v", strings.ToUpper(drift.Severity), projectName)
+	}
+	var details []string
+	if len(drift.Notify) > 0 {
+		details = append(details, "cc "+strings.Join(drift.Notify, " "))
+	}
+	if len(drift.Resources) > 0 {
+		details = append(details, "Drifted resources: `"+strings.Join(drift.Resources, "`, `")+"`")
+	}
+	if len(drift.IgnoredResources) > 0 {
+		details = append(details, "Ignored resources: `"+strings.Join(drift.IgnoredResources, "`, `")+"`")
+	}
+	if len(details) > 0 {
+		message += "\n" + strings.Join(details, "\n") + "\n"
+	} else {
+		message += " "
+	}
+	return fmt.Sprintf("%vdetails below: \n\n```\n%v\n```", message, plan)
+}
+
+func (slack SlackNotification) Send(projectName string, plan string, drift core_drift.Drift) error {
+	message := SlackDriftMessage(projectName, plan, drift)
 	httpClient := &http.Client{}
 	type SlackMessage struct {
 		Text string `json:"text"`
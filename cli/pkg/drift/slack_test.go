@@ -1,6 +1,7 @@
 package drift
 
 import (
+	core_drift "github.com/diggerhq/digger/cli/pkg/core/drift"
 	"github.com/stretchr/testify/assert"
 	"os"
 	"strings"
@@ -30,6 +31,21 @@ func TestSendSlackMessageThatIsLargerThan2Parts(t *testing.T) {
 	projectName := "dev"
 	plan := ":bangbang: drift detected\n\n ```\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\n\n\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\nhere it is\n\n```"
 	notification := SlackNotification{Url: url}
-	err := notification.Send(projectName, plan)
+	err := notification.Send(projectName, plan, core_drift.Drift{})
 	assert.Equal(t, nil, err)
 }
+
+func TestSlackDriftMessageListsPolicyDetails(t *testing.T) {
+	message := SlackDriftMessage("dev", "plan", core_drift.Drift{})
+	assert.Equal(t, ":bangbang: Drift detected in digger project dev details below: \n\n```\nplan\n```", message)
+
+	message = SlackDriftMessage("dev", "plan", core_drift.Drift{
+		Severity:         "high",
+		Notify:           []string{"@infra"},
+		Resources:        []string{"aws_iam_role.admin"},
+		IgnoredResources: []string{"aws_autoscaling_group.web"},
+	})
+	assert.True(t, strings.HasPrefix(message, ":bangbang: [HIGH] Drift detected in digger project dev\ncc @infra\n"))
+	assert.Contains(t, message, "Drifted resources: `aws_iam_role.admin`")
+	assert.Contains(t, message, "Ignored resources: `aws_autoscaling_group.web`")
+}
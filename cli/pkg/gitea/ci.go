@@ -0,0 +1,88 @@
+package gitea
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	core_backend "github.com/diggerhq/digger/cli/pkg/core/backend"
+	core_policy "github.com/diggerhq/digger/cli/pkg/core/policy"
+	"github.com/diggerhq/digger/cli/pkg/digger"
+	"github.com/diggerhq/digger/cli/pkg/usage"
+	"github.com/diggerhq/digger/cli/pkg/utils"
+	"github.com/diggerhq/digger/libs/comment_utils/reporting"
+	"github.com/diggerhq/digger/libs/digger_config"
+	core_locking "github.com/diggerhq/digger/libs/locking"
+)
+
+// GiteaCI runs the jobs of pull request and comment events in Gitea and Forgejo Actions
+func GiteaCI(lock core_locking.Lock, policyCheckerProvider core_policy.PolicyCheckerProvider, backendApi core_backend.Api, reportingStrategy reporting.ReportStrategy) {
+	log.Printf("Using Gitea.\n")
+	actor := os.Getenv("GITHUB_ACTOR")
+	usage.SendUsageRecord(actor, "log", "initialize")
+
+	hostName := os.Getenv("DIGGER_HOSTNAME")
+	diggerToken := os.Getenv("DIGGER_TOKEN")
+	orgName := os.Getenv("DIGGER_ORGANISATION")
+	policyChecker, err := policyCheckerProvider.Get(hostName, orgName, diggerToken)
+	if err != nil {
+		usage.ReportErrorAndExit(actor, fmt.Sprintf("Failed to configure policy checker: %v", err), 1)
+	}
+
+	// the token of the run can not read team memberships, GITEA_TOKEN takes precedence
+	token := os.Getenv("GITEA_TOKEN")
+	if token == "" {
+		token = os.Getenv("GITHUB_TOKEN")
+	}
+	if token == "" {
+		usage.ReportErrorAndExit(actor, "GITEA_TOKEN is not defined", 1)
+	}
+
+	serverUrl := os.Getenv("GITEA_SERVER_URL")
+	if serverUrl == "" {
+		serverUrl = os.Getenv("GITHUB_SERVER_URL")
+	}
+	if serverUrl == "" {
+		usage.ReportErrorAndExit(actor, "GITHUB_SERVER_URL is not defined", 1)
+	}
+
+	repository := os.Getenv("GITHUB_REPOSITORY")
+	if repository == "" {
+		usage.ReportErrorAndExit(actor, "GITHUB_REPOSITORY is not defined", 3)
+	}
+	repoOwner, repositoryName := utils.ParseRepoNamespace(repository)
+	giteaService := NewGiteaService(token, serverUrl, repoOwner, repositoryName)
+
+	event, err := GetGiteaEvent()
+	if err != nil {
+		usage.ReportErrorAndExit(actor, fmt.Sprintf("Failed to parse Gitea event. %s", err), 3)
+	}
+	log.Printf("Gitea event parsed successfully\n")
+
+	currentDir, err := os.Getwd()
+	if err != nil {
+		usage.ReportErrorAndExit(actor, fmt.Sprintf("Failed to get current dir. %s", err), 4)
+	}
+
+	diggerConfig, diggerConfigYaml, dependencyGraph, err := digger_config.LoadDiggerConfig("./", true, nil)
+	if err != nil {
+		usage.ReportErrorAndExit(actor, fmt.Sprintf("Failed to read Digger digger_config. %s", err), 4)
+	}
+	log.Printf("Digger digger_config read successfully\n")
+
+	if diggerConfig.PrLocks == false {
+		log.Printf("info: Using noop lock as configured in mantis.yml")
+		lock = core_locking.NoOpLock{}
+	}
+
+	digger.ReportProjects(backendApi, repository, diggerConfig, diggerConfigYaml)
+
+	ghEvent, err := ToGithubEvent(event)
+	if err != nil {
+		usage.ReportErrorAndExit(actor, fmt.Sprintf("Graceful handling of Gitea event. %s", err), 0)
+	}
+
+	digger.RunPullRequestEvent(ghEvent, &giteaService, &giteaService, &giteaService, lock, policyChecker, backendApi, reportingStrategy, diggerConfig, dependencyGraph, actor, token, repoOwner, repositoryName, currentDir)
+
+	usage.ReportErrorAndExit(actor, "Digger finished successfully", 0)
+}
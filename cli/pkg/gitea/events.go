@@ -0,0 +1,126 @@
+package gitea
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/diggerhq/digger/libs/digger_config"
+	"github.com/diggerhq/digger/libs/orchestrator"
+	dg_github "github.com/diggerhq/digger/libs/orchestrator/github"
+	"github.com/google/go-github/v61/github"
+)
+
+type Repository struct {
+	Name          string `json:"name"`
+	FullName      string `json:"full_name"`
+	DefaultBranch string `json:"default_branch"`
+	Owner         User   `json:"owner"`
+}
+
+// PullRequestEvent is the payload of pull_request events in Gitea Actions
+type PullRequestEvent struct {
+	Action      string      `json:"action"`
+	Number      int         `json:"number"`
+	PullRequest PullRequest `json:"pull_request"`
+	Repository  Repository  `json:"repository"`
+	Sender      User        `json:"sender"`
+}
+
+// IssueCommentEvent is the payload of issue_comment events, which Gitea also sends for pull request comments
+type IssueCommentEvent struct {
+	Action     string     `json:"action"`
+	Issue      Issue      `json:"issue"`
+	Comment    Comment    `json:"comment"`
+	IsPull     bool       `json:"is_pull"`
+	Repository Repository `json:"repository"`
+	Sender     User       `json:"sender"`
+}
+
+// GetGiteaEvent reads the event that triggered the Gitea Actions run
+func GetGiteaEvent() (interface{}, error) {
+	eventName := os.Getenv("GITHUB_EVENT_NAME")
+	eventPath := os.Getenv("GITHUB_EVENT_PATH")
+	if eventPath == "" {
+		return nil, fmt.Errorf("GITHUB_EVENT_PATH is not defined")
+	}
+	payload, err := os.ReadFile(eventPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not read event payload: %v", err)
+	}
+	return ParseGiteaEvent(eventName, payload)
+}
+
+func ParseGiteaEvent(eventName string, payload []byte) (interface{}, error) {
+	switch eventName {
+	case "pull_request", "pull_request_target":
+		var event PullRequestEvent
+		err := json.Unmarshal(payload, &event)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse %v event: %v", eventName, err)
+		}
+		return event, nil
+	case "issue_comment":
+		var event IssueCommentEvent
+		err := json.Unmarshal(payload, &event)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse %v event: %v", eventName, err)
+		}
+		return event, nil
+	}
+	return nil, fmt.Errorf("unsupported event type %v", eventName)
+}
+
+// ToGithubEvent converts Gitea events to the go-github events the orchestrator works with
+func ToGithubEvent(event interface{}) (interface{}, error) {
+	switch event := event.(type) {
+	case PullRequestEvent:
+		action := event.Action
+		if action == "synchronized" {
+			action = "synchronize"
+		}
+		return github.PullRequestEvent{
+			Action: &action,
+			Number: github.Int(event.PullRequest.Number),
+			PullRequest: &github.PullRequest{
+				Number: github.Int(event.PullRequest.Number),
+				Merged: github.Bool(event.PullRequest.Merged),
+				Draft:  github.Bool(event.PullRequest.Draft),
+				Head:   &github.PullRequestBranch{Ref: github.String(event.PullRequest.Head.Ref), SHA: github.String(event.PullRequest.Head.Sha)},
+				Base:   &github.PullRequestBranch{Ref: github.String(event.PullRequest.Base.Ref), SHA: github.String(event.PullRequest.Base.Sha)},
+			},
+			Repo:   toGithubRepository(event.Repository),
+			Sender: &github.User{Login: github.String(event.Sender.Login)},
+		}, nil
+	case IssueCommentEvent:
+		if !event.IsPull && event.Issue.PullRequest == nil {
+			return nil, fmt.Errorf("comment on issue #%v is not on a pull request", event.Issue.Number)
+		}
+		return github.IssueCommentEvent{
+			Action:  github.String(event.Action),
+			Issue:   &github.Issue{Number: github.Int(event.Issue.Number)},
+			Comment: &github.IssueComment{ID: github.Int64(event.Comment.Id), Body: github.String(event.Comment.Body)},
+			Repo:    toGithubRepository(event.Repository),
+			Sender:  &github.User{Login: github.String(event.Sender.Login)},
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported event type %T", event)
+}
+
+func toGithubRepository(repository Repository) *github.Repository {
+	return &github.Repository{
+		Name:          github.String(repository.Name),
+		FullName:      github.String(repository.FullName),
+		DefaultBranch: github.String(repository.DefaultBranch),
+		Owner:         &github.User{Login: github.String(repository.Owner.Login)},
+	}
+}
+
+// ProcessGiteaEvent returns the projects impacted by a Gitea event and the project requested in comments
+func ProcessGiteaEvent(event interface{}, diggerConfig *digger_config.DiggerConfig, ciService orchestrator.PullRequestService) ([]digger_config.Project, *digger_config.Project, int, error) {
+	ghEvent, err := ToGithubEvent(event)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	return dg_github.ProcessGitHubEvent(ghEvent, diggerConfig, ciService)
+}
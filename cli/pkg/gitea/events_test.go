@@ -0,0 +1,84 @@
+package gitea
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/diggerhq/digger/libs/digger_config"
+	dg_github "github.com/diggerhq/digger/libs/orchestrator/github"
+	"github.com/google/go-github/v61/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetGiteaEvent(t *testing.T) {
+	t.Setenv("GITHUB_EVENT_NAME", "pull_request")
+	t.Setenv("GITHUB_EVENT_PATH", filepath.Join("testdata", "event_pull_request.json"))
+
+	event, err := GetGiteaEvent()
+	require.NoError(t, err)
+	prEvent, ok := event.(PullRequestEvent)
+	require.True(t, ok)
+	assert.Equal(t, "synchronized", prEvent.Action)
+	assert.Equal(t, "vpc-eu-west-2", prEvent.PullRequest.Head.Ref)
+
+	_, err = ParseGiteaEvent("push", []byte("{}"))
+	assert.Error(t, err)
+}
+
+func TestToGithubEvent(t *testing.T) {
+	payload, err := os.ReadFile(filepath.Join("testdata", "event_pull_request.json"))
+	require.NoError(t, err)
+	event, err := ParseGiteaEvent("pull_request", payload)
+	require.NoError(t, err)
+
+	ghEvent, err := ToGithubEvent(event)
+	require.NoError(t, err)
+	prEvent := ghEvent.(github.PullRequestEvent)
+	assert.Equal(t, "synchronize", prEvent.GetAction())
+	assert.Equal(t, 12, prEvent.GetPullRequest().GetNumber())
+	assert.Equal(t, "main", prEvent.GetPullRequest().GetBase().GetRef())
+	assert.Equal(t, "infra/live", prEvent.GetRepo().GetFullName())
+	assert.Equal(t, "main", prEvent.GetRepo().GetDefaultBranch())
+	assert.Equal(t, "alice", prEvent.GetSender().GetLogin())
+
+	_, err = ToGithubEvent(IssueCommentEvent{Issue: Issue{Number: 3}})
+	assert.ErrorContains(t, err, "not on a pull request")
+}
+
+func TestProcessGiteaEvent(t *testing.T) {
+	service, _ := fixtureServer(t, map[string]string{"GET /api/v1/repos/infra/live/pulls/12/files": "pull_files.json"})
+	config := &digger_config.DiggerConfig{
+		Projects: []digger_config.Project{
+			{Name: "prod-vpc", Dir: "prod/vpc", Workflow: "default"},
+			{Name: "dev-vpc", Dir: "dev/vpc", Workflow: "default"},
+		},
+		Workflows: map[string]digger_config.Workflow{"default": {Configuration: &digger_config.WorkflowConfiguration{
+			OnPullRequestPushed: []string{"mantis plan"},
+			OnCommitToDefault:   []string{"mantis apply"},
+		}}},
+	}
+
+	payload, err := os.ReadFile(filepath.Join("testdata", "event_issue_comment.json"))
+	require.NoError(t, err)
+	event, err := ParseGiteaEvent("issue_comment", payload)
+	require.NoError(t, err)
+
+	impacted, requested, prNumber, err := ProcessGiteaEvent(event, config, &service)
+	require.NoError(t, err)
+	assert.Equal(t, 12, prNumber)
+	require.Len(t, impacted, 1)
+	require.NotNil(t, requested)
+	assert.Equal(t, "prod-vpc", requested.Name)
+
+	ghEvent, err := ToGithubEvent(event)
+	require.NoError(t, err)
+	commentEvent := ghEvent.(github.IssueCommentEvent)
+	jobs, _, err := dg_github.ConvertGithubIssueCommentEventToJobs(&commentEvent, impacted, requested, config.Workflows, "vpc-eu-west-2")
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, []string{"mantis plan"}, jobs[0].Commands)
+	assert.Equal(t, "infra/live", jobs[0].Namespace)
+	assert.Equal(t, "alice", jobs[0].RequestedBy)
+}
@@ -0,0 +1,426 @@
+package gitea
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/diggerhq/digger/libs/orchestrator"
+)
+
+// pageSize is the default maximum page size of the Gitea API
+const pageSize = 50
+
+// GiteaService talks to the REST API of Gitea and Forgejo instances
+type GiteaService struct {
+	HttpClient *http.Client
+	// BaseUrl is the address of the instance, e.g. https://codeberg.org
+	BaseUrl  string
+	Token    string
+	Owner    string
+	RepoName string
+}
+
+func NewGiteaService(token string, baseUrl string, owner string, repoName string) GiteaService {
+	return GiteaService{
+		HttpClient: &http.Client{Timeout: 30 * time.Second},
+		BaseUrl:    strings.TrimSuffix(baseUrl, "/"),
+		Token:      token,
+		Owner:      owner,
+		RepoName:   repoName,
+	}
+}
+
+type User struct {
+	Login string `json:"login"`
+}
+
+type Label struct {
+	Name string `json:"name"`
+}
+
+type Branch struct {
+	Ref string `json:"ref"`
+	Sha string `json:"sha"`
+}
+
+type PullRequest struct {
+	Number    int     `json:"number"`
+	State     string  `json:"state"`
+	Draft     bool    `json:"draft"`
+	Merged    bool    `json:"merged"`
+	Mergeable bool    `json:"mergeable"`
+	User      User    `json:"user"`
+	Labels    []Label `json:"labels"`
+	Head      Branch  `json:"head"`
+	Base      Branch  `json:"base"`
+	HtmlUrl   string  `json:"html_url"`
+}
+
+type Issue struct {
+	Id     int64  `json:"id"`
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+	Body   string `json:"body"`
+	// PullRequest is set when the issue is a pull request
+	PullRequest *struct {
+		Merged bool `json:"merged"`
+	} `json:"pull_request"`
+}
+
+type Comment struct {
+	Id      int64  `json:"id"`
+	Body    string `json:"body"`
+	User    User   `json:"user"`
+	HtmlUrl string `json:"html_url"`
+}
+
+type changedFile struct {
+	Filename         string `json:"filename"`
+	PreviousFilename string `json:"previous_filename"`
+}
+
+type review struct {
+	User      User   `json:"user"`
+	State     string `json:"state"`
+	Stale     bool   `json:"stale"`
+	Dismissed bool   `json:"dismissed"`
+}
+
+type team struct {
+	Id   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// apiError is returned for responses outside of the 2xx range
+type apiError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e apiError) Error() string {
+	return fmt.Sprintf("gitea api returned %v: %v", e.StatusCode, e.Message)
+}
+
+func (g GiteaService) request(method string, path string, body interface{}, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("could not marshal request: %v", err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequest(method, g.BaseUrl+"/api/v1"+path, reader)
+	if err != nil {
+		return fmt.Errorf("could not create request: %v", err)
+	}
+	req.Header.Set("Authorization", "token "+g.Token)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := g.HttpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not send request to %v: %v", path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := io.ReadAll(resp.Body)
+		return apiError{StatusCode: resp.StatusCode, Message: string(message)}
+	}
+	if result == nil {
+		return nil
+	}
+	err = json.NewDecoder(resp.Body).Decode(result)
+	if err != nil {
+		return fmt.Errorf("could not decode response of %v: %v", path, err)
+	}
+	return nil
+}
+
+func (g GiteaService) repoPath(format string, args ...interface{}) string {
+	return fmt.Sprintf("/repos/%v/%v", url.PathEscape(g.Owner), url.PathEscape(g.RepoName)) + fmt.Sprintf(format, args...)
+}
+
+func (g GiteaService) getPullRequest(prNumber int) (*PullRequest, error) {
+	var pr PullRequest
+	err := g.request("GET", g.repoPath("/pulls/%d", prNumber), nil, &pr)
+	if err != nil {
+		return nil, fmt.Errorf("could not get pull request %v: %v", prNumber, err)
+	}
+	return &pr, nil
+}
+
+func (g GiteaService) GetUserTeams(organisation string, user string) ([]string, error) {
+	var teams []string
+	for page := 1; ; page++ {
+		var orgTeams []team
+		err := g.request("GET", fmt.Sprintf("/orgs/%v/teams?page=%d&limit=%d", url.PathEscape(organisation), page, pageSize), nil, &orgTeams)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list gitea teams: %v", err)
+		}
+		for _, t := range orgTeams {
+			err := g.request("GET", fmt.Sprintf("/teams/%d/members/%v", t.Id, url.PathEscape(user)), nil, nil)
+			var notMember apiError
+			if errors.As(err, &notMember) && notMember.StatusCode == http.StatusNotFound {
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to check membership of team %v: %v", t.Name, err)
+			}
+			teams = append(teams, t.Name)
+		}
+		if len(orgTeams) < pageSize {
+			return teams, nil
+		}
+	}
+}
+
+func (g GiteaService) GetChangedFiles(prNumber int) ([]string, error) {
+	var fileNames []string
+	for page := 1; ; page++ {
+		var files []changedFile
+		err := g.request("GET", g.repoPath("/pulls/%d/files?page=%d&limit=%d", prNumber, page, pageSize), nil, &files)
+		if err != nil {
+			return nil, fmt.Errorf("could not get changed files of pull request %v: %v", prNumber, err)
+		}
+		for _, file := range files {
+			fileNames = append(fileNames, file.Filename)
+			if file.PreviousFilename != "" {
+				fileNames = append(fileNames, file.PreviousFilename)
+			}
+		}
+		if len(files) < pageSize {
+			return fileNames, nil
+		}
+	}
+}
+
+func (g GiteaService) PublishComment(prNumber int, comment string) (*orchestrator.Comment, error) {
+	var created Comment
+	err := g.request("POST", g.repoPath("/issues/%d/comments", prNumber), map[string]string{"body": comment}, &created)
+	if err != nil {
+		return nil, fmt.Errorf("could not publish comment to PR %v, %v", prNumber, err)
+	}
+	return &orchestrator.Comment{Id: created.Id, Body: &created.Body, Url: created.HtmlUrl}, nil
+}
+
+func (g GiteaService) ListIssues() ([]*orchestrator.Issue, error) {
+	allIssues := make([]*orchestrator.Issue, 0)
+	for page := 1; ; page++ {
+		var issues []Issue
+		err := g.request("GET", g.repoPath("/issues?state=open&type=issues&page=%d&limit=%d", page, pageSize), nil, &issues)
+		if err != nil {
+			return nil, fmt.Errorf("could not list issues: %v", err)
+		}
+		for _, issue := range issues {
+			allIssues = append(allIssues, &orchestrator.Issue{ID: int64(issue.Number), Title: issue.Title, Body: issue.Body})
+		}
+		if len(issues) < pageSize {
+			return allIssues, nil
+		}
+	}
+}
+
+func (g GiteaService) PublishIssue(title string, body string) (int64, error) {
+	var issue Issue
+	err := g.request("POST", g.repoPath("/issues"), map[string]string{"title": title, "body": body}, &issue)
+	if err != nil {
+		return 0, fmt.Errorf("could not publish issue: %v", err)
+	}
+	return issue.Id, nil
+}
+
+func (g GiteaService) EditComment(prNumber int, id interface{}, comment string) error {
+	err := g.request("PATCH", g.repoPath("/issues/comments/%v", id), map[string]string{"body": comment}, nil)
+	if err != nil {
+		return fmt.Errorf("could not edit comment %v: %v", id, err)
+	}
+	return nil
+}
+
+// MaxCommentLength matches GitHub, Gitea itself does not limit the length of comments
+func (g GiteaService) MaxCommentLength() int {
+	return 65536
+}
+
+func (g GiteaService) CreateCommentReaction(id interface{}, reaction string) error {
+	err := g.request("POST", g.repoPath("/issues/comments/%v/reactions", id), map[string]string{"content": reaction}, nil)
+	if err != nil {
+		return fmt.Errorf("could not add reaction to comment: %v", err)
+	}
+	return nil
+}
+
+func (g GiteaService) GetComments(prNumber int) ([]orchestrator.Comment, error) {
+	var comments []Comment
+	err := g.request("GET", g.repoPath("/issues/%d/comments", prNumber), nil, &comments)
+	if err != nil {
+		return nil, fmt.Errorf("could not get comments of pull request %v: %v", prNumber, err)
+	}
+	result := make([]orchestrator.Comment, len(comments))
+	for i, comment := range comments {
+		body := comment.Body
+		result[i] = orchestrator.Comment{Id: comment.Id, Body: &body, Url: comment.HtmlUrl}
+	}
+	return result, nil
+}
+
+// GetApprovals returns the reviewers whose approval still applies to the latest commit
+func (g GiteaService) GetApprovals(prNumber int) ([]string, error) {
+	approvals := make([]string, 0)
+	for page := 1; ; page++ {
+		var reviews []review
+		err := g.request("GET", g.repoPath("/pulls/%d/reviews?page=%d&limit=%d", prNumber, page, pageSize), nil, &reviews)
+		if err != nil {
+			return nil, fmt.Errorf("could not get reviews of pull request %v: %v", prNumber, err)
+		}
+		for _, r := range reviews {
+			if r.State == "APPROVED" && !r.Stale && !r.Dismissed {
+				approvals = append(approvals, r.User.Login)
+			}
+		}
+		if len(reviews) < pageSize {
+			return approvals, nil
+		}
+	}
+}
+
+func (g GiteaService) GetLabels(prNumber int) ([]string, error) {
+	pr, err := g.getPullRequest(prNumber)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(pr.Labels))
+	for _, label := range pr.Labels {
+		names = append(names, label.Name)
+	}
+	return names, nil
+}
+
+func (g GiteaService) GetPullRequestDetails(prNumber int) (*orchestrator.PullRequestDetails, error) {
+	pr, err := g.getPullRequest(prNumber)
+	if err != nil {
+		return nil, err
+	}
+	return &orchestrator.PullRequestDetails{
+		Author:     pr.User.Login,
+		BaseBranch: pr.Base.Ref,
+		HeadBranch: pr.Head.Ref,
+		Draft:      pr.Draft,
+	}, nil
+}
+
+func (g GiteaService) SetStatus(prNumber int, status string, statusContext string) error {
+	pr, err := g.getPullRequest(prNumber)
+	if err != nil {
+		return err
+	}
+	err = g.request("POST", g.repoPath("/statuses/%v", pr.Head.Sha), map[string]string{
+		"state":       status,
+		"context":     statusContext,
+		"description": statusContext,
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("could not set status %v: %v", statusContext, err)
+	}
+	return nil
+}
+
+func (g GiteaService) GetCombinedPullRequestStatus(prNumber int) (string, error) {
+	pr, err := g.getPullRequest(prNumber)
+	if err != nil {
+		return "", err
+	}
+	var combined struct {
+		State string `json:"state"`
+	}
+	err = g.request("GET", g.repoPath("/commits/%v/status", pr.Head.Sha), nil, &combined)
+	if err != nil {
+		return "", fmt.Errorf("could not get combined status: %v", err)
+	}
+	// Gitea also knows error and warning, callers only handle the GitHub states
+	switch combined.State {
+	case "error":
+		return "failure", nil
+	case "warning":
+		return "success", nil
+	case "":
+		return "pending", nil
+	}
+	return combined.State, nil
+}
+
+func (g GiteaService) MergePullRequest(prNumber int) error {
+	pr, err := g.getPullRequest(prNumber)
+	if err != nil {
+		return err
+	}
+	err = g.request("POST", g.repoPath("/pulls/%d/merge", prNumber), map[string]string{
+		"Do":             "squash",
+		"head_commit_id": pr.Head.Sha,
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("could not merge pull request %v: %v", prNumber, err)
+	}
+	return nil
+}
+
+func (g GiteaService) IsMergeable(prNumber int) (bool, error) {
+	pr, err := g.getPullRequest(prNumber)
+	if err != nil {
+		return false, err
+	}
+	return pr.State == "open" && pr.Mergeable, nil
+}
+
+func (g GiteaService) IsMerged(prNumber int) (bool, error) {
+	pr, err := g.getPullRequest(prNumber)
+	if err != nil {
+		return false, err
+	}
+	return pr.Merged, nil
+}
+
+func (g GiteaService) IsClosed(prNumber int) (bool, error) {
+	pr, err := g.getPullRequest(prNumber)
+	if err != nil {
+		return false, err
+	}
+	return pr.State == "closed", nil
+}
+
+func (g GiteaService) GetBranchName(prNumber int) (string, string, error) {
+	pr, err := g.getPullRequest(prNumber)
+	if err != nil {
+		return "", "", err
+	}
+	return pr.Head.Ref, pr.Head.Sha, nil
+}
+
+// SetOutput writes to GITHUB_ENV, which Gitea Actions runners provide as well
+func (g GiteaService) SetOutput(prNumber int, key string, value string) error {
+	gout := os.Getenv("GITHUB_ENV")
+	if gout == "" {
+		return fmt.Errorf("GITHUB_ENV not set, could not set the output in digger step")
+	}
+	f, err := os.OpenFile(gout, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("could not open file for writing during digger step")
+	}
+	defer f.Close()
+	_, err = f.WriteString(fmt.Sprintf("%v=%v\n", key, value))
+	if err != nil {
+		return fmt.Errorf("could not write digger file step")
+	}
+	return nil
+}
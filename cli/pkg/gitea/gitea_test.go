@@ -0,0 +1,154 @@
+package gitea
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordedRequest struct {
+	Method string
+	Path   string
+	Body   map[string]string
+}
+
+// fixtureServer serves recorded Gitea API responses from testdata, keyed by method and path
+func fixtureServer(t *testing.T, fixtures map[string]string) (GiteaService, *[]recordedRequest) {
+	var requests []recordedRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "token secret", r.Header.Get("Authorization"))
+		request := recordedRequest{Method: r.Method, Path: r.URL.Path}
+		json.NewDecoder(r.Body).Decode(&request.Body)
+		requests = append(requests, request)
+
+		fixture, ok := fixtures[r.Method+" "+r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if fixture == "" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		body, err := os.ReadFile(filepath.Join("testdata", fixture))
+		require.NoError(t, err)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+	return NewGiteaService("secret", server.URL+"/", "infra", "live"), &requests
+}
+
+func TestGetChangedFilesIncludesRenamedFiles(t *testing.T) {
+	service, _ := fixtureServer(t, map[string]string{"GET /api/v1/repos/infra/live/pulls/12/files": "pull_files.json"})
+
+	files, err := service.GetChangedFiles(12)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"prod/vpc/main.tf", "prod/vpc/network.tf", "prod/vpc/subnets.tf"}, files)
+}
+
+func TestGetApprovalsIgnoresStaleAndDismissedReviews(t *testing.T) {
+	service, _ := fixtureServer(t, map[string]string{"GET /api/v1/repos/infra/live/pulls/12/reviews": "pull_reviews.json"})
+
+	approvals, err := service.GetApprovals(12)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bob"}, approvals)
+}
+
+func TestComments(t *testing.T) {
+	service, requests := fixtureServer(t, map[string]string{
+		"GET /api/v1/repos/infra/live/issues/12/comments":             "issue_comments.json",
+		"POST /api/v1/repos/infra/live/issues/12/comments":            "issue_comment_created.json",
+		"PATCH /api/v1/repos/infra/live/issues/comments/503":          "issue_comment_created.json",
+		"POST /api/v1/repos/infra/live/issues/comments/501/reactions": "",
+	})
+
+	comments, err := service.GetComments(12)
+	require.NoError(t, err)
+	require.Len(t, comments, 2)
+	assert.Equal(t, int64(501), comments[0].Id)
+	assert.Equal(t, "mantis plan", *comments[0].Body)
+	assert.Equal(t, "Plan for prod-vpc", *comments[1].Body)
+
+	comment, err := service.PublishComment(12, "Running plan")
+	require.NoError(t, err)
+	assert.Equal(t, int64(503), comment.Id)
+	assert.Equal(t, "https://forgejo.example.com/infra/live/pulls/12#issuecomment-503", comment.Url)
+
+	require.NoError(t, service.EditComment(12, comment.Id, "Plan finished"))
+	require.NoError(t, service.CreateCommentReaction(int64(501), "+1"))
+
+	assert.Equal(t, map[string]string{"body": "Running plan"}, (*requests)[1].Body)
+	assert.Equal(t, map[string]string{"body": "Plan finished"}, (*requests)[2].Body)
+	assert.Equal(t, map[string]string{"content": "+1"}, (*requests)[3].Body)
+}
+
+func TestPullRequestDetails(t *testing.T) {
+	service, _ := fixtureServer(t, map[string]string{"GET /api/v1/repos/infra/live/pulls/12": "pull.json"})
+
+	details, err := service.GetPullRequestDetails(12)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", details.Author)
+	assert.Equal(t, "main", details.BaseBranch)
+	assert.Equal(t, "vpc-eu-west-2", details.HeadBranch)
+
+	labels, err := service.GetLabels(12)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"network", "prod"}, labels)
+
+	branch, sha, err := service.GetBranchName(12)
+	require.NoError(t, err)
+	assert.Equal(t, "vpc-eu-west-2", branch)
+	assert.Equal(t, "9c8d7e6f5a4b3c2d1e0f9a8b7c6d5e4f3a2b1c0d", sha)
+
+	mergeable, err := service.IsMergeable(12)
+	require.NoError(t, err)
+	assert.True(t, mergeable)
+	merged, err := service.IsMerged(12)
+	require.NoError(t, err)
+	assert.False(t, merged)
+	closed, err := service.IsClosed(12)
+	require.NoError(t, err)
+	assert.False(t, closed)
+
+	_, err = service.GetPullRequestDetails(13)
+	assert.Error(t, err)
+}
+
+func TestStatusesAndMerge(t *testing.T) {
+	sha := "9c8d7e6f5a4b3c2d1e0f9a8b7c6d5e4f3a2b1c0d"
+	service, requests := fixtureServer(t, map[string]string{
+		"GET /api/v1/repos/infra/live/pulls/12":                   "pull.json",
+		"POST /api/v1/repos/infra/live/statuses/" + sha:           "",
+		"GET /api/v1/repos/infra/live/commits/" + sha + "/status": "commit_status.json",
+		"POST /api/v1/repos/infra/live/pulls/12/merge":            "",
+	})
+
+	require.NoError(t, service.SetStatus(12, "pending", "digger/plan"))
+	assert.Equal(t, map[string]string{"state": "pending", "context": "digger/plan", "description": "digger/plan"}, (*requests)[1].Body)
+
+	status, err := service.GetCombinedPullRequestStatus(12)
+	require.NoError(t, err)
+	assert.Equal(t, "failure", status)
+
+	require.NoError(t, service.MergePullRequest(12))
+	assert.Equal(t, map[string]string{"Do": "squash", "head_commit_id": sha}, (*requests)[len(*requests)-1].Body)
+}
+
+func TestGetUserTeams(t *testing.T) {
+	service, _ := fixtureServer(t, map[string]string{
+		"GET /api/v1/orgs/infra/teams":      "org_teams.json",
+		"GET /api/v1/teams/2/members/alice": "",
+		"GET /api/v1/teams/3/members/alice": "",
+	})
+
+	teams, err := service.GetUserTeams("infra", "alice")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"platform", "security"}, teams)
+}
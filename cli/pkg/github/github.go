@@ -2,7 +2,6 @@ package github
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
 	"github.com/diggerhq/digger/cli/pkg/backend"
 	core_backend "github.com/diggerhq/digger/cli/pkg/core/backend"
@@ -10,19 +9,19 @@ import (
 	"github.com/diggerhq/digger/cli/pkg/digger"
 	"github.com/diggerhq/digger/cli/pkg/drift"
 	github_models "github.com/diggerhq/digger/cli/pkg/github/models"
-	"github.com/diggerhq/digger/cli/pkg/storage"
 	"github.com/diggerhq/digger/cli/pkg/usage"
 	"github.com/diggerhq/digger/cli/pkg/utils"
 	"github.com/diggerhq/digger/libs/comment_utils/reporting"
 	comment_updater "github.com/diggerhq/digger/libs/comment_utils/summary"
 	"github.com/diggerhq/digger/libs/digger_config"
 	core_locking "github.com/diggerhq/digger/libs/locking"
+	"github.com/diggerhq/digger/libs/notifications"
 	"github.com/diggerhq/digger/libs/orchestrator"
 	dg_github "github.com/diggerhq/digger/libs/orchestrator/github"
 	"github.com/diggerhq/digger/libs/orchestrator/scheduler"
+	"github.com/diggerhq/digger/libs/storage"
 	"github.com/google/go-github/v61/github"
 	"github.com/samber/lo"
-	"gopkg.in/yaml.v3"
 	"log"
 	"os"
 	"strconv"
@@ -43,7 +42,10 @@ func GitHubCI(lock core_locking.Lock, policyCheckerProvider core_policy.PolicyCh
 	hostName := os.Getenv("DIGGER_HOSTNAME")
 	token := os.Getenv("DIGGER_TOKEN")
 	orgName := os.Getenv("DIGGER_ORGANISATION")
-	var policyChecker, _ = policyCheckerProvider.Get(hostName, token, orgName)
+	policyChecker, err := policyCheckerProvider.Get(hostName, orgName, token)
+	if err != nil {
+		usage.ReportErrorAndExit(githubActor, fmt.Sprintf("Failed to configure policy checker: %v", err), 1)
+	}
 
 	ghToken := os.Getenv("GITHUB_TOKEN")
 	if ghToken == "" {
@@ -89,6 +91,7 @@ func GitHubCI(lock core_locking.Lock, policyCheckerProvider core_policy.PolicyCh
 	if err != nil {
 		usage.ReportErrorAndExit(githubActor, fmt.Sprintf("could not create pr service: %v", err), 4)
 	}
+	prService := pullRequestService(githubPrService)
 
 	currentDir, err := os.Getwd()
 	if err != nil {
@@ -124,7 +127,10 @@ func GitHubCI(lock core_locking.Lock, policyCheckerProvider core_policy.PolicyCh
 		if jobSpec.BackendHostname != "" && jobSpec.BackendOrganisationName != "" && jobSpec.BackendJobToken != "" {
 			log.Printf("Found settings sent by backend in jobSpec string, overriding backendApi and policyCheckecd r. setting: (orgName: %v BackedHost: %v token: %v)", jobSpec.BackendOrganisationName, jobSpec.BackendHostname, "****")
 			backendApi = backend.NewBackendApi(jobSpec.BackendHostname, jobSpec.BackendJobToken)
-			policyChecker, _ = policyCheckerProvider.Get(jobSpec.BackendHostname, jobSpec.BackendOrganisationName, jobSpec.BackendJobToken)
+			policyChecker, err = policyCheckerProvider.Get(jobSpec.BackendHostname, jobSpec.BackendOrganisationName, jobSpec.BackendJobToken)
+			if err != nil {
+				usage.ReportErrorAndExit(githubActor, fmt.Sprintf("Failed to configure policy checker: %v", err), 4)
+			}
 
 		} else {
 			usage.ReportErrorAndExit(githubActor, fmt.Sprintf("Missing values from job spec: hostname, orgName, token: %v %v", jobSpec.BackendHostname, jobSpec.BackendOrganisationName), 4)
@@ -154,12 +160,16 @@ func GitHubCI(lock core_locking.Lock, policyCheckerProvider core_policy.PolicyCh
 			usage.ReportErrorAndExit(githubActor, fmt.Sprintf("Failed to read Digger digger_config. %s", err), 4)
 		}
 		log.Printf("Digger digger_config read successfully\n")
+		notifier := notifications.Load(diggerConfig.Notifications, os.LookupEnv)
 
 		log.Printf("Warn: Overriding commenting strategy to Comments-per-run")
 		strategy := &reporting.CommentPerRunStrategy{
 			Title:     fmt.Sprintf("%v for %v", jobSpec.JobType, jobSpec.ProjectName),
 			TimeOfRun: time.Now(),
 		}
+		if overflow := storage.NewArtifactOverflowStorageFromEnv(); overflow != nil {
+			strategy.Overflow = overflow
+		}
 		cireporter := &reporting.CiReporter{
 			CiService:         &githubPrService,
 			PrNumber:          *jobSpec.PullRequestNumber,
@@ -170,7 +180,8 @@ func GitHubCI(lock core_locking.Lock, policyCheckerProvider core_policy.PolicyCh
 		var reporter reporting.Reporter = reporting.NewCiReporterLazy(*cireporter)
 
 		reportTerraformOutput := false
-		commentUpdater, err := commentUpdaterProvider.Get(*diggerConfig)
+		commentTemplates := digger.LoadCommentTemplates(currentDir)
+		commentUpdater, err := commentUpdaterProvider.Get(*diggerConfig, commentTemplates)
 		if err != nil {
 			usage.ReportErrorAndExit(githubActor, fmt.Sprintf("could not get comment updater: %v", err), 8)
 		}
@@ -185,7 +196,7 @@ func GitHubCI(lock core_locking.Lock, policyCheckerProvider core_policy.PolicyCh
 		commentUpdater.UpdateComment(serializedBatch.Jobs, serializedBatch.PrNumber, &githubPrService, commentId64)
 		digger.UpdateAggregateStatus(serializedBatch, &githubPrService)
 
-		planStorage := storage.NewPlanStorage(ghToken, repoOwner, repositoryName, githubActor, jobSpec.PullRequestNumber)
+		planStorage := digger.NewPlanStorage(ghToken, repoOwner, repositoryName, githubActor, jobSpec.PullRequestNumber)
 
 		if err != nil {
 			serializedBatch, reportingError := backendApi.ReportProjectJobStatus(repoName, jobSpec.ProjectName, inputs.Id, "failed", time.Now(), nil, "", "")
@@ -207,7 +218,12 @@ func GitHubCI(lock core_locking.Lock, policyCheckerProvider core_policy.PolicyCh
 
 		jobs := []orchestrator.Job{orchestrator.JsonToJob(jobSpec)}
 
-		allAppliesSuccess, _, err := digger.RunJobs(jobs, &githubPrService, &githubPrService, lock, reporter, planStorage, policyChecker, commentUpdater, backendApi, inputs.Id, true, reportTerraformOutput, commentId64, currentDir)
+		jobPrService := prService
+		if diggerConfig.ReportingCheckRuns {
+			// the backend reports the check runs of its GitHub App
+			jobPrService = &githubPrService
+		}
+		allAppliesSuccess, _, err := digger.RunJobs(jobs, jobPrService, &githubPrService, lock, reporter, planStorage, policyChecker, commentUpdater, backendApi, inputs.Id, true, reportTerraformOutput, commentId64, currentDir, diggerConfig.Hooks, diggerConfig.ProviderCache, commentTemplates, notifier)
 		if !allAppliesSuccess || err != nil {
 			serializedBatch, reportingError := backendApi.ReportProjectJobStatus(repoName, jobSpec.ProjectName, inputs.Id, "failed", time.Now(), nil, "", "")
 			if reportingError != nil {
@@ -231,21 +247,7 @@ func GitHubCI(lock core_locking.Lock, policyCheckerProvider core_policy.PolicyCh
 		lock = core_locking.NoOpLock{}
 	}
 
-	yamlData, err := yaml.Marshal(diggerConfigYaml)
-	if err != nil {
-		log.Fatalf("error: %v", err)
-	}
-
-	// Convert to string
-	yamlStr := string(yamlData)
-	repo := strings.ReplaceAll(ghRepository, "/", "-")
-
-	for _, p := range diggerConfig.Projects {
-		err = backendApi.ReportProject(repo, p.Name, yamlStr)
-		if err != nil {
-			log.Printf("Failed to report project %s. %s\n", p.Name, err)
-		}
-	}
+	digger.ReportProjects(backendApi, ghRepository, diggerConfig, diggerConfigYaml)
 
 	if runningMode == "manual" {
 		command := os.Getenv("INPUT_DIGGER_COMMAND")
@@ -267,13 +269,14 @@ func GitHubCI(lock core_locking.Lock, policyCheckerProvider core_policy.PolicyCh
 
 		stateEnvVars, commandEnvVars := digger_config.CollectTerraformEnvConfig(workflow.EnvVars)
 
-		planStorage := storage.NewPlanStorage(ghToken, repoOwner, repositoryName, githubActor, nil)
+		planStorage := digger.NewPlanStorage(ghToken, repoOwner, repositoryName, githubActor, nil)
 
 		jobs := orchestrator.Job{
 			ProjectName:       project,
 			ProjectDir:        projectConfig.Dir,
 			ProjectWorkspace:  projectConfig.Workspace,
 			Terragrunt:        projectConfig.Terragrunt,
+			TerragruntRunAll:  projectConfig.TerragruntRunAll,
 			OpenTofu:          projectConfig.OpenTofu,
 			Commands:          []string{command},
 			ApplyStage:        orchestrator.ToConfigStage(workflow.Apply),
@@ -284,8 +287,9 @@ func GitHubCI(lock core_locking.Lock, policyCheckerProvider core_policy.PolicyCh
 			Namespace:         ghRepository,
 			StateEnvVars:      stateEnvVars,
 			CommandEnvVars:    commandEnvVars,
+			EnvAllowlist:      digger_config.CollectEnvAllowlist(workflow.EnvVars),
 		}
-		err := digger.RunJob(jobs, ghRepository, githubActor, &githubPrService, policyChecker, planStorage, backendApi, nil, currentDir)
+		err := digger.RunJob(jobs, ghRepository, githubActor, &githubPrService, policyChecker, planStorage, backendApi, nil, currentDir, diggerConfig.ProviderCache)
 		if err != nil {
 			usage.ReportErrorAndExit(githubActor, fmt.Sprintf("Failed to run commands. %s", err), 8)
 		}
@@ -306,11 +310,13 @@ func GitHubCI(lock core_locking.Lock, policyCheckerProvider core_policy.PolicyCh
 				ProjectDir:         projectConfig.Dir,
 				ProjectWorkspace:   projectConfig.Workspace,
 				Terragrunt:         projectConfig.Terragrunt,
+				TerragruntRunAll:   projectConfig.TerragruntRunAll,
 				OpenTofu:           projectConfig.OpenTofu,
 				Commands:           []string{"digger drift-detect"},
 				ApplyStage:         orchestrator.ToConfigStage(workflow.Apply),
 				PlanStage:          orchestrator.ToConfigStage(workflow.Plan),
 				CommandEnvVars:     commandEnvVars,
+				EnvAllowlist:       digger_config.CollectEnvAllowlist(workflow.EnvVars),
 				StateEnvVars:       stateEnvVars,
 				RequestedBy:        githubActor,
 				Namespace:          ghRepository,
@@ -324,117 +330,23 @@ func GitHubCI(lock core_locking.Lock, policyCheckerProvider core_policy.PolicyCh
 				usage.ReportErrorAndExit(githubActor, fmt.Sprintf("could not get drift notification type: %v", err), 8)
 			}
 
-			err = digger.RunJob(job, ghRepository, githubActor, &githubPrService, policyChecker, nil, backendApi, &notification, currentDir)
+			err = digger.RunJob(job, ghRepository, githubActor, &githubPrService, policyChecker, nil, backendApi, &notification, currentDir, diggerConfig.ProviderCache)
 			if err != nil {
 				usage.ReportErrorAndExit(githubActor, fmt.Sprintf("Failed to run commands. %s", err), 8)
 			}
 		}
 	} else {
-
-		impactedProjects, requestedProject, prNumber, err := dg_github.ProcessGitHubEvent(ghEvent, diggerConfig, &githubPrService)
-		if err != nil {
-			if errors.Is(err, dg_github.UnhandledMergeGroupEventError) {
-				usage.ReportErrorAndExit(githubActor, fmt.Sprintf("Graceful handling of GitHub event. %s", err), 0)
-			} else {
-				usage.ReportErrorAndExit(githubActor, fmt.Sprintf("Failed to process GitHub event. %s", err), 6)
-			}
-		}
-		log.Printf("Following projects are impacted by pull request #%d\n", prNumber)
-		for _, p := range impactedProjects {
-			log.Printf("- %s\n", p.Name)
-		}
-		log.Println("GitHub event processed successfully")
-
-		if dg_github.CheckIfHelpComment(ghEvent) {
-			reply := utils.GetCommands()
-			_, err := githubPrService.PublishComment(prNumber, reply)
-			if err != nil {
-				usage.ReportErrorAndExit(githubActor, "Failed to publish help command output", 1)
-			}
-		}
-
-		if len(impactedProjects) == 0 {
-			usage.ReportErrorAndExit(githubActor, "No projects impacted", 0)
-		}
-
-		var jobs []orchestrator.Job
-		coversAllImpactedProjects := false
-		err = nil
-		if prEvent, ok := ghEvent.(github.PullRequestEvent); ok {
-			jobs, coversAllImpactedProjects, err = dg_github.ConvertGithubPullRequestEventToJobs(&prEvent, impactedProjects, requestedProject, *diggerConfig)
-		} else if commentEvent, ok := ghEvent.(github.IssueCommentEvent); ok {
-			prBranchName, _, err := githubPrService.GetBranchName(*commentEvent.Issue.Number)
-			if err != nil {
-				usage.ReportErrorAndExit(githubActor, fmt.Sprintf("Error while retriving default branch from Issue: %v", err), 6)
-			}
-			jobs, coversAllImpactedProjects, err = dg_github.ConvertGithubIssueCommentEventToJobs(&commentEvent, impactedProjects, requestedProject, diggerConfig.Workflows, prBranchName)
-		} else {
-			usage.ReportErrorAndExit(githubActor, fmt.Sprintf("Unsupported GitHub event type. %s", err), 6)
-		}
-
-		if err != nil {
-			usage.ReportErrorAndExit(githubActor, fmt.Sprintf("Failed to convert GitHub event to commands. %s", err), 7)
-		}
-		log.Println("GitHub event converted to commands successfully")
-		logCommands(jobs)
-
-		err = githubPrService.SetOutput(prNumber, "DIGGER_PR_NUMBER", fmt.Sprintf("%v", prNumber))
-		if err != nil {
-			usage.ReportErrorAndExit(githubActor, fmt.Sprintf("Failed to set job output. Exiting. %s", err), 4)
-		}
-
-		planStorage := storage.NewPlanStorage(ghToken, repoOwner, repositoryName, githubActor, &prNumber)
-
-		reporter := &reporting.CiReporter{
-			CiService:         &githubPrService,
-			PrNumber:          prNumber,
-			ReportStrategy:    reportingStrategy,
-			IsSupportMarkdown: true,
-		}
-
-		jobs = digger.SortedCommandsByDependency(jobs, &dependencyGraph)
-
-		allAppliesSuccessful, atLeastOneApply, err := digger.RunJobs(jobs, &githubPrService, &githubPrService, lock, reporter, planStorage, policyChecker, comment_updater.NoopCommentUpdater{}, backendApi, "", false, false, 0, currentDir)
-		if err != nil {
-			usage.ReportErrorAndExit(githubActor, fmt.Sprintf("Failed to run commands. %s", err), 8)
-			// aggregate status checks: failure
-			if allAppliesSuccessful {
-				if atLeastOneApply {
-					githubPrService.SetStatus(prNumber, "failure", "digger/apply")
-				} else {
-					githubPrService.SetStatus(prNumber, "failure", "digger/plan")
-				}
-			}
-		}
-
-		if diggerConfig.AutoMerge && allAppliesSuccessful && atLeastOneApply && coversAllImpactedProjects {
-			digger.MergePullRequest(&githubPrService, prNumber)
-			log.Println("PR merged successfully")
-		}
-
-		if allAppliesSuccessful {
-			// aggreate status checks: success
-			if atLeastOneApply {
-				githubPrService.SetStatus(prNumber, "success", "digger/apply")
-			} else {
-				githubPrService.SetStatus(prNumber, "success", "digger/plan")
-			}
-		}
-
-		log.Println("Commands executed successfully")
+		digger.RunPullRequestEvent(ghEvent, prService, &githubPrService, &githubPrService, lock, policyChecker, backendApi, reportingStrategy, diggerConfig, dependencyGraph, githubActor, ghToken, repoOwner, repositoryName, currentDir)
 	}
 
 	usage.ReportErrorAndExit(githubActor, "Digger finished successfully", 0)
 }
 
-func logCommands(projectCommands []orchestrator.Job) {
-	logMessage := fmt.Sprintf("Following commands are going to be executed:\n")
-	for _, pc := range projectCommands {
-		logMessage += fmt.Sprintf("project: %s: commands: ", pc.ProjectName)
-		for _, c := range pc.Commands {
-			logMessage += fmt.Sprintf("\"%s\", ", c)
-		}
-		logMessage += "\n"
+// pullRequestService reports project statuses as check runs when REPORTING_CHECK_RUNS is enabled. They are created
+// with the GITHUB_TOKEN and have no buttons, GitHub would send their events to GitHub Actions instead of the backend
+func pullRequestService(githubPrService dg_github.GithubService) orchestrator.PullRequestService {
+	if os.Getenv("REPORTING_CHECK_RUNS") == "true" {
+		return dg_github.NewCheckRunsService(githubPrService)
 	}
-	log.Print(logMessage)
+	return &githubPrService
 }
@@ -0,0 +1,100 @@
+package hooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/diggerhq/digger/cli/pkg/core/runners"
+	configuration "github.com/diggerhq/digger/libs/digger_config"
+	"github.com/diggerhq/digger/libs/terraform_utils"
+)
+
+type Event string
+
+const (
+	EventPrePlan   Event = "pre_plan"
+	EventPostPlan  Event = "post_plan"
+	EventPreApply  Event = "pre_apply"
+	EventPostApply Event = "post_apply"
+	EventOnFailure Event = "on_failure"
+)
+
+// ContextFileEnvVar points hook steps to the JSON file describing the current run
+const ContextFileEnvVar = "DIGGER_HOOK_CONTEXT"
+
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+type Context struct {
+	Event       Event                        `json:"event"`
+	Namespace   string                       `json:"namespace"`
+	ProjectName string                       `json:"project_name"`
+	ProjectDir  string                       `json:"project_dir"`
+	Workspace   string                       `json:"workspace"`
+	Command     string                       `json:"command"`
+	PrNumber    *int                         `json:"pr_number,omitempty"`
+	RequestedBy string                       `json:"requested_by"`
+	PlanSummary *terraform_utils.PlanSummary `json:"plan_summary,omitempty"`
+	Outcome     string                       `json:"outcome,omitempty"`
+	Error       string                       `json:"error,omitempty"`
+}
+
+type HookRunner struct {
+	Hooks         configuration.Hooks
+	CommandRunner runners.CommandRun
+}
+
+func (h HookRunner) steps(event Event) []configuration.Step {
+	switch event {
+	case EventPrePlan:
+		return h.Hooks.PrePlan
+	case EventPostPlan:
+		return h.Hooks.PostPlan
+	case EventPreApply:
+		return h.Hooks.PreApply
+	case EventPostApply:
+		return h.Hooks.PostApply
+	case EventOnFailure:
+		return h.Hooks.OnFailure
+	}
+	return nil
+}
+
+// Run executes the hook steps configured for ctx.Event in workingDir, stopping at the first failing step
+func (h HookRunner) Run(workingDir string, envs map[string]string, ctx Context) error {
+	steps := h.steps(ctx.Event)
+	if len(steps) == 0 {
+		return nil
+	}
+
+	contextFile, err := os.CreateTemp("", "digger-hook-context-*.json")
+	if err != nil {
+		return fmt.Errorf("error creating hook context file: %v", err)
+	}
+	defer os.Remove(contextFile.Name())
+
+	err = json.NewEncoder(contextFile).Encode(ctx)
+	contextFile.Close()
+	if err != nil {
+		return fmt.Errorf("error writing hook context file: %v", err)
+	}
+
+	hookEnvs := make(map[string]string, len(envs)+1)
+	for k, v := range envs {
+		hookEnvs[k] = v
+	}
+	hookEnvs[ContextFileEnvVar] = contextFile.Name()
+
+	for _, step := range steps {
+		log.Printf("Running %v hook %v for **%v**\n", ctx.Event, step.Value, ctx.Namespace+"#"+ctx.ProjectName)
+		_, _, err := h.CommandRunner.Run(workingDir, step.Shell, []string{step.Value}, hookEnvs)
+		if err != nil {
+			return fmt.Errorf("%v hook failed: %v", ctx.Event, err)
+		}
+	}
+	return nil
+}
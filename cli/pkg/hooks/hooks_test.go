@@ -0,0 +1,49 @@
+package hooks
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/diggerhq/digger/cli/pkg/core/runners"
+	configuration "github.com/diggerhq/digger/libs/digger_config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHookReceivesContextFile(t *testing.T) {
+	dir := t.TempDir()
+	out := path.Join(dir, "context.json")
+	runner := HookRunner{
+		Hooks: configuration.Hooks{
+			PostPlan: []configuration.Step{{Action: "run", Value: "cp $DIGGER_HOOK_CONTEXT " + out}},
+		},
+		CommandRunner: runners.CommandRunner{},
+	}
+	prNumber := 7
+	err := runner.Run(dir, map[string]string{}, Context{Event: EventPostPlan, ProjectName: "dev", Command: "mantis plan", PrNumber: &prNumber, Outcome: OutcomeSuccess})
+	assert.NoError(t, err)
+
+	contents, err := os.ReadFile(out)
+	assert.NoError(t, err)
+	var ctx Context
+	assert.NoError(t, json.Unmarshal(contents, &ctx))
+	assert.Equal(t, EventPostPlan, ctx.Event)
+	assert.Equal(t, "dev", ctx.ProjectName)
+	assert.Equal(t, 7, *ctx.PrNumber)
+	assert.Equal(t, OutcomeSuccess, ctx.Outcome)
+}
+
+func TestHookFailureIsReturned(t *testing.T) {
+	runner := HookRunner{
+		Hooks: configuration.Hooks{
+			PreApply: []configuration.Step{{Action: "run", Value: "exit 1"}},
+		},
+		CommandRunner: runners.CommandRunner{},
+	}
+	err := runner.Run(t.TempDir(), map[string]string{}, Context{Event: EventPreApply})
+	assert.ErrorContains(t, err, "pre_apply hook failed")
+
+	err = runner.Run(t.TempDir(), map[string]string{}, Context{Event: EventPrePlan})
+	assert.NoError(t, err)
+}
@@ -10,17 +10,17 @@ import (
 
 	"github.com/diggerhq/digger/libs/locking"
 	"github.com/diggerhq/digger/libs/locking/aws"
+	"github.com/diggerhq/digger/libs/notifications"
 
 	comment_updater "github.com/diggerhq/digger/libs/comment_utils/summary"
 
 	"github.com/diggerhq/digger/cli/pkg/core/terraform"
 	"github.com/diggerhq/digger/cli/pkg/digger"
 	"github.com/diggerhq/digger/cli/pkg/github/models"
-	"github.com/diggerhq/digger/cli/pkg/storage"
-	"github.com/diggerhq/digger/cli/pkg/utils"
 	"github.com/diggerhq/digger/libs/comment_utils/reporting"
 	configuration "github.com/diggerhq/digger/libs/digger_config"
 	dg_github "github.com/diggerhq/digger/libs/orchestrator/github"
+	"github.com/diggerhq/digger/libs/storage"
 
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
@@ -399,13 +399,11 @@ func TestHappyPath(t *testing.T) {
 	event := ghEvent.(github.PullRequestEvent)
 	jobs, _, err := dg_github.ConvertGithubPullRequestEventToJobs(&event, impactedProjects, requestedProject, *diggerConfig)
 	assert.NoError(t, err)
-	zipManager := utils.Zipper{}
 	planStorage := &storage.GithubPlanStorage{
 		Client:            github.NewTokenClient(context.Background(), ghToken),
 		Owner:             repoOwner,
 		RepoName:          repositoryName,
 		PullRequestNumber: prNumber,
-		ZipManager:        zipManager,
 	}
 
 	reporter := &reporting.CiReporter{
@@ -415,7 +413,7 @@ func TestHappyPath(t *testing.T) {
 
 	diggerProjectNamespace := repoOwner + "/" + repositoryName
 
-	_, _, err = digger.RunJobs(jobs, &githubPrService, &githubPrService, lock, reporter, planStorage, nil, comment_updater.NoopCommentUpdater{}, nil, "", false, false, 123, dir)
+	_, _, err = digger.RunJobs(jobs, &githubPrService, &githubPrService, lock, reporter, planStorage, nil, comment_updater.NoopCommentUpdater{}, nil, "", false, false, 123, dir, configuration.Hooks{}, configuration.ProviderCache{Upgrade: true}, nil, notifications.NoopNotifier{})
 	assert.NoError(t, err)
 
 	projectLock := &locking.PullRequestLock{
@@ -442,7 +440,7 @@ func TestHappyPath(t *testing.T) {
 	prEvent := ghEvent.(github.PullRequestEvent)
 	jobs, _, err = dg_github.ConvertGithubPullRequestEventToJobs(&prEvent, impactedProjects, requestedProject, *diggerConfig)
 	assert.NoError(t, err)
-	_, _, err = digger.RunJobs(jobs, &githubPrService, &githubPrService, lock, reporter, planStorage, nil, comment_updater.NoopCommentUpdater{}, nil, "", false, false, 123, dir)
+	_, _, err = digger.RunJobs(jobs, &githubPrService, &githubPrService, lock, reporter, planStorage, nil, comment_updater.NoopCommentUpdater{}, nil, "", false, false, 123, dir, configuration.Hooks{}, configuration.ProviderCache{Upgrade: true}, nil, notifications.NoopNotifier{})
 	assert.NoError(t, err)
 
 	println("--- mantis apply comment ---")
@@ -457,7 +455,7 @@ func TestHappyPath(t *testing.T) {
 	cEvent := ghEvent.(github.IssueCommentEvent)
 	jobs, _, err = dg_github.ConvertGithubIssueCommentEventToJobs(&cEvent, impactedProjects, requestedProject, diggerConfig.Workflows, "prBranch")
 	assert.NoError(t, err)
-	_, _, err = digger.RunJobs(jobs, &githubPrService, &githubPrService, lock, reporter, planStorage, nil, comment_updater.NoopCommentUpdater{}, nil, "", false, false, 123, dir)
+	_, _, err = digger.RunJobs(jobs, &githubPrService, &githubPrService, lock, reporter, planStorage, nil, comment_updater.NoopCommentUpdater{}, nil, "", false, false, 123, dir, configuration.Hooks{}, configuration.ProviderCache{Upgrade: true}, nil, notifications.NoopNotifier{})
 	assert.NoError(t, err)
 
 	projectLock = &locking.PullRequestLock{
@@ -481,7 +479,7 @@ func TestHappyPath(t *testing.T) {
 	cEvent = ghEvent.(github.IssueCommentEvent)
 	jobs, _, err = dg_github.ConvertGithubIssueCommentEventToJobs(&cEvent, impactedProjects, requestedProject, diggerConfig.Workflows, "prBranch")
 	assert.NoError(t, err)
-	_, _, err = digger.RunJobs(jobs, &githubPrService, &githubPrService, lock, reporter, planStorage, nil, comment_updater.NoopCommentUpdater{}, nil, "", false, false, 123, dir)
+	_, _, err = digger.RunJobs(jobs, &githubPrService, &githubPrService, lock, reporter, planStorage, nil, comment_updater.NoopCommentUpdater{}, nil, "", false, false, 123, dir, configuration.Hooks{}, configuration.ProviderCache{Upgrade: true}, nil, notifications.NoopNotifier{})
 	assert.NoError(t, err)
 
 	projectLock = &locking.PullRequestLock{
@@ -557,20 +555,18 @@ func TestMultiEnvHappyPath(t *testing.T) {
 	jobs, _, err := dg_github.ConvertGithubPullRequestEventToJobs(&pEvent, impactedProjects, requestedProject, *diggerConfig)
 	assert.NoError(t, err)
 
-	zipManager := utils.Zipper{}
 	planStorage := &storage.GithubPlanStorage{
 		Client:            github.NewTokenClient(context.Background(), ghToken),
 		Owner:             repoOwner,
 		RepoName:          repositoryName,
 		PullRequestNumber: prNumber,
-		ZipManager:        zipManager,
 	}
 
 	reporter := &reporting.CiReporter{
 		CiService: &githubPrService,
 		PrNumber:  prNumber,
 	}
-	_, _, err = digger.RunJobs(jobs, &githubPrService, &githubPrService, &dynamoDbLock, reporter, planStorage, nil, comment_updater.NoopCommentUpdater{}, nil, "", false, false, 123, dir)
+	_, _, err = digger.RunJobs(jobs, &githubPrService, &githubPrService, &dynamoDbLock, reporter, planStorage, nil, comment_updater.NoopCommentUpdater{}, nil, "", false, false, 123, dir, configuration.Hooks{}, configuration.ProviderCache{Upgrade: true}, nil, notifications.NoopNotifier{})
 	assert.NoError(t, err)
 
 	projectLock := &locking.PullRequestLock{
@@ -596,7 +592,7 @@ func TestMultiEnvHappyPath(t *testing.T) {
 	cEvent := ghEvent.(github.IssueCommentEvent)
 	jobs, _, err = dg_github.ConvertGithubIssueCommentEventToJobs(&cEvent, impactedProjects, requestedProject, diggerConfig.Workflows, "prBranch")
 	assert.NoError(t, err)
-	_, _, err = digger.RunJobs(jobs, &githubPrService, &githubPrService, &dynamoDbLock, reporter, planStorage, nil, comment_updater.NoopCommentUpdater{}, nil, "", false, false, 123, dir)
+	_, _, err = digger.RunJobs(jobs, &githubPrService, &githubPrService, &dynamoDbLock, reporter, planStorage, nil, comment_updater.NoopCommentUpdater{}, nil, "", false, false, 123, dir, configuration.Hooks{}, configuration.ProviderCache{Upgrade: true}, nil, notifications.NoopNotifier{})
 	assert.NoError(t, err)
 
 	println("--- mantis apply comment ---")
@@ -610,7 +606,7 @@ func TestMultiEnvHappyPath(t *testing.T) {
 	cEvent = ghEvent.(github.IssueCommentEvent)
 	jobs, _, err = dg_github.ConvertGithubIssueCommentEventToJobs(&cEvent, impactedProjects, requestedProject, diggerConfig.Workflows, "prBranch")
 	assert.NoError(t, err)
-	_, _, err = digger.RunJobs(jobs, &githubPrService, &githubPrService, &dynamoDbLock, reporter, planStorage, nil, comment_updater.NoopCommentUpdater{}, nil, "", false, false, 123, dir)
+	_, _, err = digger.RunJobs(jobs, &githubPrService, &githubPrService, &dynamoDbLock, reporter, planStorage, nil, comment_updater.NoopCommentUpdater{}, nil, "", false, false, 123, dir, configuration.Hooks{}, configuration.ProviderCache{Upgrade: true}, nil, notifications.NoopNotifier{})
 	assert.NoError(t, err)
 
 	projectLock = &locking.PullRequestLock{
@@ -634,7 +630,7 @@ func TestMultiEnvHappyPath(t *testing.T) {
 	cEvent = ghEvent.(github.IssueCommentEvent)
 	jobs, _, err = dg_github.ConvertGithubIssueCommentEventToJobs(&cEvent, impactedProjects, requestedProject, diggerConfig.Workflows, "prBranch")
 	assert.NoError(t, err)
-	_, _, err = digger.RunJobs(jobs, &githubPrService, &githubPrService, &dynamoDbLock, reporter, planStorage, nil, comment_updater.NoopCommentUpdater{}, nil, "", false, false, 123, dir)
+	_, _, err = digger.RunJobs(jobs, &githubPrService, &githubPrService, &dynamoDbLock, reporter, planStorage, nil, comment_updater.NoopCommentUpdater{}, nil, "", false, false, 123, dir, configuration.Hooks{}, configuration.ProviderCache{Upgrade: true}, nil, notifications.NoopNotifier{})
 	assert.NoError(t, err)
 
 	projectLock = &locking.PullRequestLock{
@@ -778,13 +774,11 @@ workflows:
 	jobs, _, err := dg_github.ConvertGithubPullRequestEventToJobs(&pEvent, impactedProjects, requestedProject, *diggerConfig)
 	assert.NoError(t, err)
 
-	zipManager := utils.Zipper{}
 	planStorage := &storage.GithubPlanStorage{
 		Client:            github.NewTokenClient(context.Background(), ghToken),
 		Owner:             repoOwner,
 		RepoName:          repositoryName,
 		PullRequestNumber: prNumber,
-		ZipManager:        zipManager,
 	}
 
 	reporter := &reporting.CiReporter{
@@ -792,7 +786,7 @@ workflows:
 		PrNumber:  prNumber,
 	}
 
-	_, _, err = digger.RunJobs(jobs, &githubPrService, &githubPrService, lock, reporter, planStorage, nil, comment_updater.NoopCommentUpdater{}, nil, "", false, false, 123, dir)
+	_, _, err = digger.RunJobs(jobs, &githubPrService, &githubPrService, lock, reporter, planStorage, nil, comment_updater.NoopCommentUpdater{}, nil, "", false, false, 123, dir, configuration.Hooks{}, configuration.ProviderCache{Upgrade: true}, nil, notifications.NoopNotifier{})
 	assert.NoError(t, err)
 
 	projectLock := &locking.PullRequestLock{
@@ -818,7 +812,7 @@ workflows:
 	cEvent := ghEvent.(github.IssueCommentEvent)
 	jobs, _, err = dg_github.ConvertGithubIssueCommentEventToJobs(&cEvent, impactedProjects, requestedProject, diggerConfig.Workflows, "prBranch")
 	assert.NoError(t, err)
-	_, _, err = digger.RunJobs(jobs, &githubPrService, &githubPrService, lock, reporter, planStorage, nil, comment_updater.NoopCommentUpdater{}, nil, "", false, false, 123, dir)
+	_, _, err = digger.RunJobs(jobs, &githubPrService, &githubPrService, lock, reporter, planStorage, nil, comment_updater.NoopCommentUpdater{}, nil, "", false, false, 123, dir, configuration.Hooks{}, configuration.ProviderCache{Upgrade: true}, nil, notifications.NoopNotifier{})
 	assert.NoError(t, err)
 
 	println("--- mantis apply comment ---")
@@ -832,7 +826,7 @@ workflows:
 	cEvent = ghEvent.(github.IssueCommentEvent)
 	jobs, _, err = dg_github.ConvertGithubIssueCommentEventToJobs(&cEvent, impactedProjects, requestedProject, diggerConfig.Workflows, "prBranch")
 	assert.NoError(t, err)
-	_, _, err = digger.RunJobs(jobs, &githubPrService, &githubPrService, lock, reporter, planStorage, nil, comment_updater.NoopCommentUpdater{}, nil, "", false, false, 123, dir)
+	_, _, err = digger.RunJobs(jobs, &githubPrService, &githubPrService, lock, reporter, planStorage, nil, comment_updater.NoopCommentUpdater{}, nil, "", false, false, 123, dir, configuration.Hooks{}, configuration.ProviderCache{Upgrade: true}, nil, notifications.NoopNotifier{})
 	assert.NoError(t, err)
 
 	projectLock = &locking.PullRequestLock{
@@ -855,7 +849,7 @@ workflows:
 	cEvent = ghEvent.(github.IssueCommentEvent)
 	jobs, _, err = dg_github.ConvertGithubIssueCommentEventToJobs(&cEvent, impactedProjects, requestedProject, diggerConfig.Workflows, "prBranch")
 	assert.NoError(t, err)
-	_, _, err = digger.RunJobs(jobs, &githubPrService, &githubPrService, lock, reporter, planStorage, nil, comment_updater.NoopCommentUpdater{}, nil, "", false, false, 123, dir)
+	_, _, err = digger.RunJobs(jobs, &githubPrService, &githubPrService, lock, reporter, planStorage, nil, comment_updater.NoopCommentUpdater{}, nil, "", false, false, 123, dir, configuration.Hooks{}, configuration.ProviderCache{Upgrade: true}, nil, notifications.NoopNotifier{})
 	assert.NoError(t, err)
 
 	projectLock = &locking.PullRequestLock{
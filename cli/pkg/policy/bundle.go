@@ -0,0 +1,241 @@
+package policy
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/open-policy-agent/opa/bundle"
+	"github.com/open-policy-agent/opa/keys"
+)
+
+const (
+	bundleVerificationKeyId   = "digger"
+	defaultBundleKeyAlgorithm = "RS256"
+)
+
+// DiggerBundlePolicyProvider reads policies from an OPA bundle, a tar.gz served over http or a local tar.gz or
+// directory. Policies are laid out like repository policies, e.g. plan.rego and prod/app/plan.rego. The bundle is
+// loaded once and its signature is verified with PublicKey when set
+type DiggerBundlePolicyProvider struct {
+	Source string
+	// PublicKey is the PEM encoded key bundle signatures are verified with, unsigned bundles are rejected when set
+	PublicKey    string
+	KeyAlgorithm string
+	// AuthToken is sent as a bearer token when downloading bundles
+	AuthToken string
+	// CacheDir keeps downloaded bundles, they are revalidated with their ETag
+	CacheDir           string
+	HttpClient         *http.Client
+	DiggerOrganisation string
+
+	once     sync.Once
+	policies map[string]string
+	loadErr  error
+}
+
+// NewBundlePolicyProviderFromEnv configures a bundle provider with DIGGER_POLICY_BUNDLE and its related variables,
+// it returns nil when no bundle is configured
+func NewBundlePolicyProviderFromEnv(organisation string) (*DiggerBundlePolicyProvider, error) {
+	source := os.Getenv("DIGGER_POLICY_BUNDLE")
+	if source == "" {
+		return nil, nil
+	}
+	publicKey := os.Getenv("DIGGER_POLICY_BUNDLE_PUBLIC_KEY")
+	if keyFile := os.Getenv("DIGGER_POLICY_BUNDLE_PUBLIC_KEY_FILE"); keyFile != "" {
+		// an unreadable key must not turn off signature verification
+		content, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read policy bundle public key %v: %v", keyFile, err)
+		}
+		publicKey = string(content)
+	}
+	return &DiggerBundlePolicyProvider{
+		Source:             source,
+		PublicKey:          publicKey,
+		KeyAlgorithm:       os.Getenv("DIGGER_POLICY_BUNDLE_KEY_ALGORITHM"),
+		AuthToken:          os.Getenv("DIGGER_POLICY_BUNDLE_TOKEN"),
+		CacheDir:           DefaultBundleCacheDir(),
+		HttpClient:         http.DefaultClient,
+		DiggerOrganisation: organisation,
+	}, nil
+}
+
+// DefaultBundleCacheDir is DIGGER_POLICY_BUNDLE_CACHE_DIR, or a directory in the user cache directory when it is not set
+func DefaultBundleCacheDir() string {
+	if dir := os.Getenv("DIGGER_POLICY_BUNDLE_CACHE_DIR"); dir != "" {
+		return dir
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "mantis", "policy-bundles")
+}
+
+func (p *DiggerBundlePolicyProvider) load() (map[string]string, error) {
+	p.once.Do(func() {
+		p.policies, p.loadErr = p.readBundle()
+		if p.loadErr != nil {
+			p.loadErr = fmt.Errorf("could not load policy bundle %v: %v", p.Source, p.loadErr)
+		}
+	})
+	return p.policies, p.loadErr
+}
+
+func (p *DiggerBundlePolicyProvider) readBundle() (map[string]string, error) {
+	var reader *bundle.Reader
+	if strings.HasPrefix(p.Source, "http://") || strings.HasPrefix(p.Source, "https://") {
+		content, err := p.download()
+		if err != nil {
+			return nil, err
+		}
+		reader = bundle.NewReader(bytes.NewReader(content))
+	} else if info, err := os.Stat(p.Source); err == nil && info.IsDir() {
+		reader = bundle.NewCustomReader(bundle.NewDirectoryLoader(p.Source))
+	} else {
+		file, err := os.Open(p.Source)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		reader = bundle.NewReader(file)
+	}
+
+	if p.PublicKey != "" {
+		algorithm := p.KeyAlgorithm
+		if algorithm == "" {
+			algorithm = defaultBundleKeyAlgorithm
+		}
+		// keys.NewKeyConfig would treat the PEM text as a file path, the key is validated like keys of an OPA config instead
+		rawKeys, err := json.Marshal(map[string]keys.Config{bundleVerificationKeyId: {Key: p.PublicKey, Algorithm: algorithm}})
+		if err != nil {
+			return nil, err
+		}
+		keyConfigs, err := keys.ParseKeysConfig(rawKeys)
+		if err != nil {
+			return nil, fmt.Errorf("invalid policy bundle public key: %v", err)
+		}
+		reader = reader.WithBundleVerificationConfig(bundle.NewVerificationConfig(keyConfigs, bundleVerificationKeyId, "", nil))
+	} else {
+		log.Printf("WARNING: no public key configured, the signature of policy bundle %v is not verified", p.Source)
+	}
+
+	b, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	policies := make(map[string]string)
+	for _, module := range b.Modules {
+		policies[strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(module.Path)), "/")] = string(module.Raw)
+	}
+	log.Printf("Loaded %v policies from bundle %v", len(policies), p.Source)
+	return policies, nil
+}
+
+// download fetches the bundle, a cached copy is reused when the server reports it unchanged
+func (p *DiggerBundlePolicyProvider) download() ([]byte, error) {
+	var cachedBundlePath, cachedEtagPath string
+	if p.CacheDir != "" {
+		key := sha256.Sum256([]byte(p.Source))
+		cachedBundlePath = filepath.Join(p.CacheDir, hex.EncodeToString(key[:])+".tar.gz")
+		cachedEtagPath = cachedBundlePath + ".etag"
+	}
+
+	req, err := http.NewRequest(http.MethodGet, p.Source, nil)
+	if err != nil {
+		return nil, err
+	}
+	if p.AuthToken != "" {
+		req.Header.Set("Authorization", "Bearer "+p.AuthToken)
+	}
+	var cached []byte
+	if cachedBundlePath != "" {
+		etag, etagErr := os.ReadFile(cachedEtagPath)
+		cached, err = os.ReadFile(cachedBundlePath)
+		if etagErr == nil && err == nil {
+			req.Header.Set("If-None-Match", string(etag))
+		}
+	}
+
+	client := p.HttpClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		log.Printf("Policy bundle %v is unchanged, using the cached copy", p.Source)
+		return cached, nil
+	case http.StatusOK:
+	default:
+		return nil, fmt.Errorf("unexpected status %v", resp.StatusCode)
+	}
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("could not read bundle: %v", err)
+	}
+
+	if etag := resp.Header.Get("ETag"); etag != "" && cachedBundlePath != "" {
+		err = os.MkdirAll(p.CacheDir, 0700)
+		if err == nil {
+			err = os.WriteFile(cachedBundlePath, content, 0600)
+		}
+		if err == nil {
+			err = os.WriteFile(cachedEtagPath, []byte(etag), 0600)
+		}
+		if err != nil {
+			log.Printf("Could not cache policy bundle: %v", err)
+		}
+	}
+	return content, nil
+}
+
+func (p *DiggerBundlePolicyProvider) findPolicy(projectDir string, fileName string) (string, error) {
+	policies, err := p.load()
+	if err != nil {
+		return "", err
+	}
+	return findClosestPolicy(projectDir, fileName, func(path string) (string, bool, error) {
+		content, ok := policies[filepath.ToSlash(path)]
+		return content, ok, nil
+	})
+}
+
+func (p *DiggerBundlePolicyProvider) GetAccessPolicy(organisation string, repo string, projectName string, projectDir string) (string, error) {
+	content, err := p.findPolicy(projectDir, accessPolicyFile)
+	if err != nil {
+		return "", err
+	}
+	if content == "" {
+		return DefaultAccessPolicy, nil
+	}
+	return content, nil
+}
+
+func (p *DiggerBundlePolicyProvider) GetPlanPolicy(organisation string, repo string, projectName string, projectDir string) (string, error) {
+	return p.findPolicy(projectDir, planPolicyFile)
+}
+
+func (p *DiggerBundlePolicyProvider) GetDriftPolicy() (string, error) {
+	return p.findPolicy(".", driftPolicyFile)
+}
+
+func (p *DiggerBundlePolicyProvider) GetOrganisation() string {
+	return p.DiggerOrganisation
+}
@@ -0,0 +1,138 @@
+package policy
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/open-policy-agent/opa/bundle"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func generateBundleKeys(t *testing.T) (string, string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	publicKey, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	privatePem := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	publicPem := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey})
+	return string(privatePem), string(publicPem)
+}
+
+// buildBundle writes a bundle with the given policies, signed when privateKey is set
+func buildBundle(t *testing.T, policies map[string]string, privateKey string) []byte {
+	b := bundle.Bundle{
+		Manifest: bundle.Manifest{Revision: "test"},
+		Data:     map[string]interface{}{},
+	}
+	for path, content := range policies {
+		b.Modules = append(b.Modules, bundle.ModuleFile{URL: "/" + path, Path: "/" + path, Raw: []byte(content)})
+	}
+	if privateKey != "" {
+		err := b.GenerateSignature(bundle.NewSigningConfig(privateKey, "RS256", ""), bundleVerificationKeyId, false)
+		require.NoError(t, err)
+	}
+	var buf bytes.Buffer
+	require.NoError(t, bundle.NewWriter(&buf).Write(b))
+	return buf.Bytes()
+}
+
+func newBundleServer(t *testing.T, content []byte) (*httptest.Server, *atomic.Int32) {
+	downloads := &atomic.Int32{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads.Add(1)
+		w.Header().Set("ETag", `"v1"`)
+		w.Write(content)
+	}))
+	t.Cleanup(server.Close)
+	return server, downloads
+}
+
+func TestBundlePolicyProviderVerifiesAndCachesBundles(t *testing.T) {
+	privateKey, publicKey := generateBundleKeys(t)
+	content := buildBundle(t, map[string]string{
+		"access.rego":      onlyAlicePolicy,
+		"prod/plan.rego":   denyAllPlanPolicy,
+		"prod/access.rego": DefaultAccessPolicy,
+	}, privateKey)
+	server, downloads := newBundleServer(t, content)
+	cacheDir := t.TempDir()
+
+	provider := &DiggerBundlePolicyProvider{Source: server.URL + "/bundle.tar.gz", PublicKey: publicKey, CacheDir: cacheDir}
+	accessPolicy, err := provider.GetAccessPolicy("org", "repo", "app", "prod/app")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultAccessPolicy, accessPolicy)
+	accessPolicy, err = provider.GetAccessPolicy("org", "repo", "app", "dev")
+	require.NoError(t, err)
+	assert.Equal(t, onlyAlicePolicy, accessPolicy)
+	planPolicy, err := provider.GetPlanPolicy("org", "repo", "app", "prod/app")
+	require.NoError(t, err)
+	assert.Equal(t, denyAllPlanPolicy, planPolicy)
+	assert.Equal(t, int32(1), downloads.Load())
+
+	// a later run revalidates the cached bundle instead of downloading it again
+	provider = &DiggerBundlePolicyProvider{Source: server.URL + "/bundle.tar.gz", PublicKey: publicKey, CacheDir: cacheDir}
+	planPolicy, err = provider.GetPlanPolicy("org", "repo", "app", "prod")
+	require.NoError(t, err)
+	assert.Equal(t, denyAllPlanPolicy, planPolicy)
+	assert.Equal(t, int32(1), downloads.Load())
+}
+
+func TestBundlePolicyProviderRejectsUnverifiedBundles(t *testing.T) {
+	privateKey, publicKey := generateBundleKeys(t)
+	otherPrivateKey, _ := generateBundleKeys(t)
+
+	for name, content := range map[string][]byte{
+		"unsigned":     buildBundle(t, map[string]string{"plan.rego": denyAllPlanPolicy}, ""),
+		"wrong key":    buildBundle(t, map[string]string{"plan.rego": denyAllPlanPolicy}, otherPrivateKey),
+		"valid bundle": buildBundle(t, map[string]string{"plan.rego": denyAllPlanPolicy}, privateKey),
+	} {
+		t.Run(name, func(t *testing.T) {
+			server, _ := newBundleServer(t, content)
+			provider := &DiggerBundlePolicyProvider{Source: server.URL, PublicKey: publicKey}
+			_, err := provider.GetPlanPolicy("org", "repo", "app", "app")
+			if name == "valid bundle" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, "could not load policy bundle")
+			}
+		})
+	}
+}
+
+func TestBundlePolicyProviderLocalDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writePolicy(t, dir, "plan.rego", denyAllPlanPolicy)
+	checker := DiggerPolicyChecker{PolicyProvider: &DiggerBundlePolicyProvider{Source: dir}}
+
+	allowed, result, err := checker.CheckPlanPolicy("repo", "org", "app", "app", createPlan)
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, []string{"no changes allowed"}, result.DenyMessages())
+}
+
+func TestBundlePolicyProviderFailsClosedOnKeyErrors(t *testing.T) {
+	t.Setenv("DIGGER_POLICY_BUNDLE", t.TempDir())
+	t.Setenv("DIGGER_POLICY_BUNDLE_PUBLIC_KEY_FILE", filepath.Join(t.TempDir(), "missing.pem"))
+	_, err := NewBundlePolicyProviderFromEnv("org")
+	assert.ErrorContains(t, err, "could not read policy bundle public key")
+	_, err = PolicyCheckerProviderBasic{}.Get("", "org", "")
+	assert.Error(t, err)
+
+	_, publicKey := generateBundleKeys(t)
+	provider := &DiggerBundlePolicyProvider{Source: t.TempDir(), PublicKey: publicKey, KeyAlgorithm: "none"}
+	_, err = provider.GetPlanPolicy("org", "repo", "app", "app")
+	assert.ErrorContains(t, err, "unsupported algorithm")
+}
@@ -0,0 +1,103 @@
+package policy
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+)
+
+// PolicyDecision is the outcome of an evaluated policy, reported for auditing
+type PolicyDecision struct {
+	Organisation string `json:"-"`
+	Repo         string `json:"-"`
+	Project      string `json:"-"`
+	// Type is access, plan or drift
+	Type        string `json:"type"`
+	Action      string `json:"action"`
+	RequestedBy string `json:"requested_by"`
+	// InputHash and PolicyHash are sha256 hashes of the JSON encoded input and of the evaluated policy
+	InputHash  string   `json:"input_hash"`
+	PolicyHash string   `json:"policy_hash"`
+	Allowed    bool     `json:"allowed"`
+	Violations []string `json:"violations"`
+}
+
+type DecisionReporter interface {
+	ReportDecision(decision PolicyDecision) error
+}
+
+// BackendDecisionReporter sends decisions to the digger backend, which matches them to its policy revisions
+type BackendDecisionReporter struct {
+	DiggerHost string
+	AuthToken  string
+	HttpClient *http.Client
+}
+
+func (r BackendDecisionReporter) ReportDecision(decision PolicyDecision) error {
+	u, err := url.Parse(r.DiggerHost)
+	if err != nil {
+		return fmt.Errorf("not able to parse digger cloud url: %v", err)
+	}
+	namespace := fmt.Sprintf("%v-%v", decision.Organisation, decision.Repo)
+	u = u.JoinPath("repos", namespace, "projects", decision.Project, "policy-decisions")
+
+	decision.Violations = nonNil(decision.Violations)
+	body, err := json.Marshal(decision)
+	if err != nil {
+		return fmt.Errorf("could not encode policy decision: %v", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+r.AuthToken)
+
+	client := r.HttpClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected status %v: %s", resp.StatusCode, message)
+	}
+	return nil
+}
+
+func hashContent(content []byte) string {
+	hash := sha256.Sum256(content)
+	return hex.EncodeToString(hash[:])
+}
+
+func hashPolicyInput(input map[string]interface{}) string {
+	content, err := json.Marshal(input)
+	if err != nil {
+		log.Printf("Could not encode policy input for hashing: %v", err)
+		return ""
+	}
+	return hashContent(content)
+}
+
+// reportDecision reports a decision when the checker has a reporter, failures never fail the policy check
+func (p DiggerPolicyChecker) reportDecision(decision PolicyDecision, evaluatedPolicy string, input map[string]interface{}) {
+	if p.DecisionReporter == nil {
+		return
+	}
+	decision.PolicyHash = hashContent([]byte(evaluatedPolicy))
+	decision.InputHash = hashPolicyInput(input)
+	err := p.DecisionReporter.ReportDecision(decision)
+	if err != nil {
+		log.Printf("WARNING: could not report %v policy decision for project %v: %v", decision.Type, decision.Project, err)
+	}
+}
@@ -0,0 +1,103 @@
+package policy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/diggerhq/digger/cli/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyDecisionsAreReported(t *testing.T) {
+	var paths []string
+	var decisions []PolicyDecision
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		var decision PolicyDecision
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&decision))
+		paths = append(paths, r.URL.Path)
+		decisions = append(decisions, decision)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	writePolicy(t, dir, "plan.rego", denyAllPlanPolicy)
+	writePolicy(t, dir, "access.rego", onlyAlicePolicy)
+	checker := DiggerPolicyChecker{
+		PolicyProvider:   DiggerRepoPolicyProvider{Dir: dir},
+		DecisionReporter: BackendDecisionReporter{DiggerHost: server.URL, AuthToken: "token"},
+	}
+
+	allowed, _, err := checker.CheckPlanPolicy("repo", "org", "app", "app", createPlan)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	allowed, err = checker.CheckAccessPolicy(utils.MockPullRequestManager{Teams: []string{}}, nil, "org", "repo", "app", "app", "default", "mantis plan", nil, "alice", []string{}, nil)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	require.Len(t, decisions, 2)
+	assert.Equal(t, []string{"/repos/org-repo/projects/app/policy-decisions", "/repos/org-repo/projects/app/policy-decisions"}, paths)
+	assert.Equal(t, "plan", decisions[0].Type)
+	assert.False(t, decisions[0].Allowed)
+	assert.Equal(t, []string{"no changes allowed"}, decisions[0].Violations)
+	assert.Equal(t, hashContent([]byte(denyAllPlanPolicy)), decisions[0].PolicyHash)
+	assert.NotEmpty(t, decisions[0].InputHash)
+
+	assert.Equal(t, "access", decisions[1].Type)
+	assert.True(t, decisions[1].Allowed)
+	assert.Equal(t, "alice", decisions[1].RequestedBy)
+	assert.Equal(t, "mantis plan", decisions[1].Action)
+	assert.Equal(t, hashContent([]byte(onlyAlicePolicy)), decisions[1].PolicyHash)
+}
+
+type noAccessPolicyProvider struct {
+	DiggerDefaultPolicyProvider
+}
+
+func (s *noAccessPolicyProvider) GetAccessPolicy(organisation string, repository string, projectname string, projectDir string) (string, error) {
+	return "", nil
+}
+
+func TestAccessDecisionsWithoutPolicyAreReported(t *testing.T) {
+	var decisions []PolicyDecision
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var decision PolicyDecision
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&decision))
+		decisions = append(decisions, decision)
+	}))
+	defer server.Close()
+
+	checker := DiggerPolicyChecker{
+		PolicyProvider:   &noAccessPolicyProvider{},
+		DecisionReporter: BackendDecisionReporter{DiggerHost: server.URL, AuthToken: "token"},
+	}
+	allowed, err := checker.CheckAccessPolicy(utils.MockPullRequestManager{Teams: []string{}}, nil, "org", "repo", "app", "app", "default", "mantis apply", nil, "bob", []string{}, nil)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	require.Len(t, decisions, 1)
+	assert.Equal(t, "access", decisions[0].Type)
+	assert.True(t, decisions[0].Allowed)
+	assert.Equal(t, "bob", decisions[0].RequestedBy)
+}
+
+func TestPolicyDecisionReportingFailuresAreIgnored(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	writePolicy(t, dir, "plan.rego", denyAllPlanPolicy)
+	checker := DiggerPolicyChecker{
+		PolicyProvider:   DiggerRepoPolicyProvider{Dir: dir},
+		DecisionReporter: BackendDecisionReporter{DiggerHost: server.URL},
+	}
+	allowed, result, err := checker.CheckPlanPolicy("repo", "org", "app", "app", createPlan)
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, []string{"no changes allowed"}, result.DenyMessages())
+}
@@ -0,0 +1,74 @@
+package policy
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/diggerhq/digger/cli/pkg/core/policy"
+	"github.com/diggerhq/digger/libs/orchestrator"
+	"github.com/diggerhq/digger/libs/terraform_utils"
+)
+
+// LayeredPolicyChecker combines policy checkers, a deny from any of them wins
+type LayeredPolicyChecker struct {
+	Checkers []policy.Checker
+}
+
+func (l LayeredPolicyChecker) CheckAccessPolicy(ciService orchestrator.OrgService, prService *orchestrator.PullRequestService, SCMOrganisation string, SCMrepository string, projectName string, projectDir string, projectWorkspace string, command string, prNumber *int, requestedBy string, planPolicyViolations []string, planSummary *terraform_utils.PlanSummary) (bool, error) {
+	for _, checker := range l.Checkers {
+		allowed, err := checker.CheckAccessPolicy(ciService, prService, SCMOrganisation, SCMrepository, projectName, projectDir, projectWorkspace, command, prNumber, requestedBy, planPolicyViolations, planSummary)
+		if err != nil || !allowed {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func (l LayeredPolicyChecker) CheckPlanPolicy(SCMrepository string, SCMOrganisation string, projectname string, projectDir string, planOutput string) (bool, policy.PlanPolicyResult, error) {
+	allowed := true
+	result := policy.PlanPolicyResult{}
+	for _, checker := range l.Checkers {
+		checkerAllowed, checkerResult, err := checker.CheckPlanPolicy(SCMrepository, SCMOrganisation, projectname, projectDir, planOutput)
+		if err != nil {
+			return false, policy.PlanPolicyResult{}, err
+		}
+		allowed = allowed && checkerAllowed
+		result.Deny = append(result.Deny, checkerResult.Deny...)
+		result.Warn = append(result.Warn, checkerResult.Warn...)
+		result.Info = append(result.Info, checkerResult.Info...)
+	}
+	return allowed, result, nil
+}
+
+// CheckDriftPolicy enables drift only when every layer does, notify targets and ignored resources of all layers are
+// combined and the highest severity wins
+func (l LayeredPolicyChecker) CheckDriftPolicy(SCMOrganisation string, SCMrepository string, projectName string, planOutput string) (policy.DriftPolicyResult, error) {
+	result := policy.DriftPolicyResult{Enable: true}
+	for _, checker := range l.Checkers {
+		checkerResult, err := checker.CheckDriftPolicy(SCMOrganisation, SCMrepository, projectName, planOutput)
+		if err != nil {
+			return policy.DriftPolicyResult{}, err
+		}
+		result.Enable = result.Enable && checkerResult.Enable
+		result.Notify = appendMissing(result.Notify, checkerResult.Notify)
+		result.Ignore = appendMissing(result.Ignore, checkerResult.Ignore)
+		if severityRank(checkerResult.Severity) > severityRank(result.Severity) || result.Severity == "" {
+			result.Severity = checkerResult.Severity
+		}
+	}
+	return result, nil
+}
+
+// severityRank orders the common severities, unknown ones rank lowest
+func severityRank(severity string) int {
+	return slices.Index([]string{"info", "low", "medium", "high", "critical"}, strings.ToLower(severity))
+}
+
+func appendMissing(values []string, others []string) []string {
+	for _, value := range others {
+		if !slices.Contains(values, value) {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -1,6 +1,7 @@
 package policy
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -10,13 +11,18 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"sync"
+	"time"
 
 	"github.com/diggerhq/digger/cli/pkg/core/policy"
 	"github.com/diggerhq/digger/libs/orchestrator"
+	"github.com/diggerhq/digger/libs/terraform_utils"
 
 	// "github.com/diggerhq/digger/cli/pkg/core/policy/AccessPolicyContext"
 	// TODO fix imports - publish?
 	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/topdown"
+	"github.com/open-policy-agent/opa/topdown/lineage"
 )
 
 const DefaultAccessPolicy = `
@@ -35,16 +41,16 @@ type DiggerHttpPolicyProvider struct {
 type NoOpPolicyChecker struct {
 }
 
-func (p NoOpPolicyChecker) CheckAccessPolicy(ciService orchestrator.OrgService, prService *orchestrator.PullRequestService, SCMOrganisation string, SCMrepository string, projectName string, projectDir string, command string, prNumber *int, requestedBy string, planPolicyViolations []string) (bool, error) {
+func (p NoOpPolicyChecker) CheckAccessPolicy(ciService orchestrator.OrgService, prService *orchestrator.PullRequestService, SCMOrganisation string, SCMrepository string, projectName string, projectDir string, projectWorkspace string, command string, prNumber *int, requestedBy string, planPolicyViolations []string, planSummary *terraform_utils.PlanSummary) (bool, error) {
 	return true, nil
 }
 
-func (p NoOpPolicyChecker) CheckPlanPolicy(SCMrepository string, SCMOrganisation string, projectname string, projectDir string, planOutput string) (bool, []string, error) {
-	return true, nil, nil
+func (p NoOpPolicyChecker) CheckPlanPolicy(SCMrepository string, SCMOrganisation string, projectname string, projectDir string, planOutput string) (bool, policy.PlanPolicyResult, error) {
+	return true, policy.PlanPolicyResult{}, nil
 }
 
-func (p NoOpPolicyChecker) CheckDriftPolicy(SCMOrganisation string, SCMrepository string, projectname string) (bool, error) {
-	return true, nil
+func (p NoOpPolicyChecker) CheckDriftPolicy(SCMOrganisation string, SCMrepository string, projectname string, planOutput string) (policy.DriftPolicyResult, error) {
+	return policy.DriftPolicyResult{Enable: true}, nil
 }
 
 func getAccessPolicyForOrganisation(p *DiggerHttpPolicyProvider) (string, *http.Response, error) {
@@ -261,57 +267,176 @@ func (p DiggerHttpPolicyProvider) GetOrganisation() string {
 
 type DiggerPolicyChecker struct {
 	PolicyProvider policy.Provider
+	// DecisionReporter records every evaluated policy when set
+	DecisionReporter DecisionReporter
 }
 
-// TODO refactor to use AccessPolicyContext - too many arguments
-func (p DiggerPolicyChecker) CheckAccessPolicy(ciService orchestrator.OrgService, prService *orchestrator.PullRequestService, SCMOrganisation string, SCMrepository string, projectName string, projectDir string, command string, prNumber *int, requestedBy string, planPolicyViolations []string) (bool, error) {
+// AccessPolicyInput is what access policies are evaluated against
+type AccessPolicyInput struct {
+	User                 string
+	Organisation         string
+	Teams                []string
+	Approvals            []string
+	PlanPolicyViolations []string
+	Action               string
+	Project              string
+	ProjectDir           string
+	Workspace            string
+	// pull request the command runs on, empty for commands that do not run on a pull request
+	ChangedFiles []string
+	Labels       []string
+	Author       string
+	BaseBranch   string
+	HeadBranch   string
+	Draft        bool
+	Time         time.Time
+	// PlanSummary is the summary of the stored plan an apply runs, nil for other commands
+	PlanSummary *terraform_utils.PlanSummary
+}
 
-	policy, err := p.PolicyProvider.GetAccessPolicy(SCMOrganisation, SCMrepository, projectName, projectDir)
+func (i AccessPolicyInput) ToMap() map[string]interface{} {
+	input := map[string]interface{}{
+		"user":                 i.User,
+		"organisation":         i.Organisation,
+		"teams":                nonNil(i.Teams),
+		"approvals":            nonNil(i.Approvals),
+		"planPolicyViolations": nonNil(i.PlanPolicyViolations),
+		"action":               i.Action,
+		"project":              i.Project,
+		"projectDir":           i.ProjectDir,
+		"workspace":            i.Workspace,
+		"changedFiles":         nonNil(i.ChangedFiles),
+		"labels":               nonNil(i.Labels),
+		"author":               i.Author,
+		"baseBranch":           i.BaseBranch,
+		"headBranch":           i.HeadBranch,
+		"draft":                i.Draft,
+		"time":                 timeInput(i.Time),
+	}
+	if i.PlanSummary != nil {
+		input["planSummary"] = map[string]interface{}{
+			"created": int(i.PlanSummary.ResourcesCreated),
+			"updated": int(i.PlanSummary.ResourcesUpdated),
+			"deleted": int(i.PlanSummary.ResourcesDeleted),
+		}
+		input["destroyCount"] = int(i.PlanSummary.ResourcesDeleted)
+	}
+	return input
+}
 
-	if err != nil {
-		log.Printf("Error while fetching policy: %v", err)
-		return false, err
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
 	}
+	return values
+}
 
-	teams, err := ciService.GetUserTeams(SCMOrganisation, requestedBy)
+// PlanPolicyInput is the input plan policies are evaluated against, planOutput is the output of terraform show -json
+func PlanPolicyInput(planOutput string) (map[string]interface{}, error) {
+	var parsedPlanOutput map[string]interface{}
+	err := json.Unmarshal([]byte(planOutput), &parsedPlanOutput)
 	if err != nil {
-		log.Printf("Error while fetching user teams for CI service: %v", err)
-		log.Printf("WARNING: teams failed to be fetched, passing an empty list instead for access policy checks\n")
-		teams = []string{}
+		return nil, fmt.Errorf("failed to parse json terraform output to map: %v", err)
 	}
+	return map[string]interface{}{
+		"terraform": parsedPlanOutput,
+	}, nil
+}
 
-	// list of pull request approvals (if applicable)
-	var approvals = make([]string, 0)
-	if prService != nil && prNumber != nil {
-		approvals, err = (*prService).GetApprovals(*prNumber)
+func timeInput(t time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"timestamp": t.Format(time.RFC3339),
+		"weekday":   t.Weekday().String(),
+		"hour":      t.Hour(),
+		"minute":    t.Minute(),
 	}
+}
 
+// DriftPolicyInput is the input drift policies are evaluated against. planOutput is the output of terraform show -json
+// of the drift plan, drift.resources lists the resources it changes
+func DriftPolicyInput(organisation string, repository string, projectName string, planOutput string, now time.Time) (map[string]interface{}, error) {
 	input := map[string]interface{}{
-		"user":                 requestedBy,
-		"organisation":         SCMOrganisation,
-		"teams":                teams,
-		"approvals":            approvals,
-		"planPolicyViolations": planPolicyViolations,
-		"action":               command,
-		"project":              projectName,
+		"organisation": organisation,
+		"repository":   repository,
+		"project":      projectName,
+		"time":         timeInput(now),
 	}
+	resources := make([]interface{}, 0)
+	summary := terraform_utils.PlanSummary{}
+	if planOutput != "" {
+		planInput, err := PlanPolicyInput(planOutput)
+		if err != nil {
+			return nil, err
+		}
+		input["terraform"] = planInput["terraform"]
 
-	if policy == "" {
-		return true, nil
-	}
+		changes, err := terraform_utils.GetChangedResources(planOutput)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse drift plan: %v", err)
+		}
+		for _, change := range changes {
+			actions := make([]interface{}, 0, len(change.Change.Actions))
+			for _, action := range change.Change.Actions {
+				actions = append(actions, action)
+			}
+			resources = append(resources, map[string]interface{}{
+				"address": change.Address,
+				"type":    change.Type,
+				"actions": actions,
+			})
+		}
+		_, planSummary, err := terraform_utils.GetPlanSummary(planOutput)
+		if err != nil {
+			return nil, fmt.Errorf("failed to summarise drift plan: %v", err)
+		}
+		summary = *planSummary
+	}
+	input["drift"] = map[string]interface{}{
+		"detected":  len(resources) > 0,
+		"resources": resources,
+		"summary": map[string]interface{}{
+			"created": int(summary.ResourcesCreated),
+			"updated": int(summary.ResourcesUpdated),
+			"deleted": int(summary.ResourcesDeleted),
+		},
+	}
+	return input, nil
+}
 
-	ctx := context.Background()
-	log.Printf("DEBUG: passing the following input policy: %v ||| text: %v", input, policy)
-	query, err := rego.New(
-		rego.Query("data.digger.allow"),
+type preparedQueryKey struct {
+	query  string
+	policy string
+}
+
+// preparedQueries caches compiled queries, projects sharing a policy only compile it once per run
+var preparedQueries sync.Map
+
+func prepareQuery(ctx context.Context, query string, policy string) (rego.PreparedEvalQuery, error) {
+	key := preparedQueryKey{query: query, policy: policy}
+	if cached, ok := preparedQueries.Load(key); ok {
+		return cached.(rego.PreparedEvalQuery), nil
+	}
+	preparedQuery, err := rego.New(
+		rego.Query(query),
 		rego.Module("digger", policy),
 	).PrepareForEval(ctx)
+	if err != nil {
+		return rego.PreparedEvalQuery{}, err
+	}
+	preparedQueries.Store(key, preparedQuery)
+	return preparedQuery, nil
+}
+
+// evaluateBooleanPolicy evaluates a query that every expression of must be true
+func evaluateBooleanPolicy(policy string, query string, input map[string]interface{}) (bool, error) {
+	ctx := context.Background()
+	preparedQuery, err := prepareQuery(ctx, query, policy)
 
 	if err != nil {
 		return false, err
 	}
 
-	results, err := query.Eval(ctx, rego.EvalInput(input))
+	results, err := preparedQuery.Eval(ctx, rego.EvalInput(input))
 	if len(results) == 0 || len(results[0].Expressions) == 0 {
 		return false, fmt.Errorf("no result found")
 	}
@@ -331,131 +456,359 @@ func (p DiggerPolicyChecker) CheckAccessPolicy(ciService orchestrator.OrgService
 	return true, nil
 }
 
-func (p DiggerPolicyChecker) CheckPlanPolicy(SCMrepository string, SCMOrganisation string, projectname string, projectDir string, planOutput string) (bool, []string, error) {
-	policy, err := p.PolicyProvider.GetPlanPolicy(SCMOrganisation, SCMrepository, projectname, projectDir)
+// EvaluateAccessPolicy evaluates data.digger.allow
+func EvaluateAccessPolicy(policy string, input map[string]interface{}) (bool, error) {
+	return evaluateBooleanPolicy(policy, "data.digger.allow", input)
+}
+
+// EvaluatePlanPolicy evaluates the deny, warn and info rules of a plan policy
+func EvaluatePlanPolicy(planPolicy string, input map[string]interface{}) (policy.PlanPolicyResult, error) {
+	ctx := context.Background()
+	query, err := prepareQuery(ctx, "data.digger", planPolicy)
+
 	if err != nil {
-		return false, nil, fmt.Errorf("failed get plan policy: %v", err)
+		return policy.PlanPolicyResult{}, err
 	}
-	var parsedPlanOutput map[string]interface{}
 
-	err = json.Unmarshal([]byte(planOutput), &parsedPlanOutput)
+	results, err := query.Eval(ctx, rego.EvalInput(input))
 	if err != nil {
-		return false, nil, fmt.Errorf("failed to parse json terraform output to map: %v", err)
+		return policy.PlanPolicyResult{}, err
+	}
+	if len(results) == 0 || len(results[0].Expressions) == 0 {
+		return policy.PlanPolicyResult{}, fmt.Errorf("no result found")
+	}
+	rules, ok := results[0].Expressions[0].Value.(map[string]interface{})
+	if !ok {
+		return policy.PlanPolicyResult{}, fmt.Errorf("package digger did not evaluate to an object")
+	}
+	_, hasDeny := rules["deny"]
+	_, hasWarn := rules["warn"]
+	_, hasInfo := rules["info"]
+	if !hasDeny && !hasWarn && !hasInfo {
+		return policy.PlanPolicyResult{}, fmt.Errorf("no result found, plan policies must define deny, warn or info rules")
 	}
 
-	input := map[string]interface{}{
-		"terraform": parsedPlanOutput,
+	result := policy.PlanPolicyResult{}
+	result.Deny, err = parseViolations(rules["deny"])
+	if err != nil {
+		return policy.PlanPolicyResult{}, fmt.Errorf("invalid deny result: %v", err)
+	}
+	result.Warn, err = parseViolations(rules["warn"])
+	if err != nil {
+		return policy.PlanPolicyResult{}, fmt.Errorf("invalid warn result: %v", err)
 	}
+	result.Info, err = parseViolations(rules["info"])
+	if err != nil {
+		return policy.PlanPolicyResult{}, fmt.Errorf("invalid info result: %v", err)
+	}
+	for _, violation := range result.Deny {
+		log.Printf("denied: %v\n", violation)
+	}
+	return result, nil
+}
 
-	if policy == "" {
-		log.Printf("No plan policies found, succeeding")
-		return true, nil, nil
+func parseViolations(value interface{}) ([]policy.Violation, error) {
+	violations := make([]policy.Violation, 0)
+	if value == nil {
+		return violations, nil
+	}
+	decisions, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("decision is not a slice of interfaces")
+	}
+	for _, decision := range decisions {
+		switch d := decision.(type) {
+		case string:
+			violations = append(violations, policy.Violation{Message: d})
+		case map[string]interface{}:
+			encoded, err := json.Marshal(d)
+			if err != nil {
+				return nil, err
+			}
+			var violation policy.Violation
+			err = json.Unmarshal(encoded, &violation)
+			if err != nil {
+				return nil, fmt.Errorf("could not parse %v: %v", d, err)
+			}
+			violations = append(violations, violation)
+		default:
+			return nil, fmt.Errorf("decision %v is neither a message nor an object", decision)
+		}
 	}
+	return violations, nil
+}
 
-	ctx := context.Background()
-	log.Printf("DEBUG: passing the following input policy: %v", policy)
-	query, err := rego.New(
+// ExplainPlanPolicy traces the evaluation of data.digger.deny, mode is one of notes, fails or full like opa eval --explain
+func ExplainPlanPolicy(planPolicy string, input map[string]interface{}, mode string) (string, error) {
+	var filter func([]*topdown.Event) []*topdown.Event
+	switch mode {
+	case "notes":
+		filter = lineage.Notes
+	case "fails":
+		filter = lineage.Fails
+	case "full":
+		filter = lineage.Full
+	default:
+		return "", fmt.Errorf("unknown explain mode %q, expected notes, fails or full", mode)
+	}
+
+	tracer := topdown.NewBufferTracer()
+	_, err := rego.New(
 		rego.Query("data.digger.deny"),
-		rego.Module("digger", policy),
-	).PrepareForEval(ctx)
+		rego.Module("digger", planPolicy),
+		rego.Input(input),
+		rego.QueryTracer(tracer),
+	).Eval(context.Background())
+	if err != nil {
+		return "", err
+	}
 
+	var explanation bytes.Buffer
+	topdown.PrettyTraceWithLocation(&explanation, filter(*tracer))
+	return explanation.String(), nil
+}
+
+// EvaluateDriftPolicy evaluates the enable, notify, severity and ignore rules of a drift policy, drift is enabled
+// unless the policy says otherwise
+func EvaluateDriftPolicy(driftPolicy string, input map[string]interface{}) (policy.DriftPolicyResult, error) {
+	ctx := context.Background()
+	query, err := prepareQuery(ctx, "data.digger", driftPolicy)
 	if err != nil {
-		return false, nil, err
+		return policy.DriftPolicyResult{}, err
 	}
 
 	results, err := query.Eval(ctx, rego.EvalInput(input))
+	if err != nil {
+		return policy.DriftPolicyResult{}, err
+	}
 	if len(results) == 0 || len(results[0].Expressions) == 0 {
-		return false, nil, fmt.Errorf("no result found")
+		return policy.DriftPolicyResult{}, fmt.Errorf("no result found")
+	}
+	rules, ok := results[0].Expressions[0].Value.(map[string]interface{})
+	if !ok {
+		return policy.DriftPolicyResult{}, fmt.Errorf("package digger did not evaluate to an object")
+	}
+	_, hasEnable := rules["enable"]
+	_, hasNotify := rules["notify"]
+	_, hasSeverity := rules["severity"]
+	_, hasIgnore := rules["ignore"]
+	if !hasEnable && !hasNotify && !hasSeverity && !hasIgnore {
+		return policy.DriftPolicyResult{}, fmt.Errorf("no result found, drift policies must define enable, notify, severity or ignore rules")
 	}
 
-	expressions := results[0].Expressions
-
-	decisionsResult := make([]string, 0)
-	for _, expression := range expressions {
-		decisions, ok := expression.Value.([]interface{})
-
+	result := policy.DriftPolicyResult{Enable: true}
+	if hasEnable {
+		result.Enable, ok = rules["enable"].(bool)
 		if !ok {
-			return false, nil, fmt.Errorf("decision is not a slice of interfaces")
+			return policy.DriftPolicyResult{}, fmt.Errorf("enable is not a boolean")
 		}
-		if len(decisions) > 0 {
-			for _, d := range decisions {
-				decisionsResult = append(decisionsResult, d.(string))
-				log.Printf("denied: %v\n", d)
-			}
+	}
+	if hasSeverity {
+		result.Severity, ok = rules["severity"].(string)
+		if !ok {
+			return policy.DriftPolicyResult{}, fmt.Errorf("severity is not a string")
+		}
+	}
+	result.Notify, err = parseStrings(rules["notify"])
+	if err != nil {
+		return policy.DriftPolicyResult{}, fmt.Errorf("invalid notify result: %v", err)
+	}
+	result.Ignore, err = parseStrings(rules["ignore"])
+	if err != nil {
+		return policy.DriftPolicyResult{}, fmt.Errorf("invalid ignore result: %v", err)
+	}
+	return result, nil
+}
 
+// parseStrings reads a rule that is either a single string or a set of strings
+func parseStrings(value interface{}) ([]string, error) {
+	values := make([]string, 0)
+	switch v := value.(type) {
+	case nil:
+	case string:
+		values = append(values, v)
+	case []interface{}:
+		for _, item := range v {
+			str, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("%v is not a string", item)
+			}
+			values = append(values, str)
 		}
+	default:
+		return nil, fmt.Errorf("%v is neither a string nor a set of strings", value)
+	}
+	return values, nil
+}
+
+// TODO refactor to use AccessPolicyContext - too many arguments
+func (p DiggerPolicyChecker) CheckAccessPolicy(ciService orchestrator.OrgService, prService *orchestrator.PullRequestService, SCMOrganisation string, SCMrepository string, projectName string, projectDir string, projectWorkspace string, command string, prNumber *int, requestedBy string, planPolicyViolations []string, planSummary *terraform_utils.PlanSummary) (bool, error) {
 
+	policy, err := p.PolicyProvider.GetAccessPolicy(SCMOrganisation, SCMrepository, projectName, projectDir)
+
+	if err != nil {
+		log.Printf("Error while fetching policy: %v", err)
+		return false, err
 	}
 
-	if len(decisionsResult) > 0 {
-		return false, decisionsResult, nil
+	teams, err := ciService.GetUserTeams(SCMOrganisation, requestedBy)
+	if err != nil {
+		log.Printf("Error while fetching user teams for CI service: %v", err)
+		log.Printf("WARNING: teams failed to be fetched, passing an empty list instead for access policy checks\n")
+		teams = []string{}
 	}
 
-	return true, []string{}, nil
+	input := AccessPolicyInput{
+		User:                 requestedBy,
+		Organisation:         SCMOrganisation,
+		Teams:                teams,
+		PlanPolicyViolations: planPolicyViolations,
+		Action:               command,
+		Project:              projectName,
+		ProjectDir:           projectDir,
+		Workspace:            projectWorkspace,
+		Time:                 time.Now(),
+		PlanSummary:          planSummary,
+	}
+
+	// pull request details (if applicable)
+	if policy != "" && prService != nil && prNumber != nil {
+		addPullRequestInput(&input, *prService, *prNumber)
+	}
+	inputMap := input.ToMap()
+
+	// commands are allowed without an access policy, they are still reported so that the audit log is complete
+	allowed := true
+	if policy != "" {
+		log.Printf("DEBUG: passing the following input policy: %v ||| text: %v", input, policy)
+		allowed, err = EvaluateAccessPolicy(policy, inputMap)
+		if err != nil {
+			return false, err
+		}
+	}
+	p.reportDecision(PolicyDecision{
+		Organisation: SCMOrganisation,
+		Repo:         SCMrepository,
+		Project:      projectName,
+		Type:         "access",
+		Action:       command,
+		RequestedBy:  requestedBy,
+		Allowed:      allowed,
+		Violations:   planPolicyViolations,
+	}, policy, inputMap)
+	return allowed, nil
 }
 
-func (p DiggerPolicyChecker) CheckDriftPolicy(SCMOrganisation string, SCMrepository string, projectName string) (bool, error) {
-	// TODO: Get rid of organisation if its not needed
-	//organisation := p.PolicyProvider.GetOrganisation()
-	policy, err := p.PolicyProvider.GetDriftPolicy()
+// addPullRequestInput fetches what access policies know about a pull request, failures leave the fields empty
+func addPullRequestInput(input *AccessPolicyInput, prService orchestrator.PullRequestService, prNumber int) {
+	approvals, err := prService.GetApprovals(prNumber)
 	if err != nil {
-		log.Printf("Error while fetching drift policy: %v", err)
-		return false, err
+		log.Printf("WARNING: failed to fetch approvals of pull request %v for access policy checks: %v", prNumber, err)
 	}
+	input.Approvals = approvals
 
-	input := map[string]interface{}{
-		"organisation": SCMOrganisation,
-		"project":      projectName,
+	changedFiles, err := prService.GetChangedFiles(prNumber)
+	if err != nil {
+		log.Printf("WARNING: failed to fetch changed files of pull request %v for access policy checks: %v", prNumber, err)
 	}
+	input.ChangedFiles = changedFiles
 
-	if policy == "" {
-		return true, nil
+	labels, err := prService.GetLabels(prNumber)
+	if err != nil {
+		log.Printf("WARNING: failed to fetch labels of pull request %v for access policy checks: %v", prNumber, err)
 	}
+	input.Labels = labels
 
-	ctx := context.Background()
-	log.Printf("DEBUG: passing the following input policy: %v ||| text: %v", input, policy)
-	query, err := rego.New(
-		rego.Query("data.digger.enable"),
-		rego.Module("digger", policy),
-	).PrepareForEval(ctx)
+	details, err := prService.GetPullRequestDetails(prNumber)
+	if err != nil {
+		log.Printf("WARNING: failed to fetch details of pull request %v for access policy checks: %v", prNumber, err)
+		return
+	}
+	input.Author = details.Author
+	input.BaseBranch = details.BaseBranch
+	input.HeadBranch = details.HeadBranch
+	input.Draft = details.Draft
+}
 
+func (p DiggerPolicyChecker) CheckPlanPolicy(SCMrepository string, SCMOrganisation string, projectname string, projectDir string, planOutput string) (bool, policy.PlanPolicyResult, error) {
+	planPolicy, err := p.PolicyProvider.GetPlanPolicy(SCMOrganisation, SCMrepository, projectname, projectDir)
 	if err != nil {
-		return false, err
+		return false, policy.PlanPolicyResult{}, fmt.Errorf("failed get plan policy: %v", err)
 	}
 
-	results, err := query.Eval(ctx, rego.EvalInput(input))
-	if len(results) == 0 || len(results[0].Expressions) == 0 {
-		return false, fmt.Errorf("no result found")
+	input, err := PlanPolicyInput(planOutput)
+	if err != nil {
+		return false, policy.PlanPolicyResult{}, err
 	}
 
-	expressions := results[0].Expressions
+	if planPolicy == "" {
+		log.Printf("No plan policies found, succeeding")
+		return true, policy.PlanPolicyResult{}, nil
+	}
 
-	for _, expression := range expressions {
-		decision, ok := expression.Value.(bool)
-		if !ok {
-			return false, fmt.Errorf("decision is not a boolean")
-		}
-		if !decision {
-			return false, nil
+	log.Printf("DEBUG: passing the following input policy: %v", planPolicy)
+	result, err := EvaluatePlanPolicy(planPolicy, input)
+	if err != nil {
+		return false, policy.PlanPolicyResult{}, err
+	}
+	p.reportDecision(PolicyDecision{
+		Organisation: SCMOrganisation,
+		Repo:         SCMrepository,
+		Project:      projectname,
+		Type:         "plan",
+		Allowed:      len(result.Deny) == 0,
+		Violations:   result.DenyMessages(),
+	}, planPolicy, input)
+
+	if len(result.Deny) > 0 {
+		if mode := os.Getenv("DIGGER_POLICY_EXPLAIN"); mode != "" {
+			explanation, err := ExplainPlanPolicy(planPolicy, input, mode)
+			if err != nil {
+				log.Printf("Failed to explain plan policy decision: %v", err)
+			} else {
+				log.Printf("Plan policy explanation for project %v:\n%v", projectname, explanation)
+			}
 		}
+		return false, result, nil
 	}
 
-	return true, nil
+	return true, result, nil
 }
 
-func NewPolicyChecker(hostname string, organisationName string, authToken string) policy.Checker {
-	var policyChecker policy.Checker
-	if os.Getenv("NO_BACKEND") == "true" {
-		log.Println("WARNING: running in 'backendless' mode. Features that require backend will not be available.")
-		policyChecker = NoOpPolicyChecker{}
-	} else {
-		policyChecker = DiggerPolicyChecker{
-			PolicyProvider: &DiggerHttpPolicyProvider{
-				DiggerHost:         hostname,
-				DiggerOrganisation: organisationName,
-				AuthToken:          authToken,
-				HttpClient:         http.DefaultClient,
-			}}
+func (p DiggerPolicyChecker) CheckDriftPolicy(SCMOrganisation string, SCMrepository string, projectName string, planOutput string) (policy.DriftPolicyResult, error) {
+	// TODO: Get rid of organisation if its not needed
+	//organisation := p.PolicyProvider.GetOrganisation()
+	driftPolicy, err := p.PolicyProvider.GetDriftPolicy()
+	if err != nil {
+		log.Printf("Error while fetching drift policy: %v", err)
+		return policy.DriftPolicyResult{}, err
 	}
+
+	if driftPolicy == "" {
+		return policy.DriftPolicyResult{Enable: true}, nil
+	}
+
+	input, err := DriftPolicyInput(SCMOrganisation, SCMrepository, projectName, planOutput, time.Now())
+	if err != nil {
+		return policy.DriftPolicyResult{}, err
+	}
+
+	log.Printf("DEBUG: passing the following drift policy: %v", driftPolicy)
+	result, err := EvaluateDriftPolicy(driftPolicy, input)
+	if err != nil {
+		return policy.DriftPolicyResult{}, err
+	}
+	p.reportDecision(PolicyDecision{
+		Organisation: SCMOrganisation,
+		Repo:         SCMrepository,
+		Project:      projectName,
+		Type:         "drift",
+		Allowed:      result.Enable,
+	}, driftPolicy, input)
+	return result, nil
+}
+
+func NewPolicyChecker(hostname string, organisationName string, authToken string) policy.Checker {
+	policyChecker, _ := PolicyCheckerProviderBasic{}.Get(hostname, organisationName, authToken)
 	return policyChecker
 }
@@ -2,9 +2,13 @@ package policy
 
 import (
 	"testing"
+	"time"
 
 	"github.com/diggerhq/digger/cli/pkg/core/policy"
 	"github.com/diggerhq/digger/cli/pkg/utils"
+	"github.com/diggerhq/digger/libs/orchestrator"
+	"github.com/diggerhq/digger/libs/terraform_utils"
+	"github.com/stretchr/testify/assert"
 )
 
 type OpaExamplePolicyProvider struct {
@@ -223,7 +227,7 @@ func TestDiggerAccessPolicyChecker_Check(t *testing.T) {
 				PolicyProvider: tt.fields.PolicyProvider,
 			}
 			ciService := utils.MockPullRequestManager{Teams: []string{"engineering"}}
-			got, err := p.CheckAccessPolicy(ciService, nil, tt.organisation, tt.name, tt.name, "", tt.command, nil, tt.requestedBy, tt.planPolicyViolations)
+			got, err := p.CheckAccessPolicy(ciService, nil, tt.organisation, tt.name, tt.name, "", "default", tt.command, nil, tt.requestedBy, tt.planPolicyViolations, nil)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("DiggerPolicyChecker.CheckAccessPolicy() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -286,3 +290,182 @@ func TestDiggerPlanPolicyChecker_Check(t *testing.T) {
 		})
 	}
 }
+
+const structuredPlanPolicy = `package digger
+deny[{"msg": "resources must not be deleted", "rule_id": "NO_DELETE", "resource": resource.address, "remediation": "https://docs.example.com/no-delete"}] {
+  resource := input.terraform.resource_changes[_]
+  resource.change.actions[_] == "delete"
+}
+warn[sprintf("%v is created without tags", [resource.address])] {
+  resource := input.terraform.resource_changes[_]
+  resource.change.actions[_] == "create"
+}
+info["plan was checked"]
+`
+
+func TestPlanPolicyWarnAndInfo(t *testing.T) {
+	dir := t.TempDir()
+	writePolicy(t, dir, "plan.rego", structuredPlanPolicy)
+	checker := DiggerPolicyChecker{PolicyProvider: DiggerRepoPolicyProvider{Dir: dir}}
+
+	allowed, result, err := checker.CheckPlanPolicy("repo", "org", "dev", "dev", createPlan)
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+	assert.Empty(t, result.Deny)
+	assert.Equal(t, []policy.Violation{{Message: "null_resource.a is created without tags"}}, result.Warn)
+	assert.Equal(t, []policy.Violation{{Message: "plan was checked"}}, result.Info)
+
+	t.Setenv("DIGGER_POLICY_EXPLAIN", "full")
+	allowed, result, err = checker.CheckPlanPolicy("repo", "org", "dev", "dev", deletePlan)
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, []policy.Violation{{
+		Message:         "resources must not be deleted",
+		RuleId:          "NO_DELETE",
+		ResourceAddress: "null_resource.a",
+		Remediation:     "https://docs.example.com/no-delete",
+	}}, result.Deny)
+	assert.Equal(t, "[NO_DELETE] null_resource.a: resources must not be deleted (https://docs.example.com/no-delete)", result.Deny[0].String())
+	assert.Equal(t, []string{"resources must not be deleted"}, result.DenyMessages())
+}
+
+func TestExplainPlanPolicy(t *testing.T) {
+	input, err := PlanPolicyInput(deletePlan)
+	assert.NoError(t, err)
+
+	explanation, err := ExplainPlanPolicy(structuredPlanPolicy, input, "full")
+	assert.NoError(t, err)
+	assert.Contains(t, explanation, "data.digger.deny")
+
+	_, err = ExplainPlanPolicy(structuredPlanPolicy, input, "verbose")
+	assert.ErrorContains(t, err, "unknown explain mode")
+}
+
+const changeApprovedPolicy = `package digger
+default allow = false
+allow {
+  input.labels[_] == "change-approved"
+  input.author != input.user
+  input.baseBranch == "main"
+  not input.draft
+}
+`
+
+func TestAccessPolicyPullRequestInput(t *testing.T) {
+	dir := t.TempDir()
+	writePolicy(t, dir, "access.rego", changeApprovedPolicy)
+	checker := DiggerPolicyChecker{PolicyProvider: DiggerRepoPolicyProvider{Dir: dir}}
+	prNumber := 1
+
+	var prService orchestrator.PullRequestService = &utils.MockPullRequestManager{
+		Labels:  []string{"change-approved"},
+		Details: orchestrator.PullRequestDetails{Author: "alice", BaseBranch: "main", HeadBranch: "feature"},
+	}
+	allowed, err := checker.CheckAccessPolicy(utils.MockPullRequestManager{}, &prService, "org", "repo", "prod", "prod", "default", "mantis apply", &prNumber, "bob", []string{}, nil)
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+
+	prService = &utils.MockPullRequestManager{
+		Details: orchestrator.PullRequestDetails{Author: "alice", BaseBranch: "main", HeadBranch: "feature"},
+	}
+	allowed, err = checker.CheckAccessPolicy(utils.MockPullRequestManager{}, &prService, "org", "repo", "prod", "prod", "default", "mantis apply", &prNumber, "bob", []string{}, nil)
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+}
+
+func TestAccessPolicyInputPlanSummaryAndTime(t *testing.T) {
+	input := AccessPolicyInput{
+		User:        "alice",
+		Time:        time.Date(2024, 6, 14, 16, 30, 0, 0, time.UTC),
+		PlanSummary: &terraform_utils.PlanSummary{ResourcesCreated: 1, ResourcesDeleted: 2},
+	}
+	noFridayDestroys := `package digger
+default allow = true
+allow = false {
+  input.time.weekday == "Friday"
+  input.destroyCount > 0
+}
+`
+	allowed, err := EvaluateAccessPolicy(noFridayDestroys, input.ToMap())
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+
+	inputMap := input.ToMap()
+	assert.Equal(t, map[string]interface{}{"created": 1, "updated": 0, "deleted": 2}, inputMap["planSummary"])
+	assert.Equal(t, map[string]interface{}{"timestamp": "2024-06-14T16:30:00Z", "weekday": "Friday", "hour": 16, "minute": 30}, inputMap["time"])
+	assert.Equal(t, []string{}, inputMap["labels"])
+
+	input.Time = input.Time.Add(24 * time.Hour)
+	allowed, err = EvaluateAccessPolicy(noFridayDestroys, input.ToMap())
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+}
+
+const driftPlan = `{"resource_changes": [
+  {"address": "aws_iam_role.admin", "type": "aws_iam_role", "change": {"actions": ["update"]}},
+  {"address": "aws_autoscaling_group.web", "type": "aws_autoscaling_group", "change": {"actions": ["update"]}},
+  {"address": "null_resource.unchanged", "type": "null_resource", "change": {"actions": ["no-op"]}}
+]}`
+
+const iamDriftPolicy = `package digger
+enable = input.time.weekday != "Sunday"
+ignore["aws_autoscaling_group.*"]
+notify["@security"] {
+  input.drift.resources[_].type == "aws_iam_role"
+}
+severity = "critical" {
+  input.drift.resources[_].type == "aws_iam_role"
+} else = "low"
+`
+
+func TestDriftPolicyResult(t *testing.T) {
+	monday := time.Date(2024, 6, 10, 9, 0, 0, 0, time.UTC)
+	input, err := DriftPolicyInput("org", "repo", "prod", driftPlan, monday)
+	assert.NoError(t, err)
+	drift := input["drift"].(map[string]interface{})
+	assert.Equal(t, true, drift["detected"])
+	assert.Len(t, drift["resources"], 2)
+	assert.Equal(t, map[string]interface{}{"created": 0, "updated": 2, "deleted": 0}, drift["summary"])
+
+	result, err := EvaluateDriftPolicy(iamDriftPolicy, input)
+	assert.NoError(t, err)
+	assert.True(t, result.Enable)
+	assert.Equal(t, "critical", result.Severity)
+	assert.Equal(t, []string{"@security"}, result.Notify)
+	assert.True(t, result.IsIgnored("aws_autoscaling_group.web"))
+	assert.False(t, result.IsIgnored("aws_iam_role.admin"))
+
+	sunday := time.Date(2024, 6, 9, 9, 0, 0, 0, time.UTC)
+	input, err = DriftPolicyInput("org", "repo", "prod", "", sunday)
+	assert.NoError(t, err)
+	result, err = EvaluateDriftPolicy(iamDriftPolicy, input)
+	assert.NoError(t, err)
+	assert.False(t, result.Enable)
+	assert.Equal(t, "low", result.Severity)
+	assert.Empty(t, result.Notify)
+
+	// policies that only decide enable keep working
+	result, err = EvaluateDriftPolicy("package digger\nenable = false\n", input)
+	assert.NoError(t, err)
+	assert.False(t, result.Enable)
+	_, err = EvaluateDriftPolicy("package digger\nallow = true\n", input)
+	assert.Error(t, err)
+}
+
+func TestLayeredDriftPolicy(t *testing.T) {
+	orgDir := t.TempDir()
+	writePolicy(t, orgDir, "drift.rego", "package digger\nseverity = \"high\"\nnotify = [\"@infra\"]\n")
+	repoDir := t.TempDir()
+	writePolicy(t, repoDir, "drift.rego", "package digger\nseverity = \"low\"\nnotify = [\"@team\"]\nignore = [\"aws_autoscaling_group.web\"]\n")
+
+	checker := LayeredPolicyChecker{Checkers: []policy.Checker{
+		DiggerPolicyChecker{PolicyProvider: DiggerRepoPolicyProvider{Dir: orgDir}},
+		DiggerPolicyChecker{PolicyProvider: DiggerRepoPolicyProvider{Dir: repoDir}},
+	}}
+	result, err := checker.CheckDriftPolicy("org", "repo", "prod", driftPlan)
+	assert.NoError(t, err)
+	assert.True(t, result.Enable)
+	assert.Equal(t, "high", result.Severity)
+	assert.Equal(t, []string{"@infra", "@team"}, result.Notify)
+	assert.Equal(t, []string{"aws_autoscaling_group.web"}, result.Ignore)
+}
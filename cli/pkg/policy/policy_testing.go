@@ -0,0 +1,301 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/diggerhq/digger/cli/pkg/core/policy"
+	"github.com/diggerhq/digger/libs/terraform_utils"
+	"github.com/open-policy-agent/opa/tester"
+	"gopkg.in/yaml.v3"
+)
+
+// PolicyTestFile holds fixtures policies are evaluated against by dgctl policy test
+type PolicyTestFile struct {
+	Tests []PolicyTestCase `yaml:"tests"`
+}
+
+type PolicyTestCase struct {
+	Name string `yaml:"name"`
+	// Policy is the kind of policy under test: access, plan or drift
+	Policy       string `yaml:"policy"`
+	Organisation string `yaml:"organisation"`
+	Repository   string `yaml:"repository"`
+	Project      string `yaml:"project"`
+	// ProjectDir selects the policy the same way projects do, PolicyFile evaluates a specific file instead
+	ProjectDir string `yaml:"project_dir"`
+	PolicyFile string `yaml:"policy_file"`
+	Workspace  string `yaml:"workspace"`
+	// access policy fixture
+	User                 string                 `yaml:"user"`
+	Teams                []string               `yaml:"teams"`
+	Approvals            []string               `yaml:"approvals"`
+	Action               string                 `yaml:"action"`
+	PlanPolicyViolations []string               `yaml:"plan_policy_violations"`
+	ChangedFiles         []string               `yaml:"changed_files"`
+	Labels               []string               `yaml:"labels"`
+	Author               string                 `yaml:"author"`
+	BaseBranch           string                 `yaml:"base_branch"`
+	HeadBranch           string                 `yaml:"head_branch"`
+	Draft                bool                   `yaml:"draft"`
+	Time                 time.Time              `yaml:"time"`
+	PlanSummary          *PolicyTestPlanSummary `yaml:"plan_summary"`
+	// plan and drift policy fixture, the output of terraform show -json relative to the test file
+	PlanFile string             `yaml:"plan_file"`
+	Expect   PolicyTestExpected `yaml:"expect"`
+}
+
+type PolicyTestPlanSummary struct {
+	Created uint `yaml:"created"`
+	Updated uint `yaml:"updated"`
+	Deleted uint `yaml:"deleted"`
+}
+
+type PolicyTestExpected struct {
+	Allow    *bool    `yaml:"allow"`
+	Deny     []string `yaml:"deny"`
+	Warn     []string `yaml:"warn"`
+	Enable   *bool    `yaml:"enable"`
+	Severity *string  `yaml:"severity"`
+	Notify   []string `yaml:"notify"`
+	Ignore   []string `yaml:"ignore"`
+}
+
+type PolicyTestResult struct {
+	Name string
+	// Failure describes why the test failed, it is empty for passing tests
+	Failure string
+}
+
+func LoadPolicyTestFile(path string) (*PolicyTestFile, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read test file %v: %v", path, err)
+	}
+	var testFile PolicyTestFile
+	err = yaml.Unmarshal(content, &testFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse test file %v: %v", path, err)
+	}
+	return &testFile, nil
+}
+
+// RunPolicyTests evaluates the tests of a test file against the policies in policyDir
+func RunPolicyTests(testFilePath string, policyDir string) ([]PolicyTestResult, error) {
+	testFile, err := LoadPolicyTestFile(testFilePath)
+	if err != nil {
+		return nil, err
+	}
+	provider := DiggerRepoPolicyProvider{Dir: policyDir}
+
+	var results []PolicyTestResult
+	for i, test := range testFile.Tests {
+		name := test.Name
+		if name == "" {
+			name = fmt.Sprintf("%v #%v", filepath.Base(testFilePath), i+1)
+		}
+		failure, err := runPolicyTest(test, provider, filepath.Dir(testFilePath))
+		if err != nil {
+			failure = err.Error()
+		}
+		results = append(results, PolicyTestResult{Name: name, Failure: failure})
+	}
+	return results, nil
+}
+
+func runPolicyTest(test PolicyTestCase, provider DiggerRepoPolicyProvider, baseDir string) (string, error) {
+	testedPolicy, err := testPolicy(test, provider, baseDir)
+	if err != nil {
+		return "", err
+	}
+
+	switch test.Policy {
+	case "access":
+		if test.Expect.Allow == nil {
+			return "", fmt.Errorf("access policy tests must set expect.allow")
+		}
+		input := AccessPolicyInput{
+			User:                 test.User,
+			Organisation:         test.Organisation,
+			Teams:                test.Teams,
+			Approvals:            test.Approvals,
+			PlanPolicyViolations: test.PlanPolicyViolations,
+			Action:               test.Action,
+			Project:              test.Project,
+			ProjectDir:           test.ProjectDir,
+			Workspace:            test.Workspace,
+			ChangedFiles:         test.ChangedFiles,
+			Labels:               test.Labels,
+			Author:               test.Author,
+			BaseBranch:           test.BaseBranch,
+			HeadBranch:           test.HeadBranch,
+			Draft:                test.Draft,
+			Time:                 test.Time,
+		}
+		if test.PlanSummary != nil {
+			input.PlanSummary = &terraform_utils.PlanSummary{
+				ResourcesCreated: test.PlanSummary.Created,
+				ResourcesUpdated: test.PlanSummary.Updated,
+				ResourcesDeleted: test.PlanSummary.Deleted,
+			}
+		}
+		allowed := true
+		if testedPolicy != "" {
+			allowed, err = EvaluateAccessPolicy(testedPolicy, input.ToMap())
+			if err != nil {
+				return "", err
+			}
+		}
+		if allowed != *test.Expect.Allow {
+			return fmt.Sprintf("expected allow to be %v, got %v", *test.Expect.Allow, allowed), nil
+		}
+	case "plan":
+		if test.PlanFile == "" {
+			return "", fmt.Errorf("plan policy tests must set plan_file")
+		}
+		planOutput, err := os.ReadFile(filepath.Join(baseDir, test.PlanFile))
+		if err != nil {
+			return "", fmt.Errorf("could not read plan file: %v", err)
+		}
+		input, err := PlanPolicyInput(string(planOutput))
+		if err != nil {
+			return "", err
+		}
+		result := policy.PlanPolicyResult{}
+		if testedPolicy != "" {
+			result, err = EvaluatePlanPolicy(testedPolicy, input)
+			if err != nil {
+				return "", err
+			}
+		}
+		if failure := compareMessages("deny", nonNil(test.Expect.Deny), result.Deny); failure != "" {
+			return failure, nil
+		}
+		// warnings are only asserted when the test lists them
+		if test.Expect.Warn != nil {
+			if failure := compareMessages("warn", test.Expect.Warn, result.Warn); failure != "" {
+				return failure, nil
+			}
+		}
+	case "drift":
+		if test.Expect.Enable == nil && test.Expect.Severity == nil && test.Expect.Notify == nil && test.Expect.Ignore == nil {
+			return "", fmt.Errorf("drift policy tests must set expect.enable, severity, notify or ignore")
+		}
+		planOutput := ""
+		if test.PlanFile != "" {
+			content, err := os.ReadFile(filepath.Join(baseDir, test.PlanFile))
+			if err != nil {
+				return "", fmt.Errorf("could not read plan file: %v", err)
+			}
+			planOutput = string(content)
+		}
+		input, err := DriftPolicyInput(test.Organisation, test.Repository, test.Project, planOutput, test.Time)
+		if err != nil {
+			return "", err
+		}
+		result := policy.DriftPolicyResult{Enable: true}
+		if testedPolicy != "" {
+			result, err = EvaluateDriftPolicy(testedPolicy, input)
+			if err != nil {
+				return "", err
+			}
+		}
+		if test.Expect.Enable != nil && result.Enable != *test.Expect.Enable {
+			return fmt.Sprintf("expected enable to be %v, got %v", *test.Expect.Enable, result.Enable), nil
+		}
+		if test.Expect.Severity != nil && result.Severity != *test.Expect.Severity {
+			return fmt.Sprintf("expected severity to be %q, got %q", *test.Expect.Severity, result.Severity), nil
+		}
+		if test.Expect.Notify != nil {
+			if failure := compareStrings("notify", test.Expect.Notify, result.Notify); failure != "" {
+				return failure, nil
+			}
+		}
+		if test.Expect.Ignore != nil {
+			if failure := compareStrings("ignore", test.Expect.Ignore, result.Ignore); failure != "" {
+				return failure, nil
+			}
+		}
+	default:
+		return "", fmt.Errorf("unknown policy %q, expected access, plan or drift", test.Policy)
+	}
+	return "", nil
+}
+
+func testPolicy(test PolicyTestCase, provider DiggerRepoPolicyProvider, baseDir string) (string, error) {
+	if test.PolicyFile != "" {
+		content, err := os.ReadFile(filepath.Join(baseDir, test.PolicyFile))
+		if err != nil {
+			return "", fmt.Errorf("could not read policy file: %v", err)
+		}
+		return string(content), nil
+	}
+	switch test.Policy {
+	case "access":
+		return provider.GetAccessPolicy(test.Organisation, test.Repository, test.Project, test.ProjectDir)
+	case "plan":
+		return provider.GetPlanPolicy(test.Organisation, test.Repository, test.Project, test.ProjectDir)
+	case "drift":
+		return provider.GetDriftPolicy()
+	}
+	return "", nil
+}
+
+func compareMessages(rule string, expected []string, violations []policy.Violation) string {
+	actual := make([]string, 0, len(violations))
+	for _, violation := range violations {
+		actual = append(actual, violation.Message)
+	}
+	return compareStrings(rule, expected, actual)
+}
+
+// compareStrings compares rule results regardless of their order
+func compareStrings(rule string, expected []string, actual []string) string {
+	expected = slices.Clone(expected)
+	actual = slices.Clone(actual)
+	slices.Sort(actual)
+	slices.Sort(expected)
+	if !slices.Equal(actual, expected) {
+		return fmt.Sprintf("expected %v to be %q, got %q", rule, expected, actual)
+	}
+	return ""
+}
+
+// RunRegoUnitTests runs the test_ rules of the *_test.rego files in policyDir. Every directory is tested on
+// its own since the policies of a directory override the ones of its parents
+func RunRegoUnitTests(policyDir string) ([]*tester.Result, error) {
+	filesByDir := map[string][]string{}
+	testDirs := []string{}
+	err := filepath.WalkDir(policyDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".rego" {
+			return nil
+		}
+		dir := filepath.Dir(path)
+		filesByDir[dir] = append(filesByDir[dir], path)
+		if strings.HasSuffix(path, "_test.rego") && !slices.Contains(testDirs, dir) {
+			testDirs = append(testDirs, dir)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not list policies in %v: %v", policyDir, err)
+	}
+
+	var results []*tester.Result
+	for _, dir := range testDirs {
+		dirResults, err := tester.Run(context.Background(), filesByDir[dir]...)
+		if err != nil {
+			return nil, fmt.Errorf("could not run rego tests in %v: %v", dir, err)
+		}
+		results = append(results, dirResults...)
+	}
+	return results, nil
+}
@@ -0,0 +1,101 @@
+package policy
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const noDeletionsPolicy = `package digger
+deny[sprintf("%v must not be deleted", [resource.address])] {
+  resource := input.terraform.resource_changes[_]
+  resource.change.actions[_] == "delete"
+}
+`
+
+const deletePlan = `{"resource_changes": [{"address": "null_resource.a", "change": {"actions": ["delete"]}}]}`
+
+func TestRunPolicyTests(t *testing.T) {
+	dir := t.TempDir()
+	writePolicy(t, dir, "access.rego", onlyAlicePolicy)
+	writePolicy(t, dir, "prod/plan.rego", noDeletionsPolicy)
+	writePolicy(t, dir, "tests/fixtures/create.json", createPlan)
+	writePolicy(t, dir, "tests/fixtures/delete.json", deletePlan)
+	writePolicy(t, dir, "tests/policies.yaml", `tests:
+  - name: alice can apply
+    policy: access
+    user: alice
+    action: mantis apply
+    expect:
+      allow: true
+  - name: bob can apply
+    policy: access
+    user: bob
+    action: mantis apply
+    expect:
+      allow: true
+  - name: deletions in prod are denied
+    policy: plan
+    project_dir: prod
+    plan_file: fixtures/delete.json
+    expect:
+      deny: ["null_resource.a must not be deleted"]
+  - name: creations in prod are allowed
+    policy: plan
+    project_dir: prod
+    plan_file: fixtures/create.json
+    expect:
+      deny: []
+  - policy: drift
+    expect:
+      enable: true
+  - name: missing expectation
+    policy: access
+`)
+
+	results, err := RunPolicyTests(filepath.Join(dir, "tests/policies.yaml"), dir)
+	assert.NoError(t, err)
+	assert.Len(t, results, 6)
+	assert.Equal(t, PolicyTestResult{Name: "alice can apply"}, results[0])
+	assert.Equal(t, PolicyTestResult{Name: "bob can apply", Failure: "expected allow to be true, got false"}, results[1])
+	assert.Equal(t, "", results[2].Failure)
+	assert.Equal(t, "", results[3].Failure)
+	assert.Equal(t, PolicyTestResult{Name: "policies.yaml #5"}, results[4])
+	assert.Equal(t, "access policy tests must set expect.allow", results[5].Failure)
+}
+
+func TestRunRegoUnitTests(t *testing.T) {
+	dir := t.TempDir()
+	writePolicy(t, dir, "access.rego", onlyAlicePolicy)
+	writePolicy(t, dir, "access_test.rego", `package digger
+test_alice_allowed {
+  allow with input as {"user": "alice"}
+}
+test_bob_allowed {
+  allow with input as {"user": "bob"}
+}
+`)
+	// policies of project directories define the same rules and are tested separately
+	writePolicy(t, dir, "prod/access.rego", DefaultAccessPolicy)
+	writePolicy(t, dir, "prod/access_test.rego", `package digger
+test_bob_allowed {
+  allow with input as {"user": "bob", "planPolicyViolations": []}
+}
+`)
+
+	results, err := RunRegoUnitTests(dir)
+	assert.NoError(t, err)
+	failed := map[string]bool{}
+	for _, result := range results {
+		assert.NoError(t, result.Error)
+		file, err := filepath.Rel(dir, result.Location.File)
+		assert.NoError(t, err)
+		failed[file+":"+result.Name] = result.Fail
+	}
+	assert.Equal(t, map[string]bool{
+		"access_test.rego:test_alice_allowed":    false,
+		"access_test.rego:test_bob_allowed":      true,
+		"prod/access_test.rego:test_bob_allowed": false,
+	}, failed)
+}
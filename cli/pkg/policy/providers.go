@@ -9,19 +9,66 @@ import (
 
 type PolicyCheckerProviderBasic struct{}
 
+// Get layers backend policies, or the policy bundle configured with DIGGER_POLICY_BUNDLE instead, with policies
+// committed to the repository when they are enabled, see RepoPoliciesEnabled. DIGGER_POLICY_SOURCE set to backend, bundle or repo restricts the checks to one of them.
+// Decisions are reported to the backend unless DIGGER_POLICY_AUDIT is false
 func (p PolicyCheckerProviderBasic) Get(hostname string, organisationName string, authToken string) (core_policy.Checker, error) {
-	var policyChecker core_policy.Checker
-	if os.Getenv("NO_BACKEND") == "true" {
-		log.Println("WARNING: running in 'backendless' mode. No policies will be supported.")
-		policyChecker = NoOpPolicyChecker{}
-	} else {
-		policyChecker = DiggerPolicyChecker{
+	source := os.Getenv("DIGGER_POLICY_SOURCE")
+	var checkers []core_policy.Checker
+
+	bundleProvider, err := NewBundlePolicyProviderFromEnv(organisationName)
+	if err != nil {
+		return nil, err
+	}
+
+	if bundleProvider != nil && source != "repo" && source != "backend" {
+		log.Printf("Using policies from bundle %v", bundleProvider.Source)
+		if hostname != "" && os.Getenv("NO_BACKEND") != "true" {
+			log.Printf("WARNING: policies of the backend are not checked since DIGGER_POLICY_BUNDLE is set, set DIGGER_POLICY_SOURCE=backend to use them instead")
+		}
+		checkers = append(checkers, DiggerPolicyChecker{PolicyProvider: bundleProvider})
+	} else if os.Getenv("NO_BACKEND") == "true" {
+		log.Println("WARNING: running in 'backendless' mode. Only policies in the repository will be supported, they are enabled with DIGGER_REPO_POLICIES=true.")
+	} else if source != "repo" {
+		checkers = append(checkers, DiggerPolicyChecker{
 			PolicyProvider: &DiggerHttpPolicyProvider{
 				DiggerHost:         hostname,
 				DiggerOrganisation: organisationName,
 				AuthToken:          authToken,
 				HttpClient:         http.DefaultClient,
-			}}
+			}})
+	}
+
+	if source != "backend" && source != "bundle" {
+		repoPolicyDir, err := RepoPolicyDir()
+		if err != nil {
+			return nil, err
+		}
+		if repoPolicyDir != "" {
+			log.Printf("Using policies from the %v directory of the repository", repoPolicyDir)
+			checkers = append(checkers, NewRepoPolicyChecker(repoPolicyDir, organisationName))
+		}
+	}
+
+	if hostname != "" && os.Getenv("NO_BACKEND") != "true" && os.Getenv("DIGGER_POLICY_AUDIT") != "false" {
+		reporter := BackendDecisionReporter{DiggerHost: hostname, AuthToken: authToken, HttpClient: http.DefaultClient}
+		for i, checker := range checkers {
+			switch c := checker.(type) {
+			case DiggerPolicyChecker:
+				c.DecisionReporter = reporter
+				checkers[i] = c
+			case RepoPolicyChecker:
+				c.DecisionReporter = reporter
+				checkers[i] = c
+			}
+		}
+	}
+
+	switch len(checkers) {
+	case 0:
+		return NoOpPolicyChecker{}, nil
+	case 1:
+		return checkers[0], nil
 	}
-	return policyChecker, nil
+	return LayeredPolicyChecker{Checkers: checkers}, nil
 }
@@ -0,0 +1,182 @@
+package policy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/diggerhq/digger/libs/orchestrator"
+	"github.com/diggerhq/digger/libs/terraform_utils"
+)
+
+const DefaultRepoPolicyDir = "policies"
+
+const (
+	accessPolicyFile = "access.rego"
+	planPolicyFile   = "plan.rego"
+	driftPolicyFile  = "drift.rego"
+)
+
+// DiggerRepoPolicyProvider reads policies committed to the repository. A policy in a subdirectory of Dir
+// matching a project's directory overrides the policies of its parent directories, e.g. for a project in
+// prod/app policies/prod/app/plan.rego takes precedence over policies/prod/plan.rego and policies/plan.rego
+type DiggerRepoPolicyProvider struct {
+	Dir                string
+	DiggerOrganisation string
+}
+
+// findPolicy returns the policy closest to projectDir, an empty string if there is none
+func (p DiggerRepoPolicyProvider) findPolicy(projectDir string, fileName string) (string, error) {
+	return findClosestPolicy(projectDir, fileName, func(path string) (string, bool, error) {
+		content, err := os.ReadFile(filepath.Join(p.Dir, path))
+		if err == nil {
+			return string(content), true, nil
+		}
+		if os.IsNotExist(err) {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("could not read policy %v: %v", filepath.Join(p.Dir, path), err)
+	})
+}
+
+// findClosestPolicy walks from projectDir up to the root of a policy tree and returns the first fileName read finds
+func findClosestPolicy(projectDir string, fileName string, read func(path string) (string, bool, error)) (string, error) {
+	dir := filepath.Clean(projectDir)
+	if !filepath.IsLocal(dir) {
+		dir = "."
+	}
+	for {
+		content, found, err := read(filepath.Join(dir, fileName))
+		if err != nil {
+			return "", err
+		}
+		if found {
+			return content, nil
+		}
+		if dir == "." {
+			return "", nil
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+func (p DiggerRepoPolicyProvider) GetAccessPolicy(organisation string, repo string, projectName string, projectDir string) (string, error) {
+	content, err := p.findPolicy(projectDir, accessPolicyFile)
+	if err != nil {
+		return "", err
+	}
+	if content == "" {
+		return DefaultAccessPolicy, nil
+	}
+	return content, nil
+}
+
+func (p DiggerRepoPolicyProvider) GetPlanPolicy(organisation string, repo string, projectName string, projectDir string) (string, error) {
+	return p.findPolicy(projectDir, planPolicyFile)
+}
+
+func (p DiggerRepoPolicyProvider) GetDriftPolicy() (string, error) {
+	return p.findPolicy(".", driftPolicyFile)
+}
+
+func (p DiggerRepoPolicyProvider) GetOrganisation() string {
+	return p.DiggerOrganisation
+}
+
+// RepoPolicyChecker checks the policies committed to the repository. They are read from the checked out pull
+// request, so commands of pull requests changing them are refused, otherwise a pull request could approve itself
+type RepoPolicyChecker struct {
+	DiggerPolicyChecker
+	Dir string
+}
+
+func NewRepoPolicyChecker(dir string, organisation string) RepoPolicyChecker {
+	return RepoPolicyChecker{
+		DiggerPolicyChecker: DiggerPolicyChecker{
+			PolicyProvider: DiggerRepoPolicyProvider{
+				Dir:                dir,
+				DiggerOrganisation: organisation,
+			}},
+		Dir: dir,
+	}
+}
+
+func (c RepoPolicyChecker) CheckAccessPolicy(ciService orchestrator.OrgService, prService *orchestrator.PullRequestService, SCMOrganisation string, SCMrepository string, projectName string, projectDir string, projectWorkspace string, command string, prNumber *int, requestedBy string, planPolicyViolations []string, planSummary *terraform_utils.PlanSummary) (bool, error) {
+	if prService != nil && prNumber != nil {
+		changedPolicy, err := c.changedPolicy(*prService, *prNumber)
+		if err != nil {
+			return false, err
+		}
+		if changedPolicy != "" {
+			return false, fmt.Errorf("pull request #%v changes policy %v, policies in the repository have to be changed in a separate pull request", *prNumber, changedPolicy)
+		}
+	}
+	return c.DiggerPolicyChecker.CheckAccessPolicy(ciService, prService, SCMOrganisation, SCMrepository, projectName, projectDir, projectWorkspace, command, prNumber, requestedBy, planPolicyViolations, planSummary)
+}
+
+// changedPolicy returns the first policy of the policy directory changed by the pull request, an empty string if there
+// is none. Only .rego files are policies, other files like READMEs can be changed along with the code
+func (c RepoPolicyChecker) changedPolicy(prService orchestrator.PullRequestService, prNumber int) (string, error) {
+	dir := filepath.Clean(c.Dir)
+	if filepath.IsAbs(dir) {
+		wd, err := os.Getwd()
+		if err != nil {
+			return "", fmt.Errorf("could not get working directory: %v", err)
+		}
+		dir, err = filepath.Rel(wd, dir)
+		if err != nil || !filepath.IsLocal(dir) {
+			// the policies are not part of the repository
+			return "", nil
+		}
+	}
+	changedFiles, err := prService.GetChangedFiles(prNumber)
+	if err != nil {
+		return "", fmt.Errorf("could not check whether pull request #%v changes policies: %v", prNumber, err)
+	}
+	for _, file := range changedFiles {
+		if filepath.Ext(file) != ".rego" {
+			continue
+		}
+		if dir == "." || strings.HasPrefix(filepath.Clean(file), dir+string(filepath.Separator)) {
+			return file, nil
+		}
+	}
+	return "", nil
+}
+
+// RepoPoliciesEnabled reports whether policies committed to the repository are checked. They are opt-in, with
+// DIGGER_REPO_POLICIES set to true, DIGGER_POLICY_SOURCE set to repo or a DIGGER_POLICY_DIR
+func RepoPoliciesEnabled() bool {
+	return os.Getenv("DIGGER_REPO_POLICIES") == "true" || os.Getenv("DIGGER_POLICY_SOURCE") == "repo" || os.Getenv("DIGGER_POLICY_DIR") != ""
+}
+
+// RepoPolicyDir returns the policies directory of the repository checked out in the working directory,
+// DIGGER_POLICY_DIR overrides the default location. It is empty when repo policies are not enabled, and an error
+// when they are but the directory does not exist, so that a misplaced directory does not allow everything
+func RepoPolicyDir() (string, error) {
+	if !RepoPoliciesEnabled() {
+		return "", nil
+	}
+	dir := os.Getenv("DIGGER_POLICY_DIR")
+	if dir == "" {
+		dir = DefaultRepoPolicyDir
+	}
+	err := CheckRepoPolicyDir(dir)
+	if err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// CheckRepoPolicyDir returns an error when dir is not a directory
+func CheckRepoPolicyDir(dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("repository policies are enabled but the policy directory %v can't be read: %v", dir, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("repository policies are enabled but %v is not a directory", dir)
+	}
+	return nil
+}
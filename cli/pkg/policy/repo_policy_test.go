@@ -0,0 +1,191 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/diggerhq/digger/cli/pkg/core/policy"
+	"github.com/diggerhq/digger/cli/pkg/utils"
+	"github.com/diggerhq/digger/libs/orchestrator"
+	"github.com/stretchr/testify/assert"
+)
+
+const denyAllPlanPolicy = `package digger
+deny[msg] {
+  msg := "no changes allowed"
+}
+`
+
+const onlyAlicePolicy = `package digger
+default allow = false
+allow {
+  input.user == "alice"
+}
+`
+
+const createPlan = `{"resource_changes": [{"address": "null_resource.a", "change": {"actions": ["create"]}}]}`
+
+func writePolicy(t *testing.T, dir string, path string, content string) {
+	path = filepath.Join(dir, path)
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func TestRepoPolicyProviderProjectOverrides(t *testing.T) {
+	dir := t.TempDir()
+	writePolicy(t, dir, "access.rego", onlyAlicePolicy)
+	writePolicy(t, dir, "prod/plan.rego", denyAllPlanPolicy)
+	writePolicy(t, dir, "prod/app/access.rego", DefaultAccessPolicy)
+	provider := DiggerRepoPolicyProvider{Dir: dir}
+
+	accessPolicy, err := provider.GetAccessPolicy("org", "repo", "app", "prod/app")
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultAccessPolicy, accessPolicy)
+	accessPolicy, err = provider.GetAccessPolicy("org", "repo", "db", "prod/db")
+	assert.NoError(t, err)
+	assert.Equal(t, onlyAlicePolicy, accessPolicy)
+
+	planPolicy, err := provider.GetPlanPolicy("org", "repo", "app", "prod/app/")
+	assert.NoError(t, err)
+	assert.Equal(t, denyAllPlanPolicy, planPolicy)
+	planPolicy, err = provider.GetPlanPolicy("org", "repo", "dev", "dev")
+	assert.NoError(t, err)
+	assert.Equal(t, "", planPolicy)
+	// directories outside of the repository only get the repository wide policies
+	planPolicy, err = provider.GetPlanPolicy("org", "repo", "other", "../prod")
+	assert.NoError(t, err)
+	assert.Equal(t, "", planPolicy)
+
+	driftPolicy, err := provider.GetDriftPolicy()
+	assert.NoError(t, err)
+	assert.Equal(t, "", driftPolicy)
+}
+
+func TestRepoPolicyChecker(t *testing.T) {
+	dir := t.TempDir()
+	writePolicy(t, dir, "prod/plan.rego", denyAllPlanPolicy)
+	checker := DiggerPolicyChecker{PolicyProvider: DiggerRepoPolicyProvider{Dir: dir}}
+
+	allowed, result, err := checker.CheckPlanPolicy("repo", "org", "prod", "prod", createPlan)
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, []string{"no changes allowed"}, result.DenyMessages())
+
+	allowed, _, err = checker.CheckPlanPolicy("repo", "org", "dev", "dev", createPlan)
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+
+	// without an access policy plan policy violations block the command
+	ciService := utils.MockPullRequestManager{Teams: []string{}}
+	allowed, err = checker.CheckAccessPolicy(ciService, nil, "org", "repo", "prod", "prod", "default", "mantis apply", nil, "bob", result.DenyMessages(), nil)
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+}
+
+func TestRepoPolicyCheckerRefusesPolicyChanges(t *testing.T) {
+	dir := t.TempDir()
+	writePolicy(t, dir, "policies/access.rego", DefaultAccessPolicy)
+	wd, _ := os.Getwd()
+	assert.NoError(t, os.Chdir(dir))
+	defer os.Chdir(wd)
+	checker := NewRepoPolicyChecker("policies", "org")
+	prNumber := 12
+
+	var prService orchestrator.PullRequestService = &utils.MockPullRequestManager{ChangedFiles: []string{"prod/main.tf", "policies-old/access.rego"}}
+	allowed, err := checker.CheckAccessPolicy(utils.MockPullRequestManager{}, &prService, "org", "repo", "prod", "prod", "default", "mantis plan", &prNumber, "bob", []string{}, nil)
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+
+	prService = &utils.MockPullRequestManager{ChangedFiles: []string{"prod/main.tf", "policies/README.md"}}
+	allowed, err = checker.CheckAccessPolicy(utils.MockPullRequestManager{}, &prService, "org", "repo", "prod", "prod", "default", "mantis plan", &prNumber, "bob", []string{}, nil)
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+
+	prService = &utils.MockPullRequestManager{ChangedFiles: []string{"prod/main.tf", "policies/access.rego"}}
+	allowed, err = checker.CheckAccessPolicy(utils.MockPullRequestManager{}, &prService, "org", "repo", "prod", "prod", "default", "mantis plan", &prNumber, "bob", []string{}, nil)
+	assert.ErrorContains(t, err, "changes policy policies/access.rego")
+	assert.False(t, allowed)
+}
+
+func TestLayeredPolicyCheckerDenyWins(t *testing.T) {
+	orgDir := t.TempDir()
+	writePolicy(t, orgDir, "access.rego", DefaultAccessPolicy)
+	writePolicy(t, orgDir, "plan.rego", denyAllPlanPolicy)
+	repoDir := t.TempDir()
+	writePolicy(t, repoDir, "access.rego", onlyAlicePolicy)
+
+	checker := LayeredPolicyChecker{Checkers: []policy.Checker{
+		DiggerPolicyChecker{PolicyProvider: DiggerRepoPolicyProvider{Dir: orgDir}},
+		DiggerPolicyChecker{PolicyProvider: DiggerRepoPolicyProvider{Dir: repoDir}},
+	}}
+	ciService := utils.MockPullRequestManager{Teams: []string{}}
+
+	allowed, err := checker.CheckAccessPolicy(ciService, nil, "org", "repo", "dev", "dev", "default", "mantis plan", nil, "alice", []string{}, nil)
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+	allowed, err = checker.CheckAccessPolicy(ciService, nil, "org", "repo", "dev", "dev", "default", "mantis plan", nil, "bob", []string{}, nil)
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+
+	allowed, result, err := checker.CheckPlanPolicy("repo", "org", "dev", "dev", createPlan)
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, []string{"no changes allowed"}, result.DenyMessages())
+
+	driftResult, err := checker.CheckDriftPolicy("org", "repo", "dev", createPlan)
+	assert.NoError(t, err)
+	assert.True(t, driftResult.Enable)
+}
+
+func TestPolicyCheckerProviderUsesRepoPolicies(t *testing.T) {
+	dir := t.TempDir()
+	writePolicy(t, dir, "plan.rego", denyAllPlanPolicy)
+	t.Setenv("DIGGER_POLICY_DIR", dir)
+	t.Setenv("NO_BACKEND", "true")
+
+	checker, err := PolicyCheckerProviderBasic{}.Get("", "org", "")
+	assert.NoError(t, err)
+	assert.IsType(t, RepoPolicyChecker{}, checker)
+
+	t.Setenv("NO_BACKEND", "false")
+	checker, err = PolicyCheckerProviderBasic{}.Get("https://digger.example.com", "org", "token")
+	assert.NoError(t, err)
+	assert.IsType(t, LayeredPolicyChecker{}, checker)
+
+	t.Setenv("DIGGER_POLICY_SOURCE", "backend")
+	checker, err = PolicyCheckerProviderBasic{}.Get("https://digger.example.com", "org", "token")
+	assert.NoError(t, err)
+	assert.IsType(t, DiggerPolicyChecker{}, checker)
+
+	t.Setenv("DIGGER_POLICY_DIR", filepath.Join(dir, "missing"))
+	t.Setenv("DIGGER_POLICY_SOURCE", "")
+	t.Setenv("NO_BACKEND", "true")
+	_, err = PolicyCheckerProviderBasic{}.Get("", "org", "")
+	assert.ErrorContains(t, err, "missing")
+}
+
+func TestPolicyCheckerProviderRepoPoliciesAreOptIn(t *testing.T) {
+	dir := t.TempDir()
+	writePolicy(t, dir, "policies/plan.rego", denyAllPlanPolicy)
+	wd, _ := os.Getwd()
+	assert.NoError(t, os.Chdir(dir))
+	defer os.Chdir(wd)
+	t.Setenv("NO_BACKEND", "true")
+	t.Setenv("DIGGER_POLICY_DIR", "")
+	t.Setenv("DIGGER_POLICY_SOURCE", "")
+
+	t.Setenv("DIGGER_REPO_POLICIES", "")
+	checker, err := PolicyCheckerProviderBasic{}.Get("", "org", "")
+	assert.NoError(t, err)
+	assert.IsType(t, NoOpPolicyChecker{}, checker)
+
+	t.Setenv("DIGGER_REPO_POLICIES", "true")
+	checker, err = PolicyCheckerProviderBasic{}.Get("", "org", "")
+	assert.NoError(t, err)
+	assert.IsType(t, RepoPolicyChecker{}, checker)
+
+	assert.NoError(t, os.RemoveAll(filepath.Join(dir, "policies")))
+	_, err = PolicyCheckerProviderBasic{}.Get("", "org", "")
+	assert.ErrorContains(t, err, "policies")
+}
@@ -0,0 +1,128 @@
+package reports
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// JUnit renders the report with a test suite per command and a test case per project
+func JUnit(report Report) ([]byte, error) {
+	suites := junitTestSuites{Name: "mantis"}
+	suiteIndex := map[string]int{}
+	var suiteTimes []float64
+	var total float64
+	for _, job := range report.Jobs {
+		i, ok := suiteIndex[job.Command]
+		if !ok {
+			i = len(suites.Suites)
+			suiteIndex[job.Command] = i
+			suites.Suites = append(suites.Suites, junitTestSuite{Name: job.Command})
+			suiteTimes = append(suiteTimes, 0)
+		}
+		suite := &suites.Suites[i]
+		testCase := junitTestCase{
+			Name:      job.Project,
+			ClassName: job.Command,
+			Time:      seconds(job.DurationSeconds),
+			SystemOut: junitSystemOut(job),
+		}
+		switch job.Status {
+		case StatusFailed:
+			testCase.Failure = &junitMessage{Message: job.Error, Text: junitFailureText(job)}
+			suite.Failures++
+			suites.Failures++
+		case StatusSkipped:
+			testCase.Skipped = &junitMessage{Message: job.Error}
+			suite.Skipped++
+			suites.Skipped++
+		}
+		suite.Cases = append(suite.Cases, testCase)
+		suite.Tests++
+		suites.Tests++
+		suiteTimes[i] += job.DurationSeconds
+		total += job.DurationSeconds
+	}
+	for i := range suites.Suites {
+		suites.Suites[i].Time = seconds(suiteTimes[i])
+	}
+	suites.Time = seconds(total)
+
+	content, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), content...), nil
+}
+
+func junitFailureText(job JobReport) string {
+	var lines []string
+	for _, violation := range job.PolicyViolations {
+		if violation.Severity == "deny" {
+			lines = append(lines, violationLine(violation))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func junitSystemOut(job JobReport) string {
+	var lines []string
+	if job.PlanSummary != nil {
+		lines = append(lines, fmt.Sprintf("%v to add, %v to change, %v to destroy", job.PlanSummary.Created, job.PlanSummary.Updated, job.PlanSummary.Deleted))
+	}
+	if job.Lock != "" {
+		lines = append(lines, fmt.Sprintf("lock: %v", job.Lock))
+	}
+	if job.PlanArtifact != nil {
+		lines = append(lines, fmt.Sprintf("plan artifact: %v (%v)", job.PlanArtifact.Path, job.PlanArtifact.Name))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func violationLine(violation PolicyViolation) string {
+	line := violation.Message
+	if violation.Resource != "" {
+		line = violation.Resource + ": " + line
+	}
+	if violation.RuleId != "" {
+		line = "[" + violation.RuleId + "] " + line
+	}
+	return line
+}
+
+func seconds(s float64) string {
+	return fmt.Sprintf("%.3f", s)
+}
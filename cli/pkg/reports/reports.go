@@ -0,0 +1,152 @@
+// Package reports writes machine-readable reports of the jobs run by the cli: a versioned JSON report, JUnit XML
+// for test report UIs and SARIF for policy violations.
+package reports
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Version is incremented on incompatible changes to the JSON report
+const Version = 1
+
+const (
+	JSONFileName  = "mantis-report.json"
+	JUnitFileName = "mantis-junit.xml"
+	SARIFFileName = "mantis.sarif"
+)
+
+type Status string
+
+const (
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	// StatusSkipped is used for commands the access policy did not allow
+	StatusSkipped Status = "skipped"
+)
+
+type LockOutcome string
+
+const (
+	LockAcquired LockOutcome = "acquired"
+	// LockHeld means the project is locked by another pull request
+	LockHeld  LockOutcome = "held"
+	LockError LockOutcome = "error"
+)
+
+type Report struct {
+	Version    int         `json:"version"`
+	StartedAt  time.Time   `json:"started_at"`
+	FinishedAt time.Time   `json:"finished_at"`
+	Jobs       []JobReport `json:"jobs"`
+}
+
+// JobReport is the outcome of one command of a project
+type JobReport struct {
+	Project          string            `json:"project"`
+	Directory        string            `json:"directory"`
+	Workspace        string            `json:"workspace"`
+	Command          string            `json:"command"`
+	Status           Status            `json:"status"`
+	Error            string            `json:"error,omitempty"`
+	StartedAt        time.Time         `json:"started_at"`
+	DurationSeconds  float64           `json:"duration_seconds"`
+	PlanSummary      *PlanSummary      `json:"plan_summary,omitempty"`
+	PolicyViolations []PolicyViolation `json:"policy_violations,omitempty"`
+	Lock             LockOutcome       `json:"lock,omitempty"`
+	PlanArtifact     *PlanArtifact     `json:"plan_artifact,omitempty"`
+}
+
+type PlanSummary struct {
+	Created uint `json:"created"`
+	Updated uint `json:"updated"`
+	Deleted uint `json:"deleted"`
+}
+
+type PolicyViolation struct {
+	// Severity is deny, warn or info
+	Severity    string `json:"severity"`
+	RuleId      string `json:"rule_id,omitempty"`
+	Resource    string `json:"resource,omitempty"`
+	Message     string `json:"message"`
+	Remediation string `json:"remediation,omitempty"`
+	// File and Line locate the declaration of Resource relative to the repository root, when it was found
+	File string `json:"file,omitempty"`
+	Line int    `json:"line,omitempty"`
+}
+
+// PlanArtifact is where the plan file of a project was stored for the apply
+type PlanArtifact struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+var (
+	pathMu sync.RWMutex
+	path   = os.Getenv("DIGGER_REPORT_PATH")
+)
+
+// SetPath sets the directory reports are written to, an empty path disables the reports
+func SetPath(p string) {
+	pathMu.Lock()
+	defer pathMu.Unlock()
+	path = p
+}
+
+// Path returns the directory reports are written to, DIGGER_REPORT_PATH unless set with SetPath
+func Path() string {
+	pathMu.RLock()
+	defer pathMu.RUnlock()
+	return path
+}
+
+// Write writes the JSON, JUnit and SARIF reports to dir
+func Write(dir string, report Report) error {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return fmt.Errorf("could not create report directory %v: %v", dir, err)
+	}
+	report.Version = Version
+	content, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not marshal report: %v", err)
+	}
+	writers := []struct {
+		name    string
+		content func() ([]byte, error)
+	}{
+		{JSONFileName, func() ([]byte, error) { return content, nil }},
+		{JUnitFileName, func() ([]byte, error) { return JUnit(report) }},
+		{SARIFFileName, func() ([]byte, error) { return SARIF(report) }},
+	}
+	for _, w := range writers {
+		content, err := w.content()
+		if err != nil {
+			return fmt.Errorf("could not render %v: %v", w.name, err)
+		}
+		err = os.WriteFile(filepath.Join(dir, w.name), content, 0644)
+		if err != nil {
+			return fmt.Errorf("could not write %v: %v", w.name, err)
+		}
+	}
+	return nil
+}
+
+// WriteConfigured writes the reports to Path when it is set
+func WriteConfigured(report Report) {
+	dir := Path()
+	if dir == "" {
+		return
+	}
+	err := Write(dir, report)
+	if err != nil {
+		log.Printf("Failed to write job reports: %v", err)
+		return
+	}
+	log.Printf("Job reports written to %v", dir)
+}
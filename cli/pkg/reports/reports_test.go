@@ -0,0 +1,123 @@
+package reports
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sampleReport() Report {
+	started := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	return Report{
+		StartedAt:  started,
+		FinishedAt: started.Add(time.Minute),
+		Jobs: []JobReport{
+			{
+				Project: "dev", Directory: "dev", Workspace: "default", Command: "mantis plan",
+				Status: StatusSucceeded, StartedAt: started, DurationSeconds: 12.5,
+				PlanSummary:  &PlanSummary{Created: 2},
+				Lock:         LockAcquired,
+				PlanArtifact: &PlanArtifact{Name: "dev", Path: "org-repo-1-dev.tfplan"},
+				PolicyViolations: []PolicyViolation{
+					{Severity: "warn", Message: "missing owner tag", Resource: "aws_s3_bucket.logs", File: "dev/main.tf", Line: 3},
+				},
+			},
+			{
+				Project: "prod", Directory: "prod", Workspace: "default", Command: "mantis plan",
+				Status: StatusFailed, Error: "Plan is not allowed", StartedAt: started, DurationSeconds: 3,
+				Lock: LockAcquired,
+				PolicyViolations: []PolicyViolation{
+					{Severity: "deny", RuleId: "S3_001", Message: "bucket is public", Resource: "aws_s3_bucket.site"},
+				},
+			},
+			{
+				Project: "prod", Directory: "prod", Workspace: "default", Command: "mantis apply",
+				Status: StatusSkipped, Error: "not allowed", StartedAt: started,
+			},
+		},
+	}
+}
+
+func TestWriteReports(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "reports")
+	require.NoError(t, Write(dir, sampleReport()))
+
+	content, err := os.ReadFile(filepath.Join(dir, JSONFileName))
+	require.NoError(t, err)
+	var report Report
+	require.NoError(t, json.Unmarshal(content, &report))
+	assert.Equal(t, Version, report.Version)
+	assert.Len(t, report.Jobs, 3)
+	assert.Equal(t, uint(2), report.Jobs[0].PlanSummary.Created)
+	assert.Equal(t, LockAcquired, report.Jobs[0].Lock)
+	assert.Equal(t, "org-repo-1-dev.tfplan", report.Jobs[0].PlanArtifact.Path)
+
+	assert.FileExists(t, filepath.Join(dir, JUnitFileName))
+	assert.FileExists(t, filepath.Join(dir, SARIFFileName))
+}
+
+func TestJUnit(t *testing.T) {
+	content, err := JUnit(sampleReport())
+	require.NoError(t, err)
+
+	var suites junitTestSuites
+	require.NoError(t, xml.Unmarshal(content, &suites))
+	assert.Equal(t, 3, suites.Tests)
+	assert.Equal(t, 1, suites.Failures)
+	assert.Equal(t, 1, suites.Skipped)
+	require.Len(t, suites.Suites, 2)
+
+	plan := suites.Suites[0]
+	assert.Equal(t, "mantis plan", plan.Name)
+	assert.Equal(t, "15.500", plan.Time)
+	assert.Nil(t, plan.Cases[0].Failure)
+	assert.Contains(t, plan.Cases[0].SystemOut, "2 to add, 0 to change, 0 to destroy")
+	require.NotNil(t, plan.Cases[1].Failure)
+	assert.Equal(t, "Plan is not allowed", plan.Cases[1].Failure.Message)
+	assert.Equal(t, "[S3_001] aws_s3_bucket.site: bucket is public", plan.Cases[1].Failure.Text)
+	assert.NotNil(t, suites.Suites[1].Cases[0].Skipped)
+}
+
+func TestSARIF(t *testing.T) {
+	content, err := SARIF(sampleReport())
+	require.NoError(t, err)
+
+	var log sarifLog
+	require.NoError(t, json.Unmarshal(content, &log))
+	assert.Equal(t, "2.1.0", log.Version)
+	require.Len(t, log.Runs, 1)
+	run := log.Runs[0]
+	assert.Equal(t, []sarifRule{
+		{Id: "S3_001", ShortDescription: sarifMessage{Text: "S3_001"}},
+		{Id: defaultSarifRuleId, ShortDescription: sarifMessage{Text: defaultSarifRuleId}},
+	}, run.Tool.Driver.Rules)
+	require.Len(t, run.Results, 2)
+
+	located := run.Results[0]
+	assert.Equal(t, "warning", located.Level)
+	assert.Equal(t, "dev/main.tf", located.Locations[0].PhysicalLocation.ArtifactLocation.Uri)
+	assert.Equal(t, 3, located.Locations[0].PhysicalLocation.Region.StartLine)
+
+	unlocated := run.Results[1]
+	assert.Equal(t, "error", unlocated.Level)
+	assert.Equal(t, "prod", unlocated.Locations[0].PhysicalLocation.ArtifactLocation.Uri)
+	assert.Nil(t, unlocated.Locations[0].PhysicalLocation.Region)
+	assert.Equal(t, "aws_s3_bucket.site", unlocated.Locations[0].LogicalLocations[0].FullyQualifiedName)
+}
+
+func TestWriteConfigured(t *testing.T) {
+	SetPath("")
+	defer SetPath(os.Getenv("DIGGER_REPORT_PATH"))
+	WriteConfigured(sampleReport())
+
+	dir := t.TempDir()
+	SetPath(dir)
+	WriteConfigured(sampleReport())
+	assert.FileExists(t, filepath.Join(dir, JSONFileName))
+}
@@ -0,0 +1,135 @@
+package reports
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+// rule id of violations reported by policies without a rule_id
+const defaultSarifRuleId = "policy-violation"
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationUri string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	Id               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifResult struct {
+	RuleId    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation  `json:"physicalLocation"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	Uri string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+type sarifLogicalLocation struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+// SARIF renders the policy violations of the report, violations are located at the declaration of their resource
+// or at the project directory
+func SARIF(report Report) ([]byte, error) {
+	results := []sarifResult{}
+	rules := map[string]bool{}
+	for _, job := range report.Jobs {
+		for _, violation := range job.PolicyViolations {
+			ruleId := violation.RuleId
+			if ruleId == "" {
+				ruleId = defaultSarifRuleId
+			}
+			rules[ruleId] = true
+
+			message := violation.Message
+			if violation.Remediation != "" {
+				message = message + "\n" + violation.Remediation
+			}
+			location := sarifLocation{
+				PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{Uri: job.Directory}},
+			}
+			if violation.File != "" {
+				location.PhysicalLocation.ArtifactLocation.Uri = violation.File
+				location.PhysicalLocation.Region = &sarifRegion{StartLine: violation.Line}
+			}
+			if violation.Resource != "" {
+				location.LogicalLocations = []sarifLogicalLocation{{FullyQualifiedName: violation.Resource, Kind: "resource"}}
+			}
+			results = append(results, sarifResult{
+				RuleId:    ruleId,
+				Level:     sarifLevel(violation.Severity),
+				Message:   sarifMessage{Text: message},
+				Locations: []sarifLocation{location},
+			})
+		}
+	}
+
+	ruleIds := []string{}
+	for id := range rules {
+		ruleIds = append(ruleIds, id)
+	}
+	sort.Strings(ruleIds)
+	driver := sarifDriver{Name: "mantis", InformationUri: "https://github.com/diggerhq/digger", Rules: []sarifRule{}}
+	for _, id := range ruleIds {
+		driver.Rules = append(driver.Rules, sarifRule{Id: id, ShortDescription: sarifMessage{Text: id}})
+	}
+
+	return json.MarshalIndent(sarifLog{
+		Schema:  sarifSchema,
+		Version: "2.1.0",
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: driver}, Results: results}},
+	}, "", "  ")
+}
+
+func sarifLevel(severity string) string {
+	switch severity {
+	case "deny":
+		return "error"
+	case "warn":
+		return "warning"
+	default:
+		return "note"
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
+	azure_repos "github.com/diggerhq/digger/cli/pkg/azure"
 	backend2 "github.com/diggerhq/digger/cli/pkg/backend"
 	"github.com/diggerhq/digger/cli/pkg/core/backend"
 	"github.com/diggerhq/digger/cli/pkg/core/policy"
@@ -20,7 +21,11 @@ import (
 	"github.com/diggerhq/digger/libs/locking/azure"
 	"github.com/diggerhq/digger/libs/locking/gcp"
 	"github.com/diggerhq/digger/libs/orchestrator"
+	"github.com/diggerhq/digger/libs/orchestrator/bitbucket"
 	"github.com/diggerhq/digger/libs/orchestrator/github"
+	"github.com/diggerhq/digger/libs/orchestrator/gitlab"
+	"github.com/diggerhq/digger/libs/spec"
+	storage2 "github.com/diggerhq/digger/libs/storage"
 	"github.com/samber/lo"
 	"log"
 	"net/http"
@@ -37,7 +42,7 @@ func (j JobSpecProvider) GetJob(jobSpec orchestrator.JobJson) (orchestrator.Job,
 
 type LockProvider struct{}
 
-func (l LockProvider) GetLock(lockSpec LockSpec) (locking.Lock, error) {
+func (l LockProvider) GetLock(lockSpec spec.LockSpec) (locking.Lock, error) {
 	if lockSpec.LockType == "noop" {
 		return locking.NoOpLock{}, nil
 	}
@@ -112,7 +117,7 @@ func (l LockProvider) GetLock(lockSpec LockSpec) (locking.Lock, error) {
 
 type ReporterProvider struct{}
 
-func (r ReporterProvider) GetReporter(reporterSpec ReporterSpec, ciService orchestrator.PullRequestService, prNumber int) (reporting.Reporter, error) {
+func (r ReporterProvider) GetReporter(reporterSpec spec.ReporterSpec, ciService orchestrator.PullRequestService, prNumber int) (reporting.Reporter, error) {
 	getStrategy := func(strategy string) reporting.ReportStrategy {
 		switch reporterSpec.ReportingStrategy {
 		case "comments_per_run":
@@ -155,7 +160,7 @@ func (r ReporterProvider) GetReporter(reporterSpec ReporterSpec, ciService orche
 
 type BackendApiProvider struct{}
 
-func (b BackendApiProvider) GetBackendApi(backendSpec BackendSpec) (backend.Api, error) {
+func (b BackendApiProvider) GetBackendApi(backendSpec spec.BackendSpec) (backend.Api, error) {
 	switch backendSpec.BackendType {
 	case "noop":
 		return backend2.NoopApi{}, nil
@@ -168,7 +173,7 @@ func (b BackendApiProvider) GetBackendApi(backendSpec BackendSpec) (backend.Api,
 
 type VCSProvider struct{}
 
-func (v VCSProvider) GetPrService(vcsSpec VcsSpec) (orchestrator.PullRequestService, error) {
+func (v VCSProvider) GetPrService(vcsSpec spec.VcsSpec) (orchestrator.PullRequestService, error) {
 	switch vcsSpec.VcsType {
 	case "github":
 		token := os.Getenv("GITHUB_TOKEN")
@@ -176,6 +181,43 @@ func (v VCSProvider) GetPrService(vcsSpec VcsSpec) (orchestrator.PullRequestServ
 			return nil, fmt.Errorf("failed to get githbu service: GITHUB_TOKEN not specified")
 		}
 		return github.GithubServiceProviderBasic{}.NewService(token, vcsSpec.RepoName, vcsSpec.RepoOwner)
+	case "gitlab":
+		token := os.Getenv("GITLAB_TOKEN")
+		if token == "" {
+			return nil, fmt.Errorf("failed to get gitlab service: GITLAB_TOKEN not specified")
+		}
+		baseUrl := vcsSpec.BaseUrl
+		if baseUrl == "" {
+			baseUrl = os.Getenv("GITLAB_BASE_URL")
+		}
+		// the owner of GitLab projects can be a group with subgroups
+		return gitlab.NewGitLabServiceForProject(token, baseUrl, vcsSpec.RepoOwner+"/"+vcsSpec.RepoName)
+	case "azure":
+		token := os.Getenv("AZURE_TOKEN")
+		if token == "" {
+			return nil, fmt.Errorf("failed to get azure service: AZURE_TOKEN not specified")
+		}
+		baseUrl := vcsSpec.BaseUrl
+		if baseUrl == "" {
+			baseUrl = os.Getenv("AZURE_BASE_URL")
+		}
+		if baseUrl == "" {
+			return nil, fmt.Errorf("failed to get azure service: base_url or AZURE_BASE_URL not specified")
+		}
+		// the owner of Azure Repos repositories is their project
+		return azure_repos.NewAzureReposService(token, baseUrl, vcsSpec.RepoOwner, vcsSpec.RepoName)
+	case "bitbucket":
+		token := os.Getenv("BITBUCKET_TOKEN")
+		if token == "" {
+			return nil, fmt.Errorf("failed to get bitbucket service: BITBUCKET_TOKEN not specified")
+		}
+		// the owner of Bitbucket repositories is their workspace
+		return bitbucket.BitbucketAPI{
+			AuthToken:     token,
+			HttpClient:    http.Client{},
+			RepoWorkspace: vcsSpec.RepoOwner,
+			RepoName:      vcsSpec.RepoName,
+		}, nil
 	default:
 		return nil, fmt.Errorf("could not get PRService, unknown type %v", vcsSpec.VcsType)
 	}
@@ -183,18 +225,72 @@ func (v VCSProvider) GetPrService(vcsSpec VcsSpec) (orchestrator.PullRequestServ
 
 type PolicyProvider struct{}
 
-func (p PolicyProvider) GetPolicyProvider(policySpec PolicySpec, diggerHost string, diggerOrg string, token string) (policy.Checker, error) {
+func (p PolicyProvider) GetPolicyProvider(policySpec spec.PolicySpec, diggerHost string, diggerOrg string, token string) (policy.Checker, error) {
+	httpChecker := policy2.DiggerPolicyChecker{
+		PolicyProvider: policy2.DiggerHttpPolicyProvider{
+			DiggerHost:         diggerHost,
+			DiggerOrganisation: diggerOrg,
+			AuthToken:          token,
+			HttpClient:         http.DefaultClient,
+		},
+	}
+	policyDir := policySpec.PolicyDir
+	if policyDir == "" {
+		policyDir = policy2.DefaultRepoPolicyDir
+	}
+	repoChecker := policy2.NewRepoPolicyChecker(policyDir, diggerOrg)
+	if policySpec.PolicyType == "repo" || policySpec.PolicyType == "layered" {
+		err := policy2.CheckRepoPolicyDir(policyDir)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	switch policySpec.PolicyType {
 	case "http":
+		return httpChecker, nil
+	case "repo":
+		return repoChecker, nil
+	case "layered":
+		return policy2.LayeredPolicyChecker{Checkers: []policy.Checker{httpChecker, repoChecker}}, nil
+	case "bundle":
+		if policySpec.BundleUrl == "" {
+			return nil, fmt.Errorf("bundle policy provider requires bundle_url to be set")
+		}
 		return policy2.DiggerPolicyChecker{
-			PolicyProvider: policy2.DiggerHttpPolicyProvider{
-				DiggerHost:         diggerHost,
-				DiggerOrganisation: diggerOrg,
-				AuthToken:          token,
+			PolicyProvider: &policy2.DiggerBundlePolicyProvider{
+				Source:             policySpec.BundleUrl,
+				PublicKey:          policySpec.BundlePublicKey,
+				CacheDir:           policy2.DefaultBundleCacheDir(),
 				HttpClient:         http.DefaultClient,
+				DiggerOrganisation: diggerOrg,
 			},
 		}, nil
 	default:
-		return nil, fmt.Errorf("could not find ")
+		return nil, fmt.Errorf("could not find policy provider %v", policySpec.PolicyType)
+	}
+}
+
+type PlanStorageProvider struct{}
+
+func (p PlanStorageProvider) GetPlanStorage(planStorageSpec spec.PlanStorageSpec, backendSpec spec.BackendSpec, vcsSpec spec.VcsSpec, prNumber *int) (storage2.PlanStorage, error) {
+	var planStorage storage2.PlanStorage
+	switch planStorageSpec.StorageType {
+	case "backend":
+		if backendSpec.BackendHostname == "" {
+			return nil, fmt.Errorf("backend plan storage requires a backend hostname")
+		}
+		planStorage = storage2.NewRestPlanStorage(backendSpec.BackendHostname, vcsSpec.RepoOwner+"-"+vcsSpec.RepoName, backendSpec.BackendJobToken)
+	case "local":
+		if planStorageSpec.LocalDir == "" {
+			return nil, fmt.Errorf("local plan storage requires local_dir to be set")
+		}
+		planStorage = &storage2.PlanStorageLocal{Dir: planStorageSpec.LocalDir}
+	case "":
+		// fall back to the storage configured with PLAN_UPLOAD_DESTINATION, it is encrypted already
+		return storage2.NewPlanStorageFromEnv(os.Getenv("GITHUB_TOKEN"), vcsSpec.RepoOwner, vcsSpec.RepoName, prNumber)
+	default:
+		return nil, fmt.Errorf("could not determine plan storage %v", planStorageSpec.StorageType)
 	}
+	return storage2.WithPlanEncryptionFromEnv(planStorage)
 }
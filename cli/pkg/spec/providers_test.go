@@ -0,0 +1,40 @@
+package spec
+
+import (
+	"testing"
+
+	"filippo.io/age"
+	"github.com/diggerhq/digger/libs/spec"
+	storage2 "github.com/diggerhq/digger/libs/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVCSProviderRequiresCredentials(t *testing.T) {
+	t.Setenv("GITLAB_TOKEN", "")
+	t.Setenv("BITBUCKET_TOKEN", "")
+	t.Setenv("AZURE_TOKEN", "token")
+	t.Setenv("AZURE_BASE_URL", "")
+
+	_, err := VCSProvider{}.GetPrService(spec.VcsSpec{VcsType: "gitlab", RepoOwner: "group", RepoName: "infra"})
+	assert.ErrorContains(t, err, "GITLAB_TOKEN")
+
+	_, err = VCSProvider{}.GetPrService(spec.VcsSpec{VcsType: "azure", RepoOwner: "project", RepoName: "infra"})
+	assert.ErrorContains(t, err, "AZURE_BASE_URL")
+
+	_, err = VCSProvider{}.GetPrService(spec.VcsSpec{VcsType: "bitbucket", RepoOwner: "workspace", RepoName: "infra"})
+	assert.ErrorContains(t, err, "BITBUCKET_TOKEN")
+
+	_, err = VCSProvider{}.GetPrService(spec.VcsSpec{VcsType: "gitea"})
+	assert.Error(t, err)
+}
+
+func TestPlanStorageProviderEncryptsPlans(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	assert.NoError(t, err)
+	t.Setenv("PLAN_ENCRYPTION_AGE_IDENTITIES", identity.String())
+	t.Setenv("PLAN_ENCRYPTION_AWS_KMS_KEY_ID", "")
+
+	planStorage, err := PlanStorageProvider{}.GetPlanStorage(spec.PlanStorageSpec{StorageType: "local", LocalDir: t.TempDir()}, spec.BackendSpec{}, spec.VcsSpec{}, nil)
+	assert.NoError(t, err)
+	assert.IsType(t, &storage2.EncryptedPlanStorage{}, planStorage)
+}
@@ -3,26 +3,27 @@ package spec
 import (
 	"fmt"
 	"github.com/diggerhq/digger/cli/pkg/digger"
-	storage2 "github.com/diggerhq/digger/cli/pkg/storage"
 	"github.com/diggerhq/digger/cli/pkg/usage"
 	comment_summary "github.com/diggerhq/digger/libs/comment_utils/summary"
 	"github.com/diggerhq/digger/libs/digger_config"
+	"github.com/diggerhq/digger/libs/notifications"
 	"github.com/diggerhq/digger/libs/orchestrator"
-	orchestrator_github "github.com/diggerhq/digger/libs/orchestrator/github"
 	"github.com/diggerhq/digger/libs/spec"
 	"log"
+	"os"
 	"strconv"
 	"time"
 )
 
 func RunSpec(
 	spec spec.Spec,
-	vcsProvider spec.VCSProvider,
-	jobProvider spec.JobSpecProvider,
-	lockProvider spec.LockProvider,
-	reporterProvider spec.ReporterProvider,
-	backedProvider spec.BackendApiProvider,
-	policyProvider spec.PolicyProvider,
+	vcsProvider VCSProvider,
+	jobProvider JobSpecProvider,
+	lockProvider LockProvider,
+	reporterProvider ReporterProvider,
+	backedProvider BackendApiProvider,
+	policyProvider PolicyProvider,
+	planStorageProvider PlanStorageProvider,
 	commentUpdaterProvider comment_summary.CommentUpdaterProvider,
 ) error {
 
@@ -66,13 +67,18 @@ func RunSpec(
 		usage.ReportErrorAndExit(spec.VCS.Actor, fmt.Sprintf("Failed to read Digger digger_config. %s", err), 4)
 	}
 	log.Printf("Digger digger_config read successfully\n")
+	notifier := notifications.Load(diggerConfig.Notifications, os.LookupEnv)
 
-	commentUpdater, err := commentUpdaterProvider.Get(*diggerConfig)
+	commentTemplates := digger.LoadCommentTemplates("./")
+	commentUpdater, err := commentUpdaterProvider.Get(*diggerConfig, commentTemplates)
 	if err != nil {
 		usage.ReportErrorAndExit(spec.VCS.Actor, fmt.Sprintf("could not get comment updater: %v", err), 8)
 	}
 
-	planStorage := storage2.NewPlanStorage("", "", "", "", nil)
+	planStorage, err := planStorageProvider.GetPlanStorage(spec.PlanStorage, spec.Backend, spec.VCS, spec.Job.PullRequestNumber)
+	if err != nil {
+		usage.ReportErrorAndExit(spec.VCS.Actor, fmt.Sprintf("could not get plan storage: %v", err), 9)
+	}
 
 	jobs := []orchestrator.Job{job}
 
@@ -87,9 +93,11 @@ func RunSpec(
 		usage.ReportErrorAndExit(spec.VCS.Actor, fmt.Sprintf("failed to get comment ID: %v", err), 4)
 	}
 
-	// TODO: do not require conversion to gh service
-	ghService := prService.(orchestrator_github.GithubService)
-	allAppliesSuccess, _, err := digger.RunJobs(jobs, prService, ghService, lock, reporter, planStorage, policyChecker, commentUpdater, backendApi, spec.JobId, true, false, commentId64, "")
+	orgService, ok := prService.(orchestrator.OrgService)
+	if !ok {
+		usage.ReportErrorAndExit(spec.VCS.Actor, fmt.Sprintf("vcs %v does not provide an org service", spec.VCS.VcsType), 1)
+	}
+	allAppliesSuccess, _, err := digger.RunJobs(jobs, prService, orgService, lock, reporter, planStorage, policyChecker, commentUpdater, backendApi, spec.JobId, true, false, commentId64, "", diggerConfig.Hooks, diggerConfig.ProviderCache, commentTemplates, notifier)
 	if !allAppliesSuccess || err != nil {
 		serializedBatch, reportingError := backendApi.ReportProjectJobStatus(spec.VCS.RepoName, spec.Job.ProjectName, spec.JobId, "failed", time.Now(), nil, "", "")
 		if reportingError != nil {
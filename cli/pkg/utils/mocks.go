@@ -5,7 +5,10 @@ import (
 
 	"github.com/diggerhq/digger/cli/pkg/core/backend"
 	"github.com/diggerhq/digger/cli/pkg/core/execution"
+	"github.com/diggerhq/digger/cli/pkg/core/policy"
 	"github.com/diggerhq/digger/libs/orchestrator/scheduler"
+	"github.com/diggerhq/digger/libs/storage"
+	"github.com/diggerhq/digger/libs/terraform_utils"
 
 	"github.com/diggerhq/digger/libs/orchestrator"
 )
@@ -27,22 +30,24 @@ func (tf *MockTerraform) Plan() (bool, string, string, error) {
 type MockPolicyChecker struct {
 }
 
-func (t MockPolicyChecker) CheckAccessPolicy(ciService orchestrator.OrgService, prService *orchestrator.PullRequestService, SCMOrganisation string, SCMrepository string, projectName string, projectDir string, command string, prNumber *int, requestedBy string, planPolicyViolations []string) (bool, error) {
+func (t MockPolicyChecker) CheckAccessPolicy(ciService orchestrator.OrgService, prService *orchestrator.PullRequestService, SCMOrganisation string, SCMrepository string, projectName string, projectDir string, projectWorkspace string, command string, prNumber *int, requestedBy string, planPolicyViolations []string, planSummary *terraform_utils.PlanSummary) (bool, error) {
 	return false, nil
 }
 
-func (t MockPolicyChecker) CheckPlanPolicy(SCMrepository string, SCMOrganisation string, projectname string, projectDir string, planOutput string) (bool, []string, error) {
-	return false, nil, nil
+func (t MockPolicyChecker) CheckPlanPolicy(SCMrepository string, SCMOrganisation string, projectname string, projectDir string, planOutput string) (bool, policy.PlanPolicyResult, error) {
+	return false, policy.PlanPolicyResult{}, nil
 }
 
-func (t MockPolicyChecker) CheckDriftPolicy(SCMOrganisation string, SCMrepository string, projectname string) (bool, error) {
-	return true, nil
+func (t MockPolicyChecker) CheckDriftPolicy(SCMOrganisation string, SCMrepository string, projectname string, planOutput string) (policy.DriftPolicyResult, error) {
+	return policy.DriftPolicyResult{Enable: true}, nil
 }
 
 type MockPullRequestManager struct {
 	ChangedFiles []string
 	Teams        []string
 	Approvals    []string
+	Labels       []string
+	Details      orchestrator.PullRequestDetails
 }
 
 func (t MockPullRequestManager) GetUserTeams(organisation string, user string) ([]string, error) {
@@ -76,6 +81,14 @@ func (t MockPullRequestManager) GetApprovals(prNumber int) ([]string, error) {
 	return t.Approvals, nil
 }
 
+func (t MockPullRequestManager) GetLabels(prNumber int) ([]string, error) {
+	return t.Labels, nil
+}
+
+func (t MockPullRequestManager) GetPullRequestDetails(prNumber int) (*orchestrator.PullRequestDetails, error) {
+	return &t.Details, nil
+}
+
 func (t MockPullRequestManager) MergePullRequest(prNumber int) error {
 	return nil
 }
@@ -104,6 +117,10 @@ func (t MockPullRequestManager) EditComment(prNumber int, commentId interface{},
 	return nil
 }
 
+func (t MockPullRequestManager) MaxCommentLength() int {
+	return 65536
+}
+
 func (t *MockPullRequestManager) CreateCommentReaction(id interface{}, reaction string) error {
 	return nil
 }
@@ -135,6 +152,14 @@ func (t MockPlanStorage) PlanExists(artifactName string, storedPlanFilePath stri
 	return false, nil
 }
 
+func (t MockPlanStorage) ListPlans(prefix string) ([]storage.PlanInfo, error) {
+	return nil, nil
+}
+
+func (t MockPlanStorage) DeletePlan(plan storage.PlanInfo) error {
+	return nil
+}
+
 type MockBackendApi struct {
 }
 
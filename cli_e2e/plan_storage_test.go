@@ -8,7 +8,7 @@ import (
 	"strings"
 	"testing"
 
-	"github.com/diggerhq/digger/cli/pkg/storage"
+	"github.com/diggerhq/digger/libs/storage"
 	"github.com/stretchr/testify/assert"
 )
 
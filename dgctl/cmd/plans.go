@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/diggerhq/digger/libs/orchestrator/github"
+	"github.com/diggerhq/digger/libs/storage"
+	"github.com/spf13/cobra"
+)
+
+var plansCmd = &cobra.Command{
+	Use:   "plans",
+	Short: "Manage stored plan files",
+	Long:  `Manage the plan files digger keeps in the storage configured with PLAN_UPLOAD_DESTINATION`,
+}
+
+var plansPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Delete stored plans of closed pull requests and plans older than a TTL",
+	Long: `Delete stored plans of merged or closed pull requests and plans older than --ttl.
+The storage is configured with the same environment variables as the digger cli, e.g. PLAN_UPLOAD_DESTINATION and AWS_S3_BUCKET.
+GITHUB_TOKEN is used to look up the state of pull requests.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		repo, _ := cmd.Flags().GetString("repo")
+		ttl, _ := cmd.Flags().GetDuration("ttl")
+		closedPrs, _ := cmd.Flags().GetBool("closed-prs")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		owner, repoName, found := strings.Cut(repo, "/")
+		if !found {
+			log.Printf("--repo must be in the form owner/name")
+			os.Exit(1)
+		}
+		ghToken := os.Getenv("GITHUB_TOKEN")
+
+		prNumber := 0
+		planStorage, err := storage.NewPlanStorageFromEnv(ghToken, owner, repoName, &prNumber)
+		if err != nil {
+			log.Printf("Could not create plan storage: %v", err)
+			os.Exit(1)
+		}
+		if planStorage == nil {
+			log.Printf("No plan storage configured, set PLAN_UPLOAD_DESTINATION")
+			os.Exit(1)
+		}
+
+		opts := storage.PruneOptions{
+			ProjectNamespace: repo,
+			TTL:              ttl,
+			DryRun:           dryRun,
+			Now:              time.Now(),
+		}
+		if closedPrs {
+			service, err := github.GithubServiceProviderBasic{}.NewService(ghToken, repoName, owner)
+			if err != nil {
+				log.Printf("Could not create github service: %v", err)
+				os.Exit(1)
+			}
+			opts.PullRequestClosed = service.IsMergedOrClosed
+		}
+
+		pruned, err := storage.PrunePlans(planStorage, opts)
+		for _, plan := range pruned {
+			if dryRun {
+				fmt.Printf("would delete %v\n", plan.StoredPlanFilePath)
+			} else {
+				fmt.Printf("deleted %v\n", plan.StoredPlanFilePath)
+			}
+		}
+		if err != nil {
+			log.Printf("Failed to prune plans: %v", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	plansPruneCmd.Flags().String("repo", "", "Repository the plans belong to, in the form owner/name")
+	plansPruneCmd.Flags().Duration("ttl", 0, "Delete plans older than this, e.g. 720h. Plans are kept regardless of age when not set")
+	plansPruneCmd.Flags().Bool("closed-prs", true, "Delete plans of merged and closed pull requests")
+	plansPruneCmd.Flags().Bool("dry-run", false, "Only print the plans that would be deleted")
+	plansPruneCmd.MarkFlagRequired("repo")
+
+	plansCmd.AddCommand(plansPruneCmd)
+	rootCmd.AddCommand(plansCmd)
+}
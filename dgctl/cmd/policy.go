@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/diggerhq/digger/cli/pkg/policy"
+	"github.com/spf13/cobra"
+)
+
+var policyCmd = &cobra.Command{
+	Use:   "policy",
+	Short: "Work with access, plan and drift policies",
+	Long:  `Work with the rego policies digger evaluates for access, plan and drift checks`,
+}
+
+var policyTestCmd = &cobra.Command{
+	Use:   "test [test files...]",
+	Short: "Evaluate policies against fixtures and run rego unit tests",
+	Long: `Evaluate the policies in --dir against the fixtures of YAML test files and run the *_test.rego unit tests.
+Test files default to <dir>/tests/*.yaml. Policies get the same input as during a digger run,
+plan and drift policies are evaluated against the output of terraform show -json referenced with plan_file.
+
+tests:
+  - name: only admins can apply
+    policy: access
+    project: prod
+    project_dir: prod
+    user: bob
+    teams: [developers]
+    action: "mantis apply"
+    expect:
+      allow: false
+  - name: no deletions
+    policy: plan
+    plan_file: fixtures/destroy.json
+    expect:
+      deny: ["deleting resources is not allowed"]
+  - name: iam drift is critical
+    policy: drift
+    plan_file: fixtures/iam-drift.json
+    expect:
+      severity: critical
+      notify: ["@security"]`,
+	Run: func(cmd *cobra.Command, args []string) {
+		dir, _ := cmd.Flags().GetString("dir")
+
+		testFiles := args
+		if len(testFiles) == 0 {
+			for _, pattern := range []string{"*.yaml", "*.yml"} {
+				matches, _ := filepath.Glob(filepath.Join(dir, "tests", pattern))
+				testFiles = append(testFiles, matches...)
+			}
+		}
+
+		passed, failed := 0, 0
+		for _, testFile := range testFiles {
+			results, err := policy.RunPolicyTests(testFile, dir)
+			if err != nil {
+				log.Printf("Could not run policy tests: %v", err)
+				os.Exit(1)
+			}
+			for _, result := range results {
+				if result.Failure != "" {
+					fmt.Printf("FAIL %v: %v\n", result.Name, result.Failure)
+					failed++
+				} else {
+					fmt.Printf("PASS %v\n", result.Name)
+					passed++
+				}
+			}
+		}
+
+		regoResults, err := policy.RunRegoUnitTests(dir)
+		if err != nil {
+			log.Printf("Could not run rego unit tests: %v", err)
+			os.Exit(1)
+		}
+		for _, result := range regoResults {
+			name := fmt.Sprintf("%v.%v", result.Package, result.Name)
+			if result.Location != nil {
+				name = fmt.Sprintf("%v (%v)", name, result.Location.File)
+			}
+			switch {
+			case result.Error != nil:
+				fmt.Printf("ERROR %v: %v\n", name, result.Error)
+				failed++
+			case result.Fail:
+				fmt.Printf("FAIL %v\n", name)
+				failed++
+			case result.Skip:
+				fmt.Printf("SKIP %v\n", name)
+			default:
+				fmt.Printf("PASS %v\n", name)
+				passed++
+			}
+		}
+
+		fmt.Printf("%v passed, %v failed\n", passed, failed)
+		if failed > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	policyTestCmd.Flags().String("dir", policy.DefaultRepoPolicyDir, "Directory containing the policies")
+
+	policyCmd.AddCommand(policyTestCmd)
+	rootCmd.AddCommand(policyCmd)
+}
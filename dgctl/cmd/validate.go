@@ -9,6 +9,7 @@ import (
 	"log"
 	"os"
 
+	"github.com/diggerhq/digger/libs/comment_utils/templates"
 	"github.com/diggerhq/digger/libs/digger_config"
 	"github.com/spf13/cobra"
 )
@@ -16,14 +17,19 @@ import (
 // validateCmd represents the validate command
 var validateCmd = &cobra.Command{
 	Use:   "validate",
-	Short: "Validate a mantis.yml file",
-	Long:  `Validate a mantis.yml file`,
+	Short: "Validate a mantis.yml file and the comment templates",
+	Long:  `Validate a mantis.yml file and the comment templates in .mantis/templates and comment_templates`,
 	Run: func(cmd *cobra.Command, args []string) {
 		_, configYaml, _, err := digger_config.LoadDiggerConfig("./", true, nil)
 		if err != nil {
 			log.Printf("Invalid digger config file: %v. Exiting.", err)
 			os.Exit(1)
 		}
+		_, err = templates.Load("./", configYaml.CommentTemplates)
+		if err != nil {
+			log.Printf("Invalid comment templates in %v or comment_templates: %v. Exiting.", templates.Dir, err)
+			os.Exit(1)
+		}
 		log.Printf("mantis.yml loaded successfully, here is your configuration:")
 		s, _ := json.MarshalIndent(configYaml, "", "\t")
 		fmt.Println(string(s))
@@ -0,0 +1,52 @@
+package reporting
+
+import (
+	"sort"
+	"strings"
+)
+
+// minMaskedSecretLength keeps short values such as "true" or "1" from mangling unrelated output
+const minMaskedSecretLength = 8
+
+const maskedSecret = "***"
+
+// MaskingReporter replaces known secret values in reports before passing them on to the wrapped reporter
+type MaskingReporter struct {
+	Reporter Reporter
+	secrets  []string
+}
+
+func NewMaskingReporter(reporter Reporter, secrets []string) *MaskingReporter {
+	masked := make([]string, 0, len(secrets))
+	for _, secret := range secrets {
+		if len(secret) >= minMaskedSecretLength {
+			masked = append(masked, secret)
+		}
+	}
+	// longest first so that a secret containing another one is masked as a whole
+	sort.Slice(masked, func(i, j int) bool { return len(masked[i]) > len(masked[j]) })
+	return &MaskingReporter{Reporter: reporter, secrets: masked}
+}
+
+func (m *MaskingReporter) Mask(report string) string {
+	for _, secret := range m.secrets {
+		report = strings.ReplaceAll(report, secret, maskedSecret)
+	}
+	return report
+}
+
+func (m *MaskingReporter) Report(report string, reportFormatting func(report string) string) (string, string, error) {
+	return m.Reporter.Report(m.Mask(report), reportFormatting)
+}
+
+func (m *MaskingReporter) Flush() (string, string, error) {
+	return m.Reporter.Flush()
+}
+
+func (m *MaskingReporter) Suppress() error {
+	return m.Reporter.Suppress()
+}
+
+func (m *MaskingReporter) SupportsMarkdown() bool {
+	return m.Reporter.SupportsMarkdown()
+}
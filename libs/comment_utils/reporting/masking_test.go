@@ -0,0 +1,21 @@
+package reporting
+
+import (
+	"testing"
+
+	"github.com/diggerhq/digger/libs/orchestrator"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaskingReporterMasksSecrets(t *testing.T) {
+	ciService := MockCiService{CommentsPerPr: map[int][]*orchestrator.Comment{}}
+	reporter := NewMaskingReporter(CiReporter{
+		CiService:      ciService,
+		PrNumber:       1,
+		ReportStrategy: MultipleCommentsStrategy{},
+	}, []string{"AKIASECRETKEY", "AKIASECRETKEY/extended", "true"})
+
+	_, _, err := reporter.Report("key=AKIASECRETKEY/extended other=AKIASECRETKEY enabled=true", func(report string) string { return report })
+	assert.NoError(t, err)
+	assert.Equal(t, "key=*** other=*** enabled=true", *ciService.CommentsPerPr[1][0].Body)
+}
@@ -110,6 +110,8 @@ type ReportStrategy interface {
 type CommentPerRunStrategy struct {
 	Title     string
 	TimeOfRun time.Time
+	// Overflow keeps the full output of reports truncated to fit the comment
+	Overflow OverflowStorage
 }
 
 func (strategy CommentPerRunStrategy) Report(ciService orchestrator.PullRequestService, PrNumber int, report string, reportFormatter func(report string) string, supportsCollapsibleComment bool) (string, string, error) {
@@ -124,51 +126,59 @@ func (strategy CommentPerRunStrategy) Report(ciService orchestrator.PullRequestS
 	} else {
 		reportTitle = "Digger run report at " + strategy.TimeOfRun.Format("2006-01-02 15:04:05 (MST)")
 	}
-	commentId, commentUrl, err := upsertComment(ciService, PrNumber, report, reportFormatter, comments, reportTitle, supportsCollapsibleComment)
+	commentId, commentUrl, err := upsertComment(ciService, PrNumber, report, reportFormatter, comments, reportTitle, supportsCollapsibleComment, strategy.Overflow)
 	return commentId, commentUrl, err
 }
 
-func upsertComment(ciService orchestrator.PullRequestService, PrNumber int, report string, reportFormatter func(report string) string, comments []orchestrator.Comment, reportTitle string, supportsCollapsible bool) (string, string, error) {
-	report = reportFormatter(report)
+// upsertComment appends the report to the comment with reportTitle. Reports are truncated to fit the comment size
+// limit of the service, when the existing comment is full the report goes into a continuation comment
+func upsertComment(ciService orchestrator.PullRequestService, PrNumber int, report string, reportFormatter func(report string) string, comments []orchestrator.Comment, reportTitle string, supportsCollapsible bool, overflow OverflowStorage) (string, string, error) {
 	var commentIdForThisRun interface{}
 	var commentBody string
 	var commentUrl string
+	title := reportTitle
+	continuedTitle := reportTitle + " (continued)"
+	// the latest matching comment is used so that reports are added to continuation comments
 	for _, comment := range comments {
 		if strings.Contains(*comment.Body, reportTitle) {
 			commentIdForThisRun = comment.Id
 			commentBody = *comment.Body
 			commentUrl = comment.Url
-			break
+			if strings.Contains(*comment.Body, continuedTitle) {
+				title = continuedTitle
+			}
 		}
 	}
 
-	if commentIdForThisRun == nil {
-		var commentMessage string
-		if !supportsCollapsible {
-			commentMessage = utils.AsComment(reportTitle)(report)
+	limit := maxCommentLength(ciService)
+	if commentIdForThisRun != nil {
+		// strip first and last lines
+		lines := strings.Split(commentBody, "\n")
+		lines = lines[1 : len(lines)-1]
+		commentBody = strings.Join(lines, "\n")
+
+		remaining := limit - len(commentWrapper(title, supportsCollapsible)(commentBody+"\n\n\n"))
+		if len(reportFormatter(report)) > remaining && remaining < limit/4 {
+			log.Printf("Comment %v is full, reporting in a continuation comment", commentIdForThisRun)
+			commentIdForThisRun = nil
+			title = continuedTitle
 		} else {
-			commentMessage = utils.AsCollapsibleComment(reportTitle, false)(report)
+			report = fitReport(report, reportFormatter, remaining, overflow)
 		}
-		comment, err := ciService.PublishComment(PrNumber, commentMessage)
+	}
+
+	wrap := commentWrapper(title, supportsCollapsible)
+	if commentIdForThisRun == nil {
+		report = fitReport(report, reportFormatter, limit-len(wrap("")), overflow)
+		comment, err := ciService.PublishComment(PrNumber, wrap(reportFormatter(report)))
 		if err != nil {
 			return "", "", fmt.Errorf("error publishing comment: %v", err)
 		}
 		return fmt.Sprintf("%v", comment.Id), comment.Url, nil
 	}
 
-	// strip first and last lines
-	lines := strings.Split(commentBody, "\n")
-	lines = lines[1 : len(lines)-1]
-	commentBody = strings.Join(lines, "\n")
-
-	commentBody = commentBody + "\n\n" + report + "\n"
-
-	var completeComment string
-	if !supportsCollapsible {
-		completeComment = utils.AsComment(reportTitle)(commentBody)
-	} else {
-		completeComment = utils.AsCollapsibleComment(reportTitle, false)(commentBody)
-	}
+	commentBody = commentBody + "\n\n" + reportFormatter(report) + "\n"
+	completeComment := wrap(commentBody)
 
 	err := ciService.EditComment(PrNumber, commentIdForThisRun, completeComment)
 
@@ -178,8 +188,17 @@ func upsertComment(ciService orchestrator.PullRequestService, PrNumber int, repo
 	return fmt.Sprintf("%v", commentIdForThisRun), commentUrl, nil
 }
 
+func commentWrapper(title string, supportsCollapsible bool) func(string) string {
+	if supportsCollapsible {
+		return utils.AsCollapsibleComment(title, false)
+	}
+	return utils.AsComment(title)
+}
+
 type LatestRunCommentStrategy struct {
 	TimeOfRun time.Time
+	// Overflow keeps the full output of reports truncated to fit the comment
+	Overflow OverflowStorage
 }
 
 func (strategy LatestRunCommentStrategy) Report(ciService orchestrator.PullRequestService, PrNumber int, report string, reportFormatter func(report string) string, supportsCollapsibleComment bool) (string, string, error) {
@@ -189,13 +208,21 @@ func (strategy LatestRunCommentStrategy) Report(ciService orchestrator.PullReque
 	}
 
 	reportTitle := "Digger latest run report"
-	commentId, commentUrl, err := upsertComment(ciService, PrNumber, report, reportFormatter, comments, reportTitle, supportsCollapsibleComment)
+	commentId, commentUrl, err := upsertComment(ciService, PrNumber, report, reportFormatter, comments, reportTitle, supportsCollapsibleComment, strategy.Overflow)
 	return commentId, commentUrl, err
 }
 
-type MultipleCommentsStrategy struct{}
+type MultipleCommentsStrategy struct {
+	// Overflow keeps the full output of reports too long to be split into continuation comments
+	Overflow OverflowStorage
+}
 
 func (strategy MultipleCommentsStrategy) Report(ciService orchestrator.PullRequestService, PrNumber int, report string, reportFormatter func(report string) string, supportsCollapsibleComment bool) (string, string, error) {
+	limit := maxCommentLength(ciService)
+	if len(reportFormatter(report)) > limit {
+		_, err := publishSplitReport(ciService, PrNumber, report, reportFormatter, limit, strategy.Overflow)
+		return "", "", err
+	}
 	_, err := ciService.PublishComment(PrNumber, reportFormatter(report))
 	return "", "", err
 }
@@ -7,6 +7,7 @@ import (
 
 type MockCiService struct {
 	CommentsPerPr map[int][]*orchestrator.Comment
+	CommentLimit  int
 }
 
 func (t MockCiService) GetUserTeams(organisation string, user string) ([]string, error) {
@@ -17,6 +18,14 @@ func (t MockCiService) GetApprovals(prNumber int) ([]string, error) {
 	return []string{}, nil
 }
 
+func (t MockCiService) GetLabels(prNumber int) ([]string, error) {
+	return []string{}, nil
+}
+
+func (t MockCiService) GetPullRequestDetails(prNumber int) (*orchestrator.PullRequestDetails, error) {
+	return &orchestrator.PullRequestDetails{}, nil
+}
+
 func (t MockCiService) GetChangedFiles(prNumber int) ([]string, error) {
 	return nil, nil
 }
@@ -32,9 +41,13 @@ func (t MockCiService) PublishComment(prNumber int, comment string) (*orchestrat
 		}
 	}
 
-	t.CommentsPerPr[prNumber] = append(t.CommentsPerPr[prNumber], &orchestrator.Comment{Id: latestId + 1, Body: &comment})
+	if t.CommentLimit > 0 && len(comment) > t.CommentLimit {
+		return nil, fmt.Errorf("comment is too long")
+	}
+	newComment := &orchestrator.Comment{Id: latestId + 1, Body: &comment, Url: fmt.Sprintf("https://example.com/comments/%v", latestId+1)}
+	t.CommentsPerPr[prNumber] = append(t.CommentsPerPr[prNumber], newComment)
 
-	return nil, nil
+	return newComment, nil
 }
 
 func (t MockCiService) ListIssues() ([]*orchestrator.Issue, error) {
@@ -81,7 +94,14 @@ func (t MockCiService) GetComments(prNumber int) ([]orchestrator.Comment, error)
 	return comments, nil
 }
 
+func (t MockCiService) MaxCommentLength() int {
+	return t.CommentLimit
+}
+
 func (t MockCiService) EditComment(prNumber int, commentId interface{}, comment string) error {
+	if t.CommentLimit > 0 && len(comment) > t.CommentLimit {
+		return fmt.Errorf("comment is too long")
+	}
 	for _, comments := range t.CommentsPerPr {
 		for _, c := range comments {
 			if c.Id == commentId {
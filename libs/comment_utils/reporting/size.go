@@ -0,0 +1,192 @@
+package reporting
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/diggerhq/digger/libs/orchestrator"
+)
+
+// DefaultMaxCommentLength is used for services that do not report a limit, it matches GitHub's limit
+const DefaultMaxCommentLength = 65536
+
+// MaxContinuationComments is the number of comments a report is split into before it is truncated instead
+const MaxContinuationComments = 5
+
+// space kept free for the notes and links added to split or truncated comments
+const commentNotesReserve = 256
+
+var resourceChangeHeader = regexp.MustCompile(`^\s*# \S+.* (will be|must be|has been|has changed)`)
+
+var planSummaryLine = regexp.MustCompile(`^\s*(Plan:|Changes to Outputs:|No changes\.)`)
+
+// OverflowStorage keeps the complete output of reports that do not fit in a comment
+type OverflowStorage interface {
+	// Store saves the output under name and returns a link to it
+	Store(name string, output string) (string, error)
+}
+
+func maxCommentLength(ciService orchestrator.PullRequestService) int {
+	limit := ciService.MaxCommentLength()
+	if limit <= 0 {
+		return DefaultMaxCommentLength
+	}
+	return limit
+}
+
+// overflowNote stores the full output when storage is configured and returns a note pointing at it
+func overflowNote(storage OverflowStorage, output string) string {
+	if storage != nil {
+		hash := sha256.Sum256([]byte(output))
+		link, err := storage.Store("digger-report-"+hex.EncodeToString(hash[:6]), output)
+		if err == nil {
+			return fmt.Sprintf("Full output: %v", link)
+		}
+		log.Printf("Could not store full output of truncated report: %v", err)
+	}
+	return "The full output is available in the job logs."
+}
+
+// TruncatePlanOutput shortens terraform output to at most limit bytes. The lines before the first resource change,
+// the plan summary and the diffs of as many resources as fit are kept, the others are replaced by a line ending in note
+func TruncatePlanOutput(output string, limit int, note string) string {
+	if len(output) <= limit {
+		return output
+	}
+	header, resources, summary := splitPlanOutput(output)
+
+	omittedLine := func(omitted int) string {
+		return fmt.Sprintf("... %v resource changes omitted. %v\n", omitted, note)
+	}
+	// the longest possible omitted line is reserved so that the count can be filled in afterwards
+	budget := limit - len(header) - len(summary) - len(omittedLine(len(resources)))
+	if len(resources) == 0 || budget < 0 {
+		truncatedNote := "\n... output truncated. " + note
+		return cutAtLine(output, limit-len(truncatedNote)) + truncatedNote
+	}
+
+	kept := 0
+	var body strings.Builder
+	for _, resource := range resources {
+		if body.Len()+len(resource) > budget {
+			break
+		}
+		body.WriteString(resource)
+		kept++
+	}
+	return header + body.String() + omittedLine(len(resources)-kept) + summary
+}
+
+// splitPlanOutput splits output into the text before the first resource change, one chunk per resource change and
+// the summary that follows them
+func splitPlanOutput(output string) (string, []string, string) {
+	lines := strings.SplitAfter(output, "\n")
+	var header, summary strings.Builder
+	var resources []string
+	var current *strings.Builder
+	inSummary := false
+	for _, line := range lines {
+		switch {
+		case inSummary:
+			summary.WriteString(line)
+		case planSummaryLine.MatchString(line):
+			inSummary = true
+			summary.WriteString(line)
+		case resourceChangeHeader.MatchString(line):
+			if current != nil {
+				resources = append(resources, current.String())
+			}
+			current = &strings.Builder{}
+			current.WriteString(line)
+		case current != nil:
+			current.WriteString(line)
+		default:
+			header.WriteString(line)
+		}
+	}
+	if current != nil {
+		resources = append(resources, current.String())
+	}
+	return header.String(), resources, summary.String()
+}
+
+// cutAtLine returns the longest prefix of text up to limit bytes, ending at a line break when there is one
+func cutAtLine(text string, limit int) string {
+	if limit <= 0 {
+		return ""
+	}
+	if len(text) <= limit {
+		return text
+	}
+	text = text[:limit]
+	if i := strings.LastIndex(text, "\n"); i > 0 {
+		return text[:i]
+	}
+	for len(text) > 0 && !utf8.ValidString(text) {
+		text = text[:len(text)-1]
+	}
+	return text
+}
+
+// SplitOutput splits output at line breaks into parts of at most limit bytes, longer lines are split on their own
+func SplitOutput(output string, limit int) []string {
+	if limit <= 0 {
+		return []string{output}
+	}
+	var parts []string
+	rest := output
+	for len(rest) > limit {
+		part := cutAtLine(rest, limit)
+		if part == "" {
+			part = rest[:limit]
+		}
+		parts = append(parts, part)
+		rest = strings.TrimPrefix(rest[len(part):], "\n")
+	}
+	return append(parts, rest)
+}
+
+// fitReport truncates report so that the formatted comment is at most limit bytes
+func fitReport(report string, reportFormatter func(report string) string, limit int, storage OverflowStorage) string {
+	if len(reportFormatter(report)) <= limit {
+		return report
+	}
+	note := overflowNote(storage, report)
+	return TruncatePlanOutput(report, limit-len(reportFormatter(""))-commentNotesReserve, note)
+}
+
+// publishSplitReport publishes a report that is too long for one comment as comments linking to the previous part,
+// reports needing more than MaxContinuationComments are truncated instead
+func publishSplitReport(ciService orchestrator.PullRequestService, prNumber int, report string, reportFormatter func(report string) string, limit int, storage OverflowStorage) (*orchestrator.Comment, error) {
+	parts := SplitOutput(report, limit-len(reportFormatter(""))-commentNotesReserve)
+	if len(parts) > MaxContinuationComments {
+		return ciService.PublishComment(prNumber, reportFormatter(fitReport(report, reportFormatter, limit, storage)))
+	}
+
+	var first, previous *orchestrator.Comment
+	for i, part := range parts {
+		comment := reportFormatter(part)
+		if i > 0 && previous != nil && previous.Url != "" {
+			comment = fmt.Sprintf("_Continued from [part %v](%v), part %v of %v_\n\n%v", i, previous.Url, i+1, len(parts), comment)
+		} else if i > 0 {
+			comment = fmt.Sprintf("_Continued from part %v, part %v of %v_\n\n%v", i, i+1, len(parts), comment)
+		}
+		if i < len(parts)-1 {
+			comment = fmt.Sprintf("%v\n\n_Output continues in the next comment_", comment)
+		}
+		published, err := ciService.PublishComment(prNumber, comment)
+		if err != nil {
+			return nil, fmt.Errorf("error publishing part %v of report: %v", i+1, err)
+		}
+		if first == nil {
+			first = published
+		}
+		previous = published
+	}
+	return first, nil
+}
@@ -0,0 +1,117 @@
+package reporting
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/diggerhq/digger/libs/comment_utils/utils"
+	"github.com/diggerhq/digger/libs/orchestrator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockOverflowStorage struct {
+	stored map[string]string
+}
+
+func (m *mockOverflowStorage) Store(name string, output string) (string, error) {
+	m.stored[name] = output
+	return "https://example.com/artifacts/" + name, nil
+}
+
+func planWithResources(count int) string {
+	var plan strings.Builder
+	plan.WriteString("Terraform will perform the following actions:\n\n")
+	for i := 0; i < count; i++ {
+		fmt.Fprintf(&plan, "  # null_resource.r%v will be created\n  + resource \"null_resource\" \"r%v\" {\n      + id = (known after apply)\n    }\n\n", i, i)
+	}
+	fmt.Fprintf(&plan, "Plan: %v to add, 0 to change, 0 to destroy.\n", count)
+	return plan.String()
+}
+
+func TestTruncatePlanOutputKeepsSummaryAndFirstResources(t *testing.T) {
+	plan := planWithResources(50)
+	truncated := TruncatePlanOutput(plan, 1000, "See the logs.")
+
+	assert.LessOrEqual(t, len(truncated), 1000)
+	assert.True(t, strings.HasPrefix(truncated, "Terraform will perform the following actions:"))
+	assert.Contains(t, truncated, "# null_resource.r0 will be created")
+	assert.NotContains(t, truncated, "# null_resource.r49 will be created")
+	assert.Contains(t, truncated, "resource changes omitted. See the logs.")
+	assert.True(t, strings.HasSuffix(truncated, "Plan: 50 to add, 0 to change, 0 to destroy.\n"))
+
+	assert.Equal(t, plan, TruncatePlanOutput(plan, len(plan), "See the logs."))
+}
+
+func TestTruncateOutputWithoutResources(t *testing.T) {
+	output := strings.Repeat("some log line\n", 100)
+	truncated := TruncatePlanOutput(output, 200, "See the logs.")
+	assert.LessOrEqual(t, len(truncated), 200)
+	assert.True(t, strings.HasSuffix(truncated, "... output truncated. See the logs."))
+}
+
+func TestSplitOutput(t *testing.T) {
+	output := strings.Repeat("line\n", 100) + strings.Repeat("x", 120)
+	parts := SplitOutput(output, 50)
+	for _, part := range parts {
+		assert.LessOrEqual(t, len(part), 50)
+	}
+	assert.Equal(t, strings.ReplaceAll(output, "\n", ""), strings.ReplaceAll(strings.Join(parts, ""), "\n", ""))
+}
+
+func TestMultipleCommentsStrategySplitsLongReports(t *testing.T) {
+	ciService := MockCiService{CommentsPerPr: map[int][]*orchestrator.Comment{}, CommentLimit: 2000}
+	formatter := utils.GetTerraformOutputAsCollapsibleComment("Plan output", false)
+	strategy := MultipleCommentsStrategy{}
+
+	_, _, err := strategy.Report(ciService, 1, planWithResources(30), formatter, true)
+	require.NoError(t, err)
+	comments := ciService.CommentsPerPr[1]
+	require.Greater(t, len(comments), 1)
+	assert.Contains(t, *comments[0].Body, "Output continues in the next comment")
+	assert.Contains(t, *comments[1].Body, "_Continued from [part 1](https://example.com/comments/1)")
+	for _, comment := range comments {
+		assert.Contains(t, *comment.Body, "```terraform")
+	}
+}
+
+func TestMultipleCommentsStrategyTruncatesVeryLongReports(t *testing.T) {
+	ciService := MockCiService{CommentsPerPr: map[int][]*orchestrator.Comment{}, CommentLimit: 2000}
+	overflow := &mockOverflowStorage{stored: map[string]string{}}
+	strategy := MultipleCommentsStrategy{Overflow: overflow}
+	plan := planWithResources(500)
+
+	_, _, err := strategy.Report(ciService, 1, plan, utils.GetTerraformOutputAsComment("Plan output"), true)
+	require.NoError(t, err)
+	comments := ciService.CommentsPerPr[1]
+	require.Len(t, comments, 1)
+	assert.Contains(t, *comments[0].Body, "Full output: https://example.com/artifacts/digger-report-")
+	assert.Contains(t, *comments[0].Body, "Plan: 500 to add")
+	require.Len(t, overflow.stored, 1)
+	for _, stored := range overflow.stored {
+		assert.Equal(t, plan, stored)
+	}
+}
+
+func TestCommentPerRunStrategyContinuesFullComments(t *testing.T) {
+	ciService := MockCiService{CommentsPerPr: map[int][]*orchestrator.Comment{}, CommentLimit: 3000}
+	strategy := CommentPerRunStrategy{Title: "Plans", TimeOfRun: time.Now()}
+	formatter := utils.GetTerraformOutputAsCollapsibleComment("Plan output", false)
+
+	for i := 0; i < 4; i++ {
+		_, _, err := strategy.Report(ciService, 1, planWithResources(15), formatter, true)
+		require.NoError(t, err)
+	}
+	comments := ciService.CommentsPerPr[1]
+	require.Greater(t, len(comments), 1)
+	assert.NotContains(t, *comments[0].Body, "(continued)")
+	for _, comment := range comments[1:] {
+		assert.Contains(t, *comment.Body, "(continued)")
+		assert.NotContains(t, *comment.Body, "(continued) (continued)")
+	}
+	for _, comment := range comments {
+		assert.LessOrEqual(t, len(*comment.Body), 3000)
+	}
+}
@@ -3,7 +3,7 @@ package reporting
 import (
 	"encoding/json"
 	"fmt"
-	"github.com/diggerhq/digger/libs/comment_utils/utils"
+	"github.com/diggerhq/digger/libs/comment_utils/templates"
 	"github.com/diggerhq/digger/libs/digger_config"
 	"github.com/diggerhq/digger/libs/orchestrator"
 	"github.com/diggerhq/digger/libs/orchestrator/scheduler"
@@ -25,6 +25,8 @@ type SourceGroupingReporter struct {
 	Jobs      []scheduler.SerializedJob
 	PrNumber  int
 	PrService orchestrator.PullRequestService
+	// Templates render the comments, the defaults are used when nil
+	Templates *templates.Templates
 }
 
 func (r SourceGroupingReporter) UpdateComment(sourceDetails []SourceDetails, location string, terraformOutputs map[string]string) error {
@@ -77,7 +79,14 @@ func (r SourceGroupingReporter) UpdateComment(sourceDetails []SourceDetails, loc
 			continue
 		}
 		expanded := i == 0 || !allSimilarInGroup
-		commenter := utils.GetTerraformOutputAsCollapsibleComment(fmt.Sprintf("Plan for %v", project), expanded)
+		commenter := templates.OrDefaults(r.Templates).Formatter(templates.Plan, templates.CommentData{
+			Project:  project,
+			Title:    fmt.Sprintf("Plan for %v", project),
+			Code:     true,
+			Markdown: true,
+			Open:     expanded,
+			Summary:  &templates.ChangeSummary{Created: job.ResourcesCreated, Updated: job.ResourcesUpdated, Deleted: job.ResourcesDeleted},
+		})
 		message = message + commenter(terraformOutputs[project]) + "\n"
 	}
 
@@ -0,0 +1,30 @@
+package reporting
+
+import (
+	"testing"
+
+	"github.com/diggerhq/digger/libs/comment_utils/templates"
+	"github.com/diggerhq/digger/libs/orchestrator"
+	"github.com/diggerhq/digger/libs/orchestrator/scheduler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSourceGroupingReporterUsesTemplates(t *testing.T) {
+	body := ""
+	comment := &orchestrator.Comment{Id: int64(7), Body: &body}
+	ciService := MockCiService{CommentsPerPr: map[int][]*orchestrator.Comment{1: {comment}}}
+	commentTemplates, err := templates.Parse(map[string]string{templates.Plan: "{{.Project}}: {{.Output}}"})
+	require.NoError(t, err)
+
+	reporter := SourceGroupingReporter{
+		Jobs:      []scheduler.SerializedJob{{ProjectName: "dev", Status: scheduler.DiggerJobSucceeded}},
+		PrNumber:  1,
+		PrService: ciService,
+		Templates: commentTemplates,
+	}
+	sourceDetails := []SourceDetails{{SourceLocation: "modules/app", CommentId: "7", Projects: []string{"dev"}}}
+	err = reporter.UpdateComment(sourceDetails, "modules/app", map[string]string{"dev": "no changes"})
+	require.NoError(t, err)
+	assert.Equal(t, "# Group: modules/app (similar: true)\ndev: no changes\n", *comment.Body)
+}
@@ -28,7 +28,7 @@ func PostInitialSourceComments(ghService *dg_github.GithubService, prNumber int,
 		reporter := CiReporter{
 			PrNumber:       prNumber,
 			CiService:      ghService,
-			ReportStrategy: CommentPerRunStrategy{fmt.Sprintf("Report for location: %v", location), time.Now()},
+			ReportStrategy: CommentPerRunStrategy{Title: fmt.Sprintf("Report for location: %v", location), TimeOfRun: time.Now()},
 		}
 		commentId, _, err := reporter.Report("Comment Reporter", func(report string) string { return "" })
 		if err != nil {
@@ -2,21 +2,22 @@ package comment_updater
 
 import (
 	"fmt"
+	"github.com/diggerhq/digger/libs/comment_utils/templates"
 	"github.com/diggerhq/digger/libs/digger_config"
 )
 
 type CommentUpdaterProvider interface {
-	Get(config digger_config.DiggerConfig) (CommentUpdater, error)
+	Get(config digger_config.DiggerConfig, commentTemplates *templates.Templates) (CommentUpdater, error)
 }
 
 type CommentUpdaterProviderBasic struct{}
 
-func (c CommentUpdaterProviderBasic) Get(config digger_config.DiggerConfig) (CommentUpdater, error) {
+func (c CommentUpdaterProviderBasic) Get(config digger_config.DiggerConfig, commentTemplates *templates.Templates) (CommentUpdater, error) {
 	if config.CommentRenderMode == digger_config.CommentRenderModeBasic {
-		return BasicCommentUpdater{}, nil
+		return BasicCommentUpdater{Templates: commentTemplates}, nil
 	} else if config.CommentRenderMode == digger_config.CommentRenderModeGroupByModule {
 
-		commentUpdater := BasicCommentUpdater{}
+		commentUpdater := BasicCommentUpdater{Templates: commentTemplates}
 		return commentUpdater, nil
 	} else {
 		return nil, fmt.Errorf("Unknown comment render mode found: %v", config.CommentRenderMode)
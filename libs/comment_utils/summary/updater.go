@@ -1,7 +1,7 @@
 package comment_updater
 
 import (
-	"fmt"
+	"github.com/diggerhq/digger/libs/comment_utils/templates"
 	"github.com/diggerhq/digger/libs/orchestrator"
 	"github.com/diggerhq/digger/libs/orchestrator/scheduler"
 	"golang.org/x/text/cases"
@@ -14,6 +14,8 @@ type CommentUpdater interface {
 }
 
 type BasicCommentUpdater struct {
+	// Templates render the summary, the defaults are used when nil
+	Templates *templates.Templates
 }
 
 func (b BasicCommentUpdater) UpdateComment(jobs []scheduler.SerializedJob, prNumber int, prService orchestrator.PullRequestService, prCommentId int64) error {
@@ -26,23 +28,26 @@ func (b BasicCommentUpdater) UpdateComment(jobs []scheduler.SerializedJob, prNum
 	jobType := firstJobSpec.JobType
 	isPlan := jobType == string(orchestrator.DiggerCommandPlan)
 	jobTypeTitle := cases.Title(language.AmericanEnglish).String(string(jobType))
-	message := ""
-	if isPlan {
-		message = message + fmt.Sprintf("| Project | Status | %v | + | ~ | - |\n", jobTypeTitle)
-		message = message + fmt.Sprintf("|---------|--------|------|---|---|---|\n")
-	} else {
-		message = message + fmt.Sprintf("| Project | Status | %v |\n", jobTypeTitle)
-		message = message + fmt.Sprintf("|---------|--------|-------|\n")
-	}
+	summary := templates.SummaryData{JobType: jobTypeTitle, IsPlan: isPlan}
 	for i, job := range jobs {
 		jobSpec := jobSpecs[i]
-		prCommentUrl := job.PRCommentUrl
-		if isPlan {
-			message = message + fmt.Sprintf("|%v **%v** |<a href='%v'>%v</a> | <a href='%v'>%v</a> | %v | %v | %v|\n", job.Status.ToEmoji(), jobSpec.ProjectName, *job.WorkflowRunUrl, job.Status.ToString(), prCommentUrl, jobTypeTitle, job.ResourcesCreated, job.ResourcesUpdated, job.ResourcesDeleted)
-		} else {
-			message = message + fmt.Sprintf("|%v **%v** |<a href='%v'>%v</a> | <a href='%v'>%v</a> |\n", job.Status.ToEmoji(), jobSpec.ProjectName, *job.WorkflowRunUrl, job.Status.ToString(), prCommentUrl, jobTypeTitle)
+		workflowUrl := ""
+		if job.WorkflowRunUrl != nil {
+			workflowUrl = *job.WorkflowRunUrl
 		}
+		summary.Projects = append(summary.Projects, templates.ProjectSummary{
+			Name:        jobSpec.ProjectName,
+			Status:      job.Status.ToString(),
+			StatusEmoji: job.Status.ToEmoji(),
+			Links:       templates.Links{Workflow: workflowUrl, Comment: job.PRCommentUrl},
+			ChangeSummary: templates.ChangeSummary{
+				Created: job.ResourcesCreated,
+				Updated: job.ResourcesUpdated,
+				Deleted: job.ResourcesDeleted,
+			},
+		})
 	}
+	message := templates.OrDefaults(b.Templates).RenderSummary(summary)
 
 	prService.EditComment(prNumber, prCommentId, message)
 	return nil
@@ -0,0 +1,37 @@
+package templates
+
+// defaultCommentLayout matches the layouts of the comment_utils/utils formatters
+const defaultCommentLayout = `
+{{- if and .Markdown .Code -}}
+<details {{if .Open}}open="true"{{end}}><summary>{{.Title}}</summary>
+
+` + "```terraform" + `
+{{.Output}}
+` + "```" + `
+</details>
+{{- else if .Markdown -}}
+<details><summary>{{.Title}}</summary>
+  {{.Output}}
+</details>
+{{- else if .Code -}}
+{{.Title}}
+` + "```terraform" + `
+{{.Output}}
+` + "```" + `
+{{- else -}}
+{{.Title}}
+{{.Output}}
+{{- end -}}`
+
+const defaultSummaryLayout = `
+{{- if .IsPlan -}}
+| Project | Status | {{.JobType}} | + | ~ | - |
+|---------|--------|------|---|---|---|
+{{range .Projects}}|{{.StatusEmoji}} **{{.Name}}** |<a href='{{.Links.Workflow}}'>{{.Status}}</a> | <a href='{{.Links.Comment}}'>{{$.JobType}}</a> | {{.Created}} | {{.Updated}} | {{.Deleted}}|
+{{end}}
+{{- else -}}
+| Project | Status | {{.JobType}} |
+|---------|--------|-------|
+{{range .Projects}}|{{.StatusEmoji}} **{{.Name}}** |<a href='{{.Links.Workflow}}'>{{.Status}}</a> | <a href='{{.Links.Comment}}'>{{$.JobType}}</a> |
+{{end}}
+{{- end -}}`
@@ -0,0 +1,251 @@
+// Package templates renders pull request comments from text/template templates. Repositories can replace the
+// built-in layouts by adding .mantis/templates/<name>.tmpl files for the plan, apply, error, lock and summary
+// comments, or inline in the comment_templates of mantis.yml, which is all the backend sees of a repository.
+// The summary template is executed with SummaryData, all others with CommentData.
+package templates
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// Dir is where repositories keep their comment templates
+const Dir = ".mantis/templates"
+
+const (
+	Plan    = "plan"
+	Apply   = "apply"
+	Error   = "error"
+	Lock    = "lock"
+	Summary = "summary"
+)
+
+// CommentData is the data model of the plan, apply, error and lock templates
+type CommentData struct {
+	Project string
+	// Title is the heading digger would use for the comment, e.g. "Plan output" or "Locking successful"
+	Title string
+	// Output is the reported text: terraform output, an error or a lock message
+	Output string
+	// Code is set when Output is terraform output that is usually shown as a code block
+	Code bool
+	// Markdown is set when the VCS renders markdown and collapsible sections
+	Markdown bool
+	// Open is set when collapsible sections should be expanded
+	Open bool
+	// Summary counts the resource changes of a plan, it is nil when no plan summary is available
+	Summary          *ChangeSummary
+	PolicyViolations []string
+	Links            Links
+}
+
+type ChangeSummary struct {
+	Created uint
+	Updated uint
+	Deleted uint
+}
+
+type Links struct {
+	// Workflow is the CI job that produced the comment
+	Workflow string
+	// Comment is the pull request comment holding the project's output
+	Comment string
+}
+
+// SummaryData is the data model of the summary template, the comment listing the status of every job of a run
+type SummaryData struct {
+	// JobType is the title cased command, e.g. "Plan"
+	JobType  string
+	IsPlan   bool
+	Projects []ProjectSummary
+}
+
+type ProjectSummary struct {
+	Name        string
+	Status      string
+	StatusEmoji string
+	Links       Links
+	ChangeSummary
+}
+
+// Templates holds the comment templates of a repository, names without a repository template use the defaults
+type Templates struct {
+	templates map[string]*template.Template
+}
+
+var names = []string{Plan, Apply, Error, Lock, Summary}
+
+// Defaults returns the built-in layouts
+func Defaults() *Templates {
+	t := &Templates{templates: map[string]*template.Template{}}
+	for _, name := range names {
+		layout := defaultCommentLayout
+		if name == Summary {
+			layout = defaultSummaryLayout
+		}
+		t.templates[name] = template.Must(template.New(name).Parse(layout))
+	}
+	return t
+}
+
+// Parse returns the defaults with the named templates replaced, e.g. by the comment_templates of mantis.yml
+func Parse(sources map[string]string) (*Templates, error) {
+	t := Defaults()
+	err := t.parseAll(sources, func(name string) string { return name })
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Load reads the templates in the Dir of repoDir on top of the configured ones, a missing directory gives the
+// configured templates or the defaults
+func Load(repoDir string, configured map[string]string) (*Templates, error) {
+	t := Defaults()
+	err := t.parseAll(configured, func(name string) string { return name })
+	if err != nil {
+		return nil, err
+	}
+	files, err := filepath.Glob(filepath.Join(repoDir, Dir, "*.tmpl"))
+	if err != nil {
+		return nil, err
+	}
+	sources := map[string]string{}
+	fileNames := map[string]string{}
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".tmpl")
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("could not read comment template %v: %v", file, err)
+		}
+		sources[name] = string(content)
+		fileNames[name] = file
+	}
+	err = t.parseAll(sources, func(name string) string { return fileNames[name] })
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// parseAll replaces the templates named in sources and validates the result, source names templates in errors
+func (t *Templates) parseAll(sources map[string]string, source func(name string) string) error {
+	sortedNames := make([]string, 0, len(sources))
+	for name := range sources {
+		sortedNames = append(sortedNames, name)
+	}
+	sort.Strings(sortedNames)
+	for _, name := range sortedNames {
+		if !isKnown(name) {
+			return fmt.Errorf("unknown comment template %v, expected one of %v", source(name), strings.Join(names, ", "))
+		}
+		parsed, err := template.New(name).Parse(sources[name])
+		if err != nil {
+			return fmt.Errorf("could not parse comment template %v: %v", source(name), err)
+		}
+		t.templates[name] = parsed
+	}
+	return t.Validate()
+}
+
+// Validate renders every template with sample data, catching references to fields outside of the data model
+func (t *Templates) Validate() error {
+	for _, name := range names {
+		_, err := t.Render(name, sampleData(name))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *Templates) Render(name string, data interface{}) (string, error) {
+	tmpl, ok := t.templates[name]
+	if !ok {
+		return "", fmt.Errorf("unknown comment template %v", name)
+	}
+	var out bytes.Buffer
+	err := tmpl.Execute(&out, data)
+	if err != nil {
+		return "", fmt.Errorf("could not render %v comment template: %v", name, err)
+	}
+	return out.String(), nil
+}
+
+// Formatter returns a report formatter rendering the named template, the reported text is passed as data.Output
+func (t *Templates) Formatter(name string, data CommentData) func(string) string {
+	return func(output string) string {
+		data.Output = output
+		return t.renderOrDefault(name, data)
+	}
+}
+
+// RenderSummary renders the summary comment of a run
+func (t *Templates) RenderSummary(data SummaryData) string {
+	return t.renderOrDefault(Summary, data)
+}
+
+func (t *Templates) renderOrDefault(name string, data interface{}) string {
+	comment, err := t.Render(name, data)
+	if err != nil {
+		log.Printf("%v, using the default template", err)
+		comment, _ = Defaults().Render(name, data)
+	}
+	return comment
+}
+
+// OrDefaults returns t, or the built-in layouts when t is nil
+func OrDefaults(t *Templates) *Templates {
+	if t == nil {
+		return Defaults()
+	}
+	return t
+}
+
+// LoadOrDefaults loads the templates of repoDir like Load, invalid templates are logged and the defaults are used
+func LoadOrDefaults(repoDir string, configured map[string]string) *Templates {
+	t, err := Load(repoDir, configured)
+	if err != nil {
+		log.Printf("Invalid comment templates, using the defaults: %v", err)
+		return Defaults()
+	}
+	return t
+}
+
+func isKnown(name string) bool {
+	for _, known := range names {
+		if name == known {
+			return true
+		}
+	}
+	return false
+}
+
+func sampleData(name string) interface{} {
+	links := Links{Workflow: "https://example.com/run", Comment: "https://example.com/comment"}
+	if name == Summary {
+		return SummaryData{
+			JobType: "Plan",
+			IsPlan:  true,
+			Projects: []ProjectSummary{
+				{Name: "project", Status: "succeeded", StatusEmoji: ":white_check_mark:", Links: links, ChangeSummary: ChangeSummary{Created: 1}},
+			},
+		}
+	}
+	return CommentData{
+		Project:          "project",
+		Title:            "Title",
+		Output:           "output",
+		Code:             true,
+		Markdown:         true,
+		Summary:          &ChangeSummary{Created: 1},
+		PolicyViolations: []string{"violation"},
+		Links:            links,
+	}
+}
@@ -0,0 +1,119 @@
+package templates
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/diggerhq/digger/libs/comment_utils/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTemplate(t *testing.T, dir string, name string, content string) {
+	templatesDir := filepath.Join(dir, Dir)
+	require.NoError(t, os.MkdirAll(templatesDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(templatesDir, name), []byte(content), 0644))
+}
+
+func TestDefaultsMatchBuiltInFormatters(t *testing.T) {
+	defaults := Defaults()
+	output := "Plan: 1 to add, 0 to change, 0 to destroy."
+
+	cases := []struct {
+		data     CommentData
+		expected func(string) string
+	}{
+		{CommentData{Title: "Plan output", Code: true, Markdown: true, Open: true}, utils.GetTerraformOutputAsCollapsibleComment("Plan output", true)},
+		{CommentData{Title: "Apply output", Code: true, Markdown: true}, utils.GetTerraformOutputAsCollapsibleComment("Apply output", false)},
+		{CommentData{Title: "Plan output", Code: true}, utils.GetTerraformOutputAsComment("Plan output")},
+		{CommentData{Title: "Locking successful", Markdown: true}, utils.AsCollapsibleComment("Locking successful", false)},
+		{CommentData{Title: "Locking successful"}, utils.AsComment("Locking successful")},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.expected(output), defaults.Formatter(Plan, c.data)(output))
+	}
+}
+
+func TestDefaultSummary(t *testing.T) {
+	summary := Defaults().RenderSummary(SummaryData{
+		JobType: "Plan",
+		IsPlan:  true,
+		Projects: []ProjectSummary{
+			{Name: "dev", Status: "succeeded", StatusEmoji: ":white_check_mark:", Links: Links{Workflow: "https://run", Comment: "https://comment"}, ChangeSummary: ChangeSummary{Created: 1, Deleted: 2}},
+		},
+	})
+	expected := "| Project | Status | Plan | + | ~ | - |\n" +
+		"|---------|--------|------|---|---|---|\n" +
+		"|:white_check_mark: **dev** |<a href='https://run'>succeeded</a> | <a href='https://comment'>Plan</a> | 1 | 0 | 2|\n"
+	assert.Equal(t, expected, summary)
+}
+
+func TestLoadRepositoryTemplates(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, "plan.tmpl", "### {{.Project}}{{if .Summary}} (+{{.Summary.Created}}){{end}}\n{{range .PolicyViolations}}- {{.}}\n{{end}}{{.Output}}")
+
+	loaded, err := Load(dir, nil)
+	require.NoError(t, err)
+	comment := loaded.Formatter(Plan, CommentData{Project: "dev", Summary: &ChangeSummary{Created: 3}, PolicyViolations: []string{"no public buckets"}})("plan")
+	assert.Equal(t, "### dev (+3)\n- no public buckets\nplan", comment)
+
+	// templates without a repository file keep the defaults
+	assert.Equal(t, utils.AsComment("Locking successful")("locked"), loaded.Formatter(Lock, CommentData{Title: "Locking successful"})("locked"))
+}
+
+func TestConfiguredTemplates(t *testing.T) {
+	configured := map[string]string{Plan: "config {{.Output}}", Lock: "lock {{.Output}}"}
+	parsed, err := Parse(configured)
+	require.NoError(t, err)
+	assert.Equal(t, "config plan", parsed.Formatter(Plan, CommentData{})("plan"))
+
+	// files in the templates directory take precedence over the configured templates
+	dir := t.TempDir()
+	writeTemplate(t, dir, "plan.tmpl", "file {{.Output}}")
+	loaded, err := Load(dir, configured)
+	require.NoError(t, err)
+	assert.Equal(t, "file plan", loaded.Formatter(Plan, CommentData{})("plan"))
+	assert.Equal(t, "lock locked", loaded.Formatter(Lock, CommentData{})("locked"))
+
+	_, err = Parse(map[string]string{"deploy": "{{.Output}}"})
+	assert.ErrorContains(t, err, "unknown comment template deploy")
+}
+
+func TestLoadWithoutTemplatesDir(t *testing.T) {
+	loaded, err := Load(t.TempDir(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, utils.AsComment("Apply error")("x"), loaded.Formatter(Error, CommentData{Title: "Apply error"})("x"))
+}
+
+func TestLoadRejectsInvalidTemplates(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, "plan.tmpl", "{{.Output")
+	_, err := Load(dir, nil)
+	assert.ErrorContains(t, err, "could not parse comment template")
+
+	dir = t.TempDir()
+	writeTemplate(t, dir, "summary.tmpl", "{{.Unknown}}")
+	_, err = Load(dir, nil)
+	assert.ErrorContains(t, err, "could not render summary comment template")
+
+	dir = t.TempDir()
+	writeTemplate(t, dir, "deploy.tmpl", "{{.Output}}")
+	_, err = Load(dir, nil)
+	assert.ErrorContains(t, err, "unknown comment template")
+}
+
+func TestRenderErrorsFallBackToDefaults(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, "apply.tmpl", "{{if .Summary}}{{.Summary.Created}}{{end}}{{.Output}}")
+	loaded, err := Load(dir, nil)
+	require.NoError(t, err)
+
+	// a nil summary is fine for a template that checks it, a template dereferencing it would fall back
+	assert.Equal(t, "done", loaded.Formatter(Apply, CommentData{})("done"))
+
+	writeTemplate(t, dir, "apply.tmpl", "{{.Summary.Created}}")
+	loaded, err = Load(dir, nil)
+	require.NoError(t, err)
+	assert.Equal(t, utils.GetTerraformOutputAsComment("Apply output")("done"), loaded.Formatter(Apply, CommentData{Title: "Apply output", Code: true})("done"))
+}
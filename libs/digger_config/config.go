@@ -1,5 +1,11 @@
 package digger_config
 
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
 const CommentRenderModeBasic = "basic"
 const CommentRenderModeGroupByModule = "group_by_module"
 
@@ -15,8 +21,72 @@ type DiggerConfig struct {
 	Workflows                  map[string]Workflow
 	MentionDriftedProjectsInPR bool
 	TraverseToNestedProjects   bool
+	Hooks                      Hooks
+	ProviderCache              ProviderCache
+	Notifications              Notifications
+	ReportingCheckRuns         bool
+}
+
+type ProviderCache struct {
+	Enabled bool
+	// shared TF_PLUGIN_CACHE_DIR, defaults to DefaultProviderCacheDir when empty
+	Dir     string
+	Upgrade bool
+	// filesystem mirror init installs providers from, prepared ahead of time for offline runners
+	MirrorDir       string
+	MirrorPlatforms []string
+}
+
+// DefaultProviderCacheDir is a per user directory so that the cache survives across jobs on the same runner
+func DefaultProviderCacheDir() string {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		cacheDir = os.TempDir()
+	}
+	return filepath.Join(cacheDir, "mantis", "plugin-cache")
 }
 
+type Hooks struct {
+	PrePlan   []Step
+	PostPlan  []Step
+	PreApply  []Step
+	PostApply []Step
+	OnFailure []Step
+}
+
+type Notifications struct {
+	Sinks  map[string]NotificationSink
+	Routes []NotificationRoute
+}
+
+type NotificationSink struct {
+	Type        string
+	UrlEnv      string
+	SecretEnv   string
+	SmtpHost    string
+	SmtpPort    int
+	UsernameEnv string
+	PasswordEnv string
+	From        string
+	To          []string
+}
+
+type NotificationRoute struct {
+	Projects []string
+	Events   []string
+	Sinks    []string
+}
+
+const (
+	NotificationSinkSlack   = "slack"
+	NotificationSinkTeams   = "teams"
+	NotificationSinkWebhook = "webhook"
+	NotificationSinkEmail   = "email"
+)
+
+// NotificationEvents are the event types routes can select
+var NotificationEvents = []string{"plan_failed", "apply_succeeded", "apply_failed", "lock_stolen", "policy_denied"}
+
 type DependencyConfiguration struct {
 	Mode string
 }
@@ -40,6 +110,24 @@ type Project struct {
 	DependencyProjects []string
 	DriftDetection     bool
 	AwsRoleToAssume    *AssumeRoleForProject
+	// EphemeralWorkspace runs the commands of each pull request in its own pr-N workspace, a preview that is destroyed
+	// when the pull request is closed. Once merged the on_commit_to_default commands run in Workspace, they never use
+	// the plans of pr-N which were made against the state of the preview
+	EphemeralWorkspace bool
+	TerragruntRunAll   bool
+}
+
+// EphemeralWorkspaceName is the name of the workspace a pull request gets for projects with ephemeral_workspace enabled
+func EphemeralWorkspaceName(prNumber int) string {
+	return fmt.Sprintf("pr-%d", prNumber)
+}
+
+// WorkspaceForPullRequest returns the workspace the project should run in for the given pull request
+func (p Project) WorkspaceForPullRequest(prNumber *int) string {
+	if p.EphemeralWorkspace && prNumber != nil {
+		return EphemeralWorkspaceName(*prNumber)
+	}
+	return p.Workspace
 }
 
 type Workflow struct {
@@ -57,8 +145,10 @@ type WorkflowConfiguration struct {
 }
 
 type TerraformEnvConfig struct {
-	State    []EnvVar
-	Commands []EnvVar
+	State     []EnvVar
+	Commands  []EnvVar
+	Sanitize  bool
+	Allowlist []string
 }
 
 type EnvVar struct {
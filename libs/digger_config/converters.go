@@ -47,6 +47,16 @@ func copyProjects(projects []*ProjectYaml) []Project {
 			}
 		}
 
+		ephemeralWorkspace := false
+		if p.EphemeralWorkspace != nil {
+			ephemeralWorkspace = *p.EphemeralWorkspace
+		}
+
+		terragruntRunAll := false
+		if p.TerragruntRunAll != nil {
+			terragruntRunAll = *p.TerragruntRunAll
+		}
+
 		workflowFile := "mantis_workflow.yml"
 		if p.WorkflowFile != nil {
 			workflowFile = *p.WorkflowFile
@@ -64,6 +74,8 @@ func copyProjects(projects []*ProjectYaml) []Project {
 			p.DependencyProjects,
 			driftDetection,
 			roleToAssume,
+			ephemeralWorkspace,
+			terragruntRunAll,
 		}
 		result[i] = item
 	}
@@ -95,6 +107,11 @@ func copyTerraformEnvConfig(terraformEnvConfig *TerraformEnvConfigYaml) *Terrafo
 		result.Commands[i] = item
 	}
 
+	if terraformEnvConfig.Sanitize != nil {
+		result.Sanitize = *terraformEnvConfig.Sanitize
+	}
+	result.Allowlist = terraformEnvConfig.Allowlist
+
 	return &result
 }
 
@@ -147,6 +164,69 @@ func copyWorkflows(workflows map[string]*WorkflowYaml) map[string]Workflow {
 	return result
 }
 
+func copySteps(steps []StepYaml) []Step {
+	result := make([]Step, 0, len(steps))
+	for _, step := range steps {
+		result = append(result, step.ToCoreStep())
+	}
+	return result
+}
+
+func copyHooks(hooks *HooksYaml) Hooks {
+	return Hooks{
+		PrePlan:   copySteps(hooks.PrePlan),
+		PostPlan:  copySteps(hooks.PostPlan),
+		PreApply:  copySteps(hooks.PreApply),
+		PostApply: copySteps(hooks.PostApply),
+		OnFailure: copySteps(hooks.OnFailure),
+	}
+}
+
+func (n *NotificationsYaml) ToNotifications() Notifications {
+	result := Notifications{Sinks: map[string]NotificationSink{}}
+	for name, sink := range n.Sinks {
+		result.Sinks[name] = NotificationSink{
+			Type:        sink.Type,
+			UrlEnv:      sink.UrlEnv,
+			SecretEnv:   sink.SecretEnv,
+			SmtpHost:    sink.SmtpHost,
+			SmtpPort:    sink.SmtpPort,
+			UsernameEnv: sink.UsernameEnv,
+			PasswordEnv: sink.PasswordEnv,
+			From:        sink.From,
+			To:          sink.To,
+		}
+	}
+	for _, route := range n.Routes {
+		result.Routes = append(result.Routes, NotificationRoute{
+			Projects: route.Projects,
+			Events:   route.Events,
+			Sinks:    route.Sinks,
+		})
+	}
+	return result
+}
+
+func copyProviderCache(providerCache *ProviderCacheYaml) ProviderCache {
+	if providerCache == nil {
+		return ProviderCache{Enabled: false, Upgrade: true}
+	}
+	result := ProviderCache{
+		Enabled:         true,
+		Dir:             providerCache.Dir,
+		Upgrade:         true,
+		MirrorDir:       providerCache.MirrorDir,
+		MirrorPlatforms: providerCache.MirrorPlatforms,
+	}
+	if providerCache.Enabled != nil {
+		result.Enabled = *providerCache.Enabled
+	}
+	if providerCache.Upgrade != nil {
+		result.Upgrade = *providerCache.Upgrade
+	}
+	return result
+}
+
 func ConvertDiggerYamlToConfig(diggerYaml *DiggerConfigYaml) (*DiggerConfig, graph.Graph[string, Project], error) {
 	var diggerConfig DiggerConfig
 
@@ -178,6 +258,12 @@ func ConvertDiggerYamlToConfig(diggerYaml *DiggerConfigYaml) (*DiggerConfig, gra
 		diggerConfig.CommentRenderMode = CommentRenderModeBasic
 	}
 
+	if diggerYaml.ReportingCheckRuns != nil {
+		diggerConfig.ReportingCheckRuns = *diggerYaml.ReportingCheckRuns
+	} else {
+		diggerConfig.ReportingCheckRuns = false
+	}
+
 	if diggerYaml.MentionDriftedProjectsInPR != nil {
 		diggerConfig.MentionDriftedProjectsInPR = *diggerYaml.MentionDriftedProjectsInPR
 	} else {
@@ -208,6 +294,16 @@ func ConvertDiggerYamlToConfig(diggerYaml *DiggerConfigYaml) (*DiggerConfig, gra
 		diggerConfig.AllowDraftPRs = false
 	}
 
+	if diggerYaml.Hooks != nil {
+		diggerConfig.Hooks = copyHooks(diggerYaml.Hooks)
+	}
+
+	diggerConfig.ProviderCache = copyProviderCache(diggerYaml.ProviderCache)
+
+	if diggerYaml.Notifications != nil {
+		diggerConfig.Notifications = diggerYaml.Notifications.ToNotifications()
+	}
+
 	// if workflow block is not specified in yaml we create a default one, and add it to every project
 	if diggerYaml.Workflows != nil {
 		workflows := copyWorkflows(diggerYaml.Workflows)
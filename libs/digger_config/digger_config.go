@@ -373,6 +373,9 @@ func ValidateDiggerConfig(config *DiggerConfig) error {
 		if !ok {
 			return fmt.Errorf("failed to find workflow digger_config '%s' for project '%s'", p.Workflow, p.Name)
 		}
+		if p.TerragruntRunAll && !p.Terragrunt {
+			return fmt.Errorf("project '%s': terragrunt_run_all requires terragrunt to be enabled", p.Name)
+		}
 	}
 
 	for _, w := range config.Workflows {
@@ -390,6 +393,65 @@ func ValidateDiggerConfig(config *DiggerConfig) error {
 			}
 		}
 	}
+
+	for name, w := range config.Workflows {
+		if w.EnvVars != nil && !w.EnvVars.Sanitize && len(w.EnvVars.Allowlist) > 0 {
+			return fmt.Errorf("workflow '%s': env_vars.allowlist requires env_vars.sanitize to be enabled", name)
+		}
+	}
+
+	hooks := map[string][]Step{
+		"pre_plan":   config.Hooks.PrePlan,
+		"post_plan":  config.Hooks.PostPlan,
+		"pre_apply":  config.Hooks.PreApply,
+		"post_apply": config.Hooks.PostApply,
+		"on_failure": config.Hooks.OnFailure,
+	}
+	for event, steps := range hooks {
+		for _, s := range steps {
+			if s.Action != "run" {
+				return fmt.Errorf("%v hook: only run steps are supported, got '%v'", event, s.Action)
+			}
+		}
+	}
+	return ValidateNotifications(config.Notifications)
+}
+
+func ValidateNotifications(notifications Notifications) error {
+	for name, sink := range notifications.Sinks {
+		switch sink.Type {
+		case NotificationSinkSlack, NotificationSinkTeams, NotificationSinkWebhook:
+			if sink.UrlEnv == "" {
+				return fmt.Errorf("notification sink '%s': url_env is required for %v sinks", name, sink.Type)
+			}
+		case NotificationSinkEmail:
+			if sink.SmtpHost == "" || sink.From == "" || len(sink.To) == 0 {
+				return fmt.Errorf("notification sink '%s': smtp_host, from and to are required for email sinks", name)
+			}
+		default:
+			return fmt.Errorf("notification sink '%s': unknown type '%v', expecting %v, %v, %v or %v", name, sink.Type, NotificationSinkSlack, NotificationSinkTeams, NotificationSinkWebhook, NotificationSinkEmail)
+		}
+	}
+	for i, route := range notifications.Routes {
+		if len(route.Sinks) == 0 {
+			return fmt.Errorf("notification route %v: at least one sink is required", i)
+		}
+		for _, sink := range route.Sinks {
+			if _, ok := notifications.Sinks[sink]; !ok {
+				return fmt.Errorf("notification route %v: unknown sink '%v'", i, sink)
+			}
+		}
+		for _, event := range route.Events {
+			if !lo.Contains(NotificationEvents, event) {
+				return fmt.Errorf("notification route %v: unknown event '%v', expecting one of %v", i, event, strings.Join(NotificationEvents, ", "))
+			}
+		}
+		for _, pattern := range route.Projects {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return fmt.Errorf("notification route %v: invalid project pattern '%v': %v", i, pattern, err)
+			}
+		}
+	}
 	return nil
 }
 
@@ -639,6 +701,24 @@ func isFileExists(path string) bool {
 	return !fi.IsDir()
 }
 
+// ReadCommentTemplates returns the comment_templates of the config file in workingDir, nil when there is none
+func ReadCommentTemplates(workingDir string) map[string]string {
+	fileName, err := retrieveConfigFile(workingDir)
+	if err != nil || fileName == "" {
+		return nil
+	}
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil
+	}
+	configYaml, err := LoadDiggerConfigYamlFromString(string(content))
+	if err != nil {
+		log.Printf("could not read comment templates of %v: %v", fileName, err)
+		return nil
+	}
+	return configYaml.CommentTemplates
+}
+
 func retrieveConfigFile(workingDir string) (string, error) {
 	var fileName string = "mantis"
 	customConfigFile := os.Getenv("DIGGER_FILENAME") != ""
@@ -674,6 +754,18 @@ func retrieveConfigFile(workingDir string) (string, error) {
 	return "", nil
 }
 
+// DefaultEnvAllowlist is always passed through to sanitized commands so that tools can still be found and run
+var DefaultEnvAllowlist = []string{"PATH", "HOME", "USER", "SHELL", "TMPDIR", "LANG", "TERM"}
+
+// CollectEnvAllowlist returns the runner env vars commands are allowed to see, nil means the whole environment is inherited
+func CollectEnvAllowlist(envs *TerraformEnvConfig) []string {
+	if envs == nil || !envs.Sanitize {
+		return nil
+	}
+	allowlist := append([]string{}, DefaultEnvAllowlist...)
+	return append(allowlist, envs.Allowlist...)
+}
+
 func CollectTerraformEnvConfig(envs *TerraformEnvConfig) (map[string]string, map[string]string) {
 	stateEnvVars := map[string]string{}
 	commandEnvVars := map[string]string{}
@@ -1200,6 +1200,245 @@ projects:
 	assert.Equal(t, false, dg.AllowDraftPRs)
 }
 
+func TestDiggerConfigEphemeralWorkspace(t *testing.T) {
+	tempDir, teardown := setUp()
+	defer teardown()
+
+	diggerCfg := `
+projects:
+- name: dev
+  dir: .
+  ephemeral_workspace: true
+- name: prod
+  dir: .
+`
+	defer createFile(path.Join(tempDir, "mantis.yml"), diggerCfg)()
+	defer createFile(path.Join(tempDir, "main.tf"), "resource \"null_resource\" \"test4\" {}")()
+
+	dg, _, _, err := LoadDiggerConfig(tempDir, true, nil)
+	assert.NoError(t, err)
+	prNumber := 12
+	assert.True(t, dg.Projects[0].EphemeralWorkspace)
+	assert.Equal(t, "pr-12", dg.Projects[0].WorkspaceForPullRequest(&prNumber))
+	assert.Equal(t, "default", dg.Projects[0].WorkspaceForPullRequest(nil))
+	assert.False(t, dg.Projects[1].EphemeralWorkspace)
+	assert.Equal(t, "default", dg.Projects[1].WorkspaceForPullRequest(&prNumber))
+}
+
+func TestDiggerConfigEphemeralWorkspaceForTerragrunt(t *testing.T) {
+	tempDir, teardown := setUp()
+	defer teardown()
+
+	diggerCfg := `
+projects:
+- name: dev
+  dir: .
+  terragrunt: true
+  ephemeral_workspace: true
+`
+	defer createFile(path.Join(tempDir, "mantis.yml"), diggerCfg)()
+
+	dg, _, _, err := LoadDiggerConfig(tempDir, true, nil)
+	assert.NoError(t, err)
+	assert.True(t, dg.Projects[0].Terragrunt)
+	assert.True(t, dg.Projects[0].EphemeralWorkspace)
+}
+
+func TestDiggerConfigTerragruntRunAll(t *testing.T) {
+	tempDir, teardown := setUp()
+	defer teardown()
+
+	diggerCfg := `
+projects:
+- name: live
+  dir: live
+  terragrunt: true
+  terragrunt_run_all: true
+- name: plain
+  dir: .
+  terragrunt_run_all: true
+`
+	defer createFile(path.Join(tempDir, "mantis.yml"), diggerCfg)()
+
+	_, _, _, err := LoadDiggerConfig(tempDir, true, nil)
+	assert.ErrorContains(t, err, "terragrunt_run_all requires terragrunt to be enabled")
+
+	diggerCfg = `
+projects:
+- name: live
+  dir: live
+  terragrunt: true
+  terragrunt_run_all: true
+`
+	defer createFile(path.Join(tempDir, "mantis.yml"), diggerCfg)()
+
+	dg, _, _, err := LoadDiggerConfig(tempDir, true, nil)
+	assert.NoError(t, err)
+	assert.True(t, dg.Projects[0].TerragruntRunAll)
+}
+
+func TestDiggerConfigHooks(t *testing.T) {
+	tempDir, teardown := setUp()
+	defer teardown()
+
+	diggerCfg := `
+projects:
+- name: dev
+  dir: .
+hooks:
+  pre_plan:
+    - run: echo pre plan
+  on_failure:
+    - run: ./notify.sh
+      shell: sh
+`
+	defer createFile(path.Join(tempDir, "mantis.yml"), diggerCfg)()
+	defer createFile(path.Join(tempDir, "main.tf"), "resource \"null_resource\" \"test4\" {}")()
+
+	dg, _, _, err := LoadDiggerConfig(tempDir, true, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []Step{{Action: "run", Value: "echo pre plan", ExtraArgs: nil, Shell: ""}}, dg.Hooks.PrePlan)
+	assert.Equal(t, []Step{{Action: "run", Value: "./notify.sh", ExtraArgs: nil, Shell: "sh"}}, dg.Hooks.OnFailure)
+	assert.Empty(t, dg.Hooks.PostApply)
+}
+
+func TestDiggerConfigHooksOnlyAllowRunSteps(t *testing.T) {
+	tempDir, teardown := setUp()
+	defer teardown()
+
+	diggerCfg := `
+projects:
+- name: dev
+  dir: .
+hooks:
+  post_apply:
+    - init
+`
+	defer createFile(path.Join(tempDir, "mantis.yml"), diggerCfg)()
+	defer createFile(path.Join(tempDir, "main.tf"), "resource \"null_resource\" \"test4\" {}")()
+
+	_, _, _, err := LoadDiggerConfig(tempDir, true, nil)
+	assert.ErrorContains(t, err, "post_apply hook: only run steps are supported")
+}
+
+func TestDiggerConfigSanitizedEnv(t *testing.T) {
+	tempDir, teardown := setUp()
+	defer teardown()
+
+	diggerCfg := `
+projects:
+- name: dev
+  dir: .
+  workflow: sanitized
+workflows:
+  sanitized:
+    env_vars:
+      sanitize: true
+      allowlist:
+        - AWS_REGION
+        - TF_*
+`
+	defer createFile(path.Join(tempDir, "mantis.yml"), diggerCfg)()
+	defer createFile(path.Join(tempDir, "main.tf"), "resource \"null_resource\" \"test4\" {}")()
+
+	dg, _, _, err := LoadDiggerConfig(tempDir, true, nil)
+	assert.NoError(t, err)
+	allowlist := CollectEnvAllowlist(dg.Workflows["sanitized"].EnvVars)
+	assert.Contains(t, allowlist, "PATH")
+	assert.Contains(t, allowlist, "AWS_REGION")
+	assert.Contains(t, allowlist, "TF_*")
+	assert.Nil(t, CollectEnvAllowlist(dg.Workflows["default"].EnvVars))
+}
+
+func TestDiggerConfigProviderCache(t *testing.T) {
+	tempDir, teardown := setUp()
+	defer teardown()
+
+	diggerCfg := `
+projects:
+- name: dev
+  dir: .
+provider_cache:
+  upgrade: false
+  mirror_dir: /opt/providers
+`
+	defer createFile(path.Join(tempDir, "mantis.yml"), diggerCfg)()
+	defer createFile(path.Join(tempDir, "main.tf"), "resource \"null_resource\" \"test4\" {}")()
+
+	dg, _, _, err := LoadDiggerConfig(tempDir, true, nil)
+	assert.NoError(t, err)
+	assert.True(t, dg.ProviderCache.Enabled)
+	assert.False(t, dg.ProviderCache.Upgrade)
+	assert.Equal(t, "/opt/providers", dg.ProviderCache.MirrorDir)
+}
+
+func TestDiggerConfigProviderCacheDefaults(t *testing.T) {
+	tempDir, teardown := setUp()
+	defer teardown()
+
+	diggerCfg := `
+projects:
+- name: dev
+  dir: .
+`
+	defer createFile(path.Join(tempDir, "mantis.yml"), diggerCfg)()
+	defer createFile(path.Join(tempDir, "main.tf"), "resource \"null_resource\" \"test4\" {}")()
+
+	dg, _, _, err := LoadDiggerConfig(tempDir, true, nil)
+	assert.NoError(t, err)
+	assert.False(t, dg.ProviderCache.Enabled)
+	assert.True(t, dg.ProviderCache.Upgrade)
+}
+
+func TestDiggerConfigNotifications(t *testing.T) {
+	tempDir, teardown := setUp()
+	defer teardown()
+
+	diggerCfg := `
+projects:
+- name: dev
+  dir: .
+notifications:
+  sinks:
+    oncall:
+      type: slack
+      url_env: SLACK_WEBHOOK_URL
+    audit:
+      type: email
+      smtp_host: smtp.example.com
+      from: digger@example.com
+      to: [infra@example.com]
+  routes:
+  - projects: ["prod-*"]
+    events: [apply_failed, lock_stolen]
+    sinks: [oncall, audit]
+`
+	defer createFile(path.Join(tempDir, "mantis.yml"), diggerCfg)()
+	defer createFile(path.Join(tempDir, "main.tf"), "resource \"null_resource\" \"test4\" {}")()
+
+	dg, _, _, err := LoadDiggerConfig(tempDir, true, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, NotificationSink{Type: NotificationSinkSlack, UrlEnv: "SLACK_WEBHOOK_URL"}, dg.Notifications.Sinks["oncall"])
+	assert.Equal(t, []string{"infra@example.com"}, dg.Notifications.Sinks["audit"].To)
+	assert.Equal(t, []NotificationRoute{{Projects: []string{"prod-*"}, Events: []string{"apply_failed", "lock_stolen"}, Sinks: []string{"oncall", "audit"}}}, dg.Notifications.Routes)
+}
+
+func TestValidateNotifications(t *testing.T) {
+	slack := map[string]NotificationSink{"oncall": {Type: NotificationSinkSlack, UrlEnv: "SLACK_WEBHOOK_URL"}}
+	cases := []Notifications{
+		{Sinks: map[string]NotificationSink{"oncall": {Type: "pager"}}},
+		{Sinks: map[string]NotificationSink{"oncall": {Type: NotificationSinkWebhook}}},
+		{Sinks: map[string]NotificationSink{"audit": {Type: NotificationSinkEmail, SmtpHost: "smtp.example.com"}}},
+		{Sinks: slack, Routes: []NotificationRoute{{Sinks: []string{"missing"}}}},
+		{Sinks: slack, Routes: []NotificationRoute{{Events: []string{"plan_succeeded"}, Sinks: []string{"oncall"}}}},
+		{Sinks: slack, Routes: []NotificationRoute{{Projects: []string{"[prod"}, Sinks: []string{"oncall"}}}},
+	}
+	for _, c := range cases {
+		assert.Error(t, ValidateNotifications(c))
+	}
+	assert.NoError(t, ValidateNotifications(Notifications{Sinks: slack, Routes: []NotificationRoute{{Sinks: []string{"oncall"}}}}))
+}
+
 func TestGetModifiedProjectsReturnsCorrectSourceMapping(t *testing.T) {
 	changedFiles := []string{"modules/bucket/main.tf", "dev/main.tf"}
 	projects := []Project{
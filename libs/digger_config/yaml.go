@@ -19,6 +19,61 @@ type DiggerConfigYaml struct {
 	GenerateProjectsConfig     *GenerateProjectsConfigYaml  `yaml:"generate_projects"`
 	TraverseToNestedProjects   *bool                        `yaml:"traverse_to_nested_projects"`
 	MentionDriftedProjectsInPR *bool                        `yaml:"mention_drifted_projects_in_pr"`
+	Hooks                      *HooksYaml                   `yaml:"hooks,omitempty"`
+	ProviderCache              *ProviderCacheYaml           `yaml:"provider_cache,omitempty"`
+	Notifications              *NotificationsYaml           `yaml:"notifications,omitempty"`
+	// CommentTemplates replace the built-in comment layouts by name, see the comment_utils/templates package
+	CommentTemplates map[string]string `yaml:"comment_templates,omitempty"`
+	// ReportingCheckRuns makes the backend report projects as check runs of its GitHub App, with plan and apply buttons
+	ReportingCheckRuns *bool `yaml:"reporting_check_runs,omitempty"`
+}
+
+// NotificationsYaml declares named sinks and the routes deciding which events of which projects they receive
+type NotificationsYaml struct {
+	Sinks  map[string]NotificationSinkYaml `yaml:"sinks"`
+	Routes []NotificationRouteYaml         `yaml:"routes"`
+}
+
+// NotificationSinkYaml references secrets by the name of the env var holding them, they never live in mantis.yml
+type NotificationSinkYaml struct {
+	// Type is slack, teams, webhook or email
+	Type      string `yaml:"type"`
+	UrlEnv    string `yaml:"url_env,omitempty"`
+	SecretEnv string `yaml:"secret_env,omitempty"`
+
+	// SmtpHost and SmtpPort are ignored by the backend, it sends emails through the SMTP server it is configured with
+	SmtpHost    string   `yaml:"smtp_host,omitempty"`
+	SmtpPort    int      `yaml:"smtp_port,omitempty"`
+	UsernameEnv string   `yaml:"username_env,omitempty"`
+	PasswordEnv string   `yaml:"password_env,omitempty"`
+	From        string   `yaml:"from,omitempty"`
+	To          []string `yaml:"to,omitempty"`
+}
+
+type NotificationRouteYaml struct {
+	// Projects are glob patterns of project names, all projects when empty
+	Projects []string `yaml:"projects,omitempty"`
+	// Events are event types like apply_failed, all events when empty
+	Events []string `yaml:"events,omitempty"`
+	Sinks  []string `yaml:"sinks"`
+}
+
+type ProviderCacheYaml struct {
+	Enabled *bool  `yaml:"enabled,omitempty"`
+	Dir     string `yaml:"dir,omitempty"`
+	// run init with -upgrade, disable to respect the committed .terraform.lock.hcl files
+	Upgrade         *bool    `yaml:"upgrade,omitempty"`
+	MirrorDir       string   `yaml:"mirror_dir,omitempty"`
+	MirrorPlatforms []string `yaml:"mirror_platforms,omitempty"`
+}
+
+// HooksYaml holds run steps executed for every project at the given lifecycle event
+type HooksYaml struct {
+	PrePlan   []StepYaml `yaml:"pre_plan,omitempty"`
+	PostPlan  []StepYaml `yaml:"post_plan,omitempty"`
+	PreApply  []StepYaml `yaml:"pre_apply,omitempty"`
+	PostApply []StepYaml `yaml:"post_apply,omitempty"`
+	OnFailure []StepYaml `yaml:"on_failure,omitempty"`
 }
 
 type DependencyConfigurationYaml struct {
@@ -38,6 +93,8 @@ type ProjectYaml struct {
 	DependencyProjects []string                    `yaml:"depends_on,omitempty"`
 	DriftDetection     *bool                       `yaml:"drift_detection,omitempty"`
 	AwsRoleToAssume    *AssumeRoleForProjectConfig `yaml:"aws_role_to_assume,omitempty"`
+	EphemeralWorkspace *bool                       `yaml:"ephemeral_workspace,omitempty"`
+	TerragruntRunAll   *bool                       `yaml:"terragrunt_run_all,omitempty"`
 }
 
 type WorkflowYaml struct {
@@ -77,6 +134,9 @@ type StepYaml struct {
 type TerraformEnvConfigYaml struct {
 	State    []EnvVarYaml `yaml:"state"`
 	Commands []EnvVarYaml `yaml:"commands"`
+	// start commands from a clean environment instead of inheriting the runner's one
+	Sanitize  *bool    `yaml:"sanitize,omitempty"`
+	Allowlist []string `yaml:"allowlist,omitempty"`
 }
 
 type EnvVarYaml struct {
@@ -4,10 +4,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"github.com/diggerhq/digger/libs/comment_utils/utils"
+	"github.com/diggerhq/digger/libs/comment_utils/templates"
 	"github.com/diggerhq/digger/libs/locking/aws"
 	"github.com/diggerhq/digger/libs/locking/azure"
 	"github.com/diggerhq/digger/libs/locking/gcp"
+	"github.com/diggerhq/digger/libs/notifications"
 	"log"
 	"os"
 	"strconv"
@@ -31,6 +32,12 @@ type PullRequestLock struct {
 	ProjectName      string
 	ProjectNamespace string
 	PrNumber         int
+	// EphemeralWorkspace is only set for projects running in per pull request workspaces, each of them gets its own lock
+	EphemeralWorkspace string
+	// Templates render the lock comments, the defaults are used when nil
+	Templates *templates.Templates
+	// Notifier is sent the lock_stolen events of the lock, nothing is sent when nil
+	Notifier notifications.Notifier
 }
 
 type NoOpLock struct {
@@ -75,7 +82,7 @@ func (projectLock *PullRequestLock) Lock() (bool, error) {
 			transactionIdStr := strconv.Itoa(*existingLockTransactionId)
 			comment := "Project " + projectLock.projectId() + " locked by another PR #" + transactionIdStr + " (failed to acquire lock " + projectLock.ProjectNamespace + "). The locking plan must be applied or discarded before future plans can execute"
 
-			reportLockingFailed(projectLock.Reporter, comment)
+			reportLockingFailed(projectLock.Reporter, projectLock.Templates, comment)
 			return false, fmt.Errorf(comment)
 		}
 	}
@@ -88,38 +95,26 @@ func (projectLock *PullRequestLock) Lock() (bool, error) {
 
 	if lockAcquired && !isNoOpLock {
 		comment := "Project " + projectLock.projectId() + " has been locked by PR #" + strconv.Itoa(projectLock.PrNumber)
-		reportingLockingSuccess(projectLock.Reporter, comment)
+		reportingLockingSuccess(projectLock.Reporter, projectLock.Templates, comment)
 		log.Println("project " + projectLock.projectId() + " locked successfully. PR # " + strconv.Itoa(projectLock.PrNumber))
 
 	}
 	return lockAcquired, nil
 }
 
-func reportingLockingSuccess(r reporting.Reporter, comment string) {
-	if r.SupportsMarkdown() {
-		_, _, err := r.Report(comment, utils.AsCollapsibleComment("Locking successful", false))
-		if err != nil {
-			log.Println("failed to publish comment: " + err.Error())
-		}
-	} else {
-		_, _, err := r.Report(comment, utils.AsComment("Locking successful"))
-		if err != nil {
-			log.Println("failed to publish comment: " + err.Error())
-		}
+func reportingLockingSuccess(r reporting.Reporter, t *templates.Templates, comment string) {
+	formatter := templates.OrDefaults(t).Formatter(templates.Lock, templates.CommentData{Title: "Locking successful", Markdown: r.SupportsMarkdown()})
+	_, _, err := r.Report(comment, formatter)
+	if err != nil {
+		log.Println("failed to publish comment: " + err.Error())
 	}
 }
 
-func reportLockingFailed(r reporting.Reporter, comment string) {
-	if r.SupportsMarkdown() {
-		_, _, err := r.Report(comment, utils.AsCollapsibleComment("Locking failed", false))
-		if err != nil {
-			log.Println("failed to publish comment: " + err.Error())
-		}
-	} else {
-		_, _, err := r.Report(comment, utils.AsComment("Locking failed"))
-		if err != nil {
-			log.Println("failed to publish comment: " + err.Error())
-		}
+func reportLockingFailed(r reporting.Reporter, t *templates.Templates, comment string) {
+	formatter := templates.OrDefaults(t).Formatter(templates.Lock, templates.CommentData{Title: "Locking failed", Markdown: r.SupportsMarkdown()})
+	_, _, err := r.Report(comment, formatter)
+	if err != nil {
+		log.Println("failed to publish comment: " + err.Error())
 	}
 }
 
@@ -142,11 +137,12 @@ func (projectLock *PullRequestLock) verifyNoHangingLocks() (bool, error) {
 				if err != nil {
 					return false, fmt.Errorf("failed to unlock a lock acquired by closed PR %v: %w", transactionId, err)
 				}
+				projectLock.notifyLockStolen(*transactionId, fmt.Sprintf("Lock of closed PR #%v was released by PR #%v", *transactionId, projectLock.PrNumber))
 				return true, nil
 			}
 			transactionIdStr := strconv.Itoa(*transactionId)
 			comment := "Project " + projectLock.projectId() + " locked by another PR #" + transactionIdStr + "(failed to acquire lock " + projectLock.ProjectName + "). The locking plan must be applied or discarded before future plans can execute"
-			reportLockingFailed(projectLock.Reporter, comment)
+			reportLockingFailed(projectLock.Reporter, projectLock.Templates, comment)
 			return false, fmt.Errorf(comment)
 		}
 		return true, nil
@@ -171,7 +167,7 @@ func (projectLock *PullRequestLock) Unlock() (bool, error) {
 			}
 			if lockReleased {
 				comment := "Project unlocked (" + projectLock.projectId() + ")."
-				reportSuccessfulUnlocking(projectLock.Reporter, comment)
+				reportSuccessfulUnlocking(projectLock.Reporter, projectLock.Templates, comment)
 
 				log.Println("Project unlocked")
 				return true, nil
@@ -181,17 +177,11 @@ func (projectLock *PullRequestLock) Unlock() (bool, error) {
 	return false, nil
 }
 
-func reportSuccessfulUnlocking(r reporting.Reporter, comment string) {
-	if r.SupportsMarkdown() {
-		_, _, err := r.Report(comment, utils.AsCollapsibleComment("Unlocking successful", false))
-		if err != nil {
-			log.Println("failed to publish comment: " + err.Error())
-		}
-	} else {
-		_, _, err := r.Report(comment, utils.AsComment("Unlocking successful"))
-		if err != nil {
-			log.Println("failed to publish comment: " + err.Error())
-		}
+func reportSuccessfulUnlocking(r reporting.Reporter, t *templates.Templates, comment string) {
+	formatter := templates.OrDefaults(t).Formatter(templates.Lock, templates.CommentData{Title: "Unlocking successful", Markdown: r.SupportsMarkdown()})
+	_, _, err := r.Report(comment, formatter)
+	if err != nil {
+		log.Println("failed to publish comment: " + err.Error())
 	}
 }
 
@@ -210,19 +200,38 @@ func (projectLock *PullRequestLock) ForceUnlock() error {
 
 		if lockReleased {
 			comment := "Project unlocked (" + projectLock.projectId() + ")."
-			reportSuccessfulUnlocking(projectLock.Reporter, comment)
+			reportSuccessfulUnlocking(projectLock.Reporter, projectLock.Templates, comment)
 			log.Println("Project unlocked")
+			if *lock != projectLock.PrNumber {
+				projectLock.notifyLockStolen(*lock, fmt.Sprintf("Lock held by PR #%v was force unlocked from PR #%v", *lock, projectLock.PrNumber))
+			}
 		}
 		return nil
 	}
 	return nil
 }
 
+func (projectLock *PullRequestLock) notifyLockStolen(holderPrNumber int, message string) {
+	if projectLock.Notifier == nil {
+		return
+	}
+	projectLock.Notifier.Notify(notifications.Event{
+		Type:       notifications.LockStolen,
+		Repository: projectLock.ProjectNamespace,
+		Project:    projectLock.ProjectName,
+		PrNumber:   holderPrNumber,
+		Message:    message,
+	})
+}
+
 func (projectLock *PullRequestLock) projectId() string {
 	return projectLock.ProjectNamespace + "#" + projectLock.ProjectName
 }
 
 func (projectLock *PullRequestLock) LockId() string {
+	if projectLock.EphemeralWorkspace != "" {
+		return projectLock.ProjectNamespace + "#" + projectLock.ProjectName + "#" + projectLock.EphemeralWorkspace
+	}
 	return projectLock.ProjectNamespace + "#" + projectLock.ProjectName
 }
 
@@ -2,7 +2,10 @@ package locking
 
 import (
 	"github.com/diggerhq/digger/libs/comment_utils/reporting"
+	"github.com/diggerhq/digger/libs/comment_utils/templates"
+	"github.com/diggerhq/digger/libs/notifications"
 	"github.com/diggerhq/digger/libs/orchestrator"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -37,6 +40,38 @@ func TestLockingTwiceThrowsError(t *testing.T) {
 	assert.Error(t, err2)
 }
 
+func TestLockingEphemeralWorkspacesOfDifferentPRs(t *testing.T) {
+	mockDynamoDB := MockLock{MapLock: make(map[string]int)}
+	mockPrManager := orchestrator.MockGithubPullrequestManager{}
+	reporter := reporting.MockReporter{}
+	pl := PullRequestLock{
+		InternalLock:       &mockDynamoDB,
+		CIService:          &mockPrManager,
+		Reporter:           &reporter,
+		ProjectName:        "a",
+		ProjectNamespace:   "",
+		PrNumber:           1,
+		EphemeralWorkspace: "pr-1",
+	}
+	state1, err1 := pl.Lock()
+	assert.True(t, state1)
+	assert.NoError(t, err1)
+
+	pl2 := PullRequestLock{
+		InternalLock:       &mockDynamoDB,
+		CIService:          &mockPrManager,
+		Reporter:           &reporter,
+		ProjectName:        "a",
+		ProjectNamespace:   "",
+		PrNumber:           2,
+		EphemeralWorkspace: "pr-2",
+	}
+	state2, err2 := pl2.Lock()
+	assert.True(t, state2)
+	assert.NoError(t, err2)
+	assert.NotEqual(t, pl.LockId(), pl2.LockId())
+}
+
 func TestGetLock(t *testing.T) {
 	// TODO: implement this test
 	lock, err := GetLock()
@@ -45,3 +80,58 @@ func TestGetLock(t *testing.T) {
 	}
 	print(lock)
 }
+
+type recordingNotifier struct {
+	events []notifications.Event
+}
+
+func (n *recordingNotifier) Notify(event notifications.Event) {
+	n.events = append(n.events, event)
+}
+
+func TestForceUnlockOfOtherPrNotifiesLockStolen(t *testing.T) {
+	notifier := &recordingNotifier{}
+
+	mockDynamoDB := MockLock{MapLock: make(map[string]int)}
+	mockPrManager := orchestrator.MockGithubPullrequestManager{}
+	reporter := reporting.MockReporter{}
+	holder := PullRequestLock{InternalLock: &mockDynamoDB, CIService: &mockPrManager, Reporter: &reporter, Notifier: notifier, ProjectName: "a", ProjectNamespace: "org/repo", PrNumber: 1}
+	other := PullRequestLock{InternalLock: &mockDynamoDB, CIService: &mockPrManager, Reporter: &reporter, Notifier: notifier, ProjectName: "a", ProjectNamespace: "org/repo", PrNumber: 2}
+
+	_, err := holder.Lock()
+	assert.NoError(t, err)
+	assert.NoError(t, holder.ForceUnlock())
+	assert.Empty(t, notifier.events)
+
+	_, err = holder.Lock()
+	assert.NoError(t, err)
+	assert.NoError(t, other.ForceUnlock())
+	assert.Len(t, notifier.events, 1)
+	assert.Equal(t, notifications.LockStolen, notifier.events[0].Type)
+	assert.Equal(t, 1, notifier.events[0].PrNumber)
+	assert.Equal(t, "a", notifier.events[0].Project)
+}
+
+type formattingReporter struct {
+	reporting.MockReporter
+	comments []string
+}
+
+func (r *formattingReporter) Report(report string, reportFormatting func(report string) string) (string, string, error) {
+	r.comments = append(r.comments, reportFormatting(report))
+	return "", "", nil
+}
+
+func TestLockCommentsUseTheLockTemplates(t *testing.T) {
+	commentTemplates, err := templates.Parse(map[string]string{templates.Lock: "custom {{.Title}}: {{.Output}}"})
+	assert.NoError(t, err)
+	mockDynamoDB := MockLock{MapLock: make(map[string]int)}
+	mockPrManager := orchestrator.MockGithubPullrequestManager{}
+	reporter := &formattingReporter{}
+	pl := PullRequestLock{InternalLock: &mockDynamoDB, CIService: &mockPrManager, Reporter: reporter, Templates: commentTemplates, ProjectName: "a", ProjectNamespace: "org/repo", PrNumber: 1}
+
+	_, err = pl.Lock()
+	assert.NoError(t, err)
+	assert.Len(t, reporter.comments, 1)
+	assert.True(t, strings.HasPrefix(reporter.comments[0], "custom Locking successful: "))
+}
@@ -0,0 +1,217 @@
+// Package notifications sends events like failed applies or denied plans to Slack, Microsoft Teams, signed webhooks
+// and email. Which sinks receive which events of which projects is configured by the routes of the notifications
+// block of mantis.yml.
+package notifications
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/diggerhq/digger/libs/digger_config"
+)
+
+type EventType string
+
+const (
+	PlanFailed     EventType = "plan_failed"
+	ApplySucceeded EventType = "apply_succeeded"
+	ApplyFailed    EventType = "apply_failed"
+	// LockStolen is sent when a project lock held by one pull request is released or taken over by another
+	LockStolen   EventType = "lock_stolen"
+	PolicyDenied EventType = "policy_denied"
+)
+
+// BackendEnvPrefix restricts the env vars the backend resolves for sinks, so repositories can't reference its secrets
+const BackendEnvPrefix = "DIGGER_NOTIFICATION_"
+
+type Event struct {
+	Type        EventType `json:"type"`
+	Repository  string    `json:"repository"`
+	Project     string    `json:"project"`
+	PrNumber    int       `json:"pr_number,omitempty"`
+	RequestedBy string    `json:"requested_by,omitempty"`
+	Message     string    `json:"message"`
+	// Details is the terraform output or policy violations behind the event
+	Details string    `json:"details,omitempty"`
+	Url     string    `json:"url,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// Title is a one line description of the event used as message heading and email subject
+func (e Event) Title() string {
+	title := fmt.Sprintf("%v: %v for project %v", e.Repository, strings.ReplaceAll(string(e.Type), "_", " "), e.Project)
+	if e.PrNumber != 0 {
+		title = fmt.Sprintf("%v (PR #%v)", title, e.PrNumber)
+	}
+	return title
+}
+
+type Sink interface {
+	Send(event Event) error
+}
+
+type Notifier interface {
+	// Notify sends the event to the sinks routed to it, failures are logged and never fail the run
+	Notify(event Event)
+}
+
+type NoopNotifier struct{}
+
+func (n NoopNotifier) Notify(event Event) {}
+
+type route struct {
+	projects []string
+	events   []string
+	sinks    []string
+}
+
+// Router sends events to the sinks of every matching route, a sink matched by several routes is sent the event once
+type Router struct {
+	sinks  map[string]Sink
+	routes []route
+}
+
+// LookupEnv resolves the env var names referenced by sink configurations
+type LookupEnv func(name string) (string, bool)
+
+// PrefixedLookupEnv only resolves env vars starting with prefix
+func PrefixedLookupEnv(prefix string) LookupEnv {
+	return func(name string) (string, bool) {
+		if !strings.HasPrefix(name, prefix) {
+			return "", false
+		}
+		return os.LookupEnv(name)
+	}
+}
+
+func NewRouter(config digger_config.Notifications, lookupEnv LookupEnv) (*Router, error) {
+	return newRouter(config, lookupEnv, nil)
+}
+
+// NewBackendRouter routes events of repositories served by the backend. Sinks only resolve env vars starting with
+// BackendEnvPrefix, and emails go through the SMTP server of the backend, DIGGER_NOTIFICATION_SMTP_HOST and
+// DIGGER_NOTIFICATION_SMTP_PORT, whatever smtp_host the repository sets. Repositories could otherwise send the SMTP
+// credentials of the backend to a server of their own. Emails are only sent to addresses of the comma separated domains
+// of DIGGER_NOTIFICATION_EMAIL_ALLOWED_DOMAINS, so repositories can't mail arbitrary addresses from the backend
+func NewBackendRouter(config digger_config.Notifications) (*Router, error) {
+	server := &smtpServer{Host: os.Getenv(BackendEnvPrefix + "SMTP_HOST"), Port: defaultSmtpPort}
+	for _, domain := range strings.Split(os.Getenv(BackendEnvPrefix+"EMAIL_ALLOWED_DOMAINS"), ",") {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		if domain != "" {
+			server.AllowedDomains = append(server.AllowedDomains, domain)
+		}
+	}
+	if port := os.Getenv(BackendEnvPrefix + "SMTP_PORT"); port != "" {
+		var err error
+		server.Port, err = strconv.Atoi(port)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %vSMTP_PORT %v: %v", BackendEnvPrefix, port, err)
+		}
+	}
+	return newRouter(config, PrefixedLookupEnv(BackendEnvPrefix), server)
+}
+
+// newRouter sends emails through server instead of the smtp_host of email sinks when it is set
+func newRouter(config digger_config.Notifications, lookupEnv LookupEnv, server *smtpServer) (*Router, error) {
+	err := digger_config.ValidateNotifications(config)
+	if err != nil {
+		return nil, err
+	}
+	router := &Router{sinks: map[string]Sink{}}
+	for name, sinkConfig := range config.Sinks {
+		sink, err := newSink(sinkConfig, lookupEnv, server)
+		if err != nil {
+			return nil, fmt.Errorf("notification sink '%v': %v", name, err)
+		}
+		router.sinks[name] = sink
+	}
+	for _, r := range config.Routes {
+		router.routes = append(router.routes, route{projects: r.Projects, events: r.Events, sinks: r.Sinks})
+	}
+	return router, nil
+}
+
+func (r *Router) Notify(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	for _, name := range r.sinksFor(event) {
+		err := r.sinks[name].Send(event)
+		if err != nil {
+			log.Printf("Failed to send %v notification to %v: %v", event.Type, name, err)
+		}
+	}
+}
+
+func (r *Router) sinksFor(event Event) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, route := range r.routes {
+		if !route.matches(event) {
+			continue
+		}
+		for _, name := range route.sinks {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+func (r route) matches(event Event) bool {
+	if len(r.events) > 0 && !contains(r.events, string(event.Type)) {
+		return false
+	}
+	if len(r.projects) == 0 {
+		return true
+	}
+	for _, pattern := range r.projects {
+		if matched, _ := filepath.Match(pattern, event.Project); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Load returns the notifier routing events as configured, or a notifier sending nothing when no routes are configured.
+// An invalid configuration is logged and nothing is sent
+func Load(config digger_config.Notifications, lookupEnv LookupEnv) Notifier {
+	if len(config.Routes) == 0 {
+		return NoopNotifier{}
+	}
+	router, err := NewRouter(config, lookupEnv)
+	if err != nil {
+		log.Printf("Invalid notifications configuration, notifications are disabled: %v", err)
+		return NoopNotifier{}
+	}
+	return router
+}
+
+// LoadBackend is Load for repositories served by the backend, see NewBackendRouter
+func LoadBackend(config digger_config.Notifications) Notifier {
+	if len(config.Routes) == 0 {
+		return NoopNotifier{}
+	}
+	router, err := NewBackendRouter(config)
+	if err != nil {
+		log.Printf("Invalid notifications configuration, notifications are disabled: %v", err)
+		return NoopNotifier{}
+	}
+	return router
+}
@@ -0,0 +1,227 @@
+package notifications
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/diggerhq/digger/libs/digger_config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingSink struct {
+	events []Event
+}
+
+func (s *recordingSink) Send(event Event) error {
+	s.events = append(s.events, event)
+	return nil
+}
+
+func lookupFrom(env map[string]string) LookupEnv {
+	return func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+}
+
+func TestRouterSendsEventsToMatchingSinksOnce(t *testing.T) {
+	oncall, team := &recordingSink{}, &recordingSink{}
+	router := &Router{
+		sinks: map[string]Sink{"oncall": oncall, "team": team},
+		routes: []route{
+			{projects: []string{"prod-*"}, events: []string{string(ApplyFailed)}, sinks: []string{"oncall", "team"}},
+			{sinks: []string{"team"}},
+		},
+	}
+
+	router.Notify(Event{Type: ApplyFailed, Project: "prod-eu"})
+	router.Notify(Event{Type: ApplyFailed, Project: "dev"})
+	router.Notify(Event{Type: ApplySucceeded, Project: "prod-eu"})
+
+	require.Len(t, oncall.events, 1)
+	assert.Equal(t, "prod-eu", oncall.events[0].Project)
+	assert.False(t, oncall.events[0].Time.IsZero())
+	assert.Len(t, team.events, 3)
+}
+
+func TestNewRouterResolvesSinkEnvVars(t *testing.T) {
+	config := digger_config.Notifications{
+		Sinks: map[string]digger_config.NotificationSink{
+			"hook": {Type: digger_config.NotificationSinkWebhook, UrlEnv: "HOOK_URL", SecretEnv: "HOOK_SECRET"},
+		},
+		Routes: []digger_config.NotificationRoute{{Sinks: []string{"hook"}}},
+	}
+
+	_, err := NewRouter(config, lookupFrom(map[string]string{"HOOK_URL": "https://example.com"}))
+	assert.ErrorContains(t, err, "HOOK_SECRET")
+
+	router, err := NewRouter(config, lookupFrom(map[string]string{"HOOK_URL": "https://example.com", "HOOK_SECRET": "s3cret"}))
+	require.NoError(t, err)
+	assert.Equal(t, WebhookSink{Url: "https://example.com", Secret: "s3cret"}, router.sinks["hook"])
+
+	config.Routes[0].Sinks = []string{"missing"}
+	_, err = NewRouter(config, lookupFrom(map[string]string{"HOOK_URL": "https://example.com", "HOOK_SECRET": "s3cret"}))
+	assert.Error(t, err)
+}
+
+func TestPrefixedLookupEnv(t *testing.T) {
+	t.Setenv("DIGGER_NOTIFICATION_SLACK_URL", "https://hooks.slack.com/x")
+	t.Setenv("GITHUB_APP_PRIVATE_KEY", "secret")
+	lookup := PrefixedLookupEnv(BackendEnvPrefix)
+
+	value, ok := lookup("DIGGER_NOTIFICATION_SLACK_URL")
+	assert.True(t, ok)
+	assert.Equal(t, "https://hooks.slack.com/x", value)
+	_, ok = lookup("GITHUB_APP_PRIVATE_KEY")
+	assert.False(t, ok)
+}
+
+func TestWebhookSinkSignsBody(t *testing.T) {
+	var body []byte
+	var headers http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		headers = r.Header
+	}))
+	defer server.Close()
+
+	err := WebhookSink{Url: server.URL, Secret: "s3cret"}.Send(Event{Type: LockStolen, Project: "dev"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "sha256="+Sign("s3cret", body), headers.Get(WebhookSignatureHeader))
+	assert.Equal(t, string(LockStolen), headers.Get(WebhookEventHeader))
+	var event Event
+	require.NoError(t, json.Unmarshal(body, &event))
+	assert.Equal(t, "dev", event.Project)
+}
+
+func TestChatSinkPayloads(t *testing.T) {
+	var payload map[string]interface{}
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&payload)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+	event := Event{Type: ApplyFailed, Repository: "org/repo", Project: "dev", PrNumber: 4, Message: "apply failed", Details: "Error: boom", Url: "https://ci/1"}
+
+	require.NoError(t, SlackSink{Url: server.URL}.Send(event))
+	text := payload["text"].(string)
+	assert.Contains(t, text, "*org/repo: apply failed for project dev (PR #4)*")
+	assert.Contains(t, text, "<https://ci/1|Details>")
+	assert.Contains(t, text, "Error: boom")
+
+	require.NoError(t, TeamsSink{Url: server.URL}.Send(event))
+	assert.Equal(t, "MessageCard", payload["@type"])
+	assert.Equal(t, "D50200", payload["themeColor"])
+	assert.NotNil(t, payload["potentialAction"])
+
+	status = http.StatusBadRequest
+	assert.Error(t, SlackSink{Url: server.URL}.Send(event))
+}
+
+func TestEmailMessageHeaders(t *testing.T) {
+	sink := EmailSink{From: "digger@example.com", To: []string{"a@example.com", "b@example.com"}}
+	message := string(sink.message(Event{Type: PolicyDenied, Repository: "org/repo", Project: "dev\r\nBcc: evil@example.com", Message: "denied"}))
+
+	headers, body, found := strings.Cut(message, "\r\n\r\n")
+	require.True(t, found)
+	assert.Contains(t, headers, "To: a@example.com, b@example.com\r\n")
+	assert.NotContains(t, headers, "\r\nBcc:")
+	assert.Equal(t, "denied\r\n", body)
+}
+
+func TestBackendRouterSendsEmailThroughBackendSmtpServer(t *testing.T) {
+	config := digger_config.Notifications{
+		Sinks: map[string]digger_config.NotificationSink{
+			"mail": {Type: digger_config.NotificationSinkEmail, SmtpHost: "smtp.attacker.example", From: "digger@example.com", To: []string{"a@example.com"}, UsernameEnv: "DIGGER_NOTIFICATION_SMTP_USER", PasswordEnv: "DIGGER_NOTIFICATION_SMTP_PASSWORD"},
+		},
+		Routes: []digger_config.NotificationRoute{{Sinks: []string{"mail"}}},
+	}
+	t.Setenv("DIGGER_NOTIFICATION_SMTP_USER", "user")
+	t.Setenv("DIGGER_NOTIFICATION_SMTP_PASSWORD", "password")
+
+	t.Setenv("DIGGER_NOTIFICATION_SMTP_HOST", "")
+	_, err := NewBackendRouter(config)
+	assert.ErrorContains(t, err, "no SMTP server")
+
+	t.Setenv("DIGGER_NOTIFICATION_SMTP_HOST", "smtp.internal.example")
+	t.Setenv("DIGGER_NOTIFICATION_SMTP_PORT", "2525")
+	t.Setenv("DIGGER_NOTIFICATION_EMAIL_ALLOWED_DOMAINS", "Example.com")
+	router, err := NewBackendRouter(config)
+	require.NoError(t, err)
+	sink := router.sinks["mail"].(EmailSink)
+	assert.Equal(t, "smtp.internal.example", sink.Host)
+	assert.Equal(t, 2525, sink.Port)
+	assert.Equal(t, "user", sink.Username)
+}
+
+func TestBackendRouterOnlySendsEmailToAllowedDomains(t *testing.T) {
+	config := digger_config.Notifications{
+		Sinks: map[string]digger_config.NotificationSink{
+			"mail": {Type: digger_config.NotificationSinkEmail, SmtpHost: "smtp.example.com", From: "digger@example.com", To: []string{"a@example.com", "b@other.example"}},
+		},
+		Routes: []digger_config.NotificationRoute{{Sinks: []string{"mail"}}},
+	}
+	t.Setenv("DIGGER_NOTIFICATION_SMTP_HOST", "smtp.internal.example")
+
+	t.Setenv("DIGGER_NOTIFICATION_EMAIL_ALLOWED_DOMAINS", "")
+	_, err := NewBackendRouter(config)
+	assert.ErrorContains(t, err, "a@example.com")
+
+	t.Setenv("DIGGER_NOTIFICATION_EMAIL_ALLOWED_DOMAINS", "example.com")
+	_, err = NewBackendRouter(config)
+	assert.ErrorContains(t, err, "b@other.example")
+
+	t.Setenv("DIGGER_NOTIFICATION_EMAIL_ALLOWED_DOMAINS", "example.com, other.example")
+	_, err = NewBackendRouter(config)
+	assert.NoError(t, err)
+}
+
+func TestLoadSendsNothingWithoutValidRoutes(t *testing.T) {
+	assert.Equal(t, NoopNotifier{}, Load(digger_config.Notifications{}, os.LookupEnv))
+
+	config := digger_config.Notifications{
+		Sinks:  map[string]digger_config.NotificationSink{"slack": {Type: digger_config.NotificationSinkSlack, UrlEnv: "MISSING_SLACK_URL"}},
+		Routes: []digger_config.NotificationRoute{{Sinks: []string{"slack"}}},
+	}
+	assert.Equal(t, NoopNotifier{}, Load(config, func(string) (string, bool) { return "", false }))
+	assert.IsType(t, &Router{}, Load(config, func(string) (string, bool) { return "https://hooks.example", true }))
+}
+
+func TestEmailSinkTimesOutOnUnresponsiveServer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		// accepts the connection but never greets the client
+		conn, err := listener.Accept()
+		if err == nil {
+			defer conn.Close()
+			time.Sleep(5 * time.Second)
+		}
+	}()
+	defaultTimeout := smtpTimeout
+	smtpTimeout = 100 * time.Millisecond
+	defer func() { smtpTimeout = defaultTimeout }()
+
+	addr := listener.Addr().(*net.TCPAddr)
+	sink := EmailSink{Host: "127.0.0.1", Port: addr.Port, From: "digger@example.com", To: []string{"a@example.com"}}
+	start := time.Now()
+	err = sink.Send(Event{Type: ApplyFailed, Project: "dev"})
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 2*time.Second)
+}
+
+func TestTruncateKeepsValidUtf8(t *testing.T) {
+	assert.Equal(t, "short", truncate("short", 10))
+	assert.Equal(t, "ab\n...", truncate("abé", 3))
+}
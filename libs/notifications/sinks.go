@@ -0,0 +1,334 @@
+package notifications
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/diggerhq/digger/libs/digger_config"
+)
+
+const (
+	// WebhookSignatureHeader holds the hex encoded HMAC-SHA256 of the request body, keyed with the sink secret
+	WebhookSignatureHeader = "X-Digger-Signature-256"
+	WebhookEventHeader     = "X-Digger-Event"
+
+	// chat messages show the start of the details, the full output stays in the job logs
+	maxChatDetailsLength = 3000
+	defaultSmtpPort      = 587
+)
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// smtpTimeout bounds connecting to the SMTP server and the whole conversation with it
+var smtpTimeout = 30 * time.Second
+
+type smtpServer struct {
+	Host string
+	Port int
+	// AllowedDomains are the domains emails can be sent to, no emails are sent when it is empty
+	AllowedDomains []string
+}
+
+// allows reports whether the domain of address is one of the allowed domains
+func (s *smtpServer) allows(address string) bool {
+	at := strings.LastIndex(address, "@")
+	if at < 0 {
+		return false
+	}
+	return contains(s.AllowedDomains, strings.ToLower(address[at+1:]))
+}
+
+func newSink(config digger_config.NotificationSink, lookupEnv LookupEnv, server *smtpServer) (Sink, error) {
+	env := func(name string) (string, error) {
+		value, ok := lookupEnv(name)
+		if !ok || value == "" {
+			return "", fmt.Errorf("env var %v is not set", name)
+		}
+		return value, nil
+	}
+	switch config.Type {
+	case digger_config.NotificationSinkSlack, digger_config.NotificationSinkTeams, digger_config.NotificationSinkWebhook:
+		url, err := env(config.UrlEnv)
+		if err != nil {
+			return nil, err
+		}
+		if config.Type == digger_config.NotificationSinkSlack {
+			return SlackSink{Url: url}, nil
+		}
+		if config.Type == digger_config.NotificationSinkTeams {
+			return TeamsSink{Url: url}, nil
+		}
+		sink := WebhookSink{Url: url}
+		if config.SecretEnv != "" {
+			sink.Secret, err = env(config.SecretEnv)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return sink, nil
+	case digger_config.NotificationSinkEmail:
+		sink := EmailSink{Host: config.SmtpHost, Port: config.SmtpPort, From: config.From, To: config.To}
+		if sink.Port == 0 {
+			sink.Port = defaultSmtpPort
+		}
+		if server != nil {
+			if server.Host == "" {
+				return nil, fmt.Errorf("no SMTP server is configured for email notifications")
+			}
+			sink.Host, sink.Port = server.Host, server.Port
+			for _, to := range sink.To {
+				if !server.allows(to) {
+					return nil, fmt.Errorf("emails can't be sent to %v, the allowed domains are set with %vEMAIL_ALLOWED_DOMAINS", to, BackendEnvPrefix)
+				}
+			}
+		}
+		if config.UsernameEnv != "" {
+			username, err := env(config.UsernameEnv)
+			if err != nil {
+				return nil, err
+			}
+			password, err := env(config.PasswordEnv)
+			if err != nil {
+				return nil, err
+			}
+			sink.Username, sink.Password = username, password
+		}
+		return sink, nil
+	}
+	return nil, fmt.Errorf("unknown sink type %v", config.Type)
+}
+
+type SlackSink struct {
+	Url string
+}
+
+func (s SlackSink) Send(event Event) error {
+	text := fmt.Sprintf("%v *%v*\n%v", emoji(event.Type), event.Title(), event.Message)
+	if event.Url != "" {
+		text += fmt.Sprintf("\n<%v|Details>", event.Url)
+	}
+	if event.Details != "" {
+		text += "\n```\n" + truncate(event.Details, maxChatDetailsLength) + "\n```"
+	}
+	return postJson(s.Url, map[string]string{"text": text})
+}
+
+type TeamsSink struct {
+	Url string
+}
+
+func (t TeamsSink) Send(event Event) error {
+	text := event.Message
+	if event.Details != "" {
+		text += "\n\n```\n" + truncate(event.Details, maxChatDetailsLength) + "\n```"
+	}
+	card := map[string]interface{}{
+		"@type":      "MessageCard",
+		"@context":   "https://schema.org/extensions",
+		"summary":    event.Title(),
+		"title":      event.Title(),
+		"themeColor": themeColor(event.Type),
+		"text":       text,
+	}
+	if event.Url != "" {
+		card["potentialAction"] = []map[string]interface{}{
+			{"@type": "OpenUri", "name": "Details", "targets": []map[string]string{{"os": "default", "uri": event.Url}}},
+		}
+	}
+	return postJson(t.Url, card)
+}
+
+// WebhookSink posts the event as json, signed with Secret when it is set
+type WebhookSink struct {
+	Url    string
+	Secret string
+}
+
+func (w WebhookSink) Send(event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("could not marshal notification: %v", err)
+	}
+	headers := map[string]string{WebhookEventHeader: string(event.Type)}
+	if w.Secret != "" {
+		headers[WebhookSignatureHeader] = "sha256=" + Sign(w.Secret, body)
+	}
+	return post(w.Url, body, headers)
+}
+
+// Sign returns the signature receivers of webhook notifications compare with the WebhookSignatureHeader
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+type EmailSink struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+func (e EmailSink) Send(event Event) error {
+	addr := net.JoinHostPort(e.Host, strconv.Itoa(e.Port))
+	conn, err := net.DialTimeout("tcp", addr, smtpTimeout)
+	if err != nil {
+		return fmt.Errorf("could not send email: %v", err)
+	}
+	defer conn.Close()
+	// smtp.SendMail has no timeout, an unresponsive server would block the run
+	err = conn.SetDeadline(time.Now().Add(smtpTimeout))
+	if err != nil {
+		return fmt.Errorf("could not send email: %v", err)
+	}
+	err = e.send(conn, event)
+	if err != nil {
+		return fmt.Errorf("could not send email: %v", err)
+	}
+	return nil
+}
+
+// send talks to the SMTP server like smtp.SendMail
+func (e EmailSink) send(conn net.Conn, event Event) error {
+	client, err := smtp.NewClient(conn, e.Host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		err = client.StartTLS(&tls.Config{ServerName: e.Host})
+		if err != nil {
+			return err
+		}
+	}
+	if e.Username != "" {
+		err = client.Auth(smtp.PlainAuth("", e.Username, e.Password, e.Host))
+		if err != nil {
+			return err
+		}
+	}
+	err = client.Mail(e.From)
+	if err != nil {
+		return err
+	}
+	for _, to := range e.To {
+		err = client.Rcpt(to)
+		if err != nil {
+			return err
+		}
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(e.message(event))
+	if err != nil {
+		return err
+	}
+	err = writer.Close()
+	if err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+func (e EmailSink) message(event Event) []byte {
+	var body strings.Builder
+	body.WriteString(event.Message + "\n")
+	if event.Url != "" {
+		body.WriteString("\nDetails: " + event.Url + "\n")
+	}
+	if event.Details != "" {
+		body.WriteString("\n" + event.Details + "\n")
+	}
+	// header values come from mantis.yml and project names, line breaks would inject headers
+	oneLine := strings.NewReplacer("\r", " ", "\n", " ")
+	headers := []string{
+		"From: " + oneLine.Replace(e.From),
+		"To: " + oneLine.Replace(strings.Join(e.To, ", ")),
+		"Subject: " + oneLine.Replace(event.Title()),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+	}
+	return []byte(strings.Join(headers, "\r\n") + "\r\n\r\n" + strings.ReplaceAll(body.String(), "\n", "\r\n"))
+}
+
+func postJson(url string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("could not marshal notification: %v", err)
+	}
+	return post(url, body, nil)
+}
+
+func post(url string, body []byte, headers map[string]string) error {
+	request, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("could not create notification request: %v", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+	resp, err := httpClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("could not send notification: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("notification request failed with %v: %v", resp.Status, string(respBody))
+	}
+	return nil
+}
+
+func emoji(eventType EventType) string {
+	switch eventType {
+	case ApplySucceeded:
+		return ":white_check_mark:"
+	case LockStolen:
+		return ":lock:"
+	case PolicyDenied:
+		return ":no_entry:"
+	default:
+		return ":x:"
+	}
+}
+
+func themeColor(eventType EventType) string {
+	switch eventType {
+	case ApplySucceeded:
+		return "2EB886"
+	case LockStolen:
+		return "DAA038"
+	default:
+		return "D50200"
+	}
+}
+
+func truncate(text string, limit int) string {
+	if len(text) <= limit {
+		return text
+	}
+	text = text[:limit]
+	for len(text) > 0 && !utf8.ValidString(text) {
+		text = text[:len(text)-1]
+	}
+	return text + "\n..."
+}
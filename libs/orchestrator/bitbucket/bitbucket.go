@@ -92,7 +92,13 @@ func (b BitbucketAPI) GetChangedFiles(prNumber int) ([]string, error) {
 	var files []string
 
 	for _, v := range diffStat.Values {
-		files = append(files, v.Old.Path)
+		// added files have no old path and removed files have no new one
+		if v.New.Path != "" {
+			files = append(files, v.New.Path)
+		}
+		if v.Old.Path != "" && v.Old.Path != v.New.Path {
+			files = append(files, v.Old.Path)
+		}
 	}
 	return files, nil
 }
@@ -121,7 +127,27 @@ func (b BitbucketAPI) PublishComment(prNumber int, comment string) (*orchestrato
 		return nil, fmt.Errorf("failed to publish comment. Status code: %d", resp.StatusCode)
 	}
 
-	return nil, nil
+	var createdComment struct {
+		Id      int `json:"id"`
+		Content struct {
+			Raw string `json:"raw"`
+		} `json:"content"`
+		Links struct {
+			Html struct {
+				Href string `json:"href"`
+			} `json:"html"`
+		} `json:"links"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&createdComment)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode published comment: %v", err)
+	}
+
+	return &orchestrator.Comment{
+		Id:   createdComment.Id,
+		Body: &createdComment.Content.Raw,
+		Url:  createdComment.Links.Html.Href,
+	}, nil
 }
 
 func (svc BitbucketAPI) ListIssues() ([]*orchestrator.Issue, error) {
@@ -133,10 +159,12 @@ func (svc BitbucketAPI) PublishIssue(title string, body string) (int64, error) {
 }
 
 func (b BitbucketAPI) EditComment(prNumber int, id interface{}, comment string) error {
-	url := fmt.Sprintf("%s/repositories/%s/%s/pullrequests/%d/comments/%s", bitbucketBaseURL, b.RepoWorkspace, b.RepoName, prNumber, id)
+	url := fmt.Sprintf("%s/repositories/%s/%s/pullrequests/%d/comments/%v", bitbucketBaseURL, b.RepoWorkspace, b.RepoName, prNumber, id)
 
-	commentBody := map[string]string{
-		"content": comment,
+	commentBody := map[string]interface{}{
+		"content": map[string]string{
+			"raw": comment,
+		},
 	}
 
 	commentJSON, err := json.Marshal(commentBody)
@@ -157,6 +185,11 @@ func (b BitbucketAPI) EditComment(prNumber int, id interface{}, comment string)
 	return nil
 }
 
+// MaxCommentLength is Bitbucket's limit for pull request comments
+func (b BitbucketAPI) MaxCommentLength() int {
+	return 32768
+}
+
 func (a BitbucketAPI) CreateCommentReaction(id interface{}, reaction string) error {
 	// TODO implement me
 	return nil
@@ -465,6 +498,9 @@ func (b BitbucketAPI) GetBranchName(prNumber int) (string, string, error) {
 			Branch struct {
 				Name string `json:"name"`
 			} `json:"branch"`
+			Commit struct {
+				Hash string `json:"hash"`
+			} `json:"commit"`
 		} `json:"source"`
 	}
 
@@ -473,7 +509,81 @@ func (b BitbucketAPI) GetBranchName(prNumber int) (string, string, error) {
 		return "", "", err
 	}
 
-	return pullRequest.Source.Branch.Name, "", nil
+	return pullRequest.Source.Branch.Name, pullRequest.Source.Commit.Hash, nil
+}
+
+// GetDefaultBranch returns the main branch of the repository
+func (b BitbucketAPI) GetDefaultBranch() (string, error) {
+	url := fmt.Sprintf("%s/repositories/%s/%s", bitbucketBaseURL, b.RepoWorkspace, b.RepoName)
+
+	resp, err := b.sendRequest("GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get repository. Status code: %d", resp.StatusCode)
+	}
+
+	var repository struct {
+		MainBranch struct {
+			Name string `json:"name"`
+		} `json:"mainbranch"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&repository)
+	if err != nil {
+		return "", err
+	}
+	return repository.MainBranch.Name, nil
+}
+
+// GetLabels returns no labels, bitbucket pull requests do not support them
+func (b BitbucketAPI) GetLabels(prNumber int) ([]string, error) {
+	return []string{}, nil
+}
+
+func (b BitbucketAPI) GetPullRequestDetails(prNumber int) (*orchestrator.PullRequestDetails, error) {
+	url := fmt.Sprintf("%s/repositories/%s/%s/pullrequests/%d", bitbucketBaseURL, b.RepoWorkspace, b.RepoName, prNumber)
+
+	resp, err := b.sendRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get pull request. Status code: %d", resp.StatusCode)
+	}
+
+	var pullRequest struct {
+		Author struct {
+			Nickname string `json:"nickname"`
+		} `json:"author"`
+		Source struct {
+			Branch struct {
+				Name string `json:"name"`
+			} `json:"branch"`
+		} `json:"source"`
+		Destination struct {
+			Branch struct {
+				Name string `json:"name"`
+			} `json:"branch"`
+		} `json:"destination"`
+		Draft bool `json:"draft"`
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(&pullRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	return &orchestrator.PullRequestDetails{
+		Author:     pullRequest.Author.Nickname,
+		BaseBranch: pullRequest.Destination.Branch.Name,
+		HeadBranch: pullRequest.Source.Branch.Name,
+		Draft:      pullRequest.Draft,
+	}, nil
 }
 
 func (svc BitbucketAPI) SetOutput(prNumber int, key string, value string) error {
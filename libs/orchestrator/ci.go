@@ -6,9 +6,14 @@ type PullRequestService interface {
 	ListIssues() ([]*Issue, error)
 	PublishIssue(title string, body string) (int64, error)
 	EditComment(prNumber int, id interface{}, comment string) error
+	// MaxCommentLength is the size of the longest comment the service accepts
+	MaxCommentLength() int
 	CreateCommentReaction(id interface{}, reaction string) error
 	GetComments(prNumber int) ([]Comment, error)
 	GetApprovals(prNumber int) ([]string, error)
+	GetLabels(prNumber int) ([]string, error)
+	// GetPullRequestDetails returns the author, branches and draft state of a pull request
+	GetPullRequestDetails(prNumber int) (*PullRequestDetails, error)
 	// SetStatus set status of specified pull/merge request, status could be: "pending", "failure", "success"
 	SetStatus(prNumber int, status string, statusContext string) error
 	GetCombinedPullRequestStatus(prNumber int) (string, error)
@@ -39,6 +44,37 @@ type Comment struct {
 	Url  string
 }
 
+type PullRequestDetails struct {
+	Author     string
+	BaseBranch string
+	HeadBranch string
+	Draft      bool
+}
+
 type PullRequestComment interface {
 	GetUrl() (string, error)
 }
+
+// CheckRunReporter is implemented by services that report project statuses as check runs
+type CheckRunReporter interface {
+	// SetCheckRunOutput attaches output to the check run of statusContext
+	SetCheckRunOutput(prNumber int, statusContext string, output CheckRunOutput) error
+}
+
+type CheckRunOutput struct {
+	Title string
+	// Summary and Text are markdown, Text usually holds the terraform output
+	Summary     string
+	Text        string
+	Annotations []CheckRunAnnotation
+}
+
+// CheckRunAnnotation points at lines of a file in the repository, Level is notice, warning or failure
+type CheckRunAnnotation struct {
+	Path      string
+	StartLine int
+	EndLine   int
+	Level     string
+	Title     string
+	Message   string
+}
@@ -0,0 +1,232 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	orchestrator "github.com/diggerhq/digger/libs/orchestrator"
+	"github.com/google/go-github/v61/github"
+)
+
+const (
+	// CheckRunActionPlan and CheckRunActionApply identify the buttons of completed check runs
+	CheckRunActionPlan  = "plan"
+	CheckRunActionApply = "apply"
+
+	// github rejects check run outputs with longer summaries or texts
+	maxCheckRunOutputLength = 65535
+	// github accepts at most this many annotations per request
+	maxCheckRunAnnotations = 50
+)
+
+// CheckRunsService reports project statuses as check runs instead of commit statuses. Check runs hold a markdown
+// summary, the terraform output and annotations
+type CheckRunsService struct {
+	GithubService
+	// AppId is the GitHub App the client authenticates as. GitHub only sends the requested_action events of check
+	// run buttons to the app that created the check run, so only check runs of an App offer plan and apply buttons.
+	// Check runs the App created earlier, e.g. while handling another request, are looked up by name
+	AppId       int64
+	mu          sync.Mutex
+	checkRunIds map[string]int64
+	headSha     map[int]string
+}
+
+func NewCheckRunsService(svc GithubService) *CheckRunsService {
+	return &CheckRunsService{
+		GithubService: svc,
+		checkRunIds:   map[string]int64{},
+		headSha:       map[int]string{},
+	}
+}
+
+// CheckRunExternalId identifies the project and pull request of a check run for the webhook handling its actions
+func CheckRunExternalId(prNumber int, projectName string) string {
+	return fmt.Sprintf("mantis:%v:%v", prNumber, projectName)
+}
+
+// ParseCheckRunExternalId returns the pull request number and project of an id created by CheckRunExternalId
+func ParseCheckRunExternalId(externalId string) (int, string, error) {
+	parts := strings.SplitN(externalId, ":", 3)
+	if len(parts) != 3 || parts[0] != "mantis" {
+		return 0, "", fmt.Errorf("not a mantis check run: %v", externalId)
+	}
+	prNumber, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid pull request number in check run id %v: %v", externalId, err)
+	}
+	return prNumber, parts[2], nil
+}
+
+// SetStatus starts the check run of statusContext for pending statuses and completes it for success and failure
+func (c *CheckRunsService) SetStatus(prNumber int, status string, statusContext string) error {
+	checkStatus := "in_progress"
+	var conclusion *string
+	var actions []*github.CheckRunAction
+	if status != "pending" {
+		checkStatus = "completed"
+		if status == "success" {
+			conclusion = github.String("success")
+		} else {
+			conclusion = github.String("failure")
+		}
+		if c.AppId != 0 {
+			actions = checkRunActions(statusContext, status == "success")
+		}
+	}
+	return c.upsertCheckRun(prNumber, statusContext, checkStatus, conclusion, nil, actions)
+}
+
+func (c *CheckRunsService) SetCheckRunOutput(prNumber int, statusContext string, output orchestrator.CheckRunOutput) error {
+	return c.upsertCheckRun(prNumber, statusContext, "", nil, checkRunOutput(output), nil)
+}
+
+func (c *CheckRunsService) upsertCheckRun(prNumber int, statusContext string, status string, conclusion *string, output *github.CheckRunOutput, actions []*github.CheckRunAction) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	externalId := CheckRunExternalId(prNumber, projectFromStatusContext(statusContext))
+	var statusPtr *string
+	if status != "" {
+		statusPtr = &status
+	}
+
+	id, exists := c.checkRunIds[statusContext]
+	if !exists && c.AppId != 0 {
+		var err error
+		id, exists, err = c.findCheckRun(prNumber, statusContext, externalId)
+		if err != nil {
+			return err
+		}
+	}
+	if exists {
+		_, _, err := c.Client.Checks.UpdateCheckRun(context.Background(), c.Owner, c.RepoName, id, github.UpdateCheckRunOptions{
+			Name:       statusContext,
+			ExternalID: &externalId,
+			Status:     statusPtr,
+			Conclusion: conclusion,
+			Output:     output,
+			Actions:    actions,
+		})
+		if err != nil {
+			return fmt.Errorf("error updating check run %v: %v", statusContext, err)
+		}
+		return nil
+	}
+
+	sha, err := c.pullRequestHeadSha(prNumber)
+	if err != nil {
+		return err
+	}
+	if statusPtr == nil {
+		statusPtr = github.String("in_progress")
+	}
+	checkRun, _, err := c.Client.Checks.CreateCheckRun(context.Background(), c.Owner, c.RepoName, github.CreateCheckRunOptions{
+		Name:       statusContext,
+		HeadSHA:    sha,
+		ExternalID: &externalId,
+		Status:     statusPtr,
+		Conclusion: conclusion,
+		Output:     output,
+		Actions:    actions,
+	})
+	if err != nil {
+		return fmt.Errorf("error creating check run %v: %v", statusContext, err)
+	}
+	c.checkRunIds[statusContext] = checkRun.GetID()
+	return nil
+}
+
+// findCheckRun looks up the latest check run of the App for statusContext on the head of the pull request
+func (c *CheckRunsService) findCheckRun(prNumber int, statusContext string, externalId string) (int64, bool, error) {
+	sha, err := c.pullRequestHeadSha(prNumber)
+	if err != nil {
+		return 0, false, err
+	}
+	checkRuns, _, err := c.Client.Checks.ListCheckRunsForRef(context.Background(), c.Owner, c.RepoName, sha, &github.ListCheckRunsOptions{
+		CheckName: &statusContext,
+		AppID:     &c.AppId,
+	})
+	if err != nil {
+		return 0, false, fmt.Errorf("error listing check runs %v: %v", statusContext, err)
+	}
+	for _, checkRun := range checkRuns.CheckRuns {
+		if checkRun.GetExternalID() == externalId {
+			c.checkRunIds[statusContext] = checkRun.GetID()
+			return checkRun.GetID(), true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+func (c *CheckRunsService) pullRequestHeadSha(prNumber int) (string, error) {
+	if sha, ok := c.headSha[prNumber]; ok {
+		return sha, nil
+	}
+	pr, _, err := c.Client.PullRequests.Get(context.Background(), c.Owner, c.RepoName, prNumber)
+	if err != nil {
+		return "", fmt.Errorf("error getting pull request: %v", err)
+	}
+	c.headSha[prNumber] = pr.GetHead().GetSHA()
+	return c.headSha[prNumber], nil
+}
+
+// projectFromStatusContext strips the command from contexts like "project/plan"
+func projectFromStatusContext(statusContext string) string {
+	if i := strings.LastIndex(statusContext, "/"); i > 0 {
+		return statusContext[:i]
+	}
+	return statusContext
+}
+
+// checkRunActions offers a re-run of the command, and an apply once a plan succeeded
+func checkRunActions(statusContext string, succeeded bool) []*github.CheckRunAction {
+	actions := []*github.CheckRunAction{}
+	if strings.HasSuffix(statusContext, "/plan") {
+		actions = append(actions, &github.CheckRunAction{Label: "Re-run plan", Description: "Run mantis plan for this project", Identifier: CheckRunActionPlan})
+		if succeeded {
+			actions = append(actions, &github.CheckRunAction{Label: "Apply", Description: "Run mantis apply for this project", Identifier: CheckRunActionApply})
+		}
+	} else if strings.HasSuffix(statusContext, "/apply") && !succeeded {
+		actions = append(actions, &github.CheckRunAction{Label: "Re-run apply", Description: "Run mantis apply for this project", Identifier: CheckRunActionApply})
+	}
+	return actions
+}
+
+func checkRunOutput(output orchestrator.CheckRunOutput) *github.CheckRunOutput {
+	result := &github.CheckRunOutput{
+		Title:   github.String(output.Title),
+		Summary: github.String(truncateCheckRunText(output.Summary)),
+	}
+	if output.Text != "" {
+		result.Text = github.String(truncateCheckRunText(output.Text))
+	}
+	for i, annotation := range output.Annotations {
+		if i == maxCheckRunAnnotations {
+			break
+		}
+		result.Annotations = append(result.Annotations, &github.CheckRunAnnotation{
+			Path:            github.String(annotation.Path),
+			StartLine:       github.Int(annotation.StartLine),
+			EndLine:         github.Int(annotation.EndLine),
+			AnnotationLevel: github.String(annotation.Level),
+			Title:           github.String(annotation.Title),
+			Message:         github.String(annotation.Message),
+		})
+	}
+	return result
+}
+
+func truncateCheckRunText(text string) string {
+	if len(text) <= maxCheckRunOutputLength {
+		return text
+	}
+	text = text[:maxCheckRunOutputLength]
+	for len(text) > 0 && !utf8.ValidString(text) {
+		text = text[:len(text)-1]
+	}
+	return text
+}
@@ -0,0 +1,127 @@
+package github
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	orchestrator "github.com/diggerhq/digger/libs/orchestrator"
+	"github.com/google/go-github/v61/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckRunExternalIdRoundTrip(t *testing.T) {
+	prNumber, project, err := ParseCheckRunExternalId(CheckRunExternalId(12, "dev/network"))
+	require.NoError(t, err)
+	assert.Equal(t, 12, prNumber)
+	assert.Equal(t, "dev/network", project)
+
+	_, _, err = ParseCheckRunExternalId("other:12:dev")
+	assert.Error(t, err)
+	_, _, err = ParseCheckRunExternalId("mantis:abc:dev")
+	assert.Error(t, err)
+}
+
+func TestCheckRunsServiceCreatesThenUpdatesCheckRun(t *testing.T) {
+	var created, updated []map[string]interface{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/owner/repo/pulls/7", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"number": 7, "head": {"sha": "abc123"}}`))
+	})
+	mux.HandleFunc("/repos/owner/repo/check-runs", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		created = append(created, body)
+		w.Write([]byte(`{"id": 42}`))
+	})
+	mux.HandleFunc("/repos/owner/repo/check-runs/42", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		updated = append(updated, body)
+		w.Write([]byte(`{"id": 42}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := github.NewClient(nil)
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+	checks := NewCheckRunsService(GithubService{Client: client, Owner: "owner", RepoName: "repo"})
+
+	require.NoError(t, checks.SetStatus(7, "pending", "dev/plan"))
+	require.NoError(t, checks.SetCheckRunOutput(7, "dev/plan", orchestrator.CheckRunOutput{
+		Title:   "Plan succeeded",
+		Summary: "summary",
+		Annotations: []orchestrator.CheckRunAnnotation{
+			{Path: "dev/main.tf", StartLine: 3, EndLine: 3, Level: "failure", Title: "S3_001", Message: "bucket is public"},
+		},
+	}))
+	require.NoError(t, checks.SetStatus(7, "success", "dev/plan"))
+
+	require.Len(t, created, 1)
+	assert.Equal(t, "dev/plan", created[0]["name"])
+	assert.Equal(t, "abc123", created[0]["head_sha"])
+	assert.Equal(t, "mantis:7:dev", created[0]["external_id"])
+	assert.Equal(t, "in_progress", created[0]["status"])
+
+	require.Len(t, updated, 2)
+	output := updated[0]["output"].(map[string]interface{})
+	assert.Equal(t, "Plan succeeded", output["title"])
+	assert.Len(t, output["annotations"], 1)
+	assert.Equal(t, "completed", updated[1]["status"])
+	assert.Equal(t, "success", updated[1]["conclusion"])
+	// GitHub would send the events of buttons to the creator of the check run, which is not an App here
+	assert.NotContains(t, updated[1], "actions")
+}
+
+func TestAppCheckRunsServiceCompletesCheckRunOfEarlierRequest(t *testing.T) {
+	var listQuery url.Values
+	var updated []map[string]interface{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/owner/repo/pulls/7", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"number": 7, "head": {"sha": "abc123"}}`))
+	})
+	mux.HandleFunc("/repos/owner/repo/commits/abc123/check-runs", func(w http.ResponseWriter, r *http.Request) {
+		listQuery = r.URL.Query()
+		w.Write([]byte(`{"total_count": 2, "check_runs": [{"id": 41, "external_id": "mantis:6:dev"}, {"id": 42, "external_id": "mantis:7:dev"}]}`))
+	})
+	mux.HandleFunc("/repos/owner/repo/check-runs", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected check run creation")
+	})
+	mux.HandleFunc("/repos/owner/repo/check-runs/42", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		updated = append(updated, body)
+		w.Write([]byte(`{"id": 42}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := github.NewClient(nil)
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+	checks := NewCheckRunsService(GithubService{Client: client, Owner: "owner", RepoName: "repo"})
+	checks.AppId = 99
+
+	require.NoError(t, checks.SetStatus(7, "success", "dev/plan"))
+	assert.Equal(t, "dev/plan", listQuery.Get("check_name"))
+	assert.Equal(t, "99", listQuery.Get("app_id"))
+	require.Len(t, updated, 1)
+	assert.Equal(t, "success", updated[0]["conclusion"])
+	assert.Len(t, updated[0]["actions"], 2)
+}
+
+func TestCheckRunActions(t *testing.T) {
+	identifiers := func(actions []*github.CheckRunAction) []string {
+		result := []string{}
+		for _, action := range actions {
+			result = append(result, action.Identifier)
+		}
+		return result
+	}
+	assert.Equal(t, []string{CheckRunActionPlan, CheckRunActionApply}, identifiers(checkRunActions("dev/plan", true)))
+	assert.Equal(t, []string{CheckRunActionPlan}, identifiers(checkRunActions("dev/plan", false)))
+	assert.Equal(t, []string{CheckRunActionApply}, identifiers(checkRunActions("dev/apply", false)))
+	assert.Empty(t, checkRunActions("dev/apply", true))
+}
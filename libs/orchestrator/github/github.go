@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"slices"
 	"strings"
 
 	"github.com/diggerhq/digger/libs/digger_config"
@@ -176,12 +177,42 @@ func (svc GithubService) GetApprovals(prNumber int) ([]string, error) {
 	return approvals, err
 }
 
+func (svc GithubService) GetLabels(prNumber int) ([]string, error) {
+	labels, _, err := svc.Client.Issues.ListLabelsByIssue(context.Background(), svc.Owner, svc.RepoName, prNumber, &github.ListOptions{PerPage: 100})
+	if err != nil {
+		return nil, fmt.Errorf("error getting labels of pull request: %v", err)
+	}
+	names := make([]string, 0, len(labels))
+	for _, label := range labels {
+		names = append(names, label.GetName())
+	}
+	return names, nil
+}
+
+func (svc GithubService) GetPullRequestDetails(prNumber int) (*orchestrator.PullRequestDetails, error) {
+	pr, _, err := svc.Client.PullRequests.Get(context.Background(), svc.Owner, svc.RepoName, prNumber)
+	if err != nil {
+		return nil, fmt.Errorf("error getting pull request: %v", err)
+	}
+	return &orchestrator.PullRequestDetails{
+		Author:     pr.GetUser().GetLogin(),
+		BaseBranch: pr.GetBase().GetRef(),
+		HeadBranch: pr.GetHead().GetRef(),
+		Draft:      pr.GetDraft(),
+	}, nil
+}
+
 func (svc GithubService) EditComment(prNumber int, id interface{}, comment string) error {
 	commentId := id.(int64)
 	_, _, err := svc.Client.Issues.EditComment(context.Background(), svc.Owner, svc.RepoName, commentId, &github.IssueComment{Body: &comment})
 	return err
 }
 
+// MaxCommentLength is GitHub's limit for issue and pull request comments
+func (svc GithubService) MaxCommentLength() int {
+	return 65536
+}
+
 type GithubCommentReaction string
 
 const GithubCommentPlusOneReaction GithubCommentReaction = "+1"
@@ -286,6 +317,16 @@ func (svc GithubService) IsClosed(prNumber int) (bool, error) {
 	return pr.GetState() == "closed", nil
 }
 
+// IsMergedOrClosed reports whether a pull request is no longer open, API errors are returned to the caller instead of exiting
+func (svc GithubService) IsMergedOrClosed(prNumber int) (bool, error) {
+	pr, _, err := svc.Client.PullRequests.Get(context.Background(), svc.Owner, svc.RepoName, prNumber)
+	if err != nil {
+		return false, fmt.Errorf("error getting pull request: %v", err)
+	}
+	// merged pull requests are closed as well
+	return pr.GetState() == "closed", nil
+}
+
 func (svc GithubService) SetOutput(prNumber int, key string, value string) error {
 	gout := os.Getenv("GITHUB_ENV")
 	if gout == "" {
@@ -333,17 +374,48 @@ func ConvertGithubPullRequestEventToJobs(payload *github.PullRequestEvent, impac
 
 		StateEnvProvider, CommandEnvProvider := orchestrator.GetStateAndCommandProviders(project)
 		if *payload.Action == "closed" && *payload.PullRequest.Merged && *(payload.PullRequest.Base).Ref == *(payload.Repo).DefaultBranch {
+			// the pr-N workspace is a preview of the pull request and is torn down on its own. Its plans were made against
+			// the state of the preview, so the commands on commit to default run in the workspace of the project and
+			// store their plans under a different name, see ProjectPathProvider.StoredPlanFilePath
+			if project.EphemeralWorkspace {
+				jobs = append(jobs, orchestrator.Job{
+					ProjectName:        project.Name,
+					ProjectDir:         project.Dir,
+					ProjectWorkspace:   project.WorkspaceForPullRequest(pullRequestNumber),
+					EphemeralWorkspace: true,
+					ProjectWorkflow:    project.Workflow,
+					Terragrunt:         project.Terragrunt,
+					TerragruntRunAll:   project.TerragruntRunAll,
+					OpenTofu:           project.OpenTofu,
+					Commands:           slices.Clone(orchestrator.EphemeralWorkspaceTeardownCommands),
+					ApplyStage:         orchestrator.ToConfigStage(workflow.Apply),
+					PlanStage:          orchestrator.ToConfigStage(workflow.Plan),
+					RunEnvVars:         runEnvVars,
+					CommandEnvVars:     commandEnvVars,
+					EnvAllowlist:       digger_config.CollectEnvAllowlist(workflow.EnvVars),
+					StateEnvVars:       stateEnvVars,
+					PullRequestNumber:  pullRequestNumber,
+					EventName:          "pull_request",
+					Namespace:          *payload.Repo.FullName,
+					RequestedBy:        *payload.Sender.Login,
+					CommandEnvProvider: CommandEnvProvider,
+					StateEnvProvider:   StateEnvProvider,
+				})
+			}
 			jobs = append(jobs, orchestrator.Job{
 				ProjectName:        project.Name,
 				ProjectDir:         project.Dir,
 				ProjectWorkspace:   project.Workspace,
 				ProjectWorkflow:    project.Workflow,
 				Terragrunt:         project.Terragrunt,
+				TerragruntRunAll:   project.TerragruntRunAll,
+				OpenTofu:           project.OpenTofu,
 				Commands:           workflow.Configuration.OnCommitToDefault,
 				ApplyStage:         orchestrator.ToConfigStage(workflow.Apply),
 				PlanStage:          orchestrator.ToConfigStage(workflow.Plan),
 				RunEnvVars:         runEnvVars,
 				CommandEnvVars:     commandEnvVars,
+				EnvAllowlist:       digger_config.CollectEnvAllowlist(workflow.EnvVars),
 				StateEnvVars:       stateEnvVars,
 				PullRequestNumber:  pullRequestNumber,
 				EventName:          "pull_request",
@@ -356,15 +428,18 @@ func ConvertGithubPullRequestEventToJobs(payload *github.PullRequestEvent, impac
 			jobs = append(jobs, orchestrator.Job{
 				ProjectName:        project.Name,
 				ProjectDir:         project.Dir,
-				ProjectWorkspace:   project.Workspace,
+				ProjectWorkspace:   project.WorkspaceForPullRequest(pullRequestNumber),
+				EphemeralWorkspace: project.EphemeralWorkspace,
 				ProjectWorkflow:    project.Workflow,
 				Terragrunt:         project.Terragrunt,
+				TerragruntRunAll:   project.TerragruntRunAll,
 				OpenTofu:           project.OpenTofu,
 				Commands:           workflow.Configuration.OnPullRequestPushed,
 				ApplyStage:         orchestrator.ToConfigStage(workflow.Apply),
 				PlanStage:          orchestrator.ToConfigStage(workflow.Plan),
 				RunEnvVars:         runEnvVars,
 				CommandEnvVars:     commandEnvVars,
+				EnvAllowlist:       digger_config.CollectEnvAllowlist(workflow.EnvVars),
 				StateEnvVars:       stateEnvVars,
 				PullRequestNumber:  pullRequestNumber,
 				EventName:          "pull_request",
@@ -377,15 +452,18 @@ func ConvertGithubPullRequestEventToJobs(payload *github.PullRequestEvent, impac
 			jobs = append(jobs, orchestrator.Job{
 				ProjectName:        project.Name,
 				ProjectDir:         project.Dir,
-				ProjectWorkspace:   project.Workspace,
+				ProjectWorkspace:   project.WorkspaceForPullRequest(pullRequestNumber),
+				EphemeralWorkspace: project.EphemeralWorkspace,
 				ProjectWorkflow:    project.Workflow,
 				Terragrunt:         project.Terragrunt,
+				TerragruntRunAll:   project.TerragruntRunAll,
 				OpenTofu:           project.OpenTofu,
-				Commands:           workflow.Configuration.OnPullRequestClosed,
+				Commands:           orchestrator.CommandsOnPullRequestClosed(project, workflow.Configuration.OnPullRequestClosed),
 				ApplyStage:         orchestrator.ToConfigStage(workflow.Apply),
 				PlanStage:          orchestrator.ToConfigStage(workflow.Plan),
 				RunEnvVars:         runEnvVars,
 				CommandEnvVars:     commandEnvVars,
+				EnvAllowlist:       digger_config.CollectEnvAllowlist(workflow.EnvVars),
 				StateEnvVars:       stateEnvVars,
 				PullRequestNumber:  pullRequestNumber,
 				EventName:          "pull_request",
@@ -405,15 +483,18 @@ func ConvertGithubPullRequestEventToJobs(payload *github.PullRequestEvent, impac
 			jobs = append(jobs, orchestrator.Job{
 				ProjectName:        project.Name,
 				ProjectDir:         project.Dir,
-				ProjectWorkspace:   project.Workspace,
+				ProjectWorkspace:   project.WorkspaceForPullRequest(pullRequestNumber),
+				EphemeralWorkspace: project.EphemeralWorkspace,
 				ProjectWorkflow:    project.Workflow,
 				Terragrunt:         project.Terragrunt,
+				TerragruntRunAll:   project.TerragruntRunAll,
 				OpenTofu:           project.OpenTofu,
 				Commands:           commands,
 				ApplyStage:         orchestrator.ToConfigStage(workflow.Apply),
 				PlanStage:          orchestrator.ToConfigStage(workflow.Plan),
 				RunEnvVars:         runEnvVars,
 				CommandEnvVars:     commandEnvVars,
+				EnvAllowlist:       digger_config.CollectEnvAllowlist(workflow.EnvVars),
 				StateEnvVars:       stateEnvVars,
 				PullRequestNumber:  pullRequestNumber,
 				EventName:          "pull_request_converted_to_draft",
@@ -495,19 +576,22 @@ func CreateJobsForProjects(projects []digger_config.Project, command string, eve
 		runEnvVars := GetRunEnvVars(defaultBranch, prBranch, project.Name, project.Dir)
 		stateEnvVars, commandEnvVars := digger_config.CollectTerraformEnvConfig(workflow.EnvVars)
 		StateEnvProvider, CommandEnvProvider := orchestrator.GetStateAndCommandProviders(project)
-		workspace := project.Workspace
+		workspace := project.WorkspaceForPullRequest(issueNumber)
 		jobs = append(jobs, orchestrator.Job{
 			ProjectName:        project.Name,
 			ProjectDir:         project.Dir,
 			ProjectWorkspace:   workspace,
+			EphemeralWorkspace: project.EphemeralWorkspace,
 			ProjectWorkflow:    project.Workflow,
 			Terragrunt:         project.Terragrunt,
+			TerragruntRunAll:   project.TerragruntRunAll,
 			OpenTofu:           project.OpenTofu,
 			Commands:           []string{command},
 			ApplyStage:         orchestrator.ToConfigStage(workflow.Apply),
 			PlanStage:          orchestrator.ToConfigStage(workflow.Plan),
 			RunEnvVars:         runEnvVars,
 			CommandEnvVars:     commandEnvVars,
+			EnvAllowlist:       digger_config.CollectEnvAllowlist(workflow.EnvVars),
 			StateEnvVars:       stateEnvVars,
 			PullRequestNumber:  issueNumber,
 			EventName:          event, //"issue_comment",
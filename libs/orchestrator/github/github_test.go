@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/diggerhq/digger/libs/digger_config"
+	"github.com/google/go-github/v61/github"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -120,3 +121,44 @@ func TestFindAllChangedFilesOfPR(t *testing.T) {
 	// 45 changed files including 1 renamed file so the previous filename is included
 	assert.Equal(t, 46, len(files))
 }
+
+func TestConvertMergedPullRequestEventToJobsWithEphemeralWorkspace(t *testing.T) {
+	workflow := digger_config.Workflow{
+		Configuration: &digger_config.WorkflowConfiguration{OnCommitToDefault: []string{"mantis apply"}},
+	}
+	config := digger_config.DiggerConfig{Workflows: map[string]digger_config.Workflow{"default": workflow}}
+	projects := []digger_config.Project{
+		{Name: "dev", Dir: "dev", Workspace: "default", Workflow: "default", EphemeralWorkspace: true},
+		{Name: "prod", Dir: "prod", Workspace: "prod", Workflow: "default"},
+	}
+	payload := &github.PullRequestEvent{
+		Action: github.String("closed"),
+		PullRequest: &github.PullRequest{
+			Number: github.Int(7),
+			Merged: github.Bool(true),
+			Head:   &github.PullRequestBranch{Ref: github.String("feature")},
+			Base:   &github.PullRequestBranch{Ref: github.String("main")},
+		},
+		Repo:   &github.Repository{FullName: github.String("org/repo"), DefaultBranch: github.String("main")},
+		Sender: &github.User{Login: github.String("alice")},
+	}
+
+	jobs, _, err := ConvertGithubPullRequestEventToJobs(payload, projects, nil, config)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(jobs))
+
+	assert.Equal(t, "dev", jobs[0].ProjectName)
+	assert.Equal(t, "pr-7", jobs[0].ProjectWorkspace)
+	assert.True(t, jobs[0].EphemeralWorkspace)
+	assert.Equal(t, []string{"mantis destroy", "mantis delete-workspace"}, jobs[0].Commands)
+
+	assert.Equal(t, "dev", jobs[1].ProjectName)
+	assert.Equal(t, "default", jobs[1].ProjectWorkspace)
+	assert.False(t, jobs[1].EphemeralWorkspace)
+	assert.Equal(t, 7, *jobs[1].PullRequestNumber)
+	assert.Equal(t, []string{"mantis apply"}, jobs[1].Commands)
+
+	assert.Equal(t, "prod", jobs[2].ProjectName)
+	assert.Equal(t, "prod", jobs[2].ProjectWorkspace)
+	assert.Equal(t, []string{"mantis apply"}, jobs[2].Commands)
+}
@@ -17,6 +17,14 @@ func (t MockCiService) GetApprovals(prNumber int) ([]string, error) {
 	return []string{}, nil
 }
 
+func (t MockCiService) GetLabels(prNumber int) ([]string, error) {
+	return []string{}, nil
+}
+
+func (t MockCiService) GetPullRequestDetails(prNumber int) (*orchestrator.PullRequestDetails, error) {
+	return &orchestrator.PullRequestDetails{}, nil
+}
+
 func (t MockCiService) GetChangedFiles(prNumber int) ([]string, error) {
 	return nil, nil
 }
@@ -93,6 +101,10 @@ func (t MockCiService) EditComment(prNumber int, commentId interface{}, comment
 	return nil
 }
 
+func (t MockCiService) MaxCommentLength() int {
+	return 65536
+}
+
 func (t MockCiService) CreateCommentReaction(id interface{}, reaction string) error {
 	// TODO implement me
 	return nil
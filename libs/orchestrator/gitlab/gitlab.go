@@ -3,10 +3,10 @@ package gitlab
 import (
 	"fmt"
 	"log"
+	"strconv"
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
-	"github.com/diggerhq/digger/cli/pkg/utils"
 	"github.com/diggerhq/digger/libs/digger_config"
 	orchestrator "github.com/diggerhq/digger/libs/orchestrator"
 
@@ -86,6 +86,32 @@ func NewGitLabService(token string, gitLabContext *GitLabContext) (*GitLabServic
 	}, nil
 }
 
+// NewGitLabServiceForProject creates a service for the project at projectPath, e.g. group/project, outside of GitLab CI.
+// baseUrl is the API url of self-managed instances and defaults to gitlab.com
+func NewGitLabServiceForProject(token string, baseUrl string, projectPath string) (*GitLabService, error) {
+	var options []go_gitlab.ClientOptionFunc
+	if baseUrl != "" {
+		options = append(options, go_gitlab.WithBaseURL(baseUrl))
+	}
+	client, err := go_gitlab.NewClient(token, options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gitlab client: %v", err)
+	}
+	project, _, err := client.Projects.GetProject(projectPath, &go_gitlab.GetProjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get GitLab project %v: %v", projectPath, err)
+	}
+	return &GitLabService{
+		Client: client,
+		Context: &GitLabContext{
+			ProjectId:        &project.ID,
+			ProjectName:      project.Path,
+			ProjectNamespace: projectPath,
+			Token:            token,
+		},
+	}, nil
+}
+
 func ProcessGitLabEvent(gitlabContext *GitLabContext, diggerConfig *digger_config.DiggerConfig, service *GitLabService) ([]digger_config.Project, *digger_config.Project, error) {
 	var impactedProjects []digger_config.Project
 
@@ -106,7 +132,7 @@ func ProcessGitLabEvent(gitlabContext *GitLabContext, diggerConfig *digger_confi
 	case MergeRequestComment:
 		diggerCommand := strings.ToLower(gitlabContext.DiggerCommand)
 		diggerCommand = strings.TrimSpace(diggerCommand)
-		requestedProject := utils.ParseProjectName(diggerCommand)
+		requestedProject := orchestrator.ParseProjectName(diggerCommand)
 
 		if requestedProject == "" {
 			return impactedProjects, nil, nil
@@ -155,28 +181,35 @@ func (gitlabService GitLabService) GetUserTeams(organisation string, user string
 func (gitlabService GitLabService) PublishComment(prNumber int, comment string) (*orchestrator.Comment, error) {
 	discussionId := gitlabService.Context.DiscussionID
 	projectId := *gitlabService.Context.ProjectId
-	mergeRequestIID := *gitlabService.Context.MergeRequestIId
-	commentOpt := &go_gitlab.AddMergeRequestDiscussionNoteOptions{Body: &comment}
 
-	log.Printf("PublishComment mergeRequestID : %d, projectId: %d, mergeRequestIID: %d, discussionId: %s \n", mergeRequestIID, projectId, mergeRequestIID, discussionId)
+	log.Printf("PublishComment projectId: %d, mergeRequestIID: %d, discussionId: %s \n", projectId, prNumber, discussionId)
 
+	var note *go_gitlab.Note
 	if discussionId == "" {
 		commentOpt := &go_gitlab.CreateMergeRequestDiscussionOptions{Body: &comment}
-		discussion, _, err := gitlabService.Client.Discussions.CreateMergeRequestDiscussion(projectId, mergeRequestIID, commentOpt)
+		discussion, _, err := gitlabService.Client.Discussions.CreateMergeRequestDiscussion(projectId, prNumber, commentOpt)
 		if err != nil {
 			log.Printf("Failed to publish a comment. %v\n", err)
-			print(err.Error())
+			return nil, fmt.Errorf("failed to publish comment: %v", err)
 		}
-		discussionId = discussion.ID
-		return nil, err
+		if len(discussion.Notes) == 0 {
+			return nil, fmt.Errorf("discussion %v created without notes", discussion.ID)
+		}
+		note = discussion.Notes[0]
 	} else {
-		_, _, err := gitlabService.Client.Discussions.AddMergeRequestDiscussionNote(projectId, mergeRequestIID, discussionId, commentOpt)
+		commentOpt := &go_gitlab.AddMergeRequestDiscussionNoteOptions{Body: &comment}
+		var err error
+		note, _, err = gitlabService.Client.Discussions.AddMergeRequestDiscussionNote(projectId, prNumber, discussionId, commentOpt)
 		if err != nil {
 			log.Printf("Failed to publish a comment. %v\n", err)
-			print(err.Error())
+			return nil, fmt.Errorf("failed to publish comment: %v", err)
 		}
-		return nil, err
 	}
+	return &orchestrator.Comment{
+		Id:   note.ID,
+		Body: &note.Body,
+		Url:  noteUrl(gitlabService.mergeRequestUrl(prNumber), note.ID),
+	}, nil
 }
 
 func (svc GitLabService) ListIssues() ([]*orchestrator.Issue, error) {
@@ -202,13 +235,12 @@ func (gitlabService GitLabService) GetCombinedPullRequestStatus(mergeRequestID i
 	return "success", nil
 }
 
-func (gitlabService GitLabService) MergePullRequest(mergeRequestID int) error {
+func (gitlabService GitLabService) MergePullRequest(mergeRequestIID int) error {
 	projectId := *gitlabService.Context.ProjectId
-	mergeRequestIID := *gitlabService.Context.MergeRequestIId
 	mergeWhenPipelineSucceeds := true
 	opt := &go_gitlab.AcceptMergeRequestOptions{MergeWhenPipelineSucceeds: &mergeWhenPipelineSucceeds}
 
-	log.Printf("MergePullRequest mergeRequestID : %d, projectId: %d, mergeRequestIID: %d, \n", mergeRequestID, projectId, mergeRequestIID)
+	log.Printf("MergePullRequest projectId: %d, mergeRequestIID: %d, \n", projectId, mergeRequestIID)
 
 	_, _, err := gitlabService.Client.MergeRequests.AcceptMergeRequest(projectId, mergeRequestIID, opt)
 	if err != nil {
@@ -218,50 +250,131 @@ func (gitlabService GitLabService) MergePullRequest(mergeRequestID int) error {
 	return nil
 }
 
-func (gitlabService GitLabService) IsMergeable(mergeRequestID int) (bool, error) {
-	return gitlabService.Context.IsMeargeable, nil
+func (gitlabService GitLabService) IsMergeable(mergeRequestIID int) (bool, error) {
+	// pipelines triggered by the digger webhook are told whether the merge request is mergeable
+	if gitlabService.Context.EventType != "" {
+		return gitlabService.Context.IsMeargeable, nil
+	}
+	mergeRequest, err := getMergeRequest(gitlabService, mergeRequestIID)
+	if err != nil {
+		return false, err
+	}
+	return mergeRequest.State == "opened" && !mergeRequest.Draft && !mergeRequest.HasConflicts, nil
 }
 
-func (gitlabService GitLabService) IsClosed(mergeRequestID int) (bool, error) {
-	mergeRequest := getMergeRequest(gitlabService)
-	if mergeRequest.State == "closed" {
-		return true, nil
+func (gitlabService GitLabService) IsClosed(mergeRequestIID int) (bool, error) {
+	mergeRequest, err := getMergeRequest(gitlabService, mergeRequestIID)
+	if err != nil {
+		return false, err
 	}
-	return false, nil
+	return mergeRequest.State == "closed", nil
 }
 
-func (gitlabService GitLabService) IsMerged(mergeRequestID int) (bool, error) {
-	mergeRequest := getMergeRequest(gitlabService)
-	if mergeRequest.State == "merged" {
-		return true, nil
+func (gitlabService GitLabService) IsMerged(mergeRequestIID int) (bool, error) {
+	mergeRequest, err := getMergeRequest(gitlabService, mergeRequestIID)
+	if err != nil {
+		return false, err
 	}
-	return false, nil
+	return mergeRequest.State == "merged", nil
 }
 
 func (gitlabService GitLabService) EditComment(prNumber int, id interface{}, comment string) error {
-	//TODO implement me
+	noteId, err := strconv.Atoi(fmt.Sprintf("%v", id))
+	if err != nil {
+		return fmt.Errorf("invalid note id %v: %v", id, err)
+	}
+	opt := &go_gitlab.UpdateMergeRequestNoteOptions{Body: &comment}
+	_, _, err = gitlabService.Client.Notes.UpdateMergeRequestNote(*gitlabService.Context.ProjectId, prNumber, noteId, opt)
+	if err != nil {
+		return fmt.Errorf("failed to edit note %v: %v", noteId, err)
+	}
 	return nil
 }
 
+// MaxCommentLength is GitLab's limit for merge request notes
+func (gitlabService GitLabService) MaxCommentLength() int {
+	return 1000000
+}
+
 func (gitlabService GitLabService) CreateCommentReaction(id interface{}, reaction string) error {
 	// TODO implement me
 	return nil
 }
 
+// GetComments returns the notes of a merge request oldest first, system notes like "added 1 commit" are left out
 func (gitlabService GitLabService) GetComments(prNumber int) ([]orchestrator.Comment, error) {
-	//TODO implement me
-	return nil, nil
+	mergeRequestUrl := gitlabService.mergeRequestUrl(prNumber)
+	orderBy, sort := "created_at", "asc"
+	opt := &go_gitlab.ListMergeRequestNotesOptions{
+		ListOptions: go_gitlab.ListOptions{PerPage: 100},
+		OrderBy:     &orderBy,
+		Sort:        &sort,
+	}
+	var comments []orchestrator.Comment
+	for {
+		notes, resp, err := gitlabService.Client.Notes.ListMergeRequestNotes(*gitlabService.Context.ProjectId, prNumber, opt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list notes of merge request %v: %v", prNumber, err)
+		}
+		for _, note := range notes {
+			if note.System {
+				continue
+			}
+			body := note.Body
+			comments = append(comments, orchestrator.Comment{Id: note.ID, Body: &body, Url: noteUrl(mergeRequestUrl, note.ID)})
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	return comments, nil
+}
+
+func (gitlabService GitLabService) GetApprovals(prNumber int) ([]string, error) {
+	approvals, _, err := gitlabService.Client.MergeRequestApprovals.GetConfiguration(*gitlabService.Context.ProjectId, prNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get approvals of merge request %v: %v", prNumber, err)
+	}
+	approvedBy := make([]string, 0)
+	for _, approver := range approvals.ApprovedBy {
+		if approver.User != nil {
+			approvedBy = append(approvedBy, approver.User.Username)
+		}
+	}
+	return approvedBy, nil
 }
 
-func (gitlabService *GitLabService) GetApprovals(prNumber int) ([]string, error) {
-	approvals := make([]string, 0)
-	// TODO: implement me
-	return approvals, nil
+func (gitlabService GitLabService) GetLabels(prNumber int) ([]string, error) {
+	mergeRequest, _, err := gitlabService.Client.MergeRequests.GetMergeRequest(*gitlabService.Context.ProjectId, prNumber, &go_gitlab.GetMergeRequestsOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get merge request %v: %v", prNumber, err)
+	}
+	return mergeRequest.Labels, nil
+}
+
+func (gitlabService GitLabService) GetPullRequestDetails(prNumber int) (*orchestrator.PullRequestDetails, error) {
+	mergeRequest, _, err := gitlabService.Client.MergeRequests.GetMergeRequest(*gitlabService.Context.ProjectId, prNumber, &go_gitlab.GetMergeRequestsOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get merge request %v: %v", prNumber, err)
+	}
+	details := &orchestrator.PullRequestDetails{
+		BaseBranch: mergeRequest.TargetBranch,
+		HeadBranch: mergeRequest.SourceBranch,
+		Draft:      mergeRequest.Draft,
+	}
+	if mergeRequest.Author != nil {
+		details.Author = mergeRequest.Author.Username
+	}
+	return details, nil
 }
 
 func (gitlabService GitLabService) GetBranchName(prNumber int) (string, string, error) {
-	//TODO implement me
-	return "", "", nil
+	mergeRequest, err := getMergeRequest(gitlabService, prNumber)
+	if err != nil {
+		return "", "", err
+	}
+	return mergeRequest.SourceBranch, mergeRequest.SHA, nil
 }
 
 func (svc *GitLabService) SetOutput(prNumber int, key string, value string) error {
@@ -269,17 +382,32 @@ func (svc *GitLabService) SetOutput(prNumber int, key string, value string) erro
 	return nil
 }
 
-func getMergeRequest(gitlabService GitLabService) *go_gitlab.MergeRequest {
+func getMergeRequest(gitlabService GitLabService, mergeRequestIID int) (*go_gitlab.MergeRequest, error) {
 	projectId := *gitlabService.Context.ProjectId
-	mergeRequestIID := *gitlabService.Context.MergeRequestIId
 	log.Printf("getMergeRequest mergeRequestIID : %d, projectId: %d \n", mergeRequestIID, projectId)
 	opt := &go_gitlab.GetMergeRequestsOptions{}
 	mergeRequest, _, err := gitlabService.Client.MergeRequests.GetMergeRequest(projectId, mergeRequestIID, opt)
 	if err != nil {
 		log.Printf("Failed to get a MergeRequest: %d, %v \n", mergeRequestIID, err)
-		print(err.Error())
+		return nil, fmt.Errorf("failed to get merge request %v: %v", mergeRequestIID, err)
+	}
+	return mergeRequest, nil
+}
+
+// mergeRequestUrl is used to link to notes, it is empty when the merge request can't be fetched
+func (gitlabService GitLabService) mergeRequestUrl(mergeRequestIID int) string {
+	mergeRequest, err := getMergeRequest(gitlabService, mergeRequestIID)
+	if err != nil {
+		return ""
+	}
+	return mergeRequest.WebURL
+}
+
+func noteUrl(mergeRequestUrl string, noteId int) string {
+	if mergeRequestUrl == "" {
+		return ""
 	}
-	return mergeRequest
+	return fmt.Sprintf("%v#note_%v", mergeRequestUrl, noteId)
 }
 
 type GitLabEvent struct {
@@ -325,6 +453,7 @@ func ConvertGitLabEventToCommands(event GitLabEvent, gitLabContext *GitLabContex
 				ProjectDir:         project.Dir,
 				ProjectWorkspace:   project.Workspace,
 				Terragrunt:         project.Terragrunt,
+				TerragruntRunAll:   project.TerragruntRunAll,
 				OpenTofu:           project.OpenTofu,
 				Commands:           workflow.Configuration.OnPullRequestPushed,
 				ApplyStage:         orchestrator.ToConfigStage(workflow.Apply),
@@ -335,6 +464,7 @@ func ConvertGitLabEventToCommands(event GitLabEvent, gitLabContext *GitLabContex
 				Namespace:          gitLabContext.ProjectNamespace,
 				StateEnvVars:       stateEnvVars,
 				CommandEnvVars:     commandEnvVars,
+				EnvAllowlist:       digger_config.CollectEnvAllowlist(workflow.EnvVars),
 				StateEnvProvider:   StateEnvProvider,
 				CommandEnvProvider: CommandEnvProvider,
 			})
@@ -368,6 +498,7 @@ func ConvertGitLabEventToCommands(event GitLabEvent, gitLabContext *GitLabContex
 				ProjectDir:         project.Dir,
 				ProjectWorkspace:   project.Workspace,
 				Terragrunt:         project.Terragrunt,
+				TerragruntRunAll:   project.TerragruntRunAll,
 				OpenTofu:           project.OpenTofu,
 				Commands:           workflow.Configuration.OnPullRequestClosed,
 				ApplyStage:         orchestrator.ToConfigStage(workflow.Apply),
@@ -378,6 +509,7 @@ func ConvertGitLabEventToCommands(event GitLabEvent, gitLabContext *GitLabContex
 				Namespace:          gitLabContext.ProjectNamespace,
 				StateEnvVars:       stateEnvVars,
 				CommandEnvVars:     commandEnvVars,
+				EnvAllowlist:       digger_config.CollectEnvAllowlist(workflow.EnvVars),
 				StateEnvProvider:   StateEnvProvider,
 				CommandEnvProvider: CommandEnvProvider,
 			})
@@ -409,7 +541,7 @@ func ConvertGitLabEventToCommands(event GitLabEvent, gitLabContext *GitLabContex
 						workflow = workflows["default"]
 					}
 					workspace := project.Workspace
-					workspaceOverride, err := utils.ParseWorkspace(diggerCommand)
+					workspaceOverride, err := orchestrator.ParseWorkspace(diggerCommand)
 					if err != nil {
 						return []orchestrator.Job{}, false, err
 					}
@@ -423,6 +555,7 @@ func ConvertGitLabEventToCommands(event GitLabEvent, gitLabContext *GitLabContex
 						ProjectDir:         project.Dir,
 						ProjectWorkspace:   workspace,
 						Terragrunt:         project.Terragrunt,
+						TerragruntRunAll:   project.TerragruntRunAll,
 						OpenTofu:           project.OpenTofu,
 						Commands:           []string{command},
 						ApplyStage:         orchestrator.ToConfigStage(workflow.Apply),
@@ -433,6 +566,7 @@ func ConvertGitLabEventToCommands(event GitLabEvent, gitLabContext *GitLabContex
 						Namespace:          gitLabContext.ProjectNamespace,
 						StateEnvVars:       stateEnvVars,
 						CommandEnvVars:     commandEnvVars,
+						EnvAllowlist:       digger_config.CollectEnvAllowlist(workflow.EnvVars),
 						StateEnvProvider:   StateEnvProvider,
 						CommandEnvProvider: CommandEnvProvider,
 					})
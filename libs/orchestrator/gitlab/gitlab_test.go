@@ -0,0 +1,154 @@
+package gitlab
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGitLabContext(t *testing.T) {
+	t.Setenv("CI_PIPELINE_SOURCE", "push")
+	t.Setenv("CI_PIPELINE_ID", "1")
+	t.Setenv("CI_PIPELINE_IID", "2")
+
+	context, err := ParseGitLabContext()
+	assert.NoError(t, err)
+	assert.NotNil(t, context)
+	assert.Equal(t, PipelineSourceType("push"), context.PipelineSource)
+	assert.Equal(t, 1, *context.PipelineId)
+	assert.Equal(t, 2, *context.PipelineIId)
+	assert.Nil(t, context.MergeRequestId)
+	assert.Nil(t, context.MergeRequestIId)
+}
+
+func TestOpenMergeRequestEvent(t *testing.T) {
+	t.Setenv("CI_PIPELINE_SOURCE", "push")
+	t.Setenv("CI_PIPELINE_ID", "1")
+	t.Setenv("CI_PIPELINE_IID", "2")
+
+	context, err := ParseGitLabContext()
+	assert.NoError(t, err)
+	assert.NotNil(t, context)
+	assert.Equal(t, PipelineSourceType("push"), context.PipelineSource)
+	assert.Equal(t, 1, *context.PipelineId)
+	assert.Equal(t, 2, *context.PipelineIId)
+	assert.Nil(t, context.MergeRequestId)
+	assert.Nil(t, context.MergeRequestIId)
+}
+
+func newTestGitLabService(t *testing.T, handler http.HandlerFunc) *GitLabService {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() == "/api/v4/projects/group%2Finfra" {
+			w.Write([]byte(`{"id": 42, "path": "infra"}`))
+			return
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	service, err := NewGitLabServiceForProject("token", server.URL, "group/infra")
+	require.NoError(t, err)
+	assert.Equal(t, 42, *service.Context.ProjectId)
+	return service
+}
+
+func TestGitLabGetCommentsSkipsSystemNotes(t *testing.T) {
+	service := newTestGitLabService(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v4/projects/42/merge_requests/7":
+			w.Write([]byte(`{"iid": 7, "web_url": "https://gitlab.example.com/group/infra/-/merge_requests/7"}`))
+		case "/api/v4/projects/42/merge_requests/7/notes":
+			if r.URL.Query().Get("page") == "2" {
+				w.Write([]byte(`[{"id": 3, "body": "apply output"}]`))
+				return
+			}
+			w.Header().Set("X-Next-Page", "2")
+			w.Write([]byte(`[{"id": 1, "body": "plan output"}, {"id": 2, "body": "added 1 commit", "system": true}]`))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	comments, err := service.GetComments(7)
+	require.NoError(t, err)
+	require.Len(t, comments, 2)
+	assert.Equal(t, 1, comments[0].Id)
+	assert.Equal(t, "plan output", *comments[0].Body)
+	assert.Equal(t, "https://gitlab.example.com/group/infra/-/merge_requests/7#note_1", comments[0].Url)
+	assert.Equal(t, "apply output", *comments[1].Body)
+}
+
+func TestGitLabEditComment(t *testing.T) {
+	var body map[string]string
+	service := newTestGitLabService(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method)
+		assert.Equal(t, "/api/v4/projects/42/merge_requests/7/notes/15", r.URL.Path)
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte(`{"id": 15}`))
+	})
+
+	require.NoError(t, service.EditComment(7, "15", "updated"))
+	assert.Equal(t, "updated", body["body"])
+	assert.Error(t, service.EditComment(7, "not-a-note", "updated"))
+}
+
+func TestGitLabPublishCommentReturnsNote(t *testing.T) {
+	service := newTestGitLabService(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v4/projects/42/merge_requests/7/discussions":
+			w.Write([]byte(`{"id": "abc", "notes": [{"id": 21, "body": "plan output"}]}`))
+		case "/api/v4/projects/42/merge_requests/7":
+			w.Write([]byte(`{"iid": 7, "web_url": "https://gitlab.example.com/group/infra/-/merge_requests/7"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	comment, err := service.PublishComment(7, "plan output")
+	require.NoError(t, err)
+	assert.Equal(t, 21, comment.Id)
+	assert.Equal(t, "https://gitlab.example.com/group/infra/-/merge_requests/7#note_21", comment.Url)
+}
+
+func TestGitLabGetApprovals(t *testing.T) {
+	service := newTestGitLabService(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v4/projects/42/merge_requests/7/approvals", r.URL.Path)
+		w.Write([]byte(`{"approved_by": [{"user": {"username": "alice"}}, {"user": {"username": "bob"}}]}`))
+	})
+
+	approvals, err := service.GetApprovals(7)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alice", "bob"}, approvals)
+}
+
+func TestGitLabIsMergeableOutsideWebhookPipelines(t *testing.T) {
+	service := newTestGitLabService(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"iid": 7, "state": "opened", "has_conflicts": true}`))
+	})
+
+	mergeable, err := service.IsMergeable(7)
+	require.NoError(t, err)
+	assert.False(t, mergeable)
+
+	service.Context.EventType = MergeRequestComment
+	service.Context.IsMeargeable = true
+	mergeable, err = service.IsMergeable(7)
+	require.NoError(t, err)
+	assert.True(t, mergeable)
+}
+
+func TestGitLabGetBranchName(t *testing.T) {
+	service := newTestGitLabService(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v4/projects/42/merge_requests/7", r.URL.Path)
+		w.Write([]byte(`{"iid": 7, "source_branch": "vpc-changes", "sha": "4f2a9c1"}`))
+	})
+
+	branch, sha, err := service.GetBranchName(7)
+	require.NoError(t, err)
+	assert.Equal(t, "vpc-changes", branch)
+	assert.Equal(t, "4f2a9c1", sha)
+}
@@ -22,7 +22,9 @@ type JobJson struct {
 	ProjectName             string            `json:"projectName"`
 	ProjectDir              string            `json:"projectDir"`
 	ProjectWorkspace        string            `json:"projectWorkspace"`
+	EphemeralWorkspace      bool              `json:"ephemeralWorkspace"`
 	Terragrunt              bool              `json:"terragrunt"`
+	TerragruntRunAll        bool              `json:"terragruntRunAll"`
 	OpenTofu                bool              `json:"opentofu"`
 	Commands                []string          `json:"commands"`
 	ApplyStage              StageJson         `json:"applyStage"`
@@ -35,6 +37,7 @@ type JobJson struct {
 	RequestedBy             string            `json:"requestedBy"`
 	Namespace               string            `json:"namespace"`
 	RunEnvVars              map[string]string `json:"runEnvVars"`
+	EnvAllowlist            []string          `json:"envAllowlist"`
 	StateEnvVars            map[string]string `json:"stateEnvVars"`
 	CommandEnvVars          map[string]string `json:"commandEnvVars"`
 	AwsRoleRegion           string            `json:"aws_role_region"`
@@ -70,8 +73,10 @@ func JobToJson(job Job, jobType DiggerCommand, organisationName string, branch s
 		ProjectName:             job.ProjectName,
 		ProjectDir:              job.ProjectDir,
 		ProjectWorkspace:        job.ProjectWorkspace,
+		EphemeralWorkspace:      job.EphemeralWorkspace,
 		OpenTofu:                job.OpenTofu,
 		Terragrunt:              job.Terragrunt,
+		TerragruntRunAll:        job.TerragruntRunAll,
 		Commands:                job.Commands,
 		ApplyStage:              stageToJson(job.ApplyStage),
 		PlanStage:               stageToJson(job.PlanStage),
@@ -83,6 +88,7 @@ func JobToJson(job Job, jobType DiggerCommand, organisationName string, branch s
 		RequestedBy:             job.RequestedBy,
 		Namespace:               job.Namespace,
 		RunEnvVars:              job.RunEnvVars,
+		EnvAllowlist:            job.EnvAllowlist,
 		StateEnvVars:            job.StateEnvVars,
 		CommandEnvVars:          job.CommandEnvVars,
 		AwsRoleRegion:           region,
@@ -99,8 +105,10 @@ func JsonToJob(jobJson JobJson) Job {
 		ProjectName:        jobJson.ProjectName,
 		ProjectDir:         jobJson.ProjectDir,
 		ProjectWorkspace:   jobJson.ProjectWorkspace,
+		EphemeralWorkspace: jobJson.EphemeralWorkspace,
 		OpenTofu:           jobJson.OpenTofu,
 		Terragrunt:         jobJson.Terragrunt,
+		TerragruntRunAll:   jobJson.TerragruntRunAll,
 		Commands:           jobJson.Commands,
 		ApplyStage:         jsonToStage(jobJson.ApplyStage),
 		PlanStage:          jsonToStage(jobJson.PlanStage),
@@ -110,6 +118,7 @@ func JsonToJob(jobJson JobJson) Job {
 		RequestedBy:        jobJson.RequestedBy,
 		Namespace:          jobJson.Namespace,
 		RunEnvVars:         jobJson.RunEnvVars,
+		EnvAllowlist:       jobJson.EnvAllowlist,
 		StateEnvVars:       jobJson.StateEnvVars,
 		CommandEnvVars:     jobJson.CommandEnvVars,
 		StateEnvProvider:   GetProviderFromRole(jobJson.StateRoleName, jobJson.AwsRoleRegion),
@@ -73,11 +73,25 @@ func (mockGithubPullrequestManager *MockGithubPullrequestManager) GetApprovals(p
 	return []string{}, nil
 }
 
+func (mockGithubPullrequestManager *MockGithubPullrequestManager) GetLabels(prNumber int) ([]string, error) {
+	mockGithubPullrequestManager.commands = append(mockGithubPullrequestManager.commands, "GetLabels")
+	return []string{}, nil
+}
+
+func (mockGithubPullrequestManager *MockGithubPullrequestManager) GetPullRequestDetails(prNumber int) (*PullRequestDetails, error) {
+	mockGithubPullrequestManager.commands = append(mockGithubPullrequestManager.commands, "GetPullRequestDetails")
+	return &PullRequestDetails{}, nil
+}
+
 func (mockGithubPullrequestManager *MockGithubPullrequestManager) EditComment(prNumber int, commentId interface{}, comment string) error {
 	mockGithubPullrequestManager.commands = append(mockGithubPullrequestManager.commands, "EditComment")
 	return nil
 }
 
+func (mockGithubPullrequestManager *MockGithubPullrequestManager) MaxCommentLength() int {
+	return 65536
+}
+
 func (mockGithubPullrequestManager *MockGithubPullrequestManager) CreateCommentReaction(id interface{}, reaction string) error {
 	mockGithubPullrequestManager.commands = append(mockGithubPullrequestManager.commands, "CreateCommentReaction")
 	return nil
@@ -14,8 +14,10 @@ type Job struct {
 	ProjectName        string
 	ProjectDir         string
 	ProjectWorkspace   string
+	EphemeralWorkspace bool
 	ProjectWorkflow    string
 	Terragrunt         bool
+	TerragruntRunAll   bool
 	OpenTofu           bool
 	Commands           []string
 	ApplyStage         *Stage
@@ -26,12 +28,26 @@ type Job struct {
 	RequestedBy        string
 	Namespace          string
 	RunEnvVars         map[string]string
+	// when set, commands start from a clean environment with only these runner env vars
+	EnvAllowlist       []string
 	StateEnvVars       map[string]string
 	CommandEnvVars     map[string]string
 	StateEnvProvider   *stscreds.WebIdentityRoleProvider
 	CommandEnvProvider *stscreds.WebIdentityRoleProvider
 }
 
+// SecretValues returns the values of the state and command env vars so they can be masked in reports
+func (job Job) SecretValues() []string {
+	secrets := make([]string, 0, len(job.StateEnvVars)+len(job.CommandEnvVars))
+	for _, v := range job.StateEnvVars {
+		secrets = append(secrets, v)
+	}
+	for _, v := range job.CommandEnvVars {
+		secrets = append(secrets, v)
+	}
+	return secrets
+}
+
 type Step struct {
 	Action    string
 	Value     string
@@ -66,6 +82,17 @@ func ToConfigStage(configStage *configuration.Stage) *Stage {
 	}
 }
 
+// EphemeralWorkspaceTeardownCommands are queued when a pull request is closed for projects with ephemeral workspaces
+var EphemeralWorkspaceTeardownCommands = []string{"mantis destroy", "mantis delete-workspace"}
+
+// CommandsOnPullRequestClosed returns the commands to run for a project when its pull request gets closed
+func CommandsOnPullRequestClosed(project digger_config.Project, commands []string) []string {
+	if !project.EphemeralWorkspace {
+		return commands
+	}
+	return append(slices.Clone(EphemeralWorkspaceTeardownCommands), commands...)
+}
+
 func (j *Job) IsPlan() bool {
 	return slices.Contains(j.Commands, "mantis plan")
 }
@@ -103,11 +130,13 @@ func ConvertProjectsToJobs(actor string, repoNamespace string, command string, p
 		stateEnvVars, commandEnvVars := digger_config.CollectTerraformEnvConfig(workflow.EnvVars)
 		StateEnvProvider, CommandEnvProvider := GetStateAndCommandProviders(project)
 		jobs = append(jobs, Job{
-			ProjectName:      project.Name,
-			ProjectDir:       project.Dir,
-			ProjectWorkspace: project.Workspace,
-			Terragrunt:       project.Terragrunt,
-			OpenTofu:         project.OpenTofu,
+			ProjectName:        project.Name,
+			ProjectDir:         project.Dir,
+			ProjectWorkspace:   project.WorkspaceForPullRequest(&prNumber),
+			EphemeralWorkspace: project.EphemeralWorkspace,
+			Terragrunt:         project.Terragrunt,
+			TerragruntRunAll:   project.TerragruntRunAll,
+			OpenTofu:           project.OpenTofu,
 			// TODO: expose lower level api per command configuration
 			Commands:   []string{command},
 			ApplyStage: ToConfigStage(workflow.Apply),
@@ -119,6 +148,7 @@ func ConvertProjectsToJobs(actor string, repoNamespace string, command string, p
 			Namespace:          repoNamespace,
 			StateEnvVars:       stateEnvVars,
 			CommandEnvVars:     commandEnvVars,
+			EnvAllowlist:       digger_config.CollectEnvAllowlist(workflow.EnvVars),
 			StateEnvProvider:   StateEnvProvider,
 			CommandEnvProvider: CommandEnvProvider,
 		})
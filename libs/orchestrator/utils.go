@@ -1,6 +1,7 @@
 package orchestrator
 
 import (
+	"errors"
 	"fmt"
 	"regexp"
 	"strings"
@@ -15,6 +16,25 @@ func ParseProjectName(comment string) string {
 	return ""
 }
 
+func ParseWorkspace(comment string) (string, error) {
+	re := regexp.MustCompile(`-w(?:\s+(\S+)|$)`)
+	matches := re.FindAllStringSubmatch(comment, -1)
+
+	if len(matches) == 0 {
+		return "", nil
+	}
+
+	if len(matches) > 1 {
+		return "", errors.New("more than one -w flag found")
+	}
+
+	if len(matches[0]) < 2 || matches[0][1] == "" {
+		return "", errors.New("no value found after -w flag")
+	}
+
+	return matches[0][1], nil
+}
+
 type DiggerCommand string
 
 const DiggerCommandNoop DiggerCommand = "noop"
@@ -23,6 +43,7 @@ const DiggerCommandApply DiggerCommand = "apply"
 const DiggerCommandLock DiggerCommand = "lock"
 const DiggerCommandUnlock DiggerCommand = "unlock"
 const DiggerCommandTest DiggerCommand = "test"
+const DiggerCommandDestroy DiggerCommand = "destroy"
 
 func GetCommandFromComment(comment string) (*DiggerCommand, error) {
 	supportedCommands := map[string]DiggerCommand{
@@ -45,11 +66,12 @@ func GetCommandFromComment(comment string) (*DiggerCommand, error) {
 
 func GetCommandFromJob(job Job) (*DiggerCommand, error) {
 	supportedCommands := map[string]DiggerCommand{
-		"digger noop":   DiggerCommandNoop,
-		"mantis plan":   DiggerCommandPlan,
-		"mantis apply":  DiggerCommandApply,
-		"digger unlock": DiggerCommandUnlock,
-		"digger lock":   DiggerCommandLock,
+		"digger noop":    DiggerCommandNoop,
+		"mantis plan":    DiggerCommandPlan,
+		"mantis apply":   DiggerCommandApply,
+		"digger unlock":  DiggerCommandUnlock,
+		"digger lock":    DiggerCommandLock,
+		"mantis destroy": DiggerCommandDestroy,
 	}
 
 	if len(job.Commands) == 0 {
@@ -57,9 +79,9 @@ func GetCommandFromJob(job Job) (*DiggerCommand, error) {
 		return &res, nil
 	}
 
-	diggerCommands := job.Commands
-	for command, value := range supportedCommands {
-		for _, diggerCommand := range diggerCommands {
+	// the first recognised command of the job wins, so that the result does not depend on map ordering
+	for _, diggerCommand := range job.Commands {
+		for command, value := range supportedCommands {
 			if strings.HasPrefix(diggerCommand, command) {
 				return &value, nil
 			}
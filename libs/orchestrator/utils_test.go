@@ -0,0 +1,33 @@
+package orchestrator
+
+import "testing"
+
+func TestParseWorkspace(t *testing.T) {
+	var commentTests = []struct {
+		in  string
+		out string
+		err bool
+	}{
+		{"test", "", false},
+		{"test -w workspace", "workspace", false},
+		{"test -w workspace -w workspace2", "", true},
+		{"test -w", "", true},
+	}
+
+	for _, tt := range commentTests {
+		out, err := ParseWorkspace(tt.in)
+		if tt.err {
+			if err == nil {
+				t.Errorf("ParseWorkspace(%q) = %q, want error", tt.in, out)
+			}
+		} else {
+			if err != nil {
+				t.Errorf("ParseWorkspace(%q) = %q, want %q", tt.in, err, tt.out)
+			}
+			if out != tt.out {
+				t.Errorf("ParseWorkspace(%q) = %q, want %q", tt.in, out, tt.out)
+			}
+		}
+	}
+
+}
@@ -24,10 +24,24 @@ type VcsSpec struct {
 	RepoName  string `json:"repo_name"`
 	RepoOwner string `json:"repo_owner"`
 	VcsType   string `json:"vcs_type"`
+	// api url of self-managed GitLab instances or the Azure DevOps organisation url
+	BaseUrl string `json:"base_url,omitempty"`
 }
 
 type PolicySpec struct {
 	PolicyType string `json:"policy_type"`
+	// directory of the repository policies are read from when PolicyType is repo or layered
+	PolicyDir string `json:"policy_dir"`
+	// url or local path of the OPA bundle policies are read from when PolicyType is bundle
+	BundleUrl string `json:"bundle_url"`
+	// PEM encoded public key bundle signatures are verified with
+	BundlePublicKey string `json:"bundle_public_key"`
+}
+
+type PlanStorageSpec struct {
+	StorageType string `json:"storage_type"`
+	// directory plans are kept in when StorageType is local
+	LocalDir string `json:"local_dir"`
 }
 
 type Spec struct {
@@ -36,10 +50,11 @@ type Spec struct {
 	CommentId string `json:"comment_id"`
 	RunName   string `json:"run_name"`
 
-	Job      orchestrator.JobJson `json:"job"`
-	Reporter ReporterSpec         `json:"reporter"`
-	Lock     LockSpec             `json:"lock"`
-	Backend  BackendSpec          `json:"backend"`
-	VCS      VcsSpec              `json:"vcs"`
-	Policy   PolicySpec           `json:"policy_provider"`
+	Job         orchestrator.JobJson `json:"job"`
+	Reporter    ReporterSpec         `json:"reporter"`
+	Lock        LockSpec             `json:"lock"`
+	Backend     BackendSpec          `json:"backend"`
+	VCS         VcsSpec              `json:"vcs"`
+	Policy      PolicySpec           `json:"policy_provider"`
+	PlanStorage PlanStorageSpec      `json:"plan_storage"`
 }
@@ -22,6 +22,7 @@ type S3Client interface {
 	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
 	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
 	DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+	ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
 }
 
 type PlanStorageAWS struct {
@@ -104,6 +105,31 @@ func (psa *PlanStorageAWS) DeleteStoredPlan(artifactName, storedPlanFilePath str
 	return nil
 }
 
+func (psa *PlanStorageAWS) ListPlans(prefix string) ([]PlanInfo, error) {
+	var plans []PlanInfo
+	paginator := s3.NewListObjectsV2Paginator(psa.Client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(psa.Bucket),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(psa.Context)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list objects in bucket: %v", err)
+		}
+		for _, object := range page.Contents {
+			plans = append(plans, PlanInfo{
+				StoredPlanFilePath: aws.ToString(object.Key),
+				LastModified:       aws.ToTime(object.LastModified),
+			})
+		}
+	}
+	return plans, nil
+}
+
+func (psa *PlanStorageAWS) DeletePlan(plan PlanInfo) error {
+	return psa.DeleteStoredPlan(plan.ArtifactName, plan.StoredPlanFilePath)
+}
+
 func GetAWSStorageClient() (context.Context, *s3.Client, error) {
 	ctx := context.Background()
 	sdkConfig, err := config.LoadDefaultConfig(ctx)
@@ -6,8 +6,11 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 
@@ -20,6 +23,7 @@ type mockS3Client struct {
 	MockPutObject    func(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
 	MockGetObject    func(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
 	MockDeleteObject func(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+	MockListObjects  func(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
 }
 
 func (m *mockS3Client) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
@@ -38,6 +42,10 @@ func (m *mockS3Client) DeleteObject(ctx context.Context, params *s3.DeleteObject
 	return m.MockDeleteObject(ctx, params, optFns...)
 }
 
+func (m *mockS3Client) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	return m.MockListObjects(ctx, params, optFns...)
+}
+
 type emulateS3Client struct {
 	objects map[string][]byte
 }
@@ -71,6 +79,16 @@ func (m *emulateS3Client) DeleteObject(ctx context.Context, params *s3.DeleteObj
 	return &s3.DeleteObjectOutput{}, nil
 }
 
+func (m *emulateS3Client) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	output := &s3.ListObjectsV2Output{}
+	for key := range m.objects {
+		if strings.HasPrefix(key, *params.Prefix) {
+			output.Contents = append(output.Contents, types.Object{Key: aws.String(key), LastModified: aws.Time(time.Now())})
+		}
+	}
+	return output, nil
+}
+
 func TestPlanStorageAWS_PlanExists(t *testing.T) {
 	client := &mockS3Client{
 		MockHeadObject: func(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
@@ -148,3 +166,27 @@ func TestPlanStorageAWS_E2E(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, false, exists)
 }
+
+func TestPlanStorageAWS_ListPlans(t *testing.T) {
+	client := &emulateS3Client{
+		objects: map[string][]byte{
+			"org-repo-12-dev.tfplan":  []byte("plan"),
+			"org-repo-13-dev.tfplan":  []byte("plan"),
+			"org-other-12-dev.tfplan": []byte("plan"),
+		},
+	}
+	psa := &PlanStorageAWS{
+		Client: client,
+		Bucket: "test-bucket",
+	}
+
+	plans, err := psa.ListPlans("org-repo-")
+	require.NoError(t, err)
+	require.Len(t, plans, 2)
+
+	err = psa.DeletePlan(plans[0])
+	require.NoError(t, err)
+	plans, err = psa.ListPlans("org-repo-")
+	require.NoError(t, err)
+	require.Len(t, plans, 1)
+}
@@ -0,0 +1,178 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+)
+
+var (
+	BLOB_SERVICE_URL_FORMAT = "https://%s.blob.core.windows.net/"
+)
+
+type PlanStorageAzure struct {
+	Client    *azblob.Client
+	Container string
+	Context   context.Context
+}
+
+// NewAzurePlanStorage authenticates like the azure storage account lock, selected through DIGGER_AZURE_AUTH_METHOD
+func NewAzurePlanStorage(container string) (*PlanStorageAzure, error) {
+	authMethod := os.Getenv("DIGGER_AZURE_AUTH_METHOD")
+	if authMethod == "" {
+		return nil, fmt.Errorf("'DIGGER_AZURE_AUTH_METHOD' environment variable must be set to either 'SHARED_KEY' or 'CONNECTION_STRING' or 'CLIENT_SECRET'")
+	}
+	if container == "" {
+		return nil, fmt.Errorf("a blob container name is required to store plans in azure")
+	}
+
+	client, err := getBlobClient(authMethod)
+	if err != nil {
+		return nil, err
+	}
+
+	psa := &PlanStorageAzure{
+		Client:    client,
+		Container: container,
+		Context:   context.Background(),
+	}
+	if err := psa.createContainerIfNotExists(); err != nil {
+		return nil, fmt.Errorf("error while creating container: %v", err)
+	}
+	return psa, nil
+}
+
+func (psa *PlanStorageAzure) PlanExists(artifactName string, storedPlanFilePath string) (bool, error) {
+	blobClient := psa.Client.ServiceClient().NewContainerClient(psa.Container).NewBlobClient(storedPlanFilePath)
+	_, err := blobClient.GetProperties(psa.Context, nil)
+	if err != nil {
+		if bloberror.HasCode(err, bloberror.BlobNotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("unable to get blob properties: %v", err)
+	}
+	return true, nil
+}
+
+func (psa *PlanStorageAzure) StorePlanFile(fileContents []byte, artifactName string, fileName string) error {
+	_, err := psa.Client.UploadBuffer(psa.Context, psa.Container, fileName, fileContents, nil)
+	if err != nil {
+		log.Printf("Failed to write file to container: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (psa *PlanStorageAzure) RetrievePlan(localPlanFilePath string, artifactName string, storedPlanFilePath string) (*string, error) {
+	file, err := os.Create(localPlanFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create file: %v", err)
+	}
+	defer file.Close()
+
+	_, err = psa.Client.DownloadFile(psa.Context, psa.Container, storedPlanFilePath, file, nil)
+	if err != nil {
+		// an empty or partial file left behind could be taken for a plan by a later step
+		file.Close()
+		os.Remove(localPlanFilePath)
+		return nil, fmt.Errorf("unable to read data from container: %v", err)
+	}
+	fileName, err := filepath.Abs(file.Name())
+	if err != nil {
+		return nil, fmt.Errorf("unable to get absolute path for file: %v", err)
+	}
+	return &fileName, nil
+}
+
+func (psa *PlanStorageAzure) DeleteStoredPlan(artifactName string, storedPlanFilePath string) error {
+	_, err := psa.Client.DeleteBlob(psa.Context, psa.Container, storedPlanFilePath, nil)
+	if err != nil {
+		return fmt.Errorf("unable to delete file '%v' from container: %v", storedPlanFilePath, err)
+	}
+	return nil
+}
+
+func (psa *PlanStorageAzure) ListPlans(prefix string) ([]PlanInfo, error) {
+	var plans []PlanInfo
+	pager := psa.Client.NewListBlobsFlatPager(psa.Container, &azblob.ListBlobsFlatOptions{Prefix: &prefix})
+	for pager.More() {
+		page, err := pager.NextPage(psa.Context)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list blobs in container: %v", err)
+		}
+		for _, blob := range page.Segment.BlobItems {
+			plan := PlanInfo{StoredPlanFilePath: *blob.Name}
+			if blob.Properties != nil && blob.Properties.LastModified != nil {
+				plan.LastModified = *blob.Properties.LastModified
+			}
+			plans = append(plans, plan)
+		}
+	}
+	return plans, nil
+}
+
+func (psa *PlanStorageAzure) DeletePlan(plan PlanInfo) error {
+	return psa.DeleteStoredPlan(plan.ArtifactName, plan.StoredPlanFilePath)
+}
+
+func (psa *PlanStorageAzure) createContainerIfNotExists() error {
+	_, err := psa.Client.CreateContainer(psa.Context, psa.Container, nil)
+	if err != nil && !bloberror.HasCode(err, bloberror.ContainerAlreadyExists) {
+		return fmt.Errorf("could not create container: %v", err)
+	}
+	return nil
+}
+
+func getBlobClient(authMethod string) (*azblob.Client, error) {
+	switch authMethod {
+	case "SHARED_KEY":
+		key := os.Getenv("DIGGER_AZURE_SHARED_KEY")
+		saName := os.Getenv("DIGGER_AZURE_SA_NAME")
+		if saName == "" || key == "" {
+			return nil, fmt.Errorf("you must set 'DIGGER_AZURE_SA_NAME' and 'DIGGER_AZURE_SHARED_KEY' environment variable when using shared key authentication")
+		}
+		sharedCreds, err := azblob.NewSharedKeyCredential(saName, key)
+		if err != nil {
+			return nil, fmt.Errorf("could not create shared key credentials: %v", err)
+		}
+		client, err := azblob.NewClientWithSharedKeyCredential(fmt.Sprintf(BLOB_SERVICE_URL_FORMAT, saName), sharedCreds, nil)
+		if err != nil {
+			return nil, fmt.Errorf("could not create blob client with shared key authentication: %v", err)
+		}
+		return client, nil
+	case "CONNECTION_STRING":
+		connStr := os.Getenv("DIGGER_AZURE_CONNECTION_STRING")
+		if connStr == "" {
+			return nil, fmt.Errorf("you must set 'DIGGER_AZURE_CONNECTION_STRING' when using connection string authentication")
+		}
+		client, err := azblob.NewClientFromConnectionString(connStr, nil)
+		if err != nil {
+			return nil, fmt.Errorf("could not create blob client with connection string authentication: %v", err)
+		}
+		return client, nil
+	case "CLIENT_SECRET":
+		tenantId := os.Getenv("DIGGER_AZURE_TENANT_ID")
+		clientId := os.Getenv("DIGGER_AZURE_CLIENT_ID")
+		secret := os.Getenv("DIGGER_AZURE_CLIENT_SECRET")
+		saName := os.Getenv("DIGGER_AZURE_SA_NAME")
+		if clientId == "" || secret == "" || tenantId == "" || saName == "" {
+			return nil, fmt.Errorf("you must set 'DIGGER_AZURE_CLIENT_ID' and 'DIGGER_AZURE_CLIENT_SECRET' and 'DIGGER_AZURE_TENANT_ID' and 'DIGGER_AZURE_SA_NAME' when using client secret authentication")
+		}
+		cred, err := azidentity.NewClientSecretCredential(tenantId, clientId, secret, nil)
+		if err != nil {
+			return nil, fmt.Errorf("could not create client secret credential: %v", err)
+		}
+		client, err := azblob.NewClient(fmt.Sprintf(BLOB_SERVICE_URL_FORMAT, saName), cred, nil)
+		if err != nil {
+			return nil, fmt.Errorf("could not create blob client with client secret authentication: %v", err)
+		}
+		return client, nil
+	}
+	return nil, fmt.Errorf("could not initialize blob client, because no valid authentication method was found")
+}
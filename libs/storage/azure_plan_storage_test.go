@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// Default values to connect to the Azurite blob service
+const (
+	AZURITE_BLOB_SA_NAME     = "devstoreaccount1"
+	AZURITE_BLOB_SHARED_KEY  = "Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw=="
+	AZURITE_BLOB_CONN_STRING = "DefaultEndpointsProtocol=http;AccountName=devstoreaccount1;AccountKey=Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw==;BlobEndpoint=http://127.0.0.1:10000/devstoreaccount1;"
+)
+
+func requireAzurite(t *testing.T) {
+	conn, err := net.Dial("tcp", "127.0.0.1:10000")
+	if err != nil {
+		t.Skip("Azurite blob service is not running on 127.0.0.1:10000")
+	}
+	conn.Close()
+}
+
+func testAzurePlanStorageRoundTrip(t *testing.T) {
+	psa, err := NewAzurePlanStorage("plans-" + uuid.New().String())
+	require.NoError(t, err)
+
+	storedPlanFilePath := "org-repo-12-dev.tfplan"
+	exists, err := psa.PlanExists("dev", storedPlanFilePath)
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	err = psa.StorePlanFile([]byte("plan contents"), "dev", storedPlanFilePath)
+	require.NoError(t, err)
+	exists, err = psa.PlanExists("dev", storedPlanFilePath)
+	require.NoError(t, err)
+	require.True(t, exists)
+
+	localPlanFilePath := filepath.Join(t.TempDir(), "dev.tfplan")
+	retrieved, err := psa.RetrievePlan(localPlanFilePath, "dev", storedPlanFilePath)
+	require.NoError(t, err)
+	contents, err := os.ReadFile(*retrieved)
+	require.NoError(t, err)
+	require.Equal(t, "plan contents", string(contents))
+
+	err = psa.DeleteStoredPlan("dev", storedPlanFilePath)
+	require.NoError(t, err)
+	exists, err = psa.PlanExists("dev", storedPlanFilePath)
+	require.NoError(t, err)
+	require.False(t, exists)
+}
+
+func TestAzurePlanStorageWithConnectionString(t *testing.T) {
+	requireAzurite(t)
+	t.Setenv("DIGGER_AZURE_AUTH_METHOD", "CONNECTION_STRING")
+	t.Setenv("DIGGER_AZURE_CONNECTION_STRING", AZURITE_BLOB_CONN_STRING)
+	testAzurePlanStorageRoundTrip(t)
+}
+
+func TestAzurePlanStorageWithSharedKey(t *testing.T) {
+	requireAzurite(t)
+	defaultFormat := BLOB_SERVICE_URL_FORMAT
+	BLOB_SERVICE_URL_FORMAT = "http://127.0.0.1:10000/%s"
+	defer func() { BLOB_SERVICE_URL_FORMAT = defaultFormat }()
+
+	t.Setenv("DIGGER_AZURE_AUTH_METHOD", "SHARED_KEY")
+	t.Setenv("DIGGER_AZURE_SA_NAME", AZURITE_BLOB_SA_NAME)
+	t.Setenv("DIGGER_AZURE_SHARED_KEY", AZURITE_BLOB_SHARED_KEY)
+	testAzurePlanStorageRoundTrip(t)
+}
+
+func TestNewAzurePlanStorageRequiresAuthMethod(t *testing.T) {
+	t.Setenv("DIGGER_AZURE_AUTH_METHOD", "")
+	_, err := NewAzurePlanStorage("plans")
+	require.ErrorContains(t, err, "DIGGER_AZURE_AUTH_METHOD")
+
+	t.Setenv("DIGGER_AZURE_AUTH_METHOD", "SHARED_KEY")
+	t.Setenv("DIGGER_AZURE_SA_NAME", "")
+	_, err = NewAzurePlanStorage("plans")
+	require.ErrorContains(t, err, "DIGGER_AZURE_SA_NAME")
+}
+
+func TestAzurePlanStorageRetrievePlanRemovesFileOnFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("x-ms-error-code", "BlobNotFound")
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+	client, err := azblob.NewClientWithNoCredential(server.URL+"/"+AZURITE_BLOB_SA_NAME, nil)
+	require.NoError(t, err)
+	psa := &PlanStorageAzure{Client: client, Container: "plans", Context: context.Background()}
+
+	localPlanFilePath := filepath.Join(t.TempDir(), "dev.tfplan")
+	_, err = psa.RetrievePlan(localPlanFilePath, "dev", "org-repo-12-dev.tfplan")
+	require.Error(t, err)
+	require.NoFileExists(t, localPlanFilePath)
+}
@@ -0,0 +1,325 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+)
+
+// every encrypted plan starts with this line so that unencrypted or foreign files are rejected
+const encryptedPlanMagic = "DIGGER-ENCRYPTED-PLAN-V1\n"
+
+// KeySource wraps the random data key each plan is encrypted with
+type KeySource interface {
+	// KeyId identifies the key new plans are wrapped with, it is stored next to the plan
+	KeyId() string
+	WrapKey(dataKey []byte) ([]byte, error)
+	// UnwrapKey is given the key id the plan was stored with, which may be a rotated out key
+	UnwrapKey(keyId string, wrappedKey []byte) ([]byte, error)
+}
+
+type encryptedPlanHeader struct {
+	KeyId      string `json:"key_id"`
+	WrappedKey []byte `json:"wrapped_key"`
+	Nonce      []byte `json:"nonce"`
+}
+
+// NewPlanEncryptionKeysFromEnv returns the keys configured with PLAN_ENCRYPTION_AGE_IDENTITIES, a comma or newline
+// separated list of age identities, or PLAN_ENCRYPTION_AWS_KMS_KEY_ID. It returns nil when plans are not encrypted
+func NewPlanEncryptionKeysFromEnv() (KeySource, error) {
+	ageIdentities := os.Getenv("PLAN_ENCRYPTION_AGE_IDENTITIES")
+	kmsKeyId := os.Getenv("PLAN_ENCRYPTION_AWS_KMS_KEY_ID")
+	switch {
+	case ageIdentities != "" && kmsKeyId != "":
+		return nil, fmt.Errorf("PLAN_ENCRYPTION_AGE_IDENTITIES and PLAN_ENCRYPTION_AWS_KMS_KEY_ID are mutually exclusive")
+	case ageIdentities != "":
+		return NewAgeKeySource(strings.FieldsFunc(ageIdentities, func(r rune) bool { return r == ',' || r == '\n' }))
+	case kmsKeyId != "":
+		client, err := NewAWSKMSClient()
+		if err != nil {
+			return nil, fmt.Errorf("could not create AWS KMS client: %v", err)
+		}
+		return &KMSKeySource{Client: client, Key: kmsKeyId}, nil
+	}
+	return nil, nil
+}
+
+// WithPlanEncryptionFromEnv wraps planStorage so that plans are encrypted when keys are configured,
+// see NewPlanEncryptionKeysFromEnv
+func WithPlanEncryptionFromEnv(planStorage PlanStorage) (PlanStorage, error) {
+	keys, err := NewPlanEncryptionKeysFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("could not load plan encryption keys: %v", err)
+	}
+	if keys == nil || planStorage == nil {
+		return planStorage, nil
+	}
+	return &EncryptedPlanStorage{Storage: planStorage, Keys: keys}, nil
+}
+
+// EncryptedPlanStorage encrypts plans with AES-GCM before handing them to the wrapped storage
+type EncryptedPlanStorage struct {
+	Storage PlanStorage
+	Keys    KeySource
+}
+
+func (eps *EncryptedPlanStorage) StorePlanFile(fileContents []byte, artifactName string, storedPlanFilePath string) error {
+	envelope, err := encryptPlan(eps.Keys, fileContents)
+	if err != nil {
+		return fmt.Errorf("could not encrypt plan: %v", err)
+	}
+	return eps.Storage.StorePlanFile(envelope, artifactName, storedPlanFilePath)
+}
+
+func (eps *EncryptedPlanStorage) RetrievePlan(localPlanFilePath string, artifactName string, storedPlanFilePath string) (*string, error) {
+	retrievedPath, err := eps.Storage.RetrievePlan(localPlanFilePath, artifactName, storedPlanFilePath)
+	if err != nil {
+		return nil, err
+	}
+	envelope, err := os.ReadFile(*retrievedPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read retrieved plan: %v", err)
+	}
+	plan, err := decryptPlan(eps.Keys, envelope)
+	if err != nil {
+		// never leave undecryptable content around where terraform could pick it up
+		os.Remove(*retrievedPath)
+		return nil, fmt.Errorf("refusing to use stored plan %v: %v", storedPlanFilePath, err)
+	}
+	err = os.WriteFile(*retrievedPath, plan, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("unable to write decrypted plan: %v", err)
+	}
+	return retrievedPath, nil
+}
+
+func (eps *EncryptedPlanStorage) DeleteStoredPlan(artifactName string, storedPlanFilePath string) error {
+	return eps.Storage.DeleteStoredPlan(artifactName, storedPlanFilePath)
+}
+
+func (eps *EncryptedPlanStorage) PlanExists(artifactName string, storedPlanFilePath string) (bool, error) {
+	return eps.Storage.PlanExists(artifactName, storedPlanFilePath)
+}
+
+func (eps *EncryptedPlanStorage) ListPlans(prefix string) ([]PlanInfo, error) {
+	return eps.Storage.ListPlans(prefix)
+}
+
+func (eps *EncryptedPlanStorage) DeletePlan(plan PlanInfo) error {
+	return eps.Storage.DeletePlan(plan)
+}
+
+func encryptPlan(keys KeySource, plan []byte) ([]byte, error) {
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, fmt.Errorf("could not generate data key: %v", err)
+	}
+	gcm, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("could not generate nonce: %v", err)
+	}
+	wrappedKey, err := keys.WrapKey(dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("could not wrap data key: %v", err)
+	}
+
+	header, err := json.Marshal(encryptedPlanHeader{
+		KeyId:      keys.KeyId(),
+		WrappedKey: wrappedKey,
+		Nonce:      nonce,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var envelope bytes.Buffer
+	envelope.WriteString(encryptedPlanMagic)
+	envelope.Write(header)
+	envelope.WriteByte('\n')
+	// the header is authenticated as well so that the key id can't be swapped
+	envelope.Write(gcm.Seal(nil, nonce, plan, header))
+	return envelope.Bytes(), nil
+}
+
+func decryptPlan(keys KeySource, envelope []byte) ([]byte, error) {
+	if !bytes.HasPrefix(envelope, []byte(encryptedPlanMagic)) {
+		return nil, fmt.Errorf("plan is not encrypted")
+	}
+	reader := bufio.NewReader(bytes.NewReader(envelope[len(encryptedPlanMagic):]))
+	header, err := reader.ReadBytes('\n')
+	if err != nil {
+		return nil, fmt.Errorf("plan envelope is truncated")
+	}
+	header = bytes.TrimSuffix(header, []byte("\n"))
+	var parsedHeader encryptedPlanHeader
+	err = json.Unmarshal(header, &parsedHeader)
+	if err != nil {
+		return nil, fmt.Errorf("plan envelope header is corrupted: %v", err)
+	}
+	ciphertext, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	dataKey, err := keys.UnwrapKey(parsedHeader.KeyId, parsedHeader.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("could not unwrap data key with key %v: %v", parsedHeader.KeyId, err)
+	}
+	gcm, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(parsedHeader.Nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("plan envelope header is corrupted: invalid nonce")
+	}
+	plan, err := gcm.Open(nil, parsedHeader.Nonce, ciphertext, header)
+	if err != nil {
+		return nil, fmt.Errorf("plan failed integrity check, it may have been tampered with")
+	}
+	return plan, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data key: %v", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// AgeKeySource wraps data keys for an X25519 age identity, older identities are kept to read plans stored before a rotation
+type AgeKeySource struct {
+	Identity      *age.X25519Identity
+	OldIdentities []*age.X25519Identity
+}
+
+// NewAgeKeySource parses AGE-SECRET-KEY-1... identities, the first one is used for new plans
+func NewAgeKeySource(identities []string) (*AgeKeySource, error) {
+	var parsed []*age.X25519Identity
+	for _, identity := range identities {
+		identity = strings.TrimSpace(identity)
+		if identity == "" {
+			continue
+		}
+		x25519Identity, err := age.ParseX25519Identity(identity)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse age identity: %v", err)
+		}
+		parsed = append(parsed, x25519Identity)
+	}
+	if len(parsed) == 0 {
+		return nil, fmt.Errorf("at least one age identity is required")
+	}
+	return &AgeKeySource{Identity: parsed[0], OldIdentities: parsed[1:]}, nil
+}
+
+func (a *AgeKeySource) KeyId() string {
+	return a.Identity.Recipient().String()
+}
+
+func (a *AgeKeySource) WrapKey(dataKey []byte) ([]byte, error) {
+	var wrapped bytes.Buffer
+	writer, err := age.Encrypt(&wrapped, a.Identity.Recipient())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := writer.Write(dataKey); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return wrapped.Bytes(), nil
+}
+
+func (a *AgeKeySource) UnwrapKey(keyId string, wrappedKey []byte) ([]byte, error) {
+	for _, identity := range append([]*age.X25519Identity{a.Identity}, a.OldIdentities...) {
+		if identity.Recipient().String() != keyId {
+			continue
+		}
+		reader, err := age.Decrypt(bytes.NewReader(wrappedKey), identity)
+		if err != nil {
+			return nil, err
+		}
+		return io.ReadAll(reader)
+	}
+	return nil, fmt.Errorf("no age identity configured for recipient %v", keyId)
+}
+
+// KMSClient is the subset of a key management service used to wrap data keys
+type KMSClient interface {
+	Encrypt(keyId string, plaintext []byte) ([]byte, error)
+	Decrypt(keyId string, ciphertext []byte) ([]byte, error)
+}
+
+// KMSKeySource wraps data keys with a key held by a KMS, rotated keys stay decryptable as long as the KMS keeps them
+type KMSKeySource struct {
+	Client KMSClient
+	Key    string
+}
+
+func (k *KMSKeySource) KeyId() string {
+	return k.Key
+}
+
+func (k *KMSKeySource) WrapKey(dataKey []byte) ([]byte, error) {
+	return k.Client.Encrypt(k.Key, dataKey)
+}
+
+func (k *KMSKeySource) UnwrapKey(keyId string, wrappedKey []byte) ([]byte, error) {
+	return k.Client.Decrypt(keyId, wrappedKey)
+}
+
+type awsKMSAPI interface {
+	Encrypt(ctx context.Context, params *kms.EncryptInput, optFns ...func(*kms.Options)) (*kms.EncryptOutput, error)
+	Decrypt(ctx context.Context, params *kms.DecryptInput, optFns ...func(*kms.Options)) (*kms.DecryptOutput, error)
+}
+
+// AWSKMSClient wraps data keys with AWS KMS, keys are referenced by key id, key ARN or alias
+type AWSKMSClient struct {
+	Context context.Context
+	Client  awsKMSAPI
+}
+
+// NewAWSKMSClient uses the default AWS credentials chain, like the S3 plan storage
+func NewAWSKMSClient() (*AWSKMSClient, error) {
+	ctx := context.Background()
+	sdkConfig, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &AWSKMSClient{Context: ctx, Client: kms.NewFromConfig(sdkConfig)}, nil
+}
+
+func (c *AWSKMSClient) Encrypt(keyId string, plaintext []byte) ([]byte, error) {
+	output, err := c.Client.Encrypt(c.Context, &kms.EncryptInput{KeyId: aws.String(keyId), Plaintext: plaintext})
+	if err != nil {
+		return nil, err
+	}
+	return output.CiphertextBlob, nil
+}
+
+func (c *AWSKMSClient) Decrypt(keyId string, ciphertext []byte) ([]byte, error) {
+	output, err := c.Client.Decrypt(c.Context, &kms.DecryptInput{KeyId: aws.String(keyId), CiphertextBlob: ciphertext})
+	if err != nil {
+		return nil, err
+	}
+	return output.Plaintext, nil
+}
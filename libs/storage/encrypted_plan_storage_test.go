@@ -0,0 +1,221 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"filippo.io/age"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryPlanStorage struct {
+	plans    map[string][]byte
+	modified map[string]time.Time
+}
+
+func (m *memoryPlanStorage) StorePlanFile(fileContents []byte, artifactName string, storedPlanFilePath string) error {
+	m.plans[storedPlanFilePath] = fileContents
+	return nil
+}
+
+func (m *memoryPlanStorage) RetrievePlan(localPlanFilePath string, artifactName string, storedPlanFilePath string) (*string, error) {
+	contents, ok := m.plans[storedPlanFilePath]
+	if !ok {
+		return nil, fmt.Errorf("plan not found")
+	}
+	err := os.WriteFile(localPlanFilePath, contents, 0644)
+	return &localPlanFilePath, err
+}
+
+func (m *memoryPlanStorage) DeleteStoredPlan(artifactName string, storedPlanFilePath string) error {
+	delete(m.plans, storedPlanFilePath)
+	return nil
+}
+
+func (m *memoryPlanStorage) PlanExists(artifactName string, storedPlanFilePath string) (bool, error) {
+	_, ok := m.plans[storedPlanFilePath]
+	return ok, nil
+}
+
+func (m *memoryPlanStorage) ListPlans(prefix string) ([]PlanInfo, error) {
+	var plans []PlanInfo
+	for path := range m.plans {
+		if strings.HasPrefix(path, prefix) {
+			plans = append(plans, PlanInfo{StoredPlanFilePath: path, LastModified: m.modified[path]})
+		}
+	}
+	return plans, nil
+}
+
+func (m *memoryPlanStorage) DeletePlan(plan PlanInfo) error {
+	return m.DeleteStoredPlan(plan.ArtifactName, plan.StoredPlanFilePath)
+}
+
+// localKMS stands in for a real KMS by wrapping data keys with in-memory AES keys
+type localKMS struct {
+	keys map[string][]byte
+}
+
+func (l *localKMS) Encrypt(keyId string, plaintext []byte) ([]byte, error) {
+	key, ok := l.keys[keyId]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %v", keyId)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	rand.Read(nonce)
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func (l *localKMS) Decrypt(keyId string, ciphertext []byte) ([]byte, error) {
+	key, ok := l.keys[keyId]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %v", keyId)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	return gcm.Open(nil, ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():], nil)
+}
+
+func newLocalKMS(keyIds ...string) *localKMS {
+	kms := &localKMS{keys: map[string][]byte{}}
+	for _, keyId := range keyIds {
+		key := make([]byte, 32)
+		rand.Read(key)
+		kms.keys[keyId] = key
+	}
+	return kms
+}
+
+func retrieve(t *testing.T, s *EncryptedPlanStorage) ([]byte, error) {
+	localPlanFilePath := filepath.Join(t.TempDir(), "dev.tfplan")
+	retrieved, err := s.RetrievePlan(localPlanFilePath, "dev", "dev.tfplan")
+	if err != nil {
+		_, statErr := os.Stat(localPlanFilePath)
+		require.True(t, os.IsNotExist(statErr))
+		return nil, err
+	}
+	return os.ReadFile(*retrieved)
+}
+
+func TestEncryptedPlanStorageWithAgeKeys(t *testing.T) {
+	oldIdentity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	newIdentity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+
+	backend := &memoryPlanStorage{plans: map[string][]byte{}}
+	oldKeys, err := NewAgeKeySource([]string{oldIdentity.String()})
+	require.NoError(t, err)
+	s := &EncryptedPlanStorage{Storage: backend, Keys: oldKeys}
+
+	err = s.StorePlanFile([]byte("secret plan"), "dev", "dev.tfplan")
+	require.NoError(t, err)
+	require.False(t, bytes.Contains(backend.plans["dev.tfplan"], []byte("secret plan")))
+	require.True(t, bytes.Contains(backend.plans["dev.tfplan"], []byte(oldIdentity.Recipient().String())))
+
+	// after rotating the old identity is still able to read existing plans
+	rotatedKeys, err := NewAgeKeySource([]string{newIdentity.String(), oldIdentity.String()})
+	require.NoError(t, err)
+	s.Keys = rotatedKeys
+	plan, err := retrieve(t, s)
+	require.NoError(t, err)
+	require.Equal(t, "secret plan", string(plan))
+
+	newOnlyKeys, err := NewAgeKeySource([]string{newIdentity.String()})
+	require.NoError(t, err)
+	s.Keys = newOnlyKeys
+	_, err = retrieve(t, s)
+	require.ErrorContains(t, err, "no age identity configured")
+}
+
+func TestEncryptedPlanStorageWithKMS(t *testing.T) {
+	kms := newLocalKMS("key-1", "key-2")
+	backend := &memoryPlanStorage{plans: map[string][]byte{}}
+	s := &EncryptedPlanStorage{Storage: backend, Keys: &KMSKeySource{Client: kms, Key: "key-1"}}
+
+	err := s.StorePlanFile([]byte("secret plan"), "dev", "dev.tfplan")
+	require.NoError(t, err)
+
+	s.Keys = &KMSKeySource{Client: kms, Key: "key-2"}
+	plan, err := retrieve(t, s)
+	require.NoError(t, err)
+	require.Equal(t, "secret plan", string(plan))
+}
+
+func TestEncryptedPlanStorageDetectsTampering(t *testing.T) {
+	backend := &memoryPlanStorage{plans: map[string][]byte{}}
+	s := &EncryptedPlanStorage{Storage: backend, Keys: &KMSKeySource{Client: newLocalKMS("key-1"), Key: "key-1"}}
+	err := s.StorePlanFile([]byte("secret plan"), "dev", "dev.tfplan")
+	require.NoError(t, err)
+
+	stored := backend.plans["dev.tfplan"]
+	stored[len(stored)-1] ^= 0xff
+	_, err = retrieve(t, s)
+	require.ErrorContains(t, err, "tampered")
+
+	backend.plans["dev.tfplan"] = []byte("plain plan")
+	_, err = retrieve(t, s)
+	require.ErrorContains(t, err, "plan is not encrypted")
+}
+
+// fakeAWSKMS echoes plaintexts with the key id so that the requests sent to KMS can be checked
+type fakeAWSKMS struct{}
+
+func (fakeAWSKMS) Encrypt(ctx context.Context, params *kms.EncryptInput, optFns ...func(*kms.Options)) (*kms.EncryptOutput, error) {
+	return &kms.EncryptOutput{CiphertextBlob: append([]byte(*params.KeyId+":"), params.Plaintext...)}, nil
+}
+
+func (fakeAWSKMS) Decrypt(ctx context.Context, params *kms.DecryptInput, optFns ...func(*kms.Options)) (*kms.DecryptOutput, error) {
+	prefix := []byte(*params.KeyId + ":")
+	if !bytes.HasPrefix(params.CiphertextBlob, prefix) {
+		return nil, fmt.Errorf("ciphertext was not encrypted with %v", *params.KeyId)
+	}
+	return &kms.DecryptOutput{Plaintext: params.CiphertextBlob[len(prefix):]}, nil
+}
+
+func TestEncryptedPlanStorageWithAWSKMS(t *testing.T) {
+	client := &AWSKMSClient{Context: context.Background(), Client: fakeAWSKMS{}}
+	backend := &memoryPlanStorage{plans: map[string][]byte{}}
+	s := &EncryptedPlanStorage{Storage: backend, Keys: &KMSKeySource{Client: client, Key: "alias/plans"}}
+
+	err := s.StorePlanFile([]byte("secret plan"), "dev", "dev.tfplan")
+	require.NoError(t, err)
+	plan, err := retrieve(t, s)
+	require.NoError(t, err)
+	require.Equal(t, "secret plan", string(plan))
+}
+
+func TestWithPlanEncryptionFromEnv(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	backend := &memoryPlanStorage{plans: map[string][]byte{}}
+
+	t.Setenv("PLAN_ENCRYPTION_AGE_IDENTITIES", "")
+	t.Setenv("PLAN_ENCRYPTION_AWS_KMS_KEY_ID", "")
+	planStorage, err := WithPlanEncryptionFromEnv(backend)
+	require.NoError(t, err)
+	require.Equal(t, backend, planStorage)
+
+	t.Setenv("PLAN_ENCRYPTION_AGE_IDENTITIES", identity.String())
+	planStorage, err = WithPlanEncryptionFromEnv(backend)
+	require.NoError(t, err)
+	require.IsType(t, &EncryptedPlanStorage{}, planStorage)
+
+	t.Setenv("PLAN_ENCRYPTION_AWS_KMS_KEY_ID", "alias/plans")
+	_, err = WithPlanEncryptionFromEnv(backend)
+	require.ErrorContains(t, err, "mutually exclusive")
+}
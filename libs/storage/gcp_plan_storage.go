@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 
 	"cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
 )
 
 type PlanStorageGcp struct {
@@ -75,3 +76,26 @@ func (psg *PlanStorageGcp) DeleteStoredPlan(artifactName string, storedPlanFileP
 	}
 	return nil
 }
+
+func (psg *PlanStorageGcp) ListPlans(prefix string) ([]PlanInfo, error) {
+	var plans []PlanInfo
+	it := psg.Bucket.Objects(psg.Context, &storage.Query{Prefix: prefix})
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to list objects in bucket: %v", err)
+		}
+		plans = append(plans, PlanInfo{
+			StoredPlanFilePath: attrs.Name,
+			LastModified:       attrs.Updated,
+		})
+	}
+	return plans, nil
+}
+
+func (psg *PlanStorageGcp) DeletePlan(plan PlanInfo) error {
+	return psg.DeleteStoredPlan(plan.ArtifactName, plan.StoredPlanFilePath)
+}
@@ -0,0 +1,325 @@
+package storage
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	artifactServicePath = "twirp/github.actions.results.api.v1.ArtifactService/"
+	// uploads above this size are split into blocks of this size
+	defaultArtifactChunkSize = 8 << 20
+	artifactRequestAttempts  = 3
+)
+
+// ArtifactClient uploads workflow artifacts with the v4 artifact protocol used by actions/upload-artifact@v4
+type ArtifactClient struct {
+	ResultsUrl   string
+	RuntimeToken string
+	HttpClient   *http.Client
+	ChunkSize    int
+	// RetryDelay is the wait before the first retry of a failed request, it doubles with every attempt
+	RetryDelay time.Duration
+}
+
+type createArtifactRequest struct {
+	WorkflowRunBackendId    string `json:"workflow_run_backend_id"`
+	WorkflowJobRunBackendId string `json:"workflow_job_run_backend_id"`
+	Name                    string `json:"name"`
+	Version                 int    `json:"version"`
+}
+
+type createArtifactResponse struct {
+	Ok              bool   `json:"ok"`
+	SignedUploadUrl string `json:"signed_upload_url"`
+}
+
+type finalizeArtifactRequest struct {
+	WorkflowRunBackendId    string `json:"workflow_run_backend_id"`
+	WorkflowJobRunBackendId string `json:"workflow_job_run_backend_id"`
+	Name                    string `json:"name"`
+	Size                    int64  `json:"size,string"`
+	Hash                    string `json:"hash"`
+}
+
+type finalizeArtifactResponse struct {
+	Ok         bool  `json:"ok"`
+	ArtifactId int64 `json:"artifact_id,string"`
+}
+
+type twirpError struct {
+	Code string `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+// NewArtifactClientFromEnv reads the artifact service location and token the actions runner passes to every step
+func NewArtifactClientFromEnv() (*ArtifactClient, error) {
+	resultsUrl := os.Getenv("ACTIONS_RESULTS_URL")
+	runtimeToken := os.Getenv("ACTIONS_RUNTIME_TOKEN")
+	if resultsUrl == "" || runtimeToken == "" {
+		return nil, fmt.Errorf("ACTIONS_RESULTS_URL and ACTIONS_RUNTIME_TOKEN must be set to upload artifacts, are we running in github actions?")
+	}
+	return &ArtifactClient{
+		ResultsUrl:   resultsUrl,
+		RuntimeToken: runtimeToken,
+		HttpClient:   http.DefaultClient,
+		ChunkSize:    defaultArtifactChunkSize,
+		RetryDelay:   time.Second,
+	}, nil
+}
+
+// backendIds extracts the workflow run and job ids from the Actions.Results scope of the runtime token
+func (ac *ArtifactClient) backendIds() (string, string, error) {
+	parts := strings.Split(ac.RuntimeToken, ".")
+	if len(parts) != 3 {
+		return "", "", fmt.Errorf("runtime token is not a valid jwt")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", "", fmt.Errorf("could not decode runtime token: %v", err)
+	}
+	var claims struct {
+		Scp string `json:"scp"`
+	}
+	err = json.Unmarshal(payload, &claims)
+	if err != nil {
+		return "", "", fmt.Errorf("could not parse runtime token claims: %v", err)
+	}
+	for _, scope := range strings.Fields(claims.Scp) {
+		scopeParts := strings.Split(scope, ":")
+		if len(scopeParts) == 3 && scopeParts[0] == "Actions.Results" {
+			return scopeParts[1], scopeParts[2], nil
+		}
+	}
+	return "", "", fmt.Errorf("runtime token has no Actions.Results scope")
+}
+
+// withRetries retries requests that failed with a network error, a server error or were rate limited
+func (ac *ArtifactClient) withRetries(description string, do func() (*http.Response, error)) (*http.Response, error) {
+	delay := ac.RetryDelay
+	for attempt := 1; ; attempt++ {
+		resp, err := do()
+		retryable := err != nil || resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		if !retryable || attempt == artifactRequestAttempts {
+			return resp, err
+		}
+		if err != nil {
+			log.Printf("%v failed, retrying: %v", description, err)
+		} else {
+			log.Printf("%v failed with status %v, retrying", description, resp.StatusCode)
+			resp.Body.Close()
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+func (ac *ArtifactClient) twirp(method string, request interface{}, response interface{}) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("could not marshal %v request: %v", method, err)
+	}
+	endpoint := strings.TrimSuffix(ac.ResultsUrl, "/") + "/" + artifactServicePath + method
+
+	resp, err := ac.withRetries(method, func() (*http.Response, error) {
+		req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+ac.RuntimeToken)
+		return ac.HttpClient.Do(req)
+	})
+	if err != nil {
+		return fmt.Errorf("error while calling %v: %v", method, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("could not read %v response: %v", method, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var twirpErr twirpError
+		if json.Unmarshal(respBody, &twirpErr) == nil && twirpErr.Msg != "" {
+			return fmt.Errorf("%v failed: %v (%v)", method, twirpErr.Msg, twirpErr.Code)
+		}
+		return fmt.Errorf("%v failed with status %v", method, resp.StatusCode)
+	}
+	err = json.Unmarshal(respBody, response)
+	if err != nil {
+		return fmt.Errorf("could not parse %v response: %v", method, err)
+	}
+	return nil
+}
+
+// UploadArtifact zips files into a new artifact of the current workflow run and returns its id
+func (ac *ArtifactClient) UploadArtifact(name string, files map[string][]byte) (int64, error) {
+	runBackendId, jobRunBackendId, err := ac.backendIds()
+	if err != nil {
+		return 0, err
+	}
+	archive, err := zipFiles(files)
+	if err != nil {
+		return 0, fmt.Errorf("could not create artifact archive: %v", err)
+	}
+
+	var created createArtifactResponse
+	err = ac.twirp("CreateArtifact", createArtifactRequest{
+		WorkflowRunBackendId:    runBackendId,
+		WorkflowJobRunBackendId: jobRunBackendId,
+		Name:                    name,
+		Version:                 4,
+	}, &created)
+	if err != nil {
+		return 0, err
+	}
+	if !created.Ok || created.SignedUploadUrl == "" {
+		return 0, fmt.Errorf("artifact service did not accept artifact %v", name)
+	}
+
+	err = ac.uploadBlob(created.SignedUploadUrl, archive)
+	if err != nil {
+		return 0, fmt.Errorf("could not upload artifact %v: %v", name, err)
+	}
+
+	checksum := sha256.Sum256(archive)
+	var finalized finalizeArtifactResponse
+	err = ac.twirp("FinalizeArtifact", finalizeArtifactRequest{
+		WorkflowRunBackendId:    runBackendId,
+		WorkflowJobRunBackendId: jobRunBackendId,
+		Name:                    name,
+		Size:                    int64(len(archive)),
+		Hash:                    "sha256:" + hex.EncodeToString(checksum[:]),
+	}, &finalized)
+	if err != nil {
+		return 0, err
+	}
+	if !finalized.Ok {
+		return 0, fmt.Errorf("artifact service did not finalize artifact %v", name)
+	}
+	log.Printf("Uploaded artifact %v (id: %v, %v bytes)", name, finalized.ArtifactId, len(archive))
+	return finalized.ArtifactId, nil
+}
+
+// uploadBlob writes data to the signed blob url, in blocks when it is larger than the chunk size
+func (ac *ArtifactClient) uploadBlob(signedUrl string, data []byte) error {
+	chunkSize := ac.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = defaultArtifactChunkSize
+	}
+	if len(data) <= chunkSize {
+		return ac.putBlob(signedUrl, nil, data, map[string]string{"x-ms-blob-type": "BlockBlob"})
+	}
+
+	var blockList strings.Builder
+	blockList.WriteString(`<?xml version="1.0" encoding="utf-8"?><BlockList>`)
+	for offset, index := 0, 0; offset < len(data); offset, index = offset+chunkSize, index+1 {
+		end := min(offset+chunkSize, len(data))
+		// block ids of a blob must all have the same length
+		blockId := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("block-%08d", index)))
+		err := ac.putBlob(signedUrl, url.Values{"comp": {"block"}, "blockid": {blockId}}, data[offset:end], nil)
+		if err != nil {
+			return fmt.Errorf("could not upload block %v: %v", index, err)
+		}
+		blockList.WriteString("<Latest>" + blockId + "</Latest>")
+	}
+	blockList.WriteString("</BlockList>")
+
+	err := ac.putBlob(signedUrl, url.Values{"comp": {"blocklist"}}, []byte(blockList.String()), nil)
+	if err != nil {
+		return fmt.Errorf("could not commit blocks: %v", err)
+	}
+	return nil
+}
+
+func (ac *ArtifactClient) putBlob(signedUrl string, params url.Values, data []byte, headers map[string]string) error {
+	u, err := url.Parse(signedUrl)
+	if err != nil {
+		return fmt.Errorf("invalid upload url: %v", err)
+	}
+	query := u.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	u.RawQuery = query.Encode()
+	checksum := md5.Sum(data)
+
+	resp, err := ac.withRetries("blob upload", func() (*http.Response, error) {
+		req, err := http.NewRequest(http.MethodPut, u.String(), bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		// the storage service rejects blocks that were corrupted on the way
+		req.Header.Set("Content-MD5", base64.StdEncoding.EncodeToString(checksum[:]))
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		return ac.HttpClient.Do(req)
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %v", resp.StatusCode)
+	}
+	return nil
+}
+
+func zipFiles(files map[string][]byte) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	writer := zip.NewWriter(buf)
+	for name, contents := range files {
+		file, err := writer.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		_, err = file.Write(contents)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err := writer.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// fileFromZip returns the contents of name in archive, the crc of the entry is verified while reading
+func fileFromZip(archive []byte, name string) ([]byte, error) {
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return nil, fmt.Errorf("could not open artifact archive: %v", err)
+	}
+	for _, file := range reader.File {
+		if file.Name != name {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		contents, err := io.ReadAll(rc)
+		if err != nil {
+			return nil, fmt.Errorf("could not read %v from artifact archive: %v", name, err)
+		}
+		return contents, nil
+	}
+	return nil, fmt.Errorf("%v not found in artifact archive", name)
+}
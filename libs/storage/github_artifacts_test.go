@@ -0,0 +1,277 @@
+package storage
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v61/github"
+	"github.com/stretchr/testify/require"
+)
+
+const fakeRuntimeToken = "header.eyJzY3AiOiJBY3Rpb25zLkdlbmVyaWNSZWFkOjAwMDAwIEFjdGlvbnMuUmVzdWx0czpydW4tMTpqb2ItMSJ9.signature"
+
+type fakeArtifact struct {
+	Id        int64
+	Name      string
+	Digest    string
+	UpdatedAt time.Time
+}
+
+// fakeArtifactServer implements the parts of the artifact service, blob storage and github api used to store plans
+type fakeArtifactServer struct {
+	*httptest.Server
+	mu            sync.Mutex
+	blobs         map[string][]byte
+	blocks        map[string]map[string][]byte
+	blockUploads  int
+	artifacts     []fakeArtifact
+	failNextCalls int
+	listedNames   []string
+}
+
+func newFakeArtifactServer(t *testing.T) *fakeArtifactServer {
+	f := &fakeArtifactServer{blobs: map[string][]byte{}, blocks: map[string]map[string][]byte{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/twirp/github.actions.results.api.v1.ArtifactService/", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if f.failNextCalls > 0 {
+			f.failNextCalls--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer "+fakeRuntimeToken {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(twirpError{Code: "unauthenticated", Msg: "invalid token"})
+			return
+		}
+		switch strings.TrimPrefix(r.URL.Path, "/twirp/github.actions.results.api.v1.ArtifactService/") {
+		case "CreateArtifact":
+			var req createArtifactRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			require.Equal(t, "run-1", req.WorkflowRunBackendId)
+			require.Equal(t, "job-1", req.WorkflowJobRunBackendId)
+			require.Equal(t, 4, req.Version)
+			json.NewEncoder(w).Encode(createArtifactResponse{Ok: true, SignedUploadUrl: f.URL + "/blob/" + req.Name + "?sig=signed"})
+		case "FinalizeArtifact":
+			var req finalizeArtifactRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			blob, ok := f.blobs[req.Name]
+			require.True(t, ok)
+			require.Equal(t, int64(len(blob)), req.Size)
+			artifact := fakeArtifact{Id: int64(len(f.artifacts) + 1), Name: req.Name, Digest: req.Hash, UpdatedAt: time.Now()}
+			f.artifacts = append(f.artifacts, artifact)
+			fmt.Fprintf(w, `{"ok": true, "artifact_id": "%v"}`, artifact.Id)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	mux.HandleFunc("/blob/", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		name := strings.TrimPrefix(r.URL.Path, "/blob/")
+		if r.Method == http.MethodGet {
+			w.Write(f.blobs[name])
+			return
+		}
+		require.Equal(t, "signed", r.URL.Query().Get("sig"))
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		checksum := md5.Sum(body)
+		if r.Header.Get("Content-MD5") != base64.StdEncoding.EncodeToString(checksum[:]) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		switch r.URL.Query().Get("comp") {
+		case "block":
+			if f.blocks[name] == nil {
+				f.blocks[name] = map[string][]byte{}
+			}
+			f.blocks[name][r.URL.Query().Get("blockid")] = body
+			f.blockUploads++
+		case "blocklist":
+			var blockList struct {
+				Latest []string `xml:"Latest"`
+			}
+			require.NoError(t, xml.Unmarshal(body, &blockList))
+			var blob []byte
+			for _, blockId := range blockList.Latest {
+				blob = append(blob, f.blocks[name][blockId]...)
+			}
+			f.blobs[name] = blob
+		default:
+			require.Equal(t, "BlockBlob", r.Header.Get("x-ms-blob-type"))
+			f.blobs[name] = body
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+	mux.HandleFunc("/repos/owner/repo/actions/artifacts", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		name := r.URL.Query().Get("name")
+		f.listedNames = append(f.listedNames, name)
+		list := github.ArtifactList{TotalCount: github.Int64(int64(len(f.artifacts)))}
+		for _, artifact := range f.artifacts {
+			if name != "" && artifact.Name != name {
+				continue
+			}
+			list.Artifacts = append(list.Artifacts, &github.Artifact{
+				ID:        github.Int64(artifact.Id),
+				Name:      github.String(artifact.Name),
+				UpdatedAt: &github.Timestamp{Time: artifact.UpdatedAt},
+			})
+		}
+		json.NewEncoder(w).Encode(list)
+	})
+	mux.HandleFunc("/repos/owner/repo/actions/artifacts/", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		idPart, zip := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/repos/owner/repo/actions/artifacts/"), "/zip")
+		id, err := strconv.ParseInt(idPart, 10, 64)
+		require.NoError(t, err)
+		artifact := f.artifacts[id-1]
+		if zip {
+			http.Redirect(w, r, f.URL+"/blob/"+artifact.Name, http.StatusFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": artifact.Id, "name": artifact.Name, "digest": artifact.Digest})
+	})
+	f.Server = httptest.NewServer(mux)
+	return f
+}
+
+func (f *fakeArtifactServer) planStorage() *GithubPlanStorage {
+	client := github.NewClient(nil)
+	client.BaseURL, _ = url.Parse(f.URL + "/")
+	return &GithubPlanStorage{
+		Client:   client,
+		Owner:    "owner",
+		RepoName: "repo",
+		Artifacts: &ArtifactClient{
+			ResultsUrl:   f.URL,
+			RuntimeToken: fakeRuntimeToken,
+			HttpClient:   http.DefaultClient,
+			ChunkSize:    defaultArtifactChunkSize,
+			RetryDelay:   time.Millisecond,
+		},
+	}
+}
+
+func retrieveGithubPlan(t *testing.T, gps *GithubPlanStorage) ([]byte, error) {
+	retrieved, err := gps.RetrievePlan(filepath.Join(t.TempDir(), "dev.tfplan"), "dev", "owner-repo~12-dev.tfplan")
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(*retrieved)
+}
+
+func TestGithubPlanStorageUploadAndDownload(t *testing.T) {
+	server := newFakeArtifactServer(t)
+	defer server.Close()
+	gps := server.planStorage()
+
+	exists, err := gps.PlanExists("dev", "owner-repo~12-dev.tfplan")
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	err = gps.StorePlanFile([]byte("plan"), "dev", "owner-repo~12-dev.tfplan")
+	require.NoError(t, err)
+	require.Equal(t, 0, server.blockUploads)
+
+	// apply runs in another workflow run without access to the artifact client of the plan job
+	applyStorage := server.planStorage()
+	applyStorage.Artifacts = nil
+	exists, err = applyStorage.PlanExists("dev", "owner-repo~12-dev.tfplan")
+	require.NoError(t, err)
+	require.True(t, exists)
+	plan, err := retrieveGithubPlan(t, applyStorage)
+	require.NoError(t, err)
+	require.Equal(t, "plan", string(plan))
+
+	// lookups only list the artifacts of the plan instead of every artifact of the repository
+	require.NotEmpty(t, server.listedNames)
+	for _, name := range server.listedNames {
+		require.Equal(t, "owner-repo~12-dev.tfplan", name)
+	}
+}
+
+func TestGithubPlanStorageChunkedUpload(t *testing.T) {
+	server := newFakeArtifactServer(t)
+	defer server.Close()
+	gps := server.planStorage()
+	gps.Artifacts.ChunkSize = 1024
+
+	// random data does not compress, so the archive spans several blocks
+	largePlan := make([]byte, 5000)
+	rand.Read(largePlan)
+	err := gps.StorePlanFile(largePlan, "dev", "owner-repo~12-dev.tfplan")
+	require.NoError(t, err)
+	require.Greater(t, server.blockUploads, 4)
+
+	plan, err := retrieveGithubPlan(t, gps)
+	require.NoError(t, err)
+	require.Equal(t, largePlan, plan)
+}
+
+func TestGithubPlanStorageDetectsCorruptedArtifact(t *testing.T) {
+	server := newFakeArtifactServer(t)
+	defer server.Close()
+	gps := server.planStorage()
+
+	err := gps.StorePlanFile([]byte("plan"), "dev", "owner-repo~12-dev.tfplan")
+	require.NoError(t, err)
+	blob := server.blobs["owner-repo~12-dev.tfplan"]
+	blob[len(blob)-1] ^= 0xff
+
+	_, err = retrieveGithubPlan(t, gps)
+	require.ErrorContains(t, err, "does not match its digest")
+}
+
+func TestGithubPlanStorageRetriesArtifactService(t *testing.T) {
+	server := newFakeArtifactServer(t)
+	defer server.Close()
+	gps := server.planStorage()
+
+	server.failNextCalls = 1
+	err := gps.StorePlanFile([]byte("plan"), "dev", "owner-repo~12-dev.tfplan")
+	require.NoError(t, err)
+
+	server.failNextCalls = artifactRequestAttempts
+	err = gps.StorePlanFile([]byte("plan"), "dev", "owner-repo~12-dev.tfplan")
+	require.ErrorContains(t, err, "CreateArtifact failed with status 503")
+
+	gps.Artifacts.RuntimeToken = "header." + base64.RawURLEncoding.EncodeToString([]byte(`{"scp":"Actions.GenericRead:00000"}`)) + ".signature"
+	err = gps.StorePlanFile([]byte("plan"), "dev", "owner-repo~12-dev.tfplan")
+	require.ErrorContains(t, err, "no Actions.Results scope")
+}
+
+func TestArtifactOverflowStorageLinksToArtifact(t *testing.T) {
+	server := newFakeArtifactServer(t)
+	defer server.Close()
+	overflow := ArtifactOverflowStorage{
+		RunUrl:    "https://github.com/owner/repo/actions/runs/7",
+		Artifacts: server.planStorage().Artifacts,
+	}
+
+	link, err := overflow.Store("digger-report-abc", "full output")
+	require.NoError(t, err)
+	require.Equal(t, "https://github.com/owner/repo/actions/runs/7/artifacts/1", link)
+	output, err := fileFromZip(server.blobs["digger-report-abc"], "digger-report-abc.txt")
+	require.NoError(t, err)
+	require.Equal(t, "full output", string(output))
+}
@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PlanStorageLocal keeps plans in a directory, for runners that plan and apply on the same host
+type PlanStorageLocal struct {
+	Dir string
+}
+
+func (psl *PlanStorageLocal) planPath(storedPlanFilePath string) (string, error) {
+	if !filepath.IsLocal(storedPlanFilePath) {
+		return "", fmt.Errorf("invalid plan path %v", storedPlanFilePath)
+	}
+	return filepath.Join(psl.Dir, storedPlanFilePath), nil
+}
+
+func (psl *PlanStorageLocal) PlanExists(artifactName string, storedPlanFilePath string) (bool, error) {
+	path, err := psl.planPath(storedPlanFilePath)
+	if err != nil {
+		return false, err
+	}
+	_, err = os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("unable to stat plan: %v", err)
+	}
+	return true, nil
+}
+
+func (psl *PlanStorageLocal) StorePlanFile(fileContents []byte, artifactName string, fileName string) error {
+	path, err := psl.planPath(fileName)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return fmt.Errorf("unable to create plan directory: %v", err)
+	}
+
+	// write to a temporary file first so that a concurrent apply never reads a partial plan
+	file, err := os.CreateTemp(filepath.Dir(path), ".plan-*")
+	if err != nil {
+		return fmt.Errorf("unable to create file: %v", err)
+	}
+	defer os.Remove(file.Name())
+	_, err = file.Write(fileContents)
+	closeErr := file.Close()
+	if err != nil || closeErr != nil {
+		return fmt.Errorf("unable to write plan: %v", errors.Join(err, closeErr))
+	}
+	err = os.Rename(file.Name(), path)
+	if err != nil {
+		return fmt.Errorf("unable to store plan: %v", err)
+	}
+	return nil
+}
+
+func (psl *PlanStorageLocal) RetrievePlan(localPlanFilePath string, artifactName string, storedPlanFilePath string) (*string, error) {
+	path, err := psl.planPath(storedPlanFilePath)
+	if err != nil {
+		return nil, err
+	}
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read plan: %v", err)
+	}
+	err = os.WriteFile(localPlanFilePath, contents, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("unable to write data to file: %v", err)
+	}
+	fileName, err := filepath.Abs(localPlanFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get absolute path for file: %v", err)
+	}
+	return &fileName, nil
+}
+
+func (psl *PlanStorageLocal) DeleteStoredPlan(artifactName string, storedPlanFilePath string) error {
+	path, err := psl.planPath(storedPlanFilePath)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("unable to delete plan %v: %v", storedPlanFilePath, err)
+	}
+	return nil
+}
+
+func (psl *PlanStorageLocal) ListPlans(prefix string) ([]PlanInfo, error) {
+	var plans []PlanInfo
+	err := filepath.WalkDir(psl.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == psl.Dir {
+				return fs.SkipAll
+			}
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".plan-") {
+			return nil
+		}
+		name, err := filepath.Rel(psl.Dir, path)
+		if err != nil {
+			return err
+		}
+		name = filepath.ToSlash(name)
+		if !strings.HasPrefix(name, prefix) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		plans = append(plans, PlanInfo{StoredPlanFilePath: name, LastModified: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list plans in %v: %v", psl.Dir, err)
+	}
+	return plans, nil
+}
+
+func (psl *PlanStorageLocal) DeletePlan(plan PlanInfo) error {
+	return psl.DeleteStoredPlan(plan.ArtifactName, plan.StoredPlanFilePath)
+}
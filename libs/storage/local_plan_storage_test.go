@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanStorageLocal(t *testing.T) {
+	psl := &PlanStorageLocal{Dir: filepath.Join(t.TempDir(), "plans")}
+
+	plans, err := psl.ListPlans("")
+	require.NoError(t, err)
+	require.Empty(t, plans)
+
+	exists, err := psl.PlanExists("dev", "org-repo-12-dev.tfplan")
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	err = psl.StorePlanFile([]byte("plan"), "dev", "org-repo-12-dev.tfplan")
+	require.NoError(t, err)
+	err = psl.StorePlanFile([]byte("plan"), "dev", "org-other-12-dev.tfplan")
+	require.NoError(t, err)
+
+	exists, err = psl.PlanExists("dev", "org-repo-12-dev.tfplan")
+	require.NoError(t, err)
+	require.True(t, exists)
+
+	localPlanFilePath := filepath.Join(t.TempDir(), "dev.tfplan")
+	retrieved, err := psl.RetrievePlan(localPlanFilePath, "dev", "org-repo-12-dev.tfplan")
+	require.NoError(t, err)
+	contents, err := os.ReadFile(*retrieved)
+	require.NoError(t, err)
+	require.Equal(t, "plan", string(contents))
+
+	plans, err = psl.ListPlans("org-repo-")
+	require.NoError(t, err)
+	require.Len(t, plans, 1)
+	require.Equal(t, "org-repo-12-dev.tfplan", plans[0].StoredPlanFilePath)
+
+	err = psl.DeletePlan(plans[0])
+	require.NoError(t, err)
+	exists, err = psl.PlanExists("dev", "org-repo-12-dev.tfplan")
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	err = psl.StorePlanFile([]byte("plan"), "dev", "../outside.tfplan")
+	require.Error(t, err)
+}
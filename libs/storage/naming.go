@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"strconv"
+	"strings"
+)
+
+// StoredPlanFilePrefix is the part of StoredPlanFilePath shared by all plans of a namespace. The namespace is
+// terminated with "~", which can't appear in repository names, so that org/repo doesn't match plans of org/repo-two
+func StoredPlanFilePrefix(projectNamespace string) string {
+	return strings.ReplaceAll(projectNamespace, "/", "-") + "~"
+}
+
+// StoredPlanFilePath is the path the plan of a project is stored under. The pull request is kept in a segment of its
+// own between the namespace and the project, terminated with "~" like the namespace: the ephemeral workspace of the
+// project when it runs in one, the pull request number otherwise, empty outside of pull requests. Project names can
+// contain anything after it, e.g. the plan of a project named 12-app outside of pull requests is org-repo~~12-app.tfplan
+func StoredPlanFilePath(projectNamespace string, prNumber *int, ephemeralWorkspace string, projectName string) string {
+	pr := ""
+	if ephemeralWorkspace != "" {
+		pr = ephemeralWorkspace
+	} else if prNumber != nil {
+		pr = strconv.Itoa(*prNumber)
+	}
+	return StoredPlanFilePrefix(projectNamespace) + pr + "~" + projectName + ".tfplan"
+}
+
+// LegacyStoredPlanFilePath is the path plans were stored under before StoredPlanFilePath, so that plans stored by
+// older versions can still be applied
+func LegacyStoredPlanFilePath(projectNamespace string, prNumber *int, projectName string) string {
+	if prNumber != nil {
+		return strings.ReplaceAll(projectNamespace, "/", "-") + "-" + strconv.Itoa(*prNumber) + "-" + projectName + ".tfplan"
+	}
+	return strings.ReplaceAll(projectNamespace, "/", "-") + "-" + projectName + ".tfplan"
+}
+
+// ParseStoredPlanFilePath recovers the pull request and project from a path built by StoredPlanFilePath, ok is false
+// for paths of other namespaces or not built by it
+func ParseStoredPlanFilePath(projectNamespace string, storedPlanFilePath string) (prNumber *int, projectName string, ok bool) {
+	prefix := StoredPlanFilePrefix(projectNamespace)
+	if !strings.HasPrefix(storedPlanFilePath, prefix) || !strings.HasSuffix(storedPlanFilePath, ".tfplan") {
+		return nil, "", false
+	}
+	name := strings.TrimSuffix(strings.TrimPrefix(storedPlanFilePath, prefix), ".tfplan")
+	pr, project, found := strings.Cut(name, "~")
+	if !found || project == "" {
+		return nil, "", false
+	}
+	if pr == "" {
+		return nil, project, true
+	}
+	// ephemeral workspaces are named pr-N, see digger_config.EphemeralWorkspaceName
+	number, err := strconv.Atoi(strings.TrimPrefix(pr, "pr-"))
+	if err != nil {
+		return nil, "", false
+	}
+	return &number, project, true
+}
@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseStoredPlanFilePath(t *testing.T) {
+	prNumber := 12
+	for _, ephemeralWorkspace := range []string{"", "pr-12"} {
+		parsedPr, project, ok := ParseStoredPlanFilePath("org/repo", StoredPlanFilePath("org/repo", &prNumber, ephemeralWorkspace, "dev"))
+		assert.True(t, ok)
+		assert.Equal(t, 12, *parsedPr)
+		assert.Equal(t, "dev", project)
+	}
+
+	parsedPr, project, ok := ParseStoredPlanFilePath("org/repo", StoredPlanFilePath("org/repo", nil, "", "dev"))
+	assert.True(t, ok)
+	assert.Nil(t, parsedPr)
+	assert.Equal(t, "dev", project)
+
+	_, _, ok = ParseStoredPlanFilePath("org/other", StoredPlanFilePath("org/repo", &prNumber, "", "dev"))
+	assert.False(t, ok)
+	_, _, ok = ParseStoredPlanFilePath("org/repo", StoredPlanFilePath("org/repo-two", &prNumber, "", "dev"))
+	assert.False(t, ok)
+	_, _, ok = ParseStoredPlanFilePath("org/repo", "org-repo~notes.tfplan")
+	assert.False(t, ok)
+}
+
+func TestParseStoredPlanFilePathOfProjectNamedLikeAPullRequest(t *testing.T) {
+	storedPlanFilePath := StoredPlanFilePath("org/repo", nil, "", "12-app")
+	assert.Equal(t, "org-repo~~12-app.tfplan", storedPlanFilePath)
+	parsedPr, project, ok := ParseStoredPlanFilePath("org/repo", storedPlanFilePath)
+	assert.True(t, ok)
+	assert.Nil(t, parsedPr)
+	assert.Equal(t, "12-app", project)
+
+	prNumber := 3
+	parsedPr, project, ok = ParseStoredPlanFilePath("org/repo", StoredPlanFilePath("org/repo", &prNumber, "", "12~app"))
+	assert.True(t, ok)
+	assert.Equal(t, 3, *parsedPr)
+	assert.Equal(t, "12~app", project)
+}
+
+func TestLegacyStoredPlanFilePath(t *testing.T) {
+	prNumber := 12
+	assert.Equal(t, "org-repo-12-dev.tfplan", LegacyStoredPlanFilePath("org/repo", &prNumber, "dev"))
+	assert.Equal(t, "org-repo-dev.tfplan", LegacyStoredPlanFilePath("org/repo", nil, "dev"))
+	_, _, ok := ParseStoredPlanFilePath("org/repo", LegacyStoredPlanFilePath("org/repo", &prNumber, "dev"))
+	assert.False(t, ok)
+}
@@ -0,0 +1,281 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	gcpstorage "cloud.google.com/go/storage"
+	"github.com/google/go-github/v61/github"
+)
+
+// artifactDownloadTimeout bounds the download of a plan artifact from the signed url github redirects to
+var artifactDownloadTimeout = 5 * time.Minute
+
+// GithubPlanStorage keeps each plan in a workflow artifact named after its stored plan file path, so that
+// artifacts can be listed and pruned by namespace like the plans of other storages
+type GithubPlanStorage struct {
+	Client            *github.Client
+	Owner             string
+	RepoName          string
+	PullRequestNumber int
+	// Artifacts is created from the actions runner environment on the first upload when not set
+	Artifacts *ArtifactClient
+}
+
+func (gps *GithubPlanStorage) StorePlanFile(fileContents []byte, artifactName string, storedPlanFilePath string) error {
+	if gps.Artifacts == nil {
+		artifacts, err := NewArtifactClientFromEnv()
+		if err != nil {
+			return fmt.Errorf("could not create artifact client: %v", err)
+		}
+		gps.Artifacts = artifacts
+	}
+	_, err := gps.Artifacts.UploadArtifact(storedPlanFilePath, map[string][]byte{storedPlanFilePath: fileContents})
+	if err != nil {
+		return fmt.Errorf("could not upload plan artifact: %v", err)
+	}
+	return nil
+}
+
+func (gps *GithubPlanStorage) RetrievePlan(localPlanFilePath string, artifactName string, storedPlanFilePath string) (*string, error) {
+	artifact, err := gps.latestArtifact(storedPlanFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("error looking up plan artifact: %v", err)
+	}
+	if artifact == nil {
+		return nil, fmt.Errorf("no plans found for this PR")
+	}
+
+	// artifacts are downloaded by id so that apply can use plans uploaded by another workflow run
+	contents, err := gps.DownloadArtifactFile(artifact.GetID(), storedPlanFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("error downloading plan: %v", err)
+	}
+	err = os.WriteFile(localPlanFilePath, contents, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("unable to write plan to %v: %v", localPlanFilePath, err)
+	}
+	return &localPlanFilePath, nil
+}
+
+func (gps *GithubPlanStorage) PlanExists(artifactName string, storedPlanFilePath string) (bool, error) {
+	artifact, err := gps.latestArtifact(storedPlanFilePath)
+	if err != nil {
+		return false, err
+	}
+	return artifact != nil, nil
+}
+
+func (gps *GithubPlanStorage) DeleteStoredPlan(artifactName string, storedPlanFilePath string) error {
+	return nil
+}
+
+func (gps *GithubPlanStorage) ListPlans(prefix string) ([]PlanInfo, error) {
+	var plans []PlanInfo
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		artifacts, resp, err := gps.Client.Actions.ListArtifacts(context.Background(), gps.Owner, gps.RepoName, opts)
+		if err != nil {
+			return nil, fmt.Errorf("could not list artifacts: %v", err)
+		}
+		for _, artifact := range artifacts.Artifacts {
+			if !strings.HasPrefix(artifact.GetName(), prefix) || artifact.GetExpired() {
+				continue
+			}
+			plans = append(plans, PlanInfo{
+				ArtifactName:       artifact.GetName(),
+				StoredPlanFilePath: artifact.GetName(),
+				LastModified:       artifact.GetUpdatedAt().Time,
+				Id:                 strconv.FormatInt(artifact.GetID(), 10),
+			})
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return plans, nil
+}
+
+func (gps *GithubPlanStorage) DeletePlan(plan PlanInfo) error {
+	artifactId, err := strconv.ParseInt(plan.Id, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid artifact id %v: %v", plan.Id, err)
+	}
+	_, err = gps.Client.Actions.DeleteArtifact(context.Background(), gps.Owner, gps.RepoName, artifactId)
+	if err != nil {
+		return fmt.Errorf("could not delete artifact %v: %v", plan.ArtifactName, err)
+	}
+	return nil
+}
+
+// DownloadArtifactFile downloads an artifact of any workflow run of the repository and returns the contents of fileName in it
+func (gps *GithubPlanStorage) DownloadArtifactFile(artifactId int64, fileName string) ([]byte, error) {
+	digest, err := gps.artifactDigest(artifactId)
+	if err != nil {
+		return nil, err
+	}
+
+	downloadUrl, _, err := gps.Client.Actions.DownloadArtifact(context.Background(), gps.Owner, gps.RepoName, artifactId, 0)
+	if err != nil {
+		return nil, fmt.Errorf("could not get download url of artifact %v: %v", artifactId, err)
+	}
+	client := &http.Client{Timeout: artifactDownloadTimeout}
+	resp, err := client.Get(downloadUrl.String())
+	if err != nil {
+		return nil, fmt.Errorf("could not download artifact %v: %v", artifactId, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status when downloading artifact %v: %v", artifactId, resp.StatusCode)
+	}
+	archive, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("could not read artifact %v: %v", artifactId, err)
+	}
+
+	// artifacts uploaded with the v4 protocol carry the checksum sent when they were finalized
+	if digest != "" {
+		checksum := sha256.Sum256(archive)
+		if digest != "sha256:"+hex.EncodeToString(checksum[:]) {
+			return nil, fmt.Errorf("checksum of artifact %v does not match its digest %v", artifactId, digest)
+		}
+	}
+	log.Printf("Successfully fetched plan artifact %v", artifactId)
+	return fileFromZip(archive, fileName)
+}
+
+// artifactDigest returns the digest of an artifact, which the github client does not expose yet
+func (gps *GithubPlanStorage) artifactDigest(artifactId int64) (string, error) {
+	req, err := gps.Client.NewRequest("GET", fmt.Sprintf("repos/%v/%v/actions/artifacts/%v", gps.Owner, gps.RepoName, artifactId), nil)
+	if err != nil {
+		return "", fmt.Errorf("could not create request: %v", err)
+	}
+	var artifact struct {
+		Digest string `json:"digest"`
+	}
+	_, err = gps.Client.Do(context.Background(), req, &artifact)
+	if err != nil {
+		return "", fmt.Errorf("could not get artifact %v: %v", artifactId, err)
+	}
+	return artifact.Digest, nil
+}
+
+// latestArtifact returns the most recently updated artifact named name, nil if there is none. Artifacts are filtered
+// by name by the api, the github client does not support the name parameter yet
+func (gps *GithubPlanStorage) latestArtifact(name string) (*github.Artifact, error) {
+	var latest *github.Artifact
+	query := url.Values{"name": {name}, "per_page": {"100"}}
+	for {
+		req, err := gps.Client.NewRequest("GET", fmt.Sprintf("repos/%v/%v/actions/artifacts?%v", gps.Owner, gps.RepoName, query.Encode()), nil)
+		if err != nil {
+			return nil, fmt.Errorf("could not create request: %v", err)
+		}
+		var artifacts github.ArtifactList
+		resp, err := gps.Client.Do(context.Background(), req, &artifacts)
+		if err != nil {
+			return nil, err
+		}
+		for _, artifact := range artifacts.Artifacts {
+			if artifact.GetName() != name || artifact.GetExpired() {
+				continue
+			}
+			if latest == nil || artifact.GetUpdatedAt().Time.After(latest.GetUpdatedAt().Time) {
+				latest = artifact
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		query.Set("page", strconv.Itoa(resp.NextPage))
+	}
+	return latest, nil
+}
+
+// NewPlanStorageFromEnv creates the plan storage configured with PLAN_UPLOAD_DESTINATION, it returns nil when none is configured
+func NewPlanStorageFromEnv(ghToken string, ghRepoOwner string, ghRepositoryName string, prNumber *int) (PlanStorage, error) {
+	var planStorage PlanStorage
+
+	uploadDestination := strings.ToLower(os.Getenv("PLAN_UPLOAD_DESTINATION"))
+	switch {
+	case uploadDestination == "github":
+		planStorage = &GithubPlanStorage{
+			Client:            github.NewTokenClient(context.Background(), ghToken),
+			Owner:             ghRepoOwner,
+			RepoName:          ghRepositoryName,
+			PullRequestNumber: *prNumber,
+		}
+	case uploadDestination == "gcp":
+		bucketName := strings.ToLower(os.Getenv("GOOGLE_STORAGE_PLAN_ARTEFACT_BUCKET"))
+		if bucketName == "" {
+			return nil, fmt.Errorf("GOOGLE_STORAGE_PLAN_ARTEFACT_BUCKET is not defined")
+		}
+		ctx := context.Background()
+		client, err := gcpstorage.NewClient(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to create Google Storage client: %s", err)
+		}
+		planStorage = &PlanStorageGcp{
+			Client:  client,
+			Bucket:  client.Bucket(bucketName),
+			Context: ctx,
+		}
+	case uploadDestination == "aws":
+		ctx, client, err := GetAWSStorageClient()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to create AWS storage client: %s", err)
+		}
+		bucketName := strings.ToLower(os.Getenv("AWS_S3_BUCKET"))
+		if bucketName == "" {
+			return nil, fmt.Errorf("AWS_S3_BUCKET is not defined")
+		}
+		planStorage = &PlanStorageAWS{
+			Context: ctx,
+			Client:  client,
+			Bucket:  bucketName,
+		}
+	case uploadDestination == "azure":
+		containerName := os.Getenv("AZURE_STORAGE_PLAN_ARTEFACT_CONTAINER")
+		if containerName == "" {
+			return nil, fmt.Errorf("AZURE_STORAGE_PLAN_ARTEFACT_CONTAINER is not defined")
+		}
+		azureStorage, err := NewAzurePlanStorage(containerName)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to create Azure storage client: %s", err)
+		}
+		planStorage = azureStorage
+	case uploadDestination == "rest":
+		endpoint := os.Getenv("PLAN_UPLOAD_HTTP_ENDPOINT")
+		if endpoint == "" {
+			return nil, fmt.Errorf("PLAN_UPLOAD_HTTP_ENDPOINT is not defined")
+		}
+		planStorage = NewRestPlanStorage(endpoint, ghRepoOwner+"-"+ghRepositoryName, os.Getenv("DIGGER_TOKEN"))
+	case uploadDestination == "backend":
+		planStorage = NewRestPlanStorage(os.Getenv("DIGGER_HOSTNAME"), ghRepoOwner+"-"+ghRepositoryName, os.Getenv("DIGGER_TOKEN"))
+	case uploadDestination == "local":
+		dir := os.Getenv("PLAN_UPLOAD_LOCAL_DIR")
+		if dir == "" {
+			return nil, fmt.Errorf("PLAN_UPLOAD_LOCAL_DIR is not defined")
+		}
+		planStorage = &PlanStorageLocal{Dir: dir}
+	case uploadDestination == "gitlab":
+		//TODO implement me
+	}
+
+	planStorage, err := WithPlanEncryptionFromEnv(planStorage)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load plan encryption keys: %s", err)
+	}
+
+	return planStorage, nil
+}
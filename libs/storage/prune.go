@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+type PruneOptions struct {
+	ProjectNamespace string
+	// plans older than TTL are deleted, a zero TTL keeps plans regardless of their age
+	TTL time.Duration
+	// PullRequestClosed reports whether a pull request was merged or closed, plans of open pull requests are kept
+	PullRequestClosed func(prNumber int) (bool, error)
+	DryRun            bool
+	Now               time.Time
+}
+
+// withPlanMetadata fills in the pull request and project of plans, dropping those not named like
+// StoredPlanFilePath so that unrelated objects in the storage are never pruned
+func withPlanMetadata(projectNamespace string, plans []PlanInfo) []PlanInfo {
+	var named []PlanInfo
+	for _, plan := range plans {
+		prNumber, projectName, ok := ParseStoredPlanFilePath(projectNamespace, plan.StoredPlanFilePath)
+		if !ok {
+			continue
+		}
+		plan.PrNumber = prNumber
+		plan.ProjectName = projectName
+		named = append(named, plan)
+	}
+	return named
+}
+
+// PrunePlans deletes the plans of closed pull requests and plans older than the TTL, returning the pruned plans
+func PrunePlans(planStorage PlanStorage, opts PruneOptions) ([]PlanInfo, error) {
+	plans, err := planStorage.ListPlans(StoredPlanFilePrefix(opts.ProjectNamespace))
+	if err != nil {
+		return nil, fmt.Errorf("could not list plans: %v", err)
+	}
+	plans = withPlanMetadata(opts.ProjectNamespace, plans)
+
+	closedPullRequests := make(map[int]bool)
+	var pruned []PlanInfo
+	for _, plan := range plans {
+		expired := opts.TTL > 0 && opts.Now.Sub(plan.LastModified) > opts.TTL
+		closed := false
+		if !expired && plan.PrNumber != nil && opts.PullRequestClosed != nil {
+			isClosed, checked := closedPullRequests[*plan.PrNumber]
+			if !checked {
+				isClosed, err = opts.PullRequestClosed(*plan.PrNumber)
+				if err != nil {
+					log.Printf("could not check state of pull request %v, keeping its plans: %v", *plan.PrNumber, err)
+					continue
+				}
+				closedPullRequests[*plan.PrNumber] = isClosed
+			}
+			closed = isClosed
+		}
+		if !expired && !closed {
+			continue
+		}
+
+		if !opts.DryRun {
+			err := planStorage.DeletePlan(plan)
+			if err != nil {
+				return pruned, fmt.Errorf("could not delete plan %v of project %v: %v", plan.StoredPlanFilePath, plan.ProjectName, err)
+			}
+		}
+		pruned = append(pruned, plan)
+	}
+	return pruned, nil
+}
@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrunePlans(t *testing.T) {
+	now := time.Now()
+	planStorage := &memoryPlanStorage{
+		plans: map[string][]byte{
+			"org-repo~1~dev.tfplan":       []byte("closed pr"),
+			"org-repo~2~dev.tfplan":       []byte("open pr"),
+			"org-repo~pr-3~dev.tfplan":    []byte("ephemeral workspace of closed pr"),
+			"org-repo~~dev.tfplan":        []byte("old plan without pr"),
+			"org-repo~~prod.tfplan":       []byte("recent plan without pr"),
+			"org-other~1~dev.tfplan":      []byte("other repo"),
+			"org-repo~2~staging.tfplan":   []byte("open pr"),
+			"org-repo~4~staging.tfplan":   []byte("pr with unknown state"),
+			"org-repo~5~old-stuff.tfplan": []byte("old plan of open pr"),
+			"org-repo-two~1~dev.tfplan":   []byte("repo sharing the prefix"),
+			"org-repo~report.txt":         []byte("not a plan"),
+			"org-repo~~1-app.tfplan":      []byte("recent plan of a project named like pr 1"),
+		},
+		modified: map[string]time.Time{
+			"org-repo~1~dev.tfplan":       now,
+			"org-repo~2~dev.tfplan":       now,
+			"org-repo~pr-3~dev.tfplan":    now,
+			"org-repo~~dev.tfplan":        now.Add(-48 * time.Hour),
+			"org-repo~~prod.tfplan":       now,
+			"org-other~1~dev.tfplan":      now,
+			"org-repo~2~staging.tfplan":   now,
+			"org-repo~4~staging.tfplan":   now,
+			"org-repo~5~old-stuff.tfplan": now.Add(-48 * time.Hour),
+			"org-repo-two~1~dev.tfplan":   now,
+			"org-repo~report.txt":         now.Add(-48 * time.Hour),
+			"org-repo~~1-app.tfplan":      now,
+		},
+	}
+	checked := map[int]int{}
+	opts := PruneOptions{
+		ProjectNamespace: "org/repo",
+		TTL:              24 * time.Hour,
+		PullRequestClosed: func(prNumber int) (bool, error) {
+			checked[prNumber]++
+			if prNumber == 4 {
+				return false, fmt.Errorf("rate limited")
+			}
+			return prNumber == 1 || prNumber == 3, nil
+		},
+		Now: now,
+	}
+
+	opts.DryRun = true
+	pruned, err := PrunePlans(planStorage, opts)
+	require.NoError(t, err)
+	require.Len(t, pruned, 4)
+	require.Len(t, planStorage.plans, 12)
+
+	opts.DryRun = false
+	pruned, err = PrunePlans(planStorage, opts)
+	require.NoError(t, err)
+	var prunedPaths []string
+	for _, plan := range pruned {
+		prunedPaths = append(prunedPaths, plan.StoredPlanFilePath)
+	}
+	sort.Strings(prunedPaths)
+	require.Equal(t, []string{"org-repo~1~dev.tfplan", "org-repo~5~old-stuff.tfplan", "org-repo~pr-3~dev.tfplan", "org-repo~~dev.tfplan"}, prunedPaths)
+	require.Len(t, planStorage.plans, 8)
+	// pull request state is looked up once per pull request and run
+	require.Equal(t, 2, checked[2])
+}
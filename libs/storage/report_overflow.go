@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+)
+
+// ArtifactOverflowStorage uploads the full output of reports too long for a comment as workflow artifacts
+type ArtifactOverflowStorage struct {
+	// RunUrl is the page of the workflow run the artifacts are attached to
+	RunUrl string
+	// Artifacts is created from the actions runner environment on the first upload when not set
+	Artifacts *ArtifactClient
+}
+
+// NewArtifactOverflowStorageFromEnv returns nil outside of github actions
+func NewArtifactOverflowStorageFromEnv() *ArtifactOverflowStorage {
+	if os.Getenv("GITHUB_ACTIONS") != "true" {
+		return nil
+	}
+	runUrl := fmt.Sprintf("%v/%v/actions/runs/%v", os.Getenv("GITHUB_SERVER_URL"), os.Getenv("GITHUB_REPOSITORY"), os.Getenv("GITHUB_RUN_ID"))
+	return &ArtifactOverflowStorage{RunUrl: runUrl}
+}
+
+func (s *ArtifactOverflowStorage) Store(name string, output string) (string, error) {
+	if s.Artifacts == nil {
+		artifacts, err := NewArtifactClientFromEnv()
+		if err != nil {
+			return "", fmt.Errorf("could not create artifact client: %v", err)
+		}
+		s.Artifacts = artifacts
+	}
+	id, err := s.Artifacts.UploadArtifact(name, map[string][]byte{name + ".txt": []byte(output)})
+	if err != nil {
+		return "", fmt.Errorf("could not upload report artifact: %v", err)
+	}
+	return fmt.Sprintf("%v/artifacts/%v", s.RunUrl, id), nil
+}
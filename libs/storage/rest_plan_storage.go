@@ -0,0 +1,183 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// PlanStorageRest stores plans with the digger backend, authenticated with the job token
+type PlanStorageRest struct {
+	Endpoint   string
+	Repo       string
+	AuthToken  string
+	HttpClient *http.Client
+}
+
+type RestPlanInfo struct {
+	Name         string    `json:"name"`
+	Size         int       `json:"size"`
+	LastModified time.Time `json:"last_modified"`
+}
+
+func NewRestPlanStorage(endpoint string, repo string, authToken string) *PlanStorageRest {
+	return &PlanStorageRest{
+		Endpoint:   endpoint,
+		Repo:       repo,
+		AuthToken:  authToken,
+		HttpClient: http.DefaultClient,
+	}
+}
+
+func (psr *PlanStorageRest) plansUrl(storedPlanFilePath string) (*url.URL, error) {
+	u, err := url.Parse(psr.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("not able to parse backend url: %v", err)
+	}
+	u = u.JoinPath("repos", psr.Repo, "plans")
+	if storedPlanFilePath != "" {
+		u = u.JoinPath(storedPlanFilePath)
+	}
+	return u, nil
+}
+
+func (psr *PlanStorageRest) do(method string, u *url.URL, body []byte) (*http.Response, error) {
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("error while creating request: %v", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", psr.AuthToken))
+
+	resp, err := psr.HttpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error while sending request: %v", err)
+	}
+	return resp, nil
+}
+
+func (psr *PlanStorageRest) PlanExists(artifactName string, storedPlanFilePath string) (bool, error) {
+	u, err := psr.plansUrl(storedPlanFilePath)
+	if err != nil {
+		return false, err
+	}
+	resp, err := psr.do(http.MethodHead, u, nil)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	}
+	return false, fmt.Errorf("unexpected status when checking plan %v: %v", storedPlanFilePath, resp.StatusCode)
+}
+
+func (psr *PlanStorageRest) StorePlanFile(fileContents []byte, artifactName string, fileName string) error {
+	u, err := psr.plansUrl(fileName)
+	if err != nil {
+		return err
+	}
+	resp, err := psr.do(http.MethodPut, u, fileContents)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status when uploading plan %v: %v", fileName, resp.StatusCode)
+	}
+	return nil
+}
+
+func (psr *PlanStorageRest) RetrievePlan(localPlanFilePath string, artifactName string, storedPlanFilePath string) (*string, error) {
+	u, err := psr.plansUrl(storedPlanFilePath)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := psr.do(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status when downloading plan %v: %v", storedPlanFilePath, resp.StatusCode)
+	}
+
+	file, err := os.Create(localPlanFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create file: %v", err)
+	}
+	defer file.Close()
+
+	_, err = io.Copy(file, resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to write data to file: %v", err)
+	}
+	fileName, err := filepath.Abs(file.Name())
+	if err != nil {
+		return nil, fmt.Errorf("unable to get absolute path for file: %v", err)
+	}
+	return &fileName, nil
+}
+
+func (psr *PlanStorageRest) DeleteStoredPlan(artifactName string, storedPlanFilePath string) error {
+	u, err := psr.plansUrl(storedPlanFilePath)
+	if err != nil {
+		return err
+	}
+	resp, err := psr.do(http.MethodDelete, u, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("unexpected status when deleting plan %v: %v", storedPlanFilePath, resp.StatusCode)
+	}
+	return nil
+}
+
+func (psr *PlanStorageRest) ListPlans(prefix string) ([]PlanInfo, error) {
+	u, err := psr.plansUrl("")
+	if err != nil {
+		return nil, err
+	}
+	u.RawQuery = url.Values{"prefix": []string{prefix}}.Encode()
+	resp, err := psr.do(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status when listing plans: %v", resp.StatusCode)
+	}
+
+	var restPlans []RestPlanInfo
+	err = json.NewDecoder(resp.Body).Decode(&restPlans)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse plan list: %v", err)
+	}
+	plans := make([]PlanInfo, 0, len(restPlans))
+	for _, plan := range restPlans {
+		plans = append(plans, PlanInfo{StoredPlanFilePath: plan.Name, LastModified: plan.LastModified})
+	}
+	return plans, nil
+}
+
+func (psr *PlanStorageRest) DeletePlan(plan PlanInfo) error {
+	return psr.DeleteStoredPlan(plan.ArtifactName, plan.StoredPlanFilePath)
+}
@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newPlansServer emulates the plan endpoints of the digger backend
+func newPlansServer(t *testing.T, token string) *httptest.Server {
+	plans := map[string][]byte{}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.URL.Path == "/repos/org-repo/plans" && r.Method == http.MethodGet {
+			var infos []RestPlanInfo
+			for name, contents := range plans {
+				if strings.HasPrefix(name, r.URL.Query().Get("prefix")) {
+					infos = append(infos, RestPlanInfo{Name: name, Size: len(contents), LastModified: time.Now()})
+				}
+			}
+			json.NewEncoder(w).Encode(infos)
+			return
+		}
+		name, found := strings.CutPrefix(r.URL.Path, "/repos/org-repo/plans/")
+		if !found {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		contents, exists := plans[name]
+		switch r.Method {
+		case http.MethodPut:
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			plans[name] = body
+		case http.MethodGet, http.MethodHead:
+			if !exists {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write(contents)
+		case http.MethodDelete:
+			if !exists {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			delete(plans, name)
+		}
+	}))
+}
+
+func TestPlanStorageRest(t *testing.T) {
+	server := newPlansServer(t, "job-token")
+	defer server.Close()
+	psr := NewRestPlanStorage(server.URL, "org-repo", "job-token")
+
+	exists, err := psr.PlanExists("dev", "org-repo-12-dev.tfplan")
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	err = psr.StorePlanFile([]byte("plan"), "dev", "org-repo-12-dev.tfplan")
+	require.NoError(t, err)
+	exists, err = psr.PlanExists("dev", "org-repo-12-dev.tfplan")
+	require.NoError(t, err)
+	require.True(t, exists)
+
+	localPlanFilePath := filepath.Join(t.TempDir(), "dev.tfplan")
+	retrieved, err := psr.RetrievePlan(localPlanFilePath, "dev", "org-repo-12-dev.tfplan")
+	require.NoError(t, err)
+	contents, err := os.ReadFile(*retrieved)
+	require.NoError(t, err)
+	require.Equal(t, "plan", string(contents))
+
+	plans, err := psr.ListPlans("org-repo-12-")
+	require.NoError(t, err)
+	require.Len(t, plans, 1)
+	require.Equal(t, "org-repo-12-dev.tfplan", plans[0].StoredPlanFilePath)
+
+	err = psr.DeletePlan(plans[0])
+	require.NoError(t, err)
+	exists, err = psr.PlanExists("dev", "org-repo-12-dev.tfplan")
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	_, err = NewRestPlanStorage(server.URL, "org-repo", "wrong-token").PlanExists("dev", "org-repo-12-dev.tfplan")
+	require.ErrorContains(t, err, "403")
+}
@@ -0,0 +1,25 @@
+package storage
+
+import "time"
+
+type PlanStorage interface {
+	StorePlanFile(fileContents []byte, artifactName string, storedPlanFilePath string) error
+	RetrievePlan(localPlanFilePath string, artifactName string, storedPlanFilePath string) (*string, error)
+	DeleteStoredPlan(artifactName string, storedPlanFilePath string) error
+	PlanExists(artifactName string, storedPlanFilePath string) (bool, error)
+	// ListPlans returns the stored plans whose path, or artifact name for artifact based storages, starts with prefix
+	ListPlans(prefix string) ([]PlanInfo, error)
+	// DeletePlan removes a plan returned by ListPlans
+	DeletePlan(plan PlanInfo) error
+}
+
+type PlanInfo struct {
+	ArtifactName       string
+	StoredPlanFilePath string
+	LastModified       time.Time
+	// set by storages that address plans by their own identifier, e.g. github artifact ids
+	Id string
+	// PrNumber and ProjectName are derived from the stored path when it follows the default naming
+	PrNumber    *int
+	ProjectName string
+}
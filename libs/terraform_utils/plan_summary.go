@@ -121,6 +121,17 @@ func GetPlanFootprint(planJson string) (*TerraformPlanFootprint, error) {
 	return &footprint, nil
 }
 
+// GetChangedResources returns the resource changes of a plan that are not no-ops
+func GetChangedResources(planJson string) ([]ResourceChange, error) {
+	tfplan, err := parseTerraformPlanOutput(planJson)
+	if err != nil {
+		return nil, err
+	}
+	return lo.Filter(tfplan.ResourceChanges, func(change ResourceChange, idx int) bool {
+		return len(change.Change.Actions) != 1 || change.Change.Actions[0] != "no-op"
+	}), nil
+}
+
 func PerformPlanSimilarityCheck(footprint1 TerraformPlanFootprint, footprint2 TerraformPlanFootprint) (bool, error) {
 	return footprint1.hash() == footprint2.hash(), nil
 }